@@ -17,7 +17,7 @@ import (
 // Idempotent seed:
 //  1. Upserts system roles + their permission sets.
 //  2. Upserts the admin user.
-//  3. Assigns the Admin role to the admin user.
+//  3. Assigns the Admin role and the main store to the admin user.
 //
 // Env overrides (defaults shown):
 //
@@ -52,7 +52,11 @@ func main() {
 	if err := seedBrands(ctx, bundb); err != nil {
 		log.Fatalf("seed brands: %v", err)
 	}
-	if err := seedLocations(ctx, bundb); err != nil {
+	store, err := seedMainStore(ctx, bundb)
+	if err != nil {
+		log.Fatalf("seed store: %v", err)
+	}
+	if err := seedLocations(ctx, bundb, store.ID); err != nil {
 		log.Fatalf("seed locations: %v", err)
 	}
 	if err := seedShiftTemplates(ctx, bundb); err != nil {
@@ -103,6 +107,13 @@ func main() {
 	if err != nil {
 		log.Fatalf("assign admin role: %v", err)
 	}
+	_, err = bundb.NewInsert().
+		Model(&models.UserStore{UserID: user.ID, StoreID: store.ID, IsDefault: true}).
+		On("CONFLICT (user_id, store_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		log.Fatalf("assign admin store: %v", err)
+	}
 
 	fmt.Printf("admin seeded: %s (id=%s)\n", user.Email, user.ID)
 }
@@ -134,6 +145,14 @@ var systemRoles = []struct {
 			"menu.price-history", "menu.supplier-prices", "menu.stock-movements",
 		},
 	},
+	{
+		name:        "HQ",
+		description: "Kantor pusat: laporan gabungan semua toko.",
+		permissions: []string{
+			"menu.dashboard", "menu.orders", "menu.inventory", "menu.reports",
+			"menu.reports.laba", "menu.stock-movements", "menu.price-history",
+		},
+	},
 	{
 		name:        "Kasir",
 		description: "Operasional kasir, pesanan, dan pelanggan harian.",
@@ -380,17 +399,31 @@ var systemLocations = []models.Location{
 	},
 }
 
-func seedLocations(ctx context.Context, db *bun.DB) error {
+// seedMainStore upserts the MAIN store the migration creates, so a fresh
+// database seeded without it still has one.
+func seedMainStore(ctx context.Context, db *bun.DB) (*models.Store, error) {
+	store := &models.Store{Code: "MAIN", Name: "Toko Utama", Status: models.StoreStatusActive}
+	_, err := db.NewInsert().Model(store).
+		On("CONFLICT (code) DO UPDATE").
+		Set("updated_at = current_timestamp").
+		Returning("*").
+		Exec(ctx)
+	return store, err
+}
+
+func seedLocations(ctx context.Context, db *bun.DB, storeID uuid.UUID) error {
 	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
-		// Demote all defaults first so the partial unique index won't fire.
+		// Demote the store's defaults first so the partial unique index
+		// won't fire.
 		if _, err := tx.NewUpdate().Table("locations").
 			Set("is_default_receipt = false").
-			Where("is_default_receipt = true").
+			Where("is_default_receipt = true AND store_id = ?", storeID).
 			Exec(ctx); err != nil {
 			return err
 		}
 		for _, l := range systemLocations {
 			row := l
+			row.StoreID = storeID
 			_, err := tx.NewInsert().Model(&row).
 				On("CONFLICT (slug) DO UPDATE").
 				Set("name = EXCLUDED.name").
@@ -14,6 +14,8 @@ import (
 	"github.com/sandisahdewo/pos/backend/internal/config"
 	"github.com/sandisahdewo/pos/backend/internal/db"
 	"github.com/sandisahdewo/pos/backend/internal/handlers"
+	"github.com/sandisahdewo/pos/backend/internal/jobs"
+	"github.com/sandisahdewo/pos/backend/internal/mail"
 	"github.com/sandisahdewo/pos/backend/internal/server"
 )
 
@@ -31,11 +33,23 @@ func main() {
 
 	issuer := auth.NewIssuer(cfg.JWTSecret, cfg.JWTTokenTTL)
 
+	mailer, err := mail.New(mail.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	if err != nil {
+		log.Fatalf("mail: %v", err)
+	}
+
 	router := server.NewRouter(server.Options{
 		Deps: handlers.Deps{
 			DB:         bundb,
 			Issuer:     issuer,
 			BcryptCost: cfg.BcryptCost,
+			Mailer:     mailer,
 		},
 		Issuer:          issuer,
 		CORSAllowOrigin: cfg.CORSAllowOrigin,
@@ -56,11 +70,91 @@ func main() {
 		}
 	}()
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if cfg.JobsEnabled {
+		// Expiry is date-granular; an hourly tick picks up the day rollover
+		// promptly and is a no-op otherwise.
+		jobs.Start(jobsCtx,
+			jobs.Job{
+				Name:     "quarantine-expired-batches",
+				Interval: time.Hour,
+				Run: func(ctx context.Context) error {
+					n, err := handlers.QuarantineExpiredBatches(ctx, bundb, time.Now())
+					if n > 0 {
+						log.Printf("quarantined %d expired batches", n)
+					}
+					return err
+				},
+			},
+			jobs.Job{
+				// Reclassifies, then creates the day's cycle-count drafts;
+				// a location that already has today's draft is skipped.
+				Name:     "cycle-count-schedule",
+				Interval: time.Hour,
+				Run: func(ctx context.Context) error {
+					n, err := handlers.RunCycleCountSchedule(ctx, bundb, time.Now())
+					if n > 0 {
+						log.Printf("created %d cycle-count drafts", n)
+					}
+					return err
+				},
+			},
+			jobs.Job{
+				// Snapshots each finished day once; later runs that day
+				// find nothing missing.
+				Name:     "stock-snapshots",
+				Interval: time.Hour,
+				Run: func(ctx context.Context) error {
+					n, err := handlers.RunStockSnapshots(ctx, bundb, time.Now())
+					if n > 0 {
+						log.Printf("took %d stock snapshots", n)
+					}
+					return err
+				},
+			},
+			jobs.Job{
+				// Price list rows dated ahead take effect on their day.
+				Name:     "apply-supplier-costs",
+				Interval: time.Hour,
+				Run: func(ctx context.Context) error {
+					n, err := handlers.RunSupplierCosts(ctx, bundb, time.Now())
+					if n > 0 {
+						log.Printf("applied %d supplier cost changes", n)
+					}
+					return err
+				},
+			},
+			jobs.Job{
+				// Scheduled prices are timestamped, so this ticks often
+				// enough that a midnight change lands at midnight.
+				Name:     "apply-price-schedules",
+				Interval: time.Minute,
+				Run: func(ctx context.Context) error {
+					n, err := handlers.RunPriceSchedules(ctx, bundb, time.Now())
+					if n > 0 {
+						log.Printf("applied %d scheduled prices", n)
+					}
+					return err
+				},
+			},
+			jobs.Job{
+				Name:     "release-expired-reservations",
+				Interval: time.Minute,
+				Run: func(ctx context.Context) error {
+					_, err := handlers.ReleaseExpiredReservations(ctx, bundb)
+					return err
+				},
+			},
+		)
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
 	log.Println("shutting down")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
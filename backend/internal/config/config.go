@@ -14,6 +14,16 @@ type Config struct {
 	JWTTokenTTL     time.Duration
 	BcryptCost      int
 	CORSAllowOrigin string
+	// Background jobs (expiry quarantine, …). Disable when running several
+	// API replicas and only one should do the scheduled work.
+	JobsEnabled bool
+	// Outgoing email (purchase orders to suppliers). Empty SMTPHost
+	// disables sending; point it at a catcher such as Mailpit in dev.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
 }
 
 func Load() (*Config, error) {
@@ -32,6 +42,12 @@ func Load() (*Config, error) {
 		JWTTokenTTL:     envDuration("JWT_TOKEN_TTL", 24*time.Hour),
 		BcryptCost:      envInt("BCRYPT_COST", 12),
 		CORSAllowOrigin: envOr("CORS_ALLOW_ORIGIN", "http://localhost:5173"),
+		JobsEnabled:     envBool("JOBS_ENABLED", true),
+		SMTPHost:        os.Getenv("SMTP_HOST"),
+		SMTPPort:        envInt("SMTP_PORT", 587),
+		SMTPUsername:    os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:    os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:        envOr("SMTP_FROM", "POS <no-reply@pos.local>"),
 	}, nil
 }
 
@@ -54,6 +70,18 @@ func envInt(key string, fallback int) int {
 	return n
 }
 
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
 func envDuration(key string, fallback time.Duration) time.Duration {
 	v := os.Getenv(key)
 	if v == "" {
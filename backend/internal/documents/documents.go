@@ -0,0 +1,288 @@
+// Package documents renders printable A4 business documents (handover notes,
+// statements, purchase orders) as PDF. Like the label renderer it lays out
+// the pages itself and leaves the file to the shared pdf writer, so no
+// third-party dependency is needed.
+package documents
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sandisahdewo/pos/backend/internal/pdf"
+)
+
+// Field is one "label: value" pair in the document header.
+type Field struct {
+	Label string
+	Value string
+}
+
+// Column describes one table column. Width is relative; the columns share
+// the printable width in proportion to it.
+type Column struct {
+	Title string
+	Width float64
+	Right bool
+}
+
+// Signature is a signing block printed at the bottom: a caption above the
+// line and the signer's name (may be blank for a hand-written one) below.
+type Signature struct {
+	Caption string
+	Name    string
+}
+
+type Document struct {
+	Title      string
+	Code       string
+	Issuer     string
+	Meta       []Field
+	Columns    []Column
+	Rows       [][]string
+	Totals     []string
+	Summary    []Field
+	Notes      string
+	Signatures []Signature
+}
+
+const (
+	pageW   = 595.28
+	pageH   = 841.89
+	margin  = 42.0
+	bodyPt  = 9.0
+	tablePt = 8.5
+	lineH   = 13.0
+)
+
+type writer struct {
+	pages []*strings.Builder
+	cur   *strings.Builder
+	y     float64
+}
+
+func (w *writer) newPage() {
+	w.cur = &strings.Builder{}
+	w.pages = append(w.pages, w.cur)
+	w.y = pageH - margin
+}
+
+func (w *writer) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(w.cur, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdf.String(s))
+}
+
+func (w *writer) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(w.cur, "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// ensure starts a new page when fewer than h points remain above the footer.
+func (w *writer) ensure(h float64) bool {
+	if w.y-h < margin+lineH {
+		w.newPage()
+		return true
+	}
+	return false
+}
+
+// PDF renders d on as many A4 pages as the table needs; the table header is
+// repeated on every continuation page and each page carries a footer with
+// the document code and page number.
+func PDF(d Document) []byte {
+	w := &writer{}
+	w.newPage()
+	left, right := margin, pageW-margin
+
+	if d.Issuer != "" {
+		w.text("F1", bodyPt, left, w.y-bodyPt, d.Issuer)
+		w.y -= lineH
+	}
+	w.text("F2", 15, left, w.y-15, d.Title)
+	if d.Code != "" {
+		w.text("F2", 11, right-textWidth(d.Code, 11), w.y-13, d.Code)
+	}
+	w.y -= 24
+	w.line(left, w.y, right, w.y)
+	w.y -= 6
+
+	labelW := 0.0
+	for _, f := range d.Meta {
+		labelW = max(labelW, textWidth(f.Label, bodyPt))
+	}
+	for _, f := range d.Meta {
+		w.y -= lineH
+		w.text("F1", bodyPt, left, w.y, f.Label)
+		w.text("F1", bodyPt, left+labelW+6, w.y, ": "+f.Value)
+	}
+	w.y -= lineH
+
+	cols := layoutColumns(d.Columns, right-left)
+	header := func() {
+		w.y -= lineH
+		for i, c := range d.Columns {
+			w.cell("F2", cols[i], c, c.Title)
+		}
+		w.y -= 4
+		w.line(left, w.y, right, w.y)
+	}
+	if len(d.Columns) > 0 {
+		header()
+		for _, row := range d.Rows {
+			if w.ensure(lineH) {
+				header()
+			}
+			w.y -= lineH
+			for i, c := range d.Columns {
+				if i < len(row) {
+					w.cell("F1", cols[i], c, row[i])
+				}
+			}
+		}
+		if len(d.Totals) > 0 {
+			w.ensure(lineH + 4)
+			w.y -= 4
+			w.line(left, w.y, right, w.y)
+			w.y -= lineH
+			for i, c := range d.Columns {
+				if i < len(d.Totals) {
+					w.cell("F2", cols[i], c, d.Totals[i])
+				}
+			}
+		}
+		w.y -= lineH
+	}
+
+	if len(d.Summary) > 0 {
+		for _, f := range d.Summary {
+			w.ensure(lineH)
+			w.y -= lineH
+			w.text("F1", bodyPt, right-200, w.y, f.Label)
+			w.text("F2", bodyPt, right-textWidth(f.Value, bodyPt), w.y, f.Value)
+		}
+		w.y -= lineH
+	}
+
+	if notes := strings.TrimSpace(d.Notes); notes != "" {
+		w.ensure(2 * lineH)
+		w.y -= lineH
+		w.text("F2", bodyPt, left, w.y, "Catatan")
+		for _, l := range wrap(notes, right-left, bodyPt) {
+			w.ensure(lineH)
+			w.y -= lineH
+			w.text("F1", bodyPt, left, w.y, l)
+		}
+		w.y -= lineH
+	}
+
+	if n := len(d.Signatures); n > 0 {
+		w.ensure(90)
+		slot := (right - left) / float64(n)
+		top := w.y - lineH
+		for i, s := range d.Signatures {
+			x := left + float64(i)*slot
+			boxW := slot - 24
+			w.text("F1", bodyPt, x+(boxW-textWidth(s.Caption, bodyPt))/2, top, s.Caption)
+			w.line(x, top-55, x+boxW, top-55)
+			if s.Name != "" {
+				w.text("F1", bodyPt, x+(boxW-textWidth(s.Name, bodyPt))/2, top-55-lineH, s.Name)
+			}
+		}
+		w.y = top - 55 - 2*lineH
+	}
+
+	for i, p := range w.pages {
+		footer := fmt.Sprintf("Halaman %d / %d", i+1, len(w.pages))
+		if d.Code != "" {
+			footer = d.Code + " - " + footer
+		}
+		fmt.Fprintf(p, "BT /F1 7.5 Tf %.2f %.2f Td (%s) Tj ET\n",
+			right-textWidth(footer, 7.5), margin-12, pdf.String(footer))
+	}
+	streams := make([]string, len(w.pages))
+	for i, p := range w.pages {
+		streams[i] = "0.5 w\n" + p.String()
+	}
+	return pdf.File(pageW, pageH, streams)
+}
+
+type colBox struct{ x, w float64 }
+
+func layoutColumns(cols []Column, total float64) []colBox {
+	sum := 0.0
+	for _, c := range cols {
+		sum += max(c.Width, 1)
+	}
+	out := make([]colBox, len(cols))
+	x := margin
+	for i, c := range cols {
+		cw := total * max(c.Width, 1) / sum
+		out[i] = colBox{x: x, w: cw}
+		x += cw
+	}
+	return out
+}
+
+// cell prints s inside the column box, truncated to fit with a little
+// padding, left- or right-aligned per the column.
+func (w *writer) cell(font string, b colBox, c Column, s string) {
+	const pad = 3
+	s = truncate(s, b.w-2*pad, tablePt)
+	x := b.x + pad
+	if c.Right {
+		x = b.x + b.w - pad - textWidth(s, tablePt)
+	}
+	w.text(font, tablePt, x, w.y, s)
+}
+
+// textWidth approximates Helvetica advance widths by character class — good
+// enough for right alignment and truncation without shipping AFM tables.
+func textWidth(s string, size float64) float64 {
+	em := 0.0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			em += 0.556
+		case r == ' ' || r == '.' || r == ',' || r == ':' || r == ';' || r == '|':
+			em += 0.278
+		case r == 'i' || r == 'j' || r == 'l' || r == 'I' || r == 'f' || r == 't' || r == 'r':
+			em += 0.3
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+			em += 0.85
+		case r >= 'A' && r <= 'Z':
+			em += 0.68
+		default:
+			em += 0.53
+		}
+	}
+	return em * size
+}
+
+func truncate(s string, width, size float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	rs := []rune(s)
+	for len(rs) > 0 && textWidth(string(rs)+"...", size) > width {
+		rs = rs[:len(rs)-1]
+	}
+	return string(rs) + "..."
+}
+
+// wrap breaks s into lines no wider than width, honouring explicit newlines.
+func wrap(s string, width, size float64) []string {
+	var out []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if line != "" && textWidth(next, size) > width {
+				out = append(out, line)
+				next = word
+			}
+			line = next
+		}
+		out = append(out, line)
+	}
+	return out
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
 )
 
 type AppSettingsHandler struct {
@@ -42,6 +47,22 @@ func (h *AppSettingsHandler) Put(w http.ResponseWriter, r *http.Request) {
 	if len(in.Value) == 0 {
 		in.Value = json.RawMessage("{}")
 	}
+	// The frontend only knows the keys it renders, so a PUT from it would
+	// drop server-only keys (purchasing tolerance, allocation strategy, …).
+	// Merge-patch over the stored blob instead of replacing it; send a key
+	// as null to remove it.
+	var stored json.RawMessage
+	if err := h.deps.DB.NewSelect().Table("app_settings").Column("value").
+		Where("id = 1").Scan(r.Context(), &stored); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	merged, err := mergeSettingsJSON(stored, in.Value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "value harus berupa objek JSON")
+		return
+	}
+	in.Value = merged
 	res, err := h.deps.DB.NewUpdate().Table("app_settings").
 		Where("id = 1").
 		Set("value = ?", in.Value).
@@ -66,3 +87,140 @@ func (h *AppSettingsHandler) Put(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, s)
 }
+
+// serverSettings is the subset of the /settings blob the backend itself acts
+// on. The frontend owns the full shape; unknown keys are ignored here and
+// missing keys fall back to the defaults in defaultServerSettings.
+type serverSettings struct {
+	Purchasing struct {
+		// Percent of the ordered quantity a line may be over-received by.
+		OverReceiptTolerancePct float64 `json:"overReceiptTolerancePct"`
+		// POs whose total is above this (IDR) wait in pending-approval on
+		// submit until someone with feature.purchasing.approve approves
+		// them. 0 approves every PO on submit.
+		ApprovalThreshold float64 `json:"approvalThreshold"`
+		// A supplier cost rise of at least this percent is flagged in the
+		// supplier cost alerts.
+		CostRiseAlertPct float64 `json:"costRiseAlertPct"`
+		// Three-way match tolerances for supplier invoices: how far an
+		// invoiced unit price may stray from the PO price, and how far the
+		// billed qty may run over the qty ordered and received, in percent.
+		InvoicePriceTolerancePct float64 `json:"invoicePriceTolerancePct"`
+		InvoiceQtyTolerancePct   float64 `json:"invoiceQtyTolerancePct"`
+	} `json:"purchasing"`
+	Inventory struct {
+		// "fifo" (default) draws strictly by receipt date; "fefo" draws
+		// the earliest-expiring batch first (undated last).
+		AllocationStrategy string `json:"allocationStrategy"`
+		// Fallback near-expiry horizon for categories without their own.
+		NearExpiryDays int `json:"nearExpiryDays"`
+		// How long a cart/order reservation holds stock without renewal.
+		ReservationTTLMinutes int `json:"reservationTtlMinutes"`
+		// Adjustments / write-offs worth at least this much (IDR) wait for
+		// approval; 0 sends every adjustment to the queue.
+		AdjustmentApprovalThreshold float64 `json:"adjustmentApprovalThreshold"`
+		// Reason codes that always need approval regardless of value.
+		AdjustmentApprovalReasons []string `json:"adjustmentApprovalReasons"`
+	} `json:"inventory"`
+	CycleCount struct {
+		// Cumulative share of sales value (pct) that closes class A and
+		// class B; everything after is C.
+		AThresholdPct float64 `json:"aThresholdPct"`
+		BThresholdPct float64 `json:"bThresholdPct"`
+		// Sales window the ABC classification looks at.
+		LookbackDays int `json:"lookbackDays"`
+		// Days between counts of a product at a location, per class.
+		IntervalDaysA int `json:"intervalDaysA"`
+		IntervalDaysB int `json:"intervalDaysB"`
+		IntervalDaysC int `json:"intervalDaysC"`
+	} `json:"cycleCount"`
+	Scan struct {
+		// In-store EAN-13 ranges printed by deli/produce scales.
+		EmbeddedBarcodes []embeddedBarcodeRule `json:"embeddedBarcodes"`
+	} `json:"scan"`
+}
+
+func defaultServerSettings() serverSettings {
+	var s serverSettings
+	s.Purchasing.OverReceiptTolerancePct = 0
+	s.Purchasing.ApprovalThreshold = 10000000
+	s.Purchasing.CostRiseAlertPct = 10
+	s.Purchasing.InvoicePriceTolerancePct = 1
+	s.Purchasing.InvoiceQtyTolerancePct = 0
+	s.Inventory.AllocationStrategy = allocationFIFO
+	s.Inventory.NearExpiryDays = 30
+	s.Inventory.ReservationTTLMinutes = 30
+	s.Inventory.AdjustmentApprovalThreshold = 500000
+	s.Inventory.AdjustmentApprovalReasons = []string{models.AdjustmentReasonTheft}
+	s.CycleCount.AThresholdPct = 80
+	s.CycleCount.BThresholdPct = 95
+	s.CycleCount.LookbackDays = 365
+	s.CycleCount.IntervalDaysA = 30
+	s.CycleCount.IntervalDaysB = 91
+	s.CycleCount.IntervalDaysC = 365
+	s.Scan.EmbeddedBarcodes = []embeddedBarcodeRule{
+		{Prefix: "20", Kind: embeddedWeight, PLUDigits: 5, Divisor: 1000},
+	}
+	return s
+}
+
+// loadServerSettings reads the app_settings singleton and overlays it on the
+// defaults. A missing row is not an error — defaults apply.
+func loadServerSettings(ctx context.Context, db bun.IDB) (serverSettings, error) {
+	out := defaultServerSettings()
+	var raw json.RawMessage
+	err := db.NewSelect().Table("app_settings").Column("value").
+		Where("id = 1").Scan(ctx, &raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return out, nil
+	}
+	if err != nil {
+		return out, err
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return defaultServerSettings(), fmt.Errorf("app settings: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// mergeSettingsJSON overlays patch on base with JSON merge-patch semantics
+// (RFC 7386): nested objects merge recursively, a null removes the key, and
+// any other value replaces whatever base had.
+func mergeSettingsJSON(base, patch json.RawMessage) (json.RawMessage, error) {
+	var p map[string]any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	b := map[string]any{}
+	if len(base) > 0 {
+		// A corrupt stored blob is simply replaced.
+		_ = json.Unmarshal(base, &b)
+		if b == nil {
+			b = map[string]any{}
+		}
+	}
+	mergeSettingsMap(b, p)
+	return json.Marshal(b)
+}
+
+func mergeSettingsMap(dst, src map[string]any) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+		sv, srcIsObj := v.(map[string]any)
+		if !srcIsObj {
+			dst[k] = v
+			continue
+		}
+		dv, dstIsObj := dst[k].(map[string]any)
+		if !dstIsObj {
+			dv = map[string]any{}
+			dst[k] = dv
+		}
+		mergeSettingsMap(dv, sv)
+	}
+}
@@ -93,5 +93,9 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user.RoleIDs = ids
+	if user.StoreIDs, user.DefaultStoreID, err = loadUserStores(r.Context(), h.deps.DB, user.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, user)
 }
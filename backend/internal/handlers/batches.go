@@ -24,7 +24,11 @@ func NewBatchesHandler(deps Deps) *BatchesHandler {
 
 func (h *BatchesHandler) List(w http.ResponseWriter, r *http.Request) {
 	items := []models.Batch{}
-	q := h.deps.DB.NewSelect().Model(&items).Order("received_at DESC, created_at DESC")
+	q := h.deps.DB.NewSelect().Model(&items).
+		ColumnExpr("bt.*").
+		ColumnExpr(reservedQtyExpr).
+		Order("received_at DESC, created_at DESC")
+	q = scopeStoreLocations(r.Context(), q, "bt.location_id")
 	if v := strings.TrimSpace(r.URL.Query().Get("productId")); v != "" {
 		if _, err := uuid.Parse(v); err == nil {
 			q = q.Where("product_id = ?", v)
@@ -45,6 +49,9 @@ func (h *BatchesHandler) List(w http.ResponseWriter, r *http.Request) {
 			q = q.Where("source_purchase_order_id = ?", v)
 		}
 	}
+	if v := strings.TrimSpace(r.URL.Query().Get("status")); v != "" {
+		q = q.Where("status = ?", v)
+	}
 	if err := q.Scan(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -59,51 +66,28 @@ func (h *BatchesHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var b models.Batch
-	if err := h.deps.DB.NewSelect().Model(&b).Where("id = ?", id).Scan(r.Context()); err != nil {
+	if err := h.deps.DB.NewSelect().Model(&b).
+		ColumnExpr("bt.*").
+		ColumnExpr(reservedQtyExpr).
+		Where("id = ?", id).Scan(r.Context()); err != nil {
 		writeError(w, http.StatusNotFound, "not found")
 		return
 	}
 	writeJSON(w, http.StatusOK, b)
 }
 
-func (h *BatchesHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var in models.Batch
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid json")
-		return
-	}
-	if msg := validateBatch(&in); msg != "" {
-		writeError(w, http.StatusBadRequest, msg)
-		return
-	}
-	in.ID = uuid.Nil
-	if in.Ownership != models.BatchOwnershipConsignment {
-		in.Ownership = models.BatchOwnershipOwned
-	}
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
-		code, err := nextBatchCode(ctx, tx)
-		if err != nil {
-			return err
-		}
-		in.Code = code
-		_, err = tx.NewInsert().Model(&in).Returning("*").Exec(ctx)
-		return err
-	})
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	writeJSON(w, http.StatusCreated, in)
-}
-
-// Update accepts a partial-ish payload — only mutates the fields the
-// frontend store typically changes (qty_remaining, location, notes,
-// expires_at). The rest stay as snapshotted at insert.
+// Update accepts a partial-ish payload — only mutates notes, expires_at and
+// status. The rest stay as snapshotted at insert. Quantity and location
+// only change through the stock workflows (sales, adjustments, opname,
+// returns, production, Move) so every change is logged; a payload carrying
+// them is refused. Status only toggles between active and quarantined;
+// disposal goes through Dispose.
 type batchUpdateInput struct {
 	QtyRemaining *float64 `json:"qtyRemaining,omitempty"`
 	LocationID   *string  `json:"locationId,omitempty"`
 	ExpiresAt    *string  `json:"expiresAt,omitempty"`
 	Notes        *string  `json:"notes,omitempty"`
+	Status       *string  `json:"status,omitempty"`
 }
 
 func (h *BatchesHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -117,22 +101,18 @@ func (h *BatchesHandler) Update(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
-	q := h.deps.DB.NewUpdate().Table("batches").Where("id = ?", id).
-		Set("updated_at = current_timestamp")
-	any := false
 	if in.QtyRemaining != nil {
-		q = q.Set("qty_remaining = ?", *in.QtyRemaining)
-		any = true
+		writeError(w, http.StatusBadRequest,
+			"Jumlah batch hanya berubah lewat penjualan, penyesuaian stok, opname, retur atau produksi.")
+		return
 	}
-	if in.LocationID != nil && *in.LocationID != "" {
-		loc, err := uuid.Parse(*in.LocationID)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "locationId tidak valid")
-			return
-		}
-		q = q.Set("location_id = ?", loc)
-		any = true
+	if in.LocationID != nil {
+		writeError(w, http.StatusBadRequest, "Pindahkan batch lewat /batches/{id}/move.")
+		return
 	}
+	q := h.deps.DB.NewUpdate().Table("batches").Where("id = ?", id).
+		Set("updated_at = current_timestamp")
+	any := false
 	if in.ExpiresAt != nil {
 		q = q.Set("expires_at = ?", *in.ExpiresAt)
 		any = true
@@ -141,6 +121,28 @@ func (h *BatchesHandler) Update(w http.ResponseWriter, r *http.Request) {
 		q = q.Set("notes = ?", *in.Notes)
 		any = true
 	}
+	if in.Status != nil {
+		switch *in.Status {
+		case models.BatchStatusActive:
+			code, err := openRecallCode(r.Context(), h.deps.DB, id)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if code != "" {
+				writeError(w, http.StatusBadRequest,
+					fmt.Sprintf("Batch ini ditarik lewat %s; lepaskan lewat penarikan, bukan ubah status.", code))
+				return
+			}
+			q = q.Set("status = ?", *in.Status).Set("quarantined_at = NULL")
+		case models.BatchStatusQuarantined:
+			q = q.Set("status = ?", *in.Status).Set("quarantined_at = current_timestamp")
+		default:
+			writeError(w, http.StatusBadRequest, "status tidak valid")
+			return
+		}
+		any = true
+	}
 	if !any {
 		writeError(w, http.StatusBadRequest, "tidak ada field yang diubah")
 		return
@@ -164,22 +166,6 @@ func (h *BatchesHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 // ─── helpers ────────────────────────────────────────────────────────────────
 
-func validateBatch(b *models.Batch) string {
-	if b.ProductID == uuid.Nil {
-		return "productId wajib diisi"
-	}
-	if b.LocationID == uuid.Nil {
-		return "locationId wajib diisi"
-	}
-	if b.QtyReceived <= 0 {
-		return "qtyReceived harus lebih dari 0"
-	}
-	if b.QtyRemaining < 0 {
-		return "qtyRemaining tidak boleh negatif"
-	}
-	return ""
-}
-
 func nextBatchCode(ctx context.Context, tx bun.Tx) (string, error) {
 	year := time.Now().Year()
 	prefix := fmt.Sprintf("BATCH-%d-", year)
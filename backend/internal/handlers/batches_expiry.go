@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// Allocation strategies for inventory.allocationStrategy.
+const (
+	allocationFIFO = "fifo"
+	allocationFEFO = "fefo"
+)
+
+// batchPickOrder is the ORDER BY used when drawing stock out of batches.
+// FIFO unless the store opted into FEFO, which puts undated batches last so
+// perishables go out before shelf-stable lots of the same product.
+func batchPickOrder(strategy string) string {
+	if strategy == allocationFEFO {
+		return "NULLIF(expires_at, '') ASC NULLS LAST, received_at ASC, created_at ASC"
+	}
+	return "received_at ASC, created_at ASC"
+}
+
+type nearExpiryRow struct {
+	BatchID      uuid.UUID  `bun:"batch_id" json:"batchId"`
+	BatchCode    string     `bun:"batch_code" json:"batchCode"`
+	ProductID    uuid.UUID  `bun:"product_id" json:"productId"`
+	ProductName  string     `bun:"product_name" json:"productName"`
+	VariantID    *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	VariantName  string     `bun:"variant_name" json:"variantName,omitempty"`
+	CategoryID   *uuid.UUID `bun:"category_id" json:"categoryId,omitempty"`
+	LocationID   uuid.UUID  `bun:"location_id" json:"locationId"`
+	Ownership    string     `bun:"ownership" json:"ownership"`
+	Status       string     `bun:"status" json:"status"`
+	ExpiresAt    string     `bun:"expires_at" json:"expiresAt"`
+	QtyRemaining float64    `bun:"qty_remaining" json:"qtyRemaining"`
+	UnitCost     float64    `bun:"unit_cost" json:"unitCost"`
+	DaysLeft     int        `bun:"-" json:"daysLeft"`
+	HorizonDays  int        `bun:"-" json:"horizonDays"`
+	Value        float64    `bun:"-" json:"value"`
+}
+
+// NearExpiry lists batches with stock on hand that expire within their
+// category's alert horizon, plus (by default) those already expired.
+// Sorted soonest first. Filters: locationId, categoryId (sub-categories
+// included), includeExpired.
+func (h *BatchesHandler) NearExpiry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	horizons, err := categoryExpiryHorizons(ctx, h.deps.DB, settings.Inventory.NearExpiryDays)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rows := []nearExpiryRow{}
+	q := h.deps.DB.NewSelect().
+		TableExpr("batches AS bt").
+		Join("JOIN products AS p ON p.id = bt.product_id").
+		Join("LEFT JOIN product_variants AS pv ON pv.id = bt.variant_id").
+		ColumnExpr("bt.id AS batch_id, bt.code AS batch_code, bt.product_id, p.name AS product_name").
+		ColumnExpr("bt.variant_id, COALESCE(pv.name, '') AS variant_name, p.category_id").
+		ColumnExpr("bt.location_id, bt.ownership, bt.status, bt.expires_at, bt.qty_remaining, bt.unit_cost").
+		Where("bt.expires_at <> ''").
+		Where("bt.qty_remaining > 0").
+		Where("bt.status <> ?", models.BatchStatusDisposed).
+		OrderExpr("bt.expires_at ASC, bt.received_at ASC")
+	q = scopeStoreLocations(ctx, q, "bt.location_id")
+	if v := strings.TrimSpace(r.URL.Query().Get("locationId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			q = q.Where("bt.location_id = ?", v)
+		}
+	}
+	// A category filter covers its sub-categories too.
+	if v := strings.TrimSpace(r.URL.Query().Get("categoryId")); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			q = q.Where("p.category_id IN ("+categorySubtreeSQL+")", id)
+		}
+	}
+	if err := q.Scan(ctx, &rows); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	includeExpired := r.URL.Query().Get("includeExpired") != "false"
+	today := startOfDay(time.Now())
+
+	out := make([]nearExpiryRow, 0, len(rows))
+	for _, row := range rows {
+		exp, err := time.ParseInLocation("2006-01-02", row.ExpiresAt, time.Local)
+		if err != nil {
+			continue
+		}
+		row.DaysLeft = int(exp.Sub(today).Hours() / 24)
+		row.HorizonDays = settings.Inventory.NearExpiryDays
+		if row.CategoryID != nil {
+			if d, ok := horizons[*row.CategoryID]; ok {
+				row.HorizonDays = d
+			}
+		}
+		if row.DaysLeft > row.HorizonDays || (row.DaysLeft < 0 && !includeExpired) {
+			continue
+		}
+		row.Value = row.QtyRemaining * row.UnitCost
+		out = append(out, row)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DaysLeft < out[j].DaysLeft })
+	writeJSON(w, http.StatusOK, out)
+}
+
+// categoryExpiryHorizons resolves every category's effective alert horizon,
+// walking up the parent chain until a category sets its own value.
+func categoryExpiryHorizons(ctx context.Context, db bun.IDB, fallback int) (map[uuid.UUID]int, error) {
+	var cats []models.Category
+	if err := db.NewSelect().Model(&cats).
+		Column("id", "parent_id", "expiry_alert_days").Scan(ctx); err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Category, len(cats))
+	for i := range cats {
+		byID[cats[i].ID] = &cats[i]
+	}
+	out := make(map[uuid.UUID]int, len(cats))
+	for _, c := range cats {
+		days := fallback
+		cur := byID[c.ID]
+		for hops := 0; cur != nil && hops < 32; hops++ {
+			if cur.ExpiryAlertDays != nil {
+				days = *cur.ExpiryAlertDays
+				break
+			}
+			if cur.ParentID == nil {
+				break
+			}
+			cur = byID[*cur.ParentID]
+		}
+		out[c.ID] = days
+	}
+	return out, nil
+}
+
+// QuarantineExpiredBatches flags every active batch whose expiry date is
+// before asOf's calendar day. Quarantined stock stays on hand (so opname
+// still finds it) but is no longer allocated. Run daily by the background
+// job runner; also exposed as an admin action.
+func QuarantineExpiredBatches(ctx context.Context, db bun.IDB, asOf time.Time) (int, error) {
+	res, err := db.NewUpdate().Table("batches").
+		Set("status = ?", models.BatchStatusQuarantined).
+		Set("quarantined_at = current_timestamp").
+		Set("updated_at = current_timestamp").
+		Where("status = ?", models.BatchStatusActive).
+		Where("expires_at <> ''").
+		Where("expires_at < ?", asOf.Format("2006-01-02")).
+		Where("qty_remaining > 0").
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func (h *BatchesHandler) QuarantineExpired(w http.ResponseWriter, r *http.Request) {
+	n, err := QuarantineExpiredBatches(r.Context(), h.deps.DB, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"quarantined": n})
+}
+
+type batchDisposeInput struct {
+	// Base units to write off; nil disposes everything remaining.
+	Qty      *float64 `json:"qty,omitempty"`
+	Reason   string   `json:"reason"`
+	ImageURL string   `json:"imageUrl"`
+	Notes    string   `json:"notes"`
+}
+
+// Dispose writes stock off a batch (expired, damaged, …) with a `write-off`
+// movement. A batch drained to zero is marked disposed. The write-off is
+// recorded as a stock adjustment, so above the approval threshold it waits
+// in the approval queue (HTTP 202) instead of touching stock.
+func (h *BatchesHandler) Dispose(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in batchDisposeInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	reason := strings.TrimSpace(in.Reason)
+	if reason == "" {
+		reason = models.AdjustmentReasonExpired
+	}
+	if !slices.Contains(adjustmentReasonsOut, reason) {
+		writeError(w, http.StatusBadRequest, "Alasan pemusnahan tidak valid.")
+		return
+	}
+
+	a := models.StockAdjustment{
+		Kind:       models.AdjustmentKindWriteOff,
+		BatchID:    &id,
+		ReasonCode: reason,
+		ImageURL:   strings.TrimSpace(in.ImageURL),
+		Notes:      strings.TrimSpace(in.Notes),
+	}
+	submitStockAdjustment(w, r, h.deps.DB, &a, func(ctx context.Context, tx bun.Tx) error {
+		var b models.Batch
+		if err := tx.NewSelect().Model(&b).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if b.Status == models.BatchStatusDisposed || b.QtyRemaining <= 0 {
+			return errBadInput("batch sudah tidak punya stok")
+		}
+		qty := b.QtyRemaining
+		if in.Qty != nil {
+			qty = *in.Qty
+		}
+		if qty <= 0 {
+			return errBadInput("qty harus lebih dari 0")
+		}
+		if qty > b.QtyRemaining+1e-9 {
+			return errBadInput("qty melebihi sisa stok batch")
+		}
+		if a.Notes == "" {
+			a.Notes = "Pemusnahan batch " + b.Code
+		}
+		a.ProductID, a.VariantID, a.LocationID = b.ProductID, b.VariantID, b.LocationID
+		a.QtyDelta = -qty
+		a.UnitCost = b.UnitCost
+		a.Value = qty * b.UnitCost
+		return nil
+	})
+}
+
+// writeOffBatch takes qty off an already-locked batch, marks it disposed
+// when drained and logs the write-off movement.
+func writeOffBatch(
+	ctx context.Context, tx bun.Tx, b *models.Batch, qty float64,
+	reason, imageURL, notes, performedBy string, ref models.StockMovementReference,
+) (*models.StockMovement, error) {
+	if b.Status == models.BatchStatusDisposed || qty > b.QtyRemaining+1e-9 {
+		return nil, errBadInput(fmt.Sprintf("Sisa stok batch %s tidak cukup untuk dimusnahkan.", b.Code))
+	}
+	b.QtyRemaining -= qty
+	if b.QtyRemaining < 1e-9 {
+		b.QtyRemaining = 0
+		b.Status = models.BatchStatusDisposed
+	}
+	if _, err := tx.NewUpdate().Table("batches").Where("id = ?", b.ID).
+		Set("qty_remaining = ?", b.QtyRemaining).
+		Set("status = ?", b.Status).
+		Set("updated_at = current_timestamp").
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+	mv := models.StockMovement{
+		Kind:        models.MovementKindWriteOff,
+		ProductID:   &b.ProductID,
+		VariantID:   b.VariantID,
+		LocationID:  &b.LocationID,
+		BatchID:     &b.ID,
+		QtyDelta:    -qty,
+		QtyAfter:    b.QtyRemaining,
+		UnitCost:    floatPtr(b.UnitCost),
+		Reference:   ref,
+		Reason:      &reason,
+		ImageURL:    imageURL,
+		PerformedBy: performedBy,
+		Notes:       notes,
+	}
+	if err := logMovement(ctx, tx, &mv); err != nil {
+		return nil, err
+	}
+	return &mv, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type batchMoveInput struct {
+	ToLocationID string  `json:"toLocationId"`
+	Qty          float64 `json:"qty"`
+	Notes        string  `json:"notes"`
+	// Shared by every batch moved in one bulk transfer; a fresh id is used
+	// when empty.
+	TransferGroupID string `json:"transferGroupId"`
+}
+
+// Move transfers qty of one batch to another location. Moving the whole
+// remainder relocates the batch in place (move-relocate); a partial move
+// splits off a sibling batch at the destination that keeps cost, expiry,
+// ownership and source references (move-out + move-in under one transfer
+// reference, which batch tracing follows). Quantity held by reservations
+// stays on the source batch, and batches with serials in stock can't be
+// split. Returns the batch now at the destination.
+func (h *BatchesHandler) Move(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in batchMoveInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	toLoc, err := uuid.Parse(in.ToLocationID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "toLocationId tidak valid")
+		return
+	}
+	if in.Qty <= 0 {
+		writeError(w, http.StatusBadRequest, "Jumlah harus lebih dari 0.")
+		return
+	}
+	transferID := strings.TrimSpace(in.TransferGroupID)
+	if transferID == "" {
+		transferID = uuid.NewString()
+	}
+	notes := strings.TrimSpace(in.Notes)
+	performedBy := actorName(r.Context(), h.deps.DB)
+
+	var out models.Batch
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var src models.Batch
+		if err := tx.NewSelect().Model(&src).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if src.LocationID == toLoc {
+			return errBadInput("Lokasi sumber dan tujuan sama.")
+		}
+		if in.Qty > src.QtyRemaining+1e-9 {
+			return errBadInput(fmt.Sprintf("Sisa di batch hanya %.4g.", src.QtyRemaining))
+		}
+		var fromName, toName string
+		if err := tx.NewSelect().Table("locations").Column("name").
+			Where("id = ?", src.LocationID).Scan(ctx, &fromName); err != nil {
+			return err
+		}
+		if err := tx.NewSelect().Table("locations").Column("name").
+			Where("id = ?", toLoc).Scan(ctx, &toName); err != nil {
+			return errBadInput("Lokasi tujuan tidak ditemukan.")
+		}
+		ref := models.StockMovementReference{Kind: models.MovementRefTransfer, ID: transferID}
+
+		if in.Qty >= src.QtyRemaining-1e-9 {
+			if _, err := tx.NewUpdate().Table("batches").
+				Where("id = ?", src.ID).
+				Set("location_id = ?", toLoc).
+				Set("updated_at = current_timestamp").
+				Exec(ctx); err != nil {
+				return err
+			}
+			src.LocationID = toLoc
+			note := notes
+			if note == "" {
+				note = fmt.Sprintf("Relokasi penuh · %s → %s · %s", fromName, toName, src.Code)
+			}
+			out = src
+			return logMovement(ctx, tx, &models.StockMovement{
+				Kind:        models.MovementKindMoveRelocate,
+				ProductID:   &src.ProductID,
+				VariantID:   src.VariantID,
+				LocationID:  &src.LocationID,
+				BatchID:     &src.ID,
+				QtyDelta:    0,
+				QtyAfter:    src.QtyRemaining,
+				UnitCost:    floatPtr(src.UnitCost),
+				Reference:   ref,
+				PerformedBy: performedBy,
+				Notes:       note,
+			})
+		}
+
+		// Serials point at their batch and a split can't tell which units
+		// leave, so serialized stock only moves as a whole batch.
+		serialized, err := tx.NewSelect().Table("product_serials").
+			Where("batch_id = ?", src.ID).
+			Where("status IN (?)", bun.In([]string{models.SerialStatusInStock, models.SerialStatusReturned})).
+			Exists(ctx)
+		if err != nil {
+			return err
+		}
+		if serialized {
+			return errBadInput("Batch bernomor seri hanya bisa dipindah seluruhnya.")
+		}
+
+		var held float64
+		if err := tx.NewSelect().Table("stock_reservations").
+			ColumnExpr("COALESCE(SUM(qty), 0)").
+			Where("batch_id = ?", src.ID).
+			Where("expires_at > now()").
+			Scan(ctx, &held); err != nil {
+			return err
+		}
+		if in.Qty > src.QtyRemaining-held+1e-9 {
+			return errBadInput(fmt.Sprintf("Hanya %.4g yang bisa dipindah; %.4g sedang ditahan reservasi.",
+				src.QtyRemaining-held, held))
+		}
+
+		src.QtyRemaining -= in.Qty
+		if _, err := tx.NewUpdate().Table("batches").
+			Where("id = ?", src.ID).
+			Set("qty_remaining = ?", src.QtyRemaining).
+			Set("updated_at = current_timestamp").
+			Exec(ctx); err != nil {
+			return err
+		}
+		outNote := notes
+		if outNote == "" {
+			outNote = fmt.Sprintf("Pindah ke %s · %s", toName, src.Code)
+		}
+		if err := logMovement(ctx, tx, &models.StockMovement{
+			Kind:        models.MovementKindMoveOut,
+			ProductID:   &src.ProductID,
+			VariantID:   src.VariantID,
+			LocationID:  &src.LocationID,
+			BatchID:     &src.ID,
+			QtyDelta:    -in.Qty,
+			QtyAfter:    src.QtyRemaining,
+			UnitCost:    floatPtr(src.UnitCost),
+			Reference:   ref,
+			PerformedBy: performedBy,
+			Notes:       outNote,
+		}); err != nil {
+			return err
+		}
+
+		code, err := nextBatchCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		sibling := models.Batch{
+			Code:                      code,
+			ProductID:                 src.ProductID,
+			VariantID:                 src.VariantID,
+			Ownership:                 src.Ownership,
+			SupplierID:                src.SupplierID,
+			SourcePurchaseOrderID:     src.SourcePurchaseOrderID,
+			SourcePurchaseOrderLineID: src.SourcePurchaseOrderLineID,
+			UnitCost:                  src.UnitCost,
+			QtyReceived:               in.Qty,
+			QtyRemaining:              in.Qty,
+			ReceivedAt:                src.ReceivedAt,
+			ExpiresAt:                 src.ExpiresAt,
+			LocationID:                toLoc,
+			Status:                    src.Status,
+			QuarantinedAt:             src.QuarantinedAt,
+			ReceiptID:                 src.ReceiptID,
+			LandedUnitCost:            src.LandedUnitCost,
+		}
+		sibling.Notes = notes
+		if sibling.Notes == "" {
+			sibling.Notes = "Dipindahkan dari " + src.Code + "."
+		}
+		if _, err := tx.NewInsert().Model(&sibling).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		inNote := notes
+		if inNote == "" {
+			inNote = fmt.Sprintf("Pindah dari %s · sibling %s", fromName, sibling.Code)
+		}
+		out = sibling
+		return logMovement(ctx, tx, &models.StockMovement{
+			Kind:        models.MovementKindMoveIn,
+			ProductID:   &sibling.ProductID,
+			VariantID:   sibling.VariantID,
+			LocationID:  &sibling.LocationID,
+			BatchID:     &sibling.ID,
+			QtyDelta:    in.Qty,
+			QtyAfter:    sibling.QtyRemaining,
+			UnitCost:    floatPtr(sibling.UnitCost),
+			Reference:   ref,
+			PerformedBy: performedBy,
+			Notes:       inNote,
+		})
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,592 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// ─── Traceability + recall ──────────────────────────────────────────────────
+// A batch's lineage is a graph with two kinds of edges: production (a run
+// consumed batch X and produced batch Y) and transfer (a partial move split
+// batch X into sibling Y at another location, matched by the move-out /
+// move-in pair sharing a transfer reference). Sales hang off batches via
+// order_lines.batch_allocations.
+
+// traceMaxDepth bounds the walk; real lineages are a few levels deep.
+const traceMaxDepth = 20
+
+type traceBatch struct {
+	ID                    uuid.UUID  `bun:"id" json:"id"`
+	Code                  string     `bun:"code" json:"code"`
+	ProductID             uuid.UUID  `bun:"product_id" json:"productId"`
+	ProductName           string     `bun:"product_name" json:"productName"`
+	VariantID             *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	LocationID            uuid.UUID  `bun:"location_id" json:"locationId"`
+	SupplierID            *uuid.UUID `bun:"supplier_id" json:"supplierId,omitempty"`
+	SourcePurchaseOrderID *uuid.UUID `bun:"source_purchase_order_id" json:"sourcePurchaseOrderId,omitempty"`
+	QtyReceived           float64    `bun:"qty_received" json:"qtyReceived"`
+	QtyRemaining          float64    `bun:"qty_remaining" json:"qtyRemaining"`
+	ReceivedAt            string     `bun:"received_at" json:"receivedAt"`
+	ExpiresAt             string     `bun:"expires_at" json:"expiresAt"`
+	Status                string     `bun:"status" json:"status"`
+
+	// Filled by the walk: how far from the origin, and the edge that led
+	// here ("production" or "transfer") from which batch.
+	Depth     int        `bun:"-" json:"depth"`
+	Via       string     `bun:"-" json:"via,omitempty"`
+	FromBatch *uuid.UUID `bun:"-" json:"fromBatchId,omitempty"`
+}
+
+type traceRun struct {
+	ID              uuid.UUID  `bun:"id" json:"id"`
+	Code            string     `bun:"code" json:"code"`
+	ProductID       uuid.UUID  `bun:"product_id" json:"productId"`
+	ProducedQty     float64    `bun:"produced_qty" json:"producedQty"`
+	ProducedBatchID *uuid.UUID `bun:"produced_batch_id" json:"producedBatchId,omitempty"`
+	CreatedAt       time.Time  `bun:"created_at" json:"createdAt"`
+	// "forward" when it consumed a traced batch, "backward" when it
+	// produced one.
+	Direction string `bun:"-" json:"direction"`
+}
+
+type traceSale struct {
+	OrderID      uuid.UUID  `bun:"order_id" json:"orderId"`
+	OrderCode    string     `bun:"order_code" json:"orderCode"`
+	OrderedAt    time.Time  `bun:"ordered_at" json:"orderedAt"`
+	OrderStatus  string     `bun:"order_status" json:"orderStatus"`
+	StoreID      uuid.UUID  `bun:"store_id" json:"storeId"`
+	CustomerID   *uuid.UUID `bun:"customer_id" json:"customerId,omitempty"`
+	CustomerName string     `bun:"customer_name" json:"customerName,omitempty"`
+	BatchID      uuid.UUID  `bun:"batch_id" json:"batchId"`
+	ProductName  string     `bun:"product_name" json:"productName"`
+	VariantName  string     `bun:"variant_name" json:"variantName,omitempty"`
+	Qty          float64    `bun:"qty" json:"qty"`
+}
+
+type traceCustomer struct {
+	ID     uuid.UUID `bun:"id" json:"id"`
+	Name   string    `bun:"name" json:"name"`
+	Phone  string    `bun:"phone" json:"phone"`
+	Email  string    `bun:"email" json:"email"`
+	Orders int       `bun:"-" json:"orders"`
+	Qty    float64   `bun:"-" json:"qty"`
+}
+
+type batchTrace struct {
+	Batch          traceBatch      `json:"batch"`
+	Upstream       []traceBatch    `json:"upstream"`
+	Downstream     []traceBatch    `json:"downstream"`
+	ProductionRuns []traceRun      `json:"productionRuns"`
+	Sales          []traceSale     `json:"sales"`
+	Customers      []traceCustomer `json:"customers"`
+	// Sold lines with no customer on the order (walk-in sales).
+	AnonymousSales int `json:"anonymousSales"`
+	// Downstream stock (origin included) still on hand, in base units.
+	QtyOnHand float64 `json:"qtyOnHand"`
+}
+
+// Trace walks a batch's lineage. Query: direction=forward|backward|both
+// (default both). Forward follows consumption into production output and
+// transfer splits, then collects the sales and customers of the origin and
+// everything downstream; backward finds the batches it was made or split
+// from, up to the supplier receipts.
+func (h *BatchesHandler) Trace(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	direction := strings.TrimSpace(r.URL.Query().Get("direction"))
+	if direction == "" {
+		direction = "both"
+	}
+	if direction != "forward" && direction != "backward" && direction != "both" {
+		writeError(w, http.StatusBadRequest, "direction harus forward, backward atau both")
+		return
+	}
+	trace, err := traceBatchLineage(r.Context(), h.deps.DB, id,
+		direction != "backward", direction != "forward")
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, trace)
+}
+
+func traceBatchLineage(ctx context.Context, db bun.IDB, id uuid.UUID, forward, backward bool) (*batchTrace, error) {
+	origin, err := loadTraceBatches(ctx, db, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(origin) == 0 {
+		return nil, errNotFound
+	}
+	out := &batchTrace{
+		Batch:          origin[0],
+		Upstream:       []traceBatch{},
+		Downstream:     []traceBatch{},
+		ProductionRuns: []traceRun{},
+		Sales:          []traceSale{},
+		Customers:      []traceCustomer{},
+	}
+	runsSeen := map[uuid.UUID]bool{}
+	if forward {
+		down, runs, err := walkLineage(ctx, db, id, true)
+		if err != nil {
+			return nil, err
+		}
+		out.Downstream = down
+		for _, r := range runs {
+			if !runsSeen[r.ID] {
+				runsSeen[r.ID] = true
+				out.ProductionRuns = append(out.ProductionRuns, r)
+			}
+		}
+		ids := []uuid.UUID{id}
+		if out.Batch.Status != models.BatchStatusDisposed {
+			out.QtyOnHand = out.Batch.QtyRemaining
+		}
+		for _, b := range down {
+			ids = append(ids, b.ID)
+			if b.Status != models.BatchStatusDisposed {
+				out.QtyOnHand += b.QtyRemaining
+			}
+		}
+		if err := collectTraceSales(ctx, db, ids, out); err != nil {
+			return nil, err
+		}
+	}
+	if backward {
+		up, runs, err := walkLineage(ctx, db, id, false)
+		if err != nil {
+			return nil, err
+		}
+		out.Upstream = up
+		for _, r := range runs {
+			if !runsSeen[r.ID] {
+				runsSeen[r.ID] = true
+				out.ProductionRuns = append(out.ProductionRuns, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+func loadTraceBatches(ctx context.Context, db bun.IDB, ids []uuid.UUID) ([]traceBatch, error) {
+	out := []traceBatch{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	err := db.NewSelect().TableExpr("batches AS bt").
+		Join("JOIN products AS p ON p.id = bt.product_id").
+		ColumnExpr("bt.id, bt.code, bt.product_id, p.name AS product_name, bt.variant_id").
+		ColumnExpr("bt.location_id, bt.supplier_id, bt.source_purchase_order_id").
+		ColumnExpr("bt.qty_received, bt.qty_remaining, bt.received_at, bt.expires_at, bt.status").
+		Where("bt.id IN (?)", bun.In(ids)).
+		Scan(ctx, &out)
+	return out, err
+}
+
+// lineageEdge links two batches; From is always the side nearer the origin.
+type lineageEdge struct {
+	From uuid.UUID `bun:"from_id"`
+	To   uuid.UUID `bun:"to_id"`
+	Via  string    `bun:"via"`
+}
+
+// walkLineage does a breadth-first walk from the origin, one query pair per
+// level, and returns the reached batches (origin excluded) plus the
+// production runs crossed on the way.
+func walkLineage(ctx context.Context, db bun.IDB, origin uuid.UUID, forward bool) ([]traceBatch, []traceRun, error) {
+	seen := map[uuid.UUID]bool{origin: true}
+	frontier := []uuid.UUID{origin}
+	reached := map[uuid.UUID]lineageEdge{}
+	depth := map[uuid.UUID]int{}
+	var order []uuid.UUID
+	var runs []traceRun
+
+	for level := 1; level <= traceMaxDepth && len(frontier) > 0; level++ {
+		var levelRuns []traceRun
+		rq := db.NewSelect().TableExpr("production_runs AS pr").
+			ColumnExpr("DISTINCT pr.id, pr.code, pr.product_id, pr.produced_qty, pr.produced_batch_id, pr.created_at")
+		if forward {
+			rq = rq.Join("JOIN production_run_consumptions AS prc ON prc.production_run_id = pr.id").
+				Where("prc.batch_id IN (?)", bun.In(frontier))
+		} else {
+			rq = rq.Where("pr.produced_batch_id IN (?)", bun.In(frontier))
+		}
+		if err := rq.Scan(ctx, &levelRuns); err != nil {
+			return nil, nil, err
+		}
+		for i := range levelRuns {
+			levelRuns[i].Direction = "backward"
+			if forward {
+				levelRuns[i].Direction = "forward"
+			}
+		}
+		runs = append(runs, levelRuns...)
+
+		var edges []lineageEdge
+		var pq *bun.SelectQuery
+		if forward {
+			pq = db.NewSelect().TableExpr("production_run_consumptions AS prc").
+				Join("JOIN production_runs AS pr ON pr.id = prc.production_run_id").
+				ColumnExpr("prc.batch_id AS from_id, pr.produced_batch_id AS to_id, 'production' AS via").
+				Where("prc.batch_id IN (?)", bun.In(frontier)).
+				Where("pr.produced_batch_id IS NOT NULL")
+		} else {
+			pq = db.NewSelect().TableExpr("production_run_consumptions AS prc").
+				Join("JOIN production_runs AS pr ON pr.id = prc.production_run_id").
+				ColumnExpr("pr.produced_batch_id AS from_id, prc.batch_id AS to_id, 'production' AS via").
+				Where("pr.produced_batch_id IN (?)", bun.In(frontier)).
+				Where("prc.batch_id IS NOT NULL")
+		}
+		if err := pq.Scan(ctx, &edges); err != nil {
+			return nil, nil, err
+		}
+
+		// Transfer splits: the move-out on the source and the move-in on the
+		// sibling share the transfer reference, product and quantity.
+		var moves []lineageEdge
+		tq := db.NewSelect().TableExpr("stock_movements AS mo").
+			Join(`JOIN stock_movements AS mi ON mi.kind = ?
+				AND mi.reference->>'kind' = ? AND mi.reference->>'id' = mo.reference->>'id'
+				AND mi.product_id = mo.product_id
+				AND mi.variant_id IS NOT DISTINCT FROM mo.variant_id
+				AND mi.qty_delta = -mo.qty_delta
+				AND mi.batch_id IS NOT NULL`,
+				models.MovementKindMoveIn, models.MovementRefTransfer).
+			Where("mo.kind = ?", models.MovementKindMoveOut).
+			Where("mo.reference->>'kind' = ?", models.MovementRefTransfer)
+		if forward {
+			tq = tq.ColumnExpr("DISTINCT mo.batch_id AS from_id, mi.batch_id AS to_id, 'transfer' AS via").
+				Where("mo.batch_id IN (?)", bun.In(frontier))
+		} else {
+			tq = tq.ColumnExpr("DISTINCT mi.batch_id AS from_id, mo.batch_id AS to_id, 'transfer' AS via").
+				Where("mi.batch_id IN (?)", bun.In(frontier)).
+				Where("mo.batch_id IS NOT NULL")
+		}
+		if err := tq.Scan(ctx, &moves); err != nil {
+			return nil, nil, err
+		}
+		edges = append(edges, moves...)
+
+		var next []uuid.UUID
+		for _, e := range edges {
+			if seen[e.To] {
+				continue
+			}
+			seen[e.To] = true
+			reached[e.To] = e
+			depth[e.To] = level
+			order = append(order, e.To)
+			next = append(next, e.To)
+		}
+		frontier = next
+	}
+
+	batches, err := loadTraceBatches(ctx, db, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uuid.UUID]traceBatch, len(batches))
+	for _, b := range batches {
+		byID[b.ID] = b
+	}
+	out := make([]traceBatch, 0, len(order))
+	for _, id := range order {
+		b, ok := byID[id]
+		if !ok {
+			continue
+		}
+		e := reached[id]
+		from := e.From
+		b.Depth, b.Via, b.FromBatch = depth[id], e.Via, &from
+		out = append(out, b)
+	}
+	return out, runs, nil
+}
+
+// collectTraceSales lists the sale allocations that drew from the batches
+// and rolls them up per customer. Cancelled orders are listed but don't
+// count toward the customers.
+func collectTraceSales(ctx context.Context, db bun.IDB, ids []uuid.UUID, out *batchTrace) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	if err := db.NewSelect().TableExpr("order_lines AS ol").
+		Join("CROSS JOIN LATERAL jsonb_array_elements(ol.batch_allocations) AS a").
+		Join("JOIN orders AS o ON o.id = ol.order_id").
+		Join("LEFT JOIN customers AS c ON c.id = o.customer_id").
+		ColumnExpr("o.id AS order_id, o.code AS order_code, o.created_at AS ordered_at, o.status AS order_status").
+		ColumnExpr("o.store_id, o.customer_id, COALESCE(c.name, '') AS customer_name").
+		ColumnExpr("(a->>'batchId')::uuid AS batch_id, ol.product_name, ol.variant_name").
+		ColumnExpr("(a->>'qtyTaken')::numeric AS qty").
+		Where("a->>'batchId' IN (?)", bun.In(keys)).
+		OrderExpr("o.created_at ASC").
+		Scan(ctx, &out.Sales); err != nil {
+		return err
+	}
+	if out.Sales == nil {
+		out.Sales = []traceSale{}
+	}
+
+	type agg struct {
+		orders map[uuid.UUID]bool
+		qty    float64
+	}
+	perCustomer := map[uuid.UUID]*agg{}
+	var customerIDs []uuid.UUID
+	for _, s := range out.Sales {
+		if s.OrderStatus == models.OrderStatusCancelled {
+			continue
+		}
+		if s.CustomerID == nil {
+			out.AnonymousSales++
+			continue
+		}
+		a := perCustomer[*s.CustomerID]
+		if a == nil {
+			a = &agg{orders: map[uuid.UUID]bool{}}
+			perCustomer[*s.CustomerID] = a
+			customerIDs = append(customerIDs, *s.CustomerID)
+		}
+		a.orders[s.OrderID] = true
+		a.qty += s.Qty
+	}
+	if len(customerIDs) == 0 {
+		return nil
+	}
+	if err := db.NewSelect().Table("customers").
+		Column("id", "name", "phone", "email").
+		Where("id IN (?)", bun.In(customerIDs)).
+		Order("name ASC").
+		Scan(ctx, &out.Customers); err != nil {
+		return err
+	}
+	for i := range out.Customers {
+		a := perCustomer[out.Customers[i].ID]
+		out.Customers[i].Orders = len(a.orders)
+		out.Customers[i].Qty = a.qty
+	}
+	return nil
+}
+
+type batchRecallInput struct {
+	Reason string `json:"reason"`
+}
+
+type batchRecallResult struct {
+	Recall      models.BatchRecall `json:"recall"`
+	Quarantined []traceBatch       `json:"quarantined"`
+	Trace       *batchTrace        `json:"trace"`
+}
+
+// Recall quarantines the batch and all downstream stock still on hand,
+// drops any reservations on it, and records the recall. Quarantined stock
+// stays counted but is never allocated to a sale; release (ReleaseRecall)
+// or dispose it batch by batch afterwards.
+// Returns the forward trace so the affected customers can be contacted.
+func (h *BatchesHandler) Recall(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in batchRecallInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	reason := strings.TrimSpace(in.Reason)
+	if reason == "" {
+		writeError(w, http.StatusBadRequest, "alasan penarikan wajib diisi")
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
+
+	var result batchRecallResult
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		trace, err := traceBatchLineage(ctx, tx, id, true, false)
+		if err != nil {
+			return err
+		}
+		candidates := append([]traceBatch{trace.Batch}, trace.Downstream...)
+		ids := make([]uuid.UUID, len(candidates))
+		for i, b := range candidates {
+			ids[i] = b.ID
+		}
+		var hit []uuid.UUID
+		if _, err := tx.NewUpdate().Table("batches").
+			Set("status = ?", models.BatchStatusQuarantined).
+			Set("quarantined_at = current_timestamp").
+			Set("updated_at = current_timestamp").
+			Where("id IN (?)", bun.In(ids)).
+			Where("status = ?", models.BatchStatusActive).
+			Where("qty_remaining > 0").
+			Returning("id").
+			Exec(ctx, &hit); err != nil {
+			return err
+		}
+		if len(hit) > 0 {
+			// Held stock on a recalled batch can't be sold; drop the holds
+			// so carts and drafts re-reserve from clean stock.
+			if _, err := tx.NewDelete().Model((*models.StockReservation)(nil)).
+				Where("batch_id IN (?)", bun.In(hit)).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		quarantined, err := loadTraceBatches(ctx, tx, hit)
+		if err != nil {
+			return err
+		}
+		code, err := nextRecallCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		rc := models.BatchRecall{
+			Code:        code,
+			BatchID:     id,
+			Reason:      reason,
+			BatchIDs:    hit,
+			QtyOnHand:   trace.QtyOnHand,
+			PerformedBy: performedBy,
+		}
+		if rc.BatchIDs == nil {
+			rc.BatchIDs = []uuid.UUID{}
+		}
+		rc.ReleasedBatchIDs = []uuid.UUID{}
+		if _, err := tx.NewInsert().Model(&rc).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		// Reflect the new statuses in the returned trace.
+		now := map[uuid.UUID]bool{}
+		for _, q := range hit {
+			now[q] = true
+		}
+		if now[trace.Batch.ID] {
+			trace.Batch.Status = models.BatchStatusQuarantined
+		}
+		for i := range trace.Downstream {
+			if now[trace.Downstream[i].ID] {
+				trace.Downstream[i].Status = models.BatchStatusQuarantined
+			}
+		}
+		result = batchRecallResult{Recall: rc, Quarantined: quarantined, Trace: trace}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ReleaseRecall clears a recalled batch and puts it back on sale. It is the
+// only way out of a recall quarantine; PATCH status refuses while a recall
+// that quarantined the batch is still open.
+func (h *BatchesHandler) ReleaseRecall(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var b models.Batch
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(&b).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if b.Status == models.BatchStatusDisposed {
+			return errBadInput("Batch sudah dimusnahkan.")
+		}
+		res, err := tx.NewUpdate().Table("batch_recalls").
+			Set("released_batch_ids = array_append(released_batch_ids, ?)", id).
+			Where("? = ANY(batch_ids)", id).
+			Where("NOT (? = ANY(released_batch_ids))", id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return errBadInput("Batch ini tidak sedang ditarik.")
+		}
+		if b.Status != models.BatchStatusQuarantined {
+			return nil
+		}
+		b.Status = models.BatchStatusActive
+		b.QuarantinedAt = nil
+		_, err = tx.NewUpdate().Table("batches").
+			Where("id = ?", id).
+			Set("status = ?", b.Status).
+			Set("quarantined_at = NULL").
+			Set("updated_at = current_timestamp").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// openRecallCode returns the code of a recall that quarantined the batch and
+// hasn't released it yet, or "" when there is none.
+func openRecallCode(ctx context.Context, db bun.IDB, batchID uuid.UUID) (string, error) {
+	var codes []string
+	if err := db.NewSelect().Table("batch_recalls").Column("code").
+		Where("? = ANY(batch_ids)", batchID).
+		Where("NOT (? = ANY(released_batch_ids))", batchID).
+		OrderExpr("created_at DESC").
+		Limit(1).
+		Scan(ctx, &codes); err != nil {
+		return "", err
+	}
+	if len(codes) == 0 {
+		return "", nil
+	}
+	return codes[0], nil
+}
+
+// Recalls lists the recalls raised against a batch.
+func (h *BatchesHandler) Recalls(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	items := []models.BatchRecall{}
+	if err := h.deps.DB.NewSelect().Model(&items).
+		Where("brc.batch_id = ? OR ? = ANY(brc.batch_ids)", id, id).
+		Order("brc.created_at DESC").
+		Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func nextRecallCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("RCL-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("batch_recalls").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
@@ -30,6 +30,8 @@ type categoryInput struct {
 	Color       string  `json:"color"`
 	TaxRateID   *string `json:"taxRateId,omitempty"`
 	ParentID    *string `json:"parentId,omitempty"`
+	// Near-expiry horizon in days; null inherits from the parent category.
+	ExpiryAlertDays *int `json:"expiryAlertDays,omitempty"`
 }
 
 func (h *CategoriesHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +79,8 @@ func (h *CategoriesHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Color:       colorOrDefault(in.Color),
 		TaxRateID:   nullableString(in.TaxRateID),
 		ParentID:    parsedParent,
+
+		ExpiryAlertDays: in.ExpiryAlertDays,
 	}
 	if _, err := h.deps.DB.NewInsert().Model(c).Returning("*").Exec(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -128,6 +132,7 @@ func (h *CategoriesHandler) Update(w http.ResponseWriter, r *http.Request) {
 		Set("color = ?", colorOrDefault(in.Color)).
 		Set("tax_rate_id = ?", nullableString(in.TaxRateID)).
 		Set("parent_id = ?", parsedParent).
+		Set("expiry_alert_days = ?", in.ExpiryAlertDays).
 		Set("updated_at = current_timestamp").
 		Exec(r.Context())
 	if err != nil {
@@ -210,6 +215,9 @@ func validateCategoryInput(in *categoryInput) string {
 	if strings.TrimSpace(in.Name) == "" {
 		return "nama kategori wajib diisi"
 	}
+	if in.ExpiryAlertDays != nil && *in.ExpiryAlertDays < 0 {
+		return "batas peringatan kedaluwarsa tidak boleh negatif"
+	}
 	return ""
 }
 
@@ -272,3 +280,11 @@ func wouldCreateCycle(
 	}
 	return false, nil
 }
+
+// categorySubtreeSQL selects the ids of a category (the single ? argument)
+// and every category below it.
+const categorySubtreeSQL = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION
+	SELECT c.id FROM categories AS c JOIN subtree ON c.parent_id = subtree.id
+) SELECT id FROM subtree`
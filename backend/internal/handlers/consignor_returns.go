@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/documents"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type ConsignorReturnsHandler struct {
+	deps Deps
+}
+
+func NewConsignorReturnsHandler(deps Deps) *ConsignorReturnsHandler {
+	return &ConsignorReturnsHandler{deps: deps}
+}
+
+// List filters: supplierId, start, end (inclusive returnedAt dates).
+func (h *ConsignorReturnsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := []models.ConsignorReturn{}
+	sel := h.deps.DB.NewSelect().Model(&items).
+		Relation("Lines", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("crl.position ASC")
+		}).
+		Order("cr.returned_at DESC", "cr.created_at DESC")
+	sel = scopeStore(r.Context(), sel, "cr.store_id")
+	if v := strings.TrimSpace(q.Get("supplierId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("cr.supplier_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("start")); v != "" {
+		sel = sel.Where("cr.returned_at >= ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("end")); v != "" {
+		sel = sel.Where("cr.returned_at <= ?", v)
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *ConsignorReturnsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ret, err := loadConsignorReturn(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+type consignorReturnLineInput struct {
+	BatchID string  `json:"batchId"`
+	Qty     float64 `json:"qty"`
+	Reason  string  `json:"reason"`
+}
+
+type consignorReturnInput struct {
+	SupplierID   string                     `json:"supplierId"`
+	ReturnedAt   string                     `json:"returnedAt"`
+	HandedOverBy string                     `json:"handedOverBy"`
+	ReceivedBy   string                     `json:"receivedBy"`
+	Notes        string                     `json:"notes"`
+	Lines        []consignorReturnLineInput `json:"lines"`
+}
+
+// Create posts a return in one transaction: every batch is locked, checked
+// to be the supplier's consignment stock with enough unreserved quantity,
+// decremented, and logged as a return-consignor movement. Any failing line
+// rolls the whole document back.
+func (h *ConsignorReturnsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in consignorReturnInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	supplierID, err := uuid.Parse(in.SupplierID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "supplierId tidak valid")
+		return
+	}
+	if len(in.Lines) == 0 {
+		writeError(w, http.StatusBadRequest, "Pilih minimal satu batch untuk dikembalikan.")
+		return
+	}
+	returnedAt := strings.TrimSpace(in.ReturnedAt)
+	if returnedAt == "" {
+		returnedAt = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", returnedAt); err != nil {
+		writeError(w, http.StatusBadRequest, "returnedAt harus berformat YYYY-MM-DD")
+		return
+	}
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
+	handedOverBy := strings.TrimSpace(in.HandedOverBy)
+	if handedOverBy == "" {
+		handedOverBy = performedBy
+	}
+
+	ret := models.ConsignorReturn{
+		StoreID:      storeID,
+		SupplierID:   supplierID,
+		ReturnedAt:   returnedAt,
+		HandedOverBy: handedOverBy,
+		ReceivedBy:   strings.TrimSpace(in.ReceivedBy),
+		Notes:        strings.TrimSpace(in.Notes),
+		CreatedBy:    performedBy,
+	}
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		code, err := nextConsignorReturnCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		ret.Code = code
+
+		// Validate and lock before inserting anything so a bad line never
+		// leaves a half-written header behind.
+		seen := map[uuid.UUID]bool{}
+		batchIDs := make([]uuid.UUID, len(in.Lines))
+		for i, l := range in.Lines {
+			batchID, err := uuid.Parse(l.BatchID)
+			if err != nil {
+				return errBadInput(fmt.Sprintf("baris %d: batchId tidak valid", i+1))
+			}
+			if seen[batchID] {
+				return errBadInput(fmt.Sprintf("baris %d: batch yang sama dipilih dua kali", i+1))
+			}
+			seen[batchID] = true
+			if l.Qty <= 0 {
+				return errBadInput(fmt.Sprintf("baris %d: jumlah harus lebih dari 0", i+1))
+			}
+			batchIDs[i] = batchID
+		}
+		// Lock in id order, like the other multi-batch writers, so two
+		// returns over the same batches can't deadlock.
+		lockOrder := make([]int, len(in.Lines))
+		for i := range lockOrder {
+			lockOrder[i] = i
+		}
+		sort.Slice(lockOrder, func(a, b int) bool {
+			return batchIDs[lockOrder[a]].String() < batchIDs[lockOrder[b]].String()
+		})
+		batches := make([]models.Batch, len(in.Lines))
+		for _, i := range lockOrder {
+			l := in.Lines[i]
+			b := &batches[i]
+			if err := tx.NewSelect().Model(b).
+				Where("id = ?", batchIDs[i]).
+				Where("location_id IN (SELECT id FROM locations WHERE store_id = ?)", storeID).
+				For("UPDATE").Scan(ctx); err != nil {
+				return errBadInput(fmt.Sprintf("baris %d: batch tidak ditemukan", i+1))
+			}
+			if b.Ownership != models.BatchOwnershipConsignment {
+				return errBadInput(fmt.Sprintf("%s bukan batch konsinyasi.", b.Code))
+			}
+			if b.SupplierID == nil || *b.SupplierID != supplierID {
+				return errBadInput(fmt.Sprintf("%s bukan titipan pemasok ini.", b.Code))
+			}
+			var reserved float64
+			if err := tx.NewSelect().Table("stock_reservations").
+				ColumnExpr("COALESCE(SUM(qty), 0)").
+				Where("batch_id = ?", b.ID).
+				Where("expires_at > now()").
+				Scan(ctx, &reserved); err != nil {
+				return err
+			}
+			if free := b.QtyRemaining - reserved; l.Qty > free+1e-9 {
+				return errBadInput(fmt.Sprintf("%s: hanya %.4g unit yang bisa dikembalikan.", b.Code, max(free, 0)))
+			}
+		}
+
+		for i, l := range in.Lines {
+			b := batches[i]
+			ret.TotalQty += l.Qty
+			ret.TotalValue += l.Qty * b.UnitCost
+			ret.Lines = append(ret.Lines, models.ConsignorReturnLine{
+				BatchID:    b.ID,
+				ProductID:  b.ProductID,
+				VariantID:  b.VariantID,
+				LocationID: b.LocationID,
+				Qty:        l.Qty,
+				UnitCost:   b.UnitCost,
+				Reason:     strings.TrimSpace(l.Reason),
+				Position:   i,
+			})
+		}
+		if _, err := tx.NewInsert().Model(&ret).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		for i := range ret.Lines {
+			ret.Lines[i].ReturnID = ret.ID
+		}
+		if _, err := tx.NewInsert().Model(&ret.Lines).Exec(ctx); err != nil {
+			return err
+		}
+
+		for i, l := range ret.Lines {
+			b := batches[i]
+			after := b.QtyRemaining - l.Qty
+			if after < 1e-9 {
+				after = 0
+			}
+			if _, err := tx.NewUpdate().Table("batches").Where("id = ?", b.ID).
+				Set("qty_remaining = ?", after).
+				Set("updated_at = current_timestamp").
+				Exec(ctx); err != nil {
+				return err
+			}
+			notes := "Retur konsinyasi · " + b.Code
+			if l.Reason != "" {
+				notes += " · " + l.Reason
+			}
+			if err := logMovement(ctx, tx, &models.StockMovement{
+				Kind:       models.MovementKindReturnConsignor,
+				ProductID:  &b.ProductID,
+				VariantID:  b.VariantID,
+				LocationID: &b.LocationID,
+				BatchID:    &b.ID,
+				QtyDelta:   -l.Qty,
+				QtyAfter:   after,
+				UnitCost:   floatPtr(b.UnitCost),
+				Reference: models.StockMovementReference{
+					Kind: models.MovementRefReturn, ID: ret.ID.String(), Code: ret.Code,
+				},
+				PerformedBy: performedBy,
+				Notes:       notes,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+// Handover renders the printable serah-terima note: one row per returned
+// batch and signature blocks for both sides.
+func (h *ConsignorReturnsHandler) Handover(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx := r.Context()
+	ret, err := loadConsignorReturn(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	batchIDs := make([]uuid.UUID, len(ret.Lines))
+	for i, l := range ret.Lines {
+		batchIDs[i] = l.BatchID
+	}
+	var info []struct {
+		ID          uuid.UUID `bun:"id"`
+		Code        string    `bun:"code"`
+		ProductName string    `bun:"product_name"`
+		VariantName string    `bun:"variant_name"`
+		Location    string    `bun:"location"`
+	}
+	if len(batchIDs) > 0 {
+		if err := h.deps.DB.NewSelect().TableExpr("batches AS bt").
+			ColumnExpr("bt.id, bt.code").
+			ColumnExpr("p.name AS product_name").
+			ColumnExpr("COALESCE(pv.name, '') AS variant_name").
+			ColumnExpr("COALESCE(l.name, '') AS location").
+			Join("JOIN products AS p ON p.id = bt.product_id").
+			Join("LEFT JOIN product_variants AS pv ON pv.id = bt.variant_id").
+			Join("LEFT JOIN locations AS l ON l.id = bt.location_id").
+			Where("bt.id IN (?)", bun.In(batchIDs)).
+			Scan(ctx, &info); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	byBatch := make(map[uuid.UUID]int, len(info))
+	for i, b := range info {
+		byBatch[b.ID] = i
+	}
+
+	rows := make([][]string, 0, len(ret.Lines))
+	for i, l := range ret.Lines {
+		code, name, loc := l.BatchID.String(), "", ""
+		if j, ok := byBatch[l.BatchID]; ok {
+			code, name, loc = info[j].Code, info[j].ProductName, info[j].Location
+			if info[j].VariantName != "" {
+				name += " - " + info[j].VariantName
+			}
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(i + 1), name, code, loc,
+			formatDecimal(l.Qty), formatRupiah(l.UnitCost), formatRupiah(l.Qty * l.UnitCost), l.Reason,
+		})
+	}
+
+	supplier := lookupName(ctx, h.deps.DB, "suppliers", ret.SupplierID)
+	pdf := documents.PDF(documents.Document{
+		Title: "Berita Acara Serah Terima Retur Konsinyasi",
+		Code:  ret.Code,
+		Meta: []documents.Field{
+			{Label: "Pemasok", Value: supplier},
+			{Label: "Tanggal retur", Value: labelDate(ret.ReturnedAt)},
+			{Label: "Dibuat oleh", Value: ret.CreatedBy},
+		},
+		Columns: []documents.Column{
+			{Title: "No", Width: 3, Right: true},
+			{Title: "Produk", Width: 22},
+			{Title: "Batch", Width: 13},
+			{Title: "Lokasi", Width: 10},
+			{Title: "Qty", Width: 6, Right: true},
+			{Title: "Harga setor", Width: 10, Right: true},
+			{Title: "Nilai", Width: 11, Right: true},
+			{Title: "Alasan", Width: 15},
+		},
+		Rows:   rows,
+		Totals: []string{"", "Total", "", "", formatDecimal(ret.TotalQty), "", formatRupiah(ret.TotalValue), ""},
+		Notes:  ret.Notes,
+		Signatures: []documents.Signature{
+			{Caption: "Diserahkan oleh", Name: ret.HandedOverBy},
+			{Caption: "Diterima oleh (" + supplier + ")", Name: ret.ReceivedBy},
+		},
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", ret.Code+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(pdf)
+}
+
+func loadConsignorReturn(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.ConsignorReturn, error) {
+	var ret models.ConsignorReturn
+	err := db.NewSelect().Model(&ret).
+		Relation("Lines", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("crl.position ASC")
+		}).
+		Where("cr.id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func nextConsignorReturnCode(ctx context.Context, tx bun.Tx) (string, error) {
+	year := time.Now().Year()
+	prefix := fmt.Sprintf("CRET-%d-", year)
+	var count int
+	if err := tx.NewSelect().Table("consignor_returns").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
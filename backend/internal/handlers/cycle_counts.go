@@ -0,0 +1,608 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// ─── ABC classification + cycle counts ──────────────────────────────────────
+// Products are ranked by net sales value (order_lines) into A/B/C. A daily
+// job turns the classes into small stock_opnames drafts of kind "cycle",
+// one per location, so every product with stock there is counted once per
+// class interval (A monthly, B quarterly, C yearly by default) without a
+// full-shop opname. The drafts go through the normal opname workflow.
+
+type CycleCountsHandler struct {
+	deps Deps
+}
+
+func NewCycleCountsHandler(deps Deps) *CycleCountsHandler {
+	return &CycleCountsHandler{deps: deps}
+}
+
+// ClassifyABC recomputes product_abc_classes from sales in the lookback
+// window ending at asOf. Every product gets a row; unsold products are C.
+func ClassifyABC(ctx context.Context, db *bun.DB, asOf time.Time) (int, error) {
+	settings, err := loadServerSettings(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	cfg := settings.CycleCount
+	since := asOf.AddDate(0, 0, -max(cfg.LookbackDays, 1))
+
+	var sales []struct {
+		ProductID  uuid.UUID `bun:"product_id"`
+		SalesValue float64   `bun:"sales_value"`
+	}
+	if err := db.NewSelect().
+		TableExpr("products AS p").
+		Join("LEFT JOIN order_lines AS ol ON ol.product_id = p.id").
+		Join("LEFT JOIN orders AS o ON o.id = ol.order_id AND o.status <> ? AND o.created_at >= ? AND o.created_at < ?",
+			models.OrderStatusCancelled, since, asOf).
+		ColumnExpr("p.id AS product_id").
+		ColumnExpr("COALESCE(SUM(ol.line_subtotal_net) FILTER (WHERE o.id IS NOT NULL), 0) AS sales_value").
+		GroupExpr("p.id").
+		OrderExpr("sales_value DESC, p.id ASC").
+		Scan(ctx, &sales); err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, s := range sales {
+		total += math.Max(s.SalesValue, 0)
+	}
+
+	rows := make([]models.ProductABCClass, len(sales))
+	cum := 0.0
+	for i, s := range sales {
+		share := 0.0
+		if total > 0 {
+			share = math.Max(s.SalesValue, 0) / total * 100
+		}
+		// The class is decided by where the product starts on the cumulative
+		// curve, so the product that crosses the A threshold is still A.
+		class := models.ABCClassC
+		switch {
+		case share > 0 && cum < cfg.AThresholdPct:
+			class = models.ABCClassA
+		case share > 0 && cum < cfg.BThresholdPct:
+			class = models.ABCClassB
+		}
+		cum += share
+		rows[i] = models.ProductABCClass{
+			ProductID:     s.ProductID,
+			Class:         class,
+			SalesValue:    s.SalesValue,
+			SharePct:      math.Round(share*10000) / 10000,
+			CumulativePct: math.Round(math.Min(cum, 100)*10000) / 10000,
+			Rank:          i + 1,
+			ClassifiedAt:  asOf,
+		}
+	}
+
+	err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Table("product_abc_classes").Where("TRUE").Exec(ctx); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&rows).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// RunCycleCountSchedule is the background job: classify once per day, then
+// generate the day's drafts for every store.
+func RunCycleCountSchedule(ctx context.Context, db *bun.DB, now time.Time) (int, error) {
+	var last time.Time
+	err := db.NewSelect().Table("product_abc_classes").
+		ColumnExpr("COALESCE(MAX(classified_at), 'epoch'::timestamptz)").
+		Scan(ctx, &last)
+	if err != nil {
+		return 0, err
+	}
+	if last.In(now.Location()).Format("2006-01-02") != now.Format("2006-01-02") {
+		if _, err := ClassifyABC(ctx, db, now); err != nil {
+			return 0, err
+		}
+	}
+	return GenerateCycleCounts(ctx, db, now, nil)
+}
+
+// cycleCountCandidate is one product with stock at a location, with its
+// class and when it was last counted there.
+type cycleCountCandidate struct {
+	ProductID     uuid.UUID  `bun:"product_id"`
+	Class         string     `bun:"abc_class"`
+	Rank          int        `bun:"rank"`
+	LastCountedAt *time.Time `bun:"last_counted_at"`
+	Pending       bool       `bun:"pending"`
+}
+
+func cycleIntervalDays(cfg serverSettings, class string) int {
+	days := cfg.CycleCount.IntervalDaysC
+	switch class {
+	case models.ABCClassA:
+		days = cfg.CycleCount.IntervalDaysA
+	case models.ABCClassB:
+		days = cfg.CycleCount.IntervalDaysB
+	}
+	return max(days, 1)
+}
+
+// loadCycleCountCandidates lists the products with stock at loc. A product
+// counts as counted at loc by any completed opname line for it there, cycle
+// or full.
+func loadCycleCountCandidates(ctx context.Context, db bun.IDB, loc uuid.UUID) ([]cycleCountCandidate, error) {
+	var out []cycleCountCandidate
+	err := db.NewSelect().
+		TableExpr("batches AS bt").
+		Join("LEFT JOIN product_abc_classes AS abc ON abc.product_id = bt.product_id").
+		ColumnExpr("bt.product_id").
+		ColumnExpr("COALESCE(abc.abc_class, ?) AS abc_class", models.ABCClassC).
+		ColumnExpr("COALESCE(abc.rank, 2147483647) AS rank").
+		ColumnExpr(`(SELECT MAX(so.completed_at) FROM stock_opname_lines AS sol
+			JOIN stock_opnames AS so ON so.id = sol.opname_id
+			WHERE so.status = ? AND sol.product_id = bt.product_id
+			AND sol.counted_qty IS NOT NULL
+			AND COALESCE(sol.location_id, so.location_id) = ?) AS last_counted_at`,
+			models.OpnameStatusCompleted, loc).
+		ColumnExpr(`EXISTS (SELECT 1 FROM stock_opname_lines AS sol
+			JOIN stock_opnames AS so ON so.id = sol.opname_id
+			WHERE so.kind = ? AND so.location_id = ? AND so.status IN (?, ?)
+			AND sol.product_id = bt.product_id) AS pending`,
+			models.OpnameKindCycle, loc, models.OpnameStatusDraft, models.OpnameStatusCounting).
+		Where("bt.location_id = ?", loc).
+		Where("bt.qty_remaining > 0").
+		Where("bt.status <> ?", models.BatchStatusDisposed).
+		GroupExpr("bt.product_id, abc.abc_class, abc.rank").
+		Scan(ctx, &out)
+	return out, err
+}
+
+// GenerateCycleCounts creates the day's cycle-count drafts. Per location and
+// class the daily quota is ceil(products / interval days); the most overdue
+// products (never counted first, then oldest count, then highest sales
+// rank) fill it. Products already sitting in an open cycle count are
+// skipped. Idempotent: a location that has its draft for day is left alone.
+// Classifies first when no classification exists yet. storeID narrows it to
+// one store's locations (nil = all).
+func GenerateCycleCounts(ctx context.Context, db *bun.DB, day time.Time, storeID *uuid.UUID) (int, error) {
+	settings, err := loadServerSettings(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	classified, err := db.NewSelect().Table("product_abc_classes").Exists(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !classified {
+		if _, err := ClassifyABC(ctx, db, day); err != nil {
+			return 0, err
+		}
+	}
+	dayStr := day.Format("2006-01-02")
+
+	var locations []models.Location
+	q := db.NewSelect().Model(&locations).
+		Where("status = ?", models.LocationStatusActive).
+		Order("name ASC")
+	if storeID != nil {
+		q = q.Where("store_id = ?", *storeID)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, loc := range locations {
+		exists, err := db.NewSelect().Table("stock_opnames").
+			Where("kind = ? AND location_id = ? AND scheduled_for = ?", models.OpnameKindCycle, loc.ID, dayStr).
+			Exists(ctx)
+		if err != nil {
+			return created, err
+		}
+		if exists {
+			continue
+		}
+		candidates, err := loadCycleCountCandidates(ctx, db, loc.ID)
+		if err != nil {
+			return created, err
+		}
+		picked := pickCycleCountItems(candidates, settings, day)
+		if len(picked) == 0 {
+			continue
+		}
+		ok, err := createCycleCountDraft(ctx, db, loc.ID, dayStr, picked)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+func pickCycleCountItems(candidates []cycleCountCandidate, settings serverSettings, day time.Time) []cycleCountCandidate {
+	byClass := map[string][]cycleCountCandidate{}
+	for _, c := range candidates {
+		byClass[c.Class] = append(byClass[c.Class], c)
+	}
+	var picked []cycleCountCandidate
+	for _, class := range []string{models.ABCClassA, models.ABCClassB, models.ABCClassC} {
+		items := byClass[class]
+		interval := cycleIntervalDays(settings, class)
+		quota := int(math.Ceil(float64(len(items)) / float64(interval)))
+		cutoff := day.AddDate(0, 0, -interval)
+		due := make([]cycleCountCandidate, 0, len(items))
+		for _, c := range items {
+			if c.Pending {
+				continue
+			}
+			if c.LastCountedAt == nil || !c.LastCountedAt.After(cutoff) {
+				due = append(due, c)
+			}
+		}
+		sort.SliceStable(due, func(i, j int) bool {
+			a, b := due[i].LastCountedAt, due[j].LastCountedAt
+			if (a == nil) != (b == nil) {
+				return a == nil
+			}
+			if a != nil && !a.Equal(*b) {
+				return a.Before(*b)
+			}
+			return due[i].Rank < due[j].Rank
+		})
+		if len(due) > quota {
+			due = due[:quota]
+		}
+		picked = append(picked, due...)
+	}
+	return picked
+}
+
+// createCycleCountDraft inserts the draft with one line per product/variant
+// at the location. Expected quantities here are indicative only — Start
+// snapshots the batches again when counting begins.
+func createCycleCountDraft(ctx context.Context, db *bun.DB, locID uuid.UUID, day string, picked []cycleCountCandidate) (bool, error) {
+	ids := make([]uuid.UUID, len(picked))
+	perClass := map[string]int{}
+	for i, c := range picked {
+		ids[i] = c.ProductID
+		perClass[c.Class]++
+	}
+	created := false
+	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var stock []struct {
+			ProductID uuid.UUID  `bun:"product_id"`
+			VariantID *uuid.UUID `bun:"variant_id"`
+			Qty       float64    `bun:"qty"`
+			UnitCost  float64    `bun:"unit_cost"`
+		}
+		if err := tx.NewSelect().TableExpr("batches AS bt").
+			ColumnExpr("bt.product_id, bt.variant_id").
+			ColumnExpr("SUM(bt.qty_remaining) AS qty").
+			ColumnExpr("COALESCE(SUM(bt.qty_remaining * bt.unit_cost) / NULLIF(SUM(bt.qty_remaining), 0), 0) AS unit_cost").
+			Where("bt.location_id = ?", locID).
+			Where("bt.product_id IN (?)", bun.In(ids)).
+			Where("bt.qty_remaining > 0").
+			Where("bt.status <> ?", models.BatchStatusDisposed).
+			GroupExpr("bt.product_id, bt.variant_id").
+			OrderExpr("bt.product_id, bt.variant_id").
+			Scan(ctx, &stock); err != nil {
+			return err
+		}
+		if len(stock) == 0 {
+			return nil
+		}
+		code, err := nextOpnameCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		op := models.StockOpname{
+			Code:         code,
+			LocationID:   &locID,
+			StartedAt:    time.Now(),
+			Status:       models.OpnameStatusDraft,
+			PerformedBy:  "Sistem",
+			Kind:         models.OpnameKindCycle,
+			ScheduledFor: day,
+			Notes: fmt.Sprintf("Cycle count %s — A %d, B %d, C %d produk",
+				day, perClass[models.ABCClassA], perClass[models.ABCClassB], perClass[models.ABCClassC]),
+		}
+		if _, err := tx.NewInsert().Model(&op).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		lines := make([]models.StockOpnameLine, len(stock))
+		for i, s := range stock {
+			lines[i] = models.StockOpnameLine{
+				OpnameID:    op.ID,
+				ProductID:   s.ProductID,
+				VariantID:   s.VariantID,
+				LocationID:  &locID,
+				ExpectedQty: s.Qty,
+				UnitCost:    math.Round(s.UnitCost*100) / 100,
+			}
+		}
+		if _, err := tx.NewInsert().Model(&lines).Exec(ctx); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil && strings.Contains(err.Error(), "stock_opnames_cycle_day") {
+		// Another run got there first.
+		return false, nil
+	}
+	return created, err
+}
+
+// ABC lists the current classification. Query: class, format=csv.
+func (h *CycleCountsHandler) ABC(w http.ResponseWriter, r *http.Request) {
+	items := []models.ProductABCClass{}
+	q := h.deps.DB.NewSelect().Model(&items).
+		ColumnExpr("abc.*").
+		ColumnExpr("p.name AS product_name").
+		Join("JOIN products AS p ON p.id = abc.product_id").
+		Order("abc.rank ASC")
+	if c := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("class"))); c != "" {
+		q = q.Where("abc.abc_class = ?", c)
+	}
+	if err := q.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		out := make([][]string, len(items))
+		for i, it := range items {
+			out[i] = []string{
+				fmt.Sprint(it.Rank), it.ProductName, it.Class,
+				formatDecimal(it.SalesValue), formatDecimal(it.SharePct), formatDecimal(it.CumulativePct),
+			}
+		}
+		writeCSV(w, "klasifikasi-abc.csv",
+			[]string{"Peringkat", "Produk", "Kelas", "Nilai penjualan", "Porsi %", "Kumulatif %"}, out)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Classify reruns the ABC classification now.
+func (h *CycleCountsHandler) Classify(w http.ResponseWriter, r *http.Request) {
+	n, err := ClassifyABC(r.Context(), h.deps.DB, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"classified": n})
+}
+
+type cycleCountGenerateInput struct {
+	Date string `json:"date"`
+}
+
+// Generate runs the scheduler for a day (default today) for the active
+// store, or every store when consolidated.
+func (h *CycleCountsHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	var in cycleCountGenerateInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	day := time.Now()
+	if v := strings.TrimSpace(in.Date); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "tanggal harus YYYY-MM-DD")
+			return
+		}
+		day = d
+	}
+	var storeID *uuid.UUID
+	if id, ok := activeStore(r.Context()); ok {
+		storeID = &id
+	}
+	n, err := GenerateCycleCounts(r.Context(), h.deps.DB, day, storeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"created": n})
+}
+
+// cycleCountClassStatus is today's coverage for one class: of the products
+// with stock (per location), how many were counted within the interval.
+type cycleCountClassStatus struct {
+	Class        string  `json:"class"`
+	IntervalDays int     `json:"intervalDays"`
+	Items        int     `json:"items"`
+	OnSchedule   int     `json:"onSchedule"`
+	Overdue      int     `json:"overdue"`
+	CoveragePct  float64 `json:"coveragePct"`
+}
+
+// cycleCountMonth is one month of history for one class. CoveragePct is
+// the share of items in today's scope that were on schedule at month end;
+// accuracy counts completed opname lines whose posted variance was zero.
+type cycleCountMonth struct {
+	Month         string  `bun:"month" json:"month"`
+	Class         string  `bun:"abc_class" json:"class"`
+	CoveragePct   float64 `bun:"-" json:"coveragePct"`
+	LinesCounted  int     `bun:"lines_counted" json:"linesCounted"`
+	AccurateLines int     `bun:"accurate_lines" json:"accurateLines"`
+	AccuracyPct   float64 `bun:"-" json:"accuracyPct"`
+	VarianceValue float64 `bun:"variance_value" json:"varianceValue"`
+}
+
+type cycleCountDashboard struct {
+	Current []cycleCountClassStatus `json:"current"`
+	History []cycleCountMonth       `json:"history"`
+}
+
+// Dashboard reports count coverage and accuracy per ABC class: the current
+// state plus one row per month and class. Query: months (default 12).
+func (h *CycleCountsHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	months := 12
+	if v, err := strconv.Atoi(r.URL.Query().Get("months")); err == nil && v > 0 {
+		months = min(v, 60)
+	}
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	now := time.Now()
+	firstMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -(months - 1), 0)
+	classes := []string{models.ABCClassA, models.ABCClassB, models.ABCClassC}
+
+	// Scope: (location, product) pairs with stock now.
+	var scope []struct {
+		LocationID uuid.UUID `bun:"location_id"`
+		ProductID  uuid.UUID `bun:"product_id"`
+		Class      string    `bun:"abc_class"`
+	}
+	sq := h.deps.DB.NewSelect().TableExpr("batches AS bt").
+		Join("LEFT JOIN product_abc_classes AS abc ON abc.product_id = bt.product_id").
+		ColumnExpr("DISTINCT bt.location_id, bt.product_id").
+		ColumnExpr("COALESCE(abc.abc_class, ?) AS abc_class", models.ABCClassC).
+		Where("bt.qty_remaining > 0").
+		Where("bt.status <> ?", models.BatchStatusDisposed)
+	sq = scopeStoreLocations(ctx, sq, "bt.location_id")
+	if err := sq.Scan(ctx, &scope); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Count events per pair, far enough back for the longest interval.
+	longest := max(cycleIntervalDays(settings, models.ABCClassA),
+		cycleIntervalDays(settings, models.ABCClassB), cycleIntervalDays(settings, models.ABCClassC))
+	var events []struct {
+		LocationID  uuid.UUID `bun:"location_id"`
+		ProductID   uuid.UUID `bun:"product_id"`
+		CompletedAt time.Time `bun:"completed_at"`
+	}
+	eq := h.deps.DB.NewSelect().TableExpr("stock_opname_lines AS sol").
+		Join("JOIN stock_opnames AS so ON so.id = sol.opname_id").
+		ColumnExpr("DISTINCT COALESCE(sol.location_id, so.location_id) AS location_id, sol.product_id, so.completed_at").
+		Where("so.status = ?", models.OpnameStatusCompleted).
+		Where("sol.counted_qty IS NOT NULL").
+		Where("COALESCE(sol.location_id, so.location_id) IS NOT NULL").
+		Where("so.completed_at >= ?", firstMonth.AddDate(0, 0, -longest))
+	eq = scopeStoreLocations(ctx, eq, "COALESCE(sol.location_id, so.location_id)")
+	if err := eq.Scan(ctx, &events); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	type pair struct{ loc, product uuid.UUID }
+	counts := map[pair][]time.Time{}
+	for _, e := range events {
+		k := pair{e.LocationID, e.ProductID}
+		counts[k] = append(counts[k], e.CompletedAt)
+	}
+	// onSchedule reports whether the pair had a count in (at-interval, at].
+	onSchedule := func(k pair, class string, at time.Time) bool {
+		from := at.AddDate(0, 0, -cycleIntervalDays(settings, class))
+		for _, t := range counts[k] {
+			if t.After(from) && !t.After(at) {
+				return true
+			}
+		}
+		return false
+	}
+
+	report := cycleCountDashboard{Current: []cycleCountClassStatus{}, History: []cycleCountMonth{}}
+	current := map[string]*cycleCountClassStatus{}
+	for _, c := range classes {
+		report.Current = append(report.Current, cycleCountClassStatus{Class: c, IntervalDays: cycleIntervalDays(settings, c)})
+	}
+	for i := range report.Current {
+		current[report.Current[i].Class] = &report.Current[i]
+	}
+	for _, s := range scope {
+		st := current[s.Class]
+		st.Items++
+		if onSchedule(pair{s.LocationID, s.ProductID}, s.Class, now) {
+			st.OnSchedule++
+		} else {
+			st.Overdue++
+		}
+	}
+	for i := range report.Current {
+		if st := &report.Current[i]; st.Items > 0 {
+			st.CoveragePct = math.Round(float64(st.OnSchedule)/float64(st.Items)*10000) / 100
+		}
+	}
+
+	var acc []cycleCountMonth
+	aq := h.deps.DB.NewSelect().TableExpr("stock_opname_lines AS sol").
+		Join("JOIN stock_opnames AS so ON so.id = sol.opname_id").
+		Join("LEFT JOIN product_abc_classes AS abc ON abc.product_id = sol.product_id").
+		ColumnExpr("to_char(so.completed_at, 'YYYY-MM') AS month").
+		ColumnExpr("COALESCE(abc.abc_class, ?) AS abc_class", models.ABCClassC).
+		ColumnExpr("COUNT(*) AS lines_counted").
+		ColumnExpr("COUNT(*) FILTER (WHERE ABS(COALESCE(sol.variance_qty, 0)) < 0.0001) AS accurate_lines").
+		ColumnExpr("COALESCE(SUM(sol.variance_value), 0) AS variance_value").
+		Where("so.status = ?", models.OpnameStatusCompleted).
+		Where("sol.counted_qty IS NOT NULL").
+		Where("so.completed_at >= ?", firstMonth).
+		GroupExpr("month, abc_class")
+	aq = scopeStoreLocations(ctx, aq, "COALESCE(sol.location_id, so.location_id)")
+	if err := aq.Scan(ctx, &acc); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	accByKey := map[string]cycleCountMonth{}
+	for _, a := range acc {
+		accByKey[a.Month+"|"+a.Class] = a
+	}
+
+	for m := 0; m < months; m++ {
+		start := firstMonth.AddDate(0, m, 0)
+		end := start.AddDate(0, 1, 0).Add(-time.Nanosecond)
+		if end.After(now) {
+			end = now
+		}
+		month := start.Format("2006-01")
+		for _, c := range classes {
+			row := accByKey[month+"|"+c]
+			row.Month, row.Class = month, c
+			items, ok := 0, 0
+			for _, s := range scope {
+				if s.Class != c {
+					continue
+				}
+				items++
+				if onSchedule(pair{s.LocationID, s.ProductID}, c, end) {
+					ok++
+				}
+			}
+			if items > 0 {
+				row.CoveragePct = math.Round(float64(ok)/float64(items)*10000) / 100
+			}
+			if row.LinesCounted > 0 {
+				row.AccuracyPct = math.Round(float64(row.AccurateLines)/float64(row.LinesCounted)*10000) / 100
+			}
+			report.History = append(report.History, row)
+		}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/mail"
 	"github.com/uptrace/bun"
 )
 
@@ -14,6 +19,8 @@ type Deps struct {
 	DB         *bun.DB
 	Issuer     *auth.Issuer
 	BcryptCost int
+	// Nil when SMTP is not configured.
+	Mailer *mail.Mailer
 }
 
 func writeJSON(w http.ResponseWriter, status int, body any) {
@@ -25,3 +32,33 @@ func writeJSON(w http.ResponseWriter, status int, body any) {
 func writeError(w http.ResponseWriter, status int, msg string) {
 	writeJSON(w, status, map[string]string{"error": msg})
 }
+
+// writeCSV streams a CSV download. Report endpoints use it for ?format=csv.
+func writeCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	_ = cw.WriteAll(rows)
+}
+
+// formatDecimal renders a number for CSV without exponent notation.
+func formatDecimal(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeTxError maps errors returned from a RunInTx workflow: errBadInput →
+// 400 with its message, errNotFound → 404, anything else → 500.
+func writeTxError(w http.ResponseWriter, err error) {
+	var bad *badInputError
+	if errors.As(err, &bad) {
+		writeError(w, http.StatusBadRequest, bad.msg)
+		return
+	}
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/labels"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type LabelTemplatesHandler struct {
+	deps Deps
+}
+
+func NewLabelTemplatesHandler(deps Deps) *LabelTemplatesHandler {
+	return &LabelTemplatesHandler{deps: deps}
+}
+
+type labelTemplateInput struct {
+	Name      string                  `json:"name"`
+	Kind      string                  `json:"kind"`
+	WidthMM   float64                 `json:"widthMm"`
+	HeightMM  float64                 `json:"heightMm"`
+	DPI       int                     `json:"dpi"`
+	Symbology string                  `json:"symbology"`
+	Fields    []models.LabelField     `json:"fields"`
+	Barcode   *models.LabelBarcodeBox `json:"barcode,omitempty"`
+	IsDefault bool                    `json:"isDefault"`
+}
+
+func (h *LabelTemplatesHandler) List(w http.ResponseWriter, r *http.Request) {
+	items := []models.LabelTemplate{}
+	q := h.deps.DB.NewSelect().Model(&items).Order("kind ASC", "name ASC")
+	if v := strings.TrimSpace(r.URL.Query().Get("kind")); v != "" {
+		q = q.Where("kind = ?", v)
+	}
+	if err := q.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *LabelTemplatesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var t models.LabelTemplate
+	if err := h.deps.DB.NewSelect().Model(&t).Where("id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *LabelTemplatesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in labelTemplateInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := validateLabelTemplateInput(&in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	t := labelTemplateFromInput(&in)
+	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if t.IsDefault {
+			if err := clearDefaultLabelTemplate(ctx, tx, t.Kind, uuid.Nil); err != nil {
+				return err
+			}
+		}
+		_, err := tx.NewInsert().Model(t).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *LabelTemplatesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in labelTemplateInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := validateLabelTemplateInput(&in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	t := labelTemplateFromInput(&in)
+	t.ID = id
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if t.IsDefault {
+			if err := clearDefaultLabelTemplate(ctx, tx, t.Kind, id); err != nil {
+				return err
+			}
+		}
+		res, err := tx.NewUpdate().Model(t).
+			Column("name", "kind", "width_mm", "height_mm", "dpi", "symbology", "fields", "barcode", "is_default").
+			Set("updated_at = current_timestamp").
+			WherePK().Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return errNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if err := h.deps.DB.NewSelect().Model(t).WherePK().Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *LabelTemplatesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	res, err := h.deps.DB.NewDelete().Model((*models.LabelTemplate)(nil)).Where("id = ?", id).Exec(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateLabelTemplateInput(in *labelTemplateInput) string {
+	if strings.TrimSpace(in.Name) == "" {
+		return "Nama template wajib diisi."
+	}
+	switch in.Kind {
+	case models.LabelKindProduct, models.LabelKindBatch, models.LabelKindPromotion:
+	default:
+		return "Jenis label harus product, batch atau promotion."
+	}
+	if in.WidthMM <= 0 || in.HeightMM <= 0 {
+		return "Ukuran label harus lebih dari 0."
+	}
+	switch in.DPI {
+	case 0, 203, 300, 600:
+	default:
+		return "DPI harus 203, 300 atau 600."
+	}
+	switch in.Symbology {
+	case "", labels.SymbologyCode128, labels.SymbologyEAN13, labels.SymbologyNone:
+	default:
+		return "Simbologi barcode harus code128, ean13 atau none."
+	}
+	for _, f := range in.Fields {
+		if strings.TrimSpace(f.Key) == "" || f.Size <= 0 {
+			return "Setiap field wajib punya key dan ukuran."
+		}
+	}
+	if b := in.Barcode; b != nil && (b.Width <= 0 || b.Height <= 3) {
+		return "Area barcode terlalu kecil."
+	}
+	return ""
+}
+
+func labelTemplateFromInput(in *labelTemplateInput) *models.LabelTemplate {
+	t := &models.LabelTemplate{
+		Name:      strings.TrimSpace(in.Name),
+		Kind:      in.Kind,
+		WidthMM:   in.WidthMM,
+		HeightMM:  in.HeightMM,
+		DPI:       in.DPI,
+		Symbology: in.Symbology,
+		Fields:    in.Fields,
+		Barcode:   in.Barcode,
+		IsDefault: in.IsDefault,
+	}
+	if t.DPI == 0 {
+		t.DPI = 203
+	}
+	if t.Symbology == "" {
+		t.Symbology = labels.SymbologyCode128
+	}
+	if t.Fields == nil {
+		t.Fields = []models.LabelField{}
+	}
+	return t
+}
+
+// clearDefaultLabelTemplate demotes the kind's current default (other than
+// keep) so the partial unique index isn't violated.
+func clearDefaultLabelTemplate(ctx context.Context, tx bun.Tx, kind string, keep uuid.UUID) error {
+	_, err := tx.NewUpdate().Table("label_templates").
+		Set("is_default = false").
+		Set("updated_at = current_timestamp").
+		Where("kind = ? AND is_default AND id <> ?", kind, keep).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,501 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/labels"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type LabelsHandler struct {
+	deps Deps
+}
+
+func NewLabelsHandler(deps Deps) *LabelsHandler {
+	return &LabelsHandler{deps: deps}
+}
+
+// maxLabelCopies caps a single print job; a typo in copies shouldn't
+// produce a 10k-page PDF.
+const maxLabelCopies = 500
+
+// Every label endpoint takes ?templateId (default: the kind's default
+// template), ?format=pdf|zpl (default pdf) and ?copies (default 1).
+
+// Product prints a shelf label. Extra query: variantId, packagingId,
+// pricelistId (default pricelist when omitted).
+func (h *LabelsHandler) Product(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	q := r.URL.Query()
+	tpl, err := resolveLabelTemplate(ctx, h.deps.DB, models.LabelKindProduct, q.Get("templateId"))
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	p, err := loadProduct(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	defaultID, err := defaultPricelistID(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pricelistID := strings.TrimSpace(q.Get("pricelistId"))
+	if pricelistID == "" {
+		pricelistID = defaultID
+	}
+
+	values := map[string]string{
+		"name":    labelName(p.PrintName, p.Name),
+		"sku":     p.SKU,
+		"barcode": p.Barcode,
+	}
+	barcode := p.Barcode
+	var variantID *uuid.UUID
+	if v := strings.TrimSpace(q.Get("variantId")); v != "" {
+		vid, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "variantId tidak valid")
+			return
+		}
+		found := false
+		for _, pv := range p.Variants {
+			if pv.ID == vid {
+				found = true
+				variantID = &vid
+				values["variant"] = labelName(pv.PrintName, pv.Name)
+				values["sku"] = pv.SKU
+				if pv.Barcode != "" {
+					barcode = pv.Barcode
+					values["barcode"] = pv.Barcode
+				}
+			}
+		}
+		if !found {
+			writeError(w, http.StatusBadRequest, "Varian tidak ditemukan.")
+			return
+		}
+	}
+
+	cost, err := markupCost(ctx, h.deps.DB, p, variantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	price, havePrice := productBasePrice(p, variantID, cost, pricelistID, defaultID)
+	unitID := p.UnitID
+	if v := strings.TrimSpace(q.Get("packagingId")); v != "" {
+		var pkg *models.ProductPackaging
+		for i := range p.Packagings {
+			if p.Packagings[i].ID.String() == v {
+				pkg = &p.Packagings[i]
+			}
+		}
+		if pkg == nil {
+			writeError(w, http.StatusBadRequest, "Kemasan tidak ditemukan.")
+			return
+		}
+		if pkg.Barcode != "" {
+			barcode = pkg.Barcode
+			values["barcode"] = pkg.Barcode
+		}
+		if uid, err := uuid.Parse(pkg.UnitID); err == nil {
+			unitID = &uid
+		}
+		// A packaging's own price wins; otherwise it's factor × base price.
+		price *= pkg.Factor
+		for _, id := range []string{pricelistID, defaultID} {
+			if e := findPricelistEntry(pkg.Prices, id); e != nil {
+				price, havePrice = computeSalePrice(cost*pkg.Factor, e.Pricing), true
+				break
+			}
+		}
+	}
+	if havePrice {
+		values["price"] = formatRupiah(price)
+	}
+	if unitID != nil {
+		var code string
+		if err := h.deps.DB.NewSelect().Table("units").Column("code").
+			Where("id = ?", *unitID).Scan(ctx, &code); err == nil {
+			values["unit"] = code
+		}
+	}
+	if p.CategoryID != nil {
+		values["category"] = lookupName(ctx, h.deps.DB, "categories", *p.CategoryID)
+	}
+	if p.BrandID != nil {
+		values["brand"] = lookupName(ctx, h.deps.DB, "brands", *p.BrandID)
+	}
+	if barcode == "" {
+		barcode = values["sku"]
+	}
+
+	copies, err := labelCopies(q.Get("copies"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeLabels(w, r, tpl, "label-"+p.SKU, []labels.Label{{Values: values, Barcode: barcode, Copies: copies}})
+}
+
+// Batch prints one batch label (code, expiry, BPOM / halal numbers).
+func (h *LabelsHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	q := r.URL.Query()
+	tpl, err := resolveLabelTemplate(ctx, h.deps.DB, models.LabelKindBatch, q.Get("templateId"))
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	copies, err := labelCopies(q.Get("copies"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	items, err := batchLabels(ctx, h.deps.DB.NewSelect().Where("bt.id = ?", id), func(float64) int { return copies })
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(items) == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeLabels(w, r, tpl, "label-"+items[0].Barcode, items)
+}
+
+// PurchaseOrderBatches prints labels for every batch received against the
+// PO. ?perUnit=true prints one label per received unit (rounded up) instead
+// of ?copies per batch.
+func (h *LabelsHandler) PurchaseOrderBatches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	q := r.URL.Query()
+	tpl, err := resolveLabelTemplate(ctx, h.deps.DB, models.LabelKindBatch, q.Get("templateId"))
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	var code string
+	if err := h.deps.DB.NewSelect().Table("purchase_orders").Column("code").
+		Where("id = ?", id).Scan(ctx, &code); err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	copies, err := labelCopies(q.Get("copies"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	copiesFor := func(float64) int { return copies }
+	if q.Get("perUnit") == "true" {
+		copiesFor = func(qty float64) int {
+			return min(maxLabelCopies, max(1, int(math.Ceil(qty-1e-9))))
+		}
+	}
+	items, err := batchLabels(ctx,
+		h.deps.DB.NewSelect().Where("bt.source_purchase_order_id = ?", id), copiesFor)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(items) == 0 {
+		writeError(w, http.StatusBadRequest, "PO ini belum punya batch yang diterima.")
+		return
+	}
+	writeLabels(w, r, tpl, "label-batch-"+code, items)
+}
+
+// Promotion prints a promo shelf talker.
+func (h *LabelsHandler) Promotion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	q := r.URL.Query()
+	tpl, err := resolveLabelTemplate(ctx, h.deps.DB, models.LabelKindPromotion, q.Get("templateId"))
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	var pm models.Promotion
+	if err := h.deps.DB.NewSelect().Model(&pm).Where("id = ?", id).Scan(ctx); err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	copies, err := labelCopies(q.Get("copies"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	values := map[string]string{
+		"name":        pm.Name,
+		"code":        pm.Code,
+		"kind":        promoKindLabel(pm.Kind),
+		"discount":    promoDiscountText(&pm),
+		"description": pm.Description,
+	}
+	switch {
+	case pm.StartDate != "" && pm.EndDate != "":
+		values["period"] = labelDate(pm.StartDate) + " – " + labelDate(pm.EndDate)
+	case pm.EndDate != "":
+		values["period"] = "s.d. " + labelDate(pm.EndDate)
+	case pm.StartDate != "":
+		values["period"] = "Mulai " + labelDate(pm.StartDate)
+	}
+	writeLabels(w, r, tpl, "label-"+pm.Code, []labels.Label{{Values: values, Barcode: pm.Code, Copies: copies}})
+}
+
+type batchLabelRow struct {
+	Code            string  `bun:"code"`
+	ExpiresAt       string  `bun:"expires_at"`
+	ReceivedAt      string  `bun:"received_at"`
+	QtyReceived     float64 `bun:"qty_received"`
+	ProductName     string  `bun:"product_name"`
+	PrintName       string  `bun:"print_name"`
+	SKU             string  `bun:"sku"`
+	VariantName     string  `bun:"variant_name"`
+	VariantSKU      string  `bun:"variant_sku"`
+	BPOMNumber      string  `bun:"bpom_number"`
+	HalalCertNumber string  `bun:"halal_cert_number"`
+	SupplierName    string  `bun:"supplier_name"`
+}
+
+// batchLabels loads the batches matched by sel (which filters on alias bt)
+// and turns each into a label.
+func batchLabels(ctx context.Context, sel *bun.SelectQuery, copiesFor func(qty float64) int) ([]labels.Label, error) {
+	var rows []batchLabelRow
+	if err := sel.TableExpr("batches AS bt").
+		Join("JOIN products AS p ON p.id = bt.product_id").
+		Join("LEFT JOIN product_variants AS pv ON pv.id = bt.variant_id").
+		Join("LEFT JOIN suppliers AS s ON s.id = bt.supplier_id").
+		ColumnExpr("bt.code, bt.expires_at, bt.received_at, bt.qty_received").
+		ColumnExpr("p.name AS product_name, p.print_name, p.sku, p.bpom_number, p.halal_cert_number").
+		ColumnExpr("COALESCE(pv.name, '') AS variant_name, COALESCE(pv.sku, '') AS variant_sku").
+		ColumnExpr("COALESCE(s.name, '') AS supplier_name").
+		OrderExpr("p.name ASC, bt.created_at ASC").
+		Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	out := make([]labels.Label, 0, len(rows))
+	for _, row := range rows {
+		sku := row.SKU
+		if row.VariantSKU != "" {
+			sku = row.VariantSKU
+		}
+		out = append(out, labels.Label{
+			Values: map[string]string{
+				"name":            labelName(row.PrintName, row.ProductName),
+				"variant":         row.VariantName,
+				"sku":             sku,
+				"code":            row.Code,
+				"expiresAt":       labelDate(row.ExpiresAt),
+				"receivedAt":      labelDate(row.ReceivedAt),
+				"bpomNumber":      row.BPOMNumber,
+				"halalCertNumber": row.HalalCertNumber,
+				"supplier":        row.SupplierName,
+			},
+			Barcode: row.Code,
+			Copies:  copiesFor(row.QtyReceived),
+		})
+	}
+	return out, nil
+}
+
+// resolveLabelTemplate loads ?templateId (which must be of kind) or falls
+// back to the kind's default, then to any template of that kind.
+func resolveLabelTemplate(ctx context.Context, db bun.IDB, kind, rawID string) (labels.Template, error) {
+	var t models.LabelTemplate
+	q := db.NewSelect().Model(&t).Where("kind = ?", kind)
+	if rawID = strings.TrimSpace(rawID); rawID != "" {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			return labels.Template{}, errBadInput("templateId tidak valid")
+		}
+		q = q.Where("id = ?", id)
+	} else {
+		q = q.OrderExpr("is_default DESC, name ASC")
+	}
+	if err := q.Limit(1).Scan(ctx); err != nil {
+		return labels.Template{}, errBadInput("Template label " + kind + " tidak ditemukan.")
+	}
+	out := labels.Template{
+		WidthMM:   t.WidthMM,
+		HeightMM:  t.HeightMM,
+		DPI:       t.DPI,
+		Symbology: t.Symbology,
+	}
+	for _, f := range t.Fields {
+		out.Fields = append(out.Fields, labels.Field{
+			Key: f.Key, Prefix: f.Prefix, X: f.X, Y: f.Y, Size: f.Size, Bold: f.Bold,
+		})
+	}
+	if b := t.Barcode; b != nil {
+		out.Barcode = &labels.BarcodeBox{X: b.X, Y: b.Y, Width: b.Width, Height: b.Height}
+	}
+	return out, nil
+}
+
+func writeLabels(w http.ResponseWriter, r *http.Request, tpl labels.Template, filename string, items []labels.Label) {
+	if r.URL.Query().Get("format") == "zpl" {
+		w.Header().Set("Content-Type", "application/x-zpl; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".zpl"))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(labels.ZPL(tpl, items))
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(labels.PDF(tpl, items))
+}
+
+func labelCopies(raw string) (int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > maxLabelCopies {
+		return 0, errors.New("copies harus 1–" + strconv.Itoa(maxLabelCopies))
+	}
+	return n, nil
+}
+
+func labelName(printName, name string) string {
+	if strings.TrimSpace(printName) != "" {
+		return printName
+	}
+	return name
+}
+
+// labelDate turns a stored YYYY-MM-DD into DD/MM/YYYY; anything else is
+// printed as-is.
+func labelDate(s string) string {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return s
+	}
+	return d.Format("02/01/2006")
+}
+
+func lookupName(ctx context.Context, db bun.IDB, table string, id uuid.UUID) string {
+	var name string
+	_ = db.NewSelect().Table(table).Column("name").Where("id = ?", id).Scan(ctx, &name)
+	return name
+}
+
+func findPricelistEntry(entries []models.PricelistEntry, pricelistID string) *models.PricelistEntry {
+	if pricelistID == "" {
+		return nil
+	}
+	for i := range entries {
+		if entries[i].PricelistID == pricelistID {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// formatRupiah matches the frontend's id-ID currency format: "Rp 12.500".
+func formatRupiah(v float64) string {
+	n := int64(math.Round(v))
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	digits := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	return sign + "Rp " + b.String()
+}
+
+func promoKindLabel(kind string) string {
+	switch kind {
+	case "discount":
+		return "Diskon"
+	case "combo":
+		return "Paket combo"
+	case "bogo":
+		return "Beli N gratis M"
+	case "member-tier":
+		return "Diskon member"
+	case "expiring-batch":
+		return "Diskon mau expired"
+	}
+	return kind
+}
+
+// promoDiscountText is the big headline on a promo label, following the
+// frontend's shortPromoLabel but with full rupiah amounts.
+func promoDiscountText(pm *models.Promotion) string {
+	amount := func(unit *string, value *float64) string {
+		v := 0.0
+		if value != nil {
+			v = *value
+		}
+		if unit != nil && *unit == "percent" {
+			return "-" + strconv.FormatFloat(v, 'f', -1, 64) + "%"
+		}
+		return "-" + formatRupiah(v)
+	}
+	switch pm.Kind {
+	case "discount":
+		return amount(pm.DiscountUnit, pm.DiscountValue)
+	case "combo":
+		if pm.ComboPrice != nil {
+			return formatRupiah(*pm.ComboPrice)
+		}
+		return "Combo"
+	case "bogo":
+		buy, get := 1.0, 1.0
+		if pm.BuyQuantity != nil {
+			buy = *pm.BuyQuantity
+		}
+		if pm.GetQuantity != nil {
+			get = *pm.GetQuantity
+		}
+		return fmt.Sprintf("Beli %s gratis %s",
+			strconv.FormatFloat(buy, 'f', -1, 64), strconv.FormatFloat(get, 'f', -1, 64))
+	case "member-tier":
+		if pm.MemberPercentOff != nil {
+			return "-" + strconv.FormatFloat(*pm.MemberPercentOff, 'f', -1, 64) + "%"
+		}
+	case "expiring-batch":
+		return amount(pm.ExpiryDiscountUnit, pm.ExpiryDiscountValue)
+	}
+	return "Promo"
+}
@@ -21,14 +21,15 @@ func NewLocationsHandler(deps Deps) *LocationsHandler {
 }
 
 type locationInput struct {
-	Name             string `json:"name"`
-	Slug             string `json:"slug"`
-	Kind             string `json:"kind"`
-	CustomerVisible  bool   `json:"customerVisible"`
-	IsDefaultReceipt bool   `json:"isDefaultReceipt"`
-	DisplayOrder     int    `json:"displayOrder"`
-	Description      string `json:"description"`
-	Status           string `json:"status"`
+	StoreID          *uuid.UUID `json:"storeId"`
+	Name             string     `json:"name"`
+	Slug             string     `json:"slug"`
+	Kind             string     `json:"kind"`
+	CustomerVisible  bool       `json:"customerVisible"`
+	IsDefaultReceipt bool       `json:"isDefaultReceipt"`
+	DisplayOrder     int        `json:"displayOrder"`
+	Description      string     `json:"description"`
+	Status           string     `json:"status"`
 }
 
 var allowedLocationKinds = map[string]struct{}{
@@ -39,7 +40,9 @@ var allowedLocationKinds = map[string]struct{}{
 
 func (h *LocationsHandler) List(w http.ResponseWriter, r *http.Request) {
 	items := []models.Location{}
-	if err := h.deps.DB.NewSelect().Model(&items).Order("display_order ASC, name ASC").Scan(r.Context()); err != nil {
+	q := h.deps.DB.NewSelect().Model(&items).Order("display_order ASC, name ASC")
+	q = scopeStore(r.Context(), q, "l.store_id")
+	if err := q.Scan(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -80,9 +83,19 @@ func (h *LocationsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Description:      strings.TrimSpace(in.Description),
 		Status:           locationStatusOrDefault(in.Status),
 	}
+	if in.StoreID != nil {
+		loc.StoreID = *in.StoreID
+	} else {
+		id, err := requireStore(r.Context())
+		if err != nil {
+			writeTxError(w, err)
+			return
+		}
+		loc.StoreID = id
+	}
 	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		if loc.IsDefaultReceipt {
-			if err := clearDefaultReceipt(ctx, tx); err != nil {
+			if err := clearDefaultReceipt(ctx, tx, loc.StoreID); err != nil {
 				return err
 			}
 		}
@@ -113,10 +126,12 @@ func (h *LocationsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		if in.IsDefaultReceipt {
-			// Demote any other default so the partial unique index isn't violated.
+			// Demote the store's other default so the partial unique index
+			// isn't violated.
 			if _, err := tx.NewUpdate().Table("locations").
 				Set("is_default_receipt = false").
 				Where("is_default_receipt = true AND id <> ?", id).
+				Where("store_id = (SELECT store_id FROM locations WHERE id = ?)", id).
 				Exec(ctx); err != nil {
 				return err
 			}
@@ -171,16 +186,17 @@ func (h *LocationsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "lokasi default penerimaan tidak bisa dihapus")
 		return
 	}
-	// Block the delete if this is the last location — at least one is required
-	// because PO receiving needs somewhere to put stock.
+	// Block the delete if this is the store's last location — at least one
+	// is required because PO receiving needs somewhere to put stock.
 	var total int
 	if err := h.deps.DB.NewSelect().Table("locations").
-		ColumnExpr("count(*)").Scan(r.Context(), &total); err != nil {
+		ColumnExpr("count(*)").
+		Where("store_id = ?", existing.StoreID).Scan(r.Context(), &total); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	if total <= 1 {
-		writeError(w, http.StatusBadRequest, "minimal harus ada satu lokasi")
+		writeError(w, http.StatusBadRequest, "minimal harus ada satu lokasi per toko")
 		return
 	}
 	res, err := h.deps.DB.NewDelete().Model((*models.Location)(nil)).Where("id = ?", id).Exec(r.Context())
@@ -199,10 +215,10 @@ func (h *LocationsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func clearDefaultReceipt(ctx context.Context, tx bun.Tx) error {
+func clearDefaultReceipt(ctx context.Context, tx bun.Tx, storeID uuid.UUID) error {
 	_, err := tx.NewUpdate().Table("locations").
 		Set("is_default_receipt = false").
-		Where("is_default_receipt = true").
+		Where("is_default_receipt = true AND store_id = ?", storeID).
 		Exec(ctx)
 	return err
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -57,8 +58,20 @@ func (h *OrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	in.ID = uuid.Nil
 	normalizeOrder(&in)
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	in.StoreID = storeID
+	settings, err := loadServerSettings(r.Context(), h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
 
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		code, err := nextOrderCode(ctx, tx)
 		if err != nil {
 			return err
@@ -67,10 +80,25 @@ func (h *OrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		if _, err := tx.NewInsert().Model(&in).Returning("*").Exec(ctx); err != nil {
 			return err
 		}
-		return saveOrderChildren(ctx, tx, &in)
+		// The sale now owns the stock; drop the cart's holds with it so
+		// the draw below only steps around other holders' reservations.
+		if in.CartSessionID != "" {
+			if err := releaseReservations(ctx, tx, models.ReservationHolderCart, in.CartSessionID); err != nil {
+				return err
+			}
+		}
+		if in.Status != models.OrderStatusCancelled {
+			if err := allocateOrderStock(ctx, tx, &in, settings.Inventory.AllocationStrategy, performedBy); err != nil {
+				return err
+			}
+		}
+		if err := saveOrderChildren(ctx, tx, &in); err != nil {
+			return err
+		}
+		return syncOrderSerials(ctx, tx, &in)
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeTxError(w, err)
 		return
 	}
 	full, err := loadOrder(r.Context(), h.deps.DB, in.ID)
@@ -94,10 +122,49 @@ func (h *OrdersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	in.ID = id
 	normalizeOrder(&in)
+	settings, err := loadServerSettings(r.Context(), h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
 
 	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var prev models.Order
+		if err := tx.NewSelect().Model(&prev).Column("id", "code", "store_id", "status").
+			Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errNotFound
+			}
+			return err
+		}
+		if prev.Status == models.OrderStatusCancelled && in.Status != models.OrderStatusCancelled {
+			return errBadInput("pesanan yang sudah dibatalkan tidak bisa diaktifkan lagi")
+		}
+		if err := tx.NewSelect().Model(&prev.Lines).
+			Where("order_id = ?", id).Scan(ctx); err != nil {
+			return err
+		}
+		// Batch allocations are written by the sale itself, never taken
+		// from the client: unchanged lines keep the stored ones and
+		// edited lines are drawn again. A cancellation keeps them so the
+		// restore below returns exactly what was taken.
+		if in.Status == models.OrderStatusCancelled || prev.Status == models.OrderStatusCancelled {
+			allocs := make(map[uuid.UUID][]models.BatchAllocation, len(prev.Lines))
+			for _, l := range prev.Lines {
+				allocs[l.ID] = l.BatchAllocations
+			}
+			for i := range in.Lines {
+				in.Lines[i].BatchAllocations = allocs[in.Lines[i].ID]
+			}
+		} else if err := reallocateOrderLines(ctx, tx, &prev, &in, settings.Inventory.AllocationStrategy, performedBy); err != nil {
+			return err
+		}
+		if err := checkSettledOrderEdit(ctx, tx, &in); err != nil {
+			return err
+		}
 		res, err := tx.NewUpdate().Model(&in).WherePK().
-			ExcludeColumn("id", "code", "created_at", "updated_at").
+			ExcludeColumn("id", "code", "store_id", "created_at", "updated_at").
 			Set("updated_at = current_timestamp").Exec(ctx)
 		if err != nil {
 			return err
@@ -105,14 +172,23 @@ func (h *OrdersHandler) Update(w http.ResponseWriter, r *http.Request) {
 		if n, _ := res.RowsAffected(); n == 0 {
 			return errNotFound
 		}
-		return saveOrderChildren(ctx, tx, &in)
+		// The order's stock is already drawn with its lines, so any hold
+		// still kept under the order only blocks it a second time.
+		if err := releaseReservations(ctx, tx, models.ReservationHolderOrder, id.String()); err != nil {
+			return err
+		}
+		if in.Status == models.OrderStatusCancelled && prev.Status != models.OrderStatusCancelled {
+			if err := restoreOrderStock(ctx, tx, &prev, performedBy); err != nil {
+				return err
+			}
+		}
+		if err := saveOrderChildren(ctx, tx, &in); err != nil {
+			return err
+		}
+		return syncOrderSerials(ctx, tx, &in)
 	})
-	if errors.Is(err, errNotFound) {
-		writeError(w, http.StatusNotFound, "not found")
-		return
-	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeTxError(w, err)
 		return
 	}
 	full, err := loadOrder(r.Context(), h.deps.DB, id)
@@ -165,6 +241,7 @@ func nextOrderCode(ctx context.Context, tx bun.Tx) (string, error) {
 func loadOrders(ctx context.Context, db *bun.DB, q map[string][]string) ([]models.Order, error) {
 	var orders []models.Order
 	qb := db.NewSelect().Model(&orders).Order("created_at DESC")
+	qb = scopeStore(ctx, qb, "o.store_id")
 	if vals := q["status"]; len(vals) > 0 && vals[0] != "" {
 		qb = qb.Where("status = ?", vals[0])
 	}
@@ -266,6 +343,9 @@ func syncOrderLines(ctx context.Context, tx bun.Tx, o *models.Order) error {
 		if l.BatchAllocations == nil {
 			l.BatchAllocations = []models.BatchAllocation{}
 		}
+		if l.Serials == nil {
+			l.Serials = []string{}
+		}
 		if l.ID != uuid.Nil && existingByID[l.ID] {
 			incomingByID[l.ID] = true
 			if _, err := tx.NewUpdate().Model(l).WherePK().Exec(ctx); err != nil {
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// productionModeStrict composites never fall back to their recipe at sale
+// time; only produced stock can be sold.
+const productionModeStrict = "strict"
+
+// orderStockDraw carries what every sale movement of one order shares.
+type orderStockDraw struct {
+	storeID     uuid.UUID
+	strategy    string
+	ref         models.StockMovementReference
+	performedBy string
+	notes       string
+}
+
+// allocateOrderStock draws each line's base quantity from sellable batches
+// at the order's store in allocation-strategy order, skipping what live
+// reservations hold, and stamps the snapshots on line.BatchAllocations.
+// Composites sell their own produced stock first and, unless strict, fall
+// back to the recipe for the rest; extras with components draw those per
+// unit sold. A line that can't be fully covered fails the whole sale.
+func allocateOrderStock(ctx context.Context, tx bun.Tx, o *models.Order, strategy, performedBy string) error {
+	d := orderStockDraw{
+		storeID:     o.StoreID,
+		strategy:    strategy,
+		ref:         models.StockMovementReference{Kind: models.MovementRefOrder, ID: o.ID.String(), Code: o.Code},
+		performedBy: performedBy,
+		notes:       "Penjualan · " + o.Code,
+	}
+	for i := range o.Lines {
+		l := &o.Lines[i]
+		factor := l.UnitFactor
+		if factor <= 0 {
+			factor = 1
+		}
+		allocs, err := d.draw(ctx, tx, l.ProductID, l.VariantID, l.Quantity*factor)
+		if err != nil {
+			return err
+		}
+		for _, ex := range l.Extras {
+			extraID, err := uuid.Parse(ex.ID)
+			if err != nil {
+				continue
+			}
+			var comps []models.ProductComponentRow
+			if err := tx.NewSelect().Model(&comps).
+				Where("product_id = ?", l.ProductID).
+				Where("extra_id = ?", extraID).
+				Order("position ASC").Scan(ctx); err != nil {
+				return err
+			}
+			more, err := d.drawComponents(ctx, tx, comps, l.Quantity)
+			if err != nil {
+				return err
+			}
+			allocs = append(allocs, more...)
+		}
+		if allocs == nil {
+			allocs = []models.BatchAllocation{}
+		}
+		l.BatchAllocations = allocs
+	}
+	return nil
+}
+
+func (d orderStockDraw) drawComponents(
+	ctx context.Context, tx bun.Tx, comps []models.ProductComponentRow, multiplier float64,
+) ([]models.BatchAllocation, error) {
+	var out []models.BatchAllocation
+	for _, c := range comps {
+		factor := 1.0
+		if c.UnitFactor != nil && *c.UnitFactor > 0 {
+			factor = *c.UnitFactor
+		}
+		allocs, err := d.draw(ctx, tx, c.ComponentProductID, c.ComponentVariantID, c.Quantity*factor*multiplier)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, allocs...)
+	}
+	return out, nil
+}
+
+// draw takes qty base units of (product, variant?), recursing into the
+// recipe for a flexible composite's shortfall.
+func (d orderStockDraw) draw(
+	ctx context.Context, tx bun.Tx, productID uuid.UUID, variantID *uuid.UUID, qty float64,
+) ([]models.BatchAllocation, error) {
+	if qty <= 1e-9 {
+		return nil, nil
+	}
+	var p models.Product
+	if err := tx.NewSelect().Model(&p).
+		Column("id", "name", "kind", "production_mode").
+		Where("id = ?", productID).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errBadInput("produk tidak ditemukan")
+		}
+		return nil, err
+	}
+
+	batches, heldBy, err := lockSellableBatches(ctx, tx, d.storeID, productID, variantID, d.strategy)
+	if err != nil {
+		return nil, err
+	}
+	var out []models.BatchAllocation
+	need := qty
+	for i := range batches {
+		if need <= 1e-9 {
+			break
+		}
+		b := &batches[i]
+		free := b.QtyRemaining - heldBy[b.ID]
+		if free <= 1e-9 {
+			continue
+		}
+		take := math.Min(need, free)
+		b.QtyRemaining -= take
+		if _, err := tx.NewUpdate().Table("batches").
+			Where("id = ?", b.ID).
+			Set("qty_remaining = ?", b.QtyRemaining).
+			Set("updated_at = current_timestamp").
+			Exec(ctx); err != nil {
+			return nil, err
+		}
+		if err := logMovement(ctx, tx, &models.StockMovement{
+			Kind:        models.MovementKindSale,
+			ProductID:   &b.ProductID,
+			VariantID:   b.VariantID,
+			LocationID:  &b.LocationID,
+			BatchID:     &b.ID,
+			QtyDelta:    -take,
+			QtyAfter:    b.QtyRemaining,
+			UnitCost:    floatPtr(b.UnitCost),
+			Reference:   d.ref,
+			PerformedBy: d.performedBy,
+			Notes:       d.notes,
+		}); err != nil {
+			return nil, err
+		}
+		var supplierID *string
+		if b.SupplierID != nil {
+			s := b.SupplierID.String()
+			supplierID = &s
+		}
+		out = append(out, models.BatchAllocation{
+			BatchID:    b.ID.String(),
+			QtyTaken:   take,
+			Ownership:  b.Ownership,
+			UnitCost:   b.UnitCost,
+			SupplierID: supplierID,
+		})
+		need -= take
+	}
+	if need <= 1e-9 {
+		return out, nil
+	}
+
+	if p.Kind == models.ProductKindComposite {
+		mode := p.ProductionMode
+		if variantID != nil {
+			var vm sql.NullString
+			if err := tx.NewSelect().Table("product_variants").Column("production_mode").
+				Where("id = ?", *variantID).Scan(ctx, &vm); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			if vm.Valid {
+				mode = &vm.String
+			}
+		}
+		if mode == nil || *mode != productionModeStrict {
+			recipe, err := loadRecipe(ctx, tx, productID, variantID)
+			if err != nil {
+				return nil, err
+			}
+			if len(recipe) > 0 {
+				more, err := d.drawComponents(ctx, tx, recipe, need)
+				if err != nil {
+					return nil, err
+				}
+				return append(out, more...), nil
+			}
+		}
+	}
+	return nil, errBadInput(fmt.Sprintf("stok %s tidak cukup: tersedia %.4g, diminta %.4g",
+		p.Name, qty-need, qty))
+}
+
+// reallocateOrderLines re-draws what an edit changed. Lines that keep their
+// product, variant, base quantity and extras keep their stored allocations;
+// removed and changed lines go back on their batches first, then changed
+// and new lines draw afresh at the order's store.
+func reallocateOrderLines(ctx context.Context, tx bun.Tx, prev, o *models.Order, strategy, performedBy string) error {
+	before := make(map[uuid.UUID]*models.OrderLine, len(prev.Lines))
+	for i := range prev.Lines {
+		before[prev.Lines[i].ID] = &prev.Lines[i]
+	}
+	returned := models.Order{ID: prev.ID, Code: prev.Code}
+	drawn := models.Order{ID: prev.ID, Code: prev.Code, StoreID: prev.StoreID}
+	var redraw []int
+	for i := range o.Lines {
+		l := &o.Lines[i]
+		if old, ok := before[l.ID]; ok {
+			delete(before, l.ID)
+			if sameLineStock(old, l) {
+				l.BatchAllocations = old.BatchAllocations
+				continue
+			}
+			returned.Lines = append(returned.Lines, *old)
+		}
+		redraw = append(redraw, i)
+		drawn.Lines = append(drawn.Lines, *l)
+	}
+	for _, old := range before {
+		returned.Lines = append(returned.Lines, *old)
+	}
+	if err := restoreOrderStock(ctx, tx, &returned, performedBy); err != nil {
+		return err
+	}
+	if err := allocateOrderStock(ctx, tx, &drawn, strategy, performedBy); err != nil {
+		return err
+	}
+	for j, i := range redraw {
+		o.Lines[i].BatchAllocations = drawn.Lines[j].BatchAllocations
+	}
+	return nil
+}
+
+// sameLineStock reports whether an edited line still draws exactly what
+// the stored one did.
+func sameLineStock(a, b *models.OrderLine) bool {
+	if a.ProductID != b.ProductID || !sameVariant(a.VariantID, b.VariantID) ||
+		math.Abs(lineBaseQty(a)-lineBaseQty(b)) >= 1e-9 || len(a.Extras) != len(b.Extras) {
+		return false
+	}
+	for i := range a.Extras {
+		if a.Extras[i].ID != b.Extras[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func sameVariant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func lineBaseQty(l *models.OrderLine) float64 {
+	if l.UnitFactor <= 0 {
+		return l.Quantity
+	}
+	return l.Quantity * l.UnitFactor
+}
+
+// restoreOrderStock puts an order's allocations back on their batches and
+// logs one sale-cancel movement each; Update passes only the lines an edit
+// dropped or changed. Batches deleted since the sale are skipped.
+func restoreOrderStock(ctx context.Context, tx bun.Tx, o *models.Order, performedBy string) error {
+	taken := map[uuid.UUID]float64{}
+	cost := map[uuid.UUID]float64{}
+	var ids []uuid.UUID
+	for _, l := range o.Lines {
+		for _, a := range l.BatchAllocations {
+			id, err := uuid.Parse(a.BatchID)
+			if err != nil || a.QtyTaken <= 0 {
+				continue
+			}
+			if _, seen := taken[id]; !seen {
+				ids = append(ids, id)
+				cost[id] = a.UnitCost
+			}
+			taken[id] += a.QtyTaken
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var batches []models.Batch
+	if err := tx.NewSelect().Model(&batches).
+		Where("id IN (?)", bun.In(ids)).
+		Order("id ASC").
+		For("UPDATE").
+		Scan(ctx); err != nil {
+		return err
+	}
+	ref := models.StockMovementReference{Kind: models.MovementRefOrder, ID: o.ID.String(), Code: o.Code}
+	for i := range batches {
+		b := &batches[i]
+		b.QtyRemaining += taken[b.ID]
+		if _, err := tx.NewUpdate().Table("batches").
+			Where("id = ?", b.ID).
+			Set("qty_remaining = ?", b.QtyRemaining).
+			Set("updated_at = current_timestamp").
+			Exec(ctx); err != nil {
+			return err
+		}
+		if err := logMovement(ctx, tx, &models.StockMovement{
+			Kind:        models.MovementKindSaleCancel,
+			ProductID:   &b.ProductID,
+			VariantID:   b.VariantID,
+			LocationID:  &b.LocationID,
+			BatchID:     &b.ID,
+			QtyDelta:    taken[b.ID],
+			QtyAfter:    b.QtyRemaining,
+			UnitCost:    floatPtr(cost[b.ID]),
+			Reference:   ref,
+			PerformedBy: performedBy,
+			Notes:       "Pembatalan pesanan · " + o.Code,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/documents"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// settlementLine is one consignment allocation on a paid sale: the units a
+// line drew from one of the consignor's batches, at the consignment cost
+// stamped on the sale.
+type settlementLine struct {
+	OrderID     uuid.UUID `bun:"order_id" json:"orderId"`
+	OrderCode   string    `bun:"order_code" json:"orderCode"`
+	OrderLineID uuid.UUID `bun:"order_line_id" json:"orderLineId"`
+	SoldAt      string    `bun:"sold_at" json:"soldAt"`
+	ProductName string    `bun:"product_name" json:"productName"`
+	VariantName string    `bun:"variant_name" json:"variantName,omitempty"`
+	BatchID     uuid.UUID `bun:"batch_id" json:"batchId"`
+	BatchCode   string    `bun:"batch_code" json:"batchCode"`
+	Qty         float64   `bun:"qty" json:"qty"`
+	UnitCost    float64   `bun:"unit_cost" json:"unitCost"`
+	Amount      float64   `bun:"amount" json:"amount"`
+}
+
+type settlementReturn struct {
+	ID         uuid.UUID `bun:"id" json:"id"`
+	Code       string    `bun:"code" json:"code"`
+	ReturnedAt string    `bun:"returned_at" json:"returnedAt"`
+	Qty        float64   `bun:"total_qty" json:"qty"`
+	Value      float64   `bun:"total_value" json:"value"`
+}
+
+// settlementStatement is what a consignor is owed for a period: the
+// allocations sold in it that no payout has settled yet. Returns in the
+// period and earlier payouts are listed for reference; returned units were
+// never sold, so they are not owed and not deducted.
+type settlementStatement struct {
+	SupplierID   uuid.UUID `json:"supplierId"`
+	SupplierName string    `json:"supplierName"`
+	Start        string    `json:"start"`
+	End          string    `json:"end"`
+
+	Lines     []settlementLine `json:"lines"`
+	SoldUnits float64          `json:"soldUnits"`
+	Owed      float64          `json:"owed"`
+
+	// Sold in the period but already settled by an earlier payout.
+	SettledUnits  float64 `json:"settledUnits"`
+	SettledAmount float64 `json:"settledAmount"`
+	// Unsettled sales from before start, left for another statement.
+	EarlierUnsettled float64 `json:"earlierUnsettled"`
+
+	Returns       []settlementReturn `json:"returns"`
+	ReturnedUnits float64            `json:"returnedUnits"`
+	ReturnedValue float64            `json:"returnedValue"`
+
+	PreviousPayouts []models.Payout `json:"previousPayouts"`
+	PreviousPaid    float64         `json:"previousPaid"`
+}
+
+// consignmentAllocations selects a consignor's allocations on the active
+// store's paid sales, one row per order line and batch.
+func consignmentAllocations(ctx context.Context, db bun.IDB, supplierID uuid.UUID) *bun.SelectQuery {
+	q := db.NewSelect().
+		TableExpr("orders AS o").
+		Join("JOIN order_lines AS ol ON ol.order_id = o.id").
+		Join("CROSS JOIN LATERAL jsonb_array_elements(ol.batch_allocations) AS a").
+		Join("JOIN batches AS bt ON bt.id::text = a->>'batchId'").
+		ColumnExpr("o.id AS order_id, o.code AS order_code, o.created_at::date::text AS sold_at").
+		ColumnExpr("ol.id AS order_line_id, ol.product_name, ol.variant_name").
+		ColumnExpr("bt.id AS batch_id, bt.code AS batch_code").
+		ColumnExpr("SUM((a->>'qtyTaken')::numeric) AS qty").
+		ColumnExpr("MAX((a->>'unitCost')::numeric) AS unit_cost").
+		ColumnExpr("SUM((a->>'qtyTaken')::numeric * (a->>'unitCost')::numeric) AS amount").
+		Where("o.status = ?", models.OrderStatusPaid).
+		Where("a->>'ownership' = ?", models.BatchOwnershipConsignment).
+		Where("a->>'supplierId' = ?", supplierID.String()).
+		Where("(a->>'qtyTaken')::numeric > 0").
+		GroupExpr("o.id, o.code, o.created_at, ol.id, ol.product_name, ol.variant_name, bt.id, bt.code")
+	return scopeStore(ctx, q, "o.store_id")
+}
+
+const unsettledAllocation = `NOT EXISTS (SELECT 1 FROM payout_allocations AS pa
+	WHERE pa.order_line_id = ol.id AND pa.batch_id = bt.id)`
+
+// buildSettlement computes the statement for one consignor and period
+// (inclusive dates; an empty start reaches back to the first sale). Sales,
+// returns and payouts are the active store's, since a payout settles one
+// store's sales; the consolidated view sums every store.
+func buildSettlement(
+	ctx context.Context, db bun.IDB, supplierID uuid.UUID, start, end string,
+) (*settlementStatement, error) {
+	st := &settlementStatement{
+		SupplierID:      supplierID,
+		SupplierName:    lookupName(ctx, db, "suppliers", supplierID),
+		Start:           start,
+		End:             end,
+		Lines:           []settlementLine{},
+		Returns:         []settlementReturn{},
+		PreviousPayouts: []models.Payout{},
+	}
+
+	q := consignmentAllocations(ctx, db, supplierID).
+		Where(unsettledAllocation).
+		Where("o.created_at::date <= ?", end).
+		OrderExpr("o.created_at ASC, ol.position ASC")
+	if start != "" {
+		q = q.Where("o.created_at::date >= ?", start)
+	}
+	if err := q.Scan(ctx, &st.Lines); err != nil {
+		return nil, err
+	}
+	for _, l := range st.Lines {
+		st.SoldUnits += l.Qty
+		st.Owed += l.Amount
+	}
+	st.Owed = roundMoney(st.Owed)
+
+	var settled struct {
+		Units  float64 `bun:"units"`
+		Amount float64 `bun:"amount"`
+	}
+	sq := db.NewSelect().TableExpr("payout_allocations AS pa").
+		Join("JOIN payouts AS py ON py.id = pa.payout_id").
+		ColumnExpr("COALESCE(SUM(pa.qty), 0) AS units, COALESCE(SUM(pa.amount), 0) AS amount").
+		Where("py.supplier_id = ?", supplierID).
+		Where("pa.sold_at <= ?", end)
+	if start != "" {
+		sq = sq.Where("pa.sold_at >= ?", start)
+	}
+	if err := scopeStore(ctx, sq, "py.store_id").Scan(ctx, &settled); err != nil {
+		return nil, err
+	}
+	st.SettledUnits, st.SettledAmount = settled.Units, settled.Amount
+
+	if start != "" {
+		if err := db.NewSelect().
+			TableExpr("(?) AS u", consignmentAllocations(ctx, db, supplierID).
+				Where(unsettledAllocation).
+				Where("o.created_at::date < ?", start)).
+			ColumnExpr("COALESCE(SUM(u.amount), 0)").
+			Scan(ctx, &st.EarlierUnsettled); err != nil {
+			return nil, err
+		}
+	}
+
+	rq := db.NewSelect().Table("consignor_returns").
+		Column("id", "code", "returned_at", "total_qty", "total_value").
+		Where("supplier_id = ?", supplierID).
+		Where("returned_at <= ?", end).
+		Order("returned_at ASC")
+	if start != "" {
+		rq = rq.Where("returned_at >= ?", start)
+	}
+	if err := scopeStore(ctx, rq, "store_id").Scan(ctx, &st.Returns); err != nil {
+		return nil, err
+	}
+	for _, r := range st.Returns {
+		st.ReturnedUnits += r.Qty
+		st.ReturnedValue += r.Value
+	}
+
+	pq := db.NewSelect().Model(&st.PreviousPayouts).
+		Where("py.supplier_id = ?", supplierID).
+		Order("py.paid_at DESC", "py.created_at DESC")
+	if err := scopeStore(ctx, pq, "py.store_id").Scan(ctx); err != nil {
+		return nil, err
+	}
+	for _, p := range st.PreviousPayouts {
+		st.PreviousPaid += p.Amount
+	}
+	return st, nil
+}
+
+// settlementPeriod reads start/end (YYYY-MM-DD). end defaults to today.
+func settlementPeriod(start, end string) (string, string, error) {
+	start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+	if end == "" {
+		end = time.Now().Format("2006-01-02")
+	}
+	for _, d := range []string{start, end} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return "", "", errBadInput("periode harus YYYY-MM-DD")
+		}
+	}
+	if start != "" && start > end {
+		return "", "", errBadInput("awal periode setelah akhir periode")
+	}
+	return start, end, nil
+}
+
+// Settlement is the statement for ?supplierId= over ?start=..?end= (end
+// defaults to today): the consignment sales not yet paid out, what they
+// owe, and the period's returns and earlier payouts for reference.
+func (h *PayoutsHandler) Settlement(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	supplierID, err := uuid.Parse(strings.TrimSpace(q.Get("supplierId")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "supplierId wajib diisi")
+		return
+	}
+	start, end, err := settlementPeriod(q.Get("start"), q.Get("end"))
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	st, err := buildSettlement(r.Context(), h.deps.DB, supplierID, start, end)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+type payoutDetail struct {
+	models.Payout
+	SupplierName string           `json:"supplierName"`
+	Lines        []settlementLine `json:"lines"`
+}
+
+func loadPayoutDetail(ctx context.Context, db bun.IDB, id uuid.UUID) (*payoutDetail, error) {
+	var d payoutDetail
+	if err := db.NewSelect().Model(&d.Payout).Where("id = ?", id).Scan(ctx); err != nil {
+		return nil, err
+	}
+	d.SupplierName = lookupName(ctx, db, "suppliers", d.SupplierID)
+	d.Lines = []settlementLine{}
+	if err := db.NewSelect().TableExpr("payout_allocations AS pa").
+		Join("JOIN orders AS o ON o.id = pa.order_id").
+		Join("JOIN order_lines AS ol ON ol.id = pa.order_line_id").
+		Join("JOIN batches AS bt ON bt.id = pa.batch_id").
+		ColumnExpr("pa.order_id, o.code AS order_code, pa.sold_at").
+		ColumnExpr("pa.order_line_id, ol.product_name, ol.variant_name").
+		ColumnExpr("pa.batch_id, bt.code AS batch_code").
+		ColumnExpr("pa.qty, pa.unit_cost, pa.amount").
+		Where("pa.payout_id = ?", id).
+		OrderExpr("pa.sold_at ASC, o.code ASC, ol.position ASC").
+		Scan(ctx, &d.Lines); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Get returns a payout with the sales it settled.
+func (h *PayoutsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	d, err := loadPayoutDetail(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Statement prints a payout's settlement for the consignor to sign: every
+// sale it settled, the period's returns and the amount paid.
+func (h *PayoutsHandler) Statement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx := r.Context()
+	d, err := loadPayoutDetail(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	rows := make([][]string, 0, len(d.Lines))
+	for i, l := range d.Lines {
+		name := l.ProductName
+		if l.VariantName != "" {
+			name += " - " + l.VariantName
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(i + 1), labelDate(l.SoldAt), l.OrderCode, name, l.BatchCode,
+			formatDecimal(l.Qty), formatRupiah(l.UnitCost), formatRupiah(l.Amount),
+		})
+	}
+	period := labelDate(d.CoversPeriodStart) + " – " + labelDate(d.CoversPeriodEnd)
+	if d.CoversPeriodStart == "" {
+		period = "s.d. " + labelDate(d.CoversPeriodEnd)
+	}
+	summary := []documents.Field{
+		{Label: "Unit terjual", Value: formatDecimal(d.SoldUnits)},
+		{Label: "Nilai setoran", Value: formatRupiah(d.SettledAmount)},
+	}
+	if d.ReturnedUnits > 0 {
+		summary = append(summary, documents.Field{
+			Label: "Retur ke pemasok (tidak ditagihkan)",
+			Value: formatDecimal(d.ReturnedUnits) + " unit · " + formatRupiah(d.ReturnedValue),
+		})
+	}
+	summary = append(summary, documents.Field{Label: "Dibayar", Value: formatRupiah(d.Amount)})
+
+	pdf := documents.PDF(documents.Document{
+		Title: "Laporan Setoran Konsinyasi",
+		Code:  d.Code,
+		Meta: []documents.Field{
+			{Label: "Konsinyor", Value: d.SupplierName},
+			{Label: "Periode", Value: period},
+			{Label: "Tanggal bayar", Value: labelDate(d.PaidAt)},
+			{Label: "Metode", Value: d.Method},
+		},
+		Columns: []documents.Column{
+			{Title: "No", Width: 3, Right: true},
+			{Title: "Tanggal", Width: 8},
+			{Title: "Penjualan", Width: 12},
+			{Title: "Produk", Width: 24},
+			{Title: "Batch", Width: 12},
+			{Title: "Qty", Width: 6, Right: true},
+			{Title: "Harga setor", Width: 10, Right: true},
+			{Title: "Nilai", Width: 11, Right: true},
+		},
+		Rows:    rows,
+		Totals:  []string{"", "", "", "Total", "", formatDecimal(d.SoldUnits), "", formatRupiah(d.SettledAmount)},
+		Summary: summary,
+		Notes:   d.Notes,
+		Signatures: []documents.Signature{
+			{Caption: "Dibayar oleh", Name: d.CreatedBy},
+			{Caption: "Diterima oleh (" + d.SupplierName + ")"},
+		},
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", d.Code+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(pdf)
+}
+
+// settlePayout locks the consignor, then stamps every unsettled allocation
+// in the payout's period and store (the request's active store) onto it. The payout's amount must match what they
+// owe; zero takes the owed amount.
+func settlePayout(ctx context.Context, tx bun.Tx, p *models.Payout) error {
+	if err := tx.NewSelect().Table("suppliers").Column("id").
+		Where("id = ?", p.SupplierID).For("UPDATE").Scan(ctx, new(uuid.UUID)); err != nil {
+		return errBadInput("pemasok tidak ditemukan")
+	}
+	st, err := buildSettlement(ctx, tx, p.SupplierID, p.CoversPeriodStart, p.CoversPeriodEnd)
+	if err != nil {
+		return err
+	}
+	if len(st.Lines) == 0 {
+		return errBadInput("tidak ada penjualan konsinyasi yang belum disetor pada periode ini")
+	}
+	if p.Amount <= 0 {
+		p.Amount = st.Owed
+	}
+	if math.Abs(p.Amount-st.Owed) > 0.005 {
+		return errBadInput("jumlah setoran harus " + formatRupiah(st.Owed) + " sesuai laporan periode ini")
+	}
+	p.SoldUnits = st.SoldUnits
+	p.SettledAmount = st.Owed
+	p.ReturnedUnits = st.ReturnedUnits
+	p.ReturnedValue = roundMoney(st.ReturnedValue)
+	if _, err := tx.NewInsert().Model(p).Returning("*").Exec(ctx); err != nil {
+		return err
+	}
+	allocs := make([]models.PayoutAllocation, len(st.Lines))
+	for i, l := range st.Lines {
+		allocs[i] = models.PayoutAllocation{
+			PayoutID:    p.ID,
+			OrderID:     l.OrderID,
+			OrderLineID: l.OrderLineID,
+			BatchID:     l.BatchID,
+			Qty:         l.Qty,
+			UnitCost:    l.UnitCost,
+			Amount:      roundMoney(l.Amount),
+			SoldAt:      l.SoldAt,
+		}
+	}
+	_, err = tx.NewInsert().Model(&allocs).Exec(ctx)
+	return err
+}
+
+// checkSettledOrderEdit refuses order edits that would undo a sale a
+// payout already settled: cancelling it, or taking units off a settled
+// allocation.
+func checkSettledOrderEdit(ctx context.Context, tx bun.Tx, o *models.Order) error {
+	var settled []struct {
+		OrderLineID uuid.UUID `bun:"order_line_id"`
+		BatchID     uuid.UUID `bun:"batch_id"`
+		Qty         float64   `bun:"qty"`
+		PayoutCode  string    `bun:"payout_code"`
+	}
+	if err := tx.NewSelect().TableExpr("payout_allocations AS pa").
+		Join("JOIN payouts AS py ON py.id = pa.payout_id").
+		ColumnExpr("pa.order_line_id, pa.batch_id, pa.qty, py.code AS payout_code").
+		Where("pa.order_id = ?", o.ID).
+		Scan(ctx, &settled); err != nil {
+		return err
+	}
+	if len(settled) == 0 {
+		return nil
+	}
+	locked := errBadInput("penjualan ini sudah disetor ke konsinyor (" + settled[0].PayoutCode +
+		"); hapus setorannya dulu untuk mengubahnya")
+	if o.Status != models.OrderStatusPaid {
+		return locked
+	}
+	for _, s := range settled {
+		qty := 0.0
+		for _, l := range o.Lines {
+			if l.ID != s.OrderLineID {
+				continue
+			}
+			for _, a := range l.BatchAllocations {
+				if a.BatchID == s.BatchID.String() {
+					qty += a.QtyTaken
+				}
+			}
+		}
+		if qty+1e-9 < s.Qty {
+			return locked
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -23,7 +24,8 @@ func NewPayoutsHandler(deps Deps) *PayoutsHandler {
 
 func (h *PayoutsHandler) List(w http.ResponseWriter, r *http.Request) {
 	items := []models.Payout{}
-	if err := h.deps.DB.NewSelect().Model(&items).Order("paid_at DESC").Scan(r.Context()); err != nil {
+	q := h.deps.DB.NewSelect().Model(&items).Order("paid_at DESC")
+	if err := scopeStore(r.Context(), q, "py.store_id").Scan(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -40,6 +42,9 @@ type payoutInput struct {
 	Notes              string  `json:"notes"`
 }
 
+// Create records a payout and settles it against the consignor's unpaid
+// sales in coversPeriodStart..coversPeriodEnd (end defaults to the pay
+// date). Amount may be left 0 to pay exactly what the statement owes.
 func (h *PayoutsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var in payoutInput
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
@@ -51,20 +56,37 @@ func (h *PayoutsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "supplierId tidak valid")
 		return
 	}
-	if in.Amount <= 0 {
-		writeError(w, http.StatusBadRequest, "amount harus lebih dari 0")
+	if in.Amount < 0 {
+		writeError(w, http.StatusBadRequest, "amount tidak boleh negatif")
 		return
 	}
 	if in.Method == "" {
 		in.Method = "cash"
 	}
+	if in.PaidAt == "" {
+		in.PaidAt = time.Now().Format("2006-01-02")
+	}
+	if in.CoversPeriodEnd == "" {
+		in.CoversPeriodEnd = in.PaidAt
+	}
+	start, end, err := settlementPeriod(in.CoversPeriodStart, in.CoversPeriodEnd)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
 	p := models.Payout{
+		StoreID:           storeID,
 		SupplierID:        supplierID,
 		Amount:            in.Amount,
 		PaidAt:            in.PaidAt,
 		Method:            in.Method,
-		CoversPeriodStart: in.CoversPeriodStart,
-		CoversPeriodEnd:   in.CoversPeriodEnd,
+		CoversPeriodStart: start,
+		CoversPeriodEnd:   end,
 		Notes:             in.Notes,
 	}
 	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
@@ -73,11 +95,11 @@ func (h *PayoutsHandler) Create(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 		p.Code = code
-		_, err = tx.NewInsert().Model(&p).Returning("*").Exec(ctx)
-		return err
+		p.CreatedBy = actorName(ctx, tx)
+		return settlePayout(ctx, tx, &p)
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeTxError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusCreated, p)
@@ -113,3 +135,141 @@ func nextPayoutCode(ctx context.Context, tx bun.Tx) (string, error) {
 	}
 	return fmt.Sprintf("%s%03d", prefix, count+1), nil
 }
+
+type consignmentOutstandingRow struct {
+	SupplierID    uuid.UUID `bun:"supplier_id" json:"supplierId"`
+	SoldUnits     float64   `bun:"sold_units" json:"soldUnits"`
+	Owed          float64   `bun:"owed" json:"owed"`
+	Unsettled     float64   `bun:"unsettled" json:"unsettled"`
+	ReturnedUnits float64   `bun:"returned_units" json:"returnedUnits"`
+	ReturnedValue float64   `bun:"returned_value" json:"returnedValue"`
+	OnHandUnits   float64   `bun:"on_hand_units" json:"onHandUnits"`
+	Paid          float64   `bun:"paid" json:"paid"`
+	Outstanding   float64   `bun:"-" json:"outstanding"`
+}
+
+// Outstanding is the per-consignor payable for the optional start/end period
+// (inclusive dates). Only sold units are owed — taken from the consignment
+// allocations stamped on paid order lines. Units handed back on consignor
+// returns are reported alongside but never owed, and a return can only take
+// stock that is still on hand, so a returned unit can't also count as sold.
+// Paid sums the payouts made within the same period, like every other
+// figure here. Outstanding is the part of Owed that no payout has settled
+// yet (see payout_allocations), whenever that payout was made. Every
+// figure is the active store's, like the payout list; the consolidated
+// view sums all stores.
+func (h *PayoutsHandler) Outstanding(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end := q.Get("start"), q.Get("end")
+	ctx := r.Context()
+
+	byID := map[uuid.UUID]*consignmentOutstandingRow{}
+	row := func(id uuid.UUID) *consignmentOutstandingRow {
+		if byID[id] == nil {
+			byID[id] = &consignmentOutstandingRow{SupplierID: id}
+		}
+		return byID[id]
+	}
+
+	var sold []consignmentOutstandingRow
+	sq := h.deps.DB.NewSelect().
+		TableExpr("orders AS o").
+		Join("JOIN order_lines AS ol ON ol.order_id = o.id").
+		Join("CROSS JOIN LATERAL jsonb_array_elements(ol.batch_allocations) AS a").
+		ColumnExpr("(a->>'supplierId')::uuid AS supplier_id").
+		ColumnExpr("SUM((a->>'qtyTaken')::numeric) AS sold_units").
+		ColumnExpr("SUM((a->>'qtyTaken')::numeric * (a->>'unitCost')::numeric) AS owed").
+		ColumnExpr(`SUM(CASE WHEN EXISTS (SELECT 1 FROM payout_allocations AS pa
+			WHERE pa.order_line_id = ol.id AND pa.batch_id::text = a->>'batchId')
+			THEN 0 ELSE (a->>'qtyTaken')::numeric * (a->>'unitCost')::numeric END) AS unsettled`).
+		Where("o.status = ?", models.OrderStatusPaid).
+		Where("a->>'ownership' = ?", models.BatchOwnershipConsignment).
+		Where("COALESCE(a->>'supplierId', '') <> ''").
+		GroupExpr("1")
+	sq = scopeStore(ctx, sq, "o.store_id")
+	if start != "" {
+		sq = sq.Where("o.created_at::date >= ?", start)
+	}
+	if end != "" {
+		sq = sq.Where("o.created_at::date <= ?", end)
+	}
+	if err := sq.Scan(ctx, &sold); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, s := range sold {
+		cur := row(s.SupplierID)
+		cur.SoldUnits, cur.Owed, cur.Unsettled = s.SoldUnits, s.Owed, s.Unsettled
+	}
+
+	var returned []consignmentOutstandingRow
+	rq := h.deps.DB.NewSelect().
+		TableExpr("consignor_returns AS cr").
+		Join("JOIN consignor_return_lines AS crl ON crl.return_id = cr.id").
+		ColumnExpr("cr.supplier_id").
+		ColumnExpr("SUM(crl.qty) AS returned_units").
+		ColumnExpr("SUM(crl.qty * crl.unit_cost) AS returned_value").
+		GroupExpr("cr.supplier_id")
+	rq = scopeStore(ctx, rq, "cr.store_id")
+	if start != "" {
+		rq = rq.Where("cr.returned_at >= ?", start)
+	}
+	if end != "" {
+		rq = rq.Where("cr.returned_at <= ?", end)
+	}
+	if err := rq.Scan(ctx, &returned); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, s := range returned {
+		cur := row(s.SupplierID)
+		cur.ReturnedUnits, cur.ReturnedValue = s.ReturnedUnits, s.ReturnedValue
+	}
+
+	var onHand []consignmentOutstandingRow
+	hq := h.deps.DB.NewSelect().Table("batches").
+		ColumnExpr("supplier_id").
+		ColumnExpr("SUM(qty_remaining) AS on_hand_units").
+		Where("ownership = ?", models.BatchOwnershipConsignment).
+		Where("supplier_id IS NOT NULL").
+		GroupExpr("supplier_id")
+	if err := scopeStoreLocations(ctx, hq, "location_id").Scan(ctx, &onHand); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, s := range onHand {
+		if s.OnHandUnits > 0 || byID[s.SupplierID] != nil {
+			row(s.SupplierID).OnHandUnits = s.OnHandUnits
+		}
+	}
+
+	var paid []consignmentOutstandingRow
+	pq := h.deps.DB.NewSelect().Table("payouts").
+		ColumnExpr("supplier_id").
+		ColumnExpr("SUM(amount) AS paid").
+		GroupExpr("supplier_id")
+	pq = scopeStore(ctx, pq, "store_id")
+	if start != "" {
+		pq = pq.Where("paid_at >= ?", start)
+	}
+	if end != "" {
+		pq = pq.Where("paid_at <= ?", end)
+	}
+	if err := pq.Scan(ctx, &paid); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, s := range paid {
+		if cur := byID[s.SupplierID]; cur != nil {
+			cur.Paid = s.Paid
+		}
+	}
+
+	out := make([]consignmentOutstandingRow, 0, len(byID))
+	for _, cur := range byID {
+		cur.Outstanding = cur.Unsettled
+		out = append(out, *cur)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Outstanding > out[j].Outstanding })
+	writeJSON(w, http.StatusOK, out)
+}
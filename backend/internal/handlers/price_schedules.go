@@ -0,0 +1,770 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// maxPriceScheduleItems bounds one schedule; a storewide price rise is a
+// few thousand entries at most.
+const maxPriceScheduleItems = 5000
+
+type PriceSchedulesHandler struct {
+	deps Deps
+}
+
+func NewPriceSchedulesHandler(deps Deps) *PriceSchedulesHandler {
+	return &PriceSchedulesHandler{deps: deps}
+}
+
+type priceScheduleInput struct {
+	EffectiveAt time.Time                `json:"effectiveAt"`
+	Notes       string                   `json:"notes"`
+	Items       []priceScheduleItemInput `json:"items"`
+}
+
+type priceScheduleItemInput struct {
+	ProductID      uuid.UUID              `json:"productId"`
+	VariantID      *uuid.UUID             `json:"variantId,omitempty"`
+	PackagingIndex *int                   `json:"packagingIndex,omitempty"`
+	PricelistID    string                 `json:"pricelistId"`
+	Pricing        models.PricingStrategy `json:"pricing"`
+	Tiers          []models.PricingTier   `json:"tiers"`
+}
+
+// List filters by ?status=. Items are left out; Get has them with the
+// preview figures.
+func (h *PriceSchedulesHandler) List(w http.ResponseWriter, r *http.Request) {
+	items := []models.PriceSchedule{}
+	sel := h.deps.DB.NewSelect().Model(&items).
+		ColumnExpr("psch.*").
+		ColumnExpr("(SELECT count(*) FROM price_schedule_items AS psi WHERE psi.schedule_id = psch.id) AS item_count").
+		Order("psch.effective_at DESC", "psch.created_at DESC")
+	if v := strings.TrimSpace(r.URL.Query().Get("status")); v != "" {
+		sel = sel.Where("psch.status = ?", v)
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range items {
+		items[i].Items = []models.PriceScheduleItem{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Get returns the schedule with every item next to the entry it replaces
+// and both sale prices at today's cost.
+func (h *PriceSchedulesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	s, err := loadPriceSchedule(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// Create schedules new pricelist entries for a future time. Nothing in
+// product_prices changes until the apply-price-schedules job runs.
+func (h *PriceSchedulesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in priceScheduleInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	ctx := r.Context()
+	items, err := checkPriceScheduleInput(ctx, h.deps.DB, &in)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	createdBy := actorName(ctx, h.deps.DB)
+
+	var s models.PriceSchedule
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		code, err := nextPriceScheduleCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		s = models.PriceSchedule{
+			Code:        code,
+			EffectiveAt: in.EffectiveAt,
+			Status:      models.PriceSchedulePending,
+			Notes:       in.Notes,
+			CreatedBy:   createdBy,
+		}
+		if _, err := tx.NewInsert().Model(&s).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].ScheduleID = s.ID
+		}
+		_, err = tx.NewInsert().Model(&items).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadPriceSchedule(ctx, h.deps.DB, s.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, full)
+}
+
+// Cancel drops a schedule that has not taken effect yet.
+func (h *PriceSchedulesHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx := r.Context()
+	cancelledBy := actorName(ctx, h.deps.DB)
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var s models.PriceSchedule
+		if err := tx.NewSelect().Model(&s).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		switch s.Status {
+		case models.PriceScheduleApplied:
+			return errBadInput("jadwal sudah diterapkan; ubah harganya dari halaman produk")
+		case models.PriceScheduleCancelled:
+			return errBadInput("jadwal sudah dibatalkan")
+		case models.PriceScheduleFailed:
+			return errBadInput("jadwal gagal diterapkan; buat jadwal baru bila harganya masih diperlukan")
+		}
+		_, err := tx.NewUpdate().Model((*models.PriceSchedule)(nil)).
+			Set("status = ?", models.PriceScheduleCancelled).
+			Set("cancelled_by = ?", cancelledBy).
+			Set("cancelled_at = current_timestamp").
+			Where("id = ?", s.ID).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadPriceSchedule(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, full)
+}
+
+// checkPriceScheduleInput validates the schedule and returns its items
+// ready to insert. Every target must exist now; one that disappears
+// before the schedule runs is skipped then.
+func checkPriceScheduleInput(ctx context.Context, db *bun.DB, in *priceScheduleInput) ([]models.PriceScheduleItem, error) {
+	in.Notes = strings.TrimSpace(in.Notes)
+	if in.EffectiveAt.IsZero() {
+		return nil, errBadInput("waktu berlaku wajib diisi")
+	}
+	if !in.EffectiveAt.After(time.Now()) {
+		return nil, errBadInput("waktu berlaku harus di masa depan")
+	}
+	if len(in.Items) == 0 {
+		return nil, errBadInput("jadwal belum berisi harga")
+	}
+	if len(in.Items) > maxPriceScheduleItems {
+		return nil, errBadInput(fmt.Sprintf("maksimal %d harga per jadwal", maxPriceScheduleItems))
+	}
+
+	var productIDs []uuid.UUID
+	for _, it := range in.Items {
+		if !slices.Contains(productIDs, it.ProductID) {
+			productIDs = append(productIDs, it.ProductID)
+		}
+	}
+	products, err := loadProductsByID(ctx, db, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	pricelistNames, err := pricelistNameMap(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	items := make([]models.PriceScheduleItem, 0, len(in.Items))
+	for i, it := range in.Items {
+		p := products[it.ProductID]
+		if p == nil {
+			return nil, errBadInput(fmt.Sprintf("baris %d: produk tidak ditemukan", i+1))
+		}
+		it.PricelistID = strings.TrimSpace(it.PricelistID)
+		if _, ok := pricelistNames[it.PricelistID]; !ok {
+			return nil, errBadInput(fmt.Sprintf("%s: daftar harga tidak ditemukan", p.Name))
+		}
+		if it.VariantID != nil && it.PackagingIndex != nil {
+			return nil, errBadInput(fmt.Sprintf("%s: pilih varian atau kemasan, bukan keduanya", p.Name))
+		}
+		item := models.PriceScheduleItem{
+			ProductID:      p.ID,
+			VariantID:      it.VariantID,
+			PackagingIndex: it.PackagingIndex,
+			PricelistID:    it.PricelistID,
+			Position:       i,
+		}
+		t := priceScheduleTargetOf(p, &item)
+		if t.note != "" {
+			return nil, errBadInput(fmt.Sprintf("%s: %s", p.Name, t.note))
+		}
+		if item.PackagingIndex != nil {
+			item.PackagingUnit = &t.packagingUnit
+			item.PackagingFactor = &t.factor
+		}
+		key := fmt.Sprintf("%s|%s|%s|%s", p.ID, optUUIDKey(it.VariantID), optIntKey(it.PackagingIndex), it.PricelistID)
+		if seen[key] {
+			return nil, errBadInput(fmt.Sprintf("%s: harga yang sama dijadwalkan dua kali", p.Name))
+		}
+		seen[key] = true
+
+		if err := checkPricingStrategy(it.Pricing); err != nil {
+			return nil, errBadInput(fmt.Sprintf("%s: %s", p.Name, err.Error()))
+		}
+		tiers := make([]models.PricingTier, 0, len(it.Tiers))
+		for _, t := range it.Tiers {
+			if t.MinQty <= 0 {
+				return nil, errBadInput(fmt.Sprintf("%s: qty minimum tier harus lebih dari 0", p.Name))
+			}
+			if slices.ContainsFunc(tiers, func(x models.PricingTier) bool { return x.MinQty == t.MinQty }) {
+				return nil, errBadInput(fmt.Sprintf("%s: qty minimum tier %s dobel", p.Name, formatDecimal(t.MinQty)))
+			}
+			if err := checkPricingStrategy(t.Pricing); err != nil {
+				return nil, errBadInput(fmt.Sprintf("%s: tier %s: %s", p.Name, formatDecimal(t.MinQty), err.Error()))
+			}
+			tiers = append(tiers, t)
+		}
+		slices.SortFunc(tiers, func(a, b models.PricingTier) int {
+			switch {
+			case a.MinQty < b.MinQty:
+				return -1
+			case a.MinQty > b.MinQty:
+				return 1
+			}
+			return 0
+		})
+		item.PricingKind = it.Pricing.Kind
+		item.PricingValue = it.Pricing.Value
+		item.Tiers = tiers
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func checkPricingStrategy(s models.PricingStrategy) error {
+	switch s.Kind {
+	case "fixed", "markup_pct", "markup_amount":
+	default:
+		return errors.New("jenis harga tidak dikenal")
+	}
+	if math.IsNaN(s.Value) || s.Value < 0 {
+		return errors.New("nilai harga tidak boleh negatif")
+	}
+	return nil
+}
+
+func optUUIDKey(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func optIntKey(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return fmt.Sprint(*n)
+}
+
+// priceScheduleTarget is what one scheduled item points at on a loaded
+// product. note is set when the variant or packaging no longer exists.
+type priceScheduleTarget struct {
+	entries        []models.PricelistEntry
+	variantName    string
+	packagingIndex int
+	packagingLabel string
+	packagingUnit  string
+	factor         float64
+	note           string
+}
+
+func priceScheduleTargetOf(p *models.Product, item *models.PriceScheduleItem) priceScheduleTarget {
+	t := priceScheduleTarget{entries: p.Prices, factor: 1}
+	switch {
+	case item.VariantID != nil:
+		i := slices.IndexFunc(p.Variants, func(v models.ProductVariant) bool { return v.ID == *item.VariantID })
+		if i < 0 {
+			t.note = "varian sudah dihapus"
+			return t
+		}
+		t.entries = p.Variants[i].Prices
+		t.variantName = p.Variants[i].Name
+	case item.PackagingIndex != nil:
+		i := scheduledPackagingIndex(p.Packagings, item)
+		if i < 0 {
+			t.note = "kemasan sudah dihapus atau diubah"
+			return t
+		}
+		t.packagingIndex = i
+		t.entries = p.Packagings[i].Prices
+		t.packagingUnit = p.Packagings[i].UnitID
+		t.factor = p.Packagings[i].Factor
+	}
+	return t
+}
+
+// scheduledPackagingIndex finds the packaging an item was scheduled for:
+// the one at its position while unit and factor still match, else the
+// first with that unit and factor, since a product save may reorder them.
+// Items without a recorded unit go by position alone. Returns -1 when no
+// packaging matches.
+func scheduledPackagingIndex(pkgs []models.ProductPackaging, item *models.PriceScheduleItem) int {
+	i := *item.PackagingIndex
+	if item.PackagingUnit == nil || item.PackagingFactor == nil {
+		if i < 0 || i >= len(pkgs) {
+			return -1
+		}
+		return i
+	}
+	same := func(pk models.ProductPackaging) bool {
+		return pk.UnitID == *item.PackagingUnit && math.Abs(pk.Factor-*item.PackagingFactor) < 1e-9
+	}
+	if i >= 0 && i < len(pkgs) && same(pkgs[i]) {
+		return i
+	}
+	return slices.IndexFunc(pkgs, same)
+}
+
+// label fills packagingLabel the way the FE history labels packagings:
+// "<unit> · isi <factor>".
+func (t *priceScheduleTarget) label(ctx context.Context, db bun.IDB, units map[string]string) {
+	if t.packagingUnit == "" {
+		return
+	}
+	name, ok := units[t.packagingUnit]
+	if !ok {
+		if id, err := uuid.Parse(t.packagingUnit); err == nil {
+			name = lookupName(ctx, db, "units", id)
+		}
+		if name == "" {
+			name = t.packagingUnit
+		}
+		units[t.packagingUnit] = name
+	}
+	t.packagingLabel = fmt.Sprintf("%s · isi %s", name, formatDecimal(t.factor))
+}
+
+func pricelistNameMap(ctx context.Context, db bun.IDB) (map[string]string, error) {
+	var rows []struct {
+		ID   string `bun:"id"`
+		Name string `bun:"name"`
+	}
+	if err := db.NewSelect().Table("pricelists").Column("id", "name").Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(rows))
+	for _, r := range rows {
+		out[r.ID] = r.Name
+	}
+	return out, nil
+}
+
+// loadPriceSchedule reads a schedule with its items. Pending items get
+// the preview: the entry each one replaces and both sale prices at the
+// cost markups apply to today.
+func loadPriceSchedule(ctx context.Context, db *bun.DB, id uuid.UUID) (*models.PriceSchedule, error) {
+	var s models.PriceSchedule
+	if err := db.NewSelect().Model(&s).Where("id = ?", id).Scan(ctx); err != nil {
+		return nil, err
+	}
+	s.Items = []models.PriceScheduleItem{}
+	if err := db.NewSelect().Model(&s.Items).
+		Where("schedule_id = ?", id).
+		Order("position ASC").Scan(ctx); err != nil {
+		return nil, err
+	}
+	s.ItemCount = len(s.Items)
+
+	var productIDs []uuid.UUID
+	for _, it := range s.Items {
+		if !slices.Contains(productIDs, it.ProductID) {
+			productIDs = append(productIDs, it.ProductID)
+		}
+	}
+	products, err := loadProductsByID(ctx, db, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	pricelistNames, err := pricelistNameMap(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	units := map[string]string{}
+	for i := range s.Items {
+		it := &s.Items[i]
+		it.Pricing = models.PricingStrategy{Kind: it.PricingKind, Value: it.PricingValue}
+		it.PricelistName = pricelistNames[it.PricelistID]
+		if it.Tiers == nil {
+			it.Tiers = []models.PricingTier{}
+		}
+		p := products[it.ProductID]
+		if p == nil {
+			continue
+		}
+		it.ProductName = p.Name
+		t := priceScheduleTargetOf(p, it)
+		t.label(ctx, db, units)
+		it.VariantName = t.variantName
+		it.PackagingLabel = t.packagingLabel
+		if t.note != "" {
+			if it.Result == "" {
+				it.ResultNote = t.note
+			}
+			continue
+		}
+		cost, err := markupCost(ctx, db, p, it.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		it.Cost = roundMoney(cost * t.factor)
+		it.NewSale = roundMoney(computeSalePrice(it.Cost, it.Pricing))
+		if s.Status != models.PriceSchedulePending {
+			continue
+		}
+		if cur := findPricelistEntry(t.entries, it.PricelistID); cur != nil {
+			pricing := cur.Pricing
+			it.Current = &pricing
+			it.CurrentSale = roundMoney(computeSalePrice(it.Cost, pricing))
+		}
+	}
+	return &s, nil
+}
+
+func nextPriceScheduleCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("PSCH-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("price_schedules").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
+
+// RunPriceSchedules is the apply-price-schedules job: every pending
+// schedule whose time has come is written into product_prices, oldest
+// first, so a later schedule for the same price wins. A schedule that
+// fails is marked failed so the next tick doesn't retry it, and the rest
+// still run; the failures come back joined for the job log. Returns the
+// number of prices applied.
+func RunPriceSchedules(ctx context.Context, db *bun.DB, now time.Time) (int, error) {
+	var due []uuid.UUID
+	if err := db.NewSelect().Model((*models.PriceSchedule)(nil)).
+		Column("id").
+		Where("status = ?", models.PriceSchedulePending).
+		Where("effective_at <= ?", now).
+		Order("effective_at ASC", "created_at ASC").
+		Scan(ctx, &due); err != nil {
+		return 0, err
+	}
+	total := 0
+	var errs []error
+	for _, id := range due {
+		n, err := applyPriceSchedule(ctx, db, id, now)
+		if err != nil {
+			if ctx.Err() != nil {
+				return total, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("price schedule %s: %w", id, err))
+			note := err.Error()
+			if _, err := db.NewUpdate().Model((*models.PriceSchedule)(nil)).
+				Set("status = ?", models.PriceScheduleFailed).
+				Set("failure_note = ?", note).
+				Set("failed_at = ?", now).
+				Where("id = ?", id).
+				Where("status = ?", models.PriceSchedulePending).
+				Exec(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("price schedule %s: mark failed: %w", id, err))
+			}
+			continue
+		}
+		total += n
+	}
+	return total, errors.Join(errs...)
+}
+
+// applyPriceSchedule applies one schedule in its own transaction. A
+// schedule cancelled in the meantime is left alone.
+func applyPriceSchedule(ctx context.Context, db *bun.DB, id uuid.UUID, now time.Time) (int, error) {
+	var items []models.PriceScheduleItem
+	if err := db.NewSelect().Model(&items).
+		Where("schedule_id = ?", id).
+		Order("position ASC").Scan(ctx); err != nil {
+		return 0, err
+	}
+	var productIDs []uuid.UUID
+	for _, it := range items {
+		if !slices.Contains(productIDs, it.ProductID) {
+			productIDs = append(productIDs, it.ProductID)
+		}
+	}
+	pricelistNames, err := pricelistNameMap(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var s models.PriceSchedule
+		if err := tx.NewSelect().Model(&s).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if s.Status != models.PriceSchedulePending {
+			return nil
+		}
+		// Lock the products before reading them so a product save can't
+		// replace packaging or prices between the match and the write.
+		if len(productIDs) > 0 {
+			if _, err := tx.NewSelect().Table("products").Column("id").
+				Where("id IN (?)", bun.In(productIDs)).
+				Order("id ASC").For("UPDATE").Exec(ctx); err != nil {
+				return err
+			}
+		}
+		products, err := loadProductsByID(ctx, tx, productIDs)
+		if err != nil {
+			return err
+		}
+		notes := "Jadwal " + s.Code
+		if s.Notes != "" {
+			notes += " · " + s.Notes
+		}
+		units := map[string]string{}
+		skipped := 0
+		var touched []uuid.UUID
+		for i := range items {
+			it := &items[i]
+			it.Pricing = models.PricingStrategy{Kind: it.PricingKind, Value: it.PricingValue}
+			if err := applyPriceScheduleItem(ctx, tx, products[it.ProductID], it, pricelistNames, units, notes, s.CreatedBy, now); err != nil {
+				return err
+			}
+			if it.Result == models.PriceScheduleItemApplied {
+				applied++
+				if !slices.Contains(touched, it.ProductID) {
+					touched = append(touched, it.ProductID)
+				}
+			} else {
+				skipped++
+			}
+			if _, err := tx.NewUpdate().Model(it).
+				Column("result", "result_note").
+				WherePK().Exec(ctx); err != nil {
+				return err
+			}
+		}
+		if len(touched) > 0 {
+			if _, err := tx.NewUpdate().Table("products").
+				Set("updated_at = current_timestamp").
+				Where("id IN (?)", bun.In(touched)).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		_, err = tx.NewUpdate().Model((*models.PriceSchedule)(nil)).
+			Set("status = ?", models.PriceScheduleApplied).
+			Set("applied_at = ?", now).
+			Set("items_applied = ?", applied).
+			Set("items_skipped = ?", skipped).
+			Where("id = ?", s.ID).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// applyPriceScheduleItem replaces the item's pricelist entry (tiers
+// included) and logs what changed to price_changes, one row for the
+// base price and one per tier whose min qty existed before, like the
+// product form does. A target that is gone marks the item skipped.
+func applyPriceScheduleItem(
+	ctx context.Context, tx bun.Tx, p *models.Product, it *models.PriceScheduleItem,
+	pricelistNames, units map[string]string, notes, performedBy string, now time.Time,
+) error {
+	if p == nil {
+		it.Result, it.ResultNote = models.PriceScheduleItemSkipped, "produk sudah dihapus"
+		return nil
+	}
+	t := priceScheduleTargetOf(p, it)
+	if t.note != "" {
+		it.Result, it.ResultNote = models.PriceScheduleItemSkipped, t.note
+		return nil
+	}
+	t.label(ctx, tx, units)
+
+	// Packaging rows are replaced on every product save, so find the row
+	// at the matched position now and make sure it is still that packaging.
+	var packagingID *uuid.UUID
+	if it.PackagingIndex != nil {
+		var row models.ProductPackagingRow
+		err := tx.NewSelect().Model(&row).
+			Where("product_id = ?", p.ID).
+			Order("position ASC").
+			Offset(t.packagingIndex).Limit(1).
+			Scan(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err != nil || row.UnitID.String() != t.packagingUnit || math.Abs(row.Factor-t.factor) >= 1e-9 {
+			it.Result, it.ResultNote = models.PriceScheduleItemSkipped, "kemasan sudah dihapus atau diubah"
+			return nil
+		}
+		packagingID = &row.ID
+	}
+
+	var old models.ProductPriceRow
+	q := tx.NewSelect().Model(&old).
+		Where("product_id = ?", p.ID).
+		Where("pricelist_id = ?", it.PricelistID)
+	if it.VariantID != nil {
+		q = q.Where("variant_id = ?", *it.VariantID)
+	} else {
+		q = q.Where("variant_id IS NULL")
+	}
+	if packagingID != nil {
+		q = q.Where("packaging_id = ?", *packagingID)
+	} else {
+		q = q.Where("packaging_id IS NULL")
+	}
+	hadOld := true
+	if err := q.Limit(1).Scan(ctx); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		hadOld = false
+	}
+	oldPricing := models.PricingStrategy{Kind: "fixed"}
+	var oldTiers []models.ProductPriceTierRow
+	if hadOld {
+		oldPricing = models.PricingStrategy{Kind: old.PricingKind, Value: old.PricingValue}
+		if err := tx.NewSelect().Model(&oldTiers).
+			Where("price_id = ?", old.ID).
+			Order("min_qty ASC").Scan(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewDelete().Model((*models.ProductPriceRow)(nil)).
+			Where("id = ?", old.ID).Exec(ctx); err != nil {
+			return err
+		}
+	}
+	entry := models.PricelistEntry{PricelistID: it.PricelistID, Pricing: it.Pricing, Tiers: it.Tiers}
+	if err := insertPriceWithTiers(ctx, tx, p.ID, it.VariantID, packagingID, entry); err != nil {
+		return err
+	}
+	it.Result, it.ResultNote = models.PriceScheduleItemApplied, ""
+	if !hadOld {
+		it.ResultNote = "harga baru di daftar harga ini"
+	}
+
+	cost, err := markupCost(ctx, tx, p, it.VariantID)
+	if err != nil {
+		return err
+	}
+	cost = roundMoney(cost * t.factor)
+	pricelistID := it.PricelistID
+	var packagingIndex *int
+	if it.PackagingIndex != nil {
+		packagingIndex = &t.packagingIndex
+	}
+	base := models.PriceChange{
+		At:             now,
+		ProductID:      &p.ID,
+		ProductName:    p.Name,
+		VariantID:      it.VariantID,
+		VariantName:    t.variantName,
+		PackagingIndex: packagingIndex,
+		PackagingLabel: t.packagingLabel,
+		PricelistID:    &pricelistID,
+		PricelistName:  pricelistNames[pricelistID],
+		Cost:           cost,
+		Source:         models.PriceChangeScheduled,
+		Notes:          notes,
+		PerformedBy:    performedBy,
+	}
+	if !hadOld || !samePricing(oldPricing, it.Pricing) {
+		if err := recordScheduledPriceChange(ctx, tx, base, nil, oldPricing, it.Pricing); err != nil {
+			return err
+		}
+	}
+	for _, ot := range oldTiers {
+		i := slices.IndexFunc(it.Tiers, func(t models.PricingTier) bool { return t.MinQty == ot.MinQty })
+		if i < 0 {
+			continue
+		}
+		prev := models.PricingStrategy{Kind: ot.PricingKind, Value: ot.PricingValue}
+		if samePricing(prev, it.Tiers[i].Pricing) {
+			continue
+		}
+		minQty := ot.MinQty
+		if err := recordScheduledPriceChange(ctx, tx, base, &minQty, prev, it.Tiers[i].Pricing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func samePricing(a, b models.PricingStrategy) bool {
+	return a.Kind == b.Kind && math.Abs(a.Value-b.Value) < 0.0001
+}
+
+func recordScheduledPriceChange(
+	ctx context.Context, tx bun.Tx, base models.PriceChange, tierMinQty *float64,
+	from, to models.PricingStrategy,
+) error {
+	row := base
+	row.TierMinQty = tierMinQty
+	oldJSON, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	newJSON, err := json.Marshal(to)
+	if err != nil {
+		return err
+	}
+	row.OldStrategy = oldJSON
+	row.NewStrategy = newJSON
+	row.OldSale = roundMoney(computeSalePrice(base.Cost, from))
+	row.NewSale = roundMoney(computeSalePrice(base.Cost, to))
+	code, err := nextPriceChangeCode(ctx, tx, row.At)
+	if err != nil {
+		return err
+	}
+	row.Code = code
+	_, err = tx.NewInsert().Model(&row).Exec(ctx)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/sandisahdewo/pos/backend/internal/models"
 	"github.com/uptrace/bun"
 )
@@ -24,12 +25,33 @@ type pricelistInput struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	IsDefault   bool   `json:"isDefault"`
+	// StoreID limits the pricelist to one store; null shares it everywhere.
+	// Left out of an update, the current scope is kept.
+	StoreID json.RawMessage `json:"storeId"`
+}
+
+// storeScope decodes StoreID; set is false when the field was left out.
+func (in *pricelistInput) storeScope() (id *uuid.UUID, set bool, err error) {
+	if len(in.StoreID) == 0 {
+		return nil, false, nil
+	}
+	if string(in.StoreID) == "null" {
+		return nil, true, nil
+	}
+	var v uuid.UUID
+	if err := json.Unmarshal(in.StoreID, &v); err != nil {
+		return nil, true, err
+	}
+	return &v, true, nil
 }
 
 func (h *PricelistsHandler) List(w http.ResponseWriter, r *http.Request) {
 	items := []models.Pricelist{}
-	if err := h.deps.DB.NewSelect().Model(&items).
-		Order("is_default DESC, name ASC").Scan(r.Context()); err != nil {
+	q := h.deps.DB.NewSelect().Model(&items).Order("is_default DESC, name ASC")
+	if storeID, ok := activeStore(r.Context()); ok {
+		q = q.Where("pl.store_id IS NULL OR pl.store_id = ?", storeID)
+	}
+	if err := q.Scan(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -46,13 +68,19 @@ func (h *PricelistsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, msg)
 		return
 	}
+	storeID, _, err := in.storeScope()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "storeId tidak valid")
+		return
+	}
 	p := &models.Pricelist{
 		ID:          strings.TrimSpace(in.ID),
 		Name:        strings.TrimSpace(in.Name),
 		Description: strings.TrimSpace(in.Description),
 		IsDefault:   in.IsDefault,
+		StoreID:     storeID,
 	}
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		if in.IsDefault {
 			if _, err := tx.NewUpdate().Table("pricelists").
 				Set("is_default = false").Where("is_default = true").Exec(ctx); err != nil {
@@ -80,7 +108,12 @@ func (h *PricelistsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, msg)
 		return
 	}
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+	storeID, storeSet, err := in.storeScope()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "storeId tidak valid")
+		return
+	}
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		if in.IsDefault {
 			if _, err := tx.NewUpdate().Table("pricelists").
 				Set("is_default = false").
@@ -88,11 +121,15 @@ func (h *PricelistsHandler) Update(w http.ResponseWriter, r *http.Request) {
 				return err
 			}
 		}
-		res, err := tx.NewUpdate().Table("pricelists").Where("id = ?", id).
+		q := tx.NewUpdate().Table("pricelists").Where("id = ?", id).
 			Set("name = ?", strings.TrimSpace(in.Name)).
 			Set("description = ?", strings.TrimSpace(in.Description)).
 			Set("is_default = ?", in.IsDefault).
-			Set("updated_at = current_timestamp").Exec(ctx)
+			Set("updated_at = current_timestamp")
+		if storeSet {
+			q = q.Set("store_id = ?", storeID)
+		}
+		res, err := q.Exec(ctx)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type productionExecuteInput struct {
+	ProductID   string   `json:"productId"`
+	VariantID   *string  `json:"variantId,omitempty"`
+	IntendedQty float64  `json:"intendedQty"`
+	ProducedQty *float64 `json:"producedQty,omitempty"`
+	LocationID  *string  `json:"locationId,omitempty"`
+	ExpiresAt   string   `json:"expiresAt"`
+	ShiftID     *string  `json:"shiftId,omitempty"`
+	Notes       string   `json:"notes"`
+}
+
+// Execute runs a production session entirely on the server: resolves the
+// recipe from product_components, consumes owned component batches at the
+// run's location (FEFO or FIFO per inventory.allocationStrategy) under row
+// locks, creates the output batch and records the run. Components are
+// always consumed for IntendedQty; when ProducedQty is lower the difference
+// is stored as yield loss and its cost is carried by the produced units.
+func (h *ProductionRunsHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	var in productionExecuteInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	productID, err := uuid.Parse(in.ProductID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "productId tidak valid")
+		return
+	}
+	if in.IntendedQty <= 0 {
+		writeError(w, http.StatusBadRequest, "Jumlah produksi harus lebih dari 0.")
+		return
+	}
+	producedQty := in.IntendedQty
+	if in.ProducedQty != nil {
+		producedQty = *in.ProducedQty
+	}
+	if producedQty <= 0 {
+		writeError(w, http.StatusBadRequest, "Jumlah yang dihasilkan harus lebih dari 0.")
+		return
+	}
+	if producedQty > in.IntendedQty {
+		writeError(w, http.StatusBadRequest, "Jumlah yang dihasilkan tidak boleh lebih dari yang direncanakan.")
+		return
+	}
+	var variantID *uuid.UUID
+	if in.VariantID != nil && *in.VariantID != "" {
+		v, err := uuid.Parse(*in.VariantID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "variantId tidak valid")
+			return
+		}
+		variantID = &v
+	}
+	settings, err := loadServerSettings(r.Context(), h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
+
+	var run models.ProductionRun
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var product models.Product
+		if err := tx.NewSelect().Model(&product).
+			Column("id", "name", "kind", "shelf_life_after_production_hrs").
+			Where("id = ?", productID).Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if product.Kind != models.ProductKindComposite {
+			return errBadInput("Hanya produk komposit yang bisa diproduksi.")
+		}
+		var variantNames []string
+		if err := tx.NewSelect().Table("product_variants").Column("name").
+			Where("product_id = ?", productID).Scan(ctx, &variantNames); err != nil {
+			return err
+		}
+		label := product.Name
+		if variantID == nil && len(variantNames) > 0 {
+			return errBadInput("Pilih varian yang akan diproduksi.")
+		}
+		if variantID != nil {
+			var name string
+			if err := tx.NewSelect().Table("product_variants").Column("name").
+				Where("id = ?", *variantID).Where("product_id = ?", productID).
+				Scan(ctx, &name); err != nil {
+				return errBadInput("Varian tidak ditemukan.")
+			}
+			label += " · " + name
+		}
+
+		recipe, err := loadRecipe(ctx, tx, productID, variantID)
+		if err != nil {
+			return err
+		}
+		if len(recipe) == 0 {
+			return errBadInput("Resep kosong — atur komponen lebih dulu di produk.")
+		}
+
+		locID, err := resolveReceiptLocation(ctx, tx, in.LocationID)
+		if err != nil {
+			return err
+		}
+
+		code, err := nextProductionRunCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		run = models.ProductionRun{
+			Code:        code,
+			ProductID:   productID,
+			VariantID:   variantID,
+			IntendedQty: in.IntendedQty,
+			ProducedQty: producedQty,
+			LocationID:  locID,
+			ShiftID:     optUUID(in.ShiftID),
+			Status:      models.ProductionRunStatusCompleted,
+			Notes:       strings.TrimSpace(in.Notes),
+		}
+		if _, err := tx.NewInsert().Model(&run).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		ref := models.StockMovementReference{Kind: models.MovementRefProduction, ID: run.ID.String(), Code: run.Code}
+		movementNote := "Produksi " + label
+
+		var consumptions []models.ProductionRunConsumption
+		totalCost := 0.0
+		for _, c := range recipe {
+			factor := 1.0
+			if c.UnitFactor != nil && *c.UnitFactor > 0 {
+				factor = *c.UnitFactor
+			}
+			required := c.Quantity * factor * in.IntendedQty
+			if required <= 0 {
+				continue
+			}
+			draws, err := consumeComponentBatches(ctx, tx, c, required, locID,
+				settings.Inventory.AllocationStrategy, ref, performedBy, movementNote)
+			if err != nil {
+				return err
+			}
+			for _, d := range draws {
+				d.ProductionRunID = run.ID
+				totalCost += d.QtyConsumed * d.UnitCost
+				consumptions = append(consumptions, d)
+			}
+		}
+		if len(consumptions) > 0 {
+			if _, err := tx.NewInsert().Model(&consumptions).Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		// Explicit expiry wins; otherwise derive from shelf life after
+		// production, same as the frontend modal.
+		expiresAt := strings.TrimSpace(in.ExpiresAt)
+		if expiresAt == "" && product.ShelfLifeAfterProductionHours != nil && *product.ShelfLifeAfterProductionHours > 0 {
+			expiresAt = time.Now().Add(time.Duration(*product.ShelfLifeAfterProductionHours) * time.Hour).Format("2006-01-02")
+		}
+		perUnitCost := totalCost / producedQty
+		batchNotes := run.Notes
+		if batchNotes == "" {
+			batchNotes = "Hasil produksi " + run.Code
+		}
+		batchCode, err := nextBatchCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		b := models.Batch{
+			Code:         batchCode,
+			ProductID:    productID,
+			VariantID:    variantID,
+			Ownership:    models.BatchOwnershipOwned,
+			UnitCost:     perUnitCost,
+			QtyReceived:  producedQty,
+			QtyRemaining: producedQty,
+			ReceivedAt:   time.Now().Format("2006-01-02"),
+			ExpiresAt:    expiresAt,
+			LocationID:   locID,
+			Notes:        batchNotes,
+		}
+		if _, err := tx.NewInsert().Model(&b).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		if err := logMovement(ctx, tx, &models.StockMovement{
+			Kind:        models.MovementKindProductionIn,
+			ProductID:   &b.ProductID,
+			VariantID:   b.VariantID,
+			LocationID:  &b.LocationID,
+			BatchID:     &b.ID,
+			QtyDelta:    producedQty,
+			QtyAfter:    b.QtyRemaining,
+			UnitCost:    floatPtr(perUnitCost),
+			Reference:   ref,
+			PerformedBy: performedBy,
+			Notes:       movementNote,
+		}); err != nil {
+			return err
+		}
+
+		run.ProducedBatchID = &b.ID
+		run.UnitCost = perUnitCost
+		run.ExpiresAt = expiresAt
+		run.YieldLossQty = in.IntendedQty - producedQty
+		run.YieldLossValue = run.YieldLossQty * totalCost / in.IntendedQty
+		_, err = tx.NewUpdate().Model(&run).
+			Column("produced_batch_id", "unit_cost", "expires_at", "yield_loss_qty", "yield_loss_value").
+			Set("updated_at = current_timestamp").
+			WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+
+	if err := h.deps.DB.NewSelect().
+		Model(&run).
+		Relation("Consumptions").
+		Where("pr.id = ?", run.ID).
+		Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if run.Consumptions == nil {
+		run.Consumptions = []models.ProductionRunConsumption{}
+	}
+	writeJSON(w, http.StatusCreated, run)
+}
+
+// loadRecipe returns the components for (product, variant?). Variant-level
+// components override the product recipe when present; extras are not part
+// of a production recipe.
+func loadRecipe(ctx context.Context, db bun.IDB, productID uuid.UUID, variantID *uuid.UUID) ([]models.ProductComponentRow, error) {
+	var rows []models.ProductComponentRow
+	if variantID != nil {
+		if err := db.NewSelect().Model(&rows).
+			Where("product_id = ?", productID).
+			Where("parent_variant_id = ?", *variantID).
+			Where("extra_id IS NULL").
+			Order("position ASC").Scan(ctx); err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			return rows, nil
+		}
+	}
+	if err := db.NewSelect().Model(&rows).
+		Where("product_id = ?", productID).
+		Where("parent_variant_id IS NULL").
+		Where("extra_id IS NULL").
+		Order("position ASC").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// consumeComponentBatches draws `required` base units of one component from
+// its active, shop-owned batches at the run's location, in
+// allocation-strategy order. Consigned stock belongs to the consignor and is
+// never used as an ingredient. A component without a pinned variant draws
+// across all of its variants. The whole requirement must be on hand;
+// partial consumption is never posted.
+func consumeComponentBatches(
+	ctx context.Context, tx bun.Tx,
+	c models.ProductComponentRow, required float64, locationID uuid.UUID, strategy string,
+	ref models.StockMovementReference, performedBy, notes string,
+) ([]models.ProductionRunConsumption, error) {
+	var candidates []models.Batch
+	q := tx.NewSelect().Model(&candidates).
+		Where("product_id = ?", c.ComponentProductID).
+		Where("status = ?", models.BatchStatusActive).
+		Where("ownership = ?", models.BatchOwnershipOwned).
+		Where("location_id = ?", locationID).
+		Where("qty_remaining > 0").
+		OrderExpr(batchPickOrder(strategy)).
+		For("UPDATE")
+	if c.ComponentVariantID != nil {
+		q = q.Where("variant_id = ?", *c.ComponentVariantID)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, err
+	}
+	have := 0.0
+	for _, b := range candidates {
+		have += b.QtyRemaining
+	}
+	if have+1e-9 < required {
+		var name string
+		_ = tx.NewSelect().Table("products").Column("name").
+			Where("id = ?", c.ComponentProductID).Scan(ctx, &name)
+		return nil, errBadInput(fmt.Sprintf("%s: Butuh %.4g, hanya ada %.4g di lokasi produksi.", name, required, have))
+	}
+
+	var out []models.ProductionRunConsumption
+	need := required
+	for i := range candidates {
+		if need <= 1e-9 {
+			break
+		}
+		b := &candidates[i]
+		take := math.Min(need, b.QtyRemaining)
+		b.QtyRemaining -= take
+		if _, err := tx.NewUpdate().Table("batches").
+			Where("id = ?", b.ID).
+			Set("qty_remaining = ?", b.QtyRemaining).
+			Set("updated_at = current_timestamp").
+			Exec(ctx); err != nil {
+			return nil, err
+		}
+		if err := logMovement(ctx, tx, &models.StockMovement{
+			Kind:        models.MovementKindProductionOut,
+			ProductID:   &b.ProductID,
+			VariantID:   b.VariantID,
+			LocationID:  &b.LocationID,
+			BatchID:     &b.ID,
+			QtyDelta:    -take,
+			QtyAfter:    b.QtyRemaining,
+			UnitCost:    floatPtr(b.UnitCost),
+			Reference:   ref,
+			PerformedBy: performedBy,
+			Notes:       notes,
+		}); err != nil {
+			return nil, err
+		}
+		batchID := b.ID
+		out = append(out, models.ProductionRunConsumption{
+			ProductID:   b.ProductID,
+			VariantID:   b.VariantID,
+			BatchID:     &batchID,
+			BatchCode:   b.Code,
+			QtyConsumed: take,
+			UnitCost:    b.UnitCost,
+		})
+		need -= take
+	}
+	return out, nil
+}
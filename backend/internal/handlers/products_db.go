@@ -12,7 +12,7 @@ import (
 // loadProducts fetches all products and assembles their child entities. One
 // query per child table for the entire batch, then in-memory grouping by
 // product_id. Avoids per-product N+1 across the list page.
-func loadProducts(ctx context.Context, db *bun.DB) ([]models.Product, error) {
+func loadProducts(ctx context.Context, db bun.IDB) ([]models.Product, error) {
 	var products []models.Product
 	if err := db.NewSelect().Model(&products).Order("name ASC").Scan(ctx); err != nil {
 		return nil, err
@@ -20,6 +20,38 @@ func loadProducts(ctx context.Context, db *bun.DB) ([]models.Product, error) {
 	if len(products) == 0 {
 		return products, nil
 	}
+	if err := attachProductChildren(ctx, db, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// loadProductsByID is loadProducts for just the given ids, keyed by id.
+// Ids with no product are left out.
+func loadProductsByID(ctx context.Context, db bun.IDB, ids []uuid.UUID) (map[uuid.UUID]*models.Product, error) {
+	out := make(map[uuid.UUID]*models.Product, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var products []models.Product
+	if err := db.NewSelect().Model(&products).Where("id IN (?)", bun.In(ids)).Scan(ctx); err != nil {
+		return nil, err
+	}
+	if err := attachProductChildren(ctx, db, products); err != nil {
+		return nil, err
+	}
+	for i := range products {
+		out[products[i].ID] = &products[i]
+	}
+	return out, nil
+}
+
+// attachProductChildren fills the nested child entities of products in
+// place, one query per child table.
+func attachProductChildren(ctx context.Context, db bun.IDB, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
 	ids := make([]uuid.UUID, len(products))
 	idx := make(map[uuid.UUID]int, len(products))
 	for i := range products {
@@ -29,58 +61,37 @@ func loadProducts(ctx context.Context, db *bun.DB) ([]models.Product, error) {
 	}
 
 	if err := attachAttributes(ctx, db, ids, products, idx); err != nil {
-		return nil, err
+		return err
 	}
 	if err := attachPackagings(ctx, db, ids, products, idx); err != nil {
-		return nil, err
+		return err
 	}
 	if err := attachSuppliers(ctx, db, ids, products, idx); err != nil {
-		return nil, err
+		return err
 	}
 	if err := attachVariants(ctx, db, ids, products, idx); err != nil {
-		return nil, err
+		return err
 	}
 	if err := attachExtras(ctx, db, ids, products, idx); err != nil {
-		return nil, err
-	}
-	if err := attachPricesAndComponents(ctx, db, ids, products, idx); err != nil {
-		return nil, err
+		return err
 	}
-	return products, nil
+	return attachPricesAndComponents(ctx, db, ids, products, idx)
 }
 
-func loadProduct(ctx context.Context, db *bun.DB, id uuid.UUID) (*models.Product, error) {
+func loadProduct(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.Product, error) {
 	var p models.Product
 	if err := db.NewSelect().Model(&p).Where("id = ?", id).Scan(ctx); err != nil {
 		return nil, err
 	}
-	p.EnsureSlices()
 	products := []models.Product{p}
-	ids := []uuid.UUID{id}
-	idx := map[uuid.UUID]int{id: 0}
-	if err := attachAttributes(ctx, db, ids, products, idx); err != nil {
-		return nil, err
-	}
-	if err := attachPackagings(ctx, db, ids, products, idx); err != nil {
-		return nil, err
-	}
-	if err := attachSuppliers(ctx, db, ids, products, idx); err != nil {
-		return nil, err
-	}
-	if err := attachVariants(ctx, db, ids, products, idx); err != nil {
-		return nil, err
-	}
-	if err := attachExtras(ctx, db, ids, products, idx); err != nil {
-		return nil, err
-	}
-	if err := attachPricesAndComponents(ctx, db, ids, products, idx); err != nil {
+	if err := attachProductChildren(ctx, db, products); err != nil {
 		return nil, err
 	}
 	return &products[0], nil
 }
 
 func attachAttributes(
-	ctx context.Context, db *bun.DB,
+	ctx context.Context, db bun.IDB,
 	ids []uuid.UUID, products []models.Product, idx map[uuid.UUID]int,
 ) error {
 	var rows []models.ProductAttributeRow
@@ -101,7 +112,7 @@ func attachAttributes(
 }
 
 func attachPackagings(
-	ctx context.Context, db *bun.DB,
+	ctx context.Context, db bun.IDB,
 	ids []uuid.UUID, products []models.Product, idx map[uuid.UUID]int,
 ) error {
 	var rows []models.ProductPackagingRow
@@ -124,7 +135,7 @@ func attachPackagings(
 }
 
 func attachSuppliers(
-	ctx context.Context, db *bun.DB,
+	ctx context.Context, db bun.IDB,
 	ids []uuid.UUID, products []models.Product, idx map[uuid.UUID]int,
 ) error {
 	var rows []models.ProductSupplierRow
@@ -150,7 +161,7 @@ func attachSuppliers(
 }
 
 func attachVariants(
-	ctx context.Context, db *bun.DB,
+	ctx context.Context, db bun.IDB,
 	ids []uuid.UUID, products []models.Product, idx map[uuid.UUID]int,
 ) error {
 	var rows []models.ProductVariantRow
@@ -183,7 +194,7 @@ func attachVariants(
 }
 
 func attachExtras(
-	ctx context.Context, db *bun.DB,
+	ctx context.Context, db bun.IDB,
 	ids []uuid.UUID, products []models.Product, idx map[uuid.UUID]int,
 ) error {
 	var rows []models.ProductExtraRow
@@ -207,7 +218,7 @@ func attachExtras(
 // attachPricesAndComponents pulls price+tier+component rows, then routes each
 // to the right slot (product-level, variant-level, packaging-level, extra-level).
 func attachPricesAndComponents(
-	ctx context.Context, db *bun.DB,
+	ctx context.Context, db bun.IDB,
 	ids []uuid.UUID, products []models.Product, idx map[uuid.UUID]int,
 ) error {
 	var priceRows []models.ProductPriceRow
@@ -372,7 +383,17 @@ func saveProductChildren(
 		}
 	}
 
-	// 3. Suppliers — replace.
+	// 3. Suppliers — replace. Cost changes are logged to the supplier cost
+	// history, so remember what each supplier charged before.
+	var oldSuppliers []models.ProductSupplierRow
+	if err := tx.NewSelect().Model(&oldSuppliers).
+		Where("product_id = ?", p.ID).Scan(ctx); err != nil {
+		return err
+	}
+	oldCosts := make(map[uuid.UUID]float64, len(oldSuppliers))
+	for _, s := range oldSuppliers {
+		oldCosts[s.SupplierID] = s.UnitCost
+	}
 	if _, err := tx.NewDelete().Model((*models.ProductSupplierRow)(nil)).
 		Where("product_id = ?", p.ID).Exec(ctx); err != nil {
 		return err
@@ -394,6 +415,9 @@ func saveProductChildren(
 		}
 		s.ID = row.ID
 	}
+	if err := recordSupplierCostChanges(ctx, tx, p.ID, oldCosts, p.Suppliers); err != nil {
+		return err
+	}
 
 	// 4. Attributes — replace.
 	if _, err := tx.NewDelete().Model((*models.ProductAttributeRow)(nil)).
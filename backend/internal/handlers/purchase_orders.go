@@ -60,9 +60,25 @@ func (h *PurchaseOrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	in.ID = uuid.Nil
+	in.DebitNoteAmount = 0
+	// Payments come in through supplier payments once the PO is sent.
+	in.PaidAmount, in.Payments = 0, nil
 	normalizePO(&in)
+	// Every PO starts as a draft; status moves through the transition
+	// endpoints.
+	in.Status = models.POStatusDraft
+	in.ReceivedDate, in.DueDate = "", ""
+	for i := range in.Lines {
+		in.Lines[i].ReceivedQty = 0
+	}
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	in.StoreID = storeID
 
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		code, err := nextPOCode(ctx, tx)
 		if err != nil {
 			return err
@@ -71,7 +87,10 @@ func (h *PurchaseOrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		if _, err := tx.NewInsert().Model(&in).Returning("*").Exec(ctx); err != nil {
 			return err
 		}
-		return savePOChildren(ctx, tx, &in)
+		if err := savePOChildren(ctx, tx, &in); err != nil {
+			return err
+		}
+		return logPOTransition(ctx, tx, in.ID, models.POActionCreate, "", in.Status, purchaseOrderTotal(in.Lines), "")
 	})
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -104,25 +123,55 @@ func (h *PurchaseOrdersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	normalizePO(&in)
 
 	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
-		res, err := tx.NewUpdate().Model(&in).
+		var cur models.PurchaseOrder
+		if err := tx.NewSelect().Model(&cur).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if err := tx.NewSelect().Model(&cur.Lines).
+			Where("purchase_order_id = ?", id).Order("position ASC").Scan(ctx); err != nil {
+			return err
+		}
+		if err := tx.NewSelect().Model(&cur.Payments).
+			Where("purchase_order_id = ?", id).Order("paid_at ASC").Scan(ctx); err != nil {
+			return err
+		}
+		rules := poEditRulesFor(cur.Status)
+		if err := checkPOEdit(&cur, &in, rules); err != nil {
+			return err
+		}
+
+		next := cur
+		next.Notes = in.Notes
+		if rules.Header {
+			next.Type, next.SupplierID, next.OrderDate = in.Type, in.SupplierID, in.OrderDate
+		}
+		if rules.ExpectedDate {
+			next.ExpectedDate = in.ExpectedDate
+		}
+		if _, err := tx.NewUpdate().Model(&next).
 			WherePK().
-			ExcludeColumn("id", "code", "created_at", "updated_at").
+			ExcludeColumn("id", "code", "store_id", "status", "received_date", "due_date",
+				"paid_amount", "debit_note_amount", "created_at", "updated_at").
 			Set("updated_at = current_timestamp").
-			Exec(ctx)
-		if err != nil {
+			Exec(ctx); err != nil {
 			return err
 		}
-		if n, _ := res.RowsAffected(); n == 0 {
-			return errNotFound
+		if rules.Lines {
+			// Only drafts edit lines, and drafts have received nothing.
+			next.Lines = in.Lines
+			for i := range next.Lines {
+				next.Lines[i].ReceivedQty = 0
+			}
+			return syncPOLines(ctx, tx, &next)
 		}
-		return savePOChildren(ctx, tx, &in)
+		return nil
 	})
 	if errors.Is(err, errNotFound) {
 		writeError(w, http.StatusNotFound, "not found")
 		return
 	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeTxError(w, err)
 		return
 	}
 	full, err := loadPurchaseOrder(r.Context(), h.deps.DB, id)
@@ -171,7 +220,7 @@ func validatePO(p *models.PurchaseOrder) string {
 
 func normalizePO(p *models.PurchaseOrder) {
 	p.Type = poTypeOrDefault(p.Type)
-	p.Status = poStatusOrDefault(p.Status)
+	p.Status = strings.TrimSpace(p.Status)
 	p.OrderDate = strings.TrimSpace(p.OrderDate)
 	p.ExpectedDate = strings.TrimSpace(p.ExpectedDate)
 	p.ReceivedDate = strings.TrimSpace(p.ReceivedDate)
@@ -186,14 +235,6 @@ func poTypeOrDefault(s string) string {
 	return models.POTypeStandard
 }
 
-func poStatusOrDefault(s string) string {
-	switch strings.TrimSpace(s) {
-	case models.POStatusSent, models.POStatusPartial, models.POStatusReceived, models.POStatusCancelled:
-		return s
-	}
-	return models.POStatusDraft
-}
-
 // nextPOCode generates the next PO code for the current year. Format:
 // PO-YYYY-NNN where NNN is 3-digit padded. Counts existing rows whose code
 // matches the year prefix so the sequence resets each January. A UNIQUE
@@ -10,8 +10,9 @@ import (
 
 func loadPurchaseOrders(ctx context.Context, db *bun.DB) ([]models.PurchaseOrder, error) {
 	var pos []models.PurchaseOrder
-	if err := db.NewSelect().Model(&pos).
-		Order("order_date DESC, created_at DESC").Scan(ctx); err != nil {
+	q := db.NewSelect().Model(&pos).
+		Order("order_date DESC, created_at DESC")
+	if err := scopeStore(ctx, q, "po.store_id").Scan(ctx); err != nil {
 		return nil, err
 	}
 	if len(pos) == 0 {
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/documents"
+	"github.com/sandisahdewo/pos/backend/internal/mail"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// poEmailStatuses are the statuses a PO may be emailed in: approved (the
+// email is what sends it) and resends while goods are still expected.
+var poEmailStatuses = []string{models.POStatusApproved, models.POStatusSent, models.POStatusPartial}
+
+// purchaseOrderDocument renders a PO for the supplier: its details,
+// every line with the supplier's own SKU and the ordered unit, the
+// expected date and notes.
+func purchaseOrderDocument(
+	ctx context.Context, db bun.IDB, po *models.PurchaseOrder, supplier *models.Supplier,
+) ([]byte, error) {
+	var info []struct {
+		LineID      uuid.UUID `bun:"id"`
+		ProductName string    `bun:"product_name"`
+		VariantName string    `bun:"variant_name"`
+		SKU         string    `bun:"sku"`
+		SupplierSKU string    `bun:"supplier_sku"`
+		Unit        string    `bun:"unit"`
+	}
+	if err := db.NewSelect().TableExpr("purchase_order_lines AS pol").
+		ColumnExpr("pol.id, p.name AS product_name, p.sku").
+		ColumnExpr("COALESCE(pv.name, '') AS variant_name").
+		ColumnExpr("COALESCE(ps.supplier_sku, '') AS supplier_sku").
+		ColumnExpr("COALESCE(u.code, bu.code, '') AS unit").
+		Join("JOIN products AS p ON p.id = pol.product_id").
+		Join("LEFT JOIN product_variants AS pv ON pv.id = pol.variant_id").
+		Join("LEFT JOIN product_suppliers AS ps ON ps.product_id = pol.product_id AND ps.supplier_id = ?", supplier.ID).
+		Join("LEFT JOIN units AS u ON u.id = pol.unit_id").
+		Join("LEFT JOIN units AS bu ON bu.id = p.unit_id").
+		Where("pol.purchase_order_id = ?", po.ID).
+		Scan(ctx, &info); err != nil {
+		return nil, err
+	}
+	byLine := make(map[uuid.UUID]int, len(info))
+	for i, l := range info {
+		byLine[l.LineID] = i
+	}
+
+	rows := make([][]string, 0, len(po.Lines))
+	total := 0.0
+	for i, l := range po.Lines {
+		name, sku, supplierSKU, unit := l.ProductID.String(), "", "", ""
+		if j, ok := byLine[l.ID]; ok {
+			name, sku, supplierSKU, unit = info[j].ProductName, info[j].SKU, info[j].SupplierSKU, info[j].Unit
+			if info[j].VariantName != "" {
+				name += " - " + info[j].VariantName
+			}
+		}
+		if l.UnitFactor > 1 {
+			unit += " (isi " + formatDecimal(l.UnitFactor) + ")"
+		}
+		if l.Notes != "" {
+			name += " — " + l.Notes
+		}
+		if supplierSKU == "" {
+			supplierSKU = sku
+		}
+		total += l.Quantity * l.UnitPrice
+		rows = append(rows, []string{
+			fmt.Sprint(i + 1), supplierSKU, name,
+			formatDecimal(l.Quantity), unit, formatRupiah(l.UnitPrice), formatRupiah(l.Quantity * l.UnitPrice),
+		})
+	}
+
+	meta := []documents.Field{
+		{Label: "Kepada", Value: supplier.Name},
+	}
+	if supplier.ContactPerson != "" {
+		meta = append(meta, documents.Field{Label: "Up.", Value: supplier.ContactPerson})
+	}
+	if supplier.Address != "" {
+		meta = append(meta, documents.Field{Label: "Alamat", Value: supplier.Address})
+	}
+	if contact := strings.Trim(supplier.Phone+" / "+supplier.Email, " /"); contact != "" {
+		meta = append(meta, documents.Field{Label: "Telp / email", Value: contact})
+	}
+	meta = append(meta, documents.Field{Label: "Tanggal PO", Value: labelDate(po.OrderDate)})
+	if po.ExpectedDate != "" {
+		meta = append(meta, documents.Field{Label: "Diharapkan tiba", Value: labelDate(po.ExpectedDate)})
+	}
+	if supplier.PaymentTermsDays > 0 {
+		meta = append(meta, documents.Field{
+			Label: "Termin", Value: fmt.Sprintf("%d hari setelah barang diterima", supplier.PaymentTermsDays),
+		})
+	}
+
+	approver := ""
+	_ = db.NewSelect().Table("purchase_order_transitions").Column("actor").
+		Where("purchase_order_id = ?", po.ID).
+		Where("to_status = ?", models.POStatusApproved).
+		OrderExpr("created_at DESC").Limit(1).
+		Scan(ctx, &approver)
+	return documents.PDF(documents.Document{
+		Title: "Purchase Order",
+		Code:  po.Code,
+		Meta:  meta,
+		Columns: []documents.Column{
+			{Title: "No", Width: 3, Right: true},
+			{Title: "Kode pemasok", Width: 12},
+			{Title: "Barang", Width: 32},
+			{Title: "Qty", Width: 7, Right: true},
+			{Title: "Satuan", Width: 10},
+			{Title: "Harga", Width: 12, Right: true},
+			{Title: "Jumlah", Width: 14, Right: true},
+		},
+		Rows:   rows,
+		Totals: []string{"", "", "Total", "", "", "", formatRupiah(total)},
+		Notes:  po.Notes,
+		Signatures: []documents.Signature{
+			{Caption: "Disetujui oleh", Name: approver},
+			{Caption: "Dikonfirmasi oleh (" + supplier.Name + ")"},
+		},
+	}), nil
+}
+
+func loadPOForDocument(ctx context.Context, db *bun.DB, id uuid.UUID) (*models.PurchaseOrder, *models.Supplier, error) {
+	po, err := loadPurchaseOrder(ctx, db, id)
+	if err != nil {
+		return nil, nil, errNotFound
+	}
+	var supplier models.Supplier
+	if err := db.NewSelect().Model(&supplier).Where("id = ?", po.SupplierID).Scan(ctx); err != nil {
+		return nil, nil, err
+	}
+	return po, &supplier, nil
+}
+
+// PDF renders the PO as the supplier receives it.
+func (h *PurchaseOrdersHandler) PDF(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx := r.Context()
+	po, supplier, err := loadPOForDocument(ctx, h.deps.DB, id)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	pdf, err := purchaseOrderDocument(ctx, h.deps.DB, po, supplier)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", po.Code+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(pdf)
+}
+
+type poEmailInput struct {
+	Cc      []string `json:"cc"`
+	Message string   `json:"message"`
+}
+
+type poEmailResult struct {
+	Email         models.PurchaseOrderEmail `json:"email"`
+	PurchaseOrder *models.PurchaseOrder     `json:"purchaseOrder"`
+}
+
+// Email sends the PO's PDF to the supplier's email address from the
+// configured sender, replying to the user who sent it. Every attempt is
+// logged with its outcome. Delivering an approved PO marks it sent, as
+// the send action would; sent and partial POs may be re-sent. A failed
+// delivery is logged and answered with 502, leaving the status alone.
+func (h *PurchaseOrdersHandler) Email(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in poEmailInput
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	if h.deps.Mailer == nil {
+		writeError(w, http.StatusServiceUnavailable, mail.ErrNotConfigured.Error())
+		return
+	}
+	ctx := r.Context()
+	po, supplier, err := loadPOForDocument(ctx, h.deps.DB, id)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if po.Type != models.POTypeStandard {
+		writeError(w, http.StatusBadRequest, "hanya PO standar yang dikirim ke pemasok")
+		return
+	}
+	if !slices.Contains(poEmailStatuses, po.Status) {
+		writeError(w, http.StatusBadRequest, "PO berstatus "+po.Status+" tidak bisa dikirim")
+		return
+	}
+	to := strings.TrimSpace(supplier.Email)
+	if to == "" {
+		writeError(w, http.StatusBadRequest, "email pemasok "+supplier.Name+" belum diisi")
+		return
+	}
+	cc := []string{}
+	for _, c := range in.Cc {
+		if c = strings.TrimSpace(c); c != "" {
+			cc = append(cc, c)
+		}
+	}
+	pdf, err := purchaseOrderDocument(ctx, h.deps.DB, po, supplier)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rec := models.PurchaseOrderEmail{
+		PurchaseOrderID: po.ID,
+		Sender:          h.deps.Mailer.From(),
+		Recipients:      []string{to},
+		Cc:              cc,
+		Subject:         "Purchase Order " + po.Code,
+		Message:         strings.TrimSpace(in.Message),
+		Attachment:      po.Code + ".pdf",
+		SentBy:          actorName(ctx, h.deps.DB),
+	}
+	replyTo := ""
+	if claims, ok := auth.ClaimsFrom(ctx); ok {
+		rec.SentByID = &claims.UserID
+		_ = h.deps.DB.NewSelect().Table("users").Column("email").
+			Where("id = ?", claims.UserID).Scan(ctx, &replyTo)
+	}
+	sendErr := h.deps.Mailer.Send(ctx, mail.Message{
+		To:      rec.Recipients,
+		Cc:      rec.Cc,
+		ReplyTo: replyTo,
+		Subject: rec.Subject,
+		Body:    poEmailBody(po, supplier, rec.Message, rec.SentBy),
+		Attachments: []mail.Attachment{
+			{Filename: rec.Attachment, ContentType: "application/pdf", Data: pdf},
+		},
+	})
+	rec.Status = models.POEmailSent
+	if sendErr != nil {
+		rec.Status = models.POEmailFailed
+		rec.Error = sendErr.Error()
+	}
+
+	// The email has gone out (or not) either way; the log and the status
+	// change are committed together afterwards.
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(&rec).Exec(ctx); err != nil {
+			return err
+		}
+		if sendErr != nil {
+			return nil
+		}
+		var cur models.PurchaseOrder
+		if err := tx.NewSelect().Model(&cur).Where("id = ?", po.ID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if cur.Status != models.POStatusApproved {
+			return nil
+		}
+		if _, err := tx.NewUpdate().Table("purchase_orders").
+			Set("status = ?", models.POStatusSent).
+			Set("updated_at = current_timestamp").
+			Where("id = ?", po.ID).Exec(ctx); err != nil {
+			return err
+		}
+		return logPOTransition(ctx, tx, po.ID, models.POActionSend, cur.Status, models.POStatusSent,
+			purchaseOrderTotal(po.Lines), "dikirim lewat email ke "+to)
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sendErr != nil {
+		writeError(w, http.StatusBadGateway, "gagal mengirim email: "+sendErr.Error())
+		return
+	}
+	full, err := loadPurchaseOrder(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, poEmailResult{Email: rec, PurchaseOrder: full})
+}
+
+func poEmailBody(po *models.PurchaseOrder, supplier *models.Supplier, message, sender string) string {
+	to := supplier.ContactPerson
+	if to == "" {
+		to = supplier.Name
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Yth. %s,\n\n", to)
+	fmt.Fprintf(&b, "Terlampir purchase order %s tertanggal %s.", po.Code, labelDate(po.OrderDate))
+	if po.ExpectedDate != "" {
+		fmt.Fprintf(&b, " Mohon barang dikirim paling lambat %s.", labelDate(po.ExpectedDate))
+	}
+	b.WriteString(" Harap konfirmasi ketersediaan dan jadwal pengiriman dengan membalas email ini.\n\n")
+	if message != "" {
+		b.WriteString(message + "\n\n")
+	}
+	b.WriteString("Terima kasih,\n" + sender + "\n")
+	return b.String()
+}
+
+// Emails lists every attempt to email the PO, newest first.
+func (h *PurchaseOrdersHandler) Emails(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	items := []models.PurchaseOrderEmail{}
+	if err := h.deps.DB.NewSelect().Model(&items).
+		Where("purchase_order_id = ?", id).
+		Order("created_at DESC").Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
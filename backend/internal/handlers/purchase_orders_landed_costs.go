@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// landedCostInput is one charge on a receipt: freight, unloading, duty,
+// insurance or other. SupplierID / Reference identify who billed it.
+type landedCostInput struct {
+	Kind       string  `json:"kind"`
+	Amount     float64 `json:"amount"`
+	SupplierID *string `json:"supplierId,omitempty"`
+	Reference  string  `json:"reference"`
+	Notes      string  `json:"notes"`
+}
+
+type addLandedCostsInput struct {
+	AllocationMethod string            `json:"allocationMethod"`
+	LandedCosts      []landedCostInput `json:"landedCosts"`
+}
+
+// Receipts lists a PO's receipts with their landed cost charges (and how
+// each was allocated) and the batches they created.
+func (h *PurchaseOrdersHandler) Receipts(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	items := []models.PurchaseOrderReceipt{}
+	if err := h.deps.DB.NewSelect().Model(&items).
+		Relation("LandedCosts", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("plc.created_at ASC")
+		}).
+		Relation("LandedCosts.Allocations").
+		Relation("Batches", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("bt.created_at ASC")
+		}).
+		Where("por.purchase_order_id = ?", id).
+		Order("por.created_at ASC").
+		Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range items {
+		if items[i].LandedCosts == nil {
+			items[i].LandedCosts = []models.PurchaseOrderLandedCost{}
+		}
+		if items[i].Batches == nil {
+			items[i].Batches = []models.Batch{}
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// AddLandedCosts books charges that arrive after the goods (the forwarder's
+// invoice, customs duty) against an existing receipt. They are spread over
+// the receipt's batches the same way as at receive time; stock already sold
+// keeps the cost it was sold at.
+func (h *PurchaseOrdersHandler) AddLandedCosts(w http.ResponseWriter, r *http.Request) {
+	poID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	receiptID, err := uuid.Parse(chi.URLParam(r, "receiptId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid receipt id")
+		return
+	}
+	var in addLandedCostsInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(in.LandedCosts) == 0 {
+		writeError(w, http.StatusBadRequest, "tidak ada biaya untuk dialokasikan")
+		return
+	}
+	createdBy := actorName(r.Context(), h.deps.DB)
+
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var rc models.PurchaseOrderReceipt
+		if err := tx.NewSelect().Model(&rc).
+			Where("id = ? AND purchase_order_id = ?", receiptID, poID).
+			For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		var poType string
+		if err := tx.NewSelect().Table("purchase_orders").Column("type").
+			Where("id = ?", poID).Scan(ctx, &poType); err != nil {
+			return err
+		}
+		if poType == models.POTypeConsignment {
+			return errBadInput("biaya tambahan tidak berlaku untuk PO konsinyasi")
+		}
+		if m := strings.TrimSpace(in.AllocationMethod); m != "" && m != rc.AllocationMethod {
+			if rc.LandedCostTotal > 0 {
+				return errBadInput("metode alokasi penerimaan ini sudah " + rc.AllocationMethod)
+			}
+			if !validAllocationMethod(m) {
+				return errBadInput("metode alokasi harus value, qty atau weight")
+			}
+			rc.AllocationMethod = m
+			if _, err := tx.NewUpdate().Table("purchase_order_receipts").
+				Set("allocation_method = ?", m).
+				Where("id = ?", rc.ID).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		var batches []models.Batch
+		if err := tx.NewSelect().Model(&batches).
+			Where("receipt_id = ?", rc.ID).
+			Order("created_at ASC").
+			For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		return allocateLandedCosts(ctx, tx, &rc, batches, in.LandedCosts, createdBy)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	var out models.PurchaseOrderReceipt
+	if err := h.deps.DB.NewSelect().Model(&out).
+		Relation("LandedCosts").
+		Relation("LandedCosts.Allocations").
+		Relation("Batches").
+		Where("por.id = ?", receiptID).
+		Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func validAllocationMethod(m string) bool {
+	return m == models.AllocateByValue || m == models.AllocateByQty || m == models.AllocateByWeight
+}
+
+// validateLandedCosts checks the charges before anything is written.
+func validateLandedCosts(inputs []landedCostInput) error {
+	for _, lc := range inputs {
+		if !slices.Contains(models.LandedCostKinds, strings.TrimSpace(lc.Kind)) {
+			return errBadInput("jenis biaya harus salah satu dari " + strings.Join(models.LandedCostKinds, ", "))
+		}
+		if lc.Amount <= 0 {
+			return errBadInput("jumlah biaya harus lebih dari 0")
+		}
+		if lc.SupplierID != nil && strings.TrimSpace(*lc.SupplierID) != "" {
+			if _, err := uuid.Parse(strings.TrimSpace(*lc.SupplierID)); err != nil {
+				return errBadInput("supplierId biaya tidak valid")
+			}
+		}
+	}
+	return nil
+}
+
+// allocateLandedCosts stores each charge and spreads it over the batches in
+// proportion to the receipt's basis: purchase value (qty × supplier unit
+// cost), quantity in base units, or recorded weight. Each batch's share is
+// added to unit_cost per base unit; the last batch absorbs rounding so the
+// shares always sum to the charge.
+func allocateLandedCosts(
+	ctx context.Context, tx bun.Tx,
+	rc *models.PurchaseOrderReceipt,
+	batches []models.Batch,
+	inputs []landedCostInput,
+	createdBy string,
+) error {
+	if err := validateLandedCosts(inputs); err != nil {
+		return err
+	}
+	if len(batches) == 0 {
+		return errBadInput("penerimaan ini tidak punya batch untuk dialokasikan")
+	}
+	basis := make([]float64, len(batches))
+	total := 0.0
+	for i, b := range batches {
+		switch rc.AllocationMethod {
+		case models.AllocateByQty:
+			basis[i] = b.QtyReceived
+		case models.AllocateByWeight:
+			basis[i] = b.ReceivedWeight
+		default:
+			basis[i] = b.QtyReceived * (b.UnitCost - b.LandedUnitCost)
+		}
+		total += basis[i]
+	}
+	if total <= 0 {
+		if rc.AllocationMethod == models.AllocateByWeight {
+			return errBadInput("berat barang belum dicatat saat penerimaan")
+		}
+		return errBadInput("dasar alokasi biaya bernilai nol")
+	}
+
+	added := make([]float64, len(batches))
+	sum := 0.0
+	for _, lc := range inputs {
+		charge := models.PurchaseOrderLandedCost{
+			ReceiptID:  rc.ID,
+			Kind:       strings.TrimSpace(lc.Kind),
+			Amount:     math.Round(lc.Amount*100) / 100,
+			SupplierID: optUUID(lc.SupplierID),
+			Reference:  strings.TrimSpace(lc.Reference),
+			Notes:      strings.TrimSpace(lc.Notes),
+			CreatedBy:  createdBy,
+		}
+		if _, err := tx.NewInsert().Model(&charge).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		allocs := make([]models.LandedCostAllocation, len(batches))
+		left := charge.Amount
+		for i, b := range batches {
+			share := math.Round(charge.Amount*basis[i]/total*100) / 100
+			if i == len(batches)-1 {
+				share = math.Round(left*100) / 100
+			}
+			left -= share
+			unit := 0.0
+			if b.QtyReceived > 0 {
+				unit = share / b.QtyReceived
+			}
+			added[i] += unit
+			allocs[i] = models.LandedCostAllocation{
+				LandedCostID: charge.ID,
+				BatchID:      b.ID,
+				Basis:        basis[i],
+				Amount:       share,
+				UnitAmount:   unit,
+			}
+		}
+		if _, err := tx.NewInsert().Model(&allocs).Exec(ctx); err != nil {
+			return err
+		}
+		sum += charge.Amount
+	}
+
+	for i, b := range batches {
+		if added[i] == 0 {
+			continue
+		}
+		if _, err := tx.NewUpdate().Table("batches").
+			Set("unit_cost = unit_cost + ?", added[i]).
+			Set("landed_unit_cost = landed_unit_cost + ?", added[i]).
+			Set("updated_at = current_timestamp").
+			Where("id = ?", b.ID).Exec(ctx); err != nil {
+			return err
+		}
+	}
+	rc.LandedCostTotal += sum
+	_, err := tx.NewUpdate().Table("purchase_order_receipts").
+		Set("landed_cost_total = ?", rc.LandedCostTotal).
+		Where("id = ?", rc.ID).Exec(ctx)
+	return err
+}
+
+func nextReceiptCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("RCV-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("purchase_order_receipts").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%04d", prefix, count+1), nil
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// poReceiveLineInput is one line of a receipt. Qty is in the PO line's unit
+// (same as PurchaseOrderLine.Quantity); the batch is created in base units
+// via the line's unit_factor snapshot.
+type poReceiveLineInput struct {
+	LineID     string   `json:"lineId"`
+	Qty        float64  `json:"qty"`
+	ExpiresAt  string   `json:"expiresAt"`
+	UnitPrice  *float64 `json:"unitPrice,omitempty"`
+	LocationID *string  `json:"locationId,omitempty"`
+	Notes      string   `json:"notes"`
+	// One per received base unit; required for serial-tracked products.
+	Serials []string `json:"serials,omitempty"`
+	// Total weight of the line as received (kg); the basis for
+	// weight-allocated landed costs.
+	Weight float64 `json:"weight,omitempty"`
+}
+
+type poReceiveInput struct {
+	ReceivedDate string               `json:"receivedDate"`
+	LocationID   *string              `json:"locationId,omitempty"`
+	Notes        string               `json:"notes"`
+	Lines        []poReceiveLineInput `json:"lines"`
+	// Landed costs (freight, duty, ...) spread over this receipt's batches
+	// by AllocationMethod: value (default), qty or weight.
+	AllocationMethod string            `json:"allocationMethod"`
+	LandedCosts      []landedCostInput `json:"landedCosts"`
+}
+
+type poReceiveResult struct {
+	PurchaseOrder *models.PurchaseOrder        `json:"purchaseOrder"`
+	Receipt       *models.PurchaseOrderReceipt `json:"receipt"`
+	Batches       []models.Batch               `json:"batches"`
+}
+
+// Receive books goods against a PO in one transaction: creates one batch per
+// received line (owned vs consignment from the PO type), logs a `receive`
+// movement per batch, bumps received_qty and moves the PO to partial or
+// received. Replaces the FE flow that PATCHed the PO and then created
+// batches one request at a time. Each call is recorded as a receipt; landed
+// costs sent with it are folded into the new batches' unit_cost.
+func (h *PurchaseOrdersHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in poReceiveInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(in.Lines) == 0 {
+		writeError(w, http.StatusBadRequest, "tidak ada item untuk diterima")
+		return
+	}
+	receivedDate := strings.TrimSpace(in.ReceivedDate)
+	if receivedDate == "" {
+		receivedDate = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", receivedDate); err != nil {
+		writeTxError(w, errBadInput("tanggal terima harus YYYY-MM-DD"))
+		return
+	}
+	method := strings.TrimSpace(in.AllocationMethod)
+	if method == "" {
+		method = models.AllocateByValue
+	}
+	if !validAllocationMethod(method) {
+		writeError(w, http.StatusBadRequest, "metode alokasi harus value, qty atau weight")
+		return
+	}
+	if err := validateLandedCosts(in.LandedCosts); err != nil {
+		writeTxError(w, err)
+		return
+	}
+	settings, err := loadServerSettings(r.Context(), h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
+
+	var created []models.Batch
+	var receipt models.PurchaseOrderReceipt
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var po models.PurchaseOrder
+		if err := tx.NewSelect().Model(&po).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errNotFound
+			}
+			return err
+		}
+		switch po.Status {
+		case models.POStatusSent, models.POStatusPartial:
+		case models.POStatusDraft, models.POStatusPendingApproval, models.POStatusApproved:
+			return errBadInput("kirim PO terlebih dulu")
+		case models.POStatusReceived:
+			return errBadInput("PO sudah diterima sepenuhnya")
+		case models.POStatusClosed:
+			return errBadInput("PO sudah ditutup")
+		default:
+			return errBadInput("PO sudah dibatalkan")
+		}
+		if po.Type == models.POTypeConsignment && len(in.LandedCosts) > 0 {
+			return errBadInput("biaya tambahan tidak berlaku untuk PO konsinyasi")
+		}
+
+		var lines []models.PurchaseOrderLine
+		if err := tx.NewSelect().Model(&lines).
+			Where("purchase_order_id = ?", po.ID).
+			Order("position ASC").Scan(ctx); err != nil {
+			return err
+		}
+		lineByID := make(map[uuid.UUID]*models.PurchaseOrderLine, len(lines))
+		for i := range lines {
+			lineByID[lines[i].ID] = &lines[i]
+		}
+
+		headerLoc, err := resolveReceiptLocation(ctx, tx, in.LocationID)
+		if err != nil {
+			return err
+		}
+
+		code, err := nextReceiptCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		receipt = models.PurchaseOrderReceipt{
+			Code:             code,
+			PurchaseOrderID:  po.ID,
+			ReceivedDate:     receivedDate,
+			AllocationMethod: method,
+			Notes:            strings.TrimSpace(in.Notes),
+			PerformedBy:      performedBy,
+		}
+		if _, err := tx.NewInsert().Model(&receipt).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+
+		batches, err := receivePOLines(ctx, tx, &po, lineByID, in.Lines, receiveOpts{
+			receivedDate: receivedDate,
+			locationID:   headerLoc,
+			tolerancePct: settings.Purchasing.OverReceiptTolerancePct,
+			performedBy:  performedBy,
+			notes:        strings.TrimSpace(in.Notes),
+			receiptID:    receipt.ID,
+		})
+		if err != nil {
+			return err
+		}
+		created = batches
+
+		if len(in.LandedCosts) > 0 {
+			if err := allocateLandedCosts(ctx, tx, &receipt, batches, in.LandedCosts, performedBy); err != nil {
+				return err
+			}
+			// The receive movements carry the landed unit cost, not the
+			// supplier price they were logged with.
+			ids := make([]uuid.UUID, len(batches))
+			for i := range batches {
+				ids[i] = batches[i].ID
+			}
+			if _, err := tx.NewUpdate().TableExpr("stock_movements AS sm").
+				Set("unit_cost = (SELECT bt.unit_cost FROM batches AS bt WHERE bt.id = sm.batch_id)").
+				Where("sm.kind = ?", models.MovementKindReceive).
+				Where("sm.batch_id IN (?)", bun.In(ids)).
+				Exec(ctx); err != nil {
+				return err
+			}
+			created = created[:0]
+			if err := tx.NewSelect().Model(&created).
+				Where("id IN (?)", bun.In(ids)).
+				Order("created_at ASC").Scan(ctx); err != nil {
+				return err
+			}
+		}
+
+		allReceived := true
+		for _, l := range lines {
+			if l.Quantity > 0 && l.ReceivedQty < l.Quantity {
+				allReceived = false
+				break
+			}
+		}
+		q := tx.NewUpdate().Table("purchase_orders").Where("id = ?", po.ID).
+			Set("updated_at = current_timestamp")
+		to := models.POStatusPartial
+		if allReceived {
+			to = models.POStatusReceived
+			q = q.Set("received_date = ?", receivedDate)
+		}
+		if po.DueDate == "" && po.Type == models.POTypeStandard {
+			// Payment terms run from the first delivery.
+			due, err := poDueDate(ctx, tx, po.SupplierID, receivedDate)
+			if err != nil {
+				return err
+			}
+			q = q.Set("due_date = ?", due)
+		}
+		if _, err := q.Set("status = ?", to).Exec(ctx); err != nil {
+			return err
+		}
+		// Invoices that ran ahead of the goods may match now.
+		if err := matchSupplierInvoices(ctx, tx, []uuid.UUID{po.ID}, settings); err != nil {
+			return err
+		}
+		return logPOTransition(ctx, tx, po.ID, models.POActionReceive, po.Status, to, purchaseOrderTotal(lines), receipt.Code)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadPurchaseOrder(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if created == nil {
+		created = []models.Batch{}
+	}
+	writeJSON(w, http.StatusOK, poReceiveResult{PurchaseOrder: full, Receipt: &receipt, Batches: created})
+}
+
+type receiveOpts struct {
+	receivedDate string
+	locationID   uuid.UUID
+	tolerancePct float64
+	performedBy  string
+	notes        string
+	receiptID    uuid.UUID
+}
+
+// receivePOLines validates each requested line against what is still open
+// (plus tolerance), then creates the batch + movement and bumps the line's
+// received_qty. lineByID entries are mutated in place so the caller can
+// derive the new PO status.
+func receivePOLines(
+	ctx context.Context, tx bun.Tx,
+	po *models.PurchaseOrder,
+	lineByID map[uuid.UUID]*models.PurchaseOrderLine,
+	inputs []poReceiveLineInput,
+	opts receiveOpts,
+) ([]models.Batch, error) {
+	ownership := models.BatchOwnershipOwned
+	movementNote := "Penerimaan PO"
+	if po.Type == models.POTypeConsignment {
+		ownership = models.BatchOwnershipConsignment
+		movementNote = "Penerimaan konsinyasi"
+	}
+	if opts.notes != "" {
+		movementNote = movementNote + " — " + opts.notes
+	}
+
+	seen := map[uuid.UUID]bool{}
+	batches := make([]models.Batch, 0, len(inputs))
+	for _, li := range inputs {
+		lineID, err := uuid.Parse(li.LineID)
+		if err != nil {
+			return nil, errBadInput("lineId tidak valid")
+		}
+		line := lineByID[lineID]
+		if line == nil {
+			return nil, errBadInput("baris PO tidak ditemukan: " + li.LineID)
+		}
+		if seen[lineID] {
+			return nil, errBadInput("baris PO diterima lebih dari sekali dalam satu penerimaan")
+		}
+		seen[lineID] = true
+		if li.Qty < 0 {
+			return nil, errBadInput("qty tidak boleh negatif")
+		}
+		if li.Weight < 0 {
+			return nil, errBadInput("berat tidak boleh negatif")
+		}
+		if li.Qty == 0 {
+			continue
+		}
+		limit := line.Quantity * (1 + math.Max(opts.tolerancePct, 0)/100)
+		if line.ReceivedQty+li.Qty > limit+1e-9 {
+			return nil, errBadInput(fmt.Sprintf(
+				"penerimaan melebihi jumlah pesanan (dipesan %.4g, sudah diterima %.4g, diterima sekarang %.4g)",
+				line.Quantity, line.ReceivedQty, li.Qty))
+		}
+
+		var product models.Product
+		if err := tx.NewSelect().Model(&product).
+			Column("id", "name", "requires_expiration", "serial_tracked").
+			Where("id = ?", line.ProductID).Scan(ctx); err != nil {
+			return nil, err
+		}
+		expiresAt := strings.TrimSpace(li.ExpiresAt)
+		if product.RequiresExpiration && expiresAt == "" {
+			return nil, errBadInput("tanggal kedaluwarsa wajib diisi untuk " + product.Name)
+		}
+
+		locID := opts.locationID
+		if li.LocationID != nil && *li.LocationID != "" {
+			if locID, err = resolveReceiptLocation(ctx, tx, li.LocationID); err != nil {
+				return nil, err
+			}
+		}
+
+		factor := line.UnitFactor
+		if factor <= 0 {
+			factor = 1
+		}
+		unitPrice := line.UnitPrice
+		if li.UnitPrice != nil {
+			if *li.UnitPrice < 0 {
+				return nil, errBadInput("harga tidak boleh negatif")
+			}
+			unitPrice = *li.UnitPrice
+		}
+		baseQty := li.Qty * factor
+		perBaseCost := unitPrice / factor
+		serials, err := receiptSerials(ctx, tx, &product, baseQty, li.Serials)
+		if err != nil {
+			return nil, err
+		}
+
+		code, err := nextBatchCode(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		supplierID := po.SupplierID
+		poID := po.ID
+		b := models.Batch{
+			Code:                      code,
+			ProductID:                 line.ProductID,
+			VariantID:                 line.VariantID,
+			Ownership:                 ownership,
+			SupplierID:                &supplierID,
+			SourcePurchaseOrderID:     &poID,
+			SourcePurchaseOrderLineID: &lineID,
+			UnitCost:                  perBaseCost,
+			QtyReceived:               baseQty,
+			QtyRemaining:              baseQty,
+			ReceivedAt:                opts.receivedDate,
+			ExpiresAt:                 expiresAt,
+			LocationID:                locID,
+			Notes:                     strings.TrimSpace(li.Notes),
+			ReceivedWeight:            li.Weight,
+		}
+		if opts.receiptID != uuid.Nil {
+			b.ReceiptID = &opts.receiptID
+		}
+		if _, err := tx.NewInsert().Model(&b).Returning("*").Exec(ctx); err != nil {
+			return nil, err
+		}
+		if len(serials) > 0 {
+			rows := make([]models.ProductSerial, len(serials))
+			for i, sn := range serials {
+				rows[i] = models.ProductSerial{
+					Serial:    sn,
+					ProductID: b.ProductID,
+					VariantID: b.VariantID,
+					BatchID:   &b.ID,
+					Status:    models.SerialStatusInStock,
+				}
+			}
+			if _, err := tx.NewInsert().Model(&rows).Exec(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		mv := models.StockMovement{
+			Kind:        models.MovementKindReceive,
+			ProductID:   &b.ProductID,
+			VariantID:   b.VariantID,
+			LocationID:  &b.LocationID,
+			BatchID:     &b.ID,
+			QtyDelta:    baseQty,
+			QtyAfter:    b.QtyRemaining,
+			UnitCost:    floatPtr(perBaseCost),
+			Reference:   models.StockMovementReference{Kind: models.MovementRefPO, ID: po.ID.String(), Code: po.Code},
+			PerformedBy: opts.performedBy,
+			Notes:       movementNote,
+		}
+		if err := logMovement(ctx, tx, &mv); err != nil {
+			return nil, err
+		}
+
+		line.ReceivedQty += li.Qty
+		if _, err := tx.NewUpdate().Table("purchase_order_lines").
+			Where("id = ?", line.ID).
+			Set("received_qty = ?", line.ReceivedQty).
+			Exec(ctx); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	if len(batches) == 0 {
+		return nil, errBadInput("tidak ada kuantitas untuk diterima")
+	}
+	return batches, nil
+}
+
+// resolveReceiptLocation parses an explicit location id (checking it is in
+// the active store) or falls back to the default receipt location.
+func resolveReceiptLocation(ctx context.Context, tx bun.Tx, raw *string) (uuid.UUID, error) {
+	if raw == nil || strings.TrimSpace(*raw) == "" {
+		id, err := defaultReceiptLocation(ctx, tx)
+		if err != nil {
+			return uuid.Nil, errBadInput("belum ada lokasi penerimaan default")
+		}
+		return id, nil
+	}
+	id, err := uuid.Parse(strings.TrimSpace(*raw))
+	if err != nil {
+		return uuid.Nil, errBadInput("locationId tidak valid")
+	}
+	if err := checkStoreLocation(ctx, tx, id); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// poDueDate is receivedDate plus the supplier's payment terms.
+func poDueDate(ctx context.Context, db bun.IDB, supplierID uuid.UUID, receivedDate string) (string, error) {
+	var terms int
+	if err := db.NewSelect().Table("suppliers").Column("payment_terms_days").
+		Where("id = ?", supplierID).Scan(ctx, &terms); err != nil {
+		return "", err
+	}
+	d, err := time.Parse("2006-01-02", receivedDate)
+	if err != nil {
+		return "", errBadInput("tanggal terima harus YYYY-MM-DD")
+	}
+	return d.AddDate(0, 0, terms).Format("2006-01-02"), nil
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// ─── PO state machine ───────────────────────────────────────────────────────
+//
+//	draft ─submit─▶ pending-approval ─approve─▶ approved ─send─▶ sent
+//	  │  (total ≤ threshold: straight to approved)  ▲ reject ▶ draft
+//	  └───────────────────────────────────────────────┘
+//	sent/partial ─receive─▶ partial/received ─close─▶ closed
+//	draft/pending-approval/approved/sent ─cancel─▶ cancelled
+//
+// PATCH never changes status; it only edits the fields poEditRulesFor
+// allows in the current one.
+
+type poTransitionRule struct {
+	from []string
+	to   string
+}
+
+var poTransitions = map[string]poTransitionRule{
+	models.POActionSubmit:  {from: []string{models.POStatusDraft}, to: models.POStatusPendingApproval},
+	models.POActionApprove: {from: []string{models.POStatusPendingApproval}, to: models.POStatusApproved},
+	models.POActionReject:  {from: []string{models.POStatusPendingApproval}, to: models.POStatusDraft},
+	models.POActionSend:    {from: []string{models.POStatusApproved}, to: models.POStatusSent},
+	models.POActionClose:   {from: []string{models.POStatusPartial, models.POStatusReceived}, to: models.POStatusClosed},
+	models.POActionCancel: {
+		from: []string{models.POStatusDraft, models.POStatusPendingApproval, models.POStatusApproved, models.POStatusSent},
+		to:   models.POStatusCancelled,
+	},
+}
+
+type poTransitionInput struct {
+	Notes string `json:"notes"`
+}
+
+// Submit sends a draft for approval. POs at or under the purchasing
+// approval threshold are approved on the spot.
+func (h *PurchaseOrdersHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, models.POActionSubmit)
+}
+
+// Approve requires feature.purchasing.approve.
+func (h *PurchaseOrdersHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, models.POActionApprove)
+}
+
+// Reject returns a pending PO to draft for rework. Requires
+// feature.purchasing.approve.
+func (h *PurchaseOrdersHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, models.POActionReject)
+}
+
+// Send marks an approved PO as ordered from the supplier.
+func (h *PurchaseOrdersHandler) Send(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, models.POActionSend)
+}
+
+// Close finishes a PO: a partial one won't get the rest of its goods, a
+// received one is done. Closed POs still take supplier payments.
+func (h *PurchaseOrdersHandler) Close(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, models.POActionClose)
+}
+
+// Cancel drops a PO before anything was received.
+func (h *PurchaseOrdersHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, models.POActionCancel)
+}
+
+func (h *PurchaseOrdersHandler) transition(w http.ResponseWriter, r *http.Request, action string) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in poTransitionInput
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	ctx := r.Context()
+	if action == models.POActionApprove || action == models.POActionReject {
+		ok, err := hasPermission(ctx, h.deps.DB, PermPurchaseApprove)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusForbidden, "Anda tidak berhak menyetujui PO.")
+			return
+		}
+	}
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rule := poTransitions[action]
+
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var po models.PurchaseOrder
+		if err := tx.NewSelect().Model(&po).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if !slices.Contains(rule.from, po.Status) {
+			return errBadInput("PO berstatus " + po.Status + " tidak bisa di-" + action)
+		}
+		if action == models.POActionCancel {
+			n, err := tx.NewSelect().TableExpr("supplier_invoice_lines AS sinl").
+				Join("JOIN supplier_invoices AS si ON si.id = sinl.invoice_id").
+				Where("sinl.purchase_order_id = ?", po.ID).
+				Where("si.status <> ?", models.SupplierInvoiceCancelled).
+				Count(ctx)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return errBadInput("PO sudah ditagih lewat faktur pemasok; batalkan fakturnya dulu")
+			}
+		}
+		var lines []models.PurchaseOrderLine
+		if err := tx.NewSelect().Model(&lines).
+			Where("purchase_order_id = ?", po.ID).Scan(ctx); err != nil {
+			return err
+		}
+		total := purchaseOrderTotal(lines)
+		to := rule.to
+		if action == models.POActionSubmit {
+			if len(lines) == 0 {
+				return errBadInput("Tambahkan minimal satu item.")
+			}
+			if limit := settings.Purchasing.ApprovalThreshold; limit <= 0 || total <= limit {
+				to = models.POStatusApproved
+			}
+		}
+		if _, err := tx.NewUpdate().Table("purchase_orders").
+			Set("status = ?", to).
+			Set("updated_at = current_timestamp").
+			Where("id = ?", po.ID).Exec(ctx); err != nil {
+			return err
+		}
+		return logPOTransition(ctx, tx, po.ID, action, po.Status, to, total, in.Notes)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadPurchaseOrder(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, full)
+}
+
+// Transitions lists a PO's status history, oldest first.
+func (h *PurchaseOrdersHandler) Transitions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	items := []models.PurchaseOrderTransition{}
+	if err := h.deps.DB.NewSelect().Model(&items).
+		Where("purchase_order_id = ?", id).
+		Order("created_at ASC").Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// logPOTransition records a status change by the calling user.
+func logPOTransition(
+	ctx context.Context, tx bun.Tx,
+	poID uuid.UUID, action, from, to string, total float64, notes string,
+) error {
+	t := models.PurchaseOrderTransition{
+		PurchaseOrderID: poID,
+		Action:          action,
+		FromStatus:      from,
+		ToStatus:        to,
+		POTotal:         math.Round(total*100) / 100,
+		Actor:           actorName(ctx, tx),
+		Notes:           strings.TrimSpace(notes),
+	}
+	if claims, ok := auth.ClaimsFrom(ctx); ok {
+		t.ActorID = &claims.UserID
+	}
+	_, err := tx.NewInsert().Model(&t).Exec(ctx)
+	return err
+}
+
+func purchaseOrderTotal(lines []models.PurchaseOrderLine) float64 {
+	total := 0.0
+	for _, l := range lines {
+		total += l.Quantity * l.UnitPrice
+	}
+	return total
+}
+
+// poEditRules is what a PATCH may change besides notes, which are always
+// editable. Payments are never edited here; they are allocations of
+// supplier payments.
+type poEditRules struct {
+	// Type, supplier and order date.
+	Header       bool
+	Lines        bool
+	ExpectedDate bool
+}
+
+func poEditRulesFor(status string) poEditRules {
+	switch status {
+	case models.POStatusDraft:
+		return poEditRules{Header: true, Lines: true, ExpectedDate: true}
+	case models.POStatusApproved, models.POStatusSent, models.POStatusPartial:
+		return poEditRules{ExpectedDate: true}
+	}
+	// pending-approval is frozen while it is reviewed; received, closed
+	// and cancelled only take notes.
+	return poEditRules{}
+}
+
+// checkPOEdit refuses a PATCH that changes a field the PO's status locks.
+// Unchanged locked fields are fine, so clients may send the whole PO back.
+func checkPOEdit(cur, in *models.PurchaseOrder, rules poEditRules) error {
+	if in.Status != "" && in.Status != cur.Status {
+		return errBadInput("status PO diubah lewat submit, approve, send, receive, close atau cancel")
+	}
+	locked := func(what string) error {
+		return errBadInput(what + " tidak bisa diubah saat PO berstatus " + cur.Status)
+	}
+	if !rules.Header && (in.Type != cur.Type || in.SupplierID != cur.SupplierID || in.OrderDate != cur.OrderDate) {
+		return locked("pemasok, jenis dan tanggal PO")
+	}
+	if !rules.ExpectedDate && in.ExpectedDate != cur.ExpectedDate {
+		return locked("tanggal perkiraan tiba")
+	}
+	if !rules.Lines && !samePOLines(cur.Lines, in.Lines) {
+		return locked("item PO")
+	}
+	if !samePOPayments(cur, in) {
+		return errBadInput("pembayaran PO dicatat lewat pembayaran pemasok")
+	}
+	return nil
+}
+
+func samePOLines(a, b []models.PurchaseOrderLine) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		factor := y.UnitFactor
+		if factor <= 0 {
+			factor = 1
+		}
+		if x.ID != y.ID || x.ProductID != y.ProductID ||
+			!sameOptUUID(x.VariantID, y.VariantID) || !sameOptUUID(x.UnitID, y.UnitID) ||
+			math.Abs(x.Quantity-y.Quantity) > 1e-9 || math.Abs(x.UnitFactor-factor) > 1e-9 ||
+			math.Abs(x.UnitPrice-y.UnitPrice) > 0.005 || x.Notes != y.Notes {
+			return false
+		}
+	}
+	return true
+}
+
+func samePOPayments(cur, in *models.PurchaseOrder) bool {
+	if math.Abs(cur.PaidAmount-in.PaidAmount) > 0.005 || len(cur.Payments) != len(in.Payments) {
+		return false
+	}
+	for i := range cur.Payments {
+		x, y := cur.Payments[i], in.Payments[i]
+		if math.Abs(x.Amount-y.Amount) > 0.005 || x.Notes != y.Notes {
+			return false
+		}
+	}
+	return true
+}
+
+func sameOptUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,468 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/documents"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type PurchaseReturnsHandler struct {
+	deps Deps
+}
+
+func NewPurchaseReturnsHandler(deps Deps) *PurchaseReturnsHandler {
+	return &PurchaseReturnsHandler{deps: deps}
+}
+
+// List filters: supplierId, purchaseOrderId, start, end (inclusive
+// returnedAt dates).
+func (h *PurchaseReturnsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := []models.PurchaseReturn{}
+	sel := h.deps.DB.NewSelect().Model(&items).
+		Relation("Lines", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("prl.position ASC")
+		}).
+		Relation("DebitNote").
+		Order("pr.returned_at DESC", "pr.created_at DESC")
+	sel = scopeStore(r.Context(), sel, "pr.store_id")
+	for param, col := range map[string]string{
+		"supplierId":      "pr.supplier_id",
+		"purchaseOrderId": "pr.purchase_order_id",
+	} {
+		if v := strings.TrimSpace(q.Get(param)); v != "" {
+			if _, err := uuid.Parse(v); err == nil {
+				sel = sel.Where(col+" = ?", v)
+			}
+		}
+	}
+	if v := strings.TrimSpace(q.Get("start")); v != "" {
+		sel = sel.Where("pr.returned_at >= ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("end")); v != "" {
+		sel = sel.Where("pr.returned_at <= ?", v)
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *PurchaseReturnsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ret, err := loadPurchaseReturn(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+type purchaseReturnLineInput struct {
+	BatchID string  `json:"batchId"`
+	Qty     float64 `json:"qty"`
+	Reason  string  `json:"reason"`
+	Notes   string  `json:"notes"`
+}
+
+type purchaseReturnInput struct {
+	SupplierID      string                    `json:"supplierId"`
+	PurchaseOrderID *string                   `json:"purchaseOrderId,omitempty"`
+	ReturnedAt      string                    `json:"returnedAt"`
+	Notes           string                    `json:"notes"`
+	Lines           []purchaseReturnLineInput `json:"lines"`
+}
+
+// Create posts a purchase return in one transaction. Every batch is locked
+// and checked to be owned stock bought from the supplier (and from the PO,
+// when one is given) with enough unreserved quantity, then decremented and
+// logged as a return-supplier movement. The returned value at batch unit
+// cost becomes a debit note; with a PO it also lowers that PO's payable.
+// When no PO is given but every batch came from the same one, that PO is
+// used.
+func (h *PurchaseReturnsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in purchaseReturnInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	supplierID, err := uuid.Parse(in.SupplierID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "supplierId tidak valid")
+		return
+	}
+	var poID *uuid.UUID
+	if in.PurchaseOrderID != nil && strings.TrimSpace(*in.PurchaseOrderID) != "" {
+		id, err := uuid.Parse(strings.TrimSpace(*in.PurchaseOrderID))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "purchaseOrderId tidak valid")
+			return
+		}
+		poID = &id
+	}
+	if len(in.Lines) == 0 {
+		writeError(w, http.StatusBadRequest, "Pilih minimal satu batch untuk diretur.")
+		return
+	}
+	returnedAt := strings.TrimSpace(in.ReturnedAt)
+	if returnedAt == "" {
+		returnedAt = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", returnedAt); err != nil {
+		writeError(w, http.StatusBadRequest, "returnedAt harus berformat YYYY-MM-DD")
+		return
+	}
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	performedBy := actorName(r.Context(), h.deps.DB)
+
+	ret := models.PurchaseReturn{
+		StoreID:    storeID,
+		SupplierID: supplierID,
+		ReturnedAt: returnedAt,
+		Notes:      strings.TrimSpace(in.Notes),
+		CreatedBy:  performedBy,
+	}
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if poID != nil {
+			var po models.PurchaseOrder
+			if err := tx.NewSelect().Model(&po).Where("id = ?", *poID).
+				For("UPDATE").Scan(ctx); err != nil {
+				return errBadInput("PO tidak ditemukan")
+			}
+			if po.SupplierID != supplierID {
+				return errBadInput(po.Code + " bukan PO pemasok ini.")
+			}
+			if po.Type == models.POTypeConsignment {
+				return errBadInput("stok konsinyasi dikembalikan lewat retur konsinyasi")
+			}
+		}
+
+		// Validate and lock before inserting anything so a bad line never
+		// leaves a half-written header behind.
+		seen := map[uuid.UUID]bool{}
+		batches := make([]models.Batch, len(in.Lines))
+		for i, l := range in.Lines {
+			batchID, err := uuid.Parse(l.BatchID)
+			if err != nil {
+				return errBadInput(fmt.Sprintf("baris %d: batchId tidak valid", i+1))
+			}
+			if seen[batchID] {
+				return errBadInput(fmt.Sprintf("baris %d: batch yang sama dipilih dua kali", i+1))
+			}
+			seen[batchID] = true
+			if l.Qty <= 0 {
+				return errBadInput(fmt.Sprintf("baris %d: jumlah harus lebih dari 0", i+1))
+			}
+			if reason := strings.TrimSpace(l.Reason); reason != "" && !slices.Contains(models.PurchaseReturnReasons, reason) {
+				return errBadInput(fmt.Sprintf("baris %d: alasan harus salah satu dari %s", i+1, strings.Join(models.PurchaseReturnReasons, ", ")))
+			}
+			b := &batches[i]
+			if err := tx.NewSelect().Model(b).Where("id = ?", batchID).For("UPDATE").Scan(ctx); err != nil {
+				return errBadInput(fmt.Sprintf("baris %d: batch tidak ditemukan", i+1))
+			}
+			if b.Ownership != models.BatchOwnershipOwned {
+				return errBadInput(fmt.Sprintf("%s adalah batch konsinyasi; gunakan retur konsinyasi.", b.Code))
+			}
+			if b.SupplierID == nil || *b.SupplierID != supplierID {
+				return errBadInput(fmt.Sprintf("%s tidak dibeli dari pemasok ini.", b.Code))
+			}
+			if poID != nil && (b.SourcePurchaseOrderID == nil || *b.SourcePurchaseOrderID != *poID) {
+				return errBadInput(fmt.Sprintf("%s tidak berasal dari PO ini.", b.Code))
+			}
+			if b.Status == models.BatchStatusDisposed {
+				return errBadInput(fmt.Sprintf("%s sudah dimusnahkan.", b.Code))
+			}
+			var reserved float64
+			if err := tx.NewSelect().Table("stock_reservations").
+				ColumnExpr("COALESCE(SUM(qty), 0)").
+				Where("batch_id = ?", b.ID).
+				Where("expires_at > now()").
+				Scan(ctx, &reserved); err != nil {
+				return err
+			}
+			if free := b.QtyRemaining - reserved; l.Qty > free+1e-9 {
+				return errBadInput(fmt.Sprintf("%s: hanya %.4g unit yang bisa diretur.", b.Code, max(free, 0)))
+			}
+		}
+		if poID == nil {
+			poID = commonSourcePO(batches)
+		}
+		ret.PurchaseOrderID = poID
+
+		code, err := nextPurchaseReturnCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		ret.Code = code
+		for i, l := range in.Lines {
+			b := batches[i]
+			reason := strings.TrimSpace(l.Reason)
+			if reason == "" {
+				reason = models.PurchaseReturnDamaged
+			}
+			// The supplier credits what they charged; freight and other
+			// landed charges folded into the batch cost aren't theirs.
+			supplierCost := max(b.UnitCost-b.LandedUnitCost, 0)
+			ret.TotalQty += l.Qty
+			ret.TotalValue += l.Qty * supplierCost
+			ret.Lines = append(ret.Lines, models.PurchaseReturnLine{
+				BatchID:    b.ID,
+				ProductID:  b.ProductID,
+				VariantID:  b.VariantID,
+				LocationID: b.LocationID,
+				Qty:        l.Qty,
+				UnitCost:   supplierCost,
+				Reason:     reason,
+				Notes:      strings.TrimSpace(l.Notes),
+				Position:   i,
+			})
+		}
+		ret.TotalValue = math.Round(ret.TotalValue*100) / 100
+		if _, err := tx.NewInsert().Model(&ret).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		for i := range ret.Lines {
+			ret.Lines[i].ReturnID = ret.ID
+		}
+		if _, err := tx.NewInsert().Model(&ret.Lines).Exec(ctx); err != nil {
+			return err
+		}
+
+		for i, l := range ret.Lines {
+			b := batches[i]
+			after := b.QtyRemaining - l.Qty
+			if after < 1e-9 {
+				after = 0
+			}
+			if _, err := tx.NewUpdate().Table("batches").Where("id = ?", b.ID).
+				Set("qty_remaining = ?", after).
+				Set("updated_at = current_timestamp").
+				Exec(ctx); err != nil {
+				return err
+			}
+			notes := "Retur pembelian · " + b.Code + " · " + l.Reason
+			if l.Notes != "" {
+				notes += " · " + l.Notes
+			}
+			if err := logMovement(ctx, tx, &models.StockMovement{
+				Kind:       models.MovementKindReturnSupplier,
+				ProductID:  &b.ProductID,
+				VariantID:  b.VariantID,
+				LocationID: &b.LocationID,
+				BatchID:    &b.ID,
+				QtyDelta:   -l.Qty,
+				QtyAfter:   after,
+				UnitCost:   floatPtr(b.UnitCost),
+				Reference: models.StockMovementReference{
+					Kind: models.MovementRefReturn, ID: ret.ID.String(), Code: ret.Code,
+				},
+				PerformedBy: performedBy,
+				Notes:       notes,
+			}); err != nil {
+				return err
+			}
+		}
+
+		dnCode, err := nextDebitNoteCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		ret.DebitNote = &models.SupplierDebitNote{
+			Code:            dnCode,
+			SupplierID:      supplierID,
+			PurchaseOrderID: poID,
+			ReturnID:        ret.ID,
+			Amount:          ret.TotalValue,
+			IssuedAt:        returnedAt,
+		}
+		if _, err := tx.NewInsert().Model(ret.DebitNote).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		if poID != nil {
+			if _, err := tx.NewUpdate().Table("purchase_orders").
+				Set("debit_note_amount = debit_note_amount + ?", ret.TotalValue).
+				Set("updated_at = current_timestamp").
+				Where("id = ?", *poID).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+// commonSourcePO returns the PO every batch was received from, or nil when
+// they don't share one.
+func commonSourcePO(batches []models.Batch) *uuid.UUID {
+	var po *uuid.UUID
+	for _, b := range batches {
+		if b.SourcePurchaseOrderID == nil || (po != nil && *po != *b.SourcePurchaseOrderID) {
+			return nil
+		}
+		po = b.SourcePurchaseOrderID
+	}
+	return po
+}
+
+// DebitNote renders the printable nota debit sent to the supplier with the
+// returned goods.
+func (h *PurchaseReturnsHandler) DebitNote(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx := r.Context()
+	ret, err := loadPurchaseReturn(ctx, h.deps.DB, id)
+	if err != nil || ret.DebitNote == nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	batchIDs := make([]uuid.UUID, len(ret.Lines))
+	for i, l := range ret.Lines {
+		batchIDs[i] = l.BatchID
+	}
+	var info []struct {
+		ID          uuid.UUID `bun:"id"`
+		Code        string    `bun:"code"`
+		ProductName string    `bun:"product_name"`
+		VariantName string    `bun:"variant_name"`
+	}
+	if len(batchIDs) > 0 {
+		if err := h.deps.DB.NewSelect().TableExpr("batches AS bt").
+			ColumnExpr("bt.id, bt.code").
+			ColumnExpr("p.name AS product_name").
+			ColumnExpr("COALESCE(pv.name, '') AS variant_name").
+			Join("JOIN products AS p ON p.id = bt.product_id").
+			Join("LEFT JOIN product_variants AS pv ON pv.id = bt.variant_id").
+			Where("bt.id IN (?)", bun.In(batchIDs)).
+			Scan(ctx, &info); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	byBatch := make(map[uuid.UUID]int, len(info))
+	for i, b := range info {
+		byBatch[b.ID] = i
+	}
+
+	rows := make([][]string, 0, len(ret.Lines))
+	for i, l := range ret.Lines {
+		code, name := l.BatchID.String(), ""
+		if j, ok := byBatch[l.BatchID]; ok {
+			code, name = info[j].Code, info[j].ProductName
+			if info[j].VariantName != "" {
+				name += " - " + info[j].VariantName
+			}
+		}
+		reason := l.Reason
+		if l.Notes != "" {
+			reason += ": " + l.Notes
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(i + 1), name, code,
+			formatDecimal(l.Qty), formatRupiah(l.UnitCost), formatRupiah(l.Qty * l.UnitCost), reason,
+		})
+	}
+
+	supplier := lookupName(ctx, h.deps.DB, "suppliers", ret.SupplierID)
+	meta := []documents.Field{
+		{Label: "Pemasok", Value: supplier},
+		{Label: "Retur", Value: ret.Code},
+		{Label: "Tanggal", Value: labelDate(ret.DebitNote.IssuedAt)},
+	}
+	if ret.PurchaseOrderID != nil {
+		var poCode string
+		_ = h.deps.DB.NewSelect().Table("purchase_orders").Column("code").
+			Where("id = ?", *ret.PurchaseOrderID).Scan(ctx, &poCode)
+		meta = append(meta, documents.Field{Label: "PO", Value: poCode})
+	}
+	pdf := documents.PDF(documents.Document{
+		Title: "Nota Debit Retur Pembelian",
+		Code:  ret.DebitNote.Code,
+		Meta:  meta,
+		Columns: []documents.Column{
+			{Title: "No", Width: 3, Right: true},
+			{Title: "Produk", Width: 26},
+			{Title: "Batch", Width: 14},
+			{Title: "Qty", Width: 7, Right: true},
+			{Title: "Harga", Width: 12, Right: true},
+			{Title: "Nilai", Width: 13, Right: true},
+			{Title: "Alasan", Width: 17},
+		},
+		Rows:   rows,
+		Totals: []string{"", "Total", "", formatDecimal(ret.TotalQty), "", formatRupiah(ret.DebitNote.Amount), ""},
+		Notes:  ret.Notes,
+		Signatures: []documents.Signature{
+			{Caption: "Dibuat oleh", Name: ret.CreatedBy},
+			{Caption: "Diterima oleh (" + supplier + ")"},
+		},
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", ret.DebitNote.Code+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(pdf)
+}
+
+func loadPurchaseReturn(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.PurchaseReturn, error) {
+	var ret models.PurchaseReturn
+	err := db.NewSelect().Model(&ret).
+		Relation("Lines", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("prl.position ASC")
+		}).
+		Relation("DebitNote").
+		Where("pr.id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func nextPurchaseReturnCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("PRET-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("purchase_returns").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
+
+func nextDebitNoteCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("DN-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("supplier_debit_notes").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
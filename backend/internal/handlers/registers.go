@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+)
+
+type RegistersHandler struct {
+	deps Deps
+}
+
+func NewRegistersHandler(deps Deps) *RegistersHandler {
+	return &RegistersHandler{deps: deps}
+}
+
+type registerInput struct {
+	StoreID *uuid.UUID `json:"storeId"`
+	Code    string     `json:"code"`
+	Name    string     `json:"name"`
+	Status  string     `json:"status"`
+}
+
+// List returns the active store's registers (every store's when
+// consolidated; ?storeId narrows that down).
+func (h *RegistersHandler) List(w http.ResponseWriter, r *http.Request) {
+	items := []models.Register{}
+	q := h.deps.DB.NewSelect().Model(&items).Order("code ASC")
+	q = scopeStore(r.Context(), q, "rg.store_id")
+	if v := strings.TrimSpace(r.URL.Query().Get("storeId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			q = q.Where("rg.store_id = ?", v)
+		}
+	}
+	if err := q.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *RegistersHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in registerInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := validateRegisterInput(&in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	reg := &models.Register{
+		Code:   strings.ToUpper(strings.TrimSpace(in.Code)),
+		Name:   strings.TrimSpace(in.Name),
+		Status: storeStatusOrDefault(in.Status),
+	}
+	if in.StoreID != nil {
+		reg.StoreID = *in.StoreID
+	} else {
+		id, err := requireStore(r.Context())
+		if err != nil {
+			writeTxError(w, err)
+			return
+		}
+		reg.StoreID = id
+	}
+	if _, err := h.deps.DB.NewInsert().Model(reg).Returning("*").Exec(r.Context()); err != nil {
+		if strings.Contains(err.Error(), "registers_store_id_code_key") {
+			writeError(w, http.StatusBadRequest, "kode kasir sudah dipakai di toko ini")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, reg)
+}
+
+func (h *RegistersHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in registerInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := validateRegisterInput(&in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	res, err := h.deps.DB.NewUpdate().Table("registers").Where("id = ?", id).
+		Set("code = ?", strings.ToUpper(strings.TrimSpace(in.Code))).
+		Set("name = ?", strings.TrimSpace(in.Name)).
+		Set("status = ?", storeStatusOrDefault(in.Status)).
+		Set("updated_at = current_timestamp").
+		Exec(r.Context())
+	if err != nil {
+		if strings.Contains(err.Error(), "registers_store_id_code_key") {
+			writeError(w, http.StatusBadRequest, "kode kasir sudah dipakai di toko ini")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var reg models.Register
+	if err := h.deps.DB.NewSelect().Model(&reg).Where("id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, reg)
+}
+
+func (h *RegistersHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	res, err := h.deps.DB.NewDelete().Model((*models.Register)(nil)).Where("id = ?", id).Exec(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateRegisterInput(in *registerInput) string {
+	if strings.TrimSpace(in.Code) == "" {
+		return "kode kasir wajib diisi"
+	}
+	if strings.TrimSpace(in.Name) == "" {
+		return "nama kasir wajib diisi"
+	}
+	return ""
+}
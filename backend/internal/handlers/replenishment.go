@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type ReplenishmentHandler struct {
+	deps Deps
+}
+
+func NewReplenishmentHandler(deps Deps) *ReplenishmentHandler {
+	return &ReplenishmentHandler{deps: deps}
+}
+
+// replenishmentParams mirrors the knobs on the /forecast page.
+type replenishmentParams struct {
+	WindowDays int `json:"windowDays"`
+	BufferDays int `json:"bufferDays"`
+}
+
+func (p *replenishmentParams) normalize() {
+	if p.WindowDays <= 0 {
+		p.WindowDays = 30
+	}
+	if p.BufferDays < 0 {
+		p.BufferDays = 0
+	}
+}
+
+type replenishmentSuggestion struct {
+	ProductID   uuid.UUID  `json:"productId"`
+	VariantID   *uuid.UUID `json:"variantId,omitempty"`
+	ProductName string     `json:"productName"`
+	VariantName string     `json:"variantName,omitempty"`
+	UnitID      *uuid.UUID `json:"unitId,omitempty"`
+
+	SupplierID   *uuid.UUID `json:"supplierId,omitempty"`
+	SupplierName string     `json:"supplierName,omitempty"`
+	UnitCost     float64    `json:"unitCost"`
+	LeadTimeDays int        `json:"leadTimeDays"`
+	MinOrderQty  float64    `json:"minOrderQty"`
+
+	// Base units per day over the window, and the per-weekday multipliers
+	// (index 0 = Monday) applied when projecting demand.
+	DailyRate    float64    `json:"dailyRate"`
+	Seasonality  [7]float64 `json:"seasonality"`
+	OnHand       float64    `json:"onHand"`
+	OnOrder      float64    `json:"onOrder"`
+	DaysOfSupply *float64   `json:"daysOfSupply"`
+	CoverageDays int        `json:"coverageDays"`
+	Demand       float64    `json:"forecastDemand"`
+	SuggestedQty float64    `json:"suggestedQty"`
+}
+
+// replenishmentReport splits the suggestions by whether the product has a
+// primary supplier; the rest can't go on a draft PO until one is set.
+type replenishmentReport struct {
+	Suggestions            []replenishmentSuggestion `json:"suggestions"`
+	WithoutPrimarySupplier []replenishmentSuggestion `json:"withoutPrimarySupplier"`
+}
+
+// Suggestions returns reorder suggestions for active goods. Query:
+// windowDays (default 30), bufferDays (default 7), all=true to include rows
+// with nothing to order. Products without a primary supplier are listed
+// separately.
+func (h *ReplenishmentHandler) Suggestions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	params := replenishmentParams{WindowDays: 30, BufferDays: 7}
+	if v, err := strconv.Atoi(q.Get("windowDays")); err == nil {
+		params.WindowDays = v
+	}
+	if v, err := strconv.Atoi(q.Get("bufferDays")); err == nil {
+		params.BufferDays = v
+	}
+	params.normalize()
+
+	rows, err := buildReplenishment(r.Context(), h.deps.DB, params, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := replenishmentReport{
+		Suggestions:            []replenishmentSuggestion{},
+		WithoutPrimarySupplier: []replenishmentSuggestion{},
+	}
+	for _, row := range rows {
+		if row.SuggestedQty <= 0 && q.Get("all") != "true" {
+			continue
+		}
+		if row.SupplierID == nil {
+			out.WithoutPrimarySupplier = append(out.WithoutPrimarySupplier, row)
+			continue
+		}
+		out.Suggestions = append(out.Suggestions, row)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+type replenishmentDraftItem struct {
+	ProductID string   `json:"productId"`
+	VariantID *string  `json:"variantId,omitempty"`
+	Qty       *float64 `json:"qty,omitempty"`
+}
+
+type replenishmentDraftInput struct {
+	replenishmentParams
+	// Empty = every suggestion with a primary supplier and qty > 0.
+	Items []replenishmentDraftItem `json:"items"`
+}
+
+// CreateDraftPOs turns suggestions into draft purchase orders, one per
+// primary supplier. Lines are in base units at the supplier's unit cost;
+// items without a primary supplier are skipped and reported back.
+func (h *ReplenishmentHandler) CreateDraftPOs(w http.ResponseWriter, r *http.Request) {
+	in := replenishmentDraftInput{replenishmentParams: replenishmentParams{WindowDays: 30, BufferDays: 7}}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	in.normalize()
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	now := time.Now()
+	rows, err := buildReplenishment(r.Context(), h.deps.DB, in.replenishmentParams, now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	type pick struct {
+		s   replenishmentSuggestion
+		qty float64
+	}
+	var picks []pick
+	if len(in.Items) == 0 {
+		for _, s := range rows {
+			if s.SuggestedQty > 0 {
+				picks = append(picks, pick{s, s.SuggestedQty})
+			}
+		}
+	} else {
+		bySubject := make(map[string]replenishmentSuggestion, len(rows))
+		for _, s := range rows {
+			bySubject[subjectKey(s.ProductID, s.VariantID)] = s
+		}
+		for _, it := range in.Items {
+			pid, err := uuid.Parse(it.ProductID)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "productId tidak valid")
+				return
+			}
+			s, ok := bySubject[subjectKey(pid, optUUID(it.VariantID))]
+			if !ok {
+				writeError(w, http.StatusBadRequest, "produk tidak ditemukan atau bukan barang: "+it.ProductID)
+				return
+			}
+			qty := s.SuggestedQty
+			if it.Qty != nil {
+				qty = *it.Qty
+			}
+			if qty > 0 {
+				picks = append(picks, pick{s, qty})
+			}
+		}
+	}
+
+	skipped := []replenishmentSuggestion{}
+	bySupplier := map[uuid.UUID][]pick{}
+	var supplierOrder []uuid.UUID
+	for _, p := range picks {
+		if p.s.SupplierID == nil {
+			skipped = append(skipped, p.s)
+			continue
+		}
+		sid := *p.s.SupplierID
+		if _, ok := bySupplier[sid]; !ok {
+			supplierOrder = append(supplierOrder, sid)
+		}
+		bySupplier[sid] = append(bySupplier[sid], p)
+	}
+	if len(supplierOrder) == 0 {
+		writeError(w, http.StatusBadRequest, "tidak ada saran pemesanan dengan pemasok utama")
+		return
+	}
+
+	var createdIDs []uuid.UUID
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, sid := range supplierOrder {
+			items := bySupplier[sid]
+			lead := 0
+			po := models.PurchaseOrder{
+				Type:       models.POTypeStandard,
+				StoreID:    storeID,
+				SupplierID: sid,
+				Status:     models.POStatusDraft,
+				OrderDate:  now.Format("2006-01-02"),
+				Notes:      "Dibuat otomatis dari saran pemesanan ulang",
+			}
+			for _, p := range items {
+				if p.s.LeadTimeDays > lead {
+					lead = p.s.LeadTimeDays
+				}
+				po.Lines = append(po.Lines, models.PurchaseOrderLine{
+					ProductID:  p.s.ProductID,
+					VariantID:  p.s.VariantID,
+					Quantity:   p.qty,
+					UnitID:     p.s.UnitID,
+					UnitFactor: 1,
+					UnitPrice:  p.s.UnitCost,
+				})
+			}
+			po.ExpectedDate = now.AddDate(0, 0, lead).Format("2006-01-02")
+			code, err := nextPOCode(ctx, tx)
+			if err != nil {
+				return err
+			}
+			po.Code = code
+			if _, err := tx.NewInsert().Model(&po).Returning("*").Exec(ctx); err != nil {
+				return err
+			}
+			if err := savePOChildren(ctx, tx, &po); err != nil {
+				return err
+			}
+			createdIDs = append(createdIDs, po.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	created := make([]models.PurchaseOrder, 0, len(createdIDs))
+	for _, id := range createdIDs {
+		po, err := loadPurchaseOrder(r.Context(), h.deps.DB, id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		created = append(created, *po)
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"purchaseOrders": created,
+		"skipped":        skipped,
+	})
+}
+
+func subjectKey(productID uuid.UUID, variantID *uuid.UUID) string {
+	if variantID == nil {
+		return productID.String()
+	}
+	return productID.String() + "/" + variantID.String()
+}
+
+type replenishmentSubject struct {
+	ProductID    uuid.UUID  `bun:"product_id"`
+	VariantID    *uuid.UUID `bun:"variant_id"`
+	ProductName  string     `bun:"product_name"`
+	VariantName  string     `bun:"variant_name"`
+	UnitID       *uuid.UUID `bun:"unit_id"`
+	SupplierID   *uuid.UUID `bun:"supplier_id"`
+	SupplierName string     `bun:"supplier_name"`
+	UnitCost     float64    `bun:"unit_cost"`
+	LeadTimeDays int        `bun:"lead_time_days"`
+	MinOrderQty  float64    `bun:"min_order_qty"`
+}
+
+type subjectQty struct {
+	ProductID uuid.UUID  `bun:"product_id"`
+	VariantID *uuid.UUID `bun:"variant_id"`
+	Dow       int        `bun:"dow"`
+	Qty       float64    `bun:"qty"`
+}
+
+// buildReplenishment computes one suggestion per active goods product (or
+// per variant). Demand over lead time + buffer is projected day by day from
+// the window's average rate scaled by that weekday's seasonal index; the
+// suggestion is whatever that demand exceeds on-hand + open PO quantity,
+// rounded up to the supplier's minimum order. Only the primary supplier's
+// terms are used; a product without one gets no supplier, cost or lead time.
+func buildReplenishment(ctx context.Context, db bun.IDB, p replenishmentParams, now time.Time) ([]replenishmentSuggestion, error) {
+	var subjects []replenishmentSubject
+	if err := db.NewSelect().
+		TableExpr("products AS p").
+		Join("LEFT JOIN product_variants AS pv ON pv.product_id = p.id").
+		Join("LEFT JOIN LATERAL (SELECT * FROM product_suppliers AS x WHERE x.product_id = p.id AND x.is_primary ORDER BY x.id LIMIT 1) AS ps ON true").
+		Join("LEFT JOIN suppliers AS s ON s.id = ps.supplier_id").
+		ColumnExpr("p.id AS product_id, pv.id AS variant_id, p.name AS product_name").
+		ColumnExpr("COALESCE(pv.name, '') AS variant_name, p.unit_id").
+		ColumnExpr("ps.supplier_id, COALESCE(s.name, '') AS supplier_name, COALESCE(ps.unit_cost, 0) AS unit_cost").
+		ColumnExpr("COALESCE(ps.lead_time_days, s.lead_time_days, 0) AS lead_time_days").
+		ColumnExpr("COALESCE(ps.min_order_qty, 0) AS min_order_qty").
+		Where("p.kind = ?", models.ProductKindGoods).
+		Where("p.status = 'active'").
+		OrderExpr("p.name ASC, pv.name ASC").
+		Scan(ctx, &subjects); err != nil {
+		return nil, err
+	}
+
+	since := startOfDay(now).AddDate(0, 0, -(p.WindowDays - 1))
+	var sales []subjectQty
+	if err := db.NewSelect().
+		TableExpr("order_lines AS ol").
+		Join("JOIN orders AS o ON o.id = ol.order_id").
+		ColumnExpr("ol.product_id, ol.variant_id").
+		ColumnExpr("EXTRACT(ISODOW FROM o.created_at)::int AS dow").
+		ColumnExpr("SUM(ol.quantity * ol.unit_factor) AS qty").
+		Where("o.status <> 'cancelled'").
+		Where("o.created_at >= ?", since).
+		GroupExpr("ol.product_id, ol.variant_id, dow").
+		Scan(ctx, &sales); err != nil {
+		return nil, err
+	}
+	var onHand []subjectQty
+	if err := db.NewSelect().Table("batches").
+		ColumnExpr("product_id, variant_id, SUM(qty_remaining) AS qty").
+		Where("status = ?", models.BatchStatusActive).
+		Where("qty_remaining > 0").
+		GroupExpr("product_id, variant_id").
+		Scan(ctx, &onHand); err != nil {
+		return nil, err
+	}
+	var onOrder []subjectQty
+	if err := db.NewSelect().
+		TableExpr("purchase_order_lines AS pol").
+		Join("JOIN purchase_orders AS po ON po.id = pol.purchase_order_id").
+		ColumnExpr("pol.product_id, pol.variant_id").
+		ColumnExpr("SUM(GREATEST(pol.quantity - pol.received_qty, 0) * pol.unit_factor) AS qty").
+		Where("po.status IN (?)", bun.In([]string{
+			models.POStatusDraft, models.POStatusPendingApproval, models.POStatusApproved,
+			models.POStatusSent, models.POStatusPartial,
+		})).
+		GroupExpr("pol.product_id, pol.variant_id").
+		Scan(ctx, &onOrder); err != nil {
+		return nil, err
+	}
+
+	// Weekday occurrences inside the window, so a weekday that appears
+	// five times isn't over-weighted against one that appears four.
+	var dowDays [7]int
+	for d := since; !d.After(now); d = d.AddDate(0, 0, 1) {
+		dowDays[isoWeekdayIndex(d)]++
+	}
+
+	salesBy := map[string]*[7]float64{}
+	for _, s := range sales {
+		k := subjectKey(s.ProductID, s.VariantID)
+		if salesBy[k] == nil {
+			salesBy[k] = &[7]float64{}
+		}
+		salesBy[k][(s.Dow+6)%7] += s.Qty
+	}
+	sumBy := func(rows []subjectQty) map[string]float64 {
+		out := make(map[string]float64, len(rows))
+		for _, r := range rows {
+			out[subjectKey(r.ProductID, r.VariantID)] += r.Qty
+		}
+		return out
+	}
+	stock := sumBy(onHand)
+	ordered := sumBy(onOrder)
+
+	out := make([]replenishmentSuggestion, 0, len(subjects))
+	for _, sub := range subjects {
+		k := subjectKey(sub.ProductID, sub.VariantID)
+		s := replenishmentSuggestion{
+			ProductID:    sub.ProductID,
+			VariantID:    sub.VariantID,
+			ProductName:  sub.ProductName,
+			VariantName:  sub.VariantName,
+			UnitID:       sub.UnitID,
+			SupplierID:   sub.SupplierID,
+			SupplierName: sub.SupplierName,
+			UnitCost:     sub.UnitCost,
+			LeadTimeDays: sub.LeadTimeDays,
+			MinOrderQty:  sub.MinOrderQty,
+			OnHand:       stock[k],
+			OnOrder:      ordered[k],
+			CoverageDays: sub.LeadTimeDays + p.BufferDays,
+		}
+		for i := range s.Seasonality {
+			s.Seasonality[i] = 1
+		}
+		if byDow := salesBy[k]; byDow != nil {
+			total := 0.0
+			for _, q := range byDow {
+				total += q
+			}
+			s.DailyRate = total / float64(p.WindowDays)
+			// Seasonality needs at least two of each weekday to mean
+			// anything; shorter windows project flat.
+			if s.DailyRate > 0 && p.WindowDays >= 14 {
+				for i, q := range byDow {
+					if dowDays[i] > 0 {
+						s.Seasonality[i] = (q / float64(dowDays[i])) / s.DailyRate
+					}
+				}
+			}
+		}
+		if s.DailyRate > 0 {
+			dos := s.OnHand / s.DailyRate
+			s.DaysOfSupply = &dos
+			for i := 1; i <= s.CoverageDays; i++ {
+				day := now.AddDate(0, 0, i)
+				s.Demand += s.DailyRate * s.Seasonality[isoWeekdayIndex(day)]
+			}
+			need := math.Ceil(s.Demand - s.OnHand - s.OnOrder)
+			if need > 0 {
+				s.SuggestedQty = math.Max(need, math.Ceil(s.MinOrderQty))
+			}
+		}
+		out = append(out, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].SuggestedQty > 0 && out[j].SuggestedQty == 0
+	})
+	return out, nil
+}
+
+// isoWeekdayIndex maps Monday → 0 … Sunday → 6.
+func isoWeekdayIndex(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
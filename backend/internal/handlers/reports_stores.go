@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+)
+
+// storeSummaryRow is one store's line in the consolidated HQ report.
+type storeSummaryRow struct {
+	StoreID          uuid.UUID `bun:"store_id" json:"storeId"`
+	Code             string    `bun:"code" json:"code"`
+	Name             string    `bun:"name" json:"name"`
+	Orders           int       `bun:"orders" json:"orders"`
+	Sales            float64   `bun:"sales" json:"sales"`
+	TaxTotal         float64   `bun:"tax_total" json:"taxTotal"`
+	CreditSales      float64   `bun:"credit_sales" json:"creditSales"`
+	CancelledOrders  int       `bun:"cancelled_orders" json:"cancelledOrders"`
+	AvgTicket        float64   `bun:"-" json:"avgTicket"`
+	OpenShifts       int       `bun:"open_shifts" json:"openShifts"`
+	OwnedQty         float64   `bun:"owned_qty" json:"ownedQty"`
+	OwnedValue       float64   `bun:"owned_value" json:"ownedValue"`
+	ConsignmentQty   float64   `bun:"consignment_qty" json:"consignmentQty"`
+	ConsignmentValue float64   `bun:"consignment_value" json:"consignmentValue"`
+}
+
+type storeSummaryReport struct {
+	Start  string            `json:"start,omitempty"`
+	End    string            `json:"end,omitempty"`
+	Stores []storeSummaryRow `json:"stores"`
+	Totals storeSummaryRow   `json:"totals"`
+}
+
+// StoreSummary is the consolidated cross-store report for HQ: sales for the
+// optional start/end period (inclusive dates) next to each store's current
+// stock on hand at cost. Query: start, end, format=csv. Ignores the active
+// store — the route is gated to ConsolidatedRoles.
+func (h *ReportsHandler) StoreSummary(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end := strings.TrimSpace(q.Get("start")), strings.TrimSpace(q.Get("end"))
+	ctx := r.Context()
+
+	salesJoin := "LEFT JOIN orders AS o ON o.store_id = st.id"
+	var args []any
+	if start != "" {
+		salesJoin += " AND o.created_at::date >= ?"
+		args = append(args, start)
+	}
+	if end != "" {
+		salesJoin += " AND o.created_at::date <= ?"
+		args = append(args, end)
+	}
+
+	rows := []storeSummaryRow{}
+	if err := h.deps.DB.NewSelect().
+		TableExpr("stores AS st").
+		Join(salesJoin, args...).
+		ColumnExpr("st.id AS store_id, st.code, st.name").
+		ColumnExpr("COUNT(o.id) FILTER (WHERE o.status <> ?) AS orders", models.OrderStatusCancelled).
+		ColumnExpr("COALESCE(SUM(o.total) FILTER (WHERE o.status <> ?), 0) AS sales", models.OrderStatusCancelled).
+		ColumnExpr("COALESCE(SUM(o.tax_total) FILTER (WHERE o.status <> ?), 0) AS tax_total", models.OrderStatusCancelled).
+		ColumnExpr("COALESCE(SUM(o.total - o.paid_amount) FILTER (WHERE o.status = ?), 0) AS credit_sales", models.OrderStatusCredit).
+		ColumnExpr("COUNT(o.id) FILTER (WHERE o.status = ?) AS cancelled_orders", models.OrderStatusCancelled).
+		ColumnExpr("(SELECT COUNT(*) FROM shift_sessions AS ss WHERE ss.store_id = st.id AND ss.status = ?) AS open_shifts", models.ShiftStatusOpen).
+		GroupExpr("st.id").
+		OrderExpr("st.name ASC").
+		Scan(ctx, &rows); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var stock []storeSummaryRow
+	if err := h.deps.DB.NewSelect().
+		TableExpr("batches AS bt").
+		Join("JOIN locations AS loc ON loc.id = bt.location_id").
+		ColumnExpr("loc.store_id").
+		ColumnExpr("COALESCE(SUM(bt.qty_remaining) FILTER (WHERE bt.ownership <> ?), 0) AS owned_qty", models.BatchOwnershipConsignment).
+		ColumnExpr("COALESCE(SUM(bt.qty_remaining * bt.unit_cost) FILTER (WHERE bt.ownership <> ?), 0) AS owned_value", models.BatchOwnershipConsignment).
+		ColumnExpr("COALESCE(SUM(bt.qty_remaining) FILTER (WHERE bt.ownership = ?), 0) AS consignment_qty", models.BatchOwnershipConsignment).
+		ColumnExpr("COALESCE(SUM(bt.qty_remaining * bt.unit_cost) FILTER (WHERE bt.ownership = ?), 0) AS consignment_value", models.BatchOwnershipConsignment).
+		Where("bt.qty_remaining > 0").
+		Where("bt.status <> ?", models.BatchStatusDisposed).
+		GroupExpr("loc.store_id").
+		Scan(ctx, &stock); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	byStore := make(map[uuid.UUID]storeSummaryRow, len(stock))
+	for _, s := range stock {
+		byStore[s.StoreID] = s
+	}
+
+	report := storeSummaryReport{Start: start, End: end, Stores: rows, Totals: storeSummaryRow{Code: "TOTAL", Name: "Total"}}
+	t := &report.Totals
+	for i := range report.Stores {
+		row := &report.Stores[i]
+		s := byStore[row.StoreID]
+		row.OwnedQty, row.OwnedValue = s.OwnedQty, s.OwnedValue
+		row.ConsignmentQty, row.ConsignmentValue = s.ConsignmentQty, s.ConsignmentValue
+		if row.Orders > 0 {
+			row.AvgTicket = row.Sales / float64(row.Orders)
+		}
+		t.Orders += row.Orders
+		t.Sales += row.Sales
+		t.TaxTotal += row.TaxTotal
+		t.CreditSales += row.CreditSales
+		t.CancelledOrders += row.CancelledOrders
+		t.OpenShifts += row.OpenShifts
+		t.OwnedQty += row.OwnedQty
+		t.OwnedValue += row.OwnedValue
+		t.ConsignmentQty += row.ConsignmentQty
+		t.ConsignmentValue += row.ConsignmentValue
+	}
+	if t.Orders > 0 {
+		t.AvgTicket = t.Sales / float64(t.Orders)
+	}
+
+	if q.Get("format") == "csv" {
+		out := make([][]string, 0, len(report.Stores)+1)
+		for _, s := range append(report.Stores, report.Totals) {
+			out = append(out, []string{
+				s.Code, s.Name,
+				fmt.Sprint(s.Orders), formatDecimal(s.Sales), formatDecimal(s.AvgTicket),
+				formatDecimal(s.TaxTotal), formatDecimal(s.CreditSales), fmt.Sprint(s.CancelledOrders),
+				fmt.Sprint(s.OpenShifts),
+				formatDecimal(s.OwnedQty), formatDecimal(s.OwnedValue),
+				formatDecimal(s.ConsignmentQty), formatDecimal(s.ConsignmentValue),
+			})
+		}
+		writeCSV(w, "ringkasan-toko.csv",
+			[]string{"Kode", "Toko", "Pesanan", "Penjualan", "Rata-rata", "Pajak", "Piutang",
+				"Batal", "Shift terbuka", "Qty milik", "Nilai milik", "Qty konsinyasi", "Nilai konsinyasi"},
+			out)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
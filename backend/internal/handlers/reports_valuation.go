@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type ReportsHandler struct {
+	deps Deps
+}
+
+func NewReportsHandler(deps Deps) *ReportsHandler {
+	return &ReportsHandler{deps: deps}
+}
+
+// Valuation methods for the inventory valuation report.
+const (
+	valuationFIFO     = "fifo"
+	valuationWeighted = "weighted-average"
+)
+
+// Where a valuation's quantities came from.
+const (
+	valuationFromSnapshot  = "snapshot"
+	valuationFromMovements = "movements"
+)
+
+// valuationBatchRow is one batch with its quantity reconstructed as of the
+// cutoff. QtyAsOf = current qty_remaining minus every movement logged on the
+// batch after the cutoff, so it holds even for seeded batches that never got
+// a receive movement.
+type valuationBatchRow struct {
+	BatchID     uuid.UUID  `bun:"batch_id" json:"batchId"`
+	BatchCode   string     `bun:"batch_code" json:"batchCode"`
+	ProductID   uuid.UUID  `bun:"product_id" json:"productId"`
+	ProductName string     `bun:"product_name" json:"productName"`
+	VariantID   *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	VariantName string     `bun:"variant_name" json:"variantName,omitempty"`
+	CategoryID  *uuid.UUID `bun:"category_id" json:"categoryId,omitempty"`
+	LocationID  uuid.UUID  `bun:"location_id" json:"locationId"`
+	StoreID     uuid.UUID  `bun:"store_id" json:"storeId"`
+	SupplierID  *uuid.UUID `bun:"supplier_id" json:"supplierId,omitempty"`
+	Ownership   string     `bun:"ownership" json:"ownership"`
+	ReceivedAt  string     `bun:"received_at" json:"receivedAt"`
+	QtyReceived float64    `bun:"qty_received" json:"-"`
+	BatchCost   float64    `bun:"unit_cost" json:"batchUnitCost"`
+	QtyAsOf     float64    `bun:"qty_as_of" json:"qty"`
+	UnitCost    float64    `bun:"-" json:"unitCost"`
+	Value       float64    `bun:"-" json:"value"`
+}
+
+type valuationGroup struct {
+	Key              string  `json:"key"`
+	Label            string  `json:"label"`
+	OwnedQty         float64 `json:"ownedQty"`
+	OwnedValue       float64 `json:"ownedValue"`
+	ConsignmentQty   float64 `json:"consignmentQty"`
+	ConsignmentValue float64 `json:"consignmentValue"`
+}
+
+type valuationReport struct {
+	AsOf    time.Time           `json:"asOf"`
+	Method  string              `json:"method"`
+	GroupBy string              `json:"groupBy"`
+	Source  string              `json:"source"`
+	Totals  valuationGroup      `json:"totals"`
+	Groups  []valuationGroup    `json:"groups"`
+	Lines   []valuationBatchRow `json:"lines"`
+}
+
+// InventoryValuation answers "what was stock worth at <asOf>". Query:
+//
+//	asOf     RFC3339 timestamp or YYYY-MM-DD (end of that day); default now
+//	method   fifo (per-batch unit_cost, default) | weighted-average
+//	groupBy  category (default) | location | store | supplier | ownership
+//	format   json (default) | csv
+//
+// Consignment stock is reported at cost in its own columns and never counted
+// in owned value. A date with a stock snapshot is read from it, locations
+// included; otherwise quantities are replayed from movements and locations
+// are the batches' current ones. Only the active store's stock is valued
+// unless the caller is consolidated.
+func (h *ReportsHandler) InventoryValuation(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	asOf, err := parseAsOf(q.Get("asOf"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "asOf tidak valid")
+		return
+	}
+	method := strings.TrimSpace(q.Get("method"))
+	if method == "" {
+		method = valuationFIFO
+	}
+	if method != valuationFIFO && method != valuationWeighted {
+		writeError(w, http.StatusBadRequest, "method harus fifo atau weighted-average")
+		return
+	}
+	groupBy := strings.TrimSpace(q.Get("groupBy"))
+	if groupBy == "" {
+		groupBy = "category"
+	}
+	switch groupBy {
+	case "category", "location", "store", "supplier", "ownership":
+	default:
+		writeError(w, http.StatusBadRequest, "groupBy tidak valid")
+		return
+	}
+
+	report, err := buildValuation(r.Context(), h.deps.DB, asOf, method, groupBy)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if q.Get("format") == "csv" {
+		rows := make([][]string, 0, len(report.Groups)+1)
+		for _, g := range append(report.Groups, report.Totals) {
+			rows = append(rows, []string{
+				g.Label,
+				formatDecimal(g.OwnedQty), formatDecimal(g.OwnedValue),
+				formatDecimal(g.ConsignmentQty), formatDecimal(g.ConsignmentValue),
+			})
+		}
+		writeCSV(w, fmt.Sprintf("valuasi-stok-%s-%s.csv", groupBy, asOf.Format("20060102")),
+			[]string{groupLabel(groupBy), "Qty milik", "Nilai milik", "Qty konsinyasi", "Nilai konsinyasi"},
+			rows)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func buildValuation(ctx context.Context, db bun.IDB, asOf time.Time, method, groupBy string) (*valuationReport, error) {
+	// A cutoff at the end of a snapshotted day reads that day's snapshot:
+	// the quantity, cost and location the batch had then. Anything else
+	// replays movements back from today.
+	source := valuationFromMovements
+	day := asOf.Format("2006-01-02")
+	if end, err := endOfDay(day); err == nil && end.Equal(asOf) {
+		ok, err := hasStockSnapshot(ctx, db, day)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			source = valuationFromSnapshot
+		}
+	}
+
+	var rows []valuationBatchRow
+	q := db.NewSelect().
+		TableExpr("batches AS bt").
+		Join("JOIN products AS p ON p.id = bt.product_id").
+		Join("LEFT JOIN product_variants AS pv ON pv.id = bt.variant_id").
+		ColumnExpr("bt.id AS batch_id, bt.code AS batch_code, bt.product_id, p.name AS product_name").
+		ColumnExpr("bt.variant_id, COALESCE(pv.name, '') AS variant_name, p.category_id").
+		ColumnExpr("bt.supplier_id, bt.ownership, bt.received_at, bt.qty_received").
+		Where("bt.created_at <= ?", asOf).
+		OrderExpr("p.name ASC, bt.received_at ASC")
+	if source == valuationFromSnapshot {
+		// Batches empty at the cutoff have no snapshot row but still count
+		// toward the weighted average.
+		q = q.Join("LEFT JOIN stock_snapshots AS ss ON ss.batch_id = bt.id AND ss.snapshot_date = ?", day).
+			Join("JOIN locations AS loc ON loc.id = COALESCE(ss.location_id, bt.location_id)").
+			ColumnExpr("loc.id AS location_id, loc.store_id").
+			ColumnExpr("COALESCE(ss.unit_cost, bt.unit_cost) AS unit_cost, COALESCE(ss.qty, 0) AS qty_as_of")
+	} else {
+		q = q.Join("JOIN locations AS loc ON loc.id = bt.location_id").
+			ColumnExpr("bt.location_id, loc.store_id, bt.unit_cost").
+			ColumnExpr(`bt.qty_remaining - COALESCE((
+				SELECT SUM(sm.qty_delta) FROM stock_movements AS sm
+				WHERE sm.batch_id = bt.id AND sm.happened_at > ?), 0) AS qty_as_of`, asOf)
+	}
+	q = scopeStore(ctx, q, "loc.store_id")
+	if err := q.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	// Periodic weighted average per product/variant/ownership over every
+	// batch received up to the cutoff.
+	type avgKey struct {
+		product   uuid.UUID
+		variant   uuid.UUID
+		ownership string
+	}
+	keyOf := func(row valuationBatchRow) avgKey {
+		k := avgKey{product: row.ProductID, ownership: row.Ownership}
+		if row.VariantID != nil {
+			k.variant = *row.VariantID
+		}
+		return k
+	}
+	avgCost := map[avgKey]float64{}
+	if method == valuationWeighted {
+		qty := map[avgKey]float64{}
+		cost := map[avgKey]float64{}
+		for _, row := range rows {
+			k := keyOf(row)
+			qty[k] += row.QtyReceived
+			cost[k] += row.QtyReceived * row.BatchCost
+		}
+		for k, q := range qty {
+			if q > 0 {
+				avgCost[k] = cost[k] / q
+			}
+		}
+	}
+
+	labels, err := valuationLabels(ctx, db, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &valuationReport{
+		AsOf:    asOf,
+		Method:  method,
+		GroupBy: groupBy,
+		Source:  source,
+		Totals:  valuationGroup{Key: "total", Label: "Total"},
+		Groups:  []valuationGroup{},
+		Lines:   []valuationBatchRow{},
+	}
+	groups := map[string]*valuationGroup{}
+	for _, row := range rows {
+		if row.QtyAsOf <= 1e-9 {
+			continue
+		}
+		row.UnitCost = row.BatchCost
+		if method == valuationWeighted {
+			row.UnitCost = avgCost[keyOf(row)]
+		}
+		row.Value = row.QtyAsOf * row.UnitCost
+
+		key := valuationGroupKey(row, groupBy)
+		g := groups[key]
+		if g == nil {
+			label := labels[key]
+			if label == "" {
+				label = "Tanpa " + strings.ToLower(groupLabel(groupBy))
+			}
+			g = &valuationGroup{Key: key, Label: label}
+			groups[key] = g
+		}
+		for _, acc := range []*valuationGroup{g, &report.Totals} {
+			if row.Ownership == models.BatchOwnershipConsignment {
+				acc.ConsignmentQty += row.QtyAsOf
+				acc.ConsignmentValue += row.Value
+			} else {
+				acc.OwnedQty += row.QtyAsOf
+				acc.OwnedValue += row.Value
+			}
+		}
+		report.Lines = append(report.Lines, row)
+	}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].OwnedValue > report.Groups[j].OwnedValue
+	})
+	return report, nil
+}
+
+func valuationGroupKey(row valuationBatchRow, groupBy string) string {
+	switch groupBy {
+	case "location":
+		return row.LocationID.String()
+	case "store":
+		return row.StoreID.String()
+	case "supplier":
+		if row.SupplierID != nil {
+			return row.SupplierID.String()
+		}
+	case "ownership":
+		return row.Ownership
+	default:
+		if row.CategoryID != nil {
+			return row.CategoryID.String()
+		}
+	}
+	return ""
+}
+
+// valuationLabels maps group keys to display names for the chosen dimension.
+func valuationLabels(ctx context.Context, db bun.IDB, groupBy string) (map[string]string, error) {
+	out := map[string]string{}
+	var table string
+	switch groupBy {
+	case "ownership":
+		out[models.BatchOwnershipOwned] = "Milik sendiri"
+		out[models.BatchOwnershipConsignment] = "Konsinyasi"
+		return out, nil
+	case "location":
+		table = "locations"
+	case "store":
+		table = "stores"
+	case "supplier":
+		table = "suppliers"
+	default:
+		table = "categories"
+	}
+	var rows []struct {
+		ID   uuid.UUID `bun:"id"`
+		Name string    `bun:"name"`
+	}
+	if err := db.NewSelect().Table(table).Column("id", "name").Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.ID.String()] = r.Name
+	}
+	return out, nil
+}
+
+func groupLabel(groupBy string) string {
+	switch groupBy {
+	case "location":
+		return "Lokasi"
+	case "store":
+		return "Toko"
+	case "supplier":
+		return "Pemasok"
+	case "ownership":
+		return "Kepemilikan"
+	default:
+		return "Kategori"
+	}
+}
+
+// parseAsOf accepts an RFC3339 timestamp or a bare date (meaning the end of
+// that day, server local time). Empty means now.
+func parseAsOf(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return d.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
 	"github.com/sandisahdewo/pos/backend/internal/models"
 	"github.com/uptrace/bun"
 )
@@ -169,3 +170,34 @@ func replaceRolePermissions(
 		return err
 	})
 }
+
+// Permission keys the backend itself enforces (the rest of the catalog only
+// gates frontend menus).
+const (
+	PermStockAdjustApprove = "feature.stock.adjust-approve"
+	PermPurchaseApprove    = "feature.purchasing.approve"
+)
+
+// hasPermission reports whether the calling user holds perm through any of
+// their roles (or the wildcard). Permissions aren't in the JWT, so this reads
+// the role tables on each call.
+func hasPermission(ctx context.Context, db *bun.DB, perm string) (bool, error) {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return false, nil
+	}
+	roleIDs, err := loadRoleIDsFor(ctx, db, claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	perms, err := loadPermissionsForRoles(ctx, db, roleIDs)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p == perm || p == models.WildcardPermission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
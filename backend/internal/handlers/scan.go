@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type ScanHandler struct {
+	deps Deps
+}
+
+func NewScanHandler(deps Deps) *ScanHandler {
+	return &ScanHandler{deps: deps}
+}
+
+// What a scanned code resolved to.
+const (
+	scanKindProduct   = "product"
+	scanKindVariant   = "variant"
+	scanKindPackaging = "packaging"
+	scanKindBatch     = "batch"
+	scanKindEmbedded  = "embedded"
+)
+
+// Embedded-value kinds for scale barcodes.
+const (
+	embeddedWeight = "weight"
+	embeddedPrice  = "price"
+)
+
+// embeddedBarcodeRule describes one in-store EAN-13 range:
+// <prefix><PLU, PLUDigits long><value><check digit>. The value field takes
+// whatever digits remain and is divided by Divisor — grams → kg is 1000, a
+// whole-rupiah price is 1.
+type embeddedBarcodeRule struct {
+	Prefix    string  `json:"prefix"`
+	Kind      string  `json:"kind"`
+	PLUDigits int     `json:"pluDigits"`
+	Divisor   float64 `json:"divisor"`
+}
+
+type scanResult struct {
+	Code        string          `json:"code"`
+	Kind        string          `json:"kind"`
+	Product     *models.Product `json:"product"`
+	VariantID   *uuid.UUID      `json:"variantId,omitempty"`
+	PackagingID *uuid.UUID      `json:"packagingId,omitempty"`
+	// Base units per scanned unit (packaging factor, else 1).
+	UnitFactor float64 `json:"unitFactor"`
+	// Quantity for the cart line, in scanned units. 1 for plain barcodes;
+	// fractional for scale barcodes.
+	Qty   float64       `json:"qty"`
+	Batch *models.Batch `json:"batch,omitempty"`
+
+	// Scale barcodes only.
+	EmbeddedKind  string   `json:"embeddedKind,omitempty"`
+	EmbeddedValue float64  `json:"embeddedValue,omitempty"`
+	PLU           string   `json:"plu,omitempty"`
+	UnitPrice     *float64 `json:"unitPrice,omitempty"`
+	LineTotal     *float64 `json:"lineTotal,omitempty"`
+}
+
+// Resolve looks a scanned code up, in order: variant, packaging and product
+// barcodes (active products only), batch codes at the active store (a
+// non-active batch is an error), then the configured scale barcode ranges.
+// Exact barcodes win so a fixed-weight pack registered under an in-store
+// code still resolves to itself.
+//
+// For price-embedded codes the quantity is the printed price divided by the
+// base price on ?pricelistId (default pricelist when omitted).
+func (h *ScanHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	code := strings.TrimSpace(chi.URLParam(r, "code"))
+	if code == "" {
+		writeError(w, http.StatusBadRequest, "kode kosong")
+		return
+	}
+	res := scanResult{Code: code, UnitFactor: 1, Qty: 1}
+
+	productID, err := h.matchBarcode(ctx, code, &res)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if productID == uuid.Nil {
+		var b models.Batch
+		q := h.deps.DB.NewSelect().Model(&b).
+			Where("upper(bt.code) = upper(?)", code).
+			Limit(1)
+		err := scopeStoreLocations(ctx, q, "bt.location_id").Scan(ctx)
+		switch {
+		case err == nil && b.Status != models.BatchStatusActive:
+			// Quarantined and disposed batches are known codes but must
+			// never land in a cart.
+			label := "dikarantina"
+			if b.Status == models.BatchStatusDisposed {
+				label = "sudah dimusnahkan"
+			}
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Batch %s %s dan tidak bisa dijual.", b.Code, label))
+			return
+		case err == nil:
+			res.Kind = scanKindBatch
+			res.Batch = &b
+			res.VariantID = b.VariantID
+			productID = b.ProductID
+		case !errors.Is(err, sql.ErrNoRows):
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if productID == uuid.Nil {
+		settings, err := loadServerSettings(ctx, h.deps.DB)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, rule := range settings.Scan.EmbeddedBarcodes {
+			plu, value, ok := parseEmbeddedBarcode(code, rule)
+			if !ok {
+				continue
+			}
+			id, variantID, err := h.matchPLU(ctx, plu)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if id == uuid.Nil {
+				continue
+			}
+			productID = id
+			res.Kind = scanKindEmbedded
+			res.VariantID = variantID
+			res.PLU = plu
+			res.EmbeddedKind = rule.Kind
+			res.EmbeddedValue = value
+			break
+		}
+	}
+
+	if productID == uuid.Nil {
+		writeError(w, http.StatusNotFound, "barcode tidak dikenal")
+		return
+	}
+	product, err := loadProduct(ctx, h.deps.DB, productID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res.Product = product
+
+	if res.Kind == scanKindEmbedded {
+		pricelistID := strings.TrimSpace(r.URL.Query().Get("pricelistId"))
+		defaultID, err := defaultPricelistID(ctx, h.deps.DB)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if pricelistID == "" {
+			pricelistID = defaultID
+		}
+		cost, err := markupCost(ctx, h.deps.DB, product, res.VariantID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		unitPrice, ok := productBasePrice(product, res.VariantID, cost, pricelistID, defaultID)
+		if ok {
+			res.UnitPrice = &unitPrice
+		}
+		switch res.EmbeddedKind {
+		case embeddedWeight:
+			res.Qty = res.EmbeddedValue
+			if ok {
+				total := math.Round(res.Qty * unitPrice)
+				res.LineTotal = &total
+			}
+		case embeddedPrice:
+			total := res.EmbeddedValue
+			res.LineTotal = &total
+			if ok && unitPrice > 0 {
+				res.Qty = math.Round(total/unitPrice*1000) / 1000
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// matchBarcode tries variant, packaging and product barcodes in that order
+// and fills the variant/packaging part of res. Returns uuid.Nil on no match.
+func (h *ScanHandler) matchBarcode(ctx context.Context, code string, res *scanResult) (uuid.UUID, error) {
+	var hit struct {
+		ID        uuid.UUID `bun:"id"`
+		ProductID uuid.UUID `bun:"product_id"`
+		Factor    float64   `bun:"factor"`
+	}
+	lookup := func(table, cols, productCol string) (bool, error) {
+		err := h.deps.DB.NewSelect().TableExpr(table).
+			Join("JOIN products AS p ON p.id = x."+productCol).
+			ColumnExpr(cols).
+			Where("x.barcode = ?", code).
+			Where("p.status = ?", models.ProductStatusActive).
+			Limit(1).Scan(ctx, &hit)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if ok, err := lookup("product_variants AS x", "x.id, x.product_id", "product_id"); err != nil || ok {
+		if ok {
+			res.Kind = scanKindVariant
+			res.VariantID = &hit.ID
+		}
+		return hit.ProductID, err
+	}
+	if ok, err := lookup("product_packagings AS x", "x.id, x.product_id, x.factor", "product_id"); err != nil || ok {
+		if ok {
+			res.Kind = scanKindPackaging
+			res.PackagingID = &hit.ID
+			if hit.Factor > 0 {
+				res.UnitFactor = hit.Factor
+			}
+		}
+		return hit.ProductID, err
+	}
+	if ok, err := lookup("products AS x", "x.id, x.id AS product_id", "id"); err != nil || ok {
+		if ok {
+			res.Kind = scanKindProduct
+		}
+		return hit.ProductID, err
+	}
+	return uuid.Nil, nil
+}
+
+// matchPLU finds the product a scale PLU refers to. Scales are programmed
+// with the item's own barcode or SKU, with or without leading zeros.
+func (h *ScanHandler) matchPLU(ctx context.Context, plu string) (uuid.UUID, *uuid.UUID, error) {
+	candidates := []string{plu}
+	if trimmed := strings.TrimLeft(plu, "0"); trimmed != "" && trimmed != plu {
+		candidates = append(candidates, trimmed)
+	}
+	var v struct {
+		ID        uuid.UUID `bun:"id"`
+		ProductID uuid.UUID `bun:"product_id"`
+	}
+	err := h.deps.DB.NewSelect().TableExpr("product_variants AS pv").
+		Join("JOIN products AS p ON p.id = pv.product_id").
+		ColumnExpr("pv.id, pv.product_id").
+		Where("pv.barcode IN (?) OR pv.sku IN (?)", bun.In(candidates), bun.In(candidates)).
+		Where("p.status = ?", models.ProductStatusActive).
+		Limit(1).Scan(ctx, &v)
+	if err == nil {
+		return v.ProductID, &v.ID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil, err
+	}
+	var id uuid.UUID
+	err = h.deps.DB.NewSelect().Table("products").Column("id").
+		Where("barcode IN (?) OR sku IN (?)", bun.In(candidates), bun.In(candidates)).
+		Where("status = ?", models.ProductStatusActive).
+		Limit(1).Scan(ctx, &id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil, nil
+	}
+	return id, nil, err
+}
+
+// parseEmbeddedBarcode splits a scale EAN-13 per rule. The check digit must
+// be valid so a mis-scan never turns into a quantity.
+func parseEmbeddedBarcode(code string, rule embeddedBarcodeRule) (plu string, value float64, ok bool) {
+	if len(code) != 13 || rule.Prefix == "" || !strings.HasPrefix(code, rule.Prefix) {
+		return "", 0, false
+	}
+	if rule.Kind != embeddedWeight && rule.Kind != embeddedPrice {
+		return "", 0, false
+	}
+	if !validEAN13(code) {
+		return "", 0, false
+	}
+	pluDigits := rule.PLUDigits
+	if pluDigits <= 0 {
+		pluDigits = 5
+	}
+	start := len(rule.Prefix)
+	if start+pluDigits >= 12 {
+		return "", 0, false
+	}
+	plu = code[start : start+pluDigits]
+	raw, err := strconv.Atoi(code[start+pluDigits : 12])
+	if err != nil {
+		return "", 0, false
+	}
+	divisor := rule.Divisor
+	if divisor <= 0 {
+		divisor = 1
+	}
+	return plu, float64(raw) / divisor, true
+}
+
+func validEAN13(code string) bool {
+	if len(code) != 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := code[i]
+		if d < '0' || d > '9' {
+			return false
+		}
+		if i%2 == 1 {
+			sum += int(d-'0') * 3
+		} else {
+			sum += int(d - '0')
+		}
+	}
+	last := code[12]
+	return last >= '0' && last <= '9' && int(last-'0') == (10-sum%10)%10
+}
+
+// defaultPricelistID is the active store's default pricelist when it has
+// one, else the company-wide default.
+func defaultPricelistID(ctx context.Context, db bun.IDB) (string, error) {
+	var id string
+	if storeID, ok := activeStore(ctx); ok {
+		err := db.NewSelect().Table("stores").Column("default_pricelist_id").
+			Where("id = ? AND default_pricelist_id IS NOT NULL", storeID).Scan(ctx, &id)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+	err := db.NewSelect().Table("pricelists").Column("id").
+		Where("is_default = true").Limit(1).Scan(ctx, &id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// productBasePrice mirrors the frontend basePrice: the variant's own entry
+// for the pricelist if it has one, else the product's, falling back to the
+// default pricelist. Markup strategies apply to the stored cost.
+func productBasePrice(p *models.Product, variantID *uuid.UUID, cost float64, pricelistID, defaultID string) (float64, bool) {
+	var entries []models.PricelistEntry
+	if variantID != nil {
+		for _, v := range p.Variants {
+			if v.ID == *variantID {
+				entries = v.Prices
+				break
+			}
+		}
+	}
+	for _, set := range [][]models.PricelistEntry{entries, p.Prices} {
+		for _, id := range []string{pricelistID, defaultID} {
+			for _, e := range set {
+				if id != "" && e.PricelistID == id {
+					return computeSalePrice(cost, e.Pricing), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// markupCost is the cost a markup pricing strategy applies to, following the
+// product's MarkupCostSource like the FE's costFromSource: fifo-current takes
+// the unit_cost (landed costs included) of the first owned batch to sell
+// under the allocation strategy, batch-avg the qty-weighted average over owned stock on hand. Falls back to
+// the manual cost when there is no owned stock.
+func markupCost(ctx context.Context, db bun.IDB, p *models.Product, variantID *uuid.UUID) (float64, error) {
+	cost := p.Cost
+	if variantID != nil {
+		for _, v := range p.Variants {
+			if v.ID == *variantID && v.Cost > 0 {
+				cost = v.Cost
+				break
+			}
+		}
+	}
+	source := markupSourceOrDefault(p.MarkupCostSource)
+	if source == "manual" {
+		return cost, nil
+	}
+	q := db.NewSelect().Table("batches").
+		Where("product_id = ?", p.ID).
+		Where("ownership = ?", models.BatchOwnershipOwned).
+		Where("status = ?", models.BatchStatusActive).
+		Where("qty_remaining > 0")
+	if variantID != nil {
+		q = q.Where("variant_id = ?", *variantID)
+	}
+	var row struct {
+		Cost float64 `bun:"cost"`
+		Qty  float64 `bun:"qty"`
+	}
+	if source == "fifo-current" {
+		settings, err := loadServerSettings(ctx, db)
+		if err != nil {
+			return 0, err
+		}
+		q = q.ColumnExpr("unit_cost AS cost, qty_remaining AS qty").
+			OrderExpr(batchPickOrder(settings.Inventory.AllocationStrategy)).
+			Limit(1)
+	} else {
+		q = q.ColumnExpr("COALESCE(SUM(qty_remaining * unit_cost) / NULLIF(SUM(qty_remaining), 0), 0) AS cost, COALESCE(SUM(qty_remaining), 0) AS qty")
+	}
+	if err := q.Scan(ctx, &row); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cost, nil
+		}
+		return 0, err
+	}
+	if row.Qty <= 0 {
+		return cost, nil
+	}
+	return row.Cost, nil
+}
+
+func computeSalePrice(cost float64, s models.PricingStrategy) float64 {
+	switch s.Kind {
+	case "markup_amount":
+		return cost + s.Value
+	case "markup_pct":
+		return cost * (1 + s.Value/100)
+	default:
+		return s.Value
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import "testing"
+
+func TestValidEAN13(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"4006381333931", true},
+		{"2112345012506", true},
+		{"2000000000008", true},
+		{"4006381333932", false},
+		{"2112345012505", false},
+		{"400638133393", false},
+		{"40063813339310", false},
+		{"21123458", false},
+		{"40063813339X1", false},
+		{"400638133393X", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validEAN13(tt.code); got != tt.want {
+			t.Errorf("validEAN13(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestParseEmbeddedBarcode(t *testing.T) {
+	weight := embeddedBarcodeRule{Prefix: "21", Kind: embeddedWeight, PLUDigits: 5, Divisor: 1000}
+	price := embeddedBarcodeRule{Prefix: "22", Kind: embeddedPrice, PLUDigits: 4}
+	tests := []struct {
+		name      string
+		code      string
+		rule      embeddedBarcodeRule
+		wantPLU   string
+		wantValue float64
+		wantOK    bool
+	}{
+		{
+			name: "weight in grams", code: "2112345012506", rule: weight,
+			wantPLU: "12345", wantValue: 1.25, wantOK: true,
+		},
+		{
+			name: "price without divisor", code: "2200420150004", rule: price,
+			wantPLU: "0042", wantValue: 15000, wantOK: true,
+		},
+		{
+			name: "default plu digits", code: "2112345012506",
+			rule:    embeddedBarcodeRule{Prefix: "21", Kind: embeddedWeight, Divisor: 1000},
+			wantPLU: "12345", wantValue: 1.25, wantOK: true,
+		},
+		{name: "other prefix", code: "2200420150004", rule: weight},
+		{name: "bad check digit", code: "2112345012505", rule: weight},
+		{name: "short code", code: "211234501250", rule: weight},
+		{name: "ean-8", code: "21123458", rule: weight},
+		{name: "empty prefix", code: "2112345012506", rule: embeddedBarcodeRule{Kind: embeddedWeight}},
+		{name: "unknown kind", code: "2112345012506", rule: embeddedBarcodeRule{Prefix: "21", Kind: "count"}},
+		{
+			name: "plu leaves no value digits", code: "2112345012506",
+			rule: embeddedBarcodeRule{Prefix: "21", Kind: embeddedWeight, PLUDigits: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plu, value, ok := parseEmbeddedBarcode(tt.code, tt.rule)
+			if ok != tt.wantOK || plu != tt.wantPLU || value != tt.wantValue {
+				t.Errorf("got (%q, %v, %v), want (%q, %v, %v)",
+					plu, value, ok, tt.wantPLU, tt.wantValue, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type SerialsHandler struct {
+	deps Deps
+}
+
+func NewSerialsHandler(deps Deps) *SerialsHandler {
+	return &SerialsHandler{deps: deps}
+}
+
+// List filters: productId, batchId, status, q (serial substring).
+func (h *SerialsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := []models.ProductSerial{}
+	sel := h.deps.DB.NewSelect().Model(&items).Order("created_at DESC", "serial ASC").Limit(500)
+	if v := strings.TrimSpace(q.Get("productId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("product_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("batchId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("batch_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("status")); v != "" {
+		sel = sel.Where("status = ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("q")); v != "" {
+		sel = sel.Where("serial ILIKE ?", "%"+v+"%")
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+type serialStatusInput struct {
+	Status string `json:"status"`
+}
+
+// UpdateStatus handles the manual moves: a sold unit brought back by the
+// customer becomes `returned`; a returned unit put back on the shelf becomes
+// `in_stock` (and loses its sale link). RMA moves go through warranty claims.
+func (h *SerialsHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in serialStatusInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	var sn models.ProductSerial
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(&sn).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		q := tx.NewUpdate().Model(&sn).WherePK().Set("updated_at = current_timestamp")
+		switch {
+		case in.Status == models.SerialStatusReturned && sn.Status == models.SerialStatusSold:
+			q = q.Set("status = ?", models.SerialStatusReturned)
+		case in.Status == models.SerialStatusInStock && sn.Status == models.SerialStatusReturned:
+			q = q.Set("status = ?", models.SerialStatusInStock).
+				Set("order_id = NULL, order_line_id = NULL, customer_id = NULL, sold_at = NULL").
+				Set("warranty_expires_at = ''")
+		default:
+			return errBadInput(fmt.Sprintf("status %s tidak bisa diubah ke %s", sn.Status, in.Status))
+		}
+		_, err := q.Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sn)
+}
+
+type warrantyLookupRow struct {
+	Serial       models.ProductSerial   `json:"serial"`
+	ProductName  string                 `json:"productName"`
+	VariantName  string                 `json:"variantName,omitempty"`
+	BatchCode    string                 `json:"batchCode,omitempty"`
+	SupplierID   *uuid.UUID             `json:"supplierId,omitempty"`
+	OrderCode    string                 `json:"orderCode,omitempty"`
+	CustomerName string                 `json:"customerName,omitempty"`
+	InWarranty   bool                   `json:"inWarranty"`
+	DaysLeft     *int                   `json:"daysLeft,omitempty"`
+	Claims       []models.WarrantyClaim `json:"claims"`
+}
+
+// Warranty looks a serial up across products (serials are only unique per
+// product) and returns who bought it, when, and whether it's still covered.
+func (h *SerialsHandler) Warranty(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serial := strings.TrimSpace(chi.URLParam(r, "serial"))
+	var serials []models.ProductSerial
+	if err := h.deps.DB.NewSelect().Model(&serials).
+		Where("upper(serial) = upper(?)", serial).
+		Order("created_at DESC").Scan(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(serials) == 0 {
+		writeError(w, http.StatusNotFound, "nomor seri tidak ditemukan")
+		return
+	}
+	today := startOfDay(time.Now())
+	out := make([]warrantyLookupRow, 0, len(serials))
+	for _, sn := range serials {
+		row := warrantyLookupRow{Serial: sn, Claims: []models.WarrantyClaim{}}
+		row.ProductName = lookupName(ctx, h.deps.DB, "products", sn.ProductID)
+		if sn.VariantID != nil {
+			row.VariantName = lookupName(ctx, h.deps.DB, "product_variants", *sn.VariantID)
+		}
+		if sn.BatchID != nil {
+			var b models.Batch
+			if err := h.deps.DB.NewSelect().Model(&b).Column("code", "supplier_id").
+				Where("id = ?", *sn.BatchID).Scan(ctx); err == nil {
+				row.BatchCode = b.Code
+				row.SupplierID = b.SupplierID
+			}
+		}
+		if sn.OrderID != nil {
+			_ = h.deps.DB.NewSelect().Table("orders").Column("code").
+				Where("id = ?", *sn.OrderID).Scan(ctx, &row.OrderCode)
+		}
+		if sn.CustomerID != nil {
+			row.CustomerName = lookupName(ctx, h.deps.DB, "customers", *sn.CustomerID)
+		}
+		if exp, err := time.ParseInLocation("2006-01-02", sn.WarrantyExpiresAt, time.Local); err == nil {
+			days := int(exp.Sub(today).Hours() / 24)
+			row.DaysLeft = &days
+			row.InWarranty = days >= 0
+		}
+		if err := h.deps.DB.NewSelect().Model(&row.Claims).
+			Where("serial_id = ?", sn.ID).
+			Order("created_at DESC").Scan(ctx); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out = append(out, row)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// receiptSerials validates the serials captured for one receipt line. A
+// serial-tracked product needs exactly one distinct, not-yet-known serial
+// per received base unit; other products must not carry any.
+func receiptSerials(ctx context.Context, tx bun.Tx, product *models.Product, baseQty float64, raw []string) ([]string, error) {
+	serials := make([]string, 0, len(raw))
+	seen := map[string]bool{}
+	for _, s := range raw {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		key := strings.ToUpper(s)
+		if seen[key] {
+			return nil, errBadInput("nomor seri ganda: " + s)
+		}
+		seen[key] = true
+		serials = append(serials, s)
+	}
+	if !product.SerialTracked {
+		if len(serials) > 0 {
+			return nil, errBadInput(product.Name + " tidak dilacak per nomor seri")
+		}
+		return nil, nil
+	}
+	if math.Abs(baseQty-math.Round(baseQty)) > 1e-9 {
+		return nil, errBadInput(product.Name + " dilacak per nomor seri; jumlah harus bulat")
+	}
+	if len(serials) != int(math.Round(baseQty)) {
+		return nil, errBadInput(fmt.Sprintf("%s butuh %d nomor seri, diisi %d",
+			product.Name, int(math.Round(baseQty)), len(serials)))
+	}
+	var existing []string
+	if err := tx.NewSelect().Table("product_serials").Column("serial").
+		Where("product_id = ?", product.ID).
+		Where("upper(serial) IN (?)", bun.In(keys(seen))).
+		Scan(ctx, &existing); err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, errBadInput("nomor seri sudah terdaftar: " + strings.Join(existing, ", "))
+	}
+	return serials, nil
+}
+
+// syncOrderSerials keeps product_serials in step with the order's lines:
+// serials dropped from a line (or every serial, when the order is
+// cancelled) go back in stock; new ones must be in stock (or returned),
+// out of a batch the line drew from, and are marked sold to the order's
+// customer with the warranty clock started at the order date.
+// Serial-tracked lines must list one serial per unit.
+func syncOrderSerials(ctx context.Context, tx bun.Tx, o *models.Order) error {
+	var order models.Order
+	if err := tx.NewSelect().Model(&order).
+		Column("id", "customer_id", "status", "created_at").
+		Where("id = ?", o.ID).Scan(ctx); err != nil {
+		return err
+	}
+
+	type wanted struct {
+		lineID    uuid.UUID
+		productID uuid.UUID
+		// Batches the line drew from; a serial must come out of one of
+		// them. nil while the line holds no allocations yet.
+		batches map[string]bool
+	}
+	fromLineBatch := func(wn wanted, batchID *uuid.UUID) bool {
+		return wn.batches == nil || (batchID != nil && wn.batches[batchID.String()])
+	}
+	want := map[string]wanted{}
+	if order.Status != models.OrderStatusCancelled {
+		productIDs := make([]uuid.UUID, 0, len(o.Lines))
+		for _, l := range o.Lines {
+			productIDs = append(productIDs, l.ProductID)
+		}
+		tracked := map[uuid.UUID]models.Product{}
+		if len(productIDs) > 0 {
+			var products []models.Product
+			if err := tx.NewSelect().Model(&products).
+				Column("id", "name", "serial_tracked", "warranty_months").
+				Where("id IN (?)", bun.In(productIDs)).
+				Where("serial_tracked").Scan(ctx); err != nil {
+				return err
+			}
+			for _, p := range products {
+				tracked[p.ID] = p
+			}
+		}
+		for _, l := range o.Lines {
+			p, isTracked := tracked[l.ProductID]
+			if !isTracked {
+				if len(l.Serials) > 0 {
+					return errBadInput(l.ProductName + " tidak dilacak per nomor seri")
+				}
+				continue
+			}
+			units := l.Quantity * l.UnitFactor
+			if len(l.Serials) != int(math.Round(units)) {
+				return errBadInput(fmt.Sprintf("%s butuh %d nomor seri, diisi %d",
+					p.Name, int(math.Round(units)), len(l.Serials)))
+			}
+			var batches map[string]bool
+			if len(l.BatchAllocations) > 0 {
+				batches = make(map[string]bool, len(l.BatchAllocations))
+				for _, a := range l.BatchAllocations {
+					batches[a.BatchID] = true
+				}
+			}
+			for _, s := range l.Serials {
+				key := l.ProductID.String() + "|" + strings.ToUpper(strings.TrimSpace(s))
+				if _, dup := want[key]; dup {
+					return errBadInput("nomor seri ganda: " + s)
+				}
+				want[key] = wanted{lineID: l.ID, productID: l.ProductID, batches: batches}
+			}
+		}
+	}
+
+	var current []models.ProductSerial
+	if err := tx.NewSelect().Model(&current).
+		Where("order_id = ?", o.ID).For("UPDATE").Scan(ctx); err != nil {
+		return err
+	}
+	for _, sn := range current {
+		key := sn.ProductID.String() + "|" + strings.ToUpper(sn.Serial)
+		if wn, ok := want[key]; ok && sn.OrderLineID != nil && *sn.OrderLineID == wn.lineID &&
+			fromLineBatch(wn, sn.BatchID) {
+			delete(want, key)
+			continue
+		}
+		if sn.Status == models.SerialStatusRMA {
+			return errBadInput("nomor seri " + sn.Serial + " sedang dalam klaim garansi")
+		}
+		if _, err := tx.NewUpdate().Model((*models.ProductSerial)(nil)).
+			Where("id = ?", sn.ID).
+			Set("status = ?", models.SerialStatusInStock).
+			Set("order_id = NULL, order_line_id = NULL, customer_id = NULL, sold_at = NULL").
+			Set("warranty_expires_at = ''").
+			Set("updated_at = current_timestamp").
+			Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	warrantyMonths := map[uuid.UUID]*int{}
+	for key, wn := range want {
+		serial := key[strings.Index(key, "|")+1:]
+		var sn models.ProductSerial
+		if err := tx.NewSelect().Model(&sn).
+			Where("product_id = ?", wn.productID).
+			Where("upper(serial) = ?", serial).
+			For("UPDATE").Scan(ctx); err != nil {
+			return errBadInput("nomor seri tidak ditemukan: " + serial)
+		}
+		if sn.Status != models.SerialStatusInStock && sn.Status != models.SerialStatusReturned {
+			return errBadInput("nomor seri " + sn.Serial + " tidak tersedia (" + sn.Status + ")")
+		}
+		if !fromLineBatch(wn, sn.BatchID) {
+			return errBadInput("nomor seri " + sn.Serial + " bukan dari batch yang terjual di baris ini")
+		}
+		months, ok := warrantyMonths[wn.productID]
+		if !ok {
+			var p models.Product
+			if err := tx.NewSelect().Model(&p).Column("warranty_months").
+				Where("id = ?", wn.productID).Scan(ctx); err != nil {
+				return err
+			}
+			months = p.WarrantyMonths
+			warrantyMonths[wn.productID] = months
+		}
+		expires := ""
+		if months != nil && *months > 0 {
+			expires = order.CreatedAt.AddDate(0, *months, 0).Format("2006-01-02")
+		}
+		if _, err := tx.NewUpdate().Model((*models.ProductSerial)(nil)).
+			Where("id = ?", sn.ID).
+			Set("status = ?", models.SerialStatusSold).
+			Set("order_id = ?", o.ID).
+			Set("order_line_id = ?", wn.lineID).
+			Set("customer_id = ?", order.CustomerID).
+			Set("sold_at = ?", order.CreatedAt).
+			Set("warranty_expires_at = ?", expires).
+			Set("updated_at = current_timestamp").
+			Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func keys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -66,8 +66,25 @@ func (h *ShiftsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		in.OpenedAt = time.Now()
 	}
 	in.EnsureSlices()
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	in.StoreID = storeID
 
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if in.RegisterID != nil {
+			ok, err := tx.NewSelect().Table("registers").
+				Where("id = ? AND store_id = ? AND status = ?", *in.RegisterID, storeID, models.StoreStatusActive).
+				Exists(ctx)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errBadInput("kasir tidak ditemukan di toko aktif")
+			}
+		}
 		code, err := nextShiftCode(ctx, tx)
 		if err != nil {
 			return err
@@ -80,10 +97,10 @@ func (h *ShiftsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "shift_sessions_one_open") {
-			writeError(w, http.StatusBadRequest, "masih ada shift terbuka. Tutup dulu sebelum membuka yang baru.")
+			writeError(w, http.StatusBadRequest, "masih ada shift terbuka di kasir ini. Tutup dulu sebelum membuka yang baru.")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeTxError(w, err)
 		return
 	}
 	full, err := loadShiftSession(r.Context(), h.deps.DB, in.ID)
@@ -110,7 +127,7 @@ func (h *ShiftsHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
 		res, err := tx.NewUpdate().Model(&in).WherePK().
-			ExcludeColumn("id", "code", "created_at", "updated_at").
+			ExcludeColumn("id", "code", "store_id", "register_id", "created_at", "updated_at").
 			Set("updated_at = current_timestamp").Exec(ctx)
 		if err != nil {
 			return err
@@ -126,7 +143,7 @@ func (h *ShiftsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "shift_sessions_one_open") {
-			writeError(w, http.StatusBadRequest, "hanya satu shift terbuka pada satu waktu")
+			writeError(w, http.StatusBadRequest, "hanya satu shift terbuka per kasir pada satu waktu")
 			return
 		}
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -159,6 +176,12 @@ func nextShiftCode(ctx context.Context, tx bun.Tx) (string, error) {
 func loadShiftSessions(ctx context.Context, db *bun.DB, q map[string][]string) ([]models.ShiftSession, error) {
 	var sessions []models.ShiftSession
 	qb := db.NewSelect().Model(&sessions).Order("opened_at DESC")
+	qb = scopeStore(ctx, qb, "ss.store_id")
+	if vals := q["registerId"]; len(vals) > 0 && vals[0] != "" {
+		if _, err := uuid.Parse(vals[0]); err == nil {
+			qb = qb.Where("register_id = ?", vals[0])
+		}
+	}
 	if vals := q["status"]; len(vals) > 0 && vals[0] != "" {
 		qb = qb.Where("status = ?", vals[0])
 	}
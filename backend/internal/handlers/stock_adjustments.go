@@ -0,0 +1,537 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type StockAdjustmentsHandler struct {
+	deps Deps
+}
+
+func NewStockAdjustmentsHandler(deps Deps) *StockAdjustmentsHandler {
+	return &StockAdjustmentsHandler{deps: deps}
+}
+
+// Reason codes accepted per direction; mirrors the frontend
+// adjustmentReasonsForIn / adjustmentReasonsForOut lists.
+var (
+	adjustmentReasonsIn = []string{
+		models.AdjustmentReasonFound, models.AdjustmentReasonInitialSeed,
+		models.AdjustmentReasonCorrection, models.AdjustmentReasonOther,
+	}
+	adjustmentReasonsOut = []string{
+		models.AdjustmentReasonDamaged, models.AdjustmentReasonExpired, models.AdjustmentReasonTheft,
+		models.AdjustmentReasonLost, models.AdjustmentReasonSample,
+		models.AdjustmentReasonCorrection, models.AdjustmentReasonOther,
+	}
+)
+
+// stockAdjustmentResult is what create/approve respond with: the adjustment
+// plus the movements it posted (empty while pending).
+type stockAdjustmentResult struct {
+	models.StockAdjustment
+	Movements []models.StockMovement `json:"movements"`
+}
+
+// List is the adjustment history. Filters: status, productId, reasonCode,
+// limit.
+func (h *StockAdjustmentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := []models.StockAdjustment{}
+	sel := h.deps.DB.NewSelect().Model(&items).Order("sa.created_at DESC")
+	sel = scopeStoreLocations(r.Context(), sel, "sa.location_id")
+	if v := strings.TrimSpace(q.Get("status")); v != "" {
+		sel = sel.Where("sa.status = ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("productId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("sa.product_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("reasonCode")); v != "" {
+		sel = sel.Where("sa.reason_code = ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			sel = sel.Limit(n)
+		}
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Queue lists pending adjustments oldest first — the approver's inbox.
+func (h *StockAdjustmentsHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	items := []models.StockAdjustment{}
+	q := h.deps.DB.NewSelect().Model(&items).
+		Where("sa.status = ?", models.AdjustmentStatusPending).
+		Order("sa.created_at ASC")
+	q = scopeStoreLocations(r.Context(), q, "sa.location_id")
+	if err := q.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *StockAdjustmentsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var a models.StockAdjustment
+	if err := h.deps.DB.NewSelect().Model(&a).Where("sa.id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+type stockAdjustmentInput struct {
+	ProductID  string  `json:"productId"`
+	VariantID  *string `json:"variantId,omitempty"`
+	LocationID *string `json:"locationId,omitempty"`
+	// Base units; positive opens a new owned batch, negative drains owned
+	// batches newest-first.
+	Delta float64 `json:"delta"`
+	// Per base unit; only used for positive deltas. Zero takes the newest
+	// batch or product cost.
+	UnitCost   float64 `json:"unitCost"`
+	ExpiresAt  string  `json:"expiresAt"`
+	ReasonCode string  `json:"reasonCode"`
+	ImageURL   string  `json:"imageUrl"`
+	Notes      string  `json:"notes"`
+}
+
+// Create records a manual adjustment. It posts immediately when it is below
+// the approval threshold or the caller may approve it themselves; otherwise
+// it is stored pending (HTTP 202) and stock is untouched until approved.
+func (h *StockAdjustmentsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in stockAdjustmentInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	productID, err := uuid.Parse(in.ProductID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "productId tidak valid")
+		return
+	}
+	if in.Delta == 0 {
+		writeError(w, http.StatusBadRequest, "Jumlah penyesuaian tidak boleh 0.")
+		return
+	}
+	allowed := adjustmentReasonsOut
+	if in.Delta > 0 {
+		allowed = adjustmentReasonsIn
+		if in.UnitCost < 0 {
+			writeError(w, http.StatusBadRequest, "Harga satuan harus 0 atau lebih.")
+			return
+		}
+	}
+	if !slices.Contains(allowed, in.ReasonCode) {
+		writeError(w, http.StatusBadRequest, "Alasan penyesuaian tidak valid untuk arah ini.")
+		return
+	}
+	ctx := r.Context()
+	locationID := optUUID(in.LocationID)
+	if locationID == nil {
+		def, err := defaultReceiptLocation(ctx, h.deps.DB)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Belum ada lokasi aktif.")
+			return
+		}
+		locationID = &def
+	} else if err := checkStoreLocation(ctx, h.deps.DB, *locationID); err != nil {
+		writeTxError(w, err)
+		return
+	}
+	a := models.StockAdjustment{
+		Kind:       models.AdjustmentKindAdjust,
+		ProductID:  productID,
+		VariantID:  optUUID(in.VariantID),
+		LocationID: *locationID,
+		QtyDelta:   in.Delta,
+		UnitCost:   in.UnitCost,
+		ReasonCode: in.ReasonCode,
+		ExpiresAt:  strings.TrimSpace(in.ExpiresAt),
+		ImageURL:   strings.TrimSpace(in.ImageURL),
+		Notes:      strings.TrimSpace(in.Notes),
+	}
+	submitStockAdjustment(w, r, h.deps.DB, &a, func(ctx context.Context, tx bun.Tx) error {
+		if a.QtyDelta > 0 {
+			// The threshold is priced at what the stock is known to cost,
+			// so a low client unitCost can't slip an adjustment past it.
+			refCost, err := adjustmentInUnitCost(ctx, tx, a.ProductID, a.VariantID)
+			if err != nil {
+				return err
+			}
+			if a.UnitCost <= 0 {
+				a.UnitCost = refCost
+			}
+			a.Value = a.QtyDelta * max(refCost, a.UnitCost)
+			return nil
+		}
+		value, onHand, err := adjustmentOutValue(ctx, tx, a.ProductID, a.VariantID, a.LocationID, -a.QtyDelta)
+		if err != nil {
+			return err
+		}
+		if -a.QtyDelta > onHand+1e-9 {
+			return errBadInput(fmt.Sprintf("Stok saat ini hanya %.4g. Tidak bisa kurangi lebih dari itu.", onHand))
+		}
+		a.Value = value
+		a.UnitCost = value / -a.QtyDelta
+		return nil
+	})
+}
+
+// submitStockAdjustment runs prepare (which fills Value and validates
+// against current stock), stores the adjustment and posts it unless it needs
+// an approval the caller can't give. Shared by Create and batch disposal.
+func submitStockAdjustment(
+	w http.ResponseWriter, r *http.Request, db *bun.DB, a *models.StockAdjustment,
+	prepare func(ctx context.Context, tx bun.Tx) error,
+) {
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, db)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	canApprove, err := hasPermission(ctx, db, PermStockAdjustApprove)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	actor := actorName(ctx, db)
+	var actorID *uuid.UUID
+	if claims, ok := auth.ClaimsFrom(ctx); ok {
+		actorID = &claims.UserID
+	}
+	a.RequestedBy, a.RequestedByID = actor, actorID
+
+	var res stockAdjustmentResult
+	err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := prepare(ctx, tx); err != nil {
+			return err
+		}
+		if err := insertPendingAdjustment(ctx, tx, a); err != nil {
+			return err
+		}
+		needsApproval := adjustmentNeedsApproval(settings, a)
+		if needsApproval && !canApprove {
+			res.StockAdjustment = *a
+			return nil
+		}
+		mvs, err := postStockAdjustment(ctx, tx, a)
+		if err != nil {
+			return err
+		}
+		a.Status = models.AdjustmentStatusPosted
+		if needsApproval {
+			now := time.Now()
+			a.DecidedBy, a.DecidedByID, a.DecidedAt = actor, actorID, &now
+		}
+		if err := saveAdjustmentDecision(ctx, tx, a); err != nil {
+			return err
+		}
+		res = stockAdjustmentResult{StockAdjustment: *a, Movements: mvs}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if res.Movements == nil {
+		res.Movements = []models.StockMovement{}
+	}
+	status := http.StatusCreated
+	if res.Status == models.AdjustmentStatusPending {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, res)
+}
+
+// insertPendingAdjustment numbers and stores an adjustment as pending.
+func insertPendingAdjustment(ctx context.Context, tx bun.Tx, a *models.StockAdjustment) error {
+	code, err := nextStockAdjustmentCode(ctx, tx)
+	if err != nil {
+		return err
+	}
+	a.Code = code
+	a.Status = models.AdjustmentStatusPending
+	_, err = tx.NewInsert().Model(a).Returning("*").Exec(ctx)
+	return err
+}
+
+type stockAdjustmentDecisionInput struct {
+	Notes string `json:"notes"`
+}
+
+// Approve posts a pending adjustment. Requires feature.stock.adjust-approve.
+// Stock may have moved since the request; a write-off larger than what is
+// left on its batch is refused, a drain takes what owned stock remains.
+func (h *StockAdjustmentsHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, func(ctx context.Context, tx bun.Tx, a *models.StockAdjustment) ([]models.StockMovement, error) {
+		mvs, err := postStockAdjustment(ctx, tx, a)
+		if err != nil {
+			return nil, err
+		}
+		a.Status = models.AdjustmentStatusPosted
+		return mvs, nil
+	})
+}
+
+// Reject closes a pending adjustment without touching stock.
+func (h *StockAdjustmentsHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, func(ctx context.Context, tx bun.Tx, a *models.StockAdjustment) ([]models.StockMovement, error) {
+		a.Status = models.AdjustmentStatusRejected
+		return nil, nil
+	})
+}
+
+func (h *StockAdjustmentsHandler) decide(
+	w http.ResponseWriter, r *http.Request,
+	apply func(ctx context.Context, tx bun.Tx, a *models.StockAdjustment) ([]models.StockMovement, error),
+) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in stockAdjustmentDecisionInput
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	ctx := r.Context()
+	ok, err := hasPermission(ctx, h.deps.DB, PermStockAdjustApprove)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusForbidden, "Anda tidak berhak menyetujui penyesuaian stok.")
+		return
+	}
+	actor := actorName(ctx, h.deps.DB)
+	var actorID *uuid.UUID
+	if claims, ok := auth.ClaimsFrom(ctx); ok {
+		actorID = &claims.UserID
+	}
+
+	var res stockAdjustmentResult
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var a models.StockAdjustment
+		if err := tx.NewSelect().Model(&a).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if a.Status != models.AdjustmentStatusPending {
+			return errBadInput("Penyesuaian ini sudah diputuskan.")
+		}
+		mvs, err := apply(ctx, tx, &a)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		a.DecidedBy, a.DecidedByID, a.DecidedAt = actor, actorID, &now
+		a.DecisionNotes = strings.TrimSpace(in.Notes)
+		if err := saveAdjustmentDecision(ctx, tx, &a); err != nil {
+			return err
+		}
+		res = stockAdjustmentResult{StockAdjustment: a, Movements: mvs}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if res.Movements == nil {
+		res.Movements = []models.StockMovement{}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func adjustmentNeedsApproval(s serverSettings, a *models.StockAdjustment) bool {
+	if slices.Contains(s.Inventory.AdjustmentApprovalReasons, a.ReasonCode) {
+		return true
+	}
+	return a.Value >= s.Inventory.AdjustmentApprovalThreshold
+}
+
+// postStockAdjustment applies the adjustment to stock and returns the
+// movements it wrote, each referencing the adjustment document. An adjust
+// with a batch (an opname variance) corrects that batch while it exists.
+func postStockAdjustment(ctx context.Context, tx bun.Tx, a *models.StockAdjustment) ([]models.StockMovement, error) {
+	ref := models.StockMovementReference{Kind: models.MovementRefManual, ID: a.ID.String(), Code: a.Code}
+	reason := a.ReasonCode
+	if a.Kind == models.AdjustmentKindWriteOff {
+		if a.BatchID == nil {
+			return nil, errBadInput("Batch untuk pemusnahan tidak ada.")
+		}
+		var b models.Batch
+		if err := tx.NewSelect().Model(&b).Where("id = ?", *a.BatchID).For("UPDATE").Scan(ctx); err != nil {
+			return nil, errBadInput("Batch untuk pemusnahan sudah tidak ada.")
+		}
+		mv, err := writeOffBatch(ctx, tx, &b, -a.QtyDelta, reason, a.ImageURL, a.Notes, a.RequestedBy, ref)
+		if err != nil {
+			return nil, err
+		}
+		return []models.StockMovement{*mv}, nil
+	}
+	notes := a.Notes
+	if notes == "" {
+		notes = "Penyesuaian stok " + a.Code
+	}
+	adj := stockAdjustment{
+		ProductID:   a.ProductID,
+		VariantID:   a.VariantID,
+		LocationID:  a.LocationID,
+		Delta:       a.QtyDelta,
+		UnitCost:    a.UnitCost,
+		ExpiresAt:   a.ExpiresAt,
+		Reference:   ref,
+		Reason:      &reason,
+		ImageURL:    a.ImageURL,
+		Notes:       notes,
+		PerformedBy: a.RequestedBy,
+	}
+	if a.BatchID != nil {
+		var b models.Batch
+		err := tx.NewSelect().Model(&b).Where("id = ?", *a.BatchID).For("UPDATE").Scan(ctx)
+		switch {
+		case err == nil:
+			mv, err := adjustBatchQty(ctx, tx, &b, a.QtyDelta, adj)
+			if err != nil {
+				return nil, err
+			}
+			return []models.StockMovement{*mv}, nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+	}
+	return applyStockAdjustment(ctx, tx, adj)
+}
+
+func saveAdjustmentDecision(ctx context.Context, tx bun.Tx, a *models.StockAdjustment) error {
+	_, err := tx.NewUpdate().Model(a).
+		Column("status", "decided_by", "decided_by_id", "decided_at", "decision_notes").
+		Set("updated_at = current_timestamp").
+		WherePK().Exec(ctx)
+	return err
+}
+
+// adjustmentOutValue prices draining qty from owned stock in the order
+// applyStockAdjustment would take it (active batches in the target
+// location's store only, target location first, newest batch first), and
+// reports the owned quantity there that no live reservation holds.
+func adjustmentOutValue(
+	ctx context.Context, db bun.IDB,
+	productID uuid.UUID, variantID *uuid.UUID, locationID uuid.UUID, qty float64,
+) (value, onHand float64, err error) {
+	var batches []models.Batch
+	q := db.NewSelect().Model(&batches).
+		Where("product_id = ?", productID).
+		Where("ownership = ?", models.BatchOwnershipOwned).
+		Where("status = ?", models.BatchStatusActive).
+		Where("qty_remaining > 0").
+		Where("location_id"+sameStoreLocationsSQL, locationID).
+		OrderExpr("(location_id = ?) DESC, received_at DESC, created_at DESC", locationID)
+	if variantID != nil {
+		q = q.Where("variant_id = ?", *variantID)
+	} else {
+		q = q.Where("variant_id IS NULL")
+	}
+	if err := q.Scan(ctx); err != nil {
+		return 0, 0, err
+	}
+	ids := make([]uuid.UUID, len(batches))
+	for i, b := range batches {
+		ids[i] = b.ID
+	}
+	heldBy, err := reservedByBatch(ctx, db, ids)
+	if err != nil {
+		return 0, 0, err
+	}
+	need := qty
+	for _, b := range batches {
+		free := max(b.QtyRemaining-heldBy[b.ID], 0)
+		onHand += free
+		take := min(need, free)
+		if take > 0 {
+			value += take * b.UnitCost
+			need -= take
+		}
+	}
+	return value, onHand, nil
+}
+
+// adjustmentInUnitCost is the cost a positive adjustment is valued at: the
+// newest owned batch of the product/variant, else the variant or product
+// cost.
+func adjustmentInUnitCost(ctx context.Context, db bun.IDB, productID uuid.UUID, variantID *uuid.UUID) (float64, error) {
+	var costs []float64
+	q := db.NewSelect().Table("batches").Column("unit_cost").
+		Where("product_id = ?", productID).
+		Where("ownership = ?", models.BatchOwnershipOwned).
+		Order("received_at DESC", "created_at DESC").
+		Limit(1)
+	if variantID != nil {
+		q = q.Where("variant_id = ?", *variantID)
+	} else {
+		q = q.Where("variant_id IS NULL")
+	}
+	if err := q.Scan(ctx, &costs); err != nil {
+		return 0, err
+	}
+	if len(costs) > 0 {
+		return costs[0], nil
+	}
+	var cost float64
+	var err error
+	if variantID != nil {
+		err = db.NewSelect().Table("product_variants").Column("cost").
+			Where("id = ?", *variantID).Scan(ctx, &cost)
+	} else {
+		err = db.NewSelect().Table("products").Column("cost").
+			Where("id = ?", productID).Scan(ctx, &cost)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errBadInput("produk tidak ditemukan")
+	}
+	return cost, err
+}
+
+func nextStockAdjustmentCode(ctx context.Context, tx bun.Tx) (string, error) {
+	year := time.Now().Year()
+	prefix := fmt.Sprintf("ADJ-%d-", year)
+	var count int
+	if err := tx.NewSelect().Table("stock_adjustments").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%04d", prefix, count+1), nil
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// logMovement stamps a code on the movement and inserts it inside the
+// caller's transaction. Server-side stock workflows (PO receive, opname
+// completion, production) all go through here so every batch mutation gets
+// exactly one audit row.
+func logMovement(ctx context.Context, tx bun.Tx, m *models.StockMovement) error {
+	m.ID = uuid.Nil
+	if m.At.IsZero() {
+		m.At = time.Now()
+	}
+	code, err := nextMovementCode(ctx, tx, m.At)
+	if err != nil {
+		return err
+	}
+	m.Code = code
+	_, err = tx.NewInsert().Model(m).Returning("*").Exec(ctx)
+	return err
+}
+
+// actorName resolves the calling user's display name for performed_by
+// columns. Falls back to "" when the request carries no claims (shouldn't
+// happen behind RequireAuth) or the user row is gone.
+func actorName(ctx context.Context, db bun.IDB) string {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return ""
+	}
+	var name string
+	if err := db.NewSelect().Table("users").Column("name").
+		Where("id = ?", claims.UserID).Scan(ctx, &name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// defaultReceiptLocation returns the location flagged is_default_receipt, or
+// the first active location by display order when none is flagged — within
+// the active store when the request has one.
+func defaultReceiptLocation(ctx context.Context, db bun.IDB) (uuid.UUID, error) {
+	var id uuid.UUID
+	q := db.NewSelect().Table("locations").Column("id").
+		Where("status = ?", models.LocationStatusActive).
+		OrderExpr("is_default_receipt DESC, display_order ASC, name ASC").
+		Limit(1)
+	if storeID, ok := activeStore(ctx); ok {
+		q = q.Where("store_id = ?", storeID)
+	}
+	err := q.Scan(ctx, &id)
+	return id, err
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+// stockAdjustment describes a quantity correction not tied to a specific
+// batch. Mirrors the frontend batches.adjustStock contract: a positive delta
+// opens a new owned batch at LocationID; a negative delta drains active owned
+// batches in LocationID's store newest-first (at LocationID before
+// elsewhere) so FIFO order for future sales is preserved. Quantity held by
+// live reservations is never drained.
+type stockAdjustment struct {
+	ProductID   uuid.UUID
+	VariantID   *uuid.UUID
+	LocationID  uuid.UUID
+	Delta       float64
+	UnitCost    float64
+	ExpiresAt   string
+	Reference   models.StockMovementReference
+	Reason      *string
+	ImageURL    string
+	Notes       string
+	PerformedBy string
+}
+
+// applyStockAdjustment posts the adjustment and returns the movements it
+// wrote. A negative delta larger than on-hand owned stock drains what there
+// is; the shortfall is silently dropped, matching the frontend behaviour.
+func applyStockAdjustment(ctx context.Context, tx bun.Tx, a stockAdjustment) ([]models.StockMovement, error) {
+	if a.Delta == 0 {
+		return nil, nil
+	}
+	if a.Delta > 0 {
+		code, err := nextBatchCode(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		b := models.Batch{
+			Code:         code,
+			ProductID:    a.ProductID,
+			VariantID:    a.VariantID,
+			Ownership:    models.BatchOwnershipOwned,
+			UnitCost:     a.UnitCost,
+			QtyReceived:  a.Delta,
+			QtyRemaining: a.Delta,
+			ReceivedAt:   time.Now().Format("2006-01-02"),
+			ExpiresAt:    a.ExpiresAt,
+			LocationID:   a.LocationID,
+			Notes:        a.Notes,
+		}
+		if _, err := tx.NewInsert().Model(&b).Returning("*").Exec(ctx); err != nil {
+			return nil, err
+		}
+		mv := models.StockMovement{
+			Kind:        models.MovementKindAdjustIn,
+			ProductID:   &b.ProductID,
+			VariantID:   b.VariantID,
+			LocationID:  &b.LocationID,
+			BatchID:     &b.ID,
+			QtyDelta:    a.Delta,
+			QtyAfter:    b.QtyRemaining,
+			UnitCost:    floatPtr(a.UnitCost),
+			Reference:   a.Reference,
+			Reason:      a.Reason,
+			ImageURL:    a.ImageURL,
+			PerformedBy: a.PerformedBy,
+			Notes:       a.Notes,
+		}
+		if err := logMovement(ctx, tx, &mv); err != nil {
+			return nil, err
+		}
+		return []models.StockMovement{mv}, nil
+	}
+
+	var candidates []models.Batch
+	q := tx.NewSelect().Model(&candidates).
+		Where("product_id = ?", a.ProductID).
+		Where("ownership = ?", models.BatchOwnershipOwned).
+		Where("status = ?", models.BatchStatusActive).
+		Where("qty_remaining > 0").
+		Where("location_id"+sameStoreLocationsSQL, a.LocationID).
+		OrderExpr("(location_id = ?) DESC, received_at DESC, created_at DESC", a.LocationID).
+		For("UPDATE")
+	if a.VariantID != nil {
+		q = q.Where("variant_id = ?", *a.VariantID)
+	} else {
+		q = q.Where("variant_id IS NULL")
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(candidates))
+	for i, b := range candidates {
+		ids[i] = b.ID
+	}
+	heldBy, err := reservedByBatch(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	remaining := -a.Delta
+	var out []models.StockMovement
+	for i := range candidates {
+		if remaining <= 1e-9 {
+			break
+		}
+		b := &candidates[i]
+		free := b.QtyRemaining - heldBy[b.ID]
+		if free <= 1e-9 {
+			continue
+		}
+		mv, err := adjustBatchQty(ctx, tx, b, -min(remaining, free), a)
+		if err != nil {
+			return nil, err
+		}
+		remaining += mv.QtyDelta
+		out = append(out, *mv)
+	}
+	return out, nil
+}
+
+// adjustBatchQty applies delta to one already-locked batch (clamped so
+// qty_remaining never goes negative) and logs the adjust-in/adjust-out
+// movement. The batch struct is updated in place.
+func adjustBatchQty(
+	ctx context.Context, tx bun.Tx, b *models.Batch, delta float64, a stockAdjustment,
+) (*models.StockMovement, error) {
+	if b.QtyRemaining+delta < 0 {
+		delta = -b.QtyRemaining
+	}
+	b.QtyRemaining += delta
+	if _, err := tx.NewUpdate().Table("batches").
+		Where("id = ?", b.ID).
+		Set("qty_remaining = ?", b.QtyRemaining).
+		Set("updated_at = current_timestamp").
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+	kind := models.MovementKindAdjustIn
+	if delta < 0 {
+		kind = models.MovementKindAdjustOut
+	}
+	mv := models.StockMovement{
+		Kind:        kind,
+		ProductID:   &b.ProductID,
+		VariantID:   b.VariantID,
+		LocationID:  &b.LocationID,
+		BatchID:     &b.ID,
+		QtyDelta:    delta,
+		QtyAfter:    b.QtyRemaining,
+		UnitCost:    floatPtr(b.UnitCost),
+		Reference:   a.Reference,
+		Reason:      a.Reason,
+		ImageURL:    a.ImageURL,
+		PerformedBy: a.PerformedBy,
+		Notes:       a.Notes,
+	}
+	if err := logMovement(ctx, tx, &mv); err != nil {
+		return nil, err
+	}
+	return &mv, nil
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -25,6 +24,7 @@ func NewStockMovementsHandler(deps Deps) *StockMovementsHandler {
 func (h *StockMovementsHandler) List(w http.ResponseWriter, r *http.Request) {
 	items := []models.StockMovement{}
 	q := h.deps.DB.NewSelect().Model(&items).Order("happened_at DESC")
+	q = scopeStoreLocations(r.Context(), q, "sm.location_id")
 	if v := strings.TrimSpace(r.URL.Query().Get("productId")); v != "" {
 		if _, err := uuid.Parse(v); err == nil {
 			q = q.Where("product_id = ?", v)
@@ -52,37 +52,6 @@ func (h *StockMovementsHandler) List(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, items)
 }
 
-// Create logs a single movement. Code generated server-side.
-func (h *StockMovementsHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var in models.StockMovement
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid json")
-		return
-	}
-	if strings.TrimSpace(in.Kind) == "" {
-		writeError(w, http.StatusBadRequest, "kind wajib diisi")
-		return
-	}
-	in.ID = uuid.Nil
-	if in.At.IsZero() {
-		in.At = time.Now()
-	}
-	err := h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
-		code, err := nextMovementCode(ctx, tx, in.At)
-		if err != nil {
-			return err
-		}
-		in.Code = code
-		_, err = tx.NewInsert().Model(&in).Returning("*").Exec(ctx)
-		return err
-	})
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	writeJSON(w, http.StatusCreated, in)
-}
-
 func nextMovementCode(ctx context.Context, tx bun.Tx, at time.Time) (string, error) {
 	year := at.Year()
 	prefix := fmt.Sprintf("MOV-%d-", year)
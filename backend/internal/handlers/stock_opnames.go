@@ -23,11 +23,18 @@ func NewStockOpnamesHandler(deps Deps) *StockOpnamesHandler {
 
 func (h *StockOpnamesHandler) List(w http.ResponseWriter, r *http.Request) {
 	items := []models.StockOpname{}
-	if err := h.deps.DB.NewSelect().
+	q := h.deps.DB.NewSelect().
 		Model(&items).
 		Relation("Lines").
-		Order("started_at DESC").
-		Scan(r.Context()); err != nil {
+		Order("started_at DESC")
+	// Whole-shop opnames (no location) predate stores; keep them visible.
+	if storeID, ok := activeStore(r.Context()); ok {
+		q = q.Where("so.location_id IS NULL OR so.location_id IN (SELECT id FROM locations WHERE store_id = ?)", storeID)
+	}
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		q = q.Where("so.kind = ?", kind)
+	}
+	if err := q.Scan(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -57,21 +64,23 @@ func (h *StockOpnamesHandler) Get(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, op)
 }
 
+// opnameLineInput scopes a draft. Expected quantities and costs are never
+// taken from the client: Start snapshots them from the batches, and counts
+// go through RecordCounts.
 type opnameLineInput struct {
-	ID          string   `json:"id,omitempty"`
-	ProductID   string   `json:"productId"`
-	VariantID   *string  `json:"variantId,omitempty"`
-	ExpectedQty float64  `json:"expectedQty"`
-	CountedQty  *float64 `json:"countedQty"`
-	UnitCost    float64  `json:"unitCost"`
-	Notes       string   `json:"notes"`
+	ProductID  string  `json:"productId"`
+	VariantID  *string `json:"variantId,omitempty"`
+	BatchID    *string `json:"batchId,omitempty"`
+	LocationID *string `json:"locationId,omitempty"`
+	Notes      string  `json:"notes"`
 }
 
+// opnameCreateInput opens a draft; status only moves through the
+// start / complete / cancel endpoints.
 type opnameCreateInput struct {
 	LocationID  *string           `json:"locationId,omitempty"`
 	PerformedBy string            `json:"performedBy"`
 	Notes       string            `json:"notes"`
-	Status      string            `json:"status"`
 	Lines       []opnameLineInput `json:"lines"`
 }
 
@@ -81,13 +90,10 @@ func (h *StockOpnamesHandler) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
-	if in.Status == "" {
-		in.Status = "draft"
-	}
 	op := models.StockOpname{
 		LocationID:  optUUID(in.LocationID),
 		StartedAt:   time.Now(),
-		Status:      in.Status,
+		Status:      models.OpnameStatusDraft,
 		PerformedBy: in.PerformedBy,
 		Notes:       in.Notes,
 	}
@@ -108,13 +114,12 @@ func (h *StockOpnamesHandler) Create(w http.ResponseWriter, r *http.Request) {
 					return fmt.Errorf("line productId invalid: %w", err)
 				}
 				rows = append(rows, models.StockOpnameLine{
-					OpnameID:    op.ID,
-					ProductID:   pid,
-					VariantID:   optUUID(l.VariantID),
-					ExpectedQty: l.ExpectedQty,
-					CountedQty:  l.CountedQty,
-					UnitCost:    l.UnitCost,
-					Notes:       l.Notes,
+					OpnameID:   op.ID,
+					ProductID:  pid,
+					VariantID:  optUUID(l.VariantID),
+					BatchID:    optUUID(l.BatchID),
+					LocationID: optUUID(l.LocationID),
+					Notes:      l.Notes,
 				})
 			}
 			if _, err := tx.NewInsert().Model(&rows).Exec(ctx); err != nil {
@@ -142,10 +147,8 @@ func (h *StockOpnamesHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 type opnameUpdateInput struct {
-	Status      *string            `json:"status,omitempty"`
-	CompletedAt *time.Time         `json:"completedAt,omitempty"`
-	Notes       *string            `json:"notes,omitempty"`
-	Lines       *[]opnameLineInput `json:"lines,omitempty"`
+	Notes *string            `json:"notes,omitempty"`
+	Lines *[]opnameLineInput `json:"lines,omitempty"`
 }
 
 func (h *StockOpnamesHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -164,20 +167,8 @@ func (h *StockOpnamesHandler) Update(w http.ResponseWriter, r *http.Request) {
 		q := tx.NewUpdate().Table("stock_opnames").
 			Where("id = ?", id).
 			Set("updated_at = current_timestamp")
-		any := false
-		if in.Status != nil {
-			q = q.Set("status = ?", *in.Status)
-			any = true
-		}
-		if in.CompletedAt != nil {
-			q = q.Set("completed_at = ?", *in.CompletedAt)
-			any = true
-		}
 		if in.Notes != nil {
 			q = q.Set("notes = ?", *in.Notes)
-			any = true
-		}
-		if any {
 			res, err := q.Exec(ctx)
 			if err != nil {
 				return err
@@ -187,6 +178,16 @@ func (h *StockOpnamesHandler) Update(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if in.Lines != nil {
+			// Once Start has snapshotted expected quantities the lines belong
+			// to the server workflow; counts go through RecordCounts.
+			var status string
+			if err := tx.NewSelect().Table("stock_opnames").Column("status").
+				Where("id = ?", id).Scan(ctx, &status); err != nil {
+				return fmt.Errorf("not found")
+			}
+			if status != models.OpnameStatusDraft {
+				return errBadInput("baris opname hanya bisa diganti saat draft")
+			}
 			if _, err := tx.NewDelete().Table("stock_opname_lines").
 				Where("opname_id = ?", id).Exec(ctx); err != nil {
 				return err
@@ -199,13 +200,12 @@ func (h *StockOpnamesHandler) Update(w http.ResponseWriter, r *http.Request) {
 						return fmt.Errorf("line productId invalid: %w", err)
 					}
 					rows = append(rows, models.StockOpnameLine{
-						OpnameID:    id,
-						ProductID:   pid,
-						VariantID:   optUUID(l.VariantID),
-						ExpectedQty: l.ExpectedQty,
-						CountedQty:  l.CountedQty,
-						UnitCost:    l.UnitCost,
-						Notes:       l.Notes,
+						OpnameID:   id,
+						ProductID:  pid,
+						VariantID:  optUUID(l.VariantID),
+						BatchID:    optUUID(l.BatchID),
+						LocationID: optUUID(l.LocationID),
+						Notes:      l.Notes,
 					})
 				}
 				if _, err := tx.NewInsert().Model(&rows).Exec(ctx); err != nil {
@@ -220,7 +220,7 @@ func (h *StockOpnamesHandler) Update(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusNotFound, "not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeTxError(w, err)
 		return
 	}
 
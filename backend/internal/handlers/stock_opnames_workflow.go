@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// ─── Server-driven opname workflow ──────────────────────────────────────────
+// draft → Start (snapshot expected qty per batch+location) → counting →
+// RecordCounts (any number of times) → Complete (post variances) → completed.
+// Expected quantities are never taken from the client on this path.
+
+type opnameStartInput struct {
+	ProductIDs []string `json:"productIds"`
+	CategoryID *string  `json:"categoryId,omitempty"`
+}
+
+// Start freezes expected quantities. One line per batch with stock left at
+// the opname's location (or every location when the opname has none),
+// optionally narrowed to a product list or category (a cycle count defaults
+// to its draft's products). Any draft lines posted earlier are replaced.
+func (h *StockOpnamesHandler) Start(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in opnameStartInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	productIDs := make([]uuid.UUID, 0, len(in.ProductIDs))
+	for _, s := range in.ProductIDs {
+		pid, err := uuid.Parse(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "productIds tidak valid")
+			return
+		}
+		productIDs = append(productIDs, pid)
+	}
+	categoryID := optUUID(in.CategoryID)
+
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		op, err := lockOpname(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if op.Status != models.OpnameStatusDraft {
+			return errBadInput("hanya opname draft yang bisa dimulai")
+		}
+		if op.Kind == models.OpnameKindCycle && len(productIDs) == 0 {
+			// A scheduled cycle count is scoped to the products its draft
+			// lines were generated for.
+			if err := tx.NewSelect().Table("stock_opname_lines").
+				ColumnExpr("DISTINCT product_id").
+				Where("opname_id = ?", id).
+				Scan(ctx, &productIDs); err != nil {
+				return err
+			}
+		}
+		lines, err := snapshotOpnameLines(ctx, tx, op, productIDs, categoryID)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return errBadInput("tidak ada stok untuk dihitung pada lingkup ini")
+		}
+		if _, err := tx.NewDelete().Table("stock_opname_lines").
+			Where("opname_id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewInsert().Model(&lines).Exec(ctx); err != nil {
+			return err
+		}
+		now := time.Now()
+		_, err = tx.NewUpdate().Table("stock_opnames").Where("id = ?", id).
+			Set("status = ?", models.OpnameStatusCounting).
+			Set("snapshot_at = ?", now).
+			Set("started_at = ?", now).
+			Set("updated_at = current_timestamp").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	h.respondOpname(w, r, id, http.StatusOK)
+}
+
+// snapshotOpnameLines reads the batches in scope: the opname's location,
+// else every location of the active store. Runs inside the Start tx so the
+// snapshot and snapshot_at agree.
+func snapshotOpnameLines(
+	ctx context.Context, tx bun.Tx, op *models.StockOpname,
+	productIDs []uuid.UUID, categoryID *uuid.UUID,
+) ([]models.StockOpnameLine, error) {
+	var batches []models.Batch
+	q := tx.NewSelect().Model(&batches).
+		Where("bt.qty_remaining > 0").
+		OrderExpr("bt.product_id, bt.variant_id, bt.location_id, bt.received_at")
+	if op.LocationID != nil {
+		q = q.Where("bt.location_id = ?", *op.LocationID)
+	} else {
+		q = scopeStoreLocations(ctx, q, "bt.location_id")
+	}
+	if len(productIDs) > 0 {
+		q = q.Where("bt.product_id IN (?)", bun.In(productIDs))
+	}
+	if categoryID != nil {
+		q = q.Join("JOIN products AS p ON p.id = bt.product_id").
+			Where("p.category_id IN ("+categorySubtreeSQL+")", *categoryID)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, err
+	}
+	lines := make([]models.StockOpnameLine, 0, len(batches))
+	for _, b := range batches {
+		batchID, locID := b.ID, b.LocationID
+		lines = append(lines, models.StockOpnameLine{
+			OpnameID:    op.ID,
+			ProductID:   b.ProductID,
+			VariantID:   b.VariantID,
+			BatchID:     &batchID,
+			LocationID:  &locID,
+			ExpectedQty: b.QtyRemaining,
+			UnitCost:    b.UnitCost,
+		})
+	}
+	return lines, nil
+}
+
+type opnameCountInput struct {
+	LineID     string   `json:"lineId,omitempty"`
+	ProductID  string   `json:"productId,omitempty"`
+	VariantID  *string  `json:"variantId,omitempty"`
+	LocationID *string  `json:"locationId,omitempty"`
+	CountedQty *float64 `json:"countedQty"`
+	UnitCost   *float64 `json:"unitCost,omitempty"`
+	Notes      *string  `json:"notes,omitempty"`
+}
+
+type opnameCountsInput struct {
+	Lines []opnameCountInput `json:"lines"`
+}
+
+// RecordCounts stores counted quantities with the time they were taken.
+// Entries with lineId update that line; entries without add a "found" line
+// (expected 0, no batch) for stock that wasn't in the snapshot.
+func (h *StockOpnamesHandler) RecordCounts(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in opnameCountsInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		op, err := lockOpname(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if op.Status != models.OpnameStatusCounting {
+			return errBadInput("opname belum dimulai atau sudah selesai")
+		}
+		now := time.Now()
+		for _, c := range in.Lines {
+			if c.CountedQty != nil && *c.CountedQty < 0 {
+				return errBadInput("jumlah hitung tidak boleh negatif")
+			}
+			if c.LineID != "" {
+				lineID, err := uuid.Parse(c.LineID)
+				if err != nil {
+					return errBadInput("lineId tidak valid")
+				}
+				q := tx.NewUpdate().Table("stock_opname_lines").
+					Where("id = ? AND opname_id = ?", lineID, id).
+					Set("counted_qty = ?", c.CountedQty)
+				if c.CountedQty != nil {
+					q = q.Set("counted_at = ?", now)
+				} else {
+					q = q.Set("counted_at = NULL")
+				}
+				if c.Notes != nil {
+					q = q.Set("notes = ?", *c.Notes)
+				}
+				res, err := q.Exec(ctx)
+				if err != nil {
+					return err
+				}
+				if n, _ := res.RowsAffected(); n == 0 {
+					return errBadInput("baris opname tidak ditemukan: " + c.LineID)
+				}
+				continue
+			}
+			pid, err := uuid.Parse(c.ProductID)
+			if err != nil {
+				return errBadInput("productId tidak valid")
+			}
+			if c.CountedQty == nil {
+				return errBadInput("countedQty wajib diisi untuk baris baru")
+			}
+			locID := op.LocationID
+			if l := optUUID(c.LocationID); l != nil {
+				locID = l
+			}
+			unitCost := 0.0
+			if c.UnitCost != nil {
+				unitCost = *c.UnitCost
+			} else if err := tx.NewSelect().Table("products").Column("cost").
+				Where("id = ?", pid).Scan(ctx, &unitCost); err != nil {
+				return errBadInput("produk tidak ditemukan")
+			}
+			counted := *c.CountedQty
+			line := models.StockOpnameLine{
+				OpnameID:   id,
+				ProductID:  pid,
+				VariantID:  optUUID(c.VariantID),
+				LocationID: locID,
+				CountedQty: &counted,
+				CountedAt:  &now,
+				UnitCost:   unitCost,
+			}
+			if c.Notes != nil {
+				line.Notes = *c.Notes
+			}
+			if _, err := tx.NewInsert().Model(&line).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		_, err = tx.NewUpdate().Table("stock_opnames").Where("id = ?", id).
+			Set("updated_at = current_timestamp").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	h.respondOpname(w, r, id, http.StatusOK)
+}
+
+type opnameCompleteInput struct {
+	SkipUncounted bool `json:"skipUncounted"`
+}
+
+// OpnameSummary is the shrinkage report for a completed (or in-progress)
+// opname. Values are at each line's UnitCost snapshot.
+type OpnameSummary struct {
+	CountedLines   int `json:"countedLines"`
+	UncountedLines int `json:"uncountedLines"`
+	AdjustedLines  int `json:"adjustedLines"`
+	// Lines whose variance waits on a pending stock adjustment.
+	PendingLines   int     `json:"pendingLines"`
+	ExpectedQty    float64 `json:"expectedQty"`
+	CountedQty     float64 `json:"countedQty"`
+	MovementQty    float64 `json:"movementQty"`
+	ShrinkageQty   float64 `json:"shrinkageQty"`
+	ShrinkageValue float64 `json:"shrinkageValue"`
+	SurplusQty     float64 `json:"surplusQty"`
+	SurplusValue   float64 `json:"surplusValue"`
+	NetValue       float64 `json:"netValue"`
+}
+
+type opnameCompleteResult struct {
+	Opname  models.StockOpname `json:"opname"`
+	Summary OpnameSummary      `json:"summary"`
+}
+
+// Complete posts the variances. For each counted line the expected quantity
+// at count time is the snapshot plus any movement on the batch between
+// snapshot_at and counted_at (sales during counting), so those sales don't
+// show up as shrinkage. A variance at or above the adjustment approval
+// threshold goes to a pending stock adjustment unless the caller may
+// approve it themselves.
+func (h *StockOpnamesHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in opnameCompleteInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	canApprove, err := hasPermission(ctx, h.deps.DB, PermStockAdjustApprove)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	performedBy := actorName(ctx, h.deps.DB)
+	var performedByID *uuid.UUID
+	if claims, ok := auth.ClaimsFrom(ctx); ok {
+		performedByID = &claims.UserID
+	}
+
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		op, err := lockOpname(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if op.Status != models.OpnameStatusCounting {
+			return errBadInput("opname belum dimulai atau sudah selesai")
+		}
+		var lines []models.StockOpnameLine
+		if err := tx.NewSelect().Model(&lines).
+			Where("opname_id = ?", id).Scan(ctx); err != nil {
+			return err
+		}
+		ref := models.StockMovementReference{Kind: models.MovementRefOpname, ID: op.ID.String(), Code: op.Code}
+		now := time.Now()
+		for i := range lines {
+			l := &lines[i]
+			if l.CountedQty == nil {
+				if in.SkipUncounted {
+					continue
+				}
+				return errBadInput("masih ada baris yang belum dihitung")
+			}
+			countedAt := now
+			if l.CountedAt != nil {
+				countedAt = *l.CountedAt
+			}
+			movement := 0.0
+			if l.BatchID != nil && op.SnapshotAt != nil {
+				if err := tx.NewSelect().Table("stock_movements").
+					ColumnExpr("COALESCE(SUM(qty_delta), 0)").
+					Where("batch_id = ?", *l.BatchID).
+					Where("happened_at > ? AND happened_at <= ?", *op.SnapshotAt, countedAt).
+					Where("NOT (reference->>'kind' = ? AND reference->>'id' = ?)",
+						models.MovementRefOpname, op.ID.String()).
+					Scan(ctx, &movement); err != nil {
+					return err
+				}
+			}
+			variance := *l.CountedQty - (l.ExpectedQty + movement)
+			posted := variance
+			a, err := opnameVarianceAdjustment(ctx, tx, op, l, variance)
+			if err != nil {
+				return err
+			}
+			if a != nil && adjustmentNeedsApproval(settings, a) && !canApprove {
+				a.RequestedBy, a.RequestedByID = performedBy, performedByID
+				if err := insertPendingAdjustment(ctx, tx, a); err != nil {
+					return err
+				}
+				l.AdjustmentID = &a.ID
+			} else if posted, err = postOpnameVariance(ctx, tx, op, l, variance, ref, performedBy); err != nil {
+				return err
+			}
+			value := math.Round(posted*l.UnitCost*100) / 100
+			if _, err := tx.NewUpdate().Table("stock_opname_lines").
+				Where("id = ?", l.ID).
+				Set("movement_qty = ?", movement).
+				Set("variance_qty = ?", posted).
+				Set("variance_value = ?", value).
+				Set("adjustment_id = ?", l.AdjustmentID).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		_, err = tx.NewUpdate().Table("stock_opnames").Where("id = ?", id).
+			Set("status = ?", models.OpnameStatusCompleted).
+			Set("completed_at = ?", now).
+			Set("updated_at = current_timestamp").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	op, err := loadOpname(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, opnameCompleteResult{Opname: *op, Summary: summarizeOpname(op)})
+}
+
+// Cancel abandons a draft or an opname mid-count. Nothing was posted yet,
+// so there is no stock to unwind.
+func (h *StockOpnamesHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		op, err := lockOpname(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if op.Status != models.OpnameStatusDraft && op.Status != models.OpnameStatusCounting {
+			return errBadInput("opname sudah selesai atau dibatalkan")
+		}
+		_, err = tx.NewUpdate().Table("stock_opnames").Where("id = ?", id).
+			Set("status = ?", models.OpnameStatusCancelled).
+			Set("completed_at = ?", time.Now()).
+			Set("updated_at = current_timestamp").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	h.respondOpname(w, r, id, http.StatusOK)
+}
+
+// Summary returns the shrinkage summary without mutating anything.
+func (h *StockOpnamesHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	op, err := loadOpname(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, summarizeOpname(op))
+}
+
+// opnameVarianceAdjustment is the stock adjustment one line's variance
+// amounts to, valued at the line's unit cost, so it can be checked against
+// the approval rules. nil when there is no variance.
+func opnameVarianceAdjustment(
+	ctx context.Context, tx bun.Tx,
+	op *models.StockOpname, l *models.StockOpnameLine, variance float64,
+) (*models.StockAdjustment, error) {
+	if math.Abs(variance) < 1e-9 {
+		return nil, nil
+	}
+	a := &models.StockAdjustment{
+		Kind:       models.AdjustmentKindAdjust,
+		ProductID:  l.ProductID,
+		VariantID:  l.VariantID,
+		QtyDelta:   variance,
+		UnitCost:   l.UnitCost,
+		Value:      roundMoney(math.Abs(variance) * l.UnitCost),
+		ReasonCode: models.AdjustmentReasonCorrection,
+		Notes:      opnameVarianceNote(op, l, variance),
+	}
+	if l.BatchID != nil {
+		var locID uuid.UUID
+		err := tx.NewSelect().Table("batches").Column("location_id").
+			Where("id = ?", *l.BatchID).Scan(ctx, &locID)
+		if err == nil {
+			a.BatchID, a.LocationID = l.BatchID, locID
+			return a, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	locID, err := opnameLineLocation(ctx, tx, op, l)
+	if err != nil {
+		return nil, err
+	}
+	a.LocationID = locID
+	return a, nil
+}
+
+func opnameVarianceNote(op *models.StockOpname, l *models.StockOpnameLine, variance float64) string {
+	note := fmt.Sprintf("Surplus dari opname %s", op.Code)
+	if variance < 0 {
+		note = fmt.Sprintf("Shrinkage dari opname %s", op.Code)
+	}
+	if l.Notes != "" {
+		note += " · " + l.Notes
+	}
+	return note
+}
+
+// opnameLineLocation is where a batch-less variance lands: the line's
+// location, else the opname's, else the default receipt location.
+func opnameLineLocation(
+	ctx context.Context, tx bun.Tx, op *models.StockOpname, l *models.StockOpnameLine,
+) (uuid.UUID, error) {
+	switch {
+	case l.LocationID != nil:
+		return *l.LocationID, nil
+	case op.LocationID != nil:
+		return *op.LocationID, nil
+	}
+	loc, err := defaultReceiptLocation(ctx, tx)
+	if err != nil {
+		return uuid.Nil, errBadInput("belum ada lokasi penerimaan default")
+	}
+	return loc, nil
+}
+
+// postOpnameVariance applies one line's variance and returns the quantity
+// actually posted (a shortfall larger than the batch holds is clamped).
+// Batch lines adjust their own batch; batch-less lines fall back to the
+// product-level adjustment rules.
+func postOpnameVariance(
+	ctx context.Context, tx bun.Tx,
+	op *models.StockOpname, l *models.StockOpnameLine,
+	variance float64, ref models.StockMovementReference, performedBy string,
+) (float64, error) {
+	if math.Abs(variance) < 1e-9 {
+		return 0, nil
+	}
+	adj := stockAdjustment{
+		ProductID:   l.ProductID,
+		VariantID:   l.VariantID,
+		Delta:       variance,
+		UnitCost:    l.UnitCost,
+		Reference:   ref,
+		Notes:       opnameVarianceNote(op, l, variance),
+		PerformedBy: performedBy,
+	}
+	if l.BatchID != nil {
+		var b models.Batch
+		err := tx.NewSelect().Model(&b).Where("id = ?", *l.BatchID).For("UPDATE").Scan(ctx)
+		if err == nil {
+			mv, err := adjustBatchQty(ctx, tx, &b, variance, adj)
+			if err != nil {
+				return 0, err
+			}
+			return mv.QtyDelta, nil
+		}
+		// Batch was deleted since the snapshot — fall through to a
+		// product-level adjustment.
+	}
+	loc, err := opnameLineLocation(ctx, tx, op, l)
+	if err != nil {
+		return 0, err
+	}
+	adj.LocationID = loc
+	mvs, err := applyStockAdjustment(ctx, tx, adj)
+	if err != nil {
+		return 0, err
+	}
+	posted := 0.0
+	for _, mv := range mvs {
+		posted += mv.QtyDelta
+	}
+	return posted, nil
+}
+
+func summarizeOpname(op *models.StockOpname) OpnameSummary {
+	var s OpnameSummary
+	for _, l := range op.Lines {
+		s.ExpectedQty += l.ExpectedQty
+		if l.CountedQty == nil {
+			s.UncountedLines++
+			continue
+		}
+		s.CountedLines++
+		s.CountedQty += *l.CountedQty
+		s.MovementQty += l.MovementQty
+		v := *l.CountedQty - l.ExpectedQty - l.MovementQty
+		if l.VarianceQty != nil {
+			v = *l.VarianceQty
+			switch {
+			case l.AdjustmentID != nil:
+				s.PendingLines++
+			case v != 0:
+				s.AdjustedLines++
+			}
+		}
+		if v < 0 {
+			s.ShrinkageQty += -v
+			s.ShrinkageValue += -v * l.UnitCost
+		} else {
+			s.SurplusQty += v
+			s.SurplusValue += v * l.UnitCost
+		}
+	}
+	s.ShrinkageValue = math.Round(s.ShrinkageValue*100) / 100
+	s.SurplusValue = math.Round(s.SurplusValue*100) / 100
+	s.NetValue = s.SurplusValue - s.ShrinkageValue
+	return s
+}
+
+func lockOpname(ctx context.Context, tx bun.Tx, id uuid.UUID) (*models.StockOpname, error) {
+	var op models.StockOpname
+	if err := tx.NewSelect().Model(&op).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+		return nil, errNotFound
+	}
+	return &op, nil
+}
+
+func loadOpname(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.StockOpname, error) {
+	var op models.StockOpname
+	if err := db.NewSelect().
+		Model(&op).
+		Relation("Lines").
+		Where("so.id = ?", id).
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	if op.Lines == nil {
+		op.Lines = []models.StockOpnameLine{}
+	}
+	return &op, nil
+}
+
+func (h *StockOpnamesHandler) respondOpname(w http.ResponseWriter, r *http.Request, id uuid.UUID, status int) {
+	op, err := loadOpname(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, status, op)
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type StockReservationsHandler struct {
+	deps Deps
+}
+
+func NewStockReservationsHandler(deps Deps) *StockReservationsHandler {
+	return &StockReservationsHandler{deps: deps}
+}
+
+// reservedQtyExpr sums live reservations on the batch aliased `bt`.
+const reservedQtyExpr = `COALESCE((SELECT SUM(sr.qty) FROM stock_reservations AS sr
+	WHERE sr.batch_id = bt.id AND sr.expires_at > now()), 0) AS qty_reserved`
+
+type reservationLineInput struct {
+	ProductID string  `json:"productId"`
+	VariantID *string `json:"variantId,omitempty"`
+	// Base units (line quantity × unit factor).
+	Qty float64 `json:"qty"`
+}
+
+type reservationReplaceInput struct {
+	Lines []reservationLineInput `json:"lines"`
+	// Optional TTL override; defaults to inventory.reservationTtlMinutes.
+	TTLMinutes *int `json:"ttlMinutes,omitempty"`
+}
+
+func parseHolder(r *http.Request) (kind, id string, ok bool) {
+	kind = chi.URLParam(r, "holderKind")
+	id = strings.TrimSpace(chi.URLParam(r, "holderId"))
+	if kind != models.ReservationHolderCart && kind != models.ReservationHolderOrder {
+		return "", "", false
+	}
+	return kind, id, id != ""
+}
+
+func (h *StockReservationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	kind, id, ok := parseHolder(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "holder tidak valid")
+		return
+	}
+	items := []models.StockReservation{}
+	if err := h.deps.DB.NewSelect().Model(&items).
+		Where("holder_kind = ?", kind).
+		Where("holder_id = ?", id).
+		Where("expires_at > now()").
+		Order("created_at ASC").Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Replace sets the holder's reservations to exactly the given lines: the
+// old rows are dropped and each line is re-allocated against batches in
+// allocation-strategy order, skipping quantity other holders already hold.
+// Every call also renews the TTL, so the POS re-posts on each cart edit.
+// A line that can't be fully covered fails the whole request.
+func (h *StockReservationsHandler) Replace(w http.ResponseWriter, r *http.Request) {
+	kind, holderID, ok := parseHolder(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "holder tidak valid")
+		return
+	}
+	var in reservationReplaceInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if kind == models.ReservationHolderOrder {
+		// A saved order has drawn its stock already; holding it again
+		// under the order would block the same units twice.
+		drawn, err := h.deps.DB.NewSelect().Table("orders").
+			Where("id::text = ?", holderID).
+			Where("status <> ?", models.OrderStatusCancelled).
+			Exists(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if drawn {
+			writeError(w, http.StatusBadRequest, "stok pesanan ini sudah dipotong saat disimpan")
+			return
+		}
+	}
+	storeID, err := requireStore(r.Context())
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	settings, err := loadServerSettings(r.Context(), h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ttl := settings.Inventory.ReservationTTLMinutes
+	if in.TTLMinutes != nil {
+		ttl = *in.TTLMinutes
+	}
+	if ttl <= 0 {
+		writeError(w, http.StatusBadRequest, "ttlMinutes harus lebih dari 0")
+		return
+	}
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Minute)
+	createdBy := actorName(r.Context(), h.deps.DB)
+
+	items := []models.StockReservation{}
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := releaseReservations(ctx, tx, kind, holderID); err != nil {
+			return err
+		}
+		// Merge duplicate product/variant lines (same item in two cart rows).
+		type subject struct {
+			productID uuid.UUID
+			variantID *uuid.UUID
+			qty       float64
+		}
+		var order []string
+		merged := map[string]*subject{}
+		for _, l := range in.Lines {
+			pid, err := uuid.Parse(l.ProductID)
+			if err != nil {
+				return errBadInput("productId tidak valid")
+			}
+			if l.Qty < 0 {
+				return errBadInput("qty tidak boleh negatif")
+			}
+			vid := optUUID(l.VariantID)
+			k := subjectKey(pid, vid)
+			if merged[k] == nil {
+				merged[k] = &subject{productID: pid, variantID: vid}
+				order = append(order, k)
+			}
+			merged[k].qty += l.Qty
+		}
+		// Stable key order so two holders reserving the same products lock
+		// them in the same sequence.
+		sort.Strings(order)
+		for _, k := range order {
+			s := merged[k]
+			if s.qty <= 0 {
+				continue
+			}
+			rows, err := reserveBatches(ctx, tx, storeID, s.productID, s.variantID, s.qty,
+				settings.Inventory.AllocationStrategy)
+			if err != nil {
+				return err
+			}
+			for i := range rows {
+				rows[i].HolderKind = kind
+				rows[i].HolderID = holderID
+				rows[i].ExpiresAt = expiresAt
+				rows[i].CreatedBy = createdBy
+			}
+			items = append(items, rows...)
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&items).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Release drops every reservation of the holder (cart cleared / closed).
+func (h *StockReservationsHandler) Release(w http.ResponseWriter, r *http.Request) {
+	kind, id, ok := parseHolder(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "holder tidak valid")
+		return
+	}
+	if err := releaseReservations(r.Context(), h.deps.DB, kind, id); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reserveBatches carves qty out of the product's sellable batches in the
+// store that isn't already held by someone else. Returned rows only carry
+// product/variant/batch/qty; the caller stamps the holder.
+func reserveBatches(
+	ctx context.Context, tx bun.Tx, storeID uuid.UUID,
+	productID uuid.UUID, variantID *uuid.UUID, qty float64, strategy string,
+) ([]models.StockReservation, error) {
+	batches, heldBy, err := lockSellableBatches(ctx, tx, storeID, productID, variantID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, errBadInput("stok tidak tersedia")
+	}
+
+	var out []models.StockReservation
+	need := qty
+	available := 0.0
+	for _, b := range batches {
+		free := b.QtyRemaining - heldBy[b.ID]
+		if free <= 1e-9 {
+			continue
+		}
+		available += free
+		if need <= 1e-9 {
+			continue
+		}
+		take := math.Min(need, free)
+		out = append(out, models.StockReservation{
+			ProductID: b.ProductID,
+			VariantID: b.VariantID,
+			BatchID:   b.ID,
+			Qty:       take,
+		})
+		need -= take
+	}
+	if need > 1e-9 {
+		var name string
+		_ = tx.NewSelect().Table("products").Column("name").
+			Where("id = ?", productID).Scan(ctx, &name)
+		return nil, errBadInput(fmt.Sprintf("stok %s tidak cukup: tersedia %.4g, diminta %.4g",
+			name, available, qty))
+	}
+	return out, nil
+}
+
+// lockSellableBatches locks the active batches of (product, variant?) at
+// the store's locations and returns them in allocation-strategy order, with
+// the quantity live reservations hold on each. Rows are locked in id order first so carts and
+// sales touching the same batches always queue the same way instead of
+// deadlocking on differing pick orders.
+func lockSellableBatches(
+	ctx context.Context, tx bun.Tx, storeID uuid.UUID,
+	productID uuid.UUID, variantID *uuid.UUID, strategy string,
+) ([]models.Batch, map[uuid.UUID]float64, error) {
+	var ids []uuid.UUID
+	lock := tx.NewSelect().Table("batches").Column("id").
+		Where("product_id = ?", productID).
+		Where("location_id IN (SELECT id FROM locations WHERE store_id = ?)", storeID).
+		Where("status = ?", models.BatchStatusActive).
+		Where("qty_remaining > 0").
+		Order("id ASC").
+		For("UPDATE")
+	if variantID != nil {
+		lock = lock.Where("variant_id = ?", *variantID)
+	} else {
+		lock = lock.Where("variant_id IS NULL")
+	}
+	if err := lock.Scan(ctx, &ids); err != nil {
+		return nil, nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	var batches []models.Batch
+	if err := tx.NewSelect().Model(&batches).
+		Where("id IN (?)", bun.In(ids)).
+		OrderExpr(batchPickOrder(strategy)).
+		Scan(ctx); err != nil {
+		return nil, nil, err
+	}
+	heldBy, err := reservedByBatch(ctx, tx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batches, heldBy, nil
+}
+
+// reservedByBatch sums the live reservations on each of the batches.
+func reservedByBatch(ctx context.Context, db bun.IDB, ids []uuid.UUID) (map[uuid.UUID]float64, error) {
+	heldBy := map[uuid.UUID]float64{}
+	if len(ids) == 0 {
+		return heldBy, nil
+	}
+	var held []struct {
+		BatchID uuid.UUID `bun:"batch_id"`
+		Qty     float64   `bun:"qty"`
+	}
+	if err := db.NewSelect().Table("stock_reservations").
+		ColumnExpr("batch_id, SUM(qty) AS qty").
+		Where("batch_id IN (?)", bun.In(ids)).
+		Where("expires_at > now()").
+		GroupExpr("batch_id").
+		Scan(ctx, &held); err != nil {
+		return nil, err
+	}
+	for _, h := range held {
+		heldBy[h.BatchID] = h.Qty
+	}
+	return heldBy, nil
+}
+
+func releaseReservations(ctx context.Context, db bun.IDB, kind, holderID string) error {
+	_, err := db.NewDelete().Model((*models.StockReservation)(nil)).
+		Where("holder_kind = ?", kind).
+		Where("holder_id = ?", holderID).
+		Exec(ctx)
+	return err
+}
+
+// ReleaseExpiredReservations deletes lapsed rows. Availability queries
+// already ignore them; this just keeps the table small.
+func ReleaseExpiredReservations(ctx context.Context, db bun.IDB) (int, error) {
+	res, err := db.NewDelete().Model((*models.StockReservation)(nil)).
+		Where("expires_at <= now()").
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+type stockAvailabilityRow struct {
+	ProductID uuid.UUID  `bun:"product_id" json:"productId"`
+	VariantID *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	OnHand    float64    `bun:"on_hand" json:"onHand"`
+	Reserved  float64    `bun:"reserved" json:"reserved"`
+	Available float64    `bun:"-" json:"available"`
+}
+
+// Availability returns on-hand, reserved and available-to-sell per
+// product/variant over active batches. Filters: productId, locationId.
+// excludeHolder=<kind>:<id> leaves that holder's own reservations out of
+// `reserved`, so a cart sees the stock it is already holding as available.
+func (h *StockReservationsHandler) Availability(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	heldFilter := ""
+	var args []any
+	if v := strings.TrimSpace(q.Get("excludeHolder")); v != "" {
+		kind, id, found := strings.Cut(v, ":")
+		if !found {
+			writeError(w, http.StatusBadRequest, "excludeHolder harus berformat kind:id")
+			return
+		}
+		heldFilter = " AND NOT (holder_kind = ? AND holder_id = ?)"
+		args = append(args, kind, id)
+	}
+
+	rows := []stockAvailabilityRow{}
+	sel := h.deps.DB.NewSelect().TableExpr("batches AS bt").
+		Join(`LEFT JOIN (
+			SELECT batch_id, SUM(qty) AS qty FROM stock_reservations
+			WHERE expires_at > now()`+heldFilter+`
+			GROUP BY batch_id) AS held ON held.batch_id = bt.id`, args...).
+		ColumnExpr("bt.product_id, bt.variant_id, SUM(bt.qty_remaining) AS on_hand").
+		ColumnExpr("COALESCE(SUM(held.qty), 0) AS reserved").
+		Where("bt.status = ?", models.BatchStatusActive).
+		Where("bt.qty_remaining > 0").
+		GroupExpr("bt.product_id, bt.variant_id")
+	sel = scopeStoreLocations(r.Context(), sel, "bt.location_id")
+	if v := strings.TrimSpace(q.Get("productId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("bt.product_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("locationId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("bt.location_id = ?", v)
+		}
+	}
+	if err := sel.Scan(r.Context(), &rows); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range rows {
+		rows[i].Available = math.Max(0, rows[i].OnHand-rows[i].Reserved)
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
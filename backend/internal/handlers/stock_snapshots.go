@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// ─── Daily stock snapshots ──────────────────────────────────────────────────
+// End-of-day quantity and value per batch, so "what was on hand on day X"
+// is a lookup instead of a replay of stock_movements. The nightly job
+// snapshots every finished day it hasn't seen yet; backfill and repair
+// rebuild past days by replaying movements back from today's quantities.
+
+// snapshotCatchUpDays caps how many missed days the job fills on its own;
+// older gaps go through backfill.
+const snapshotCatchUpDays = 31
+
+// snapshotMaxBackfillDays caps one backfill request.
+const snapshotMaxBackfillDays = 366
+
+type StockSnapshotsHandler struct {
+	deps Deps
+}
+
+func NewStockSnapshotsHandler(deps Deps) *StockSnapshotsHandler {
+	return &StockSnapshotsHandler{deps: deps}
+}
+
+// endOfDay is the last instant of a YYYY-MM-DD day in server local time.
+func endOfDay(day string) (time.Time, error) {
+	d, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return d.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// TakeStockSnapshot (re)builds the snapshot for a finished day. Quantities
+// are current qty_remaining minus every movement after the day ended, as in
+// the valuation report; unit cost is the batch's. Replaces whatever the day
+// had before, in one transaction.
+func TakeStockSnapshot(ctx context.Context, db *bun.DB, day, source string) (*models.StockSnapshotDay, error) {
+	end, err := endOfDay(day)
+	if err != nil {
+		return nil, errBadInput("tanggal harus YYYY-MM-DD")
+	}
+	if !end.Before(time.Now()) {
+		return nil, errBadInput("snapshot hanya bisa diambil untuk hari yang sudah lewat")
+	}
+
+	summary := &models.StockSnapshotDay{Date: day, Source: source, TakenAt: time.Now()}
+	err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*models.StockSnapshotDay)(nil)).
+			Where("snapshot_date = ?", day).Exec(ctx); err != nil {
+			return err
+		}
+		var rows []models.StockSnapshot
+		if err := tx.NewSelect().
+			TableExpr("batches AS bt").
+			Join("JOIN locations AS loc ON loc.id = bt.location_id").
+			ColumnExpr("? AS snapshot_date", day).
+			ColumnExpr("bt.id AS batch_id, bt.product_id, bt.variant_id, bt.location_id, loc.store_id, bt.ownership, bt.unit_cost").
+			ColumnExpr(`bt.qty_remaining - COALESCE((
+				SELECT SUM(sm.qty_delta) FROM stock_movements AS sm
+				WHERE sm.batch_id = bt.id AND sm.happened_at > ?), 0) AS qty`, end).
+			Where("bt.created_at <= ?", end).
+			Scan(ctx, &rows); err != nil {
+			return err
+		}
+		kept := rows[:0]
+		for _, row := range rows {
+			if row.Qty <= 1e-9 {
+				continue
+			}
+			row.Value = math.Round(row.Qty*row.UnitCost*100) / 100
+			if row.Ownership == models.BatchOwnershipConsignment {
+				summary.ConsignmentQty += row.Qty
+				summary.ConsignmentValue += row.Value
+			} else {
+				summary.OwnedQty += row.Qty
+				summary.OwnedValue += row.Value
+			}
+			kept = append(kept, row)
+		}
+		summary.Rows = len(kept)
+		if _, err := tx.NewInsert().Model(summary).Exec(ctx); err != nil {
+			return err
+		}
+		for start := 0; start < len(kept); start += 1000 {
+			chunk := kept[start:min(start+1000, len(kept))]
+			if _, err := tx.NewInsert().Model(&chunk).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// RunStockSnapshots is the nightly job: snapshot every finished day after
+// the latest one on file, up to yesterday (at most snapshotCatchUpDays).
+func RunStockSnapshots(ctx context.Context, db *bun.DB, now time.Time) (int, error) {
+	yesterday := now.AddDate(0, 0, -1)
+	start := yesterday
+	var last string
+	if err := db.NewSelect().Table("stock_snapshot_days").
+		ColumnExpr("COALESCE(MAX(snapshot_date), '')").
+		Scan(ctx, &last); err != nil {
+		return 0, err
+	}
+	if last != "" {
+		d, err := time.ParseInLocation("2006-01-02", last, time.Local)
+		if err != nil {
+			return 0, err
+		}
+		start = d.AddDate(0, 0, 1)
+		if floor := yesterday.AddDate(0, 0, -(snapshotCatchUpDays - 1)); start.Before(floor) {
+			start = floor
+		}
+	}
+	n := 0
+	for d := start; !d.After(yesterday); d = d.AddDate(0, 0, 1) {
+		if _, err := TakeStockSnapshot(ctx, db, d.Format("2006-01-02"), models.SnapshotSourceJob); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// hasStockSnapshot reports whether day has a complete snapshot.
+func hasStockSnapshot(ctx context.Context, db bun.IDB, day string) (bool, error) {
+	return db.NewSelect().Table("stock_snapshot_days").
+		Where("snapshot_date = ?", day).Exists(ctx)
+}
+
+// List returns the snapshotted days, newest first. Query: start, end.
+func (h *StockSnapshotsHandler) List(w http.ResponseWriter, r *http.Request) {
+	items := []models.StockSnapshotDay{}
+	q := h.deps.DB.NewSelect().Model(&items).Order("snapshot_date DESC")
+	if v := strings.TrimSpace(r.URL.Query().Get("start")); v != "" {
+		q = q.Where("snapshot_date >= ?", v)
+	}
+	if v := strings.TrimSpace(r.URL.Query().Get("end")); v != "" {
+		q = q.Where("snapshot_date <= ?", v)
+	}
+	if err := q.Limit(snapshotMaxBackfillDays).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+type snapshotBackfillInput struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Retake days that already have a snapshot.
+	Overwrite bool `json:"overwrite"`
+}
+
+// Backfill snapshots a range of past days (inclusive). Existing days are
+// skipped unless overwrite is set. Each day commits on its own, so a
+// failure part-way keeps the days already done.
+func (h *StockSnapshotsHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	var in snapshotBackfillInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	from, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(in.From), time.Local)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from harus YYYY-MM-DD")
+		return
+	}
+	to := time.Now().AddDate(0, 0, -1)
+	if v := strings.TrimSpace(in.To); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			writeError(w, http.StatusBadRequest, "to harus YYYY-MM-DD")
+			return
+		}
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "from harus sebelum to")
+		return
+	}
+	if to.Sub(from) > snapshotMaxBackfillDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, "rentang backfill maksimal 366 hari")
+		return
+	}
+
+	taken := []models.StockSnapshotDay{}
+	skipped := 0
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := d.Format("2006-01-02")
+		if !in.Overwrite {
+			exists, err := hasStockSnapshot(r.Context(), h.deps.DB, day)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if exists {
+				skipped++
+				continue
+			}
+		}
+		s, err := TakeStockSnapshot(r.Context(), h.deps.DB, day, models.SnapshotSourceBackfill)
+		if err != nil {
+			writeTxError(w, err)
+			return
+		}
+		taken = append(taken, *s)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"taken": taken, "skipped": skipped})
+}
+
+// Repair retakes one day's snapshot, e.g. after a back-dated correction.
+func (h *StockSnapshotsHandler) Repair(w http.ResponseWriter, r *http.Request) {
+	s, err := TakeStockSnapshot(r.Context(), h.deps.DB, chi.URLParam(r, "date"), models.SnapshotSourceRepair)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// ─── Reports reading from snapshots ─────────────────────────────────────────
+
+type stockHistoryPoint struct {
+	Date             string  `bun:"snapshot_date" json:"date"`
+	OwnedQty         float64 `bun:"owned_qty" json:"ownedQty"`
+	OwnedValue       float64 `bun:"owned_value" json:"ownedValue"`
+	ConsignmentQty   float64 `bun:"consignment_qty" json:"consignmentQty"`
+	ConsignmentValue float64 `bun:"consignment_value" json:"consignmentValue"`
+}
+
+type stockHistoryReport struct {
+	Start  string              `json:"start"`
+	End    string              `json:"end"`
+	Points []stockHistoryPoint `json:"points"`
+	// Days in the range without a snapshot; backfill them to fill the gaps.
+	MissingDays []string `json:"missingDays"`
+}
+
+// parseReportRange reads start/end (YYYY-MM-DD, inclusive), defaulting to
+// the 30 days ending yesterday.
+func parseReportRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	end := time.Now().AddDate(0, 0, -1)
+	if v := strings.TrimSpace(q.Get("end")); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errBadInput("end harus YYYY-MM-DD")
+		}
+		end = d
+	}
+	start := end.AddDate(0, 0, -29)
+	if v := strings.TrimSpace(q.Get("start")); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errBadInput("start harus YYYY-MM-DD")
+		}
+		start = d
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errBadInput("start harus sebelum end")
+	}
+	if end.Sub(start) > snapshotMaxBackfillDays*24*time.Hour {
+		return time.Time{}, time.Time{}, errBadInput("rentang maksimal 366 hari")
+	}
+	return start, end, nil
+}
+
+// StockHistory is the daily on-hand series for charts, read from the
+// snapshots. Query: start, end, productId, variantId, locationId.
+func (h *ReportsHandler) StockHistory(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseReportRange(r)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	qs := r.URL.Query()
+	q := h.deps.DB.NewSelect().
+		TableExpr("stock_snapshots AS ss").
+		ColumnExpr("ss.snapshot_date").
+		ColumnExpr("COALESCE(SUM(ss.qty) FILTER (WHERE ss.ownership <> ?), 0) AS owned_qty", models.BatchOwnershipConsignment).
+		ColumnExpr("COALESCE(SUM(ss.value) FILTER (WHERE ss.ownership <> ?), 0) AS owned_value", models.BatchOwnershipConsignment).
+		ColumnExpr("COALESCE(SUM(ss.qty) FILTER (WHERE ss.ownership = ?), 0) AS consignment_qty", models.BatchOwnershipConsignment).
+		ColumnExpr("COALESCE(SUM(ss.value) FILTER (WHERE ss.ownership = ?), 0) AS consignment_value", models.BatchOwnershipConsignment).
+		Where("ss.snapshot_date BETWEEN ? AND ?", start.Format("2006-01-02"), end.Format("2006-01-02")).
+		GroupExpr("ss.snapshot_date")
+	for param, col := range map[string]string{
+		"productId":  "ss.product_id",
+		"variantId":  "ss.variant_id",
+		"locationId": "ss.location_id",
+	} {
+		if v := strings.TrimSpace(qs.Get(param)); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, param+" tidak valid")
+				return
+			}
+			q = q.Where(col+" = ?", id)
+		}
+	}
+	q = scopeStore(r.Context(), q, "ss.store_id")
+	var points []stockHistoryPoint
+	if err := q.Scan(r.Context(), &points); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	taken, err := snapshotDaysIn(r.Context(), h.deps.DB, start, end)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// One point per snapshotted day, zero when nothing matched that day.
+	byDay := make(map[string]stockHistoryPoint, len(points))
+	for _, p := range points {
+		byDay[p.Date] = p
+	}
+	report := stockHistoryReport{
+		Start: start.Format("2006-01-02"), End: end.Format("2006-01-02"),
+		Points: []stockHistoryPoint{}, MissingDays: []string{},
+	}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		day := d.Format("2006-01-02")
+		if !taken[day] {
+			report.MissingDays = append(report.MissingDays, day)
+			continue
+		}
+		p := byDay[day]
+		p.Date = day
+		report.Points = append(report.Points, p)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func snapshotDaysIn(ctx context.Context, db bun.IDB, start, end time.Time) (map[string]bool, error) {
+	var days []string
+	if err := db.NewSelect().Table("stock_snapshot_days").Column("snapshot_date").
+		Where("snapshot_date BETWEEN ? AND ?", start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Scan(ctx, &days); err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(days))
+	for _, d := range days {
+		out[d] = true
+	}
+	return out, nil
+}
+
+type turnoverRow struct {
+	Key           string  `bun:"key" json:"key"`
+	Label         string  `bun:"label" json:"label"`
+	QtySold       float64 `bun:"qty_sold" json:"qtySold"`
+	COGS          float64 `bun:"cogs" json:"cogs"`
+	AvgQty        float64 `bun:"-" json:"avgQty"`
+	AvgValue      float64 `bun:"-" json:"avgValue"`
+	Turnover      float64 `bun:"-" json:"turnover"`
+	DaysOnHand    float64 `bun:"-" json:"daysOnHand"`
+	snapshotQty   float64
+	snapshotValue float64
+}
+
+type turnoverReport struct {
+	Start        string        `json:"start"`
+	End          string        `json:"end"`
+	GroupBy      string        `json:"groupBy"`
+	SnapshotDays int           `json:"snapshotDays"`
+	MissingDays  []string      `json:"missingDays"`
+	Rows         []turnoverRow `json:"rows"`
+	Totals       turnoverRow   `json:"totals"`
+}
+
+// StockTurnover compares cost of goods sold over a period with the average
+// owned inventory from the daily snapshots. Turnover = COGS / average value;
+// days on hand = days in period / turnover. Consignment stock is left out on
+// both sides. Query: start, end, groupBy=product|category, format=csv.
+func (h *ReportsHandler) StockTurnover(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start, end, err := parseReportRange(r)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	groupBy := strings.TrimSpace(r.URL.Query().Get("groupBy"))
+	if groupBy == "" {
+		groupBy = "product"
+	}
+	var keyExpr, labelExpr string
+	switch groupBy {
+	case "product":
+		keyExpr, labelExpr = "p.id::text", "p.name"
+	case "category":
+		keyExpr, labelExpr = "COALESCE(p.category_id::text, '')", "COALESCE(c.name, 'Tanpa kategori')"
+	default:
+		writeError(w, http.StatusBadRequest, "groupBy harus product atau category")
+		return
+	}
+	startDay, endDay := start.Format("2006-01-02"), end.Format("2006-01-02")
+	taken, err := snapshotDaysIn(ctx, h.deps.DB, start, end)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Sales net of cancellations, at the cost logged on the movement.
+	var sold []turnoverRow
+	sq := h.deps.DB.NewSelect().
+		TableExpr("stock_movements AS sm").
+		Join("JOIN batches AS bt ON bt.id = sm.batch_id").
+		Join("JOIN products AS p ON p.id = sm.product_id").
+		Join("LEFT JOIN categories AS c ON c.id = p.category_id").
+		ColumnExpr(keyExpr+" AS key, "+labelExpr+" AS label").
+		ColumnExpr("-SUM(sm.qty_delta) AS qty_sold").
+		ColumnExpr("-SUM(sm.qty_delta * COALESCE(sm.unit_cost, bt.unit_cost)) AS cogs").
+		Where("sm.kind IN (?)", bun.In([]models.StockMovementKind{models.MovementKindSale, models.MovementKindSaleCancel})).
+		Where("bt.ownership <> ?", models.BatchOwnershipConsignment).
+		Where("sm.happened_at >= ? AND sm.happened_at < ?", start, end.AddDate(0, 0, 1)).
+		GroupExpr("1, 2")
+	sq = scopeStoreLocations(ctx, sq, "sm.location_id")
+	if err := sq.Scan(ctx, &sold); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var held []struct {
+		Key   string  `bun:"key"`
+		Label string  `bun:"label"`
+		Qty   float64 `bun:"qty"`
+		Value float64 `bun:"value"`
+	}
+	hq := h.deps.DB.NewSelect().
+		TableExpr("stock_snapshots AS ss").
+		Join("JOIN products AS p ON p.id = ss.product_id").
+		Join("LEFT JOIN categories AS c ON c.id = p.category_id").
+		ColumnExpr(keyExpr+" AS key, "+labelExpr+" AS label").
+		ColumnExpr("SUM(ss.qty) AS qty, SUM(ss.value) AS value").
+		Where("ss.ownership <> ?", models.BatchOwnershipConsignment).
+		Where("ss.snapshot_date BETWEEN ? AND ?", startDay, endDay).
+		GroupExpr("1, 2")
+	hq = scopeStore(ctx, hq, "ss.store_id")
+	if err := hq.Scan(ctx, &held); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	report := turnoverReport{
+		Start: startDay, End: endDay, GroupBy: groupBy,
+		SnapshotDays: len(taken), MissingDays: []string{},
+		Rows: []turnoverRow{}, Totals: turnoverRow{Key: "total", Label: "Total"},
+	}
+	periodDays := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		periodDays++
+		if day := d.Format("2006-01-02"); !taken[day] {
+			report.MissingDays = append(report.MissingDays, day)
+		}
+	}
+	rows := map[string]*turnoverRow{}
+	get := func(key, label string) *turnoverRow {
+		if row := rows[key]; row != nil {
+			return row
+		}
+		row := &turnoverRow{Key: key, Label: label}
+		rows[key] = row
+		return row
+	}
+	for _, s := range sold {
+		row := get(s.Key, s.Label)
+		row.QtySold, row.COGS = s.QtySold, s.COGS
+	}
+	for _, s := range held {
+		row := get(s.Key, s.Label)
+		row.snapshotQty, row.snapshotValue = s.Qty, s.Value
+	}
+	finish := func(row *turnoverRow) {
+		// Averages are over the days that were snapshotted.
+		if report.SnapshotDays > 0 {
+			row.AvgQty = row.snapshotQty / float64(report.SnapshotDays)
+			row.AvgValue = row.snapshotValue / float64(report.SnapshotDays)
+		}
+		if row.AvgValue > 0 {
+			row.Turnover = math.Round(row.COGS/row.AvgValue*100) / 100
+		}
+		if row.Turnover > 0 {
+			row.DaysOnHand = math.Round(float64(periodDays)/row.Turnover*10) / 10
+		}
+	}
+	t := &report.Totals
+	for _, row := range rows {
+		finish(row)
+		report.Rows = append(report.Rows, *row)
+		t.QtySold += row.QtySold
+		t.COGS += row.COGS
+		t.snapshotQty += row.snapshotQty
+		t.snapshotValue += row.snapshotValue
+	}
+	finish(t)
+	sortTurnoverRows(report.Rows)
+
+	if r.URL.Query().Get("format") == "csv" {
+		out := make([][]string, 0, len(report.Rows)+1)
+		for _, row := range append(report.Rows, report.Totals) {
+			out = append(out, []string{
+				row.Label, formatDecimal(row.QtySold), formatDecimal(row.COGS),
+				formatDecimal(row.AvgQty), formatDecimal(row.AvgValue),
+				formatDecimal(row.Turnover), formatDecimal(row.DaysOnHand),
+			})
+		}
+		writeCSV(w, "perputaran-stok-"+startDay+"-"+endDay+".csv",
+			[]string{groupLabel(groupBy), "Qty terjual", "HPP", "Rata-rata qty", "Rata-rata nilai", "Perputaran", "Hari persediaan"},
+			out)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// sortTurnoverRows puts the slowest movers (most days on hand) first; rows
+// that didn't sell at all lead, by value held.
+func sortTurnoverRows(rows []turnoverRow) {
+	less := func(a, b turnoverRow) bool {
+		if (a.Turnover == 0) != (b.Turnover == 0) {
+			return a.Turnover == 0
+		}
+		if a.Turnover == 0 {
+			return a.AvgValue > b.AvgValue
+		}
+		return a.DaysOnHand > b.DaysOnHand
+	}
+	for i := 1; i < len(rows); i++ {
+		for j := i; j > 0 && less(rows[j], rows[j-1]); j-- {
+			rows[j], rows[j-1] = rows[j-1], rows[j]
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/auth"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// StoreHeader carries the caller's active store on every authed request.
+// "all" asks for the consolidated (every store) view, HQ roles only.
+const StoreHeader = "X-Store-ID"
+
+// ConsolidatedRoles may read across stores and act in any store without
+// being assigned to it.
+var ConsolidatedRoles = []string{"Admin", "HQ"}
+
+type storeCtxKey struct{}
+
+// storeScope is the resolved active store. All means consolidated: list
+// endpoints don't filter and transactional writes are refused.
+type storeScope struct {
+	StoreID uuid.UUID
+	All     bool
+}
+
+// StoreScope resolves the active store after RequireAuth: the X-Store-ID
+// header when present (checked against the caller's assignments), else the
+// caller's default store. HQ callers without a store fall back to the
+// consolidated view; anyone else without an assignment gets an empty scope
+// that matches no rows.
+func StoreScope(deps Deps) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := auth.ClaimsFrom(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			scope, status, msg := resolveStoreScope(r.Context(), deps.DB, claims, strings.TrimSpace(r.Header.Get(StoreHeader)))
+			if status != 0 {
+				writeError(w, status, msg)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), storeCtxKey{}, scope)))
+		})
+	}
+}
+
+func resolveStoreScope(ctx context.Context, db *bun.DB, claims *auth.Claims, want string) (storeScope, int, string) {
+	hq := canConsolidate(claims)
+	if strings.EqualFold(want, "all") {
+		if !hq {
+			return storeScope{}, http.StatusForbidden, "hanya HQ yang bisa melihat semua toko"
+		}
+		return storeScope{All: true}, 0, ""
+	}
+	if want != "" {
+		id, err := uuid.Parse(want)
+		if err != nil {
+			return storeScope{}, http.StatusBadRequest, "X-Store-ID tidak valid"
+		}
+		q := db.NewSelect().Table("stores").Where("id = ?", id)
+		if !hq {
+			q = db.NewSelect().Table("user_stores").
+				Where("user_id = ? AND store_id = ?", claims.UserID, id)
+		}
+		exists, err := q.Exists(ctx)
+		if err != nil {
+			return storeScope{}, http.StatusInternalServerError, err.Error()
+		}
+		if !exists {
+			return storeScope{}, http.StatusForbidden, "Anda tidak ditugaskan di toko ini"
+		}
+		return storeScope{StoreID: id}, 0, ""
+	}
+	var id uuid.UUID
+	err := db.NewSelect().Table("user_stores").Column("store_id").
+		Where("user_id = ?", claims.UserID).
+		OrderExpr("is_default DESC").
+		Limit(1).Scan(ctx, &id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storeScope{All: hq}, 0, ""
+	}
+	if err != nil {
+		return storeScope{}, http.StatusInternalServerError, err.Error()
+	}
+	return storeScope{StoreID: id}, 0, ""
+}
+
+func canConsolidate(claims *auth.Claims) bool {
+	for _, role := range ConsolidatedRoles {
+		if claims.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeStore returns the request's store. ok is false when the request is
+// consolidated or didn't pass through StoreScope (background jobs).
+func activeStore(ctx context.Context) (uuid.UUID, bool) {
+	s, ok := ctx.Value(storeCtxKey{}).(storeScope)
+	if !ok || s.All {
+		return uuid.Nil, false
+	}
+	return s.StoreID, true
+}
+
+// requireStore is activeStore for writes that must land in one store.
+func requireStore(ctx context.Context) (uuid.UUID, error) {
+	id, ok := activeStore(ctx)
+	if !ok || id == uuid.Nil {
+		return uuid.Nil, errBadInput("pilih toko aktif terlebih dahulu")
+	}
+	return id, nil
+}
+
+// scopeStore narrows q to rows of the active store on column col.
+func scopeStore(ctx context.Context, q *bun.SelectQuery, col string) *bun.SelectQuery {
+	if id, ok := activeStore(ctx); ok {
+		return q.Where(col+" = ?", id)
+	}
+	return q
+}
+
+// scopeStoreLocations narrows q to rows whose location (column col) belongs
+// to the active store — how batches, movements and other stock rows are
+// scoped.
+func scopeStoreLocations(ctx context.Context, q *bun.SelectQuery, col string) *bun.SelectQuery {
+	if id, ok := activeStore(ctx); ok {
+		return q.Where(col+" IN (SELECT id FROM locations WHERE store_id = ?)", id)
+	}
+	return q
+}
+
+// sameStoreLocationsSQL matches a location column against every location
+// in the store of location ?. Stock a document drains on approval follows
+// the store of the location it names rather than the approver's.
+const sameStoreLocationsSQL = " IN (SELECT id FROM locations WHERE store_id = (SELECT store_id FROM locations WHERE id = ?))"
+
+// checkStoreLocation refuses a location outside the active store;
+// consolidated requests accept any existing location.
+func checkStoreLocation(ctx context.Context, db bun.IDB, id uuid.UUID) error {
+	q := db.NewSelect().Table("locations").Where("id = ?", id)
+	exists, err := scopeStore(ctx, q, "store_id").Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errBadInput("lokasi tidak ditemukan di toko aktif")
+	}
+	return nil
+}
+
+type StoresHandler struct {
+	deps Deps
+}
+
+func NewStoresHandler(deps Deps) *StoresHandler {
+	return &StoresHandler{deps: deps}
+}
+
+type storeInput struct {
+	Code               string  `json:"code"`
+	Name               string  `json:"name"`
+	Address            string  `json:"address"`
+	Phone              string  `json:"phone"`
+	Status             string  `json:"status"`
+	DefaultPricelistID *string `json:"defaultPricelistId"`
+}
+
+// myStore is one entry of the store picker.
+type myStore struct {
+	models.Store
+	IsDefault bool `json:"isDefault"`
+}
+
+type myStoresResponse struct {
+	Stores         []myStore  `json:"stores"`
+	ActiveStoreID  *uuid.UUID `json:"activeStoreId,omitempty"`
+	CanConsolidate bool       `json:"canConsolidate"`
+}
+
+func (h *StoresHandler) List(w http.ResponseWriter, r *http.Request) {
+	items := []models.Store{}
+	if err := h.deps.DB.NewSelect().Model(&items).Order("name ASC").Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Mine lists the stores the caller can switch to (every active store for HQ
+// roles) plus the store this request resolved to.
+func (h *StoresHandler) Mine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "no claims")
+		return
+	}
+	resp := myStoresResponse{Stores: []myStore{}, CanConsolidate: canConsolidate(claims)}
+	q := h.deps.DB.NewSelect().
+		TableExpr("stores AS st").
+		ColumnExpr("st.*").
+		ColumnExpr("COALESCE(us.is_default, false) AS is_default").
+		Join("LEFT JOIN user_stores AS us ON us.store_id = st.id AND us.user_id = ?", claims.UserID).
+		Where("st.status = ?", models.StoreStatusActive).
+		Order("st.name ASC")
+	if !resp.CanConsolidate {
+		q = q.Where("us.user_id IS NOT NULL")
+	}
+	if err := q.Scan(ctx, &resp.Stores); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if id, ok := activeStore(ctx); ok && id != uuid.Nil {
+		resp.ActiveStoreID = &id
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *StoresHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var s models.Store
+	if err := h.deps.DB.NewSelect().Model(&s).Where("id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+func (h *StoresHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in storeInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := validateStoreInput(&in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	s := &models.Store{
+		Code:               strings.ToUpper(strings.TrimSpace(in.Code)),
+		Name:               strings.TrimSpace(in.Name),
+		Address:            strings.TrimSpace(in.Address),
+		Phone:              strings.TrimSpace(in.Phone),
+		Status:             storeStatusOrDefault(in.Status),
+		DefaultPricelistID: in.DefaultPricelistID,
+	}
+	if _, err := h.deps.DB.NewInsert().Model(s).Returning("*").Exec(r.Context()); err != nil {
+		if strings.Contains(err.Error(), "stores_code_key") {
+			writeError(w, http.StatusBadRequest, "kode toko sudah dipakai")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, s)
+}
+
+func (h *StoresHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in storeInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := validateStoreInput(&in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	res, err := h.deps.DB.NewUpdate().Table("stores").Where("id = ?", id).
+		Set("code = ?", strings.ToUpper(strings.TrimSpace(in.Code))).
+		Set("name = ?", strings.TrimSpace(in.Name)).
+		Set("address = ?", strings.TrimSpace(in.Address)).
+		Set("phone = ?", strings.TrimSpace(in.Phone)).
+		Set("status = ?", storeStatusOrDefault(in.Status)).
+		Set("default_pricelist_id = ?", in.DefaultPricelistID).
+		Set("updated_at = current_timestamp").
+		Exec(r.Context())
+	if err != nil {
+		if strings.Contains(err.Error(), "stores_code_key") {
+			writeError(w, http.StatusBadRequest, "kode toko sudah dipakai")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var s models.Store
+	if err := h.deps.DB.NewSelect().Model(&s).Where("id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+func (h *StoresHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var total int
+	if err := h.deps.DB.NewSelect().Table("stores").
+		ColumnExpr("count(*)").Scan(r.Context(), &total); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if total <= 1 {
+		writeError(w, http.StatusBadRequest, "minimal harus ada satu toko")
+		return
+	}
+	res, err := h.deps.DB.NewDelete().Model((*models.Store)(nil)).Where("id = ?", id).Exec(r.Context())
+	if err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			writeError(w, http.StatusBadRequest, "toko masih punya lokasi, kasir, shift atau pesanan; arsipkan saja")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateStoreInput(in *storeInput) string {
+	if strings.TrimSpace(in.Code) == "" {
+		return "kode toko wajib diisi"
+	}
+	if strings.TrimSpace(in.Name) == "" {
+		return "nama toko wajib diisi"
+	}
+	if in.DefaultPricelistID != nil && strings.TrimSpace(*in.DefaultPricelistID) == "" {
+		in.DefaultPricelistID = nil
+	}
+	return ""
+}
+
+func storeStatusOrDefault(s string) string {
+	if strings.TrimSpace(s) == models.StoreStatusArchived {
+		return models.StoreStatusArchived
+	}
+	return models.StoreStatusActive
+}
+
+// loadUserStores returns a user's store assignments and their default store.
+func loadUserStores(ctx context.Context, db bun.IDB, userID uuid.UUID) ([]uuid.UUID, *uuid.UUID, error) {
+	var rows []models.UserStore
+	if err := db.NewSelect().Model(&rows).
+		Where("user_id = ?", userID).
+		Scan(ctx); err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	var def *uuid.UUID
+	for _, row := range rows {
+		ids = append(ids, row.StoreID)
+		if row.IsDefault {
+			id := row.StoreID
+			def = &id
+		}
+	}
+	return ids, def, nil
+}
+
+// replaceUserStores rewrites a user's store assignments. The default must be
+// one of them; when it isn't given the first store becomes the default.
+func replaceUserStores(ctx context.Context, db *bun.DB, userID uuid.UUID, storeIDs []uuid.UUID, def *uuid.UUID) error {
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*models.UserStore)(nil)).
+			Where("user_id = ?", userID).Exec(ctx); err != nil {
+			return err
+		}
+		rows := make([]models.UserStore, 0, len(storeIDs))
+		seen := map[uuid.UUID]bool{}
+		for _, id := range storeIDs {
+			if id == uuid.Nil || seen[id] {
+				continue
+			}
+			seen[id] = true
+			rows = append(rows, models.UserStore{UserID: userID, StoreID: id})
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if def != nil && !seen[*def] {
+			return errBadInput("toko default harus salah satu toko yang ditugaskan")
+		}
+		for i := range rows {
+			rows[i].IsDefault = def == nil && i == 0 || def != nil && rows[i].StoreID == *def
+		}
+		_, err := tx.NewInsert().Model(&rows).Exec(ctx)
+		return err
+	})
+}
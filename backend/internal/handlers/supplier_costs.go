@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type SupplierCostsHandler struct {
+	deps Deps
+}
+
+func NewSupplierCostsHandler(deps Deps) *SupplierCostsHandler {
+	return &SupplierCostsHandler{deps: deps}
+}
+
+type supplierCostHistoryRow struct {
+	ID            uuid.UUID  `bun:"id" json:"id"`
+	ProductID     uuid.UUID  `bun:"product_id" json:"productId"`
+	ProductName   string     `bun:"product_name" json:"productName"`
+	SupplierID    uuid.UUID  `bun:"supplier_id" json:"supplierId"`
+	SupplierName  string     `bun:"supplier_name" json:"supplierName"`
+	UnitCost      float64    `bun:"unit_cost" json:"unitCost"`
+	PreviousCost  *float64   `bun:"previous_cost" json:"previousCost,omitempty"`
+	EffectiveFrom string     `bun:"effective_from" json:"effectiveFrom"`
+	Source        string     `bun:"source" json:"source"`
+	ImportID      *uuid.UUID `bun:"import_id" json:"importId,omitempty"`
+	ImportCode    string     `bun:"import_code" json:"importCode,omitempty"`
+	CreatedBy     string     `bun:"created_by" json:"createdBy"`
+	CreatedAt     time.Time  `bun:"created_at" json:"createdAt"`
+	// Rows dated after today have not reached product_suppliers yet.
+	Pending bool `bun:"-" json:"pending"`
+}
+
+func selectSupplierCostHistory(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("supplier_cost_history AS sch").
+		ColumnExpr("sch.id, sch.product_id, sch.supplier_id, sch.unit_cost, sch.previous_cost").
+		ColumnExpr("sch.effective_from, sch.source, sch.import_id, sch.created_by, sch.created_at").
+		ColumnExpr("p.name AS product_name, s.name AS supplier_name").
+		ColumnExpr("COALESCE(spi.code, '') AS import_code").
+		Join("JOIN products AS p ON p.id = sch.product_id").
+		Join("JOIN suppliers AS s ON s.id = sch.supplier_id").
+		Join("LEFT JOIN supplier_price_imports AS spi ON spi.id = sch.import_id")
+}
+
+// History lists cost changes for ?productId= and/or ?supplierId= (one is
+// required), newest effective date first. ?start= / ?end= bound the
+// effective date.
+func (h *SupplierCostsHandler) History(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	productID := strings.TrimSpace(q.Get("productId"))
+	supplierID := strings.TrimSpace(q.Get("supplierId"))
+	if productID == "" && supplierID == "" {
+		writeError(w, http.StatusBadRequest, "productId atau supplierId wajib diisi")
+		return
+	}
+	items := []supplierCostHistoryRow{}
+	sel := selectSupplierCostHistory(h.deps.DB).
+		OrderExpr("sch.effective_from DESC, sch.created_at DESC")
+	for _, f := range []struct{ value, col string }{
+		{productID, "sch.product_id"},
+		{supplierID, "sch.supplier_id"},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if _, err := uuid.Parse(f.value); err != nil {
+			writeError(w, http.StatusBadRequest, "id tidak valid")
+			return
+		}
+		sel = sel.Where(f.col+" = ?", f.value)
+	}
+	if v := strings.TrimSpace(q.Get("start")); v != "" {
+		sel = sel.Where("sch.effective_from >= ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("end")); v != "" {
+		sel = sel.Where("sch.effective_from <= ?", v)
+	}
+	if err := sel.Limit(1000).Scan(r.Context(), &items); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	for i := range items {
+		items[i].Pending = items[i].EffectiveFrom > today
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+type supplierCostOffer struct {
+	SupplierID   uuid.UUID `bun:"supplier_id" json:"supplierId"`
+	SupplierName string    `bun:"supplier_name" json:"supplierName"`
+	IsPrimary    bool      `bun:"is_primary" json:"isPrimary"`
+	SupplierSKU  string    `bun:"supplier_sku" json:"supplierSku"`
+	UnitCost     float64   `bun:"unit_cost" json:"unitCost"`
+	// The change that set UnitCost, if any is on record.
+	PreviousCost *float64 `bun:"previous_cost" json:"previousCost,omitempty"`
+	ChangedFrom  string   `bun:"changed_from" json:"changedFrom"`
+	ChangePct    float64  `bun:"-" json:"changePct"`
+	// Next scheduled change, from a future-dated price list.
+	NextCost float64 `bun:"next_cost" json:"nextCost,omitempty"`
+	NextFrom string  `bun:"next_from" json:"nextFrom,omitempty"`
+	// Latest owned batch actually received from this supplier.
+	LastReceivedCost float64 `bun:"last_received_cost" json:"lastReceivedCost"`
+	LastReceivedAt   string  `bun:"last_received_at" json:"lastReceivedAt"`
+	Cheapest         bool    `bun:"-" json:"cheapest"`
+}
+
+// Compare lists every supplier linked to ?productId= with its current
+// cost, the last change, any scheduled change and the last received
+// cost, cheapest first.
+func (h *SupplierCostsHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(strings.TrimSpace(r.URL.Query().Get("productId")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "productId wajib diisi")
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	items := []supplierCostOffer{}
+	if err := h.deps.DB.NewSelect().
+		TableExpr("product_suppliers AS ps").
+		Join("JOIN suppliers AS s ON s.id = ps.supplier_id").
+		ColumnExpr("ps.supplier_id, s.name AS supplier_name, ps.is_primary, ps.supplier_sku, ps.unit_cost").
+		ColumnExpr(`cur.previous_cost, COALESCE(cur.effective_from, '') AS changed_from`).
+		ColumnExpr(`COALESCE(nxt.unit_cost, 0) AS next_cost, COALESCE(nxt.effective_from, '') AS next_from`).
+		ColumnExpr(`COALESCE(rcv.unit_cost, 0) AS last_received_cost, COALESCE(rcv.received_at, '') AS last_received_at`).
+		Join(`LEFT JOIN LATERAL (
+			SELECT h.previous_cost, h.effective_from FROM supplier_cost_history AS h
+			WHERE h.product_id = ps.product_id AND h.supplier_id = ps.supplier_id AND h.effective_from <= ?
+			ORDER BY h.effective_from DESC, h.created_at DESC LIMIT 1) AS cur ON true`, today).
+		Join(`LEFT JOIN LATERAL (
+			SELECT h.unit_cost, h.effective_from FROM supplier_cost_history AS h
+			WHERE h.product_id = ps.product_id AND h.supplier_id = ps.supplier_id AND h.effective_from > ?
+			ORDER BY h.effective_from ASC, h.created_at DESC LIMIT 1) AS nxt ON true`, today).
+		Join(`LEFT JOIN LATERAL (
+			SELECT bt.unit_cost, bt.received_at FROM batches AS bt
+			WHERE bt.product_id = ps.product_id AND bt.supplier_id = ps.supplier_id AND bt.ownership = ?
+			ORDER BY bt.received_at DESC, bt.created_at DESC LIMIT 1) AS rcv ON true`, models.BatchOwnershipOwned).
+		Where("ps.product_id = ?", productID).
+		OrderExpr("ps.unit_cost = 0, ps.unit_cost ASC, s.name ASC").
+		Scan(r.Context(), &items); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// A zero cost means "not set", not free.
+	cheapest := 0.0
+	for i := range items {
+		if p := items[i].PreviousCost; p != nil && *p > 0 {
+			items[i].ChangePct = costChangePct(*p, items[i].UnitCost)
+		}
+		if c := items[i].UnitCost; c > 0 && (cheapest == 0 || c < cheapest) {
+			cheapest = c
+		}
+	}
+	for i := range items {
+		items[i].Cheapest = cheapest > 0 && math.Abs(items[i].UnitCost-cheapest) < 0.005
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+type supplierCostAlert struct {
+	supplierCostHistoryRow
+	ChangePct float64 `json:"changePct"`
+}
+
+type supplierCostAlerts struct {
+	ThresholdPct float64             `json:"thresholdPct"`
+	Since        string              `json:"since"`
+	Items        []supplierCostAlert `json:"items"`
+}
+
+// Alerts lists cost rises of at least the purchasing cost-rise threshold
+// (or ?pct=) that took or take effect from ?days= ago (default 30) on,
+// including scheduled ones. Biggest rise first. ?supplierId= narrows.
+func (h *SupplierCostsHandler) Alerts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	threshold := settings.Purchasing.CostRiseAlertPct
+	if v := strings.TrimSpace(q.Get("pct")); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "pct harus angka non-negatif")
+			return
+		}
+		threshold = n
+	}
+	days := 30
+	if v := strings.TrimSpace(q.Get("days")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 366 {
+			writeError(w, http.StatusBadRequest, "days harus 1–366")
+			return
+		}
+		days = n
+	}
+	since := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+
+	rows := []supplierCostHistoryRow{}
+	sel := selectSupplierCostHistory(h.deps.DB).
+		Where("sch.effective_from >= ?", since).
+		Where("sch.previous_cost > 0").
+		Where("sch.unit_cost > sch.previous_cost").
+		Where("(sch.unit_cost - sch.previous_cost) * 100 >= sch.previous_cost * ?", threshold)
+	if v := strings.TrimSpace(q.Get("supplierId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("sch.supplier_id = ?", v)
+		}
+	}
+	if err := sel.Limit(1000).Scan(ctx, &rows); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	out := supplierCostAlerts{ThresholdPct: threshold, Since: since, Items: []supplierCostAlert{}}
+	for _, row := range rows {
+		row.Pending = row.EffectiveFrom > today
+		out.Items = append(out.Items, supplierCostAlert{
+			supplierCostHistoryRow: row,
+			ChangePct:              costChangePct(*row.PreviousCost, row.UnitCost),
+		})
+	}
+	sort.SliceStable(out.Items, func(i, j int) bool {
+		return out.Items[i].ChangePct > out.Items[j].ChangePct
+	})
+	writeJSON(w, http.StatusOK, out)
+}
+
+func costChangePct(from, to float64) float64 {
+	if from <= 0 {
+		return 0
+	}
+	return math.Round((to-from)/from*10000) / 100
+}
+
+// recordSupplierCostChanges logs the product_suppliers costs that differ
+// from before, effective today. before maps supplier to its old cost; a
+// supplier missing from it is newly linked.
+func recordSupplierCostChanges(
+	ctx context.Context, tx bun.Tx, productID uuid.UUID,
+	before map[uuid.UUID]float64, after []models.ProductSupplier,
+) error {
+	today := time.Now().Format("2006-01-02")
+	actor := actorName(ctx, tx)
+	for _, s := range after {
+		change := models.SupplierCostChange{
+			ProductID:     productID,
+			SupplierID:    s.SupplierID,
+			UnitCost:      math.Round(s.UnitCost*100) / 100,
+			EffectiveFrom: today,
+			Source:        models.SupplierCostManual,
+			CreatedBy:     actor,
+		}
+		if old, ok := before[s.SupplierID]; ok {
+			if math.Abs(old-change.UnitCost) < 0.005 {
+				continue
+			}
+			change.PreviousCost = &old
+		}
+		if _, err := tx.NewInsert().Model(&change).Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySupplierCosts sets product_suppliers.unit_cost to the latest cost
+// in effect on day, for one supplier or (supplierID nil) all of them.
+func applySupplierCosts(ctx context.Context, db bun.IDB, day string, supplierID *uuid.UUID) (int, error) {
+	const latest = `(SELECT h.unit_cost FROM supplier_cost_history AS h
+		WHERE h.product_id = ps.product_id AND h.supplier_id = ps.supplier_id AND h.effective_from <= ?
+		ORDER BY h.effective_from DESC, h.created_at DESC LIMIT 1)`
+	q := db.NewUpdate().TableExpr("product_suppliers AS ps").
+		Set("unit_cost = "+latest, day).
+		Where(latest+" <> ps.unit_cost", day)
+	if supplierID != nil {
+		q = q.Where("ps.supplier_id = ?", *supplierID)
+	}
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// RunSupplierCosts is the apply-supplier-costs job: it brings
+// product_suppliers up to date with price list rows whose effective date
+// has arrived.
+func RunSupplierCosts(ctx context.Context, db *bun.DB, now time.Time) (int, error) {
+	return applySupplierCosts(ctx, db, now.Format("2006-01-02"), nil)
+}
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/sandisahdewo/pos/backend/internal/sheets"
+	"github.com/uptrace/bun"
+)
+
+const maxPriceListBytes = 10 << 20
+
+// priceListHeaders maps the column names suppliers commonly use (after
+// priceListKey) to the field they hold.
+var priceListHeaders = map[string]string{
+	"sku": "sku", "suppliersku": "sku", "kodepemasok": "sku", "kode": "sku",
+	"kodebarang": "sku", "itemcode": "sku", "code": "sku",
+	"barcode": "barcode", "ean": "barcode", "upc": "barcode", "kodebarcode": "barcode",
+	"cost": "cost", "unitcost": "cost", "harga": "cost", "hargabeli": "cost",
+	"price": "cost", "hargasatuan": "cost", "hpp": "cost",
+	"name": "name", "nama": "name", "namabarang": "name", "produk": "name",
+	"product": "name", "description": "name", "deskripsi": "name",
+}
+
+func priceListKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var thousandsOnly = regexp.MustCompile(`^\d{1,3}([.,]\d{3})+$`)
+
+// parsePriceListCost reads "12.500", "12,500.00", "Rp 12.500,50" and
+// plain numbers. With both separators present the last one is the
+// decimal point; a lone separator grouping digits in threes is read as
+// thousands.
+func parsePriceListCost(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "Rp"), "rp")
+	s = strings.NewReplacer(" ", "", "\u00a0", "").Replace(s)
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		if dot > comma {
+			s = strings.ReplaceAll(s, ",", "")
+		} else {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.ReplaceAll(s, ",", ".")
+		}
+	case thousandsOnly.MatchString(s):
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	default:
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+type priceListRow struct {
+	row                       int
+	sku, barcode, name, costS string
+}
+
+// readPriceList turns the sheet into rows, using the first row that names
+// a cost column and a SKU or barcode column as the header.
+func readPriceList(rows [][]string) ([]priceListRow, error) {
+	for h, header := range rows {
+		cols := map[string]int{}
+		for i, cell := range header {
+			if field, ok := priceListHeaders[priceListKey(cell)]; ok {
+				if _, seen := cols[field]; !seen {
+					cols[field] = i
+				}
+			}
+		}
+		_, hasCost := cols["cost"]
+		_, hasSKU := cols["sku"]
+		_, hasBarcode := cols["barcode"]
+		if !hasCost || (!hasSKU && !hasBarcode) {
+			continue
+		}
+		cell := func(r []string, field string) string {
+			i, ok := cols[field]
+			if !ok || i >= len(r) {
+				return ""
+			}
+			return strings.TrimSpace(r[i])
+		}
+		out := []priceListRow{}
+		for i, r := range rows[h+1:] {
+			row := priceListRow{
+				row:     h + i + 2,
+				sku:     cell(r, "sku"),
+				barcode: cell(r, "barcode"),
+				name:    cell(r, "name"),
+				costS:   cell(r, "cost"),
+			}
+			if row.sku == "" && row.barcode == "" && row.costS == "" {
+				continue
+			}
+			out = append(out, row)
+		}
+		return out, nil
+	}
+	return nil, errBadInput("kolom harga dan kode/barcode tidak ditemukan di baris judul")
+}
+
+// priceListMatch is a product the supplier supplies, found by a price
+// list row. Factor converts the listed cost to the product's base unit
+// when the row names a packaging barcode.
+type priceListMatch struct {
+	ProductID   uuid.UUID `bun:"product_id"`
+	ProductName string    `bun:"product_name"`
+	UnitCost    float64   `bun:"unit_cost"`
+	Key         string    `bun:"key"`
+	Factor      float64   `bun:"factor"`
+	Linked      bool      `bun:"linked"`
+}
+
+// matchPriceList looks up the supplier's products by supplier SKU and by
+// product, variant or packaging barcode.
+func matchPriceList(
+	ctx context.Context, db bun.IDB, supplierID uuid.UUID, rows []priceListRow,
+) (bySKU, byBarcode map[string]priceListMatch, err error) {
+	bySKU = map[string]priceListMatch{}
+	byBarcode = map[string]priceListMatch{}
+	skus, barcodes := []string{}, []string{}
+	for _, r := range rows {
+		if r.sku != "" {
+			skus = append(skus, strings.ToLower(r.sku))
+		}
+		if r.barcode != "" {
+			barcodes = append(barcodes, r.barcode)
+		}
+	}
+	if len(skus) > 0 {
+		var found []priceListMatch
+		if err := db.NewSelect().
+			TableExpr("product_suppliers AS ps").
+			Join("JOIN products AS p ON p.id = ps.product_id").
+			ColumnExpr("ps.product_id, p.name AS product_name, ps.unit_cost").
+			ColumnExpr("lower(ps.supplier_sku) AS key, 1 AS factor, TRUE AS linked").
+			Where("ps.supplier_id = ?", supplierID).
+			Where("lower(ps.supplier_sku) IN (?)", bun.In(skus)).
+			Scan(ctx, &found); err != nil {
+			return nil, nil, err
+		}
+		for _, m := range found {
+			bySKU[m.Key] = m
+		}
+	}
+	if len(barcodes) > 0 {
+		var found []priceListMatch
+		if err := db.NewSelect().
+			TableExpr(`(SELECT id AS product_id, barcode, 1::numeric AS factor FROM products
+				UNION ALL SELECT product_id, barcode, 1 FROM product_variants
+				UNION ALL SELECT product_id, barcode, factor FROM product_packagings) AS b`).
+			Join("JOIN products AS p ON p.id = b.product_id").
+			Join("LEFT JOIN product_suppliers AS ps ON ps.product_id = b.product_id AND ps.supplier_id = ?", supplierID).
+			ColumnExpr("b.product_id, p.name AS product_name, COALESCE(ps.unit_cost, 0) AS unit_cost").
+			ColumnExpr("b.barcode AS key, b.factor, ps.id IS NOT NULL AS linked").
+			Where("b.barcode IN (?)", bun.In(barcodes)).
+			Scan(ctx, &found); err != nil {
+			return nil, nil, err
+		}
+		for _, m := range found {
+			if _, dup := byBarcode[m.Key]; !dup {
+				byBarcode[m.Key] = m
+			}
+		}
+	}
+	return bySKU, byBarcode, nil
+}
+
+// Import reads a supplier price list (multipart "file", CSV or XLSX) and
+// logs a cost change for every row whose cost differs from what the
+// supplier charges now, effective from the "effectiveFrom" field (default
+// today). Changes dated today or earlier reach product_suppliers at once;
+// later ones are applied by the apply-supplier-costs job. Rows rising by
+// at least the purchasing costRiseAlertPct are flagged. With dryRun=true
+// nothing is saved and the response is the preview.
+func (h *SupplierCostsHandler) Import(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPriceListBytes)
+	if err := r.ParseMultipartForm(maxPriceListBytes); err != nil {
+		writeError(w, http.StatusBadRequest, "file terlalu besar atau form tidak valid")
+		return
+	}
+	supplierID, err := uuid.Parse(strings.TrimSpace(r.FormValue("supplierId")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "supplierId tidak valid")
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	effectiveFrom := today
+	if v := strings.TrimSpace(r.FormValue("effectiveFrom")); v != "" {
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			writeError(w, http.StatusBadRequest, "effectiveFrom harus YYYY-MM-DD")
+			return
+		}
+		effectiveFrom = v
+	}
+	dryRun, _ := strconv.ParseBool(r.FormValue("dryRun"))
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file daftar harga wajib diunggah")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sheet, err := sheets.Read(header.Filename, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := readPriceList(sheet)
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if len(rows) == 0 {
+		writeError(w, http.StatusBadRequest, "daftar harga kosong")
+		return
+	}
+
+	ctx := r.Context()
+	var supplier models.Supplier
+	if err := h.deps.DB.NewSelect().Model(&supplier).Where("id = ?", supplierID).Scan(ctx); err != nil {
+		writeError(w, http.StatusNotFound, "pemasok tidak ditemukan")
+		return
+	}
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	bySKU, byBarcode, err := matchPriceList(ctx, h.deps.DB, supplierID, rows)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	imp := models.SupplierPriceImport{
+		SupplierID:    supplierID,
+		Filename:      header.Filename,
+		EffectiveFrom: effectiveFrom,
+		RowsTotal:     len(rows),
+		Lines:         make([]models.SupplierPriceImportLine, 0, len(rows)),
+		CreatedBy:     actorName(ctx, h.deps.DB),
+	}
+	changed := map[uuid.UUID]int{}
+	for _, row := range rows {
+		line := models.SupplierPriceImportLine{
+			Row: row.row, SupplierSKU: row.sku, Barcode: row.barcode, Name: row.name,
+		}
+		cost, perr := parsePriceListCost(row.costS)
+		m, ok := bySKU[strings.ToLower(row.sku)]
+		line.MatchedBy = "sku"
+		if !ok || row.sku == "" {
+			m, ok = byBarcode[row.barcode]
+			line.MatchedBy = "barcode"
+			if row.barcode == "" {
+				ok = false
+			}
+		}
+		switch {
+		case perr != nil || cost <= 0:
+			line.Status = models.PriceImportInvalid
+			line.Message = "harga tidak valid: " + row.costS
+		case !ok:
+			line.Status = models.PriceImportUnmatched
+			line.MatchedBy = ""
+			line.Message = "kode/barcode tidak dikenal"
+		case !m.Linked:
+			line.Status = models.PriceImportUnmatched
+			line.Message = "produk belum terhubung ke pemasok ini"
+		}
+		if line.Status != "" {
+			line.Cost = cost
+			imp.RowsSkipped++
+			imp.Lines = append(imp.Lines, line)
+			continue
+		}
+		factor := m.Factor
+		if factor <= 0 {
+			factor = 1
+		}
+		line.Cost = cost
+		line.ProductID = &m.ProductID
+		line.ProductName = m.ProductName
+		line.OldCost = m.UnitCost
+		line.NewCost = math.Round(cost/factor*100) / 100
+		if prev, dup := changed[m.ProductID]; dup {
+			line.Status = models.PriceImportInvalid
+			line.Message = fmt.Sprintf("produk sama dengan baris %d", prev)
+			imp.RowsSkipped++
+			imp.Lines = append(imp.Lines, line)
+			continue
+		}
+		changed[m.ProductID] = row.row
+		line.ChangePct = costChangePct(line.OldCost, line.NewCost)
+		line.Alert = settings.Purchasing.CostRiseAlertPct > 0 && line.OldCost > 0 &&
+			line.ChangePct >= settings.Purchasing.CostRiseAlertPct
+		switch {
+		case math.Abs(line.NewCost-line.OldCost) < 0.005:
+			line.Status = models.PriceImportUnchanged
+			imp.RowsUnchanged++
+		case effectiveFrom <= today:
+			line.Status = models.PriceImportUpdated
+			imp.RowsUpdated++
+		default:
+			line.Status = models.PriceImportScheduled
+			imp.RowsUpdated++
+		}
+		imp.Lines = append(imp.Lines, line)
+	}
+	if dryRun {
+		writeJSON(w, http.StatusOK, imp)
+		return
+	}
+
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		code, err := nextSupplierPriceImportCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		imp.Code = code
+		if _, err := tx.NewInsert().Model(&imp).Exec(ctx); err != nil {
+			return err
+		}
+		for _, line := range imp.Lines {
+			if line.Status != models.PriceImportUpdated && line.Status != models.PriceImportScheduled {
+				continue
+			}
+			old := line.OldCost
+			change := models.SupplierCostChange{
+				ProductID:     *line.ProductID,
+				SupplierID:    supplierID,
+				UnitCost:      line.NewCost,
+				PreviousCost:  &old,
+				EffectiveFrom: effectiveFrom,
+				Source:        models.SupplierCostImport,
+				ImportID:      &imp.ID,
+				CreatedBy:     imp.CreatedBy,
+			}
+			if _, err := tx.NewInsert().Model(&change).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		_, err = applySupplierCosts(ctx, tx, today, &supplierID)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, imp)
+}
+
+// supplierPriceImportSummary is an import without its lines, for lists.
+type supplierPriceImportSummary struct {
+	ID            uuid.UUID `bun:"id" json:"id"`
+	Code          string    `bun:"code" json:"code"`
+	SupplierID    uuid.UUID `bun:"supplier_id" json:"supplierId"`
+	SupplierName  string    `bun:"supplier_name" json:"supplierName"`
+	Filename      string    `bun:"filename" json:"filename"`
+	EffectiveFrom string    `bun:"effective_from" json:"effectiveFrom"`
+	RowsTotal     int       `bun:"rows_total" json:"rowsTotal"`
+	RowsUpdated   int       `bun:"rows_updated" json:"rowsUpdated"`
+	RowsUnchanged int       `bun:"rows_unchanged" json:"rowsUnchanged"`
+	RowsSkipped   int       `bun:"rows_skipped" json:"rowsSkipped"`
+	CreatedBy     string    `bun:"created_by" json:"createdBy"`
+	CreatedAt     time.Time `bun:"created_at" json:"createdAt"`
+}
+
+// Imports lists price list uploads, newest first, optionally for one
+// ?supplierId=.
+func (h *SupplierCostsHandler) Imports(w http.ResponseWriter, r *http.Request) {
+	items := []supplierPriceImportSummary{}
+	q := h.deps.DB.NewSelect().
+		TableExpr("supplier_price_imports AS spi").
+		Join("JOIN suppliers AS s ON s.id = spi.supplier_id").
+		ColumnExpr("spi.id, spi.code, spi.supplier_id, spi.filename, spi.effective_from").
+		ColumnExpr("spi.rows_total, spi.rows_updated, spi.rows_unchanged, spi.rows_skipped").
+		ColumnExpr("spi.created_by, spi.created_at, s.name AS supplier_name").
+		OrderExpr("spi.created_at DESC").
+		Limit(200)
+	if v := strings.TrimSpace(r.URL.Query().Get("supplierId")); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "supplierId tidak valid")
+			return
+		}
+		q = q.Where("spi.supplier_id = ?", id)
+	}
+	if err := q.Scan(r.Context(), &items); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// GetImport returns one price list upload with its row outcomes.
+func (h *SupplierCostsHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var imp models.SupplierPriceImport
+	if err := h.deps.DB.NewSelect().Model(&imp).Where("id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusNotFound, "import tidak ditemukan")
+		return
+	}
+	writeJSON(w, http.StatusOK, imp)
+}
+
+func nextSupplierPriceImportCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("SPL-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("supplier_price_imports").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
@@ -0,0 +1,626 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// matchedInvoicedExpr is what a supplier has billed on matched invoices
+// for the PO aliased `po`: the amount accounts payable counts as owed.
+const matchedInvoicedExpr = `COALESCE((SELECT SUM(sinl.amount)
+	FROM supplier_invoice_lines AS sinl
+	JOIN supplier_invoices AS si ON si.id = sinl.invoice_id
+	WHERE sinl.purchase_order_id = po.id AND si.status = 'matched'), 0)`
+
+type SupplierInvoicesHandler struct {
+	deps Deps
+}
+
+func NewSupplierInvoicesHandler(deps Deps) *SupplierInvoicesHandler {
+	return &SupplierInvoicesHandler{deps: deps}
+}
+
+type supplierInvoiceInput struct {
+	SupplierID       uuid.UUID                  `json:"supplierId"`
+	InvoiceNumber    string                     `json:"invoiceNumber"`
+	InvoiceDate      string                     `json:"invoiceDate"`
+	TaxInvoiceNumber string                     `json:"taxInvoiceNumber"`
+	Notes            string                     `json:"notes"`
+	Lines            []supplierInvoiceLineInput `json:"lines"`
+}
+
+type supplierInvoiceLineInput struct {
+	PurchaseOrderLineID uuid.UUID `json:"purchaseOrderLineId"`
+	Qty                 float64   `json:"qty"`
+	UnitPrice           float64   `json:"unitPrice"`
+}
+
+// List filters by ?supplierId=, ?status=, ?purchaseOrderId= and an
+// invoice date range ?start=..?end=. Lines are left out; Get has them.
+func (h *SupplierInvoicesHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := []models.SupplierInvoice{}
+	sel := h.deps.DB.NewSelect().Model(&items).
+		ColumnExpr("sinv.*").
+		ColumnExpr("s.name AS supplier_name").
+		Join("JOIN suppliers AS s ON s.id = sinv.supplier_id").
+		Order("sinv.invoice_date DESC", "sinv.created_at DESC")
+	if v := strings.TrimSpace(q.Get("supplierId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("sinv.supplier_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("purchaseOrderId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where(`EXISTS (SELECT 1 FROM supplier_invoice_lines AS l
+				WHERE l.invoice_id = sinv.id AND l.purchase_order_id = ?)`, v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("status")); v != "" {
+		sel = sel.Where("sinv.status = ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("start")); v != "" {
+		sel = sel.Where("sinv.invoice_date >= ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("end")); v != "" {
+		sel = sel.Where("sinv.invoice_date <= ?", v)
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range items {
+		items[i].Lines = []models.SupplierInvoiceLine{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *SupplierInvoicesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	inv, err := loadSupplierInvoice(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// Create records a supplier invoice and matches it straight away; the
+// response says whether it matched or which lines are off.
+func (h *SupplierInvoicesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in supplierInvoiceInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := normalizeSupplierInvoiceInput(&in); err != nil {
+		writeTxError(w, err)
+		return
+	}
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	createdBy := actorName(ctx, h.deps.DB)
+
+	var inv models.SupplierInvoice
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		code, err := nextSupplierInvoiceCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		inv = models.SupplierInvoice{
+			Code:             code,
+			SupplierID:       in.SupplierID,
+			InvoiceNumber:    in.InvoiceNumber,
+			InvoiceDate:      in.InvoiceDate,
+			TaxInvoiceNumber: in.TaxInvoiceNumber,
+			Status:           models.SupplierInvoiceDiscrepancy,
+			Notes:            in.Notes,
+			CreatedBy:        createdBy,
+		}
+		poIDs, err := checkSupplierInvoiceLines(ctx, tx, &inv, in.Lines)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.NewInsert().Model(&inv).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		if err := insertSupplierInvoiceLines(ctx, tx, &inv); err != nil {
+			return err
+		}
+		return matchSupplierInvoices(ctx, tx, poIDs, settings)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadSupplierInvoice(ctx, h.deps.DB, inv.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, full)
+}
+
+// Update replaces an invoice's header and lines and matches it again,
+// along with any later invoice on the same POs whose match depends on it.
+func (h *SupplierInvoicesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in supplierInvoiceInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := normalizeSupplierInvoiceInput(&in); err != nil {
+		writeTxError(w, err)
+		return
+	}
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var inv models.SupplierInvoice
+		if err := tx.NewSelect().Model(&inv).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if inv.Status == models.SupplierInvoiceCancelled {
+			return errBadInput("faktur yang dibatalkan tidak bisa diubah")
+		}
+		if in.SupplierID != inv.SupplierID {
+			return errBadInput("pemasok faktur tidak bisa diubah")
+		}
+		oldPOs, err := supplierInvoicePOs(ctx, tx, inv.ID)
+		if err != nil {
+			return err
+		}
+		inv.InvoiceNumber = in.InvoiceNumber
+		inv.InvoiceDate = in.InvoiceDate
+		inv.TaxInvoiceNumber = in.TaxInvoiceNumber
+		inv.Notes = in.Notes
+		newPOs, err := checkSupplierInvoiceLines(ctx, tx, &inv, in.Lines)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.NewUpdate().Model(&inv).
+			Column("invoice_number", "invoice_date", "tax_invoice_number", "notes").
+			Set("updated_at = current_timestamp").
+			WherePK().Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewDelete().Model((*models.SupplierInvoiceLine)(nil)).
+			Where("invoice_id = ?", inv.ID).Exec(ctx); err != nil {
+			return err
+		}
+		if err := insertSupplierInvoiceLines(ctx, tx, &inv); err != nil {
+			return err
+		}
+		poIDs := unionIDs(oldPOs, newPOs)
+		if err := matchSupplierInvoices(ctx, tx, poIDs, settings); err != nil {
+			return err
+		}
+		return checkInvoicesCoverPayments(ctx, tx, poIDs)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadSupplierInvoice(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, full)
+}
+
+// Match re-runs the three-way match, e.g. after the tolerances changed.
+// Receiving a PO re-matches its invoices on its own.
+func (h *SupplierInvoicesHandler) Match(w http.ResponseWriter, r *http.Request) {
+	h.rematch(w, r, false)
+}
+
+// Cancel voids an invoice. It no longer counts toward payables, and later
+// invoices on the same POs are matched again without it.
+func (h *SupplierInvoicesHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.rematch(w, r, true)
+}
+
+func (h *SupplierInvoicesHandler) rematch(w http.ResponseWriter, r *http.Request, cancel bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx := r.Context()
+	settings, err := loadServerSettings(ctx, h.deps.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	err = h.deps.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var inv models.SupplierInvoice
+		if err := tx.NewSelect().Model(&inv).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if inv.Status == models.SupplierInvoiceCancelled {
+			return errBadInput("faktur sudah dibatalkan")
+		}
+		poIDs, err := supplierInvoicePOs(ctx, tx, inv.ID)
+		if err != nil {
+			return err
+		}
+		if cancel {
+			if _, err := tx.NewUpdate().Model((*models.SupplierInvoice)(nil)).
+				Set("status = ?", models.SupplierInvoiceCancelled).
+				Set("matched_at = NULL").
+				Set("updated_at = current_timestamp").
+				Where("id = ?", inv.ID).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		if err := matchSupplierInvoices(ctx, tx, poIDs, settings); err != nil {
+			return err
+		}
+		return checkInvoicesCoverPayments(ctx, tx, poIDs)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadSupplierInvoice(ctx, h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, full)
+}
+
+// ─── helpers ────────────────────────────────────────────────────────────────
+
+func normalizeSupplierInvoiceInput(in *supplierInvoiceInput) error {
+	in.InvoiceNumber = strings.TrimSpace(in.InvoiceNumber)
+	in.InvoiceDate = strings.TrimSpace(in.InvoiceDate)
+	in.TaxInvoiceNumber = strings.TrimSpace(in.TaxInvoiceNumber)
+	in.Notes = strings.TrimSpace(in.Notes)
+	if in.SupplierID == uuid.Nil {
+		return errBadInput("pemasok wajib diisi")
+	}
+	if in.InvoiceNumber == "" {
+		return errBadInput("nomor faktur wajib diisi")
+	}
+	if in.InvoiceDate == "" {
+		in.InvoiceDate = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", in.InvoiceDate); err != nil {
+		return errBadInput("tanggal faktur harus YYYY-MM-DD")
+	}
+	if len(in.Lines) == 0 {
+		return errBadInput("faktur butuh minimal satu baris")
+	}
+	seen := map[uuid.UUID]bool{}
+	for i := range in.Lines {
+		l := &in.Lines[i]
+		l.UnitPrice = roundMoney(l.UnitPrice)
+		if l.PurchaseOrderLineID == uuid.Nil || l.Qty <= 0 {
+			return errBadInput("setiap baris butuh item PO dan qty lebih dari 0")
+		}
+		if l.UnitPrice < 0 {
+			return errBadInput("harga tidak boleh negatif")
+		}
+		if seen[l.PurchaseOrderLineID] {
+			return errBadInput("item PO yang sama ditagih dua kali di faktur ini")
+		}
+		seen[l.PurchaseOrderLineID] = true
+	}
+	return nil
+}
+
+// checkSupplierInvoiceLines locks the POs the lines bill, checks they are
+// ordered standard POs of the invoice's supplier and that the invoice
+// number is new for it, and fills inv.Lines. Returns the POs touched.
+func checkSupplierInvoiceLines(
+	ctx context.Context, tx bun.Tx, inv *models.SupplierInvoice, in []supplierInvoiceLineInput,
+) ([]uuid.UUID, error) {
+	var supplierName string
+	if err := tx.NewSelect().Table("suppliers").Column("name").
+		Where("id = ?", inv.SupplierID).Scan(ctx, &supplierName); err != nil {
+		return nil, errBadInput("pemasok tidak ditemukan")
+	}
+	dupQ := tx.NewSelect().Table("supplier_invoices").Column("code").
+		Where("supplier_id = ?", inv.SupplierID).
+		Where("lower(invoice_number) = lower(?)", inv.InvoiceNumber).
+		Where("status <> ?", models.SupplierInvoiceCancelled)
+	if inv.ID != uuid.Nil {
+		dupQ = dupQ.Where("id <> ?", inv.ID)
+	}
+	var dup []string
+	if err := dupQ.Scan(ctx, &dup); err != nil {
+		return nil, err
+	}
+	if len(dup) > 0 {
+		return nil, errBadInput(fmt.Sprintf("faktur %s dari %s sudah dicatat (%s)", inv.InvoiceNumber, supplierName, dup[0]))
+	}
+
+	ids := make([]uuid.UUID, len(in))
+	for i, l := range in {
+		ids[i] = l.PurchaseOrderLineID
+	}
+	var poLines []struct {
+		ID              uuid.UUID `bun:"id"`
+		PurchaseOrderID uuid.UUID `bun:"purchase_order_id"`
+		POCode          string    `bun:"po_code"`
+		SupplierID      uuid.UUID `bun:"supplier_id"`
+		Type            string    `bun:"type"`
+		Status          string    `bun:"status"`
+	}
+	if err := tx.NewSelect().TableExpr("purchase_order_lines AS pol").
+		Join("JOIN purchase_orders AS po ON po.id = pol.purchase_order_id").
+		ColumnExpr("pol.id, pol.purchase_order_id, po.code AS po_code, po.supplier_id, po.type, po.status").
+		Where("pol.id IN (?)", bun.In(ids)).
+		Scan(ctx, &poLines); err != nil {
+		return nil, err
+	}
+	byLine := make(map[uuid.UUID]int, len(poLines))
+	var poIDs []uuid.UUID
+	seenPO := map[uuid.UUID]bool{}
+	for i, pl := range poLines {
+		byLine[pl.ID] = i
+		switch {
+		case pl.SupplierID != inv.SupplierID:
+			return nil, errBadInput("PO " + pl.POCode + " bukan dari " + supplierName)
+		case pl.Type != models.POTypeStandard:
+			return nil, errBadInput("PO " + pl.POCode + " adalah konsinyasi; konsinyasi dibayar lewat setoran")
+		case !slices.Contains(payablePOStatuses, pl.Status):
+			return nil, errBadInput("PO " + pl.POCode + " berstatus " + pl.Status + " belum bisa ditagih")
+		}
+		if !seenPO[pl.PurchaseOrderID] {
+			seenPO[pl.PurchaseOrderID] = true
+			poIDs = append(poIDs, pl.PurchaseOrderID)
+		}
+	}
+	// Invoices on the same PO are matched against each other; take them
+	// one at a time.
+	if len(poIDs) > 0 {
+		if err := tx.NewSelect().Table("purchase_orders").Column("id").
+			Where("id IN (?)", bun.In(poIDs)).
+			OrderExpr("id").For("UPDATE").
+			Scan(ctx, new([]uuid.UUID)); err != nil {
+			return nil, err
+		}
+	}
+
+	inv.Lines = make([]models.SupplierInvoiceLine, len(in))
+	for i, l := range in {
+		j, ok := byLine[l.PurchaseOrderLineID]
+		if !ok {
+			return nil, errBadInput("item PO tidak ditemukan")
+		}
+		inv.Lines[i] = models.SupplierInvoiceLine{
+			PurchaseOrderID:     poLines[j].PurchaseOrderID,
+			PurchaseOrderLineID: l.PurchaseOrderLineID,
+			Qty:                 l.Qty,
+			UnitPrice:           l.UnitPrice,
+			Amount:              roundMoney(l.Qty * l.UnitPrice),
+			Position:            i,
+		}
+	}
+	return poIDs, nil
+}
+
+func insertSupplierInvoiceLines(ctx context.Context, tx bun.Tx, inv *models.SupplierInvoice) error {
+	for i := range inv.Lines {
+		inv.Lines[i].InvoiceID = inv.ID
+	}
+	_, err := tx.NewInsert().Model(&inv.Lines).Exec(ctx)
+	return err
+}
+
+// matchSupplierInvoices three-way matches every open invoice on the given
+// POs, oldest first. Each line compares the invoiced price with the PO
+// price, and the qty billed so far (earlier invoices plus this one) with
+// the qty ordered and the line's received_qty, within the purchasing
+// tolerances. Batches aren't summed: moves split them into siblings that
+// keep the PO line as their source. An invoice matches when all its lines do.
+func matchSupplierInvoices(ctx context.Context, tx bun.Tx, poIDs []uuid.UUID, settings serverSettings) error {
+	if len(poIDs) == 0 {
+		return nil
+	}
+	var invoices []models.SupplierInvoice
+	if err := tx.NewSelect().Model(&invoices).
+		Where("sinv.status <> ?", models.SupplierInvoiceCancelled).
+		Where(`EXISTS (SELECT 1 FROM supplier_invoice_lines AS l
+			WHERE l.invoice_id = sinv.id AND l.purchase_order_id IN (?))`, bun.In(poIDs)).
+		Order("sinv.created_at ASC", "sinv.code ASC").
+		Scan(ctx); err != nil {
+		return err
+	}
+	qtyTol := 1 + settings.Purchasing.InvoiceQtyTolerancePct/100
+	priceTol := settings.Purchasing.InvoicePriceTolerancePct / 100
+
+	for _, inv := range invoices {
+		var lines []struct {
+			ID             uuid.UUID `bun:"id"`
+			Qty            float64   `bun:"qty"`
+			UnitPrice      float64   `bun:"unit_price"`
+			Amount         float64   `bun:"amount"`
+			OrderedQty     float64   `bun:"ordered_qty"`
+			POUnitPrice    float64   `bun:"po_unit_price"`
+			ReceivedQty    float64   `bun:"received_qty"`
+			InvoicedBefore float64   `bun:"invoiced_before"`
+		}
+		if err := tx.NewSelect().TableExpr("supplier_invoice_lines AS sinl").
+			Join("JOIN purchase_order_lines AS pol ON pol.id = sinl.purchase_order_line_id").
+			ColumnExpr("sinl.id, sinl.qty, sinl.unit_price, sinl.amount").
+			ColumnExpr("pol.quantity AS ordered_qty, pol.unit_price AS po_unit_price, pol.received_qty").
+			ColumnExpr(`COALESCE((SELECT SUM(o.qty) FROM supplier_invoice_lines AS o
+				JOIN supplier_invoices AS oi ON oi.id = o.invoice_id
+				WHERE o.purchase_order_line_id = sinl.purchase_order_line_id
+				  AND oi.status <> ?
+				  AND (oi.created_at, oi.code) < (?, ?)), 0) AS invoiced_before`,
+				models.SupplierInvoiceCancelled, inv.CreatedAt, inv.Code).
+			Where("sinl.invoice_id = ?", inv.ID).
+			Scan(ctx, &lines); err != nil {
+			return err
+		}
+		total := 0.0
+		discrepancies := 0
+		for _, l := range lines {
+			received := l.ReceivedQty
+			billed := l.InvoicedBefore + l.Qty
+			status := models.InvoiceMatchOK
+			var notes []string
+			if math.Abs(l.UnitPrice-l.POUnitPrice) > l.POUnitPrice*priceTol+0.005 {
+				status = models.InvoiceMatchPrice
+				notes = append(notes, fmt.Sprintf("harga %s, PO %s",
+					formatRupiah(l.UnitPrice), formatRupiah(l.POUnitPrice)))
+			}
+			switch {
+			case billed > l.OrderedQty*qtyTol+1e-9:
+				status = models.InvoiceMatchQtyOrdered
+				notes = append(notes, fmt.Sprintf("ditagih %s, dipesan %s",
+					formatDecimal(billed), formatDecimal(l.OrderedQty)))
+			case billed > received*qtyTol+1e-9:
+				status = models.InvoiceMatchQtyReceived
+				notes = append(notes, fmt.Sprintf("ditagih %s, diterima %s",
+					formatDecimal(billed), formatDecimal(received)))
+			}
+			if status != models.InvoiceMatchOK {
+				discrepancies++
+			}
+			total += l.Amount
+			if _, err := tx.NewUpdate().Model((*models.SupplierInvoiceLine)(nil)).
+				Set("ordered_qty = ?", l.OrderedQty).
+				Set("po_unit_price = ?", l.POUnitPrice).
+				Set("received_qty = ?", received).
+				Set("invoiced_before = ?", l.InvoicedBefore).
+				Set("match_status = ?", status).
+				Set("match_note = ?", strings.Join(notes, "; ")).
+				Where("id = ?", l.ID).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		q := tx.NewUpdate().Model((*models.SupplierInvoice)(nil)).
+			Set("total = ?", roundMoney(total)).
+			Set("discrepancies = ?", discrepancies).
+			Set("updated_at = current_timestamp").
+			Where("id = ?", inv.ID)
+		if discrepancies == 0 {
+			q = q.Set("status = ?", models.SupplierInvoiceMatched).
+				Set("matched_at = COALESCE(matched_at, current_timestamp)")
+		} else {
+			q = q.Set("status = ?", models.SupplierInvoiceDiscrepancy).Set("matched_at = NULL")
+		}
+		if _, err := q.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkInvoicesCoverPayments refuses a change that leaves a PO paid for
+// more than its matched invoices bill.
+func checkInvoicesCoverPayments(ctx context.Context, tx bun.Tx, poIDs []uuid.UUID) error {
+	if len(poIDs) == 0 {
+		return nil
+	}
+	var rows []struct {
+		Code     string  `bun:"code"`
+		Paid     float64 `bun:"paid_amount"`
+		Invoiced float64 `bun:"invoiced"`
+	}
+	if err := tx.NewSelect().TableExpr("purchase_orders AS po").
+		ColumnExpr("po.code, po.paid_amount").
+		ColumnExpr(matchedInvoicedExpr+" AS invoiced").
+		Where("po.id IN (?)", bun.In(poIDs)).
+		Scan(ctx, &rows); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if row.Paid > row.Invoiced+0.005 {
+			return errBadInput(fmt.Sprintf(
+				"PO %s sudah dibayar %s, lebih dari faktur cocok %s",
+				row.Code, formatRupiah(row.Paid), formatRupiah(row.Invoiced)))
+		}
+	}
+	return nil
+}
+
+func supplierInvoicePOs(ctx context.Context, db bun.IDB, invoiceID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.NewSelect().Table("supplier_invoice_lines").
+		ColumnExpr("DISTINCT purchase_order_id").
+		Where("invoice_id = ?", invoiceID).
+		Scan(ctx, &ids)
+	return ids, err
+}
+
+func loadSupplierInvoice(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.SupplierInvoice, error) {
+	var inv models.SupplierInvoice
+	if err := db.NewSelect().Model(&inv).
+		ColumnExpr("sinv.*").
+		ColumnExpr("s.name AS supplier_name").
+		Join("JOIN suppliers AS s ON s.id = sinv.supplier_id").
+		Where("sinv.id = ?", id).Scan(ctx); err != nil {
+		return nil, err
+	}
+	inv.Lines = []models.SupplierInvoiceLine{}
+	if err := db.NewSelect().Model(&inv.Lines).
+		ColumnExpr("sinl.*").
+		ColumnExpr("po.code AS purchase_order_code").
+		ColumnExpr("p.name || COALESCE(' - ' || NULLIF(pv.name, ''), '') AS product_name").
+		Join("JOIN purchase_orders AS po ON po.id = sinl.purchase_order_id").
+		Join("JOIN purchase_order_lines AS pol ON pol.id = sinl.purchase_order_line_id").
+		Join("JOIN products AS p ON p.id = pol.product_id").
+		Join("LEFT JOIN product_variants AS pv ON pv.id = pol.variant_id").
+		Where("sinl.invoice_id = ?", id).
+		Order("sinl.position ASC").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func nextSupplierInvoiceCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("SINV-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("supplier_invoices").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
+
+func unionIDs(a, b []uuid.UUID) []uuid.UUID {
+	out := append([]uuid.UUID{}, a...)
+	for _, id := range b {
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// payablePOStatuses are the PO statuses that count as owed to the
+// supplier: ordered and not cancelled. Consignment POs are settled
+// through payouts and never count.
+var payablePOStatuses = []string{
+	models.POStatusSent, models.POStatusPartial, models.POStatusReceived, models.POStatusClosed,
+}
+
+// apAging splits outstanding amounts by how far past due they are.
+// Current also holds POs that have no due date yet because nothing was
+// received.
+type apAging struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days1To30"`
+	Days31To60 float64 `json:"days31To60"`
+	Days61To90 float64 `json:"days61To90"`
+	Over90     float64 `json:"over90"`
+}
+
+func (a *apAging) add(daysOverdue int, amount float64) {
+	switch {
+	case daysOverdue <= 0:
+		a.Current += amount
+	case daysOverdue <= 30:
+		a.Days1To30 += amount
+	case daysOverdue <= 60:
+		a.Days31To60 += amount
+	case daysOverdue <= 90:
+		a.Days61To90 += amount
+	default:
+		a.Over90 += amount
+	}
+}
+
+type apSupplier struct {
+	SupplierID       uuid.UUID `json:"supplierId"`
+	SupplierName     string    `json:"supplierName"`
+	PaymentTermsDays int       `json:"paymentTermsDays"`
+	// Billed on matched invoices; Disputed is billed on invoices still
+	// failing the three-way match, which is not owed yet.
+	Purchased  float64 `json:"purchased"`
+	Disputed   float64 `json:"disputed"`
+	Paid       float64 `json:"paid"`
+	DebitNotes float64 `json:"debitNotes"`
+	// Debit notes from returns not tied to a PO: credit against the
+	// supplier as a whole.
+	Credit      float64 `json:"credit"`
+	Outstanding float64 `json:"outstanding"`
+	apAging
+}
+
+type apItem struct {
+	PurchaseOrderID uuid.UUID `bun:"id" json:"purchaseOrderId"`
+	Code            string    `bun:"code" json:"code"`
+	SupplierID      uuid.UUID `bun:"supplier_id" json:"supplierId"`
+	SupplierName    string    `bun:"supplier_name" json:"supplierName"`
+	TermsDays       int       `bun:"payment_terms_days" json:"-"`
+	Status          string    `bun:"status" json:"status"`
+	OrderDate       string    `bun:"order_date" json:"orderDate"`
+	DueDate         string    `bun:"due_date" json:"dueDate"`
+	Ordered         float64   `bun:"ordered" json:"ordered"`
+	// Matched invoices only.
+	Total       float64 `bun:"total" json:"total"`
+	Disputed    float64 `bun:"disputed" json:"disputed"`
+	Paid        float64 `bun:"paid_amount" json:"paid"`
+	DebitNotes  float64 `bun:"debit_note_amount" json:"debitNotes"`
+	Outstanding float64 `bun:"-" json:"outstanding"`
+	// Days past the due date; negative while it is still ahead.
+	DaysOverdue int `bun:"-" json:"daysOverdue"`
+}
+
+type apReport struct {
+	AsOf         string       `json:"asOf"`
+	UpcomingDays int          `json:"upcomingDays"`
+	Outstanding  float64      `json:"outstanding"`
+	Aging        apAging      `json:"aging"`
+	Suppliers    []apSupplier `json:"suppliers"`
+	Upcoming     []apItem     `json:"upcoming"`
+	Overdue      []apItem     `json:"overdue"`
+	Open         []apItem     `json:"open"`
+}
+
+// Payables is accounts payable as of ?asOf= (default today): per supplier
+// what was billed on matched supplier invoices, paid and credited, the
+// outstanding balance split into aging buckets by due date, plus the POs
+// falling due within ?upcomingDays= (default 14) and those already
+// overdue. ?supplierId= narrows everything to one supplier. Paid amounts
+// are current; asOf only moves the date the aging is measured from.
+// Invoices failing the three-way match are reported as disputed, not owed.
+func (h *SuppliersHandler) Payables(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	asOf := time.Now().Format("2006-01-02")
+	if v := strings.TrimSpace(q.Get("asOf")); v != "" {
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			writeError(w, http.StatusBadRequest, "asOf harus YYYY-MM-DD")
+			return
+		}
+		asOf = v
+	}
+	upcomingDays := 14
+	if v := strings.TrimSpace(q.Get("upcomingDays")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 365 {
+			writeError(w, http.StatusBadRequest, "upcomingDays harus 0–365")
+			return
+		}
+		upcomingDays = n
+	}
+	var supplierID *uuid.UUID
+	if v := strings.TrimSpace(q.Get("supplierId")); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "supplierId tidak valid")
+			return
+		}
+		supplierID = &id
+	}
+	ctx := r.Context()
+
+	pos := []apItem{}
+	sel := h.deps.DB.NewSelect().
+		TableExpr("purchase_orders AS po").
+		Join("JOIN suppliers AS s ON s.id = po.supplier_id").
+		ColumnExpr("po.id, po.code, po.supplier_id, po.status, po.order_date, po.due_date").
+		ColumnExpr("po.paid_amount, po.debit_note_amount").
+		ColumnExpr("s.name AS supplier_name, s.payment_terms_days").
+		ColumnExpr(`COALESCE((SELECT SUM(pol.quantity * pol.unit_price)
+			FROM purchase_order_lines AS pol WHERE pol.purchase_order_id = po.id), 0) AS ordered`).
+		ColumnExpr(matchedInvoicedExpr+" AS total").
+		ColumnExpr(`COALESCE((SELECT SUM(sinl.amount) FROM supplier_invoice_lines AS sinl
+			JOIN supplier_invoices AS si ON si.id = sinl.invoice_id
+			WHERE sinl.purchase_order_id = po.id AND si.status = ?), 0) AS disputed`,
+			models.SupplierInvoiceDiscrepancy).
+		Where("po.type = ?", models.POTypeStandard).
+		Where("po.status IN (?)", bun.In(payablePOStatuses)).
+		Where("po.order_date <= ?", asOf).
+		OrderExpr("NULLIF(po.due_date, '') ASC NULLS LAST, po.code ASC")
+	if supplierID != nil {
+		sel = sel.Where("po.supplier_id = ?", *supplierID)
+	}
+	if err := sel.Scan(ctx, &pos); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var credits []struct {
+		SupplierID   uuid.UUID `bun:"supplier_id"`
+		SupplierName string    `bun:"supplier_name"`
+		TermsDays    int       `bun:"payment_terms_days"`
+		Amount       float64   `bun:"amount"`
+	}
+	csel := h.deps.DB.NewSelect().
+		TableExpr("supplier_debit_notes AS sdn").
+		Join("JOIN suppliers AS s ON s.id = sdn.supplier_id").
+		ColumnExpr("sdn.supplier_id, s.name AS supplier_name, s.payment_terms_days").
+		ColumnExpr("SUM(sdn.amount) AS amount").
+		Where("sdn.purchase_order_id IS NULL").
+		Where("sdn.issued_at <= ?", asOf).
+		GroupExpr("sdn.supplier_id, s.name, s.payment_terms_days")
+	if supplierID != nil {
+		csel = csel.Where("sdn.supplier_id = ?", *supplierID)
+	}
+	if err := csel.Scan(ctx, &credits); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	asOfDay, _ := time.Parse("2006-01-02", asOf)
+	report := apReport{
+		AsOf:         asOf,
+		UpcomingDays: upcomingDays,
+		Suppliers:    []apSupplier{},
+		Upcoming:     []apItem{},
+		Overdue:      []apItem{},
+		Open:         []apItem{},
+	}
+	bySupplier := map[uuid.UUID]*apSupplier{}
+	supplierRow := func(id uuid.UUID, name string, terms int) *apSupplier {
+		s, ok := bySupplier[id]
+		if !ok {
+			s = &apSupplier{SupplierID: id, SupplierName: name, PaymentTermsDays: terms}
+			bySupplier[id] = s
+		}
+		return s
+	}
+	for _, it := range pos {
+		it.Outstanding = roundMoney(it.Total - it.Paid - it.DebitNotes)
+		s := supplierRow(it.SupplierID, it.SupplierName, it.TermsDays)
+		s.Purchased += it.Total
+		s.Disputed += it.Disputed
+		s.Paid += it.Paid
+		s.DebitNotes += it.DebitNotes
+		s.Outstanding += it.Outstanding
+		if it.Outstanding <= 0.005 {
+			continue
+		}
+		if it.DueDate != "" {
+			if due, err := time.Parse("2006-01-02", it.DueDate); err == nil {
+				it.DaysOverdue = int(asOfDay.Sub(due).Hours() / 24)
+			}
+		}
+		s.add(it.DaysOverdue, it.Outstanding)
+		report.Aging.add(it.DaysOverdue, it.Outstanding)
+		report.Open = append(report.Open, it)
+		switch {
+		case it.DueDate == "":
+		case it.DaysOverdue > 0:
+			report.Overdue = append(report.Overdue, it)
+		case -it.DaysOverdue <= upcomingDays:
+			report.Upcoming = append(report.Upcoming, it)
+		}
+	}
+	for _, c := range credits {
+		s := supplierRow(c.SupplierID, c.SupplierName, c.TermsDays)
+		s.Credit += c.Amount
+		s.Outstanding -= c.Amount
+	}
+	for _, s := range bySupplier {
+		s.Outstanding = roundMoney(s.Outstanding)
+		report.Outstanding += s.Outstanding
+		report.Suppliers = append(report.Suppliers, *s)
+	}
+	report.Outstanding = roundMoney(report.Outstanding)
+	sort.Slice(report.Suppliers, func(i, j int) bool {
+		return report.Suppliers[i].SupplierName < report.Suppliers[j].SupplierName
+	})
+	// Most overdue first.
+	sort.SliceStable(report.Overdue, func(i, j int) bool {
+		return report.Overdue[i].DaysOverdue > report.Overdue[j].DaysOverdue
+	})
+	writeJSON(w, http.StatusOK, report)
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type SupplierPaymentsHandler struct {
+	deps Deps
+}
+
+func NewSupplierPaymentsHandler(deps Deps) *SupplierPaymentsHandler {
+	return &SupplierPaymentsHandler{deps: deps}
+}
+
+type supplierPaymentInput struct {
+	SupplierID  uuid.UUID                        `json:"supplierId"`
+	Amount      float64                          `json:"amount"`
+	Method      string                           `json:"method"`
+	PaidAt      string                           `json:"paidAt"`
+	Reference   string                           `json:"reference"`
+	Notes       string                           `json:"notes"`
+	Allocations []supplierPaymentAllocationInput `json:"allocations"`
+}
+
+type supplierPaymentAllocationInput struct {
+	PurchaseOrderID uuid.UUID `json:"purchaseOrderId"`
+	Amount          float64   `json:"amount"`
+}
+
+// payablePO is a standard PO with what is still owed on it.
+type payablePO struct {
+	ID          uuid.UUID `bun:"id"`
+	Code        string    `bun:"code"`
+	Total       float64   `bun:"total"`
+	Paid        float64   `bun:"paid_amount"`
+	DebitNotes  float64   `bun:"debit_note_amount"`
+	Outstanding float64   `bun:"-"`
+}
+
+func (h *SupplierPaymentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := []models.SupplierPayment{}
+	sel := h.deps.DB.NewSelect().Model(&items).
+		Order("spay.paid_at DESC", "spay.created_at DESC")
+	if v := strings.TrimSpace(q.Get("supplierId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			sel = sel.Where("spay.supplier_id = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(q.Get("start")); v != "" {
+		sel = sel.Where("spay.paid_at >= ?", v)
+	}
+	if v := strings.TrimSpace(q.Get("end")); v != "" {
+		sel = sel.Where("spay.paid_at <= ?", v)
+	}
+	if err := sel.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := loadSupplierPaymentAllocations(r.Context(), h.deps.DB, items); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *SupplierPaymentsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	p, err := loadSupplierPayment(r.Context(), h.deps.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// Create records a payment to a supplier and splits it over the POs in
+// allocations. Without allocations the amount pays off the supplier's POs
+// oldest due date first. No PO may be paid beyond what it still owes
+// (matched invoices less payments and debit notes), and the allocations
+// must add up to the payment.
+func (h *SupplierPaymentsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in supplierPaymentInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if in.SupplierID == uuid.Nil {
+		writeError(w, http.StatusBadRequest, "pemasok wajib diisi")
+		return
+	}
+	in.Amount = math.Round(in.Amount*100) / 100
+	if in.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "jumlah pembayaran harus lebih dari 0")
+		return
+	}
+	method := strings.TrimSpace(in.Method)
+	if method == "" {
+		method = models.POPaymentTransfer
+	}
+	if method != models.POPaymentCash && method != models.POPaymentTransfer && method != models.POPaymentOther {
+		writeError(w, http.StatusBadRequest, "metode harus cash, transfer atau other")
+		return
+	}
+	paidAt := strings.TrimSpace(in.PaidAt)
+	if paidAt == "" {
+		paidAt = time.Now().Format("2006-01-02")
+	}
+	paidTime, err := time.ParseInLocation("2006-01-02", paidAt, time.Local)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "tanggal bayar harus YYYY-MM-DD")
+		return
+	}
+	if paidAt == time.Now().Format("2006-01-02") {
+		paidTime = time.Now()
+	}
+	seen := map[uuid.UUID]bool{}
+	allocated := 0.0
+	for i := range in.Allocations {
+		a := &in.Allocations[i]
+		a.Amount = math.Round(a.Amount*100) / 100
+		if a.PurchaseOrderID == uuid.Nil || a.Amount <= 0 {
+			writeError(w, http.StatusBadRequest, "setiap alokasi butuh PO dan jumlah lebih dari 0")
+			return
+		}
+		if seen[a.PurchaseOrderID] {
+			writeError(w, http.StatusBadRequest, "PO yang sama dialokasikan dua kali")
+			return
+		}
+		seen[a.PurchaseOrderID] = true
+		allocated += a.Amount
+	}
+	if len(in.Allocations) > 0 && math.Abs(allocated-in.Amount) > 0.005 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(
+			"total alokasi %s tidak sama dengan jumlah pembayaran %s",
+			formatRupiah(allocated), formatRupiah(in.Amount)))
+		return
+	}
+	createdBy := actorName(r.Context(), h.deps.DB)
+
+	var payment models.SupplierPayment
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var supplier models.Supplier
+		if err := tx.NewSelect().Model(&supplier).Where("id = ?", in.SupplierID).Scan(ctx); err != nil {
+			return errBadInput("pemasok tidak ditemukan")
+		}
+		open, err := lockPayablePOs(ctx, tx, supplier.ID)
+		if err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]*payablePO, len(open))
+		for i := range open {
+			byID[open[i].ID] = &open[i]
+		}
+
+		allocations := in.Allocations
+		if len(allocations) == 0 {
+			// Oldest due first; lockPayablePOs already returns that order.
+			left := in.Amount
+			for _, po := range open {
+				if left <= 0.005 {
+					break
+				}
+				if po.Outstanding <= 0.005 {
+					continue
+				}
+				amt := math.Min(left, po.Outstanding)
+				allocations = append(allocations, supplierPaymentAllocationInput{PurchaseOrderID: po.ID, Amount: amt})
+				left -= amt
+			}
+			if left > 0.005 {
+				return errBadInput(fmt.Sprintf(
+					"pembayaran melebihi total utang ke %s; kelebihan %s", supplier.Name, formatRupiah(left)))
+			}
+		}
+		for _, a := range allocations {
+			po, ok := byID[a.PurchaseOrderID]
+			if !ok {
+				return errBadInput("PO bukan utang terbuka dari " + supplier.Name)
+			}
+			if a.Amount > po.Outstanding+0.005 {
+				return errBadInput(fmt.Sprintf(
+					"pembayaran %s melebihi sisa utang PO %s (%s)",
+					formatRupiah(a.Amount), po.Code, formatRupiah(po.Outstanding)))
+			}
+		}
+
+		code, err := nextSupplierPaymentCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		payment = models.SupplierPayment{
+			Code:       code,
+			SupplierID: supplier.ID,
+			Amount:     in.Amount,
+			Method:     method,
+			PaidAt:     paidAt,
+			Reference:  strings.TrimSpace(in.Reference),
+			Notes:      strings.TrimSpace(in.Notes),
+			CreatedBy:  createdBy,
+		}
+		if _, err := tx.NewInsert().Model(&payment).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		notes := payment.Code
+		if payment.Reference != "" {
+			notes += " · " + payment.Reference
+		}
+		for _, a := range allocations {
+			row := models.PurchaseOrderPayment{
+				PurchaseOrderID:   a.PurchaseOrderID,
+				Amount:            a.Amount,
+				Method:            method,
+				PaidAt:            paidTime,
+				Notes:             notes,
+				SupplierPaymentID: &payment.ID,
+			}
+			if _, err := tx.NewInsert().Model(&row).Exec(ctx); err != nil {
+				return err
+			}
+			if _, err := tx.NewUpdate().Table("purchase_orders").
+				Set("paid_amount = paid_amount + ?", a.Amount).
+				Set("updated_at = current_timestamp").
+				Where("id = ?", a.PurchaseOrderID).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	full, err := loadSupplierPayment(r.Context(), h.deps.DB, payment.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, full)
+}
+
+// lockPayablePOs locks a supplier's payable POs and returns them with
+// their outstanding amount, earliest due date first. POs not yet
+// received (no due date) come last, oldest order first.
+func lockPayablePOs(ctx context.Context, tx bun.Tx, supplierID uuid.UUID) ([]payablePO, error) {
+	var ids []uuid.UUID
+	if err := tx.NewSelect().Table("purchase_orders").Column("id").
+		Where("supplier_id = ?", supplierID).
+		Where("type = ?", models.POTypeStandard).
+		Where("status IN (?)", bun.In(payablePOStatuses)).
+		OrderExpr("id").For("UPDATE").
+		Scan(ctx, &ids); err != nil {
+		return nil, err
+	}
+	out := []payablePO{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	if err := tx.NewSelect().TableExpr("purchase_orders AS po").
+		ColumnExpr("po.id, po.code, po.paid_amount, po.debit_note_amount").
+		ColumnExpr(matchedInvoicedExpr+" AS total").
+		Where("po.id IN (?)", bun.In(ids)).
+		OrderExpr("NULLIF(po.due_date, '') ASC NULLS LAST, po.order_date ASC, po.code ASC").
+		Scan(ctx, &out); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Outstanding = math.Round((out[i].Total-out[i].Paid-out[i].DebitNotes)*100) / 100
+	}
+	return out, nil
+}
+
+func loadSupplierPayment(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.SupplierPayment, error) {
+	var p models.SupplierPayment
+	if err := db.NewSelect().Model(&p).Where("spay.id = ?", id).Scan(ctx); err != nil {
+		return nil, err
+	}
+	items := []models.SupplierPayment{p}
+	if err := loadSupplierPaymentAllocations(ctx, db, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+func loadSupplierPaymentAllocations(ctx context.Context, db bun.IDB, items []models.SupplierPayment) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+		items[i].Allocations = []models.SupplierPaymentAllocation{}
+	}
+	var rows []struct {
+		SupplierPaymentID uuid.UUID `bun:"supplier_payment_id"`
+		PurchaseOrderID   uuid.UUID `bun:"purchase_order_id"`
+		PurchaseOrderCode string    `bun:"purchase_order_code"`
+		Amount            float64   `bun:"amount"`
+	}
+	if err := db.NewSelect().TableExpr("purchase_order_payments AS pop").
+		Join("JOIN purchase_orders AS po ON po.id = pop.purchase_order_id").
+		ColumnExpr("pop.supplier_payment_id, pop.purchase_order_id, pop.amount").
+		ColumnExpr("po.code AS purchase_order_code").
+		Where("pop.supplier_payment_id IN (?)", bun.In(ids)).
+		OrderExpr("po.code ASC").
+		Scan(ctx, &rows); err != nil {
+		return err
+	}
+	index := make(map[uuid.UUID]int, len(items))
+	for i := range items {
+		index[items[i].ID] = i
+	}
+	for _, row := range rows {
+		i := index[row.SupplierPaymentID]
+		items[i].Allocations = append(items[i].Allocations, models.SupplierPaymentAllocation{
+			PurchaseOrderID:   row.PurchaseOrderID,
+			PurchaseOrderCode: row.PurchaseOrderCode,
+			Amount:            row.Amount,
+		})
+	}
+	return nil
+}
+
+func nextSupplierPaymentCode(ctx context.Context, tx bun.Tx) (string, error) {
+	prefix := fmt.Sprintf("SPAY-%d-", time.Now().Year())
+	var count int
+	if err := tx.NewSelect().Table("supplier_payments").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
@@ -19,14 +19,15 @@ func NewSuppliersHandler(deps Deps) *SuppliersHandler {
 }
 
 type supplierInput struct {
-	Name          string `json:"name"`
-	ContactPerson string `json:"contactPerson"`
-	Email         string `json:"email"`
-	Phone         string `json:"phone"`
-	Address       string `json:"address"`
-	LeadTimeDays  int    `json:"leadTimeDays"`
-	Status        string `json:"status"`
-	Notes         string `json:"notes"`
+	Name             string `json:"name"`
+	ContactPerson    string `json:"contactPerson"`
+	Email            string `json:"email"`
+	Phone            string `json:"phone"`
+	Address          string `json:"address"`
+	LeadTimeDays     int    `json:"leadTimeDays"`
+	PaymentTermsDays int    `json:"paymentTermsDays"`
+	Status           string `json:"status"`
+	Notes            string `json:"notes"`
 }
 
 func (h *SuppliersHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -63,14 +64,15 @@ func (h *SuppliersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s := &models.Supplier{
-		Name:          strings.TrimSpace(in.Name),
-		ContactPerson: strings.TrimSpace(in.ContactPerson),
-		Email:         strings.TrimSpace(in.Email),
-		Phone:         strings.TrimSpace(in.Phone),
-		Address:       strings.TrimSpace(in.Address),
-		LeadTimeDays:  in.LeadTimeDays,
-		Status:        supplierStatusOrDefault(in.Status),
-		Notes:         strings.TrimSpace(in.Notes),
+		Name:             strings.TrimSpace(in.Name),
+		ContactPerson:    strings.TrimSpace(in.ContactPerson),
+		Email:            strings.TrimSpace(in.Email),
+		Phone:            strings.TrimSpace(in.Phone),
+		Address:          strings.TrimSpace(in.Address),
+		LeadTimeDays:     in.LeadTimeDays,
+		PaymentTermsDays: in.PaymentTermsDays,
+		Status:           supplierStatusOrDefault(in.Status),
+		Notes:            strings.TrimSpace(in.Notes),
 	}
 	if _, err := h.deps.DB.NewInsert().Model(s).Returning("*").Exec(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -101,6 +103,7 @@ func (h *SuppliersHandler) Update(w http.ResponseWriter, r *http.Request) {
 		Set("phone = ?", strings.TrimSpace(in.Phone)).
 		Set("address = ?", strings.TrimSpace(in.Address)).
 		Set("lead_time_days = ?", in.LeadTimeDays).
+		Set("payment_terms_days = ?", in.PaymentTermsDays).
 		Set("status = ?", supplierStatusOrDefault(in.Status)).
 		Set("notes = ?", strings.TrimSpace(in.Notes)).
 		Set("updated_at = current_timestamp").
@@ -146,6 +149,9 @@ func validateSupplierInput(in *supplierInput) string {
 	if in.LeadTimeDays < 0 {
 		return "lead time tidak boleh negatif"
 	}
+	if in.PaymentTermsDays < 0 {
+		return "termin pembayaran tidak boleh negatif"
+	}
 	return ""
 }
 
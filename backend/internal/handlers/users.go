@@ -31,6 +31,10 @@ type userInput struct {
 	Status   string      `json:"status"`
 	JoinedAt string      `json:"joinedAt"`
 	RoleIDs  []uuid.UUID `json:"roleIds"`
+	// StoreIDs replaces the store assignments when present; omitted keeps
+	// them on update and assigns the creator's active store on create.
+	StoreIDs       []uuid.UUID `json:"storeIds"`
+	DefaultStoreID *uuid.UUID  `json:"defaultStoreId"`
 }
 
 func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +52,10 @@ func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		users[i].RoleIDs = ids
+		if users[i].StoreIDs, users[i].DefaultStoreID, err = loadUserStores(r.Context(), h.deps.DB, users[i].ID); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	}
 	writeJSON(w, http.StatusOK, users)
 }
@@ -69,6 +77,10 @@ func (h *UsersHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user.RoleIDs = ids
+	if user.StoreIDs, user.DefaultStoreID, err = loadUserStores(r.Context(), h.deps.DB, user.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, user)
 }
 
@@ -109,6 +121,19 @@ func (h *UsersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user.RoleIDs = in.RoleIDs
+	if in.StoreIDs == nil {
+		if id, ok := activeStore(r.Context()); ok && id != uuid.Nil {
+			in.StoreIDs = []uuid.UUID{id}
+		}
+	}
+	if err := replaceUserStores(r.Context(), h.deps.DB, user.ID, in.StoreIDs, in.DefaultStoreID); err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if user.StoreIDs, user.DefaultStoreID, err = loadUserStores(r.Context(), h.deps.DB, user.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusCreated, user)
 }
 
@@ -167,6 +192,16 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user.RoleIDs = in.RoleIDs
+	if in.StoreIDs != nil {
+		if err := replaceUserStores(r.Context(), h.deps.DB, id, in.StoreIDs, in.DefaultStoreID); err != nil {
+			writeTxError(w, err)
+			return
+		}
+	}
+	if user.StoreIDs, user.DefaultStoreID, err = loadUserStores(r.Context(), h.deps.DB, id); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, user)
 }
 
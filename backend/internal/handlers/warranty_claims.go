@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sandisahdewo/pos/backend/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type WarrantyClaimsHandler struct {
+	deps Deps
+}
+
+func NewWarrantyClaimsHandler(deps Deps) *WarrantyClaimsHandler {
+	return &WarrantyClaimsHandler{deps: deps}
+}
+
+// List filters: status, supplierId.
+func (h *WarrantyClaimsHandler) List(w http.ResponseWriter, r *http.Request) {
+	items := []models.WarrantyClaim{}
+	q := h.deps.DB.NewSelect().Model(&items).Relation("Serial").Order("wc.created_at DESC")
+	if v := strings.TrimSpace(r.URL.Query().Get("status")); v != "" {
+		q = q.Where("wc.status = ?", v)
+	}
+	if v := strings.TrimSpace(r.URL.Query().Get("supplierId")); v != "" {
+		if _, err := uuid.Parse(v); err == nil {
+			q = q.Where("wc.supplier_id = ?", v)
+		}
+	}
+	if err := q.Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *WarrantyClaimsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var c models.WarrantyClaim
+	if err := h.deps.DB.NewSelect().Model(&c).Relation("Serial").
+		Where("wc.id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+type warrantyClaimInput struct {
+	SerialID   string  `json:"serialId"`
+	SupplierID *string `json:"supplierId,omitempty"`
+	Complaint  string  `json:"complaint"`
+	Notes      string  `json:"notes"`
+}
+
+// Create opens a claim for a sold (or customer-returned) unit and moves the
+// serial to `rma`. The supplier defaults to the one the unit's batch came
+// from.
+func (h *WarrantyClaimsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in warrantyClaimInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	serialID, err := uuid.Parse(in.SerialID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "serialId tidak valid")
+		return
+	}
+	if strings.TrimSpace(in.Complaint) == "" {
+		writeError(w, http.StatusBadRequest, "Keluhan wajib diisi.")
+		return
+	}
+	createdBy := actorName(r.Context(), h.deps.DB)
+
+	var c models.WarrantyClaim
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		var sn models.ProductSerial
+		if err := tx.NewSelect().Model(&sn).Where("id = ?", serialID).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if sn.Status != models.SerialStatusSold && sn.Status != models.SerialStatusReturned {
+			return errBadInput("Hanya unit yang sudah terjual yang bisa diklaim garansi.")
+		}
+		supplierID := optUUID(in.SupplierID)
+		if supplierID == nil && sn.BatchID != nil {
+			_ = tx.NewSelect().Table("batches").Column("supplier_id").
+				Where("id = ?", *sn.BatchID).Scan(ctx, &supplierID)
+		}
+		code, err := nextWarrantyClaimCode(ctx, tx)
+		if err != nil {
+			return err
+		}
+		c = models.WarrantyClaim{
+			Code:               code,
+			SerialID:           sn.ID,
+			SupplierID:         supplierID,
+			CustomerID:         sn.CustomerID,
+			Status:             models.WarrantyClaimOpen,
+			Complaint:          strings.TrimSpace(in.Complaint),
+			Notes:              strings.TrimSpace(in.Notes),
+			CreatedBy:          createdBy,
+			SerialStatusBefore: sn.Status,
+		}
+		if _, err := tx.NewInsert().Model(&c).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		return setSerialStatus(ctx, tx, sn.ID, models.SerialStatusRMA)
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+type warrantyClaimSendInput struct {
+	SupplierReference string `json:"supplierReference"`
+	Notes             string `json:"notes"`
+}
+
+// Send records the unit leaving for the supplier.
+func (h *WarrantyClaimsHandler) Send(w http.ResponseWriter, r *http.Request) {
+	var in warrantyClaimSendInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	h.transition(w, r, func(ctx context.Context, tx bun.Tx, c *models.WarrantyClaim) error {
+		if c.Status != models.WarrantyClaimOpen {
+			return errBadInput("Klaim sudah dikirim atau selesai.")
+		}
+		now := time.Now()
+		c.Status = models.WarrantyClaimSent
+		c.SentAt = &now
+		c.SupplierReference = strings.TrimSpace(in.SupplierReference)
+		c.Notes = appendNote(c.Notes, in.Notes)
+		return nil
+	})
+}
+
+type warrantyClaimResolveInput struct {
+	Resolution        string `json:"resolution"`
+	ReplacementSerial string `json:"replacementSerial"`
+	Notes             string `json:"notes"`
+}
+
+// Resolve closes a claim with the supplier's outcome. Repaired / rejected
+// units go back to the status they had when the claim opened; a replacement
+// registers the new serial on the same sale with the original warranty
+// expiry and swaps it into the order line's serials, while the old serial
+// stays `rma` and is detached from the sale.
+func (h *WarrantyClaimsHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	var in warrantyClaimResolveInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	h.transition(w, r, func(ctx context.Context, tx bun.Tx, c *models.WarrantyClaim) error {
+		if c.Status != models.WarrantyClaimOpen && c.Status != models.WarrantyClaimSent {
+			return errBadInput("Klaim sudah selesai.")
+		}
+		var sn models.ProductSerial
+		if err := tx.NewSelect().Model(&sn).Where("id = ?", c.SerialID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		switch in.Resolution {
+		case models.WarrantyResolutionRepaired, models.WarrantyResolutionRejected:
+			if err := restoreSerialAfterClaim(ctx, tx, c, &sn); err != nil {
+				return err
+			}
+		case models.WarrantyResolutionReplaced:
+			replacement := strings.TrimSpace(in.ReplacementSerial)
+			if replacement == "" {
+				return errBadInput("Nomor seri pengganti wajib diisi.")
+			}
+			exists, err := tx.NewSelect().Table("product_serials").
+				Where("product_id = ?", sn.ProductID).
+				Where("upper(serial) = upper(?)", replacement).Exists(ctx)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return errBadInput("Nomor seri pengganti sudah terdaftar.")
+			}
+			repl := models.ProductSerial{
+				Serial:            replacement,
+				ProductID:         sn.ProductID,
+				VariantID:         sn.VariantID,
+				BatchID:           sn.BatchID,
+				Status:            serialStatusBeforeClaim(c, &sn),
+				OrderID:           sn.OrderID,
+				OrderLineID:       sn.OrderLineID,
+				CustomerID:        sn.CustomerID,
+				SoldAt:            sn.SoldAt,
+				WarrantyExpiresAt: sn.WarrantyExpiresAt,
+			}
+			if _, err := tx.NewInsert().Model(&repl).Exec(ctx); err != nil {
+				return err
+			}
+			if sn.OrderLineID != nil {
+				// The order line lists serials by text; swap the new one in so
+				// the next order save keeps it linked.
+				if _, err := tx.NewUpdate().Table("order_lines").
+					Set(`serials = ARRAY(SELECT CASE WHEN upper(s) = upper(?) THEN ? ELSE s END
+						FROM unnest(serials) WITH ORDINALITY AS t(s, n) ORDER BY n)`, sn.Serial, replacement).
+					Where("id = ?", *sn.OrderLineID).
+					Exec(ctx); err != nil {
+					return err
+				}
+				if _, err := tx.NewUpdate().Table("product_serials").
+					Set("order_id = NULL, order_line_id = NULL").
+					Set("updated_at = current_timestamp").
+					Where("id = ?", sn.ID).
+					Exec(ctx); err != nil {
+					return err
+				}
+			}
+			c.ReplacementSerial = replacement
+		default:
+			return errBadInput("resolution harus repaired, replaced atau rejected")
+		}
+		now := time.Now()
+		c.Status = models.WarrantyClaimResolved
+		c.Resolution = in.Resolution
+		c.ResolvedAt = &now
+		c.Notes = appendNote(c.Notes, in.Notes)
+		return nil
+	})
+}
+
+// Cancel withdraws a claim that hasn't been resolved; the unit goes back to
+// where it was.
+func (h *WarrantyClaimsHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, func(ctx context.Context, tx bun.Tx, c *models.WarrantyClaim) error {
+		if c.Status != models.WarrantyClaimOpen && c.Status != models.WarrantyClaimSent {
+			return errBadInput("Klaim sudah selesai.")
+		}
+		var sn models.ProductSerial
+		if err := tx.NewSelect().Model(&sn).Where("id = ?", c.SerialID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if err := restoreSerialAfterClaim(ctx, tx, c, &sn); err != nil {
+			return err
+		}
+		c.Status = models.WarrantyClaimCancelled
+		return nil
+	})
+}
+
+// transition locks the claim, lets apply mutate it, then saves the mutable
+// columns and responds with the fresh row.
+func (h *WarrantyClaimsHandler) transition(
+	w http.ResponseWriter, r *http.Request,
+	apply func(ctx context.Context, tx bun.Tx, c *models.WarrantyClaim) error,
+) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var c models.WarrantyClaim
+	err = h.deps.DB.RunInTx(r.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(&c).Where("id = ?", id).For("UPDATE").Scan(ctx); err != nil {
+			return errNotFound
+		}
+		if err := apply(ctx, tx, &c); err != nil {
+			return err
+		}
+		_, err := tx.NewUpdate().Model(&c).
+			Column("status", "resolution", "replacement_serial", "supplier_reference", "sent_at", "resolved_at", "notes").
+			Set("updated_at = current_timestamp").
+			WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		writeTxError(w, err)
+		return
+	}
+	if err := h.deps.DB.NewSelect().Model(&c).Relation("Serial").
+		Where("wc.id = ?", id).Scan(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func restoreSerialAfterClaim(ctx context.Context, tx bun.Tx, c *models.WarrantyClaim, sn *models.ProductSerial) error {
+	return setSerialStatus(ctx, tx, sn.ID, serialStatusBeforeClaim(c, sn))
+}
+
+// serialStatusBeforeClaim is the status the unit had when the claim opened.
+// Claims opened before the status was recorded fall back to sold / in stock.
+func serialStatusBeforeClaim(c *models.WarrantyClaim, sn *models.ProductSerial) string {
+	if c.SerialStatusBefore != "" {
+		return c.SerialStatusBefore
+	}
+	if sn.OrderID != nil {
+		return models.SerialStatusSold
+	}
+	return models.SerialStatusInStock
+}
+
+func setSerialStatus(ctx context.Context, tx bun.Tx, id uuid.UUID, status string) error {
+	_, err := tx.NewUpdate().Table("product_serials").
+		Set("status = ?", status).
+		Set("updated_at = current_timestamp").
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+func appendNote(existing, extra string) string {
+	extra = strings.TrimSpace(extra)
+	if extra == "" {
+		return existing
+	}
+	if existing == "" {
+		return extra
+	}
+	return existing + "\n" + extra
+}
+
+func nextWarrantyClaimCode(ctx context.Context, tx bun.Tx) (string, error) {
+	year := time.Now().Year()
+	prefix := fmt.Sprintf("RMA-%d-", year)
+	var count int
+	if err := tx.NewSelect().Table("warranty_claims").
+		ColumnExpr("count(*)").
+		Where("code LIKE ?", prefix+"%").
+		Scan(ctx, &count); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, count+1), nil
+}
@@ -0,0 +1,50 @@
+// Package jobs runs periodic background work (expiry quarantine, snapshots,
+// scheduled price changes, …) inside the API process. There is no external
+// scheduler: each job ticks on its own goroutine and logs failures instead
+// of crashing the server. Jobs must be idempotent — every one also runs
+// once at boot to catch up after downtime.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start launches every job and returns immediately. Cancel ctx to stop them.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, j := range jobs {
+		go loop(ctx, j)
+	}
+}
+
+func loop(ctx context.Context, j Job) {
+	runOnce(ctx, j)
+	t := time.NewTicker(j.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			runOnce(ctx, j)
+		}
+	}
+}
+
+func runOnce(ctx context.Context, j Job) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("job %s: panic: %v", j.Name, rec)
+		}
+	}()
+	if err := j.Run(ctx); err != nil {
+		log.Printf("job %s: %v", j.Name, err)
+	}
+}
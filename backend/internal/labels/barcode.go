@@ -0,0 +1,157 @@
+package labels
+
+// Barcodes are encoded to a module sequence (true = bar) so the PDF renderer
+// can draw them; the ZPL renderer hands the data to the printer's own
+// encoder and only uses the module count to pick a bar width.
+
+// code128Patterns are the bar/space widths for symbol values 0-106
+// (103-105 are start A/B/C, 106 is stop).
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// encodeCode128 encodes data using subset C for even-length all-digit data
+// and subset B otherwise. Characters outside printable ASCII become '?'.
+func encodeCode128(data string) []bool {
+	var values []int
+	if len(data) >= 4 && len(data)%2 == 0 && allDigits(data) {
+		values = append(values, code128StartC)
+		for i := 0; i < len(data); i += 2 {
+			values = append(values, int(data[i]-'0')*10+int(data[i+1]-'0'))
+		}
+	} else {
+		values = append(values, code128StartB)
+		for _, r := range data {
+			if r < 32 || r > 126 {
+				r = '?'
+			}
+			values = append(values, int(r)-32)
+		}
+	}
+	sum := values[0]
+	for i, v := range values[1:] {
+		sum += (i + 1) * v
+	}
+	values = append(values, sum%103, code128Stop)
+
+	var out []bool
+	for _, v := range values {
+		bar := true
+		for _, w := range code128Patterns[v] {
+			for n := 0; n < int(w-'0'); n++ {
+				out = append(out, bar)
+			}
+			bar = !bar
+		}
+	}
+	return out
+}
+
+// ean13L are the left-hand odd-parity patterns; even parity (G) is the
+// reversed right-hand pattern and the right-hand pattern (R) is L inverted.
+var ean13L = [10]string{
+	"0001101", "0011001", "0010011", "0111101", "0100011",
+	"0110001", "0101111", "0111011", "0110111", "0001011",
+}
+
+// ean13Parity gives the L/G pattern of the left half per leading digit.
+var ean13Parity = [10]string{
+	"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+	"LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+}
+
+// EAN13CheckDigit computes the check digit for the first 12 digits.
+func EAN13CheckDigit(digits12 string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(digits12[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// normalizeEAN13 accepts 12 digits (check digit appended) or 13 digits with
+// a valid check digit and returns the full 13-digit code.
+func normalizeEAN13(data string) (string, bool) {
+	if !allDigits(data) {
+		return "", false
+	}
+	switch len(data) {
+	case 12:
+		return data + string(EAN13CheckDigit(data)), true
+	case 13:
+		return data, data[12] == EAN13CheckDigit(data[:12])
+	}
+	return "", false
+}
+
+func encodeEAN13(code string) []bool {
+	out := make([]bool, 0, 95)
+	put := func(pattern string) {
+		for _, c := range pattern {
+			out = append(out, c == '1')
+		}
+	}
+	right := func(d byte) string {
+		l := ean13L[d-'0']
+		b := []byte(l)
+		for i := range b {
+			if b[i] == '0' {
+				b[i] = '1'
+			} else {
+				b[i] = '0'
+			}
+		}
+		return string(b)
+	}
+	put("101")
+	parity := ean13Parity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		if parity[i-1] == 'L' {
+			put(ean13L[code[i]-'0'])
+		} else {
+			r := []byte(right(code[i]))
+			for a, b := 0, len(r)-1; a < b; a, b = a+1, b-1 {
+				r[a], r[b] = r[b], r[a]
+			}
+			put(string(r))
+		}
+	}
+	put("01010")
+	for i := 7; i <= 12; i++ {
+		put(right(code[i]))
+	}
+	put("101")
+	return out
+}
+
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+// Package labels renders shelf, batch and promo labels for printing: ZPL for
+// Zebra-compatible thermal printers (TSC and most others emulate it) and PDF
+// for everything else. Layouts are described in millimetres from the
+// label's top-left corner so one template prints the same on both.
+package labels
+
+import "strings"
+
+// Barcode symbologies a template can use.
+const (
+	SymbologyCode128 = "code128"
+	SymbologyEAN13   = "ean13"
+	SymbologyNone    = "none"
+)
+
+// Field places one text value on the label. Size is the text height in mm.
+// Prefix is printed before the value ("ED ", "BPOM "); a field whose value is
+// empty is skipped entirely, prefix included.
+type Field struct {
+	Key    string
+	Prefix string
+	X      float64
+	Y      float64
+	Size   float64
+	Bold   bool
+}
+
+// BarcodeBox is where the barcode goes. Bars are scaled to fill Width; the
+// human-readable line is printed under them, inside Height.
+type BarcodeBox struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+type Template struct {
+	WidthMM   float64
+	HeightMM  float64
+	DPI       int
+	Symbology string
+	Fields    []Field
+	// Nil means no barcode regardless of Symbology.
+	Barcode *BarcodeBox
+}
+
+// Label is one distinct label; Copies prints it that many times.
+type Label struct {
+	Values  map[string]string
+	Barcode string
+	Copies  int
+}
+
+// textLineMM is the height reserved under the bars for the human-readable
+// barcode line.
+const textLineMM = 3.0
+
+func (t Template) dpi() int {
+	if t.DPI <= 0 {
+		return 203
+	}
+	return t.DPI
+}
+
+func (l Label) copies() int {
+	if l.Copies < 1 {
+		return 1
+	}
+	return l.Copies
+}
+
+// text returns the rendered text of a field, or "" when it should be skipped.
+func (f Field) text(values map[string]string) string {
+	v := strings.TrimSpace(values[f.Key])
+	if v == "" {
+		return ""
+	}
+	return f.Prefix + v
+}
+
+// symbolFor picks the symbology actually used for data. EAN-13 needs 12 or
+// 13 digits with a valid check digit; anything else falls back to Code 128
+// so a label never fails to print over a non-retail code.
+func symbolFor(t Template, data string) string {
+	if t.Barcode == nil || data == "" || t.Symbology == SymbologyNone {
+		return SymbologyNone
+	}
+	if t.Symbology == SymbologyEAN13 {
+		if _, ok := normalizeEAN13(data); ok {
+			return SymbologyEAN13
+		}
+	}
+	return SymbologyCode128
+}
@@ -0,0 +1,83 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sandisahdewo/pos/backend/internal/pdf"
+)
+
+const ptPerMM = 72 / 25.4
+
+// PDF renders one page per printed label (copies included), each page the
+// size of the label. Text uses the standard Helvetica faces, so nothing is
+// embedded and the file stays small; characters outside Latin-1 print as '?'.
+func PDF(t Template, labels []Label) []byte {
+	w := t.WidthMM * ptPerMM
+	h := t.HeightMM * ptPerMM
+
+	var pages []string
+	for _, l := range labels {
+		content := pageContent(t, l, h)
+		for i := 0; i < l.copies(); i++ {
+			pages = append(pages, content)
+		}
+	}
+	return pdf.File(w, h, pages)
+}
+
+// pageContent draws one label. PDF's origin is bottom-left, templates are
+// top-left, hence the flips against the page height.
+func pageContent(t Template, l Label, pageH float64) string {
+	var c strings.Builder
+	for _, f := range t.Fields {
+		text := f.text(l.Values)
+		if text == "" {
+			continue
+		}
+		font := "F1"
+		if f.Bold {
+			font = "F2"
+		}
+		size := f.Size * ptPerMM
+		fmt.Fprintf(&c, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+			font, size, f.X*ptPerMM, pageH-(f.Y+f.Size)*ptPerMM, pdf.String(text))
+	}
+
+	box := t.Barcode
+	var modules []bool
+	human := l.Barcode
+	switch symbolFor(t, l.Barcode) {
+	case SymbologyEAN13:
+		human, _ = normalizeEAN13(l.Barcode)
+		modules = encodeEAN13(human)
+	case SymbologyCode128:
+		modules = encodeCode128(l.Barcode)
+	}
+	if len(modules) > 0 {
+		moduleW := box.Width / float64(len(modules)) * ptPerMM
+		barH := (box.Height - textLineMM) * ptPerMM
+		top := pageH - box.Y*ptPerMM
+		for i := 0; i < len(modules); {
+			if !modules[i] {
+				i++
+				continue
+			}
+			j := i
+			for j < len(modules) && modules[j] {
+				j++
+			}
+			fmt.Fprintf(&c, "%.3f %.3f %.3f %.3f re\n",
+				box.X*ptPerMM+float64(i)*moduleW, top-barH, float64(j-i)*moduleW, barH)
+			i = j
+		}
+		c.WriteString("f\n")
+		size := (textLineMM - 0.6) * ptPerMM
+		// Helvetica digits are 0.556 em wide; close enough to centre any code.
+		textW := float64(len(human)) * 0.556 * size
+		fmt.Fprintf(&c, "BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+			size, box.X*ptPerMM+(box.Width*ptPerMM-textW)/2, top-box.Height*ptPerMM+0.3*ptPerMM,
+			pdf.String(human))
+	}
+	return c.String()
+}
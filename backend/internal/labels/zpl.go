@@ -0,0 +1,69 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ZPL renders every label as its own ^XA…^XZ format with ^PQ copies, so the
+// printer handles repeats without the host resending data.
+func ZPL(t Template, labels []Label) []byte {
+	var b bytes.Buffer
+	dots := func(mm float64) int {
+		return int(math.Round(mm * float64(t.dpi()) / 25.4))
+	}
+	for _, l := range labels {
+		b.WriteString("^XA\n^CI28\n")
+		fmt.Fprintf(&b, "^PW%d\n^LL%d\n^LH0,0\n", dots(t.WidthMM), dots(t.HeightMM))
+		for _, f := range t.Fields {
+			text := f.text(l.Values)
+			if text == "" {
+				continue
+			}
+			h := dots(f.Size)
+			w := h
+			if f.Bold {
+				// Font 0 has no bold face; widen it a little instead.
+				w = h * 6 / 5
+			}
+			fmt.Fprintf(&b, "^FO%d,%d^A0N,%d,%d^FH_^FD%s^FS\n",
+				dots(f.X), dots(f.Y), h, w, zplEscape(text))
+		}
+		if box := t.Barcode; box != nil {
+			barH := dots(box.Height - textLineMM)
+			switch symbolFor(t, l.Barcode) {
+			case SymbologyEAN13:
+				code, _ := normalizeEAN13(l.Barcode)
+				fmt.Fprintf(&b, "^FO%d,%d^BY%d^BEN,%d,Y,N^FD%s^FS\n",
+					dots(box.X), dots(box.Y), moduleDots(t, box, 95), barH, code[:12])
+			case SymbologyCode128:
+				modules := len(encodeCode128(l.Barcode))
+				fmt.Fprintf(&b, "^FO%d,%d^BY%d^BCN,%d,Y,N,N,A^FH_^FD%s^FS\n",
+					dots(box.X), dots(box.Y), moduleDots(t, box, modules), barH, zplEscape(l.Barcode))
+			}
+		}
+		fmt.Fprintf(&b, "^PQ%d\n^XZ\n", l.copies())
+	}
+	return b.Bytes()
+}
+
+// moduleDots is the widest whole-dot bar width that fits the box; printers
+// can't render fractional dots, so the barcode may come out narrower.
+func moduleDots(t Template, box *BarcodeBox, modules int) int {
+	w := int(box.Width * float64(t.dpi()) / 25.4 / float64(modules))
+	if w < 1 {
+		return 1
+	}
+	if w > 10 {
+		return 10
+	}
+	return w
+}
+
+// zplEscape hex-escapes the characters ZPL treats as commands, for use with
+// ^FH_ (underscore as the escape indicator).
+func zplEscape(s string) string {
+	return strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E").Replace(s)
+}
@@ -0,0 +1,228 @@
+// Package mail sends outgoing email (purchase orders to suppliers, …) over
+// SMTP with net/smtp. Messages are multipart/mixed: a plain-text body plus
+// attachments. A local catcher such as Mailpit works as the server: no TLS
+// is required and auth is only attempted when a username is configured.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned by a nil Mailer, i.e. when SMTP_HOST is
+// unset.
+var ErrNotConfigured = errors.New("SMTP belum dikonfigurasi")
+
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Sender address, optionally with a display name:
+	// "Toko Maju <purchasing@tokomaju.id>".
+	From string
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Message struct {
+	To          []string
+	Cc          []string
+	ReplyTo     string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+type Mailer struct {
+	cfg  Config
+	from *mail.Address
+}
+
+// New returns nil when no host is configured; sending through a nil
+// Mailer fails with ErrNotConfigured.
+func New(cfg Config) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, nil
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP_FROM: %w", err)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &Mailer{cfg: cfg, from: from}, nil
+}
+
+// From is the configured sender as it appears in the From header.
+func (m *Mailer) From() string {
+	if m == nil {
+		return ""
+	}
+	return m.from.String()
+}
+
+// Send delivers msg to every To and Cc recipient. The whole SMTP
+// conversation is bounded by ctx (or 30 seconds when ctx has no deadline).
+func (m *Mailer) Send(ctx context.Context, msg Message) error {
+	if m == nil {
+		return ErrNotConfigured
+	}
+	rcpts := append(append([]string{}, msg.To...), msg.Cc...)
+	if len(rcpts) == 0 {
+		return errors.New("tidak ada penerima")
+	}
+	for i, r := range rcpts {
+		a, err := mail.ParseAddress(r)
+		if err != nil {
+			return fmt.Errorf("alamat %q tidak valid", r)
+		}
+		rcpts[i] = a.Address
+	}
+	body, err := m.build(msg)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	if m.cfg.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: m.cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if m.cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+			if err := c.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return err
+	}
+	for _, r := range rcpts {
+		if err := c.Rcpt(r); err != nil {
+			return err
+		}
+	}
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(body); err != nil {
+		wc.Close()
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (m *Mailer) build(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", m.from.String())
+	header("To", strings.Join(msg.To, ", "))
+	if len(msg.Cc) > 0 {
+		header("Cc", strings.Join(msg.Cc, ", "))
+	}
+	if msg.ReplyTo != "" {
+		header("Reply-To", msg.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%d.%s>", time.Now().UnixNano(), m.from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range msg.Attachments {
+		ct := a.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(ct, map[string]string{"name": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 76 {
+			if _, err := part.Write([]byte(enc[:76] + "\r\n")); err != nil {
+				return nil, err
+			}
+			enc = enc[76:]
+		}
+		if _, err := part.Write([]byte(enc + "\r\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	ABCClassA = "A"
+	ABCClassB = "B"
+	ABCClassC = "C"
+)
+
+// ProductABCClass is a product's latest ABC class by sales value.
+type ProductABCClass struct {
+	bun.BaseModel `bun:"table:product_abc_classes,alias:abc"`
+
+	ProductID     uuid.UUID `bun:"product_id,pk,type:uuid" json:"productId"`
+	Class         string    `bun:"abc_class,notnull" json:"class"`
+	SalesValue    float64   `bun:"sales_value,notnull,default:0" json:"salesValue"`
+	SharePct      float64   `bun:"share_pct,notnull,default:0" json:"sharePct"`
+	CumulativePct float64   `bun:"cumulative_pct,notnull,default:0" json:"cumulativePct"`
+	Rank          int       `bun:",notnull,default:0" json:"rank"`
+	ClassifiedAt  time.Time `bun:"classified_at,notnull,default:current_timestamp" json:"classifiedAt"`
+
+	ProductName string `bun:"product_name,scanonly" json:"productName,omitempty"`
+}
@@ -14,6 +14,14 @@ const (
 	BatchOwnershipConsignment BatchOwnership = "consignment"
 )
 
+type BatchStatus = string
+
+const (
+	BatchStatusActive      BatchStatus = "active"
+	BatchStatusQuarantined BatchStatus = "quarantined"
+	BatchStatusDisposed    BatchStatus = "disposed"
+)
+
 type Batch struct {
 	bun.BaseModel `bun:"table:batches,alias:bt"`
 
@@ -32,6 +40,20 @@ type Batch struct {
 	ExpiresAt                 string     `bun:"expires_at,notnull,default:''" json:"expiresAt"`
 	LocationID                uuid.UUID  `bun:"location_id,notnull" json:"locationId"`
 	Notes                     string     `bun:",notnull,default:''" json:"notes"`
+	Status                    string     `bun:",notnull,default:'active'" json:"status"`
+	QuarantinedAt             *time.Time `bun:"quarantined_at" json:"quarantinedAt,omitempty"`
 	CreatedAt                 time.Time  `bun:",notnull,default:current_timestamp" json:"-"`
 	UpdatedAt                 time.Time  `bun:",notnull,default:current_timestamp" json:"-"`
+
+	// Landed costs: ReceiptID is the PO receipt that created the batch,
+	// LandedUnitCost the part of UnitCost allocated from its charges (the
+	// supplier price is UnitCost - LandedUnitCost) and ReceivedWeight the
+	// weight recorded on receipt, used for weight-based allocation.
+	ReceiptID      *uuid.UUID `bun:"receipt_id" json:"receiptId,omitempty"`
+	LandedUnitCost float64    `bun:"landed_unit_cost,notnull,default:0" json:"landedUnitCost,omitempty"`
+	ReceivedWeight float64    `bun:"received_weight,notnull,default:0" json:"receivedWeight,omitempty"`
+
+	// Live (unexpired) stock_reservations against this batch. Only filled by
+	// read endpoints; available-to-sell = QtyRemaining - QtyReserved.
+	QtyReserved float64 `bun:"qty_reserved,scanonly" json:"qtyReserved"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// BatchRecall is a recall raised against a batch. BatchIDs are the batches
+// (the origin and its downstream stock) it quarantined; ReleasedBatchIDs
+// those of them since cleared and put back on sale.
+type BatchRecall struct {
+	bun.BaseModel `bun:"table:batch_recalls,alias:brc"`
+
+	ID               uuid.UUID   `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code             string      `bun:",notnull,unique" json:"code"`
+	BatchID          uuid.UUID   `bun:"batch_id,notnull" json:"batchId"`
+	Reason           string      `bun:",notnull,default:''" json:"reason"`
+	BatchIDs         []uuid.UUID `bun:"batch_ids,array,notnull,default:'{}'" json:"batchIds"`
+	ReleasedBatchIDs []uuid.UUID `bun:"released_batch_ids,array,notnull,default:'{}'" json:"releasedBatchIds"`
+	QtyOnHand        float64     `bun:"qty_on_hand,notnull,default:0" json:"qtyOnHand"`
+	PerformedBy      string      `bun:"performed_by,notnull,default:''" json:"performedBy"`
+	CreatedAt        time.Time   `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ConsignorReturn is the handover document for unsold consignment stock going
+// back to its consignor. Posting happens on creation, so a stored return has
+// always moved stock.
+type ConsignorReturn struct {
+	bun.BaseModel `bun:"table:consignor_returns,alias:cr"`
+
+	ID           uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code         string    `bun:",notnull,unique" json:"code"`
+	StoreID      uuid.UUID `bun:"store_id,type:uuid,notnull" json:"storeId"`
+	SupplierID   uuid.UUID `bun:"supplier_id,notnull" json:"supplierId"`
+	ReturnedAt   string    `bun:"returned_at,notnull,default:''" json:"returnedAt"`
+	HandedOverBy string    `bun:"handed_over_by,notnull,default:''" json:"handedOverBy"`
+	ReceivedBy   string    `bun:"received_by,notnull,default:''" json:"receivedBy"`
+	TotalQty     float64   `bun:"total_qty,notnull,default:0" json:"totalQty"`
+	TotalValue   float64   `bun:"total_value,notnull,default:0" json:"totalValue"`
+	Notes        string    `bun:",notnull,default:''" json:"notes"`
+	CreatedBy    string    `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt    time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt    time.Time `bun:",notnull,default:current_timestamp" json:"-"`
+
+	Lines []ConsignorReturnLine `bun:"rel:has-many,join:id=return_id" json:"lines"`
+}
+
+type ConsignorReturnLine struct {
+	bun.BaseModel `bun:"table:consignor_return_lines,alias:crl"`
+
+	ID         uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ReturnID   uuid.UUID  `bun:"return_id,notnull" json:"-"`
+	BatchID    uuid.UUID  `bun:"batch_id,notnull" json:"batchId"`
+	ProductID  uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	VariantID  *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	LocationID uuid.UUID  `bun:"location_id,notnull" json:"locationId"`
+	Qty        float64    `bun:"qty,notnull" json:"qty"`
+	UnitCost   float64    `bun:"unit_cost,notnull,default:0" json:"unitCost"`
+	Reason     string     `bun:",notnull,default:''" json:"reason"`
+	Position   int        `bun:",notnull,default:0" json:"-"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type LabelKind = string
+
+const (
+	LabelKindProduct   LabelKind = "product"
+	LabelKindBatch     LabelKind = "batch"
+	LabelKindPromotion LabelKind = "promotion"
+)
+
+// LabelField / LabelBarcodeBox are stored as JSONB on the template and
+// mirror labels.Field / labels.BarcodeBox. Positions are mm from top-left.
+type LabelField struct {
+	Key    string  `json:"key"`
+	Prefix string  `json:"prefix,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Size   float64 `json:"size"`
+	Bold   bool    `json:"bold,omitempty"`
+}
+
+type LabelBarcodeBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type LabelTemplate struct {
+	bun.BaseModel `bun:"table:label_templates,alias:lt"`
+
+	ID        uuid.UUID        `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Name      string           `bun:",notnull" json:"name"`
+	Kind      string           `bun:",notnull" json:"kind"`
+	WidthMM   float64          `bun:"width_mm,notnull" json:"widthMm"`
+	HeightMM  float64          `bun:"height_mm,notnull" json:"heightMm"`
+	DPI       int              `bun:"dpi,notnull,default:203" json:"dpi"`
+	Symbology string           `bun:",notnull,default:'code128'" json:"symbology"`
+	Fields    []LabelField     `bun:"fields,type:jsonb,notnull,default:'[]'" json:"fields"`
+	Barcode   *LabelBarcodeBox `bun:"barcode,type:jsonb" json:"barcode,omitempty"`
+	IsDefault bool             `bun:"is_default,notnull,default:false" json:"isDefault"`
+	CreatedAt time.Time        `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt time.Time        `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+}
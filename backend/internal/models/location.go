@@ -26,6 +26,7 @@ type Location struct {
 	bun.BaseModel `bun:"table:locations,alias:l"`
 
 	ID               uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	StoreID          uuid.UUID `bun:"store_id,type:uuid,notnull" json:"storeId"`
 	Name             string    `bun:",notnull" json:"name"`
 	Slug             string    `bun:",notnull,unique" json:"slug"`
 	Kind             string    `bun:",notnull,default:'shelf'" json:"kind"`
@@ -27,7 +27,7 @@ const (
 // OrderLineExtra is one extra picked at sale time. Snapshotted as JSONB on
 // the line — never queried directly.
 type OrderLineExtra struct {
-	ID         string  `json:"id"`
+	ID         string  `json:"extraId"`
 	Name       string  `json:"name"`
 	PriceDelta float64 `json:"priceDelta"`
 }
@@ -78,6 +78,7 @@ type OrderLine struct {
 	LineTax            float64           `bun:"line_tax,notnull,default:0" json:"lineTax"`
 	LineTotal          float64           `bun:"line_total,notnull,default:0" json:"lineTotal"`
 	BatchAllocations   []BatchAllocation `bun:"batch_allocations,type:jsonb,notnull,default:'[]'" json:"batchAllocations"`
+	Serials            []string          `bun:"serials,array,notnull,default:'{}'" json:"serials"`
 	Position           int               `bun:",notnull,default:0" json:"-"`
 }
 
@@ -97,6 +98,7 @@ type Order struct {
 
 	ID             uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
 	Code           string     `bun:",notnull,unique" json:"code"`
+	StoreID        uuid.UUID  `bun:"store_id,type:uuid,notnull" json:"storeId"`
 	PricelistID    *string    `bun:"pricelist_id" json:"pricelistId,omitempty"`
 	CustomerID     *uuid.UUID `bun:"customer_id" json:"customerId,omitempty"`
 	EmployeeID     *uuid.UUID `bun:"employee_id" json:"employeeId,omitempty"`
@@ -119,6 +121,10 @@ type Order struct {
 	// API-only: filled from child tables.
 	Lines    []OrderLine    `bun:"-" json:"lines"`
 	Payments []OrderPayment `bun:"-" json:"payments"`
+
+	// Request-only: the parked cart this order was charged from. Its stock
+	// reservations are released in the same transaction as the insert.
+	CartSessionID string `bun:"-" json:"cartSessionId,omitempty"`
 }
 
 func (o *Order) EnsureSlices() {
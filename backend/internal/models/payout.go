@@ -12,6 +12,7 @@ type Payout struct {
 
 	ID                  uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
 	Code                string    `bun:",notnull,unique" json:"code"`
+	StoreID             uuid.UUID `bun:"store_id,type:uuid,notnull" json:"storeId"`
 	SupplierID          uuid.UUID `bun:"supplier_id,notnull" json:"supplierId"`
 	Amount              float64   `bun:"amount,notnull" json:"amount"`
 	PaidAt              string    `bun:"paid_at,notnull,default:''" json:"paidAt"`
@@ -19,6 +20,29 @@ type Payout struct {
 	CoversPeriodStart   string    `bun:"covers_period_start,notnull,default:''" json:"coversPeriodStart"`
 	CoversPeriodEnd     string    `bun:"covers_period_end,notnull,default:''" json:"coversPeriodEnd"`
 	Notes               string    `bun:",notnull,default:''" json:"notes"`
+	// Settlement totals: consignment units sold and owed for the period
+	// (the allocations below), plus consignor returns in it for reference.
+	SoldUnits           float64   `bun:"sold_units,notnull,default:0" json:"soldUnits"`
+	SettledAmount       float64   `bun:"settled_amount,notnull,default:0" json:"settledAmount"`
+	ReturnedUnits       float64   `bun:"returned_units,notnull,default:0" json:"returnedUnits"`
+	ReturnedValue       float64   `bun:"returned_value,notnull,default:0" json:"returnedValue"`
+	CreatedBy           string    `bun:"created_by,notnull,default:''" json:"createdBy"`
 	CreatedAt           time.Time `bun:",notnull,default:current_timestamp" json:"-"`
 	UpdatedAt           time.Time `bun:",notnull,default:current_timestamp" json:"-"`
 }
+
+// PayoutAllocation is one consignment allocation (order line × batch) a
+// payout settled. An allocation can be settled once.
+type PayoutAllocation struct {
+	bun.BaseModel `bun:"table:payout_allocations,alias:pya"`
+
+	ID          uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PayoutID    uuid.UUID `bun:"payout_id,notnull" json:"payoutId"`
+	OrderID     uuid.UUID `bun:"order_id,notnull" json:"orderId"`
+	OrderLineID uuid.UUID `bun:"order_line_id,notnull" json:"orderLineId"`
+	BatchID     uuid.UUID `bun:"batch_id,notnull" json:"batchId"`
+	Qty         float64   `bun:",notnull" json:"qty"`
+	UnitCost    float64   `bun:"unit_cost,notnull,default:0" json:"unitCost"`
+	Amount      float64   `bun:",notnull,default:0" json:"amount"`
+	SoldAt      string    `bun:"sold_at,notnull,default:''" json:"soldAt"`
+}
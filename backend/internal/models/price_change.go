@@ -8,6 +8,10 @@ import (
 	"github.com/uptrace/bun"
 )
 
+// PriceChangeScheduled is the source of rows written when a price
+// schedule takes effect.
+const PriceChangeScheduled = "scheduled"
+
 type PriceChange struct {
 	bun.BaseModel `bun:"table:price_changes,alias:pc"`
 
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Price schedule statuses.
+const (
+	PriceSchedulePending   = "pending"
+	PriceScheduleApplied   = "applied"
+	PriceScheduleCancelled = "cancelled"
+	PriceScheduleFailed    = "failed"
+)
+
+// Outcome of one scheduled item once its schedule has run.
+const (
+	PriceScheduleItemApplied = "applied"
+	PriceScheduleItemSkipped = "skipped"
+)
+
+// PriceSchedule is a batch of pricelist entries that take effect at
+// EffectiveAt. ItemCount is filled on list reads. A schedule the job
+// could not apply is left failed with the error in FailureNote.
+type PriceSchedule struct {
+	bun.BaseModel `bun:"table:price_schedules,alias:psch"`
+
+	ID           uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code         string     `bun:",notnull,unique" json:"code"`
+	EffectiveAt  time.Time  `bun:"effective_at,notnull" json:"effectiveAt"`
+	Status       string     `bun:",notnull,default:'pending'" json:"status"`
+	Notes        string     `bun:",notnull,default:''" json:"notes"`
+	ItemsApplied int        `bun:"items_applied,notnull,default:0" json:"itemsApplied"`
+	ItemsSkipped int        `bun:"items_skipped,notnull,default:0" json:"itemsSkipped"`
+	AppliedAt    *time.Time `bun:"applied_at" json:"appliedAt,omitempty"`
+	CancelledBy  string     `bun:"cancelled_by,notnull,default:''" json:"cancelledBy,omitempty"`
+	CancelledAt  *time.Time `bun:"cancelled_at" json:"cancelledAt,omitempty"`
+	FailureNote  string     `bun:"failure_note,notnull,default:''" json:"failureNote,omitempty"`
+	FailedAt     *time.Time `bun:"failed_at" json:"failedAt,omitempty"`
+	CreatedBy    string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt    time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+
+	ItemCount int                 `bun:"item_count,scanonly" json:"itemCount"`
+	Items     []PriceScheduleItem `bun:"-" json:"items"`
+}
+
+// PriceScheduleItem is the new pricelist entry for one product, variant
+// or packaging (by position, with the unit and factor it had when
+// scheduled). The preview fields describe the entry it replaces and are
+// computed on read, never stored.
+type PriceScheduleItem struct {
+	bun.BaseModel `bun:"table:price_schedule_items,alias:psi"`
+
+	ID              uuid.UUID     `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ScheduleID      uuid.UUID     `bun:"schedule_id,notnull" json:"-"`
+	ProductID       uuid.UUID     `bun:"product_id,notnull" json:"productId"`
+	VariantID       *uuid.UUID    `bun:"variant_id" json:"variantId,omitempty"`
+	PackagingIndex  *int          `bun:"packaging_index" json:"packagingIndex,omitempty"`
+	PackagingUnit   *string       `bun:"packaging_unit_id" json:"-"`
+	PackagingFactor *float64      `bun:"packaging_factor" json:"-"`
+	PricelistID     string        `bun:"pricelist_id,notnull" json:"pricelistId"`
+	PricingKind     string        `bun:"pricing_kind,notnull" json:"-"`
+	PricingValue    float64       `bun:"pricing_value,notnull,default:0" json:"-"`
+	Tiers           []PricingTier `bun:"tiers,type:jsonb,notnull,default:'[]'" json:"tiers"`
+	Result          string        `bun:",notnull,default:''" json:"result"`
+	ResultNote      string        `bun:"result_note,notnull,default:''" json:"resultNote,omitempty"`
+	Position        int           `bun:",notnull,default:0" json:"-"`
+
+	Pricing        PricingStrategy  `bun:"-" json:"pricing"`
+	ProductName    string           `bun:"-" json:"productName"`
+	VariantName    string           `bun:"-" json:"variantName,omitempty"`
+	PackagingLabel string           `bun:"-" json:"packagingLabel,omitempty"`
+	PricelistName  string           `bun:"-" json:"pricelistName"`
+	Current        *PricingStrategy `bun:"-" json:"current,omitempty"`
+	Cost           float64          `bun:"-" json:"cost"`
+	CurrentSale    float64          `bun:"-" json:"currentSale"`
+	NewSale        float64          `bun:"-" json:"newSale"`
+}
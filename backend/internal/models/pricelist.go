@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
@@ -11,10 +12,11 @@ import (
 type Pricelist struct {
 	bun.BaseModel `bun:"table:pricelists,alias:pl"`
 
-	ID          string    `bun:",pk" json:"id"`
-	Name        string    `bun:",notnull" json:"name"`
-	Description string    `bun:",notnull,default:''" json:"description"`
-	IsDefault   bool      `bun:"is_default,notnull,default:false" json:"isDefault"`
-	CreatedAt   time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
-	UpdatedAt   time.Time `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+	ID          string     `bun:",pk" json:"id"`
+	Name        string     `bun:",notnull" json:"name"`
+	Description string     `bun:",notnull,default:''" json:"description"`
+	IsDefault   bool       `bun:"is_default,notnull,default:false" json:"isDefault"`
+	StoreID     *uuid.UUID `bun:"store_id,type:uuid" json:"storeId,omitempty"`
+	CreatedAt   time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt   time.Time  `bun:",notnull,default:current_timestamp" json:"updatedAt"`
 }
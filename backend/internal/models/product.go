@@ -131,6 +131,7 @@ type Product struct {
 
 	RequiresBatchLabel              bool    `bun:"requires_batch_label,notnull,default:false" json:"requiresBatchLabel,omitempty"`
 	RequiresExpiration              bool    `bun:"requires_expiration,notnull,default:false" json:"requiresExpiration,omitempty"`
+	SerialTracked                   bool    `bun:"serial_tracked,notnull,default:false" json:"serialTracked,omitempty"`
 	BPOMNumber                      string  `bun:"bpom_number,notnull,default:''" json:"bpomNumber,omitempty"`
 	HalalCertNumber                 string  `bun:"halal_cert_number,notnull,default:''" json:"halalCertNumber,omitempty"`
 	WarrantyMonths                  *int    `bun:"warranty_months" json:"warrantyMonths,omitempty"`
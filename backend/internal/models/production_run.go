@@ -26,9 +26,17 @@ type ProductionRun struct {
 	CreatedAt        time.Time                   `bun:",notnull,default:current_timestamp" json:"createdAt"`
 	UpdatedAt        time.Time                   `bun:",notnull,default:current_timestamp" json:"-"`
 
+	// Planned-but-not-produced output. Only set by the server-executed flow.
+	YieldLossQty   float64 `bun:"yield_loss_qty,notnull,default:0" json:"yieldLossQty"`
+	YieldLossValue float64 `bun:"yield_loss_value,notnull,default:0" json:"yieldLossValue"`
+
 	Consumptions []ProductionRunConsumption `bun:"rel:has-many,join:id=production_run_id" json:"componentConsumptions"`
 }
 
+const (
+	ProductionRunStatusCompleted = "completed"
+)
+
 type ProductionRunConsumption struct {
 	bun.BaseModel `bun:"table:production_run_consumptions,alias:prc"`
 
@@ -17,11 +17,26 @@ const (
 type PurchaseOrderStatus = string
 
 const (
-	POStatusDraft     PurchaseOrderStatus = "draft"
-	POStatusSent      PurchaseOrderStatus = "sent"
-	POStatusPartial   PurchaseOrderStatus = "partial"
-	POStatusReceived  PurchaseOrderStatus = "received"
-	POStatusCancelled PurchaseOrderStatus = "cancelled"
+	POStatusDraft           PurchaseOrderStatus = "draft"
+	POStatusPendingApproval PurchaseOrderStatus = "pending-approval"
+	POStatusApproved        PurchaseOrderStatus = "approved"
+	POStatusSent            PurchaseOrderStatus = "sent"
+	POStatusPartial         PurchaseOrderStatus = "partial"
+	POStatusReceived        PurchaseOrderStatus = "received"
+	POStatusClosed          PurchaseOrderStatus = "closed"
+	POStatusCancelled       PurchaseOrderStatus = "cancelled"
+)
+
+// PO transition actions, as logged in purchase_order_transitions.
+const (
+	POActionCreate  = "create"
+	POActionSubmit  = "submit"
+	POActionApprove = "approve"
+	POActionReject  = "reject"
+	POActionSend    = "send"
+	POActionReceive = "receive"
+	POActionClose   = "close"
+	POActionCancel  = "cancel"
 )
 
 type PurchaseOrderPaymentMethod = string
@@ -59,6 +74,9 @@ type PurchaseOrderPayment struct {
 	Method          string    `bun:",notnull,default:'cash'" json:"method"`
 	PaidAt          time.Time `bun:"paid_at,notnull,default:current_timestamp" json:"at"`
 	Notes           string    `bun:",notnull,default:''" json:"notes"`
+	// The supplier payment this is an allocation of; nil for payments
+	// recorded before supplier payments existed.
+	SupplierPaymentID *uuid.UUID `bun:"supplier_payment_id" json:"supplierPaymentId,omitempty"`
 }
 
 type PurchaseOrder struct {
@@ -66,6 +84,7 @@ type PurchaseOrder struct {
 
 	ID           uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
 	Code         string    `bun:",notnull,unique" json:"code"`
+	StoreID      uuid.UUID `bun:"store_id,type:uuid,notnull" json:"storeId"`
 	Type         string    `bun:",notnull,default:'standard'" json:"type"`
 	SupplierID   uuid.UUID `bun:"supplier_id,notnull" json:"supplierId"`
 	Status       string    `bun:",notnull,default:'draft'" json:"status"`
@@ -74,7 +93,13 @@ type PurchaseOrder struct {
 	OrderDate    string `bun:"order_date,notnull,default:''" json:"orderDate"`
 	ExpectedDate string `bun:"expected_date,notnull,default:''" json:"expectedDate"`
 	ReceivedDate string `bun:"received_date,notnull,default:''" json:"receivedDate"`
+	// First receipt date plus the supplier's payment terms; set
+	// server-side, "" until something is received.
+	DueDate      string `bun:"due_date,notnull,default:''" json:"dueDate"`
 	PaidAmount   float64   `bun:"paid_amount,notnull,default:0" json:"paidAmount"`
+	// Sum of debit notes from purchase returns against this PO; maintained
+	// server-side and subtracted from what is owed.
+	DebitNoteAmount float64 `bun:"debit_note_amount,notnull,default:0" json:"debitNoteAmount"`
 	Notes        string    `bun:",notnull,default:''" json:"notes"`
 	CreatedAt    time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
 	UpdatedAt    time.Time `bun:",notnull,default:current_timestamp" json:"updatedAt"`
@@ -92,3 +117,19 @@ func (p *PurchaseOrder) EnsureSlices() {
 		p.Payments = []PurchaseOrderPayment{}
 	}
 }
+
+// PurchaseOrderTransition is one status change on a PO, with who did it.
+type PurchaseOrderTransition struct {
+	bun.BaseModel `bun:"table:purchase_order_transitions,alias:pot"`
+
+	ID              uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PurchaseOrderID uuid.UUID  `bun:"purchase_order_id,notnull" json:"purchaseOrderId"`
+	Action          string     `bun:",notnull" json:"action"`
+	FromStatus      string     `bun:"from_status,notnull,default:''" json:"fromStatus"`
+	ToStatus        string     `bun:"to_status,notnull" json:"toStatus"`
+	POTotal         float64    `bun:"po_total,notnull,default:0" json:"poTotal"`
+	Actor           string     `bun:",notnull,default:''" json:"actor"`
+	ActorID         *uuid.UUID `bun:"actor_id" json:"actorId,omitempty"`
+	Notes           string     `bun:",notnull,default:''" json:"notes"`
+	CreatedAt       time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
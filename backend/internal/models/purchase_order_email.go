@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Outcome of emailing a PO.
+const (
+	POEmailSent   = "sent"
+	POEmailFailed = "failed"
+)
+
+// PurchaseOrderEmail is one attempt to email a PO's PDF to its supplier.
+// Error holds the SMTP failure when Status is failed.
+type PurchaseOrderEmail struct {
+	bun.BaseModel `bun:"table:purchase_order_emails,alias:poe"`
+
+	ID              uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PurchaseOrderID uuid.UUID  `bun:"purchase_order_id,notnull" json:"purchaseOrderId"`
+	Sender          string     `bun:",notnull,default:''" json:"sender"`
+	Recipients      []string   `bun:",array,notnull,default:'{}'" json:"recipients"`
+	Cc              []string   `bun:",array,notnull,default:'{}'" json:"cc"`
+	Subject         string     `bun:",notnull,default:''" json:"subject"`
+	Message         string     `bun:",notnull,default:''" json:"message"`
+	Attachment      string     `bun:",notnull,default:''" json:"attachment"`
+	Status          string     `bun:",notnull" json:"status"`
+	Error           string     `bun:",notnull,default:''" json:"error,omitempty"`
+	SentBy          string     `bun:"sent_by,notnull,default:''" json:"sentBy"`
+	SentByID        *uuid.UUID `bun:"sent_by_id" json:"sentById,omitempty"`
+	CreatedAt       time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
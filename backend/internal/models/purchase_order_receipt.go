@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Landed cost allocation bases.
+const (
+	AllocateByValue  = "value"
+	AllocateByQty    = "qty"
+	AllocateByWeight = "weight"
+)
+
+// LandedCostKinds is the closed set of charge types.
+var LandedCostKinds = []string{"freight", "unloading", "duty", "insurance", "other"}
+
+// PurchaseOrderReceipt is one receiving event on a PO. Batches created by it
+// carry its id; landed cost charges are booked against it.
+type PurchaseOrderReceipt struct {
+	bun.BaseModel `bun:"table:purchase_order_receipts,alias:por"`
+
+	ID               uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code             string    `bun:",notnull,unique" json:"code"`
+	PurchaseOrderID  uuid.UUID `bun:"purchase_order_id,notnull" json:"purchaseOrderId"`
+	ReceivedDate     string    `bun:"received_date,notnull,default:''" json:"receivedDate"`
+	AllocationMethod string    `bun:"allocation_method,notnull,default:'value'" json:"allocationMethod"`
+	LandedCostTotal  float64   `bun:"landed_cost_total,notnull,default:0" json:"landedCostTotal"`
+	Notes            string    `bun:",notnull,default:''" json:"notes"`
+	PerformedBy      string    `bun:"performed_by,notnull,default:''" json:"performedBy"`
+	CreatedAt        time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
+
+	LandedCosts []PurchaseOrderLandedCost `bun:"rel:has-many,join:id=receipt_id" json:"landedCosts"`
+	Batches     []Batch                   `bun:"rel:has-many,join:id=receipt_id" json:"batches"`
+}
+
+// PurchaseOrderLandedCost is one charge (freight, duty, ...) on a receipt.
+type PurchaseOrderLandedCost struct {
+	bun.BaseModel `bun:"table:purchase_order_landed_costs,alias:plc"`
+
+	ID         uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ReceiptID  uuid.UUID  `bun:"receipt_id,notnull" json:"receiptId"`
+	Kind       string     `bun:",notnull" json:"kind"`
+	Amount     float64    `bun:",notnull" json:"amount"`
+	SupplierID *uuid.UUID `bun:"supplier_id" json:"supplierId,omitempty"`
+	Reference  string     `bun:",notnull,default:''" json:"reference"`
+	Notes      string     `bun:",notnull,default:''" json:"notes"`
+	CreatedBy  string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt  time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+
+	Allocations []LandedCostAllocation `bun:"rel:has-many,join:id=landed_cost_id" json:"allocations"`
+}
+
+// LandedCostAllocation is a charge's share on one batch.
+type LandedCostAllocation struct {
+	bun.BaseModel `bun:"table:landed_cost_allocations,alias:lca"`
+
+	ID           uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	LandedCostID uuid.UUID `bun:"landed_cost_id,notnull" json:"landedCostId"`
+	BatchID      uuid.UUID `bun:"batch_id,notnull" json:"batchId"`
+	Basis        float64   `bun:",notnull,default:0" json:"basis"`
+	Amount       float64   `bun:",notnull,default:0" json:"amount"`
+	UnitAmount   float64   `bun:"unit_amount,notnull,default:0" json:"unitAmount"`
+}
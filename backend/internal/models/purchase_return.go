@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Reasons a purchase return line can carry.
+const (
+	PurchaseReturnDamaged   = "damaged"
+	PurchaseReturnExpired   = "expired"
+	PurchaseReturnDefective = "defective"
+	PurchaseReturnWrongItem = "wrong-item"
+	PurchaseReturnOther     = "other"
+)
+
+var PurchaseReturnReasons = []string{
+	PurchaseReturnDamaged, PurchaseReturnExpired, PurchaseReturnDefective,
+	PurchaseReturnWrongItem, PurchaseReturnOther,
+}
+
+// PurchaseReturn sends owned stock back to its supplier for credit. Like
+// ConsignorReturn it is posted on creation; DebitNote is the credit it
+// raised against the supplier.
+type PurchaseReturn struct {
+	bun.BaseModel `bun:"table:purchase_returns,alias:pr"`
+
+	ID              uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code            string     `bun:",notnull,unique" json:"code"`
+	StoreID         uuid.UUID  `bun:"store_id,type:uuid,notnull" json:"storeId"`
+	SupplierID      uuid.UUID  `bun:"supplier_id,notnull" json:"supplierId"`
+	PurchaseOrderID *uuid.UUID `bun:"purchase_order_id" json:"purchaseOrderId,omitempty"`
+	ReturnedAt      string     `bun:"returned_at,notnull,default:''" json:"returnedAt"`
+	TotalQty        float64    `bun:"total_qty,notnull,default:0" json:"totalQty"`
+	TotalValue      float64    `bun:"total_value,notnull,default:0" json:"totalValue"`
+	Notes           string     `bun:",notnull,default:''" json:"notes"`
+	CreatedBy       string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt       time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt       time.Time  `bun:",notnull,default:current_timestamp" json:"-"`
+
+	Lines     []PurchaseReturnLine `bun:"rel:has-many,join:id=return_id" json:"lines"`
+	DebitNote *SupplierDebitNote   `bun:"rel:has-one,join:id=return_id" json:"debitNote,omitempty"`
+}
+
+// PurchaseReturnLine is one batch sent back. UnitCost is the supplier price
+// (batch cost less landed charges), which is what the debit note credits.
+type PurchaseReturnLine struct {
+	bun.BaseModel `bun:"table:purchase_return_lines,alias:prl"`
+
+	ID         uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ReturnID   uuid.UUID  `bun:"return_id,notnull" json:"-"`
+	BatchID    uuid.UUID  `bun:"batch_id,notnull" json:"batchId"`
+	ProductID  uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	VariantID  *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	LocationID uuid.UUID  `bun:"location_id,notnull" json:"locationId"`
+	Qty        float64    `bun:"qty,notnull" json:"qty"`
+	UnitCost   float64    `bun:"unit_cost,notnull,default:0" json:"unitCost"`
+	Reason     string     `bun:",notnull,default:'damaged'" json:"reason"`
+	Notes      string     `bun:",notnull,default:''" json:"notes"`
+	Position   int        `bun:",notnull,default:0" json:"-"`
+}
+
+// SupplierDebitNote is the credit a purchase return raises against the
+// supplier. Tied to a PO it lowers that PO's outstanding payable.
+type SupplierDebitNote struct {
+	bun.BaseModel `bun:"table:supplier_debit_notes,alias:sdn"`
+
+	ID              uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code            string     `bun:",notnull,unique" json:"code"`
+	SupplierID      uuid.UUID  `bun:"supplier_id,notnull" json:"supplierId"`
+	PurchaseOrderID *uuid.UUID `bun:"purchase_order_id" json:"purchaseOrderId,omitempty"`
+	ReturnID        uuid.UUID  `bun:"return_id,notnull" json:"returnId"`
+	Amount          float64    `bun:",notnull" json:"amount"`
+	IssuedAt        string     `bun:"issued_at,notnull,default:''" json:"issuedAt"`
+	CreatedAt       time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type SerialStatus = string
+
+const (
+	SerialStatusInStock  SerialStatus = "in_stock"
+	SerialStatusSold     SerialStatus = "sold"
+	SerialStatusReturned SerialStatus = "returned"
+	SerialStatusRMA      SerialStatus = "rma"
+)
+
+type ProductSerial struct {
+	bun.BaseModel `bun:"table:product_serials,alias:psr"`
+
+	ID                uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Serial            string     `bun:",notnull" json:"serial"`
+	ProductID         uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	VariantID         *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	BatchID           *uuid.UUID `bun:"batch_id" json:"batchId,omitempty"`
+	Status            string     `bun:",notnull,default:'in_stock'" json:"status"`
+	OrderID           *uuid.UUID `bun:"order_id" json:"orderId,omitempty"`
+	OrderLineID       *uuid.UUID `bun:"order_line_id" json:"orderLineId,omitempty"`
+	CustomerID        *uuid.UUID `bun:"customer_id" json:"customerId,omitempty"`
+	SoldAt            *time.Time `bun:"sold_at" json:"soldAt,omitempty"`
+	WarrantyExpiresAt string     `bun:"warranty_expires_at,notnull,default:''" json:"warrantyExpiresAt,omitempty"`
+	CreatedAt         time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt         time.Time  `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+type WarrantyClaimStatus = string
+
+const (
+	WarrantyClaimOpen      WarrantyClaimStatus = "open"
+	WarrantyClaimSent      WarrantyClaimStatus = "sent"
+	WarrantyClaimResolved  WarrantyClaimStatus = "resolved"
+	WarrantyClaimCancelled WarrantyClaimStatus = "cancelled"
+)
+
+// Warranty claim outcomes, stored in WarrantyClaim.Resolution.
+const (
+	WarrantyResolutionRepaired = "repaired"
+	WarrantyResolutionReplaced = "replaced"
+	WarrantyResolutionRejected = "rejected"
+)
+
+type WarrantyClaim struct {
+	bun.BaseModel `bun:"table:warranty_claims,alias:wc"`
+
+	ID                uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code              string     `bun:",notnull,unique" json:"code"`
+	SerialID          uuid.UUID  `bun:"serial_id,notnull" json:"serialId"`
+	SupplierID        *uuid.UUID `bun:"supplier_id" json:"supplierId,omitempty"`
+	CustomerID        *uuid.UUID `bun:"customer_id" json:"customerId,omitempty"`
+	Status            string     `bun:",notnull,default:'open'" json:"status"`
+	Complaint         string     `bun:",notnull,default:''" json:"complaint"`
+	Resolution        string     `bun:",notnull,default:''" json:"resolution,omitempty"`
+	ReplacementSerial string     `bun:"replacement_serial,notnull,default:''" json:"replacementSerial,omitempty"`
+	SupplierReference string     `bun:"supplier_reference,notnull,default:''" json:"supplierReference,omitempty"`
+	SentAt            *time.Time `bun:"sent_at" json:"sentAt,omitempty"`
+	ResolvedAt        *time.Time `bun:"resolved_at" json:"resolvedAt,omitempty"`
+	Notes             string     `bun:",notnull,default:''" json:"notes"`
+	CreatedBy         string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt         time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt         time.Time  `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+
+	// SerialStatusBefore is the serial's status when the claim opened; the
+	// unit returns to it unless the claim ends in a replacement.
+	SerialStatusBefore string `bun:"serial_status_before,notnull,default:''" json:"serialStatusBefore,omitempty"`
+
+	Serial *ProductSerial `bun:"rel:belongs-to,join:serial_id=id" json:"serial,omitempty"`
+}
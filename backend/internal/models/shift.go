@@ -71,6 +71,8 @@ type ShiftSession struct {
 
 	ID                  uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
 	Code                string     `bun:",notnull,unique" json:"code"`
+	StoreID             uuid.UUID  `bun:"store_id,type:uuid,notnull" json:"storeId"`
+	RegisterID          *uuid.UUID `bun:"register_id" json:"registerId,omitempty"`
 	EmployeeID          uuid.UUID  `bun:"employee_id,notnull" json:"employeeId"`
 	TemplateID          *uuid.UUID `bun:"template_id" json:"templateId,omitempty"`
 	OpenedAt            time.Time  `bun:"opened_at,notnull,default:current_timestamp" json:"openedAt"`
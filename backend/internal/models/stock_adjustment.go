@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type StockAdjustmentStatus = string
+
+const (
+	AdjustmentStatusPending  StockAdjustmentStatus = "pending"
+	AdjustmentStatusPosted   StockAdjustmentStatus = "posted"
+	AdjustmentStatusRejected StockAdjustmentStatus = "rejected"
+)
+
+// Adjustment kinds: a free quantity correction (new owned batch in, or
+// drained from owned batches out) or a write-off against one batch.
+const (
+	AdjustmentKindAdjust   = "adjust"
+	AdjustmentKindWriteOff = "write-off"
+)
+
+// Adjustment reason codes, shared with the frontend StockAdjustmentReason
+// union and stored on both the adjustment and its movements.
+const (
+	AdjustmentReasonDamaged     = "damaged"
+	AdjustmentReasonExpired     = "expired"
+	AdjustmentReasonTheft       = "theft"
+	AdjustmentReasonLost        = "lost"
+	AdjustmentReasonSample      = "sample"
+	AdjustmentReasonFound       = "found"
+	AdjustmentReasonInitialSeed = "initial-seed"
+	AdjustmentReasonCorrection  = "correction"
+	AdjustmentReasonOther       = "other"
+)
+
+var AdjustmentReasons = []string{
+	AdjustmentReasonDamaged, AdjustmentReasonExpired, AdjustmentReasonTheft,
+	AdjustmentReasonLost, AdjustmentReasonSample, AdjustmentReasonFound,
+	AdjustmentReasonInitialSeed, AdjustmentReasonCorrection, AdjustmentReasonOther,
+}
+
+type StockAdjustment struct {
+	bun.BaseModel `bun:"table:stock_adjustments,alias:sa"`
+
+	ID            uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code          string     `bun:",notnull,unique" json:"code"`
+	Kind          string     `bun:",notnull,default:'adjust'" json:"kind"`
+	Status        string     `bun:",notnull,default:'pending'" json:"status"`
+	ProductID     uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	VariantID     *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	LocationID    uuid.UUID  `bun:"location_id,notnull" json:"locationId"`
+	BatchID       *uuid.UUID `bun:"batch_id" json:"batchId,omitempty"`
+	QtyDelta      float64    `bun:"qty_delta,notnull" json:"qtyDelta"`
+	UnitCost      float64    `bun:"unit_cost,notnull,default:0" json:"unitCost"`
+	Value         float64    `bun:"value,notnull,default:0" json:"value"`
+	ReasonCode    string     `bun:"reason_code,notnull" json:"reasonCode"`
+	ExpiresAt     string     `bun:"expires_at,notnull,default:''" json:"expiresAt,omitempty"`
+	ImageURL      string     `bun:"image_url,notnull,default:''" json:"imageUrl,omitempty"`
+	Notes         string     `bun:",notnull,default:''" json:"notes"`
+	RequestedBy   string     `bun:"requested_by,notnull,default:''" json:"requestedBy"`
+	RequestedByID *uuid.UUID `bun:"requested_by_id" json:"requestedById,omitempty"`
+	DecidedBy     string     `bun:"decided_by,notnull,default:''" json:"decidedBy,omitempty"`
+	DecidedByID   *uuid.UUID `bun:"decided_by_id" json:"decidedById,omitempty"`
+	DecidedAt     *time.Time `bun:"decided_at" json:"decidedAt,omitempty"`
+	DecisionNotes string     `bun:"decision_notes,notnull,default:''" json:"decisionNotes,omitempty"`
+	CreatedAt     time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt     time.Time  `bun:",notnull,default:current_timestamp" json:"-"`
+}
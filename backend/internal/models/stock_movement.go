@@ -7,6 +7,39 @@ import (
 	"github.com/uptrace/bun"
 )
 
+// StockMovementKind mirrors the frontend StockMovementKind union. Stored as
+// TEXT; the server only writes the kinds below, clients may log others.
+type StockMovementKind = string
+
+const (
+	MovementKindReceive         StockMovementKind = "receive"
+	MovementKindSale            StockMovementKind = "sale"
+	MovementKindSaleCancel      StockMovementKind = "sale-cancel"
+	MovementKindAdjustIn        StockMovementKind = "adjust-in"
+	MovementKindAdjustOut       StockMovementKind = "adjust-out"
+	MovementKindMoveOut         StockMovementKind = "move-out"
+	MovementKindMoveIn          StockMovementKind = "move-in"
+	MovementKindMoveRelocate    StockMovementKind = "move-relocate"
+	MovementKindReturnConsignor StockMovementKind = "return-consignor"
+	MovementKindReturnSupplier  StockMovementKind = "return-supplier"
+	MovementKindProductionIn    StockMovementKind = "production-in"
+	MovementKindProductionOut   StockMovementKind = "production-out"
+	MovementKindWriteOff        StockMovementKind = "write-off"
+)
+
+// StockMovementReferenceKind is the `reference.kind` discriminator.
+type StockMovementReferenceKind = string
+
+const (
+	MovementRefPO         StockMovementReferenceKind = "po"
+	MovementRefOrder      StockMovementReferenceKind = "order"
+	MovementRefOpname     StockMovementReferenceKind = "opname"
+	MovementRefManual     StockMovementReferenceKind = "manual"
+	MovementRefTransfer   StockMovementReferenceKind = "transfer"
+	MovementRefReturn     StockMovementReferenceKind = "return"
+	MovementRefProduction StockMovementReferenceKind = "production"
+)
+
 // StockMovementReference is the small "what triggered this" pointer.
 // kind ∈ {po, order, opname, manual, transfer, return, production}
 // id is the originating row id; code is an optional human-readable label.
@@ -7,6 +7,20 @@ import (
 	"github.com/uptrace/bun"
 )
 
+type StockOpnameStatus = string
+
+const (
+	OpnameStatusDraft     StockOpnameStatus = "draft"
+	OpnameStatusCounting  StockOpnameStatus = "counting"
+	OpnameStatusCompleted StockOpnameStatus = "completed"
+	OpnameStatusCancelled StockOpnameStatus = "cancelled"
+)
+
+const (
+	OpnameKindFull  = "full"
+	OpnameKindCycle = "cycle"
+)
+
 type StockOpname struct {
 	bun.BaseModel `bun:"table:stock_opnames,alias:so"`
 
@@ -21,6 +35,16 @@ type StockOpname struct {
 	CreatedAt   time.Time  `bun:",notnull,default:current_timestamp" json:"-"`
 	UpdatedAt   time.Time  `bun:",notnull,default:current_timestamp" json:"-"`
 
+	// SnapshotAt is when expected quantities were frozen by Start. Movements
+	// after this instant (up to each line's CountedAt) are netted out at
+	// completion. Nil for legacy opnames whose lines came from the client.
+	SnapshotAt *time.Time `bun:"snapshot_at" json:"snapshotAt,omitempty"`
+
+	// Kind is "full" for opnames created by hand and "cycle" for the daily
+	// cycle-count drafts, which also carry the day they were scheduled for.
+	Kind         string `bun:",notnull,default:'full'" json:"kind"`
+	ScheduledFor string `bun:"scheduled_for,notnull,default:''" json:"scheduledFor,omitempty"`
+
 	Lines []StockOpnameLine `bun:"rel:has-many,join:id=opname_id" json:"lines"`
 }
 
@@ -31,8 +55,20 @@ type StockOpnameLine struct {
 	OpnameID    uuid.UUID  `bun:"opname_id,notnull" json:"-"`
 	ProductID   uuid.UUID  `bun:"product_id,notnull" json:"productId"`
 	VariantID   *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	BatchID     *uuid.UUID `bun:"batch_id" json:"batchId,omitempty"`
+	LocationID  *uuid.UUID `bun:"location_id" json:"locationId,omitempty"`
 	ExpectedQty float64    `bun:"expected_qty,notnull,default:0" json:"expectedQty"`
 	CountedQty  *float64   `bun:"counted_qty" json:"countedQty"`
+	CountedAt   *time.Time `bun:"counted_at" json:"countedAt,omitempty"`
 	UnitCost    float64    `bun:"unit_cost,notnull,default:0" json:"unitCost"`
 	Notes       string     `bun:",notnull,default:''" json:"notes"`
+
+	// Filled at completion: net stock movement on the batch between the
+	// snapshot and the count, and the posted variance (counted − expected −
+	// movement) with its value at UnitCost. When AdjustmentID is set the
+	// variance needed approval and waits on that adjustment instead.
+	MovementQty   float64    `bun:"movement_qty,notnull,default:0" json:"movementQty"`
+	VarianceQty   *float64   `bun:"variance_qty" json:"varianceQty,omitempty"`
+	VarianceValue *float64   `bun:"variance_value" json:"varianceValue,omitempty"`
+	AdjustmentID  *uuid.UUID `bun:"adjustment_id" json:"adjustmentId,omitempty"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type ReservationHolderKind = string
+
+const (
+	ReservationHolderCart  ReservationHolderKind = "cart"
+	ReservationHolderOrder ReservationHolderKind = "order"
+)
+
+type StockReservation struct {
+	bun.BaseModel `bun:"table:stock_reservations,alias:sr"`
+
+	ID         uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	HolderKind string     `bun:"holder_kind,notnull" json:"holderKind"`
+	HolderID   string     `bun:"holder_id,notnull" json:"holderId"`
+	ProductID  uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	VariantID  *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	BatchID    uuid.UUID  `bun:"batch_id,notnull" json:"batchId"`
+	Qty        float64    `bun:",notnull" json:"qty"`
+	ExpiresAt  time.Time  `bun:"expires_at,notnull" json:"expiresAt"`
+	CreatedBy  string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt  time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
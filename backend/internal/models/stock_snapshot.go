@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	SnapshotSourceJob      = "job"
+	SnapshotSourceBackfill = "backfill"
+	SnapshotSourceRepair   = "repair"
+)
+
+// StockSnapshotDay marks a day whose end-of-day snapshot is complete, with
+// its totals.
+type StockSnapshotDay struct {
+	bun.BaseModel `bun:"table:stock_snapshot_days,alias:ssd"`
+
+	Date             string    `bun:"snapshot_date,pk" json:"date"`
+	Rows             int       `bun:",notnull,default:0" json:"rows"`
+	OwnedQty         float64   `bun:"owned_qty,notnull,default:0" json:"ownedQty"`
+	OwnedValue       float64   `bun:"owned_value,notnull,default:0" json:"ownedValue"`
+	ConsignmentQty   float64   `bun:"consignment_qty,notnull,default:0" json:"consignmentQty"`
+	ConsignmentValue float64   `bun:"consignment_value,notnull,default:0" json:"consignmentValue"`
+	Source           string    `bun:",notnull,default:'job'" json:"source"`
+	TakenAt          time.Time `bun:"taken_at,notnull,default:current_timestamp" json:"takenAt"`
+}
+
+// StockSnapshot is one batch's end-of-day quantity and value.
+type StockSnapshot struct {
+	bun.BaseModel `bun:"table:stock_snapshots,alias:ss"`
+
+	Date       string     `bun:"snapshot_date,pk" json:"date"`
+	BatchID    uuid.UUID  `bun:"batch_id,pk,type:uuid" json:"batchId"`
+	ProductID  uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	VariantID  *uuid.UUID `bun:"variant_id" json:"variantId,omitempty"`
+	LocationID uuid.UUID  `bun:"location_id,notnull" json:"locationId"`
+	StoreID    uuid.UUID  `bun:"store_id,notnull" json:"storeId"`
+	Ownership  string     `bun:",notnull" json:"ownership"`
+	Qty        float64    `bun:",notnull" json:"qty"`
+	UnitCost   float64    `bun:"unit_cost,notnull,default:0" json:"unitCost"`
+	Value      float64    `bun:",notnull,default:0" json:"value"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type StoreStatus = string
+
+const (
+	StoreStatusActive   StoreStatus = "active"
+	StoreStatusArchived StoreStatus = "archived"
+)
+
+// Store is one outlet. Locations, registers, shifts, orders and
+// store-specific pricelists carry its id; stock belongs to a store through
+// the batch's location.
+type Store struct {
+	bun.BaseModel `bun:"table:stores,alias:st"`
+
+	ID                 uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code               string    `bun:",notnull,unique" json:"code"`
+	Name               string    `bun:",notnull" json:"name"`
+	Address            string    `bun:",notnull,default:''" json:"address"`
+	Phone              string    `bun:",notnull,default:''" json:"phone"`
+	Status             string    `bun:",notnull,default:'active'" json:"status"`
+	DefaultPricelistID *string   `bun:"default_pricelist_id" json:"defaultPricelistId,omitempty"`
+	CreatedAt          time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt          time.Time `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+// Register is a till inside a store.
+type Register struct {
+	bun.BaseModel `bun:"table:registers,alias:rg"`
+
+	ID        uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	StoreID   uuid.UUID `bun:"store_id,type:uuid,notnull" json:"storeId"`
+	Code      string    `bun:",notnull" json:"code"`
+	Name      string    `bun:",notnull" json:"name"`
+	Status    string    `bun:",notnull,default:'active'" json:"status"`
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt time.Time `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+// UserStore assigns an employee to a store. At most one row per user is
+// the default store.
+type UserStore struct {
+	bun.BaseModel `bun:"table:user_stores,alias:us"`
+
+	UserID    uuid.UUID `bun:"user_id,pk,type:uuid" json:"userId"`
+	StoreID   uuid.UUID `bun:"store_id,pk,type:uuid" json:"storeId"`
+	IsDefault bool      `bun:"is_default,notnull,default:false" json:"isDefault"`
+}
@@ -24,8 +24,11 @@ type Supplier struct {
 	Phone         string    `bun:",notnull,default:''" json:"phone"`
 	Address       string    `bun:",notnull,default:''" json:"address"`
 	LeadTimeDays  int       `bun:"lead_time_days,notnull,default:0" json:"leadTimeDays"`
-	Status        string    `bun:",notnull,default:'active'" json:"status"`
-	Notes         string    `bun:",notnull,default:''" json:"notes"`
-	CreatedAt     time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
-	UpdatedAt     time.Time `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+	// Net payment terms: received POs fall due this many days after
+	// their first receipt.
+	PaymentTermsDays int       `bun:"payment_terms_days,notnull,default:0" json:"paymentTermsDays"`
+	Status           string    `bun:",notnull,default:'active'" json:"status"`
+	Notes            string    `bun:",notnull,default:''" json:"notes"`
+	CreatedAt        time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt        time.Time `bun:",notnull,default:current_timestamp" json:"updatedAt"`
 }
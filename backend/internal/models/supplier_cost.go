@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Where a supplier cost change came from.
+const (
+	SupplierCostInitial = "initial"
+	SupplierCostManual  = "manual"
+	SupplierCostImport  = "import"
+)
+
+// SupplierCostChange is one effective-dated change to a supplier's cost
+// for a product. PreviousCost is nil for the first known cost.
+type SupplierCostChange struct {
+	bun.BaseModel `bun:"table:supplier_cost_history,alias:sch"`
+
+	ID            uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ProductID     uuid.UUID  `bun:"product_id,notnull" json:"productId"`
+	SupplierID    uuid.UUID  `bun:"supplier_id,notnull" json:"supplierId"`
+	UnitCost      float64    `bun:"unit_cost,notnull" json:"unitCost"`
+	PreviousCost  *float64   `bun:"previous_cost" json:"previousCost,omitempty"`
+	EffectiveFrom string     `bun:"effective_from,notnull" json:"effectiveFrom"`
+	Source        string     `bun:",notnull,default:'manual'" json:"source"`
+	ImportID      *uuid.UUID `bun:"import_id" json:"importId,omitempty"`
+	CreatedBy     string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt     time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// Outcomes of one price list row.
+const (
+	PriceImportUpdated   = "updated"
+	PriceImportScheduled = "scheduled"
+	PriceImportUnchanged = "unchanged"
+	PriceImportUnmatched = "unmatched"
+	PriceImportInvalid   = "invalid"
+)
+
+// SupplierPriceImportLine is the outcome of one price list row, kept as
+// JSONB on the import.
+type SupplierPriceImportLine struct {
+	Row         int        `json:"row"`
+	SupplierSKU string     `json:"supplierSku,omitempty"`
+	Barcode     string     `json:"barcode,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Cost        float64    `json:"cost"`
+	ProductID   *uuid.UUID `json:"productId,omitempty"`
+	ProductName string     `json:"productName,omitempty"`
+	MatchedBy   string     `json:"matchedBy,omitempty"`
+	OldCost     float64    `json:"oldCost"`
+	NewCost     float64    `json:"newCost"`
+	ChangePct   float64    `json:"changePct"`
+	Alert       bool       `json:"alert"`
+	Status      string     `json:"status"`
+	Message     string     `json:"message,omitempty"`
+}
+
+// SupplierPriceImport is one uploaded supplier price list.
+type SupplierPriceImport struct {
+	bun.BaseModel `bun:"table:supplier_price_imports,alias:spi"`
+
+	ID            uuid.UUID                 `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code          string                    `bun:",notnull,unique" json:"code"`
+	SupplierID    uuid.UUID                 `bun:"supplier_id,notnull" json:"supplierId"`
+	Filename      string                    `bun:",notnull,default:''" json:"filename"`
+	EffectiveFrom string                    `bun:"effective_from,notnull" json:"effectiveFrom"`
+	RowsTotal     int                       `bun:"rows_total,notnull,default:0" json:"rowsTotal"`
+	RowsUpdated   int                       `bun:"rows_updated,notnull,default:0" json:"rowsUpdated"`
+	RowsUnchanged int                       `bun:"rows_unchanged,notnull,default:0" json:"rowsUnchanged"`
+	RowsSkipped   int                       `bun:"rows_skipped,notnull,default:0" json:"rowsSkipped"`
+	Lines         []SupplierPriceImportLine `bun:"lines,type:jsonb,notnull,default:'[]'" json:"lines"`
+	CreatedBy     string                    `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt     time.Time                 `bun:",notnull,default:current_timestamp" json:"createdAt"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Supplier invoice statuses. Only matched invoices count toward payables.
+const (
+	SupplierInvoiceMatched     = "matched"
+	SupplierInvoiceDiscrepancy = "discrepancy"
+	SupplierInvoiceCancelled   = "cancelled"
+)
+
+// Three-way match outcome per invoice line.
+const (
+	InvoiceMatchOK          = "ok"
+	InvoiceMatchPrice       = "price"
+	InvoiceMatchQtyReceived = "qty-received"
+	InvoiceMatchQtyOrdered  = "qty-ordered"
+)
+
+// SupplierInvoice is a supplier's bill for goods on its standard POs.
+// Discrepancies counts the lines that failed the match.
+type SupplierInvoice struct {
+	bun.BaseModel `bun:"table:supplier_invoices,alias:sinv"`
+
+	ID               uuid.UUID  `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code             string     `bun:",notnull,unique" json:"code"`
+	SupplierID       uuid.UUID  `bun:"supplier_id,notnull" json:"supplierId"`
+	InvoiceNumber    string     `bun:"invoice_number,notnull" json:"invoiceNumber"`
+	InvoiceDate      string     `bun:"invoice_date,notnull,default:''" json:"invoiceDate"`
+	TaxInvoiceNumber string     `bun:"tax_invoice_number,notnull,default:''" json:"taxInvoiceNumber"`
+	Status           string     `bun:",notnull,default:'discrepancy'" json:"status"`
+	Total            float64    `bun:",notnull,default:0" json:"total"`
+	Discrepancies    int        `bun:",notnull,default:0" json:"discrepancies"`
+	MatchedAt        *time.Time `bun:"matched_at" json:"matchedAt,omitempty"`
+	Notes            string     `bun:",notnull,default:''" json:"notes"`
+	CreatedBy        string     `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt        time.Time  `bun:",notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt        time.Time  `bun:",notnull,default:current_timestamp" json:"updatedAt"`
+
+	SupplierName string                `bun:"supplier_name,scanonly" json:"supplierName"`
+	Lines        []SupplierInvoiceLine `bun:"-" json:"lines"`
+}
+
+// SupplierInvoiceLine bills one PO line. OrderedQty, POUnitPrice,
+// ReceivedQty and InvoicedBefore are what it was last matched against.
+type SupplierInvoiceLine struct {
+	bun.BaseModel `bun:"table:supplier_invoice_lines,alias:sinl"`
+
+	ID                  uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	InvoiceID           uuid.UUID `bun:"invoice_id,notnull" json:"-"`
+	PurchaseOrderID     uuid.UUID `bun:"purchase_order_id,notnull" json:"purchaseOrderId"`
+	PurchaseOrderLineID uuid.UUID `bun:"purchase_order_line_id,notnull" json:"purchaseOrderLineId"`
+	Qty                 float64   `bun:",notnull" json:"qty"`
+	UnitPrice           float64   `bun:"unit_price,notnull,default:0" json:"unitPrice"`
+	Amount              float64   `bun:",notnull,default:0" json:"amount"`
+	OrderedQty          float64   `bun:"ordered_qty,notnull,default:0" json:"orderedQty"`
+	POUnitPrice         float64   `bun:"po_unit_price,notnull,default:0" json:"poUnitPrice"`
+	ReceivedQty         float64   `bun:"received_qty,notnull,default:0" json:"receivedQty"`
+	InvoicedBefore      float64   `bun:"invoiced_before,notnull,default:0" json:"invoicedBefore"`
+	MatchStatus         string    `bun:"match_status,notnull,default:'ok'" json:"matchStatus"`
+	MatchNote           string    `bun:"match_note,notnull,default:''" json:"matchNote"`
+	Position            int       `bun:",notnull,default:0" json:"-"`
+
+	PurchaseOrderCode string `bun:"purchase_order_code,scanonly" json:"purchaseOrderCode"`
+	ProductName       string `bun:"product_name,scanonly" json:"productName"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// SupplierPayment is one payment to a supplier, allocated over one or
+// more of its POs. Each allocation is stored as a PurchaseOrderPayment so
+// the PO's own paid amount and history stay the source of truth.
+type SupplierPayment struct {
+	bun.BaseModel `bun:"table:supplier_payments,alias:spay"`
+
+	ID         uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code       string    `bun:",notnull,unique" json:"code"`
+	SupplierID uuid.UUID `bun:"supplier_id,notnull" json:"supplierId"`
+	Amount     float64   `bun:",notnull" json:"amount"`
+	Method     string    `bun:",notnull,default:'transfer'" json:"method"`
+	PaidAt     string    `bun:"paid_at,notnull,default:''" json:"paidAt"`
+	Reference  string    `bun:",notnull,default:''" json:"reference"`
+	Notes      string    `bun:",notnull,default:''" json:"notes"`
+	CreatedBy  string    `bun:"created_by,notnull,default:''" json:"createdBy"`
+	CreatedAt  time.Time `bun:",notnull,default:current_timestamp" json:"createdAt"`
+
+	Allocations []SupplierPaymentAllocation `bun:"-" json:"allocations"`
+}
+
+// SupplierPaymentAllocation is the API view of one PO's share of a
+// supplier payment.
+type SupplierPaymentAllocation struct {
+	PurchaseOrderID   uuid.UUID `bun:"purchase_order_id" json:"purchaseOrderId"`
+	PurchaseOrderCode string    `bun:"purchase_order_code" json:"purchaseOrderCode"`
+	Amount            float64   `bun:"amount" json:"amount"`
+}
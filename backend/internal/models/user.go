@@ -35,4 +35,7 @@ type User struct {
 	// RoleIDs are populated by handlers via a separate user_roles query and
 	// returned in the JSON response. Not stored on the users row.
 	RoleIDs []uuid.UUID `bun:"-" json:"roleIds"`
+	// StoreIDs / DefaultStoreID come from user_stores the same way.
+	StoreIDs       []uuid.UUID `bun:"-" json:"storeIds"`
+	DefaultStoreID *uuid.UUID  `bun:"-" json:"defaultStoreId,omitempty"`
 }
@@ -0,0 +1,73 @@
+// Package pdf writes the minimal PDF files behind labels and printable
+// documents: every page shares one size, text uses the standard Helvetica
+// faces (F1 regular, F2 bold, WinAnsi encoded) so nothing is embedded, and
+// callers supply each page's content stream.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// File assembles the page content streams into a PDF of w×h point pages.
+func File(w, h float64, pages []string) []byte {
+	// Object layout: 1 catalog, 2 page tree, 3-4 fonts, then a page and its
+	// content stream per page.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	for i, c := range pages {
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", w, h, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(c), c),
+		)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return b.Bytes()
+}
+
+// String escapes a literal string and maps it to single-byte WinAnsi;
+// characters outside Latin-1 print as '?'.
+func String(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '—' || r == '–':
+			b.WriteByte('-')
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -29,7 +29,7 @@ func NewRouter(opts Options) http.Handler {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{opts.CORSAllowOrigin},
 		AllowedMethods:   []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", handlers.StoreHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -56,10 +56,29 @@ func NewRouter(opts Options) http.Handler {
 	stockMovementsH := handlers.NewStockMovementsHandler(opts.Deps)
 	productionRunsH := handlers.NewProductionRunsHandler(opts.Deps)
 	stockOpnamesH := handlers.NewStockOpnamesHandler(opts.Deps)
+	cycleCountsH := handlers.NewCycleCountsHandler(opts.Deps)
 	payoutsH := handlers.NewPayoutsHandler(opts.Deps)
+	consignorReturnsH := handlers.NewConsignorReturnsHandler(opts.Deps)
+	purchaseReturnsH := handlers.NewPurchaseReturnsHandler(opts.Deps)
+	supplierPaymentsH := handlers.NewSupplierPaymentsHandler(opts.Deps)
+	supplierInvoicesH := handlers.NewSupplierInvoicesHandler(opts.Deps)
+	supplierCostsH := handlers.NewSupplierCostsHandler(opts.Deps)
+	stockAdjustmentsH := handlers.NewStockAdjustmentsHandler(opts.Deps)
 	priceChangesH := handlers.NewPriceChangesHandler(opts.Deps)
+	priceSchedulesH := handlers.NewPriceSchedulesHandler(opts.Deps)
+	reportsH := handlers.NewReportsHandler(opts.Deps)
+	stockSnapshotsH := handlers.NewStockSnapshotsHandler(opts.Deps)
+	replenishmentH := handlers.NewReplenishmentHandler(opts.Deps)
+	reservationsH := handlers.NewStockReservationsHandler(opts.Deps)
+	scanH := handlers.NewScanHandler(opts.Deps)
+	labelTemplatesH := handlers.NewLabelTemplatesHandler(opts.Deps)
+	labelsH := handlers.NewLabelsHandler(opts.Deps)
+	serialsH := handlers.NewSerialsHandler(opts.Deps)
+	warrantyClaimsH := handlers.NewWarrantyClaimsHandler(opts.Deps)
 	promotionsH := handlers.NewPromotionsHandler(opts.Deps)
 	settingsH := handlers.NewAppSettingsHandler(opts.Deps)
+	storesH := handlers.NewStoresHandler(opts.Deps)
+	registersH := handlers.NewRegistersHandler(opts.Deps)
 
 	r.Get("/healthz", healthz)
 
@@ -70,8 +89,13 @@ func NewRouter(opts Options) http.Handler {
 		// Authenticated
 		api.Group(func(p chi.Router) {
 			p.Use(middleware.RequireAuth(opts.Issuer))
+			// Active store from X-Store-ID (or the user's default store).
+			// List endpoints below filter by it; "all" is HQ-only.
+			p.Use(handlers.StoreScope(opts.Deps))
 
 			p.Get("/auth/me", authH.Me)
+			p.Get("/stores/mine", storesH.Mine)
+			p.Get("/registers", registersH.List)
 
 			// Reads available to any authed user (POS/products need them);
 			// writes gated to Admin below.
@@ -89,8 +113,36 @@ func NewRouter(opts Options) http.Handler {
 			p.Get("/locations/{id}", locationsH.Get)
 			p.Get("/products", productsH.List)
 			p.Get("/products/{id}", productsH.Get)
+			// Barcode scan: product/variant/packaging/batch codes and scale
+			// (weight- or price-embedded) EAN-13s.
+			p.Get("/scan/{code}", scanH.Resolve)
+
+			// Labels: PDF (default) or ZPL (?format=zpl) rendered from a
+			// label template; templates themselves are managed by Admin.
+			p.Get("/label-templates", labelTemplatesH.List)
+			p.Get("/label-templates/{id}", labelTemplatesH.Get)
+			p.Get("/labels/products/{id}", labelsH.Product)
+			p.Get("/labels/batches/{id}", labelsH.Batch)
+			p.Get("/labels/promotions/{id}", labelsH.Promotion)
+			p.Get("/labels/purchase-orders/{id}/batches", labelsH.PurchaseOrderBatches)
 			p.Get("/purchase-orders", purchaseOrdersH.List)
 			p.Get("/purchase-orders/{id}", purchaseOrdersH.Get)
+			// Receiving is done by warehouse staff, not just Admin. Batches,
+			// movements and the PO status update commit in one transaction.
+			p.Post("/purchase-orders/{id}/receive", purchaseOrdersH.Receive)
+			// Receipts and their landed costs (freight, duty, ...). Charges
+			// billed after receiving are added to an existing receipt.
+			p.Get("/purchase-orders/{id}/receipts", purchaseOrdersH.Receipts)
+			p.Post("/purchase-orders/{id}/receipts/{receiptId}/landed-costs", purchaseOrdersH.AddLandedCosts)
+			// Status history, and approval of POs over the purchasing
+			// threshold (feature.purchasing.approve, checked in the handler).
+			p.Get("/purchase-orders/{id}/transitions", purchaseOrdersH.Transitions)
+			p.Post("/purchase-orders/{id}/approve", purchaseOrdersH.Approve)
+			p.Post("/purchase-orders/{id}/reject", purchaseOrdersH.Reject)
+			// The PO as a PDF, and the log of emailing it to the supplier
+			// (sending one is admin-only, like the send action).
+			p.Get("/purchase-orders/{id}/pdf", purchaseOrdersH.PDF)
+			p.Get("/purchase-orders/{id}/emails", purchaseOrdersH.Emails)
 
 			// Shifts (operational): kasir needs to open/close + add cash entries.
 			p.Get("/shift-templates", shiftTemplatesH.List)
@@ -115,37 +167,148 @@ func NewRouter(opts Options) http.Handler {
 			// Stock: batches + movements. Reads + writes authed (kasir,
 			// PO receive, opname, production all need to mutate).
 			p.Get("/batches", batchesH.List)
+			p.Get("/batches/near-expiry", batchesH.NearExpiry)
 			p.Get("/batches/{id}", batchesH.Get)
-			p.Post("/batches", batchesH.Create)
 			p.Patch("/batches/{id}", batchesH.Update)
+			p.Post("/batches/{id}/move", batchesH.Move)
+			p.Post("/batches/{id}/dispose", batchesH.Dispose)
+			// Lineage through production runs and transfer splits, with the
+			// orders/customers that bought downstream stock.
+			p.Get("/batches/{id}/trace", batchesH.Trace)
+			p.Get("/batches/{id}/recalls", batchesH.Recalls)
+			// Read-only: every movement is written by the workflow that
+			// changes the batch it records.
 			p.Get("/stock-movements", stockMovementsH.List)
-			p.Post("/stock-movements", stockMovementsH.Create)
 
-			// Production runs + stock opnames. Both write rows but their
-			// stock side-effects (batch + movement mutations) are persisted
-			// by the FE before posting the header, so the backend just
-			// records the resulting paperwork.
+			// Manual adjustments with reason codes. Above the value threshold
+			// they queue as pending; approve/reject checks the
+			// feature.stock.adjust-approve permission in the handler.
+			p.Get("/stock-adjustments", stockAdjustmentsH.List)
+			p.Get("/stock-adjustments/queue", stockAdjustmentsH.Queue)
+			p.Get("/stock-adjustments/{id}", stockAdjustmentsH.Get)
+			p.Post("/stock-adjustments", stockAdjustmentsH.Create)
+			p.Post("/stock-adjustments/{id}/approve", stockAdjustmentsH.Approve)
+			p.Post("/stock-adjustments/{id}/reject", stockAdjustmentsH.Reject)
+
+			// Serial numbers (captured at PO receipt, linked at sale) and
+			// warranty claims sent back to the supplier.
+			p.Get("/serials", serialsH.List)
+			p.Patch("/serials/{id}", serialsH.UpdateStatus)
+			p.Get("/warranty/{serial}", serialsH.Warranty)
+			p.Get("/warranty-claims", warrantyClaimsH.List)
+			p.Get("/warranty-claims/{id}", warrantyClaimsH.Get)
+			p.Post("/warranty-claims", warrantyClaimsH.Create)
+			p.Post("/warranty-claims/{id}/send", warrantyClaimsH.Send)
+			p.Post("/warranty-claims/{id}/resolve", warrantyClaimsH.Resolve)
+			p.Post("/warranty-claims/{id}/cancel", warrantyClaimsH.Cancel)
+
+			// Stock reservations for parked carts / pending orders. PUT
+			// replaces (and renews) the holder's whole set; DELETE releases.
+			p.Get("/stock/availability", reservationsH.Availability)
+			p.Get("/stock-reservations/{holderKind}/{holderId}", reservationsH.List)
+			p.Put("/stock-reservations/{holderKind}/{holderId}", reservationsH.Replace)
+			p.Delete("/stock-reservations/{holderKind}/{holderId}", reservationsH.Release)
+
+			// Production runs. execute consumes components FIFO and creates
+			// the output batch server-side; the plain POST still records a
+			// run whose stock side-effects the FE already persisted.
 			p.Get("/production-runs", productionRunsH.List)
-			p.Post("/production-runs", productionRunsH.Create)
+			p.Post("/production-runs/execute", productionRunsH.Execute)
+
+			// Stock opnames. start snapshots expected qty per batch+location,
+			// counts records what was found, complete posts the variances
+			// (net of movements during counting) as adjust movements.
 			p.Get("/stock-opnames", stockOpnamesH.List)
 			p.Get("/stock-opnames/{id}", stockOpnamesH.Get)
+			p.Get("/stock-opnames/{id}/summary", stockOpnamesH.Summary)
 			p.Post("/stock-opnames", stockOpnamesH.Create)
 			p.Patch("/stock-opnames/{id}", stockOpnamesH.Update)
+			p.Post("/stock-opnames/{id}/start", stockOpnamesH.Start)
+			p.Post("/stock-opnames/{id}/counts", stockOpnamesH.RecordCounts)
+			p.Post("/stock-opnames/{id}/complete", stockOpnamesH.Complete)
+			p.Post("/stock-opnames/{id}/cancel", stockOpnamesH.Cancel)
+
+			// ABC classes and cycle counts. The daily job creates the
+			// drafts (stock-opnames?kind=cycle); the dashboard shows
+			// coverage and accuracy per class.
+			p.Get("/cycle-counts/abc", cycleCountsH.ABC)
+			p.Get("/cycle-counts/dashboard", cycleCountsH.Dashboard)
 
 			// Consignor payouts (settlement). Reads + writes authed since
 			// kasir & finance both touch them.
 			p.Get("/payouts", payoutsH.List)
+			p.Get("/payouts/outstanding", payoutsH.Outstanding)
 			p.Post("/payouts", payoutsH.Create)
 			p.Delete("/payouts/{id}", payoutsH.Delete)
+			// Settlement statement for a consignor and period; a payout
+			// locks the sales it settles and prints for them to sign.
+			p.Get("/payouts/settlement", payoutsH.Settlement)
+			p.Get("/payouts/{id}", payoutsH.Get)
+			p.Get("/payouts/{id}/statement", payoutsH.Statement)
+
+			// Consignor returns: unsold consignment stock handed back, posted
+			// atomically on create, with a printable handover note.
+			p.Get("/consignor-returns", consignorReturnsH.List)
+			p.Get("/consignor-returns/{id}", consignorReturnsH.Get)
+			p.Get("/consignor-returns/{id}/handover", consignorReturnsH.Handover)
+			p.Post("/consignor-returns", consignorReturnsH.Create)
+
+			// Purchase returns: owned stock sent back to the supplier, posted
+			// on create with a debit note against the supplier's payables.
+			p.Get("/purchase-returns", purchaseReturnsH.List)
+			p.Get("/purchase-returns/{id}", purchaseReturnsH.Get)
+			p.Get("/purchase-returns/{id}/debit-note", purchaseReturnsH.DebitNote)
+			p.Post("/purchase-returns", purchaseReturnsH.Create)
+
+			// Accounts payable: aging by due date, and supplier payments
+			// split over POs (recording one is admin-only).
+			p.Get("/payables", suppliersH.Payables)
+			p.Get("/supplier-payments", supplierPaymentsH.List)
+			p.Get("/supplier-payments/{id}", supplierPaymentsH.Get)
+			// Supplier invoices, three-way matched against PO and receipts;
+			// only matched ones count toward payables.
+			p.Get("/supplier-invoices", supplierInvoicesH.List)
+			p.Get("/supplier-invoices/{id}", supplierInvoicesH.Get)
+
+			// Supplier costs: effective-dated history, per-supplier
+			// comparison, rise alerts and price list uploads (importing one
+			// is admin-only).
+			p.Get("/supplier-costs/history", supplierCostsH.History)
+			p.Get("/supplier-costs/compare", supplierCostsH.Compare)
+			p.Get("/supplier-costs/alerts", supplierCostsH.Alerts)
+			p.Get("/supplier-costs/imports", supplierCostsH.Imports)
+			p.Get("/supplier-costs/imports/{id}", supplierCostsH.GetImport)
 
 			// Price change audit log. Bulk-write on product save.
 			p.Get("/price-changes", priceChangesH.List)
 			p.Post("/price-changes", priceChangesH.Create)
 
+			// Scheduled prices; the apply-price-schedules job applies them.
+			p.Get("/price-schedules", priceSchedulesH.List)
+			p.Get("/price-schedules/{id}", priceSchedulesH.Get)
+
 			// Promotions. List/inc-usage authed (POS needs both); CRUD admin.
 			p.Get("/promotions", promotionsH.List)
 			p.Post("/promotions/{id}/usage", promotionsH.IncrementUsage)
 
+			// Reports. Read-only aggregates; ?format=csv for export.
+			p.Get("/reports/inventory-valuation", reportsH.InventoryValuation)
+			p.Get("/reports/stock-turnover", reportsH.StockTurnover)
+			p.Get("/reports/stock-history", reportsH.StockHistory)
+
+			// End-of-day stock snapshots the reports above read from.
+			p.Get("/stock-snapshots", stockSnapshotsH.List)
+
+			// Consolidated cross-store reporting for HQ.
+			p.Group(func(hq chi.Router) {
+				hq.Use(middleware.RequireRole(handlers.ConsolidatedRoles...))
+				hq.Get("/reports/stores", reportsH.StoreSummary)
+			})
+
+			// Demand forecast + reorder suggestions (replaces the in-browser
+			// /forecast math). Turning them into draft POs is admin-only.
+			p.Get("/replenishment/suggestions", replenishmentH.Suggestions)
+
 			// App-wide settings. Read authed (every page hydrates feature
 			// flags). Write admin (changes affect everyone).
 			p.Get("/settings", settingsH.Get)
@@ -194,6 +357,16 @@ func NewRouter(opts Options) http.Handler {
 				adm.Patch("/tags/{id}", tagsH.Update)
 				adm.Delete("/tags/{id}", tagsH.Delete)
 
+				adm.Get("/stores", storesH.List)
+				adm.Get("/stores/{id}", storesH.Get)
+				adm.Post("/stores", storesH.Create)
+				adm.Patch("/stores/{id}", storesH.Update)
+				adm.Delete("/stores/{id}", storesH.Delete)
+
+				adm.Post("/registers", registersH.Create)
+				adm.Patch("/registers/{id}", registersH.Update)
+				adm.Delete("/registers/{id}", registersH.Delete)
+
 				adm.Post("/locations", locationsH.Create)
 				adm.Patch("/locations/{id}", locationsH.Update)
 				adm.Delete("/locations/{id}", locationsH.Delete)
@@ -201,15 +374,42 @@ func NewRouter(opts Options) http.Handler {
 				adm.Post("/products", productsH.Create)
 				adm.Patch("/products/{id}", productsH.Update)
 				adm.Delete("/products/{id}", productsH.Delete)
+				adm.Post("/price-schedules", priceSchedulesH.Create)
+				adm.Post("/price-schedules/{id}/cancel", priceSchedulesH.Cancel)
+
+				// Manual trigger for the hourly expiry quarantine job.
+				adm.Post("/batches/quarantine-expired", batchesH.QuarantineExpired)
+				adm.Post("/batches/{id}/recall", batchesH.Recall)
+				adm.Post("/batches/{id}/recall/release", batchesH.ReleaseRecall)
+				adm.Post("/cycle-counts/abc/classify", cycleCountsH.Classify)
+				adm.Post("/cycle-counts/generate", cycleCountsH.Generate)
+				adm.Post("/stock-snapshots/backfill", stockSnapshotsH.Backfill)
+				adm.Post("/stock-snapshots/{date}/repair", stockSnapshotsH.Repair)
 
 				adm.Post("/purchase-orders", purchaseOrdersH.Create)
+				adm.Post("/replenishment/purchase-orders", replenishmentH.CreateDraftPOs)
 				adm.Patch("/purchase-orders/{id}", purchaseOrdersH.Update)
 				adm.Delete("/purchase-orders/{id}", purchaseOrdersH.Delete)
+				adm.Post("/purchase-orders/{id}/submit", purchaseOrdersH.Submit)
+				adm.Post("/purchase-orders/{id}/send", purchaseOrdersH.Send)
+				adm.Post("/purchase-orders/{id}/email", purchaseOrdersH.Email)
+				adm.Post("/purchase-orders/{id}/close", purchaseOrdersH.Close)
+				adm.Post("/purchase-orders/{id}/cancel", purchaseOrdersH.Cancel)
+				adm.Post("/supplier-payments", supplierPaymentsH.Create)
+				adm.Post("/supplier-invoices", supplierInvoicesH.Create)
+				adm.Patch("/supplier-invoices/{id}", supplierInvoicesH.Update)
+				adm.Post("/supplier-invoices/{id}/match", supplierInvoicesH.Match)
+				adm.Post("/supplier-invoices/{id}/cancel", supplierInvoicesH.Cancel)
+				adm.Post("/supplier-costs/import", supplierCostsH.Import)
 
 				adm.Post("/promotions", promotionsH.Create)
 				adm.Patch("/promotions/{id}", promotionsH.Update)
 				adm.Delete("/promotions/{id}", promotionsH.Delete)
 
+				adm.Post("/label-templates", labelTemplatesH.Create)
+				adm.Patch("/label-templates/{id}", labelTemplatesH.Update)
+				adm.Delete("/label-templates/{id}", labelTemplatesH.Delete)
+
 				adm.Put("/settings", settingsH.Put)
 
 				// Templates + assignments are admin-managed master data.
@@ -0,0 +1,242 @@
+// Package sheets reads uploaded spreadsheets (CSV and XLSX) into rows of
+// strings. Like the document renderer it avoids third-party dependencies:
+// XLSX is a zip of XML parts, and only the first worksheet's cell values
+// are needed, so archive/zip and encoding/xml are enough.
+package sheets
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned for files that are neither CSV nor XLSX.
+var ErrUnsupported = errors.New("format file harus CSV atau XLSX")
+
+// Read parses data as XLSX when it is a zip archive and as CSV otherwise.
+// filename is only used to refuse the old binary .xls format early. Rows
+// keep their original order; trailing empty cells are trimmed.
+func Read(filename string, data []byte) ([][]string, error) {
+	switch ext := strings.ToLower(path.Ext(filename)); {
+	case ext == ".xls":
+		return nil, ErrUnsupported
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return readXLSX(data)
+	case ext == "" || ext == ".csv" || ext == ".txt":
+		return readCSV(data)
+	}
+	return nil, ErrUnsupported
+}
+
+// readCSV accepts comma- or semicolon-separated files; spreadsheet apps in
+// an Indonesian locale export the latter.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV tidak bisa dibaca: %w", err)
+	}
+	for i := range rows {
+		rows[i] = trimRow(rows[i])
+	}
+	return rows, nil
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("XLSX tidak bisa dibuka: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeXML(f, &sst); err != nil {
+			return nil, err
+		}
+		shared = make([]string, len(sst.Items))
+		for i, it := range sst.Items {
+			shared[i] = it.String()
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("XLSX tidak punya lembar kerja %s", sheetPath)
+	}
+	var sheet xlsxSheet
+	if err := decodeXML(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// Blank rows are omitted from the XML; keep the numbering so
+		// callers can report spreadsheet row numbers.
+		for row.R > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+		var out []string
+		for i, c := range row.Cells {
+			col := columnIndex(c.Ref)
+			if col < 0 {
+				col = i
+			}
+			for len(out) <= col {
+				out = append(out, "")
+			}
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(strings.TrimSpace(c.Value))
+				if err == nil && n >= 0 && n < len(shared) {
+					out[col] = shared[n]
+				}
+			case "inlineStr":
+				out[col] = c.Inline.String()
+			case "b":
+				out[col] = map[string]string{"1": "TRUE", "0": "FALSE"}[c.Value]
+			default:
+				out[col] = c.Value
+			}
+		}
+		rows = append(rows, trimRow(out))
+	}
+	return rows, nil
+}
+
+// firstSheetPath resolves the first sheet listed in the workbook to its
+// part name, falling back to the conventional sheet1.xml.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	wbFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("XLSX tidak valid: workbook.xml tidak ada")
+	}
+	var wb xlsxWorkbook
+	if err := decodeXML(wbFile, &wb); err != nil {
+		return "", err
+	}
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok || len(wb.Sheets) == 0 {
+		return fallback, nil
+	}
+	var rels xlsxRels
+	if err := decodeXML(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Rels {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			return strings.TrimPrefix(target, "/"), nil
+		}
+		return path.Join("xl", target), nil
+	}
+	return fallback, nil
+}
+
+func decodeXML(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v); err != nil {
+		return fmt.Errorf("XLSX tidak valid (%s): %w", f.Name, err)
+	}
+	return nil
+}
+
+// columnIndex turns the letters of a cell reference ("C7", "AA12") into a
+// zero-based column index.
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
+
+func trimRow(row []string) []string {
+	for i := range row {
+		row[i] = strings.TrimSpace(row[i])
+	}
+	for len(row) > 0 && row[len(row)-1] == "" {
+		row = row[:len(row)-1]
+	}
+	return row
+}
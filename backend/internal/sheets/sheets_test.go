@@ -0,0 +1,205 @@
+package sheets
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want [][]string
+	}{
+		{
+			name: "comma",
+			data: "kode,harga\nA-1,12000\n",
+			want: [][]string{{"kode", "harga"}, {"A-1", "12000"}},
+		},
+		{
+			name: "semicolon from an Indonesian locale",
+			data: "kode;harga\nA-1;12.000,50\n",
+			want: [][]string{{"kode", "harga"}, {"A-1", "12.000,50"}},
+		},
+		{
+			name: "byte order mark, padding and trailing empty cells",
+			data: "\xef\xbb\xbfkode , harga,,\n A-1,  12000 ,\n",
+			want: [][]string{{"kode", "harga"}, {"A-1", "12000"}},
+		},
+		{
+			name: "quoted separator and ragged rows",
+			data: "nama,harga\n\"Gula, 1kg\",15000,catatan\nTeh\n",
+			want: [][]string{{"nama", "harga"}, {"Gula, 1kg", "15000", "catatan"}, {"Teh"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read("harga.csv", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadUnsupported(t *testing.T) {
+	for _, name := range []string{"harga.xls", "harga.pdf", "HARGA.XLS"} {
+		if _, err := Read(name, []byte("kode,harga\n")); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: got %v, want ErrUnsupported", name, err)
+		}
+	}
+}
+
+const (
+	testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+  xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Harga" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+	testSharedStrings = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>kode</t></si>
+  <si><t>harga</t></si>
+  <si><r><t>Gula </t></r><r><t>1kg</t></r></si>
+</sst>`
+	testSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+    <row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>15000</v></c></row>
+    <row r="4"><c r="A4" t="inlineStr"><is><t> Teh </t></is></c><c r="C4" t="b"><v>1</v></c></row>
+  </sheetData>
+</worksheet>`
+)
+
+// xlsx zips the given parts into an in-memory workbook.
+func xlsx(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func rels(target string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId9" Type="styles" Target="styles.xml"/>
+  <Relationship Id="rId1" Type="worksheet" Target="` + target + `"/>
+</Relationships>`
+}
+
+func TestReadXLSX(t *testing.T) {
+	want := [][]string{
+		{"kode", "harga"},
+		{"Gula 1kg", "15000"},
+		nil,
+		{"Teh", "", "TRUE"},
+	}
+	tests := []struct {
+		name  string
+		parts map[string]string
+	}{
+		{
+			name: "conventional sheet1 without rels",
+			parts: map[string]string{
+				"xl/workbook.xml":          testWorkbook,
+				"xl/sharedStrings.xml":     testSharedStrings,
+				"xl/worksheets/sheet1.xml": testSheet,
+			},
+		},
+		{
+			name: "relative rels target",
+			parts: map[string]string{
+				"xl/workbook.xml":            testWorkbook,
+				"xl/_rels/workbook.xml.rels": rels("worksheets/harga.xml"),
+				"xl/sharedStrings.xml":       testSharedStrings,
+				"xl/worksheets/harga.xml":    testSheet,
+			},
+		},
+		{
+			name: "absolute rels target",
+			parts: map[string]string{
+				"xl/workbook.xml":            testWorkbook,
+				"xl/_rels/workbook.xml.rels": rels("/xl/worksheets/harga.xml"),
+				"xl/sharedStrings.xml":       testSharedStrings,
+				"xl/worksheets/harga.xml":    testSheet,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The zip signature wins over the extension.
+			got, err := Read("harga.csv", xlsx(t, tt.parts))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestReadXLSXInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts map[string]string
+	}{
+		{
+			name:  "no workbook",
+			parts: map[string]string{"xl/worksheets/sheet1.xml": testSheet},
+		},
+		{
+			name:  "missing sheet part",
+			parts: map[string]string{"xl/workbook.xml": testWorkbook},
+		},
+		{
+			name: "malformed sheet",
+			parts: map[string]string{
+				"xl/workbook.xml":          testWorkbook,
+				"xl/worksheets/sheet1.xml": "<worksheet><sheetData><row>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read("harga.xlsx", xlsx(t, tt.parts)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{
+		"A1":   0,
+		"C7":   2,
+		"Z99":  25,
+		"AA12": 26,
+		"AZ1":  51,
+		"":     -1,
+		"12":   -1,
+	} {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS stock_opname_lines_batch_idx;
+
+--bun:split
+
+ALTER TABLE stock_opname_lines
+    DROP COLUMN IF EXISTS variance_value,
+    DROP COLUMN IF EXISTS variance_qty,
+    DROP COLUMN IF EXISTS movement_qty,
+    DROP COLUMN IF EXISTS counted_at,
+    DROP COLUMN IF EXISTS location_id,
+    DROP COLUMN IF EXISTS batch_id;
+
+--bun:split
+
+ALTER TABLE stock_opnames DROP COLUMN IF EXISTS snapshot_at;
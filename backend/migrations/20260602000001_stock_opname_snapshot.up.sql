@@ -0,0 +1,23 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- snapshot_at: when Start froze expected quantities. Movements between it
+-- and each line's counted_at are netted out when the opname is completed.
+ALTER TABLE stock_opnames ADD COLUMN snapshot_at TIMESTAMPTZ;
+
+--bun:split
+
+-- Lines snapshotted by the server are per batch + location so variances can
+-- be posted against the exact lot that was counted. Client-created lines
+-- (legacy flow, or "found" items with no batch) leave batch_id NULL.
+ALTER TABLE stock_opname_lines
+    ADD COLUMN batch_id       UUID          REFERENCES batches(id) ON DELETE SET NULL,
+    ADD COLUMN location_id    UUID          REFERENCES locations(id) ON DELETE SET NULL,
+    ADD COLUMN counted_at     TIMESTAMPTZ,
+    ADD COLUMN movement_qty   NUMERIC(14,4) NOT NULL DEFAULT 0,
+    ADD COLUMN variance_qty   NUMERIC(14,4),
+    ADD COLUMN variance_value NUMERIC(14,2);
+
+CREATE INDEX stock_opname_lines_batch_idx ON stock_opname_lines(batch_id)
+    WHERE batch_id IS NOT NULL;
@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE production_runs
+    DROP COLUMN IF EXISTS yield_loss_value,
+    DROP COLUMN IF EXISTS yield_loss_qty;
@@ -0,0 +1,11 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Runs executed server-side record how much of the planned output was lost.
+-- Components are always consumed for intended_qty, so the lost units' cost is
+-- absorbed into the produced batch; yield_loss_value keeps that amount
+-- visible for reporting.
+ALTER TABLE production_runs
+    ADD COLUMN yield_loss_qty   NUMERIC(14,4) NOT NULL DEFAULT 0,
+    ADD COLUMN yield_loss_value NUMERIC(14,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE categories DROP COLUMN IF EXISTS expiry_alert_days;
+
+--bun:split
+
+DROP INDEX IF EXISTS batches_expiry_idx;
+
+--bun:split
+
+ALTER TABLE batches
+    DROP COLUMN IF EXISTS quarantined_at,
+    DROP COLUMN IF EXISTS status;
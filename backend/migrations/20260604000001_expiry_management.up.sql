@@ -0,0 +1,19 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- status: active batches are sellable; quarantined ones (expired, or held
+-- back manually) keep their qty on hand but are skipped by allocation until
+-- released or disposed. disposed = written off to zero.
+ALTER TABLE batches
+    ADD COLUMN status         TEXT        NOT NULL DEFAULT 'active',
+    ADD COLUMN quarantined_at TIMESTAMPTZ;
+
+CREATE INDEX batches_expiry_idx ON batches(expires_at)
+    WHERE expires_at <> '' AND qty_remaining > 0;
+
+--bun:split
+
+-- Near-expiry alert horizon in days. NULL inherits from the parent category,
+-- then the inventory.nearExpiryDays app setting.
+ALTER TABLE categories ADD COLUMN expiry_alert_days INTEGER;
@@ -0,0 +1,25 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- stock_reservations: quantity held against a specific batch for a parked
+-- cart or a not-yet-fulfilled order. Rows past expires_at are ignored by
+-- every availability query and swept by the background job; holders renew
+-- by re-posting their lines. holder_id is TEXT because cart sessions are
+-- client-generated ids with no table of their own.
+CREATE TABLE stock_reservations (
+    id           UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    holder_kind  TEXT          NOT NULL,
+    holder_id    TEXT          NOT NULL,
+    product_id   UUID          NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id   UUID          REFERENCES product_variants(id) ON DELETE CASCADE,
+    batch_id     UUID          NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    qty          NUMERIC(14,4) NOT NULL CHECK (qty > 0),
+    expires_at   TIMESTAMPTZ   NOT NULL,
+    created_by   TEXT          NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX stock_reservations_holder_idx ON stock_reservations(holder_kind, holder_id);
+CREATE INDEX stock_reservations_batch_idx  ON stock_reservations(batch_id);
+CREATE INDEX stock_reservations_expiry_idx ON stock_reservations(expires_at);
@@ -0,0 +1,46 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- label_templates: printable layouts for shelf (product), batch and promo
+-- labels. fields/barcode are positioned in mm from the top-left corner;
+-- see internal/labels. One default per kind is used when the print request
+-- doesn't name a template.
+CREATE TABLE label_templates (
+    id          UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        TEXT          NOT NULL,
+    kind        TEXT          NOT NULL CHECK (kind IN ('product', 'batch', 'promotion')),
+    width_mm    NUMERIC(8,2)  NOT NULL CHECK (width_mm > 0),
+    height_mm   NUMERIC(8,2)  NOT NULL CHECK (height_mm > 0),
+    dpi         INTEGER       NOT NULL DEFAULT 203,
+    symbology   TEXT          NOT NULL DEFAULT 'code128',
+    fields      JSONB         NOT NULL DEFAULT '[]',
+    barcode     JSONB,
+    is_default  BOOLEAN       NOT NULL DEFAULT false,
+    created_at  TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX label_templates_one_default ON label_templates(kind) WHERE is_default;
+
+--bun:split
+
+INSERT INTO label_templates (name, kind, width_mm, height_mm, symbology, fields, barcode, is_default) VALUES
+('Rak 50×30', 'product', 50, 30, 'ean13',
+ '[{"key":"name","x":2,"y":2,"size":3.2,"bold":true},
+   {"key":"variant","x":2,"y":6,"size":2.5},
+   {"key":"price","x":2,"y":9.5,"size":5,"bold":true},
+   {"key":"unit","prefix":"/ ","x":34,"y":11,"size":2.5}]',
+ '{"x":2,"y":16,"width":46,"height":12}', true),
+('Batch 50×25', 'batch', 50, 25, 'code128',
+ '[{"key":"name","x":2,"y":1.5,"size":3,"bold":true},
+   {"key":"expiresAt","prefix":"ED ","x":2,"y":5.5,"size":2.5},
+   {"key":"bpomNumber","prefix":"BPOM ","x":2,"y":8.5,"size":2.2},
+   {"key":"halalCertNumber","prefix":"Halal ","x":26,"y":8.5,"size":2.2}]',
+ '{"x":2,"y":12,"width":46,"height":12}', true),
+('Promo 60×40', 'promotion', 60, 40, 'code128',
+ '[{"key":"name","x":3,"y":3,"size":4,"bold":true},
+   {"key":"discount","x":3,"y":9,"size":8,"bold":true},
+   {"key":"period","x":3,"y":19,"size":2.5},
+   {"key":"kind","x":3,"y":22.5,"size":2.5}]',
+ '{"x":3,"y":26,"width":54,"height":12}', true);
@@ -0,0 +1,17 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP TABLE IF EXISTS warranty_claims;
+
+--bun:split
+
+ALTER TABLE order_lines DROP COLUMN IF EXISTS serials;
+
+--bun:split
+
+DROP TABLE IF EXISTS product_serials;
+
+--bun:split
+
+ALTER TABLE products DROP COLUMN IF EXISTS serial_tracked;
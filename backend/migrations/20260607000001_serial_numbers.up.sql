@@ -0,0 +1,64 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE products ADD COLUMN serial_tracked BOOLEAN NOT NULL DEFAULT false;
+
+--bun:split
+
+-- product_serials: one row per physical unit of a serial-tracked product.
+-- Created at PO receipt (linked to the batch), linked to the order line when
+-- sold. warranty_expires_at is fixed at sale time from the product's
+-- warranty_months so later edits don't move existing warranties.
+CREATE TABLE product_serials (
+    id                   UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    serial               TEXT         NOT NULL,
+    product_id           UUID         NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id           UUID         REFERENCES product_variants(id) ON DELETE SET NULL,
+    batch_id             UUID         REFERENCES batches(id) ON DELETE SET NULL,
+    status               TEXT         NOT NULL DEFAULT 'in_stock'
+                                      CHECK (status IN ('in_stock', 'sold', 'returned', 'rma')),
+    order_id             UUID         REFERENCES orders(id) ON DELETE SET NULL,
+    order_line_id        UUID         REFERENCES order_lines(id) ON DELETE SET NULL,
+    customer_id          UUID         REFERENCES customers(id) ON DELETE SET NULL,
+    sold_at              TIMESTAMPTZ,
+    warranty_expires_at  TEXT         NOT NULL DEFAULT '',
+    created_at           TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE (product_id, serial)
+);
+
+CREATE INDEX product_serials_serial_idx ON product_serials(serial);
+CREATE INDEX product_serials_batch_idx  ON product_serials(batch_id);
+CREATE INDEX product_serials_order_idx  ON product_serials(order_id);
+
+--bun:split
+
+ALTER TABLE order_lines ADD COLUMN serials TEXT[] NOT NULL DEFAULT '{}';
+
+--bun:split
+
+-- warranty_claims: a unit sent back to its supplier under warranty (RMA).
+-- open → sent → resolved (repaired / replaced / rejected) or cancelled.
+CREATE TABLE warranty_claims (
+    id                  UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    code                TEXT         NOT NULL UNIQUE,
+    serial_id           UUID         NOT NULL REFERENCES product_serials(id) ON DELETE RESTRICT,
+    supplier_id         UUID         REFERENCES suppliers(id) ON DELETE SET NULL,
+    customer_id         UUID         REFERENCES customers(id) ON DELETE SET NULL,
+    status              TEXT         NOT NULL DEFAULT 'open'
+                                     CHECK (status IN ('open', 'sent', 'resolved', 'cancelled')),
+    complaint           TEXT         NOT NULL DEFAULT '',
+    resolution          TEXT         NOT NULL DEFAULT '',
+    replacement_serial  TEXT         NOT NULL DEFAULT '',
+    supplier_reference  TEXT         NOT NULL DEFAULT '',
+    sent_at             TIMESTAMPTZ,
+    resolved_at         TIMESTAMPTZ,
+    notes               TEXT         NOT NULL DEFAULT '',
+    created_by          TEXT         NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX warranty_claims_serial_idx ON warranty_claims(serial_id);
+CREATE INDEX warranty_claims_status_idx ON warranty_claims(status);
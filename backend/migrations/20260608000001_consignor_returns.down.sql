@@ -0,0 +1,9 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP TABLE IF EXISTS consignor_return_lines;
+
+--bun:split
+
+DROP TABLE IF EXISTS consignor_returns;
@@ -0,0 +1,41 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- consignor_returns: unsold consignment stock handed back to its consignor.
+-- Posted on creation — the batch decrements and return-consignor movements
+-- are written in the same transaction — so there is no draft state.
+CREATE TABLE consignor_returns (
+    id              UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code            TEXT           NOT NULL UNIQUE,
+    supplier_id     UUID           NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    returned_at     TEXT           NOT NULL DEFAULT '',
+    handed_over_by  TEXT           NOT NULL DEFAULT '',
+    received_by     TEXT           NOT NULL DEFAULT '',
+    total_qty       NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    total_value     NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    notes           TEXT           NOT NULL DEFAULT '',
+    created_by      TEXT           NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX consignor_returns_supplier_idx ON consignor_returns(supplier_id, returned_at);
+
+--bun:split
+
+CREATE TABLE consignor_return_lines (
+    id           UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id    UUID           NOT NULL REFERENCES consignor_returns(id) ON DELETE CASCADE,
+    batch_id     UUID           NOT NULL REFERENCES batches(id) ON DELETE RESTRICT,
+    product_id   UUID           NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    variant_id   UUID           REFERENCES product_variants(id) ON DELETE SET NULL,
+    location_id  UUID           NOT NULL REFERENCES locations(id) ON DELETE RESTRICT,
+    qty          NUMERIC(14,4)  NOT NULL CHECK (qty > 0),
+    unit_cost    NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    reason       TEXT           NOT NULL DEFAULT '',
+    position     INT            NOT NULL DEFAULT 0
+);
+
+CREATE INDEX consignor_return_lines_return_idx ON consignor_return_lines(return_id);
+CREATE INDEX consignor_return_lines_batch_idx  ON consignor_return_lines(batch_id);
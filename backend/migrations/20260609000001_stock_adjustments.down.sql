@@ -0,0 +1,5 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP TABLE IF EXISTS stock_adjustments;
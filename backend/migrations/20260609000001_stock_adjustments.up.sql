@@ -0,0 +1,39 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- stock_adjustments: manual stock corrections and batch write-offs with a
+-- structured reason code. Anything at or above the configured value
+-- threshold (or with a reason that always needs review) is stored as
+-- `pending` and only touches stock once approved; smaller ones are posted
+-- straight away. The decided_* columns are the approval history.
+CREATE TABLE stock_adjustments (
+    id                 UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code               TEXT           NOT NULL UNIQUE,
+    kind               TEXT           NOT NULL DEFAULT 'adjust'
+                                      CHECK (kind IN ('adjust', 'write-off')),
+    status             TEXT           NOT NULL DEFAULT 'pending'
+                                      CHECK (status IN ('pending', 'posted', 'rejected')),
+    product_id         UUID           NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id         UUID           REFERENCES product_variants(id) ON DELETE SET NULL,
+    location_id        UUID           NOT NULL REFERENCES locations(id) ON DELETE RESTRICT,
+    batch_id           UUID           REFERENCES batches(id) ON DELETE SET NULL,
+    qty_delta          NUMERIC(14,4)  NOT NULL CHECK (qty_delta <> 0),
+    unit_cost          NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    value              NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    reason_code        TEXT           NOT NULL,
+    expires_at         TEXT           NOT NULL DEFAULT '',
+    image_url          TEXT           NOT NULL DEFAULT '',
+    notes              TEXT           NOT NULL DEFAULT '',
+    requested_by       TEXT           NOT NULL DEFAULT '',
+    requested_by_id    UUID           REFERENCES users(id) ON DELETE SET NULL,
+    decided_by         TEXT           NOT NULL DEFAULT '',
+    decided_by_id      UUID           REFERENCES users(id) ON DELETE SET NULL,
+    decided_at         TIMESTAMPTZ,
+    decision_notes     TEXT           NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at         TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX stock_adjustments_status_idx  ON stock_adjustments(status, created_at);
+CREATE INDEX stock_adjustments_product_idx ON stock_adjustments(product_id);
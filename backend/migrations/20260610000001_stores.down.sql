@@ -0,0 +1,32 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE pricelists DROP COLUMN IF EXISTS store_id;
+
+--bun:split
+
+DROP INDEX IF EXISTS orders_store_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS store_id;
+
+--bun:split
+
+DROP INDEX IF EXISTS shift_sessions_one_open;
+ALTER TABLE shift_sessions
+    DROP COLUMN IF EXISTS register_id,
+    DROP COLUMN IF EXISTS store_id;
+CREATE UNIQUE INDEX shift_sessions_one_open ON shift_sessions((status)) WHERE status = 'open';
+
+--bun:split
+
+DROP INDEX IF EXISTS locations_one_default_receipt;
+ALTER TABLE locations DROP COLUMN IF EXISTS store_id;
+CREATE UNIQUE INDEX locations_one_default_receipt
+    ON locations ((is_default_receipt))
+    WHERE is_default_receipt;
+
+--bun:split
+
+DROP TABLE IF EXISTS user_stores;
+DROP TABLE IF EXISTS registers;
+DROP TABLE IF EXISTS stores;
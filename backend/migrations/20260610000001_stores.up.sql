@@ -0,0 +1,93 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- stores: a physical outlet. Locations (and therefore stock), registers,
+-- shifts, orders and store-specific pricelists hang off a store. Existing
+-- data is moved into a single default store below so a one-shop install
+-- keeps working unchanged.
+CREATE TABLE stores (
+    id                   UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    code                 TEXT        NOT NULL UNIQUE,
+    name                 TEXT        NOT NULL,
+    address              TEXT        NOT NULL DEFAULT '',
+    phone                TEXT        NOT NULL DEFAULT '',
+    status               TEXT        NOT NULL DEFAULT 'active',
+    default_pricelist_id TEXT        REFERENCES pricelists(id) ON DELETE SET NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO stores (code, name) VALUES ('MAIN', 'Toko Utama');
+
+--bun:split
+
+-- registers: the tills inside a store. A shift may be opened against a
+-- register; shifts without one are store-wide (the pre-multi-store shape).
+CREATE TABLE registers (
+    id         UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    store_id   UUID        NOT NULL REFERENCES stores(id) ON DELETE RESTRICT,
+    code       TEXT        NOT NULL,
+    name       TEXT        NOT NULL,
+    status     TEXT        NOT NULL DEFAULT 'active',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (store_id, code)
+);
+
+--bun:split
+
+-- user_stores: which stores an employee works in. is_default picks the
+-- store used when a request doesn't name one (X-Store-ID header).
+CREATE TABLE user_stores (
+    user_id    UUID    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    store_id   UUID    NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (user_id, store_id)
+);
+
+CREATE UNIQUE INDEX user_stores_one_default ON user_stores(user_id) WHERE is_default;
+
+INSERT INTO user_stores (user_id, store_id, is_default)
+SELECT u.id, s.id, true FROM users AS u CROSS JOIN stores AS s WHERE s.code = 'MAIN';
+
+--bun:split
+
+ALTER TABLE locations ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE RESTRICT;
+UPDATE locations SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE locations ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX locations_store_idx ON locations(store_id);
+
+-- The default receipt location is now one per store.
+DROP INDEX IF EXISTS locations_one_default_receipt;
+CREATE UNIQUE INDEX locations_one_default_receipt
+    ON locations(store_id)
+    WHERE is_default_receipt;
+
+--bun:split
+
+ALTER TABLE shift_sessions
+    ADD COLUMN store_id    UUID REFERENCES stores(id) ON DELETE RESTRICT,
+    ADD COLUMN register_id UUID REFERENCES registers(id) ON DELETE SET NULL;
+UPDATE shift_sessions SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE shift_sessions ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX shift_sessions_store_idx ON shift_sessions(store_id);
+
+-- One open shift per register, and one open register-less shift per store.
+-- Same index name as before so the handlers' error mapping still applies.
+DROP INDEX IF EXISTS shift_sessions_one_open;
+CREATE UNIQUE INDEX shift_sessions_one_open
+    ON shift_sessions(store_id, COALESCE(register_id, '00000000-0000-0000-0000-000000000000'::uuid))
+    WHERE status = 'open';
+
+--bun:split
+
+ALTER TABLE orders ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE RESTRICT;
+UPDATE orders SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE orders ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX orders_store_idx ON orders(store_id, created_at DESC);
+
+--bun:split
+
+-- NULL store_id = the pricelist is shared by every store.
+ALTER TABLE pricelists ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE CASCADE;
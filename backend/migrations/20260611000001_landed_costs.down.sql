@@ -0,0 +1,15 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS batches_receipt_idx;
+ALTER TABLE batches
+    DROP COLUMN IF EXISTS received_weight,
+    DROP COLUMN IF EXISTS landed_unit_cost,
+    DROP COLUMN IF EXISTS receipt_id;
+
+--bun:split
+
+DROP TABLE IF EXISTS landed_cost_allocations;
+DROP TABLE IF EXISTS purchase_order_landed_costs;
+DROP TABLE IF EXISTS purchase_order_receipts;
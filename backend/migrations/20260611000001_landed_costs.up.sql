@@ -0,0 +1,71 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- purchase_order_receipts: one row per POST /purchase-orders/{id}/receive.
+-- The batches it created point back at it, and landed cost charges hang off
+-- it. allocation_method is fixed by the first charge so later charges (a
+-- freight invoice that arrives a week after the goods) spread the same way.
+CREATE TABLE purchase_order_receipts (
+    id                UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    code              TEXT          NOT NULL UNIQUE,
+    purchase_order_id UUID          NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    received_date     TEXT          NOT NULL DEFAULT '',
+    allocation_method TEXT          NOT NULL DEFAULT 'value'
+                                    CHECK (allocation_method IN ('value', 'qty', 'weight')),
+    landed_cost_total NUMERIC(14,2) NOT NULL DEFAULT 0,
+    notes             TEXT          NOT NULL DEFAULT '',
+    performed_by      TEXT          NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX purchase_order_receipts_po_idx ON purchase_order_receipts(purchase_order_id);
+
+--bun:split
+
+-- Landed cost charges (freight, unloading, duties, ...) on a receipt. The
+-- supplier is whoever billed the charge — often a forwarder, not the PO's
+-- supplier.
+CREATE TABLE purchase_order_landed_costs (
+    id          UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    receipt_id  UUID          NOT NULL REFERENCES purchase_order_receipts(id) ON DELETE CASCADE,
+    kind        TEXT          NOT NULL
+                              CHECK (kind IN ('freight', 'unloading', 'duty', 'insurance', 'other')),
+    amount      NUMERIC(14,2) NOT NULL CHECK (amount > 0),
+    supplier_id UUID          REFERENCES suppliers(id) ON DELETE SET NULL,
+    reference   TEXT          NOT NULL DEFAULT '',
+    notes       TEXT          NOT NULL DEFAULT '',
+    created_by  TEXT          NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX purchase_order_landed_costs_receipt_idx ON purchase_order_landed_costs(receipt_id);
+
+--bun:split
+
+-- How each charge was split over the receipt's batches. unit_amount is what
+-- got added to the batch's unit_cost.
+CREATE TABLE landed_cost_allocations (
+    id             UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    landed_cost_id UUID          NOT NULL REFERENCES purchase_order_landed_costs(id) ON DELETE CASCADE,
+    batch_id       UUID          NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    basis          NUMERIC(14,4) NOT NULL DEFAULT 0,
+    amount         NUMERIC(14,2) NOT NULL DEFAULT 0,
+    unit_amount    NUMERIC(14,4) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX landed_cost_allocations_charge_idx ON landed_cost_allocations(landed_cost_id);
+CREATE INDEX landed_cost_allocations_batch_idx  ON landed_cost_allocations(batch_id);
+
+--bun:split
+
+-- landed_unit_cost is the part of unit_cost that came from landed charges,
+-- so the supplier price stays recoverable (unit_cost - landed_unit_cost).
+-- received_weight is the line's total weight as weighed on receipt, the
+-- basis for weight allocation.
+ALTER TABLE batches
+    ADD COLUMN receipt_id       UUID          REFERENCES purchase_order_receipts(id) ON DELETE SET NULL,
+    ADD COLUMN landed_unit_cost NUMERIC(14,4) NOT NULL DEFAULT 0,
+    ADD COLUMN received_weight  NUMERIC(14,4) NOT NULL DEFAULT 0;
+
+CREATE INDEX batches_receipt_idx ON batches(receipt_id) WHERE receipt_id IS NOT NULL;
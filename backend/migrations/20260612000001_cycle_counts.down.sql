@@ -0,0 +1,12 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS stock_opnames_cycle_day;
+ALTER TABLE stock_opnames
+    DROP COLUMN IF EXISTS scheduled_for,
+    DROP COLUMN IF EXISTS kind;
+
+--bun:split
+
+DROP TABLE IF EXISTS product_abc_classes;
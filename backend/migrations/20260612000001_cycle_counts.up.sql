@@ -0,0 +1,32 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- product_abc_classes: latest ABC classification by sales value. Products
+-- are ranked by net sales over the lookback window; those making up the
+-- first 80% of value are A, the next 15% B, the rest (including products
+-- that didn't sell) C — thresholds come from settings. Rewritten wholesale
+-- by each classification run.
+CREATE TABLE product_abc_classes (
+    product_id     UUID          PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    abc_class      TEXT          NOT NULL CHECK (abc_class IN ('A', 'B', 'C')),
+    sales_value    NUMERIC(14,2) NOT NULL DEFAULT 0,
+    share_pct      NUMERIC(7,4)  NOT NULL DEFAULT 0,
+    cumulative_pct NUMERIC(7,4)  NOT NULL DEFAULT 0,
+    rank           INTEGER       NOT NULL DEFAULT 0,
+    classified_at  TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX product_abc_classes_class_idx ON product_abc_classes(abc_class);
+
+--bun:split
+
+-- Cycle counts are ordinary opnames of kind 'cycle' generated by the daily
+-- scheduler: one small draft per location per day (scheduled_for), scoped
+-- to the products due for a count.
+ALTER TABLE stock_opnames
+    ADD COLUMN kind          TEXT NOT NULL DEFAULT 'full' CHECK (kind IN ('full', 'cycle')),
+    ADD COLUMN scheduled_for TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX stock_opnames_cycle_day
+    ON stock_opnames(location_id, scheduled_for) WHERE kind = 'cycle';
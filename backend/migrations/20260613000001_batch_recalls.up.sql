@@ -0,0 +1,20 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- batch_recalls: a recall raised against a batch (a supplier lot, usually).
+-- The recall quarantines the batch and everything made or split from it
+-- that is still on hand; batch_ids records exactly which batches it
+-- quarantined so the action can be audited later.
+CREATE TABLE batch_recalls (
+    id           UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    code         TEXT          NOT NULL UNIQUE,
+    batch_id     UUID          NOT NULL REFERENCES batches(id) ON DELETE RESTRICT,
+    reason       TEXT          NOT NULL DEFAULT '',
+    batch_ids    UUID[]        NOT NULL DEFAULT '{}',
+    qty_on_hand  NUMERIC(14,4) NOT NULL DEFAULT 0,
+    performed_by TEXT          NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX batch_recalls_batch_idx ON batch_recalls(batch_id);
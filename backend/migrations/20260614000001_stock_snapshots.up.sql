@@ -0,0 +1,38 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- stock_snapshot_days: one row per snapshotted day. A day is only read by
+-- reports once its row exists, so a half-written snapshot is never used.
+-- source is how it was taken: job (nightly), backfill or repair.
+CREATE TABLE stock_snapshot_days (
+    snapshot_date     TEXT          PRIMARY KEY,
+    rows              INTEGER       NOT NULL DEFAULT 0,
+    owned_qty         NUMERIC(14,4) NOT NULL DEFAULT 0,
+    owned_value       NUMERIC(14,2) NOT NULL DEFAULT 0,
+    consignment_qty   NUMERIC(14,4) NOT NULL DEFAULT 0,
+    consignment_value NUMERIC(14,2) NOT NULL DEFAULT 0,
+    source            TEXT          NOT NULL DEFAULT 'job' CHECK (source IN ('job', 'backfill', 'repair')),
+    taken_at          TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+--bun:split
+
+-- stock_snapshots: end-of-day quantity and value per batch (and so per
+-- product/variant/location). Batches with nothing on hand are left out.
+CREATE TABLE stock_snapshots (
+    snapshot_date TEXT          NOT NULL REFERENCES stock_snapshot_days(snapshot_date) ON DELETE CASCADE,
+    batch_id      UUID          NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    product_id    UUID          NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id    UUID          REFERENCES product_variants(id) ON DELETE CASCADE,
+    location_id   UUID          NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    store_id      UUID          NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+    ownership     TEXT          NOT NULL,
+    qty           NUMERIC(14,4) NOT NULL,
+    unit_cost     NUMERIC(14,2) NOT NULL DEFAULT 0,
+    value         NUMERIC(14,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (snapshot_date, batch_id)
+);
+
+CREATE INDEX stock_snapshots_product_idx  ON stock_snapshots(product_id, snapshot_date);
+CREATE INDEX stock_snapshots_location_idx ON stock_snapshots(location_id, snapshot_date);
@@ -0,0 +1,17 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS debit_note_amount;
+
+--bun:split
+
+DROP TABLE IF EXISTS supplier_debit_notes;
+
+--bun:split
+
+DROP TABLE IF EXISTS purchase_return_lines;
+
+--bun:split
+
+DROP TABLE IF EXISTS purchase_returns;
@@ -0,0 +1,68 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- purchase_returns: damaged or unwanted owned stock sent back to the
+-- supplier for credit (retur pembelian). Posted on creation like consignor
+-- returns — batch decrements, return-supplier movements and the debit note
+-- are written in the same transaction.
+CREATE TABLE purchase_returns (
+    id                UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code              TEXT           NOT NULL UNIQUE,
+    supplier_id       UUID           NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    purchase_order_id UUID           REFERENCES purchase_orders(id) ON DELETE RESTRICT,
+    returned_at       TEXT           NOT NULL DEFAULT '',
+    total_qty         NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    total_value       NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    notes             TEXT           NOT NULL DEFAULT '',
+    created_by        TEXT           NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX purchase_returns_supplier_idx ON purchase_returns(supplier_id, returned_at);
+CREATE INDEX purchase_returns_po_idx       ON purchase_returns(purchase_order_id);
+
+--bun:split
+
+CREATE TABLE purchase_return_lines (
+    id           UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id    UUID           NOT NULL REFERENCES purchase_returns(id) ON DELETE CASCADE,
+    batch_id     UUID           NOT NULL REFERENCES batches(id) ON DELETE RESTRICT,
+    product_id   UUID           NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    variant_id   UUID           REFERENCES product_variants(id) ON DELETE SET NULL,
+    location_id  UUID           NOT NULL REFERENCES locations(id) ON DELETE RESTRICT,
+    qty          NUMERIC(14,4)  NOT NULL CHECK (qty > 0),
+    unit_cost    NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    reason       TEXT           NOT NULL DEFAULT 'damaged'
+                                CHECK (reason IN ('damaged', 'expired', 'defective', 'wrong-item', 'other')),
+    notes        TEXT           NOT NULL DEFAULT '',
+    position     INT            NOT NULL DEFAULT 0
+);
+
+CREATE INDEX purchase_return_lines_return_idx ON purchase_return_lines(return_id);
+CREATE INDEX purchase_return_lines_batch_idx  ON purchase_return_lines(batch_id);
+
+--bun:split
+
+-- supplier_debit_notes: what the supplier owes us back for a return. One per
+-- return. When the return is tied to a PO the amount is also added to
+-- purchase_orders.debit_note_amount, which lowers that PO's outstanding
+-- payable; otherwise it stays as credit on the supplier.
+CREATE TABLE supplier_debit_notes (
+    id                UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code              TEXT           NOT NULL UNIQUE,
+    supplier_id       UUID           NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    purchase_order_id UUID           REFERENCES purchase_orders(id) ON DELETE RESTRICT,
+    return_id         UUID           NOT NULL UNIQUE REFERENCES purchase_returns(id) ON DELETE CASCADE,
+    amount            NUMERIC(14,2)  NOT NULL CHECK (amount >= 0),
+    issued_at         TEXT           NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX supplier_debit_notes_supplier_idx ON supplier_debit_notes(supplier_id, issued_at);
+
+--bun:split
+
+ALTER TABLE purchase_orders
+    ADD COLUMN debit_note_amount NUMERIC(14,2) NOT NULL DEFAULT 0;
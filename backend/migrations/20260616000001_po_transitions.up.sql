@@ -0,0 +1,22 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- purchase_order_transitions: audit log of every status change on a PO,
+-- written by the transition endpoints and by receive. from_status is ''
+-- for the create entry.
+CREATE TABLE purchase_order_transitions (
+    id                UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    purchase_order_id UUID          NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    action            TEXT          NOT NULL
+                                    CHECK (action IN ('create', 'submit', 'approve', 'reject', 'send', 'receive', 'close', 'cancel')),
+    from_status       TEXT          NOT NULL DEFAULT '',
+    to_status         TEXT          NOT NULL,
+    po_total          NUMERIC(14,2) NOT NULL DEFAULT 0,
+    actor             TEXT          NOT NULL DEFAULT '',
+    actor_id          UUID          REFERENCES users(id) ON DELETE SET NULL,
+    notes             TEXT          NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX purchase_order_transitions_po_idx ON purchase_order_transitions(purchase_order_id, created_at);
@@ -0,0 +1,17 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE purchase_order_payments DROP COLUMN IF EXISTS supplier_payment_id;
+
+--bun:split
+
+DROP TABLE IF EXISTS supplier_payments;
+
+--bun:split
+
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS due_date;
+
+--bun:split
+
+ALTER TABLE suppliers DROP COLUMN IF EXISTS payment_terms_days;
@@ -0,0 +1,59 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Net payment terms per supplier: a received PO falls due this many days
+-- after its first receipt. 0 means due on receipt.
+ALTER TABLE suppliers
+    ADD COLUMN payment_terms_days INTEGER NOT NULL DEFAULT 0 CHECK (payment_terms_days >= 0);
+
+--bun:split
+
+-- due_date is set server-side on the first receipt of a standard PO; ''
+-- until then.
+ALTER TABLE purchase_orders ADD COLUMN due_date TEXT NOT NULL DEFAULT '';
+
+--bun:split
+
+-- Existing suppliers had no terms, so POs already received fall due on
+-- their first receipt.
+UPDATE purchase_orders AS po
+SET due_date = COALESCE(
+        (SELECT MIN(r.received_date) FROM purchase_order_receipts AS r
+         WHERE r.purchase_order_id = po.id AND r.received_date <> ''),
+        NULLIF(po.received_date, ''),
+        po.order_date)
+WHERE po.type = 'standard' AND po.status IN ('partial', 'received', 'closed');
+
+--bun:split
+
+CREATE INDEX purchase_orders_due_idx ON purchase_orders(due_date) WHERE due_date <> '';
+
+--bun:split
+
+-- supplier_payments: one transfer/cash payment to a supplier, split over
+-- one or more of its POs. Each split is a purchase_order_payments row
+-- pointing back here, so PO payment history keeps working unchanged.
+CREATE TABLE supplier_payments (
+    id           UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code         TEXT           NOT NULL UNIQUE,
+    supplier_id  UUID           NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    amount       NUMERIC(14,2)  NOT NULL CHECK (amount > 0),
+    method       TEXT           NOT NULL DEFAULT 'transfer'
+                                CHECK (method IN ('cash', 'transfer', 'other')),
+    paid_at      TEXT           NOT NULL DEFAULT '',
+    reference    TEXT           NOT NULL DEFAULT '',
+    notes        TEXT           NOT NULL DEFAULT '',
+    created_by   TEXT           NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX supplier_payments_supplier_idx ON supplier_payments(supplier_id, paid_at);
+
+--bun:split
+
+ALTER TABLE purchase_order_payments
+    ADD COLUMN supplier_payment_id UUID REFERENCES supplier_payments(id) ON DELETE RESTRICT;
+
+CREATE INDEX purchase_order_payments_supplier_payment_idx
+    ON purchase_order_payments(supplier_payment_id) WHERE supplier_payment_id IS NOT NULL;
@@ -0,0 +1,13 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS product_suppliers_sku_idx;
+
+--bun:split
+
+DROP TABLE IF EXISTS supplier_cost_history;
+
+--bun:split
+
+DROP TABLE IF EXISTS supplier_price_imports;
@@ -0,0 +1,60 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- supplier_price_imports: one uploaded supplier price list. The per-row
+-- outcome (matched product, old/new cost, why a row was skipped) is kept
+-- in lines so the import can be reviewed later.
+CREATE TABLE supplier_price_imports (
+    id              UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code            TEXT           NOT NULL UNIQUE,
+    supplier_id     UUID           NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    filename        TEXT           NOT NULL DEFAULT '',
+    effective_from  TEXT           NOT NULL,
+    rows_total      INTEGER        NOT NULL DEFAULT 0,
+    rows_updated    INTEGER        NOT NULL DEFAULT 0,
+    rows_unchanged  INTEGER        NOT NULL DEFAULT 0,
+    rows_skipped    INTEGER        NOT NULL DEFAULT 0,
+    lines           JSONB          NOT NULL DEFAULT '[]'::jsonb,
+    created_by      TEXT           NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX supplier_price_imports_supplier_idx ON supplier_price_imports(supplier_id, created_at);
+
+--bun:split
+
+-- supplier_cost_history: every change to what a supplier charges for a
+-- product, effective from a date. product_suppliers.unit_cost is the
+-- latest row whose effective_from has arrived; rows dated in the future
+-- are applied by the apply-supplier-costs job when their day comes.
+CREATE TABLE supplier_cost_history (
+    id              UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id      UUID           NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    supplier_id     UUID           NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    unit_cost       NUMERIC(14,2)  NOT NULL CHECK (unit_cost >= 0),
+    previous_cost   NUMERIC(14,2),
+    effective_from  TEXT           NOT NULL,
+    source          TEXT           NOT NULL DEFAULT 'manual'
+                                   CHECK (source IN ('initial', 'manual', 'import')),
+    import_id       UUID           REFERENCES supplier_price_imports(id) ON DELETE SET NULL,
+    created_by      TEXT           NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX supplier_cost_history_pair_idx
+    ON supplier_cost_history(product_id, supplier_id, effective_from);
+CREATE INDEX supplier_cost_history_effective_idx ON supplier_cost_history(effective_from);
+
+--bun:split
+
+-- Seed the history with today's costs so the first change has something
+-- to compare against.
+INSERT INTO supplier_cost_history (product_id, supplier_id, unit_cost, effective_from, source)
+SELECT product_id, supplier_id, unit_cost, to_char(now(), 'YYYY-MM-DD'), 'initial'
+FROM product_suppliers;
+
+--bun:split
+
+CREATE INDEX product_suppliers_sku_idx
+    ON product_suppliers(supplier_id, lower(supplier_sku)) WHERE supplier_sku <> '';
@@ -0,0 +1,25 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- purchase_order_emails: every attempt to email a PO to its supplier, with
+-- the SMTP outcome. A failed attempt leaves the PO status alone.
+CREATE TABLE purchase_order_emails (
+    id                 UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    purchase_order_id  UUID         NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    sender             TEXT         NOT NULL DEFAULT '',
+    recipients         TEXT[]       NOT NULL DEFAULT '{}',
+    cc                 TEXT[]       NOT NULL DEFAULT '{}',
+    subject            TEXT         NOT NULL DEFAULT '',
+    message            TEXT         NOT NULL DEFAULT '',
+    attachment         TEXT         NOT NULL DEFAULT '',
+    status             TEXT         NOT NULL CHECK (status IN ('sent', 'failed')),
+    error              TEXT         NOT NULL DEFAULT '',
+    sent_by            TEXT         NOT NULL DEFAULT '',
+    sent_by_id         UUID         REFERENCES users(id) ON DELETE SET NULL,
+    created_at         TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+--bun:split
+
+CREATE INDEX purchase_order_emails_po_idx ON purchase_order_emails(purchase_order_id, created_at);
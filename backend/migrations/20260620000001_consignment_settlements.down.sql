@@ -0,0 +1,14 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE payouts
+    DROP COLUMN IF EXISTS sold_units,
+    DROP COLUMN IF EXISTS settled_amount,
+    DROP COLUMN IF EXISTS returned_units,
+    DROP COLUMN IF EXISTS returned_value,
+    DROP COLUMN IF EXISTS created_by;
+
+--bun:split
+
+DROP TABLE IF EXISTS payout_allocations;
@@ -0,0 +1,64 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- payout_allocations: the consignment allocations (order line × batch) a
+-- payout settles, at the consignment unit cost stamped on the sale. The
+-- unique key is what stops the same sale from being paid out twice;
+-- deleting the payout frees its allocations again.
+CREATE TABLE payout_allocations (
+    id             UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    payout_id      UUID           NOT NULL REFERENCES payouts(id) ON DELETE CASCADE,
+    order_id       UUID           NOT NULL REFERENCES orders(id) ON DELETE RESTRICT,
+    order_line_id  UUID           NOT NULL REFERENCES order_lines(id) ON DELETE RESTRICT,
+    batch_id       UUID           NOT NULL REFERENCES batches(id) ON DELETE RESTRICT,
+    qty            NUMERIC(14,4)  NOT NULL CHECK (qty > 0),
+    unit_cost      NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    amount         NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    sold_at        TEXT           NOT NULL DEFAULT '',
+    UNIQUE (order_line_id, batch_id)
+);
+
+CREATE INDEX payout_allocations_payout_idx ON payout_allocations(payout_id);
+CREATE INDEX payout_allocations_order_idx  ON payout_allocations(order_id);
+
+--bun:split
+
+-- What the statement behind a payout showed.
+ALTER TABLE payouts
+    ADD COLUMN sold_units      NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    ADD COLUMN settled_amount  NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    ADD COLUMN returned_units  NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    ADD COLUMN returned_value  NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    ADD COLUMN created_by      TEXT           NOT NULL DEFAULT '';
+
+--bun:split
+
+-- Payouts recorded before settlements existed settle the consignment sales
+-- in the period they said they covered, oldest payout first, so those sales
+-- don't show up as owed again.
+INSERT INTO payout_allocations (payout_id, order_id, order_line_id, batch_id, qty, unit_cost, amount, sold_at)
+SELECT py.id, o.id, ol.id, bt.id,
+       SUM((a->>'qtyTaken')::numeric),
+       MAX((a->>'unitCost')::numeric),
+       SUM((a->>'qtyTaken')::numeric * (a->>'unitCost')::numeric),
+       o.created_at::date::text
+FROM payouts AS py
+JOIN orders AS o ON o.status = 'paid'
+    AND o.created_at::date >= COALESCE(NULLIF(py.covers_period_start, '')::date, '-infinity'::date)
+    AND o.created_at::date <= COALESCE(NULLIF(py.covers_period_end, '')::date, NULLIF(py.paid_at, '')::date, 'infinity'::date)
+JOIN order_lines AS ol ON ol.order_id = o.id
+CROSS JOIN LATERAL jsonb_array_elements(ol.batch_allocations) AS a
+JOIN batches AS bt ON bt.id::text = a->>'batchId'
+WHERE a->>'ownership' = 'consignment'
+  AND a->>'supplierId' = py.supplier_id::text
+  AND (a->>'qtyTaken')::numeric > 0
+GROUP BY py.id, py.paid_at, py.created_at, o.id, ol.id, bt.id
+ORDER BY py.paid_at, py.created_at
+ON CONFLICT (order_line_id, batch_id) DO NOTHING;
+
+--bun:split
+
+UPDATE payouts AS py
+SET sold_units = COALESCE((SELECT SUM(pa.qty) FROM payout_allocations AS pa WHERE pa.payout_id = py.id), 0),
+    settled_amount = COALESCE((SELECT SUM(pa.amount) FROM payout_allocations AS pa WHERE pa.payout_id = py.id), 0);
@@ -0,0 +1,9 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP TABLE IF EXISTS supplier_invoice_lines;
+
+--bun:split
+
+DROP TABLE IF EXISTS supplier_invoices;
@@ -0,0 +1,86 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- supplier_invoices: a supplier's bill against one or more of its standard
+-- POs. Every line is three-way matched (PO ordered qty/price, qty received
+-- into batches, invoiced qty/price) within the purchasing tolerances; only
+-- matched invoices count toward accounts payable.
+CREATE TABLE supplier_invoices (
+    id                  UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    code                TEXT           NOT NULL UNIQUE,
+    supplier_id         UUID           NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    invoice_number      TEXT           NOT NULL,
+    invoice_date        TEXT           NOT NULL DEFAULT '',
+    tax_invoice_number  TEXT           NOT NULL DEFAULT '',
+    status              TEXT           NOT NULL DEFAULT 'discrepancy'
+                                       CHECK (status IN ('matched', 'discrepancy', 'cancelled')),
+    total               NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    discrepancies       INTEGER        NOT NULL DEFAULT 0,
+    matched_at          TIMESTAMPTZ,
+    notes               TEXT           NOT NULL DEFAULT '',
+    created_by          TEXT           NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX supplier_invoices_supplier_idx ON supplier_invoices(supplier_id, invoice_date);
+-- A supplier's invoice number is only entered once (cancelled ones aside).
+CREATE UNIQUE INDEX supplier_invoices_number_idx
+    ON supplier_invoices(supplier_id, lower(invoice_number)) WHERE status <> 'cancelled';
+
+--bun:split
+
+-- One invoiced PO line, with the figures it was matched against at the
+-- time: ordered qty and price from the PO line, qty received into its
+-- batches, and qty already billed on the supplier's other invoices.
+CREATE TABLE supplier_invoice_lines (
+    id                      UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id              UUID           NOT NULL REFERENCES supplier_invoices(id) ON DELETE CASCADE,
+    purchase_order_id       UUID           NOT NULL REFERENCES purchase_orders(id) ON DELETE RESTRICT,
+    purchase_order_line_id  UUID           NOT NULL REFERENCES purchase_order_lines(id) ON DELETE RESTRICT,
+    qty                     NUMERIC(14,4)  NOT NULL CHECK (qty > 0),
+    unit_price              NUMERIC(14,2)  NOT NULL DEFAULT 0 CHECK (unit_price >= 0),
+    amount                  NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    ordered_qty             NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    po_unit_price           NUMERIC(14,2)  NOT NULL DEFAULT 0,
+    received_qty            NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    invoiced_before         NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    match_status            TEXT           NOT NULL DEFAULT 'ok'
+                                           CHECK (match_status IN ('ok', 'price', 'qty-received', 'qty-ordered')),
+    match_note              TEXT           NOT NULL DEFAULT '',
+    position                INTEGER        NOT NULL DEFAULT 0
+);
+
+CREATE INDEX supplier_invoice_lines_invoice_idx ON supplier_invoice_lines(invoice_id);
+CREATE INDEX supplier_invoice_lines_po_idx      ON supplier_invoice_lines(purchase_order_id);
+CREATE INDEX supplier_invoice_lines_pol_idx     ON supplier_invoice_lines(purchase_order_line_id);
+
+--bun:split
+
+-- Payables used to count every ordered PO in full. Keep existing balances
+-- by giving each payable standard PO a matched invoice for its PO total.
+INSERT INTO supplier_invoices
+    (code, supplier_id, invoice_number, invoice_date, status, total, matched_at, notes, created_at, updated_at)
+SELECT 'SINV-' || po.code, po.supplier_id, po.code,
+       COALESCE(NULLIF(po.received_date, ''), po.order_date), 'matched',
+       COALESCE((SELECT SUM(pol.quantity * pol.unit_price)
+                 FROM purchase_order_lines AS pol WHERE pol.purchase_order_id = po.id), 0),
+       now(), 'Dibuat otomatis dari total PO sebelum pencocokan faktur.', now(), now()
+FROM purchase_orders AS po
+WHERE po.type = 'standard'
+  AND po.status IN ('sent', 'partial', 'received', 'closed')
+  AND EXISTS (SELECT 1 FROM purchase_order_lines AS pol
+              WHERE pol.purchase_order_id = po.id AND pol.quantity > 0);
+
+--bun:split
+
+INSERT INTO supplier_invoice_lines
+    (invoice_id, purchase_order_id, purchase_order_line_id, qty, unit_price, amount,
+     ordered_qty, po_unit_price, received_qty, position)
+SELECT si.id, po.id, pol.id, pol.quantity, pol.unit_price, ROUND(pol.quantity * pol.unit_price, 2),
+       pol.quantity, pol.unit_price, pol.received_qty, pol.position
+FROM supplier_invoices AS si
+JOIN purchase_orders AS po ON si.code = 'SINV-' || po.code
+JOIN purchase_order_lines AS pol ON pol.purchase_order_id = po.id
+WHERE pol.quantity > 0;
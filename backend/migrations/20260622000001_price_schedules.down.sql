@@ -0,0 +1,9 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP TABLE IF EXISTS price_schedule_items;
+
+--bun:split
+
+DROP TABLE IF EXISTS price_schedules;
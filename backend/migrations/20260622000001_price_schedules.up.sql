@@ -0,0 +1,51 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- price_schedules: a batch of product prices that takes effect at
+-- effective_at. The apply-price-schedules job writes each item into
+-- product_prices/product_price_tiers once the time arrives and logs the
+-- change to price_changes with source 'scheduled'.
+CREATE TABLE price_schedules (
+    id             UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    code           TEXT         NOT NULL UNIQUE,
+    effective_at   TIMESTAMPTZ  NOT NULL,
+    status         TEXT         NOT NULL DEFAULT 'pending'
+                                CHECK (status IN ('pending', 'applied', 'cancelled')),
+    notes          TEXT         NOT NULL DEFAULT '',
+    items_applied  INTEGER      NOT NULL DEFAULT 0,
+    items_skipped  INTEGER      NOT NULL DEFAULT 0,
+    applied_at     TIMESTAMPTZ,
+    cancelled_by   TEXT         NOT NULL DEFAULT '',
+    cancelled_at   TIMESTAMPTZ,
+    created_by     TEXT         NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX price_schedules_pending_idx ON price_schedules(effective_at) WHERE status = 'pending';
+
+--bun:split
+
+-- One scheduled pricelist entry. The target uses the same scopes as
+-- product_prices, except packagings are addressed by position since their
+-- rows are replaced on every product save. tiers replaces the entry's
+-- tiers wholesale. result is '' until the schedule runs, then 'applied'
+-- or 'skipped' (target gone) with result_note saying why.
+CREATE TABLE price_schedule_items (
+    id               UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    schedule_id      UUID           NOT NULL REFERENCES price_schedules(id) ON DELETE CASCADE,
+    product_id       UUID           NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id       UUID           REFERENCES product_variants(id) ON DELETE CASCADE,
+    packaging_index  INTEGER,
+    pricelist_id     TEXT           NOT NULL REFERENCES pricelists(id) ON DELETE CASCADE,
+    pricing_kind     TEXT           NOT NULL,
+    pricing_value    NUMERIC(14,4)  NOT NULL DEFAULT 0,
+    tiers            JSONB          NOT NULL DEFAULT '[]'::jsonb,
+    result           TEXT           NOT NULL DEFAULT '',
+    result_note      TEXT           NOT NULL DEFAULT '',
+    position         INTEGER        NOT NULL DEFAULT 0,
+    CHECK (NOT (variant_id IS NOT NULL AND packaging_index IS NOT NULL))
+);
+
+CREATE INDEX price_schedule_items_schedule_idx ON price_schedule_items(schedule_id, position);
+CREATE INDEX price_schedule_items_product_idx ON price_schedule_items(product_id);
@@ -0,0 +1,5 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE warranty_claims DROP COLUMN IF EXISTS serial_status_before;
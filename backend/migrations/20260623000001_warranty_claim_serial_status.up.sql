@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- The serial's status when the claim was opened (sold or returned), so a
+-- cancelled / repaired / rejected claim puts the unit back where it was.
+ALTER TABLE warranty_claims ADD COLUMN serial_status_before TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,21 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS payouts_store_idx;
+ALTER TABLE payouts DROP COLUMN IF EXISTS store_id;
+
+--bun:split
+
+DROP INDEX IF EXISTS consignor_returns_store_idx;
+ALTER TABLE consignor_returns DROP COLUMN IF EXISTS store_id;
+
+--bun:split
+
+DROP INDEX IF EXISTS purchase_returns_store_idx;
+ALTER TABLE purchase_returns DROP COLUMN IF EXISTS store_id;
+
+--bun:split
+
+DROP INDEX IF EXISTS purchase_orders_store_idx;
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS store_id;
@@ -0,0 +1,31 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Purchasing and consignment documents belong to the store that raised
+-- them; existing rows go to the main store.
+ALTER TABLE purchase_orders ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE RESTRICT;
+UPDATE purchase_orders SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE purchase_orders ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX purchase_orders_store_idx ON purchase_orders(store_id);
+
+--bun:split
+
+ALTER TABLE purchase_returns ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE RESTRICT;
+UPDATE purchase_returns SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE purchase_returns ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX purchase_returns_store_idx ON purchase_returns(store_id);
+
+--bun:split
+
+ALTER TABLE consignor_returns ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE RESTRICT;
+UPDATE consignor_returns SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE consignor_returns ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX consignor_returns_store_idx ON consignor_returns(store_id);
+
+--bun:split
+
+ALTER TABLE payouts ADD COLUMN store_id UUID REFERENCES stores(id) ON DELETE RESTRICT;
+UPDATE payouts SET store_id = (SELECT id FROM stores WHERE code = 'MAIN');
+ALTER TABLE payouts ALTER COLUMN store_id SET NOT NULL;
+CREATE INDEX payouts_store_idx ON payouts(store_id);
@@ -0,0 +1,5 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE batch_recalls DROP COLUMN IF EXISTS released_batch_ids;
@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Batches a recall quarantined that were later cleared and put back on
+-- sale. A batch stays under recall while it is in batch_ids but not here.
+ALTER TABLE batch_recalls ADD COLUMN released_batch_ids UUID[] NOT NULL DEFAULT '{}';
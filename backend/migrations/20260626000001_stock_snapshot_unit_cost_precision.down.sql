@@ -0,0 +1,5 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE stock_snapshots ALTER COLUMN unit_cost TYPE NUMERIC(14,2);
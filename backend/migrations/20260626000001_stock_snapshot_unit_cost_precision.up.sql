@@ -0,0 +1,8 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Batch costs carry four decimals (batches.unit_cost is NUMERIC(14,4));
+-- rounding them to two here skewed per-unit figures read back from the
+-- snapshots.
+ALTER TABLE stock_snapshots ALTER COLUMN unit_cost TYPE NUMERIC(14,4);
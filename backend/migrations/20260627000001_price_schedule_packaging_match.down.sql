@@ -0,0 +1,23 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+UPDATE price_schedules SET status = 'pending' WHERE status = 'failed';
+
+--bun:split
+
+ALTER TABLE price_schedules DROP CONSTRAINT IF EXISTS price_schedules_status_check;
+
+--bun:split
+
+ALTER TABLE price_schedules
+    ADD CONSTRAINT price_schedules_status_check
+        CHECK (status IN ('pending', 'applied', 'cancelled')),
+    DROP COLUMN IF EXISTS failure_note,
+    DROP COLUMN IF EXISTS failed_at;
+
+--bun:split
+
+ALTER TABLE price_schedule_items
+    DROP COLUMN IF EXISTS packaging_unit_id,
+    DROP COLUMN IF EXISTS packaging_factor;
@@ -0,0 +1,40 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Packagings are addressed by position, but a product save can reorder
+-- or replace them before the schedule runs. Keep the unit and factor the
+-- item was scheduled for so the job can find that packaging again, or
+-- skip the item when it is gone.
+ALTER TABLE price_schedule_items
+    ADD COLUMN packaging_unit_id TEXT,
+    ADD COLUMN packaging_factor  NUMERIC(14,4);
+
+--bun:split
+
+UPDATE price_schedule_items psi
+SET packaging_unit_id = pkg.unit_id::text,
+    packaging_factor  = pkg.factor
+FROM (
+    SELECT product_id, unit_id, factor,
+           row_number() OVER (PARTITION BY product_id ORDER BY position) - 1 AS idx
+    FROM product_packagings
+) pkg
+WHERE psi.packaging_index IS NOT NULL
+  AND psi.result = ''
+  AND pkg.product_id = psi.product_id
+  AND pkg.idx = psi.packaging_index;
+
+--bun:split
+
+-- A schedule the job cannot apply is marked 'failed' with the error in
+-- failure_note instead of being retried every tick.
+ALTER TABLE price_schedules DROP CONSTRAINT IF EXISTS price_schedules_status_check;
+
+--bun:split
+
+ALTER TABLE price_schedules
+    ADD CONSTRAINT price_schedules_status_check
+        CHECK (status IN ('pending', 'applied', 'cancelled', 'failed')),
+    ADD COLUMN failure_note TEXT NOT NULL DEFAULT '',
+    ADD COLUMN failed_at    TIMESTAMPTZ;
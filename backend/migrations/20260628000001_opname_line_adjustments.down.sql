@@ -0,0 +1,5 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE stock_opname_lines DROP COLUMN IF EXISTS adjustment_id;
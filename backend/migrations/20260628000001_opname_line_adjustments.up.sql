@@ -0,0 +1,10 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- An opname variance at or above the adjustment approval threshold is not
+-- posted on completion; it becomes a pending stock adjustment like any
+-- other and only touches stock once approved. adjustment_id links the
+-- line to that adjustment.
+ALTER TABLE stock_opname_lines
+    ADD COLUMN adjustment_id UUID REFERENCES stock_adjustments(id) ON DELETE SET NULL;
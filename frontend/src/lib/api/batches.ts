@@ -1,6 +1,5 @@
 import { apiFetch } from './client';
 
-export type BatchPayload = Record<string, unknown>;
 export type BatchRecord = Record<string, unknown>;
 
 export function listBatches(params?: {
@@ -17,13 +16,50 @@ export function listBatches(params?: {
   const qs = q.toString();
   return apiFetch<BatchRecord[]>(`/api/batches${qs ? `?${qs}` : ''}`);
 }
-export function createBatch(input: BatchPayload): Promise<BatchRecord> {
-  return apiFetch<BatchRecord>('/api/batches', { method: 'POST', body: input });
-}
 // Partial update — only sends the fields the caller wants to change.
+// Quantity and location go through the stock workflows (see moveBatch).
 export function updateBatch(
   id: string,
-  patch: { qtyRemaining?: number; locationId?: string; expiresAt?: string; notes?: string }
+  patch: { expiresAt?: string; notes?: string; status?: string }
 ): Promise<BatchRecord> {
   return apiFetch<BatchRecord>(`/api/batches/${id}`, { method: 'PATCH', body: patch });
 }
+// Moves qty to another location; returns the batch now at the destination.
+export function moveBatch(
+  id: string,
+  input: { toLocationId: string; qty: number; notes?: string; transferGroupId?: string }
+): Promise<BatchRecord> {
+  return apiFetch<BatchRecord>(`/api/batches/${id}/move`, { method: 'POST', body: input });
+}
+
+// Lineage through production runs and transfer splits. Forward also lists
+// the sales and customers of everything downstream.
+export type TraceDirection = 'forward' | 'backward' | 'both';
+export type BatchTrace = {
+  batch: BatchRecord;
+  upstream: BatchRecord[];
+  downstream: BatchRecord[];
+  productionRuns: Record<string, unknown>[];
+  sales: Record<string, unknown>[];
+  customers: { id: string; name: string; phone: string; email: string; orders: number; qty: number }[];
+  anonymousSales: number;
+  qtyOnHand: number;
+};
+export function traceBatch(id: string, direction: TraceDirection = 'both'): Promise<BatchTrace> {
+  return apiFetch<BatchTrace>(`/api/batches/${id}/trace?direction=${direction}`);
+}
+// Quarantines the batch and all downstream stock still on hand (admin).
+export function recallBatch(
+  id: string,
+  reason: string
+): Promise<{ recall: Record<string, unknown>; quarantined: BatchRecord[]; trace: BatchTrace }> {
+  return apiFetch(`/api/batches/${id}/recall`, { method: 'POST', body: { reason } });
+}
+// Clears a recalled batch and puts it back on sale (admin). PATCH status
+// can't lift a recall quarantine.
+export function releaseBatchRecall(id: string): Promise<BatchRecord> {
+  return apiFetch<BatchRecord>(`/api/batches/${id}/recall/release`, { method: 'POST' });
+}
+export function listBatchRecalls(id: string): Promise<Record<string, unknown>[]> {
+  return apiFetch<Record<string, unknown>[]>(`/api/batches/${id}/recalls`);
+}
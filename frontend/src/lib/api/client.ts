@@ -2,6 +2,10 @@
 //   - resolves the API base URL from Vite env (VITE_API_BASE_URL) with a
 //     localhost fallback for `npm run dev`,
 //   - attaches the JWT (when set via setToken) as Bearer,
+//   - sends the active store (set via setStore) as X-Store-ID; the backend
+//     filters list endpoints by it ('all' = HQ consolidated view),
+//   - sends FormData bodies (file uploads) as multipart, anything else as
+//     JSON,
 //   - parses JSON or throws an ApiError carrying { status, message }.
 //
 // Components don't import this directly — call the typed helpers in
@@ -20,6 +24,12 @@ export function getToken(): string | null {
   return bearer;
 }
 
+let storeId: string | null = null;
+
+export function setStore(id: string | null): void {
+  storeId = id;
+}
+
 export class ApiError extends Error {
   constructor(
     public status: number,
@@ -38,13 +48,19 @@ export type FetchOptions = {
 
 export async function apiFetch<T>(path: string, opts: FetchOptions = {}): Promise<T> {
   const headers: Record<string, string> = { Accept: 'application/json' };
-  if (opts.body !== undefined) headers['Content-Type'] = 'application/json';
+  const multipart = opts.body instanceof FormData;
+  if (opts.body !== undefined && !multipart) headers['Content-Type'] = 'application/json';
   if (bearer) headers.Authorization = `Bearer ${bearer}`;
+  if (storeId) headers['X-Store-ID'] = storeId;
 
   const res = await fetch(`${BASE_URL}${path}`, {
     method: opts.method ?? 'GET',
     headers,
-    body: opts.body !== undefined ? JSON.stringify(opts.body) : undefined,
+    body: multipart
+      ? (opts.body as FormData)
+      : opts.body !== undefined
+        ? JSON.stringify(opts.body)
+        : undefined,
     signal: opts.signal
   });
 
@@ -65,3 +81,25 @@ export async function apiFetch<T>(path: string, opts: FetchOptions = {}): Promis
 
   return parsed as T;
 }
+
+// Binary responses (PDF documents). Same auth and store headers as
+// apiFetch; errors still come back as JSON.
+export async function apiFetchBlob(
+  path: string,
+  opts: { signal?: AbortSignal } = {}
+): Promise<Blob> {
+  const headers: Record<string, string> = {};
+  if (bearer) headers.Authorization = `Bearer ${bearer}`;
+  if (storeId) headers['X-Store-ID'] = storeId;
+
+  const res = await fetch(`${BASE_URL}${path}`, { headers, signal: opts.signal });
+  if (!res.ok) {
+    const parsed = await res.json().catch(() => undefined);
+    const message =
+      (parsed && typeof parsed === 'object' && 'error' in parsed
+        ? String((parsed as { error: unknown }).error)
+        : null) ?? `HTTP ${res.status}`;
+    throw new ApiError(res.status, message);
+  }
+  return res.blob();
+}
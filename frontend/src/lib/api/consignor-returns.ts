@@ -0,0 +1,29 @@
+import { apiFetch } from './client';
+
+export type ConsignorReturnPayload = {
+  supplierId: string;
+  returnedAt?: string;
+  handedOverBy?: string;
+  receivedBy?: string;
+  notes?: string;
+  lines: { batchId: string; qty: number; reason?: string }[];
+};
+export type ConsignorReturnRecord = Record<string, unknown>;
+
+export function listConsignorReturns(params?: {
+  supplierId?: string;
+  start?: string;
+  end?: string;
+}): Promise<ConsignorReturnRecord[]> {
+  const q = new URLSearchParams();
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  if (params?.start) q.set('start', params.start);
+  if (params?.end) q.set('end', params.end);
+  const qs = q.toString();
+  return apiFetch<ConsignorReturnRecord[]>(`/api/consignor-returns${qs ? `?${qs}` : ''}`);
+}
+// Posts the return: batches decrement and return-consignor movements are
+// written server-side in one transaction.
+export function createConsignorReturn(input: ConsignorReturnPayload): Promise<ConsignorReturnRecord> {
+  return apiFetch<ConsignorReturnRecord>('/api/consignor-returns', { method: 'POST', body: input });
+}
@@ -0,0 +1,55 @@
+import { apiFetch } from './client';
+
+export type ABCClass = 'A' | 'B' | 'C';
+
+export type ProductABCClass = {
+  productId: string;
+  productName?: string;
+  class: ABCClass;
+  salesValue: number;
+  sharePct: number;
+  cumulativePct: number;
+  rank: number;
+  classifiedAt: string;
+};
+
+export type CycleCountClassStatus = {
+  class: ABCClass;
+  intervalDays: number;
+  items: number;
+  onSchedule: number;
+  overdue: number;
+  coveragePct: number;
+};
+
+export type CycleCountMonth = {
+  month: string;
+  class: ABCClass;
+  coveragePct: number;
+  linesCounted: number;
+  accurateLines: number;
+  accuracyPct: number;
+  varianceValue: number;
+};
+
+export type CycleCountDashboard = {
+  current: CycleCountClassStatus[];
+  history: CycleCountMonth[];
+};
+
+export function listABCClasses(cls?: ABCClass): Promise<ProductABCClass[]> {
+  return apiFetch<ProductABCClass[]>(`/api/cycle-counts/abc${cls ? `?class=${cls}` : ''}`);
+}
+export function classifyABC(): Promise<{ classified: number }> {
+  return apiFetch<{ classified: number }>('/api/cycle-counts/abc/classify', { method: 'POST' });
+}
+// Creates the day's cycle-count drafts (stock opnames of kind 'cycle').
+export function generateCycleCounts(date?: string): Promise<{ created: number }> {
+  return apiFetch<{ created: number }>('/api/cycle-counts/generate', {
+    method: 'POST',
+    body: { date: date ?? '' }
+  });
+}
+export function getCycleCountDashboard(months = 12): Promise<CycleCountDashboard> {
+  return apiFetch<CycleCountDashboard>(`/api/cycle-counts/dashboard?months=${months}`);
+}
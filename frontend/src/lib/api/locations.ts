@@ -5,6 +5,7 @@ export type ApiLocationStatus = 'active' | 'archived';
 
 export type ApiLocation = {
   id: string;
+  storeId: string;
   name: string;
   slug: string;
   kind: ApiLocationKind;
@@ -18,6 +19,8 @@ export type ApiLocation = {
 };
 
 export type LocationInput = {
+  // Defaults to the active store.
+  storeId?: string;
   name: string;
   slug?: string;
   kind: ApiLocationKind;
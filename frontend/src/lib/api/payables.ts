@@ -0,0 +1,121 @@
+import { apiFetch } from './client';
+
+// Accounts payable (utang pemasok). Balances, due dates and aging are all
+// computed server-side from standard POs that were sent to the supplier.
+// Only matched supplier invoices count; disputed ones are reported apart.
+
+export type ApAging = {
+  // Not yet due, or nothing received yet (no due date).
+  current: number;
+  days1To30: number;
+  days31To60: number;
+  days61To90: number;
+  over90: number;
+};
+
+export type ApSupplier = ApAging & {
+  supplierId: string;
+  supplierName: string;
+  paymentTermsDays: number;
+  purchased: number;
+  paid: number;
+  debitNotes: number;
+  // Debit notes not tied to a PO.
+  credit: number;
+  outstanding: number;
+  disputed: number;
+};
+
+export type ApItem = {
+  purchaseOrderId: string;
+  code: string;
+  supplierId: string;
+  supplierName: string;
+  status: string;
+  orderDate: string;
+  dueDate: string;
+  // PO value; total is what matched invoices billed so far.
+  ordered: number;
+  total: number;
+  // Billed on invoices still flagged as discrepancies.
+  disputed: number;
+  paid: number;
+  debitNotes: number;
+  outstanding: number;
+  // Negative while the due date is still ahead.
+  daysOverdue: number;
+};
+
+export type ApReport = {
+  asOf: string;
+  upcomingDays: number;
+  outstanding: number;
+  aging: ApAging;
+  suppliers: ApSupplier[];
+  upcoming: ApItem[];
+  overdue: ApItem[];
+  open: ApItem[];
+};
+
+export function getPayables(params?: {
+  asOf?: string;
+  upcomingDays?: number;
+  supplierId?: string;
+}): Promise<ApReport> {
+  const q = new URLSearchParams();
+  if (params?.asOf) q.set('asOf', params.asOf);
+  if (params?.upcomingDays !== undefined) q.set('upcomingDays', String(params.upcomingDays));
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  const qs = q.toString();
+  return apiFetch<ApReport>(`/api/payables${qs ? `?${qs}` : ''}`);
+}
+
+export type SupplierPaymentMethod = 'cash' | 'transfer' | 'other';
+
+export type SupplierPaymentAllocation = {
+  purchaseOrderId: string;
+  purchaseOrderCode: string;
+  amount: number;
+};
+
+export type SupplierPayment = {
+  id: string;
+  code: string;
+  supplierId: string;
+  amount: number;
+  method: SupplierPaymentMethod;
+  paidAt: string;
+  reference: string;
+  notes: string;
+  createdBy: string;
+  createdAt: string;
+  allocations: SupplierPaymentAllocation[];
+};
+
+export type SupplierPaymentInput = {
+  supplierId: string;
+  amount: number;
+  method: SupplierPaymentMethod;
+  paidAt?: string;
+  reference?: string;
+  notes?: string;
+  // Leave empty to pay the oldest due POs first.
+  allocations?: { purchaseOrderId: string; amount: number }[];
+};
+
+export function listSupplierPayments(params?: {
+  supplierId?: string;
+  start?: string;
+  end?: string;
+}): Promise<SupplierPayment[]> {
+  const q = new URLSearchParams();
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  if (params?.start) q.set('start', params.start);
+  if (params?.end) q.set('end', params.end);
+  const qs = q.toString();
+  return apiFetch<SupplierPayment[]>(`/api/supplier-payments${qs ? `?${qs}` : ''}`);
+}
+
+export function createSupplierPayment(input: SupplierPaymentInput): Promise<SupplierPayment> {
+  return apiFetch<SupplierPayment>('/api/supplier-payments', { method: 'POST', body: input });
+}
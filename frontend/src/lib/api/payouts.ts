@@ -1,14 +1,48 @@
-import { apiFetch } from './client';
+import { apiFetch, apiFetchBlob } from './client';
 
 export type PayoutPayload = Record<string, unknown>;
 export type PayoutRecord = Record<string, unknown>;
+export type PayoutOutstandingRecord = Record<string, unknown>;
+export type PayoutSettlementRecord = Record<string, unknown>;
+
+function periodQuery(params?: { supplierId?: string; start?: string; end?: string }): string {
+  const q = new URLSearchParams();
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  if (params?.start) q.set('start', params.start);
+  if (params?.end) q.set('end', params.end);
+  const qs = q.toString();
+  return qs ? `?${qs}` : '';
+}
 
 export function listPayouts(): Promise<PayoutRecord[]> {
   return apiFetch<PayoutRecord[]>('/api/payouts');
 }
+// Server sets the amount to what the settlement owes when it's left 0, and
+// locks the sales it settles.
 export function createPayout(input: PayoutPayload): Promise<PayoutRecord> {
   return apiFetch<PayoutRecord>('/api/payouts', { method: 'POST', body: input });
 }
 export function deletePayout(id: string): Promise<{ id: string }> {
   return apiFetch<{ id: string }>(`/api/payouts/${id}`, { method: 'DELETE' });
 }
+export function getPayout(id: string): Promise<PayoutRecord> {
+  return apiFetch<PayoutRecord>(`/api/payouts/${id}`);
+}
+export function listPayoutOutstanding(params?: {
+  start?: string;
+  end?: string;
+}): Promise<PayoutOutstandingRecord[]> {
+  return apiFetch<PayoutOutstandingRecord[]>(`/api/payouts/outstanding${periodQuery(params)}`);
+}
+// Unsettled consignment sales for one consignor and period (end defaults to
+// today server-side).
+export function getPayoutSettlement(params: {
+  supplierId: string;
+  start?: string;
+  end?: string;
+}): Promise<PayoutSettlementRecord> {
+  return apiFetch<PayoutSettlementRecord>(`/api/payouts/settlement${periodQuery(params)}`);
+}
+export function getPayoutStatementPdf(id: string): Promise<Blob> {
+  return apiFetchBlob(`/api/payouts/${id}/statement`);
+}
@@ -0,0 +1,90 @@
+import { apiFetch } from './client';
+
+// Scheduled prices. Entries are written into the product's prices by a
+// server job at effectiveAt and logged to price history as 'scheduled'.
+
+export type PriceScheduleStatus = 'pending' | 'applied' | 'cancelled' | 'failed';
+
+// Same shapes as the product store's PricingStrategy / PricingTier.
+export type PricingStrategy =
+  | { kind: 'fixed'; value: number }
+  | { kind: 'markup_amount'; value: number }
+  | { kind: 'markup_pct'; value: number };
+
+export type PricingTier = { minQty: number; pricing: PricingStrategy };
+
+export type PriceScheduleItem = {
+  id: string;
+  productId: string;
+  variantId?: string;
+  packagingIndex?: number;
+  pricelistId: string;
+  pricing: PricingStrategy;
+  tiers: PricingTier[];
+  // '' until the schedule runs.
+  result: '' | 'applied' | 'skipped';
+  resultNote?: string;
+  productName: string;
+  variantName?: string;
+  packagingLabel?: string;
+  pricelistName: string;
+  // Preview at today's cost; current is only set while pending.
+  current?: PricingStrategy;
+  cost: number;
+  currentSale: number;
+  newSale: number;
+};
+
+export type PriceSchedule = {
+  id: string;
+  code: string;
+  effectiveAt: string;
+  status: PriceScheduleStatus;
+  notes: string;
+  itemsApplied: number;
+  itemsSkipped: number;
+  appliedAt?: string;
+  cancelledBy?: string;
+  cancelledAt?: string;
+  // Set when the server job could not apply the schedule.
+  failureNote?: string;
+  failedAt?: string;
+  createdBy: string;
+  createdAt: string;
+  itemCount: number;
+  items: PriceScheduleItem[];
+};
+
+export type PriceScheduleInput = {
+  effectiveAt: string;
+  notes?: string;
+  items: {
+    productId: string;
+    variantId?: string;
+    packagingIndex?: number;
+    pricelistId: string;
+    pricing: PricingStrategy;
+    tiers: PricingTier[];
+  }[];
+};
+
+export function listPriceSchedules(params?: {
+  status?: PriceScheduleStatus;
+}): Promise<PriceSchedule[]> {
+  const q = new URLSearchParams();
+  if (params?.status) q.set('status', params.status);
+  const qs = q.toString();
+  return apiFetch<PriceSchedule[]>(`/api/price-schedules${qs ? `?${qs}` : ''}`);
+}
+
+export function getPriceSchedule(id: string): Promise<PriceSchedule> {
+  return apiFetch<PriceSchedule>(`/api/price-schedules/${id}`);
+}
+
+export function createPriceSchedule(input: PriceScheduleInput): Promise<PriceSchedule> {
+  return apiFetch<PriceSchedule>('/api/price-schedules', { method: 'POST', body: input });
+}
+
+export function cancelPriceSchedule(id: string): Promise<PriceSchedule> {
+  return apiFetch<PriceSchedule>(`/api/price-schedules/${id}/cancel`, { method: 'POST' });
+}
@@ -6,6 +6,11 @@ export type ProductionRunRecord = Record<string, unknown>;
 export function listProductionRuns(): Promise<ProductionRunRecord[]> {
   return apiFetch<ProductionRunRecord[]>('/api/production-runs');
 }
-export function createProductionRun(input: ProductionRunPayload): Promise<ProductionRunRecord> {
-  return apiFetch<ProductionRunRecord>('/api/production-runs', { method: 'POST', body: input });
+// Runs the whole session server-side: consumes owned component batches at
+// the run's location, creates the output batch and records the run.
+export function executeProductionRun(input: ProductionRunPayload): Promise<ProductionRunRecord> {
+  return apiFetch<ProductionRunRecord>('/api/production-runs/execute', {
+    method: 'POST',
+    body: input
+  });
 }
@@ -1,4 +1,4 @@
-import { apiFetch } from './client';
+import { apiFetch, apiFetchBlob } from './client';
 
 // Pass-through types — the PO store is the source of truth for the nested
 // shape (lines + payments). Backend serializes/deserializes the same JSON
@@ -11,6 +11,10 @@ export function listPurchaseOrders(): Promise<PurchaseOrderRecord[]> {
   return apiFetch<PurchaseOrderRecord[]>('/api/purchase-orders');
 }
 
+export function getPurchaseOrder(id: string): Promise<PurchaseOrderRecord> {
+  return apiFetch<PurchaseOrderRecord>(`/api/purchase-orders/${id}`);
+}
+
 export function createPurchaseOrder(input: PurchaseOrderPayload): Promise<PurchaseOrderRecord> {
   return apiFetch<PurchaseOrderRecord>('/api/purchase-orders', {
     method: 'POST',
@@ -31,3 +35,110 @@ export function updatePurchaseOrder(
 export function deletePurchaseOrder(id: string): Promise<void> {
   return apiFetch<void>(`/api/purchase-orders/${id}`, { method: 'DELETE' });
 }
+
+// Landed costs (freight, unloading, duty, insurance, other) are allocated
+// over a receipt's batches by value, qty or weight and folded into each
+// batch's unitCost.
+export type LandedCostKind = 'freight' | 'unloading' | 'duty' | 'insurance' | 'other';
+export type AllocationMethod = 'value' | 'qty' | 'weight';
+
+export interface LandedCostInput {
+  kind: LandedCostKind;
+  amount: number;
+  supplierId?: string;
+  reference?: string;
+  notes?: string;
+}
+
+export function listPurchaseOrderReceipts(id: string): Promise<PurchaseOrderRecord[]> {
+  return apiFetch<PurchaseOrderRecord[]>(`/api/purchase-orders/${id}/receipts`);
+}
+
+export function addLandedCosts(
+  id: string,
+  receiptId: string,
+  input: { allocationMethod?: AllocationMethod; landedCosts: LandedCostInput[] }
+): Promise<PurchaseOrderRecord> {
+  return apiFetch<PurchaseOrderRecord>(
+    `/api/purchase-orders/${id}/receipts/${receiptId}/landed-costs`,
+    { method: 'POST', body: input }
+  );
+}
+
+// Status transitions. PATCH no longer changes status; each step goes
+// through its own endpoint and is logged with the actor.
+export type PurchaseOrderAction = 'submit' | 'approve' | 'reject' | 'send' | 'close' | 'cancel';
+
+export function transitionPurchaseOrder(
+  id: string,
+  action: PurchaseOrderAction,
+  notes = ''
+): Promise<PurchaseOrderRecord> {
+  return apiFetch<PurchaseOrderRecord>(`/api/purchase-orders/${id}/${action}`, {
+    method: 'POST',
+    body: { notes }
+  });
+}
+
+export function listPurchaseOrderTransitions(id: string): Promise<PurchaseOrderRecord[]> {
+  return apiFetch<PurchaseOrderRecord[]>(`/api/purchase-orders/${id}/transitions`);
+}
+
+// Emailing the PO's PDF to the supplier. Delivering an approved PO marks
+// it sent; every attempt is logged with its SMTP outcome.
+export type PurchaseOrderEmail = {
+  id: string;
+  purchaseOrderId: string;
+  sender: string;
+  recipients: string[];
+  cc: string[];
+  subject: string;
+  message: string;
+  attachment: string;
+  status: 'sent' | 'failed';
+  error?: string;
+  sentBy: string;
+  createdAt: string;
+};
+
+export function emailPurchaseOrder(
+  id: string,
+  input: { cc?: string[]; message?: string }
+): Promise<{ email: PurchaseOrderEmail; purchaseOrder: PurchaseOrderRecord }> {
+  return apiFetch<{ email: PurchaseOrderEmail; purchaseOrder: PurchaseOrderRecord }>(
+    `/api/purchase-orders/${id}/email`,
+    { method: 'POST', body: input }
+  );
+}
+
+export function listPurchaseOrderEmails(id: string): Promise<PurchaseOrderEmail[]> {
+  return apiFetch<PurchaseOrderEmail[]>(`/api/purchase-orders/${id}/emails`);
+}
+
+export function getPurchaseOrderPdf(id: string): Promise<Blob> {
+  return apiFetchBlob(`/api/purchase-orders/${id}/pdf`);
+}
+
+export type ReceivePurchaseOrderInput = {
+  receivedDate?: string;
+  locationId?: string;
+  notes?: string;
+  lines: {
+    lineId: string;
+    qty: number;
+    expiresAt?: string;
+    unitPrice?: number;
+    serials?: string[];
+  }[];
+  allocationMethod?: AllocationMethod;
+  landedCosts?: LandedCostInput[];
+};
+
+// Books goods server-side: batches, receive movements and the PO status in
+// one transaction.
+export function receivePurchaseOrder(
+  id: string,
+  input: ReceivePurchaseOrderInput
+): Promise<{ purchaseOrder: PurchaseOrderRecord; batches: Record<string, unknown>[] }> {
+  return apiFetch(`/api/purchase-orders/${id}/receive`, { method: 'POST', body: input });
+}
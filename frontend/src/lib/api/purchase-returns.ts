@@ -0,0 +1,33 @@
+import { apiFetch } from './client';
+
+export type PurchaseReturnReason = 'damaged' | 'expired' | 'defective' | 'wrong-item' | 'other';
+
+export type PurchaseReturnPayload = {
+  supplierId: string;
+  // Optional; inferred when every batch came from the same PO.
+  purchaseOrderId?: string;
+  returnedAt?: string;
+  notes?: string;
+  lines: { batchId: string; qty: number; reason?: PurchaseReturnReason; notes?: string }[];
+};
+export type PurchaseReturnRecord = Record<string, unknown>;
+
+export function listPurchaseReturns(params?: {
+  supplierId?: string;
+  purchaseOrderId?: string;
+  start?: string;
+  end?: string;
+}): Promise<PurchaseReturnRecord[]> {
+  const q = new URLSearchParams();
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  if (params?.purchaseOrderId) q.set('purchaseOrderId', params.purchaseOrderId);
+  if (params?.start) q.set('start', params.start);
+  if (params?.end) q.set('end', params.end);
+  const qs = q.toString();
+  return apiFetch<PurchaseReturnRecord[]>(`/api/purchase-returns${qs ? `?${qs}` : ''}`);
+}
+// Posts the return: batches decrement, return-supplier movements and the
+// debit note are written server-side in one transaction.
+export function createPurchaseReturn(input: PurchaseReturnPayload): Promise<PurchaseReturnRecord> {
+  return apiFetch<PurchaseReturnRecord>('/api/purchase-returns', { method: 'POST', body: input });
+}
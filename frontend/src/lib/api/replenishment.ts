@@ -0,0 +1,43 @@
+import { apiFetch } from './client';
+
+export type ReplenishmentSuggestion = {
+  productId: string;
+  variantId?: string;
+  productName: string;
+  variantName?: string;
+  unitId?: string;
+  supplierId?: string;
+  supplierName?: string;
+  unitCost: number;
+  leadTimeDays: number;
+  minOrderQty: number;
+  // Base units per day over the window; seasonality is per weekday,
+  // index 0 = Monday.
+  dailyRate: number;
+  seasonality: number[];
+  onHand: number;
+  onOrder: number;
+  daysOfSupply: number | null;
+  coverageDays: number;
+  forecastDemand: number;
+  suggestedQty: number;
+};
+
+export type ReplenishmentReport = {
+  suggestions: ReplenishmentSuggestion[];
+  // Products with no primary supplier; they can't go on a draft PO yet.
+  withoutPrimarySupplier: ReplenishmentSuggestion[];
+};
+
+export function getReplenishmentSuggestions(params: {
+  windowDays: number;
+  bufferDays: number;
+  all?: boolean;
+}): Promise<ReplenishmentReport> {
+  const q = new URLSearchParams({
+    windowDays: String(params.windowDays),
+    bufferDays: String(params.bufferDays)
+  });
+  if (params.all) q.set('all', 'true');
+  return apiFetch<ReplenishmentReport>(`/api/replenishment/suggestions?${q}`);
+}
@@ -0,0 +1,47 @@
+import { apiFetch } from './client';
+
+export type StockAdjustmentPayload = {
+  productId: string;
+  variantId?: string;
+  locationId?: string;
+  delta: number;
+  unitCost?: number;
+  expiresAt?: string;
+  reasonCode: string;
+  imageUrl?: string;
+  notes?: string;
+};
+export type StockAdjustmentRecord = Record<string, unknown>;
+
+export function listStockAdjustments(params?: {
+  status?: string;
+  productId?: string;
+  limit?: number;
+}): Promise<StockAdjustmentRecord[]> {
+  const q = new URLSearchParams();
+  if (params?.status) q.set('status', params.status);
+  if (params?.productId) q.set('productId', params.productId);
+  if (params?.limit) q.set('limit', String(params.limit));
+  const qs = q.toString();
+  return apiFetch<StockAdjustmentRecord[]>(`/api/stock-adjustments${qs ? `?${qs}` : ''}`);
+}
+export function listPendingStockAdjustments(): Promise<StockAdjustmentRecord[]> {
+  return apiFetch<StockAdjustmentRecord[]>('/api/stock-adjustments/queue');
+}
+// Posted immediately below the approval threshold; otherwise returned with
+// status 'pending' and no stock change until approved.
+export function createStockAdjustment(input: StockAdjustmentPayload): Promise<StockAdjustmentRecord> {
+  return apiFetch<StockAdjustmentRecord>('/api/stock-adjustments', { method: 'POST', body: input });
+}
+export function approveStockAdjustment(id: string, notes = ''): Promise<StockAdjustmentRecord> {
+  return apiFetch<StockAdjustmentRecord>(`/api/stock-adjustments/${id}/approve`, {
+    method: 'POST',
+    body: { notes }
+  });
+}
+export function rejectStockAdjustment(id: string, notes = ''): Promise<StockAdjustmentRecord> {
+  return apiFetch<StockAdjustmentRecord>(`/api/stock-adjustments/${id}/reject`, {
+    method: 'POST',
+    body: { notes }
+  });
+}
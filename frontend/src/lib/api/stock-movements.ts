@@ -1,6 +1,5 @@
 import { apiFetch } from './client';
 
-export type StockMovementPayload = Record<string, unknown>;
 export type StockMovementRecord = Record<string, unknown>;
 
 export function listStockMovements(params?: {
@@ -17,6 +16,3 @@ export function listStockMovements(params?: {
   const qs = q.toString();
   return apiFetch<StockMovementRecord[]>(`/api/stock-movements${qs ? `?${qs}` : ''}`);
 }
-export function createStockMovement(input: StockMovementPayload): Promise<StockMovementRecord> {
-  return apiFetch<StockMovementRecord>('/api/stock-movements', { method: 'POST', body: input });
-}
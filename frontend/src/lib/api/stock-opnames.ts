@@ -18,3 +18,39 @@ export function updateStockOpname(
 ): Promise<StockOpnameRecord> {
   return apiFetch<StockOpnameRecord>(`/api/stock-opnames/${id}`, { method: 'PATCH', body: patch });
 }
+
+// Server-driven workflow: start snapshots expected qty per batch, counts
+// records what was found, complete posts the variances, cancel abandons.
+export function startStockOpname(
+  id: string,
+  input: { productIds?: string[]; categoryId?: string }
+): Promise<StockOpnameRecord> {
+  return apiFetch<StockOpnameRecord>(`/api/stock-opnames/${id}/start`, {
+    method: 'POST',
+    body: input
+  });
+}
+export function recordStockOpnameCounts(
+  id: string,
+  lines: { lineId: string; countedQty: number | null; notes?: string }[]
+): Promise<StockOpnameRecord> {
+  return apiFetch<StockOpnameRecord>(`/api/stock-opnames/${id}/counts`, {
+    method: 'POST',
+    body: { lines }
+  });
+}
+export type StockOpnameSummary = {
+  countedLines: number;
+  uncountedLines: number;
+  adjustedLines: number;
+  pendingLines: number;
+};
+export function completeStockOpname(
+  id: string,
+  input: { skipUncounted?: boolean }
+): Promise<{ opname: StockOpnameRecord; summary: StockOpnameSummary }> {
+  return apiFetch(`/api/stock-opnames/${id}/complete`, { method: 'POST', body: input });
+}
+export function cancelStockOpname(id: string): Promise<StockOpnameRecord> {
+  return apiFetch<StockOpnameRecord>(`/api/stock-opnames/${id}/cancel`, { method: 'POST' });
+}
@@ -0,0 +1,58 @@
+import { apiFetch } from './client';
+
+export type ReservationHolderKind = 'cart' | 'order';
+
+export type StockReservation = {
+  id: string;
+  holderKind: ReservationHolderKind;
+  holderId: string;
+  productId: string;
+  variantId?: string;
+  batchId: string;
+  qty: number;
+  expiresAt: string;
+};
+
+// Base units (line quantity × unit factor).
+export type ReservationLine = { productId: string; variantId?: string; qty: number };
+
+export type StockAvailability = {
+  productId: string;
+  variantId?: string;
+  onHand: number;
+  reserved: number;
+  available: number;
+};
+
+// Sets the holder's reservations to exactly these lines and renews the TTL.
+// Fails with the server's "stok … tidak cukup" message when another holder
+// already has the stock.
+export function replaceReservations(
+  kind: ReservationHolderKind,
+  holderId: string,
+  lines: ReservationLine[]
+): Promise<StockReservation[]> {
+  return apiFetch<StockReservation[]>(`/api/stock-reservations/${kind}/${holderId}`, {
+    method: 'PUT',
+    body: { lines }
+  });
+}
+export function releaseReservations(kind: ReservationHolderKind, holderId: string): Promise<void> {
+  return apiFetch<void>(`/api/stock-reservations/${kind}/${holderId}`, { method: 'DELETE' });
+}
+
+// excludeHolder leaves that holder's own reservations out of `reserved`.
+export function getStockAvailability(params?: {
+  productId?: string;
+  locationId?: string;
+  excludeHolder?: { kind: ReservationHolderKind; id: string };
+}): Promise<StockAvailability[]> {
+  const q = new URLSearchParams();
+  if (params?.productId) q.set('productId', params.productId);
+  if (params?.locationId) q.set('locationId', params.locationId);
+  if (params?.excludeHolder) {
+    q.set('excludeHolder', `${params.excludeHolder.kind}:${params.excludeHolder.id}`);
+  }
+  const qs = q.toString();
+  return apiFetch<StockAvailability[]>(`/api/stock/availability${qs ? `?${qs}` : ''}`);
+}
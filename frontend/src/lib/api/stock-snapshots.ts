@@ -0,0 +1,92 @@
+import { apiFetch } from './client';
+
+export type StockSnapshotSource = 'job' | 'backfill' | 'repair';
+
+export type StockSnapshotDay = {
+  date: string;
+  rows: number;
+  ownedQty: number;
+  ownedValue: number;
+  consignmentQty: number;
+  consignmentValue: number;
+  source: StockSnapshotSource;
+  takenAt: string;
+};
+
+export type StockHistoryPoint = {
+  date: string;
+  ownedQty: number;
+  ownedValue: number;
+  consignmentQty: number;
+  consignmentValue: number;
+};
+
+export type StockHistory = {
+  start: string;
+  end: string;
+  points: StockHistoryPoint[];
+  missingDays: string[];
+};
+
+export type StockTurnoverRow = {
+  key: string;
+  label: string;
+  qtySold: number;
+  cogs: number;
+  avgQty: number;
+  avgValue: number;
+  turnover: number;
+  daysOnHand: number;
+};
+
+export type StockTurnover = {
+  start: string;
+  end: string;
+  groupBy: 'product' | 'category';
+  snapshotDays: number;
+  missingDays: string[];
+  rows: StockTurnoverRow[];
+  totals: StockTurnoverRow;
+};
+
+function qs(params: Record<string, string | undefined>): string {
+  const s = new URLSearchParams();
+  for (const [k, v] of Object.entries(params)) if (v) s.set(k, v);
+  const out = s.toString();
+  return out ? `?${out}` : '';
+}
+
+export function listStockSnapshots(start?: string, end?: string): Promise<StockSnapshotDay[]> {
+  return apiFetch<StockSnapshotDay[]>(`/api/stock-snapshots${qs({ start, end })}`);
+}
+// Snapshots past days; existing days are skipped unless overwrite.
+export function backfillStockSnapshots(
+  from: string,
+  to?: string,
+  overwrite = false
+): Promise<{ taken: StockSnapshotDay[]; skipped: number }> {
+  return apiFetch('/api/stock-snapshots/backfill', {
+    method: 'POST',
+    body: { from, to: to ?? '', overwrite }
+  });
+}
+export function repairStockSnapshot(date: string): Promise<StockSnapshotDay> {
+  return apiFetch<StockSnapshotDay>(`/api/stock-snapshots/${date}/repair`, { method: 'POST' });
+}
+
+export function getStockHistory(params: {
+  start?: string;
+  end?: string;
+  productId?: string;
+  variantId?: string;
+  locationId?: string;
+}): Promise<StockHistory> {
+  return apiFetch<StockHistory>(`/api/reports/stock-history${qs(params)}`);
+}
+export function getStockTurnover(params: {
+  start?: string;
+  end?: string;
+  groupBy?: 'product' | 'category';
+}): Promise<StockTurnover> {
+  return apiFetch<StockTurnover>(`/api/reports/stock-turnover${qs(params)}`);
+}
@@ -0,0 +1,117 @@
+import { apiFetch } from './client';
+
+export type ApiStoreStatus = 'active' | 'archived';
+
+export type ApiStore = {
+  id: string;
+  code: string;
+  name: string;
+  address: string;
+  phone: string;
+  status: ApiStoreStatus;
+  defaultPricelistId?: string;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type StoreInput = {
+  code: string;
+  name: string;
+  address: string;
+  phone: string;
+  status: ApiStoreStatus;
+  defaultPricelistId?: string;
+};
+
+export type MyStores = {
+  stores: (ApiStore & { isDefault: boolean })[];
+  activeStoreId?: string;
+  canConsolidate: boolean;
+};
+
+export type ApiRegister = {
+  id: string;
+  storeId: string;
+  code: string;
+  name: string;
+  status: ApiStoreStatus;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type RegisterInput = {
+  storeId?: string;
+  code: string;
+  name: string;
+  status: ApiStoreStatus;
+};
+
+export type StoreSummaryRow = {
+  storeId: string;
+  code: string;
+  name: string;
+  orders: number;
+  sales: number;
+  taxTotal: number;
+  creditSales: number;
+  cancelledOrders: number;
+  avgTicket: number;
+  openShifts: number;
+  ownedQty: number;
+  ownedValue: number;
+  consignmentQty: number;
+  consignmentValue: number;
+};
+
+export type StoreSummaryReport = {
+  start?: string;
+  end?: string;
+  stores: StoreSummaryRow[];
+  totals: StoreSummaryRow;
+};
+
+// Stores the caller may switch to, plus the one the server resolved.
+export function listMyStores(): Promise<MyStores> {
+  return apiFetch<MyStores>('/api/stores/mine');
+}
+
+export function listStores(): Promise<ApiStore[]> {
+  return apiFetch<ApiStore[]>('/api/stores');
+}
+
+export function createStore(input: StoreInput): Promise<ApiStore> {
+  return apiFetch<ApiStore>('/api/stores', { method: 'POST', body: input });
+}
+
+export function updateStore(id: string, input: StoreInput): Promise<ApiStore> {
+  return apiFetch<ApiStore>(`/api/stores/${id}`, { method: 'PATCH', body: input });
+}
+
+export function deleteStore(id: string): Promise<void> {
+  return apiFetch<void>(`/api/stores/${id}`, { method: 'DELETE' });
+}
+
+export function listRegisters(): Promise<ApiRegister[]> {
+  return apiFetch<ApiRegister[]>('/api/registers');
+}
+
+export function createRegister(input: RegisterInput): Promise<ApiRegister> {
+  return apiFetch<ApiRegister>('/api/registers', { method: 'POST', body: input });
+}
+
+export function updateRegister(id: string, input: RegisterInput): Promise<ApiRegister> {
+  return apiFetch<ApiRegister>(`/api/registers/${id}`, { method: 'PATCH', body: input });
+}
+
+export function deleteRegister(id: string): Promise<void> {
+  return apiFetch<void>(`/api/registers/${id}`, { method: 'DELETE' });
+}
+
+// Consolidated cross-store report (HQ / Admin only).
+export function getStoreSummary(params?: { start?: string; end?: string }): Promise<StoreSummaryReport> {
+  const q = new URLSearchParams();
+  if (params?.start) q.set('start', params.start);
+  if (params?.end) q.set('end', params.end);
+  const qs = q.toString();
+  return apiFetch<StoreSummaryReport>(`/api/reports/stores${qs ? `?${qs}` : ''}`);
+}
@@ -0,0 +1,147 @@
+import { apiFetch } from './client';
+
+// Supplier costs: the effective-dated history behind product_suppliers,
+// price list uploads, per-supplier comparison and rise alerts.
+
+export type SupplierCostSource = 'initial' | 'manual' | 'import';
+
+export type SupplierCostChange = {
+  id: string;
+  productId: string;
+  productName: string;
+  supplierId: string;
+  supplierName: string;
+  unitCost: number;
+  previousCost?: number;
+  effectiveFrom: string;
+  source: SupplierCostSource;
+  importId?: string;
+  importCode?: string;
+  createdBy: string;
+  createdAt: string;
+  // Dated after today: not applied to the product's supplier cost yet.
+  pending: boolean;
+};
+
+export type SupplierCostOffer = {
+  supplierId: string;
+  supplierName: string;
+  isPrimary: boolean;
+  supplierSku: string;
+  unitCost: number;
+  // The change that set unitCost, when one is on record.
+  previousCost?: number;
+  changedFrom: string;
+  changePct: number;
+  // Next scheduled change from a future-dated price list.
+  nextCost?: number;
+  nextFrom?: string;
+  // Latest batch actually received from this supplier.
+  lastReceivedCost: number;
+  lastReceivedAt: string;
+  cheapest: boolean;
+};
+
+export type SupplierCostAlert = SupplierCostChange & { changePct: number };
+
+export type SupplierCostAlerts = {
+  since: string;
+  thresholdPct: number;
+  items: SupplierCostAlert[];
+};
+
+export type PriceImportStatus = 'updated' | 'scheduled' | 'unchanged' | 'unmatched' | 'invalid';
+
+export type PriceImportLine = {
+  row: number;
+  supplierSku?: string;
+  barcode?: string;
+  name?: string;
+  cost: number;
+  productId?: string;
+  productName?: string;
+  matchedBy?: 'sku' | 'barcode';
+  oldCost: number;
+  newCost: number;
+  changePct: number;
+  // Rise at or above the purchasing costRiseAlertPct setting.
+  alert: boolean;
+  status: PriceImportStatus;
+  message?: string;
+};
+
+export type PriceImportSummary = {
+  id: string;
+  code: string;
+  supplierId: string;
+  supplierName?: string;
+  filename: string;
+  effectiveFrom: string;
+  rowsTotal: number;
+  rowsUpdated: number;
+  rowsUnchanged: number;
+  rowsSkipped: number;
+  createdBy: string;
+  createdAt: string;
+};
+
+export type PriceImport = PriceImportSummary & { lines: PriceImportLine[] };
+
+export type PriceImportInput = {
+  supplierId: string;
+  file: File;
+  // YYYY-MM-DD; today when empty. Later dates apply on that day.
+  effectiveFrom?: string;
+  dryRun?: boolean;
+};
+
+export function importPriceList(input: PriceImportInput): Promise<PriceImport> {
+  const form = new FormData();
+  form.set('supplierId', input.supplierId);
+  form.set('file', input.file);
+  if (input.effectiveFrom) form.set('effectiveFrom', input.effectiveFrom);
+  if (input.dryRun) form.set('dryRun', 'true');
+  return apiFetch<PriceImport>('/api/supplier-costs/import', { method: 'POST', body: form });
+}
+
+export function listPriceImports(supplierId?: string): Promise<PriceImportSummary[]> {
+  const q = supplierId ? `?supplierId=${encodeURIComponent(supplierId)}` : '';
+  return apiFetch<PriceImportSummary[]>(`/api/supplier-costs/imports${q}`);
+}
+
+export function getPriceImport(id: string): Promise<PriceImport> {
+  return apiFetch<PriceImport>(`/api/supplier-costs/imports/${id}`);
+}
+
+export function listSupplierCostHistory(params: {
+  productId?: string;
+  supplierId?: string;
+  start?: string;
+  end?: string;
+}): Promise<SupplierCostChange[]> {
+  const q = new URLSearchParams();
+  if (params.productId) q.set('productId', params.productId);
+  if (params.supplierId) q.set('supplierId', params.supplierId);
+  if (params.start) q.set('start', params.start);
+  if (params.end) q.set('end', params.end);
+  return apiFetch<SupplierCostChange[]>(`/api/supplier-costs/history?${q}`);
+}
+
+export function compareSupplierCosts(productId: string): Promise<SupplierCostOffer[]> {
+  return apiFetch<SupplierCostOffer[]>(
+    `/api/supplier-costs/compare?productId=${encodeURIComponent(productId)}`
+  );
+}
+
+export function getSupplierCostAlerts(params?: {
+  days?: number;
+  pct?: number;
+  supplierId?: string;
+}): Promise<SupplierCostAlerts> {
+  const q = new URLSearchParams();
+  if (params?.days) q.set('days', String(params.days));
+  if (params?.pct) q.set('pct', String(params.pct));
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  const qs = q.toString();
+  return apiFetch<SupplierCostAlerts>(`/api/supplier-costs/alerts${qs ? `?${qs}` : ''}`);
+}
@@ -0,0 +1,94 @@
+import { apiFetch } from './client';
+
+// Supplier invoices (faktur pemasok). Each line bills one PO line and is
+// three-way matched server-side: PO ordered qty/price, qty received into
+// batches, and qty/price invoiced, within the purchasing tolerances. Only
+// matched invoices count toward accounts payable.
+
+export type SupplierInvoiceStatus = 'matched' | 'discrepancy' | 'cancelled';
+export type InvoiceMatchStatus = 'ok' | 'price' | 'qty-received' | 'qty-ordered';
+
+export type SupplierInvoiceLine = {
+  id: string;
+  purchaseOrderId: string;
+  purchaseOrderCode: string;
+  purchaseOrderLineId: string;
+  productName: string;
+  qty: number;
+  unitPrice: number;
+  amount: number;
+  // What the line was last matched against.
+  orderedQty: number;
+  poUnitPrice: number;
+  receivedQty: number;
+  // Billed for the same PO line on earlier invoices.
+  invoicedBefore: number;
+  matchStatus: InvoiceMatchStatus;
+  matchNote: string;
+};
+
+export type SupplierInvoice = {
+  id: string;
+  code: string;
+  supplierId: string;
+  supplierName: string;
+  invoiceNumber: string;
+  invoiceDate: string;
+  taxInvoiceNumber: string;
+  status: SupplierInvoiceStatus;
+  total: number;
+  // Lines failing the match.
+  discrepancies: number;
+  matchedAt?: string;
+  notes: string;
+  createdBy: string;
+  createdAt: string;
+  // Empty in list responses.
+  lines: SupplierInvoiceLine[];
+};
+
+export type SupplierInvoiceInput = {
+  supplierId: string;
+  invoiceNumber: string;
+  invoiceDate?: string;
+  taxInvoiceNumber?: string;
+  notes?: string;
+  lines: { purchaseOrderLineId: string; qty: number; unitPrice: number }[];
+};
+
+export function listSupplierInvoices(params?: {
+  supplierId?: string;
+  purchaseOrderId?: string;
+  status?: SupplierInvoiceStatus;
+  start?: string;
+  end?: string;
+}): Promise<SupplierInvoice[]> {
+  const q = new URLSearchParams();
+  if (params?.supplierId) q.set('supplierId', params.supplierId);
+  if (params?.purchaseOrderId) q.set('purchaseOrderId', params.purchaseOrderId);
+  if (params?.status) q.set('status', params.status);
+  if (params?.start) q.set('start', params.start);
+  if (params?.end) q.set('end', params.end);
+  const qs = q.toString();
+  return apiFetch<SupplierInvoice[]>(`/api/supplier-invoices${qs ? `?${qs}` : ''}`);
+}
+
+export function getSupplierInvoice(id: string): Promise<SupplierInvoice> {
+  return apiFetch<SupplierInvoice>(`/api/supplier-invoices/${id}`);
+}
+
+export function createSupplierInvoice(input: SupplierInvoiceInput): Promise<SupplierInvoice> {
+  return apiFetch<SupplierInvoice>('/api/supplier-invoices', { method: 'POST', body: input });
+}
+
+export function updateSupplierInvoice(id: string, input: SupplierInvoiceInput): Promise<SupplierInvoice> {
+  return apiFetch<SupplierInvoice>(`/api/supplier-invoices/${id}`, { method: 'PATCH', body: input });
+}
+
+export function matchSupplierInvoice(id: string): Promise<SupplierInvoice> {
+  return apiFetch<SupplierInvoice>(`/api/supplier-invoices/${id}/match`, { method: 'POST' });
+}
+
+export function cancelSupplierInvoice(id: string): Promise<SupplierInvoice> {
+  return apiFetch<SupplierInvoice>(`/api/supplier-invoices/${id}/cancel`, { method: 'POST' });
+}
@@ -10,6 +10,7 @@ export type ApiSupplier = {
   phone: string;
   address: string;
   leadTimeDays: number;
+  paymentTermsDays: number;
   status: ApiSupplierStatus;
   notes: string;
   createdAt: string;
@@ -23,6 +24,7 @@ export type SupplierInput = {
   phone: string;
   address: string;
   leadTimeDays: number;
+  paymentTermsDays: number;
   status: ApiSupplierStatus;
   notes: string;
 };
@@ -11,6 +11,8 @@ export type ApiUser = {
   joinedAt: string;
   pin: string;
   roleIds: string[];
+  storeIds: string[];
+  defaultStoreId?: string;
   createdAt: string;
   updatedAt: string;
 };
@@ -24,6 +26,9 @@ export type UserInput = {
   status: ApiUserStatus;
   joinedAt: string;
   roleIds: string[];
+  // Omit to keep the current assignments.
+  storeIds?: string[];
+  defaultStoreId?: string;
 };
 
 export function listUsers(): Promise<ApiUser[]> {
@@ -69,6 +69,12 @@ export const PERMISSION_CATALOG: PermissionGroup[] = [
     title: 'Pengadaan & Keuangan',
     permissions: [
       { key: 'menu.purchase-orders', label: 'Order Pembelian' },
+      {
+        key: 'feature.purchasing.approve',
+        label: 'Setujui order pembelian',
+        description:
+          'Menyetujui atau menolak PO yang totalnya melewati batas persetujuan pembelian.'
+      },
       { key: 'menu.payouts', label: 'Pembayaran Konsinyasi' },
       { key: 'menu.utang', label: 'Utang Pembelian' },
       { key: 'menu.piutang', label: 'Piutang Pelanggan' }
@@ -78,6 +84,12 @@ export const PERMISSION_CATALOG: PermissionGroup[] = [
     title: 'Inventaris & Katalog',
     permissions: [
       { key: 'menu.inventory', label: 'Lihat Inventaris' },
+      {
+        key: 'feature.stock.adjust-approve',
+        label: 'Setujui penyesuaian stok',
+        description:
+          'Menyetujui atau menolak penyesuaian dan pemusnahan stok yang nilainya melewati batas persetujuan.'
+      },
       { key: 'menu.production', label: 'Produksi' },
       { key: 'menu.stock-opname', label: 'Opname Stok' },
       { key: 'menu.customers', label: 'Pelanggan' }
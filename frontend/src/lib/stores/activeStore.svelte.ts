@@ -0,0 +1,42 @@
+import { setStore } from '$lib/api/client';
+import { listMyStores, type MyStores } from '$lib/api/stores';
+
+const STORE_KEY = 'pos.store.active';
+export const ALL_STORES = 'all';
+
+// The store every API call is scoped to. The choice is remembered per
+// browser; a stale or unassigned id falls back to the user's default store.
+class ActiveStoreState {
+  stores = $state<MyStores['stores']>([]);
+  activeId = $state<string | null>(null);
+  canConsolidate = $state(false);
+
+  active = $derived(this.stores.find((s) => s.id === this.activeId) ?? null);
+  consolidated = $derived(this.activeId === ALL_STORES);
+
+  async load(): Promise<void> {
+    const res = await listMyStores();
+    this.stores = res.stores;
+    this.canConsolidate = res.canConsolidate;
+    const saved = typeof window !== 'undefined' ? window.localStorage.getItem(STORE_KEY) : null;
+    const valid =
+      (saved === ALL_STORES && res.canConsolidate) || res.stores.some((s) => s.id === saved);
+    this.select(valid ? saved : (res.activeStoreId ?? null));
+  }
+
+  select(id: string | null): void {
+    this.activeId = id;
+    setStore(id);
+    if (typeof window === 'undefined') return;
+    if (id) window.localStorage.setItem(STORE_KEY, id);
+    else window.localStorage.removeItem(STORE_KEY);
+  }
+
+  reset(): void {
+    this.stores = [];
+    this.canConsolidate = false;
+    this.select(null);
+  }
+}
+
+export const activeStore = new ActiveStoreState();
@@ -1,15 +1,11 @@
 import { products } from './products.svelte';
 import { locations } from './locations.svelte';
-import {
-  stockMovements,
-  type StockMovementReference,
-  type StockAdjustmentReason
-} from './stockMovements.svelte';
-import {
-  listBatches,
-  createBatch,
-  updateBatch as apiUpdateBatch
-} from '$lib/api/batches';
+import { allocationStrategy } from './settings.svelte';
+import { stockMovements, type StockAdjustmentReason } from './stockMovements.svelte';
+import { listBatches, moveBatch, updateBatch as apiUpdateBatch } from '$lib/api/batches';
+import { createConsignorReturn } from '$lib/api/consignor-returns';
+import { createPurchaseReturn, type PurchaseReturnReason } from '$lib/api/purchase-returns';
+import { createStockAdjustment } from '$lib/api/stock-adjustments';
 
 function normalizeBatch(raw: unknown): Batch {
   const r = raw as Partial<Batch> & Record<string, unknown>;
@@ -28,25 +24,9 @@ function normalizeBatch(raw: unknown): Batch {
     receivedAt: (r.receivedAt ?? '') as string,
     expiresAt: (r.expiresAt as string | undefined) || undefined,
     locationId: String(r.locationId ?? ''),
-    notes: (r.notes ?? '') as string
-  };
-}
-
-function toBatchPayload(b: BatchInput): Record<string, unknown> {
-  return {
-    productId: b.productId,
-    variantId: b.variantId || null,
-    ownership: b.ownership,
-    supplierId: b.supplierId || null,
-    sourcePurchaseOrderId: b.sourcePurchaseOrderId || null,
-    sourcePurchaseOrderLineId: b.sourcePurchaseOrderLineId || null,
-    unitCost: b.unitCost,
-    qtyReceived: b.qtyReceived,
-    qtyRemaining: b.qtyRemaining,
-    receivedAt: b.receivedAt,
-    expiresAt: b.expiresAt || '',
-    locationId: b.locationId,
-    notes: b.notes ?? ''
+    notes: (r.notes ?? '') as string,
+    status: (r.status ?? 'active') as BatchStatus,
+    quarantinedAt: (r.quarantinedAt as string | undefined) || undefined
   };
 }
 
@@ -68,9 +48,13 @@ export type Batch = {
   expiresAt?: string;           // ISO date when known; FIFO walks this first
   locationId: string;           // physical storage location id; defaults to locations.default()
   notes: string;
+  // Server-managed. Quarantined (expired or held back) and disposed batches
+  // are skipped by forStock, so they never reach a sale or production run.
+  status?: BatchStatus;
+  quarantinedAt?: string;
 };
 
-export type BatchInput = Omit<Batch, 'id' | 'code'>;
+export type BatchStatus = 'active' | 'quarantined' | 'disposed';
 
 // Per-line snapshot of which batches were drawn down for a sale, written to the
 // OrderLine at charge time. The single source of truth for the Consignor Payout
@@ -105,26 +89,12 @@ class BatchesStore {
     }
   }
 
-  async add(input: BatchInput): Promise<Batch> {
-    const created = await createBatch(toBatchPayload(input));
-    const b = normalizeBatch(created);
-    this.items = [...this.items, b];
-    return b;
-  }
-
   /**
-   * Partial update — only fields the API understands (qtyRemaining, location,
-   * expires, notes). Other fields are snapshot at insert and stay immutable.
+   * Partial update of the descriptive fields (expires, notes). Quantity and
+   * location only change through the server-side stock workflows.
    */
   async update(id: string, patch: Partial<Batch>): Promise<Batch | undefined> {
-    const apiPatch: {
-      qtyRemaining?: number;
-      locationId?: string;
-      expiresAt?: string;
-      notes?: string;
-    } = {};
-    if (patch.qtyRemaining !== undefined) apiPatch.qtyRemaining = patch.qtyRemaining;
-    if (patch.locationId !== undefined) apiPatch.locationId = patch.locationId;
+    const apiPatch: { expiresAt?: string; notes?: string } = {};
     if (patch.expiresAt !== undefined) apiPatch.expiresAt = patch.expiresAt || '';
     if (patch.notes !== undefined) apiPatch.notes = patch.notes;
     if (Object.keys(apiPatch).length === 0) return this.getById(id);
@@ -141,10 +111,12 @@ class BatchesStore {
   // FIFO-ordered batches with stock left for a given (product, variant?). Callers
   // walk these in order to deduct on sale (see CONSIGNMENT.md §"Sale flow").
   //
-  // Sort priority:
+  // Sort priority follows inventory.allocationStrategy: with 'fifo' (the
+  // default) only receivedAt counts, oldest first. With 'fefo':
   //   1. Soonest expiresAt first (perishables sell before they spoil)
   //   2. Batches without expiresAt come after any with one
   //   3. Within the same bucket, oldest receivedAt first
+  // Non-active (quarantined / disposed) batches are excluded.
   forStock(
     productId: string,
     variantId?: string,
@@ -157,9 +129,11 @@ class BatchesStore {
           b.productId === productId &&
           b.variantId === variantId &&
           b.qtyRemaining > 0 &&
+          (b.status ?? 'active') === 'active' &&
           (!locFilter || locFilter.has(b.locationId))
       )
       .sort((a, b) => {
+        if (allocationStrategy() !== 'fefo') return a.receivedAt.localeCompare(b.receivedAt);
         const aExp = a.expiresAt ?? '9999-12-31';
         const bExp = b.expiresAt ?? '9999-12-31';
         if (aExp !== bExp) return aExp.localeCompare(bExp);
@@ -196,129 +170,116 @@ class BatchesStore {
       .sort((a, b) => a.receivedAt.localeCompare(b.receivedAt));
   }
 
-  // Return unsold consignment stock to the consignor. Decrements an existing
-  // consignment batch — no payable, no order, no revenue impact, because we never
-  // owned the units. See docs/CONSIGNMENT.md §"Return unsold consignment stock".
+  // Return unsold consignment stock to the consignor. Posted server-side as a
+  // consignor-return document (batch decrement + return-consignor movement in
+  // one transaction) — no payable, no order, no revenue impact, because we
+  // never owned the units. See docs/CONSIGNMENT.md §"Return unsold consignment stock".
   async returnToConsignor(
     batchId: string,
-    qty: number
-  ): Promise<{ ok: boolean; reason?: string }> {
+    qty: number,
+    reason = ''
+  ): Promise<{ ok: boolean; reason?: string; returnId?: string; code?: string }> {
     const batch = this.getById(batchId);
     if (!batch) return { ok: false, reason: 'Batch not found.' };
     if (batch.ownership !== 'consignment')
       return { ok: false, reason: 'Only consignment batches can be returned to the consignor.' };
+    if (!batch.supplierId) return { ok: false, reason: 'Batch has no consignor.' };
     if (qty <= 0) return { ok: false, reason: 'Return quantity must be positive.' };
     if (qty > batch.qtyRemaining)
       return { ok: false, reason: `Only ${batch.qtyRemaining} units remain in this batch.` };
-    const updated = await this.update(batchId, { qtyRemaining: batch.qtyRemaining - qty });
-    await stockMovements.log({
-      kind: 'return-consignor',
-      productId: batch.productId,
-      variantId: batch.variantId,
-      locationId: batch.locationId,
-      batchId: batch.id,
-      qtyDelta: -qty,
-      qtyAfter: updated?.qtyRemaining ?? batch.qtyRemaining - qty,
-      unitCost: batch.unitCost,
-      reference: { kind: 'return', id: batch.id, code: batch.code },
-      notes: `Retur konsinyasi · ${batch.code}`
-    });
-    return { ok: true };
+    let created: Record<string, unknown>;
+    try {
+      created = await createConsignorReturn({
+        supplierId: batch.supplierId,
+        lines: [{ batchId, qty, reason }]
+      });
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : String(err) };
+    }
+    this.items = this.items.map((b) =>
+      b.id === batchId ? { ...b, qtyRemaining: Math.max(b.qtyRemaining - qty, 0) } : b
+    );
+    await stockMovements.load().catch(() => {});
+    return { ok: true, returnId: String(created.id ?? ''), code: String(created.code ?? '') };
   }
 
-  // Manual stock adjustment (write-offs, found stock, initial seed, form edits).
-  // Positive delta → new owned batch (returned). Negative delta → LIFO decrement
-  // across owned batches (newest first; preserves FIFO order for future sales) — returns undefined.
-  //
-  // `locationId` controls where positive deltas land and which location is depleted
-  // first for negative deltas. When omitted, falls back to the default-receipt location.
-  async adjustStock(args: {
+  // Send damaged or unwanted owned stock back to its supplier. Posted
+  // server-side as a purchase-return document: batch decrement,
+  // return-supplier movement and a debit note that lowers the payable on
+  // the batch's source PO, all in one transaction.
+  async returnToSupplier(
+    batchId: string,
+    qty: number,
+    reason: PurchaseReturnReason = 'damaged',
+    notes = ''
+  ): Promise<{ ok: boolean; reason?: string; returnId?: string; code?: string }> {
+    const batch = this.getById(batchId);
+    if (!batch) return { ok: false, reason: 'Batch not found.' };
+    if (batch.ownership !== 'owned')
+      return { ok: false, reason: 'Consignment batches go back through a consignor return.' };
+    if (!batch.supplierId) return { ok: false, reason: 'Batch has no supplier.' };
+    if (qty <= 0) return { ok: false, reason: 'Return quantity must be positive.' };
+    if (qty > batch.qtyRemaining)
+      return { ok: false, reason: `Only ${batch.qtyRemaining} units remain in this batch.` };
+    let created: Record<string, unknown>;
+    try {
+      created = await createPurchaseReturn({
+        supplierId: batch.supplierId,
+        purchaseOrderId: batch.sourcePurchaseOrderId || undefined,
+        lines: [{ batchId, qty, reason, notes }]
+      });
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : String(err) };
+    }
+    this.items = this.items.map((b) =>
+      b.id === batchId ? { ...b, qtyRemaining: Math.max(b.qtyRemaining - qty, 0) } : b
+    );
+    await stockMovements.load().catch(() => {});
+    return { ok: true, returnId: String(created.id ?? ''), code: String(created.code ?? '') };
+  }
+
+  // Reason-coded manual adjustment posted server-side through
+  // /stock-adjustments. Above the approval threshold the server keeps it
+  // pending and stock is untouched until someone with
+  // feature.stock.adjust-approve approves it. When posted, the affected
+  // batches and movements are reloaded; `batch` is the new batch for a
+  // positive delta.
+  async requestAdjustment(args: {
     productId: string;
     variantId?: string;
     delta: number;
     unitCost: number;
     expiresAt?: string;
     locationId?: string;
-    reference?: StockMovementReference;
-    reason?: StockAdjustmentReason;
+    reason: StockAdjustmentReason;
     imageUrl?: string;
     notes?: string;
-  }): Promise<Batch | undefined> {
-    if (args.delta === 0) return undefined;
-    const todayISO = new Date().toISOString().slice(0, 10);
-    const locId = args.locationId || locations.defaultId();
-    const reference: StockMovementReference =
-      args.reference ?? { kind: 'manual', id: 'inventory' };
-    if (args.delta > 0) {
-      const newBatch = await this.add({
-        productId: args.productId,
-        variantId: args.variantId,
-        ownership: 'owned',
-        unitCost: args.unitCost,
-        qtyReceived: args.delta,
-        qtyRemaining: args.delta,
-        receivedAt: todayISO,
-        expiresAt: args.expiresAt || undefined,
-        locationId: locId,
-        notes: args.notes ?? 'Manual stock adjustment.'
-      });
-      await stockMovements.log({
-        kind: 'adjust-in',
-        productId: args.productId,
-        variantId: args.variantId,
-        locationId: locId,
-        batchId: newBatch.id,
-        qtyDelta: args.delta,
-        qtyAfter: newBatch.qtyRemaining,
-        unitCost: args.unitCost,
-        reference,
-        reason: args.reason,
-        imageUrl: args.imageUrl,
-        notes: args.notes ?? 'Penyesuaian stok manual.'
-      });
-      return newBatch;
-    }
-    let remaining = -args.delta;
-    const matching = this.items.filter(
-      (b) =>
-        b.productId === args.productId &&
-        b.variantId === args.variantId &&
-        b.ownership === 'owned' &&
-        b.qtyRemaining > 0
-    );
-    const atTarget = matching
-      .filter((b) => b.locationId === locId)
-      .sort((a, b) => b.receivedAt.localeCompare(a.receivedAt));
-    const elsewhere = matching
-      .filter((b) => b.locationId !== locId)
-      .sort((a, b) => b.receivedAt.localeCompare(a.receivedAt));
-    for (const batch of [...atTarget, ...elsewhere]) {
-      if (remaining <= 0) break;
-      const take = Math.min(remaining, batch.qtyRemaining);
-      const updated = await this.update(batch.id, { qtyRemaining: batch.qtyRemaining - take });
-      await stockMovements.log({
-        kind: 'adjust-out',
-        productId: args.productId,
-        variantId: args.variantId,
-        locationId: batch.locationId,
-        batchId: batch.id,
-        qtyDelta: -take,
-        qtyAfter: updated?.qtyRemaining ?? batch.qtyRemaining - take,
-        unitCost: batch.unitCost,
-        reference,
-        reason: args.reason,
-        imageUrl: args.imageUrl,
-        notes: args.notes ?? 'Penyesuaian stok manual.'
-      });
-      remaining -= take;
-    }
-    return undefined;
+  }): Promise<{ status: 'posted' | 'pending'; code: string; batch?: Batch }> {
+    const created = await createStockAdjustment({
+      productId: args.productId,
+      variantId: args.variantId,
+      locationId: args.locationId || locations.defaultId() || undefined,
+      delta: args.delta,
+      unitCost: args.unitCost,
+      expiresAt: args.expiresAt,
+      reasonCode: args.reason,
+      imageUrl: args.imageUrl,
+      notes: args.notes
+    });
+    const status = created.status === 'pending' ? 'pending' : 'posted';
+    const code = String(created.code ?? '');
+    if (status === 'pending') return { status, code };
+    await Promise.all([this.load(), stockMovements.load()]).catch(() => {});
+    const movements = (created.movements as { batchId?: string }[] | undefined) ?? [];
+    const batch = args.delta > 0 ? this.getById(movements[0]?.batchId ?? '') : undefined;
+    return { status, code, batch };
   }
 
-  // Move qty units of a specific batch to another location. Splits the source by
-  // creating a sibling batch at the destination preserving cost, expiry,
-  // ownership, supplier, and source PO references. When qty == the full
-  // remainder, mutates locationId in place (no zombie batches).
+  // Move qty units of a specific batch to another location. Posted
+  // server-side: the whole remainder relocates the batch in place, a part
+  // splits into a sibling batch at the destination (cost, expiry, ownership
+  // and source PO kept), with the move movements logged in the same
+  // transaction. Batches and movements are reloaded afterwards.
   async moveStock(args: {
     batchId: string;
     toLocationId: string;
@@ -333,67 +294,21 @@ class BatchesStore {
       return { ok: false, reason: `Sisa di batch hanya ${src.qtyRemaining}.` };
     if (src.locationId === args.toLocationId)
       return { ok: false, reason: 'Lokasi sumber dan tujuan sama.' };
-    const transferId = args.transferGroupId ?? crypto.randomUUID();
-    const fromLoc = locations.getById(src.locationId)?.name ?? src.locationId;
-    const toLoc = locations.getById(args.toLocationId)?.name ?? args.toLocationId;
-    const reference = { kind: 'transfer' as const, id: transferId };
-    if (args.qty === src.qtyRemaining) {
-      const updated = await this.update(src.id, { locationId: args.toLocationId });
-      await stockMovements.log({
-        kind: 'move-relocate',
-        productId: src.productId,
-        variantId: src.variantId,
-        locationId: args.toLocationId,
-        batchId: src.id,
-        qtyDelta: 0,
-        qtyAfter: updated?.qtyRemaining ?? src.qtyRemaining,
-        unitCost: src.unitCost,
-        reference,
-        notes: args.notes ?? `Relokasi penuh · ${fromLoc} → ${toLoc} · ${src.code}`
-      });
-      return { ok: true, newBatch: updated };
+    let moved: Batch;
+    try {
+      moved = normalizeBatch(
+        await moveBatch(args.batchId, {
+          toLocationId: args.toLocationId,
+          qty: args.qty,
+          notes: args.notes,
+          transferGroupId: args.transferGroupId
+        })
+      );
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : String(err) };
     }
-    const updatedSrc = await this.update(src.id, { qtyRemaining: src.qtyRemaining - args.qty });
-    await stockMovements.log({
-      kind: 'move-out',
-      productId: src.productId,
-      variantId: src.variantId,
-      locationId: src.locationId,
-      batchId: src.id,
-      qtyDelta: -args.qty,
-      qtyAfter: updatedSrc?.qtyRemaining ?? src.qtyRemaining - args.qty,
-      unitCost: src.unitCost,
-      reference,
-      notes: args.notes ?? `Pindah ke ${toLoc} · ${src.code}`
-    });
-    const sibling = await this.add({
-      productId: src.productId,
-      variantId: src.variantId,
-      ownership: src.ownership,
-      supplierId: src.supplierId,
-      sourcePurchaseOrderId: src.sourcePurchaseOrderId,
-      sourcePurchaseOrderLineId: src.sourcePurchaseOrderLineId,
-      unitCost: src.unitCost,
-      qtyReceived: args.qty,
-      qtyRemaining: args.qty,
-      receivedAt: src.receivedAt,
-      expiresAt: src.expiresAt,
-      locationId: args.toLocationId,
-      notes: args.notes ?? `Dipindahkan dari ${src.code}.`
-    });
-    await stockMovements.log({
-      kind: 'move-in',
-      productId: src.productId,
-      variantId: src.variantId,
-      locationId: args.toLocationId,
-      batchId: sibling.id,
-      qtyDelta: args.qty,
-      qtyAfter: sibling.qtyRemaining,
-      unitCost: src.unitCost,
-      reference,
-      notes: args.notes ?? `Pindah dari ${fromLoc} · sibling ${sibling.code}`
-    });
-    return { ok: true, newBatch: sibling };
+    await Promise.all([this.load(), stockMovements.load()]).catch(() => {});
+    return { ok: true, newBatch: moved };
   }
 
   async moveProductStock(args: {
@@ -11,6 +11,8 @@ export type CartLine = {
   quantity: number;
   extras: string[];
   notes: string;
+  // Serial numbers sold on this line; only serial-tracked products carry them.
+  serials?: string[];
 };
 
 export type CartSession = {
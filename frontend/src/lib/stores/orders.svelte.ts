@@ -1,11 +1,5 @@
-import {
-  products,
-  productionModeOf,
-  recipeOf,
-  type CompositeComponent
-} from './products.svelte';
 import { batches, type BatchAllocation } from './batches.svelte';
-import { stockMovements, type StockMovementReference } from './stockMovements.svelte';
+import { stockMovements } from './stockMovements.svelte';
 import { listOrders, createOrder, updateOrder } from '$lib/api/orders';
 
 export type OrderStatus = 'paid' | 'credit' | 'cancelled';
@@ -43,7 +37,8 @@ export type OrderLine = {
   lineSubtotalNet?: number;     // = lineSubtotal - linePromoDiscount; omitted means lineSubtotal
   lineTax: number;              // = lineSubtotalNet × taxRatePct / 100
   lineTotal: number;            // = lineSubtotalNet + lineTax
-  batchAllocations: BatchAllocation[];  // populated by applyOrderToStock at charge time
+  batchAllocations: BatchAllocation[];  // written by the backend when the sale draws stock
+  serials?: string[];           // one per unit for serial-tracked products; backend marks them sold
 };
 
 export type OrderPromoApplication = {
@@ -86,6 +81,9 @@ export type Order = {
 export type OrderInput = Omit<Order, 'id' | 'code' | 'createdAt' | 'paidAmount' | 'payments'> & {
   paidAmount?: number;
   payments?: OrderPayment[];
+  // POS tab the order was charged from; the backend drops its stock
+  // reservations in the same transaction.
+  cartSessionId?: string;
 };
 
 function normalizeOrder(raw: unknown): Order {
@@ -108,7 +106,8 @@ function normalizeOrder(raw: unknown): Order {
     lineSubtotalNet: l.lineSubtotalNet,
     lineTax: Number(l.lineTax ?? 0),
     lineTotal: Number(l.lineTotal ?? 0),
-    batchAllocations: l.batchAllocations ?? []
+    batchAllocations: l.batchAllocations ?? [],
+    serials: l.serials ?? []
   }));
   const payments = ((r.payments as OrderPayment[] | undefined) ?? []).map((p) => ({
     id: p.id,
@@ -142,8 +141,9 @@ function normalizeOrder(raw: unknown): Order {
   };
 }
 
-function toPayload(o: Partial<Order>): Record<string, unknown> {
+function toPayload(o: Partial<Order> & { cartSessionId?: string }): Record<string, unknown> {
   return {
+    cartSessionId: o.cartSessionId || undefined,
     pricelistId: o.pricelistId || null,
     customerId: o.customerId || null,
     employeeId: o.employeeId || null,
@@ -178,7 +178,8 @@ function toPayload(o: Partial<Order>): Record<string, unknown> {
       lineSubtotalNet: l.lineSubtotalNet ?? (l.lineSubtotal ?? 0),
       lineTax: l.lineTax ?? 0,
       lineTotal: l.lineTotal ?? 0,
-      batchAllocations: l.batchAllocations ?? []
+      batchAllocations: l.batchAllocations ?? [],
+      serials: l.serials ?? []
     })),
     payments: (o.payments ?? []).map((p) => ({
       id: p.id,
@@ -208,11 +209,10 @@ class OrdersStore {
   }
 
   /**
-   * Create an order on the backend. The stock side-effects (FIFO batch
-   * deduction + stockMovements logging + persisting line.batchAllocations)
-   * are handled by applyOrderToStock(order), called by the caller after add()
-   * returns. Two-step flow because applyOrderToStock still mutates frontend-
-   * only stores (batches, stockMovements) until those migrate.
+   * Create an order on the backend. The backend draws the stock in the same
+   * transaction (skipping other holders' reservations), logs the sale
+   * movements and stamps line.batchAllocations; the local batches and
+   * movements are reloaded afterwards.
    */
   async add(input: OrderInput): Promise<Order> {
     const createdAt = new Date().toISOString();
@@ -257,9 +257,10 @@ class OrdersStore {
       tableNumber: input.tableNumber,
       createdAt
     };
-    const created = await createOrder(toPayload(payload));
+    const created = await createOrder(toPayload({ ...payload, cartSessionId: input.cartSessionId }));
     const order = normalizeOrder(created);
     this.items = [order, ...this.items];
+    await Promise.all([batches.load(), stockMovements.load()]).catch(() => {});
     return order;
   }
 
@@ -313,41 +314,19 @@ class OrdersStore {
   }
 
   /**
-   * Cancel an order: restore batch quantities (frontend), log sale-cancel
-   * movements (frontend), then PATCH status='cancelled' to the backend.
+   * Cancel an order. The backend puts the sale's batch allocations back and
+   * logs the sale-cancel movements; the local copies are reloaded after.
    */
   async cancel(id: string): Promise<{ ok: boolean; reason?: string }> {
     const order = this.getById(id);
     if (!order) return { ok: false, reason: 'Order tidak ditemukan.' };
     if (order.status === 'cancelled') return { ok: false, reason: 'Sudah dibatalkan.' };
 
-    const reference: StockMovementReference = { kind: 'order', id: order.id, code: order.code };
-    for (const line of order.lines) {
-      for (const alloc of line.batchAllocations) {
-        const batch = batches.getById(alloc.batchId);
-        if (!batch) continue;
-        const updated = await batches.update(batch.id, {
-          qtyRemaining: batch.qtyRemaining + alloc.qtyTaken
-        });
-        await stockMovements.log({
-          kind: 'sale-cancel',
-          productId: line.productId,
-          variantId: line.variantId,
-          locationId: batch.locationId,
-          batchId: batch.id,
-          qtyDelta: alloc.qtyTaken,
-          qtyAfter: updated?.qtyRemaining ?? batch.qtyRemaining + alloc.qtyTaken,
-          unitCost: alloc.unitCost,
-          reference,
-          notes: `Pembatalan pesanan · ${order.code}`
-        });
-      }
-    }
-
     try {
       const updated = await updateOrder(id, toPayload({ ...order, status: 'cancelled' }));
       const o = normalizeOrder(updated);
       this.items = this.items.map((x) => (x.id === id ? o : x));
+      await Promise.all([batches.load(), stockMovements.load()]).catch(() => {});
       return { ok: true };
     } catch (err) {
       return { ok: false, reason: err instanceof Error ? err.message : 'Gagal.' };
@@ -357,141 +336,6 @@ class OrdersStore {
 
 export const orders = new OrdersStore();
 
-// Walk batches FIFO (oldest receivedAt first), decrement qtyRemaining, and return
-// one BatchAllocation per batch touched. Each allocation is snapshotted so the
-// Consignor Payout report stays correct even if the underlying batch is later
-// mutated or its supplier renamed. See docs/CONSIGNMENT.md §"Sale flow".
-async function deductBatchesFIFO(
-  productId: string,
-  variantId: string | undefined,
-  qty: number,
-  context?: { reference?: StockMovementReference; notes?: string }
-): Promise<BatchAllocation[]> {
-  const allocations: BatchAllocation[] = [];
-  let remaining = qty;
-  for (const batch of batches.forStock(productId, variantId)) {
-    if (remaining <= 0) break;
-    const take = Math.min(remaining, batch.qtyRemaining);
-    const updated = await batches.update(batch.id, { qtyRemaining: batch.qtyRemaining - take });
-    allocations.push({
-      batchId: batch.id,
-      qtyTaken: take,
-      ownership: batch.ownership,
-      unitCost: batch.unitCost,
-      supplierId: batch.supplierId
-    });
-    if (context?.reference) {
-      await stockMovements.log({
-        kind: 'sale',
-        productId,
-        variantId,
-        locationId: batch.locationId,
-        batchId: batch.id,
-        qtyDelta: -take,
-        qtyAfter: updated?.qtyRemaining ?? batch.qtyRemaining - take,
-        unitCost: batch.unitCost,
-        reference: context.reference,
-        notes: context.notes ?? 'Penjualan'
-      });
-    }
-    remaining -= take;
-  }
-  return allocations;
-}
-
-async function deductComponents(
-  comps: CompositeComponent[],
-  multiplier: number,
-  context?: { reference?: StockMovementReference; notes?: string }
-): Promise<BatchAllocation[]> {
-  const allocations: BatchAllocation[] = [];
-  for (const c of comps) {
-    const baseQty = c.quantity * (c.unitFactor ?? 1) * multiplier;
-    allocations.push(
-      ...(await deductCompositeOrGoods(c.productId, c.variantId, baseQty, context))
-    );
-  }
-  return allocations;
-}
-
-// Mode-aware deduction for one (product, variant?, qty) tuple. Used by the
-// composite-sale branch below and by deductComponents for nested composites.
-//
-//   • Goods → straight FIFO from batches.
-//   • Composite with produced stock → straight FIFO from those batches
-//     (treat the composite like any goods product once it's been produced).
-//   • Composite without produced stock:
-//       - mode 'flexible' → recurse into the recipe (cafe / fried-chicken
-//         fallback). Variant override on the recipe is honored via recipeOf.
-//       - mode 'strict'   → no fallback; allocation list comes back short
-//         and the caller logs an out-of-stock at that line.
-async function deductCompositeOrGoods(
-  productId: string,
-  variantId: string | undefined,
-  qty: number,
-  context?: { reference?: StockMovementReference; notes?: string }
-): Promise<BatchAllocation[]> {
-  if (qty <= 0) return [];
-  const product = products.getById(productId);
-  if (!product || product.kind !== 'composite') {
-    return deductBatchesFIFO(productId, variantId, qty, context);
-  }
-  const fromOwn = await deductBatchesFIFO(productId, variantId, qty, context);
-  const taken = fromOwn.reduce((s, a) => s + a.qtyTaken, 0);
-  const shortfall = qty - taken;
-  if (shortfall <= 0) return fromOwn;
-  const mode = productionModeOf(product, variantId);
-  if (mode === 'strict') return fromOwn;
-  const recipe = recipeOf(product, variantId);
-  if (recipe.length === 0) return fromOwn;
-  return [...fromOwn, ...(await deductComponents(recipe, shortfall, context))];
-}
-
-/**
- * Apply a completed order to inventory.
- * Goods: deplete batches FIFO. Composites: deplete each component's batches via the recipe.
- * Extras with stock impact: deplete the extra's components per quantity sold.
- * Every batch touched is stamped onto the line's batchAllocations for downstream reports,
- * then PATCH'd back to the backend so the snapshot survives a reload.
- *
- * The batches/stockMovements mutations stay frontend-only until those stores
- * migrate — see docs/CONSIGNMENT.md for the snapshot rationale.
- */
-export async function applyOrderToStock(order: Order): Promise<void> {
-  const context = {
-    reference: { kind: 'order' as const, id: order.id, code: order.code },
-    notes: `Penjualan · ${order.code}`
-  };
-  for (const line of order.lines) {
-    const product = products.getById(line.productId);
-    if (!product) continue;
-    const baseQty = line.quantity * (line.unitFactor || 1);
-    const allocations: BatchAllocation[] = [];
-
-    allocations.push(
-      ...(await deductCompositeOrGoods(product.id, line.variantId, baseQty, context))
-    );
-
-    for (const ex of line.extras) {
-      const extraDef = product.extras.find((e) => e.id === ex.extraId);
-      if (extraDef && extraDef.components.length > 0) {
-        allocations.push(...(await deductComponents(extraDef.components, line.quantity, context)));
-      }
-    }
-
-    line.batchAllocations = allocations;
-  }
-
-  // Persist the populated allocations back to the order row. Best-effort —
-  // if it fails, the allocations live only in the in-memory cache until the
-  // next reload. Acceptable since batches/stockMovements are still local too.
-  try {
-    await updateOrder(order.id, toPayload(order));
-  } catch {
-    /* allocations stay client-side; reload will lose them until backend persists */
-  }
-}
-
 export const paymentMethodLabels: Record<PaymentMethod, string> = {
   cash: 'Tunai',
   card: 'Kartu',
@@ -515,30 +359,3 @@ export const orderStatusLabels: Record<OrderStatus, string> = {
 export function orderItemCount(order: Order): number {
   return order.lines.reduce((s, l) => s + l.quantity, 0);
 }
-
-// Walks paid orders (optionally restricted by date range) and aggregates
-// consignment-owed amounts per supplier from each line's batchAllocations.
-// Drives the Outstanding payables table on /payouts.
-export function consignmentOwedBySupplier(args: {
-  start?: string;
-  end?: string;
-}): Map<string, { units: number; amount: number }> {
-  const result = new Map<string, { units: number; amount: number }>();
-  for (const order of orders.items) {
-    if (order.status !== 'paid') continue;
-    const orderDate = order.createdAt.slice(0, 10);
-    if (args.start && orderDate < args.start) continue;
-    if (args.end && orderDate > args.end) continue;
-    for (const line of order.lines) {
-      for (const alloc of line.batchAllocations) {
-        if (alloc.ownership !== 'consignment') continue;
-        if (!alloc.supplierId) continue;
-        const cur = result.get(alloc.supplierId) ?? { units: 0, amount: 0 };
-        cur.units += alloc.qtyTaken;
-        cur.amount += alloc.qtyTaken * alloc.unitCost;
-        result.set(alloc.supplierId, cur);
-      }
-    }
-  }
-  return result;
-}
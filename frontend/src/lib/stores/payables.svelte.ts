@@ -0,0 +1,45 @@
+import {
+  getPayables,
+  createSupplierPayment,
+  type ApReport,
+  type SupplierPaymentInput
+} from '$lib/api/payables';
+import { purchaseOrders } from './purchaseOrders.svelte';
+
+// Accounts payable. The report (balances, due dates, aging) comes from the
+// server; the page only filters it.
+class PayablesStore {
+  report = $state<ApReport | null>(null);
+  loading = $state(false);
+  upcomingDays = $state(14);
+
+  async load(): Promise<void> {
+    if (this.loading) return;
+    this.loading = true;
+    try {
+      this.report = await getPayables({ upcomingDays: this.upcomingDays });
+    } finally {
+      this.loading = false;
+    }
+  }
+
+  // Record a supplier payment, optionally split over several of its POs.
+  // The server rejects paying any PO past what it still owes; afterwards
+  // the affected POs and the report are reloaded.
+  async pay(input: SupplierPaymentInput): Promise<{ ok: boolean; reason?: string }> {
+    if (!Number.isFinite(input.amount) || input.amount <= 0)
+      return { ok: false, reason: 'Jumlah pembayaran harus lebih dari 0.' };
+    try {
+      const payment = await createSupplierPayment(input);
+      await Promise.all([
+        purchaseOrders.refresh(payment.allocations.map((a) => a.purchaseOrderId)),
+        this.load()
+      ]);
+      return { ok: true };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal mencatat pembayaran.' };
+    }
+  }
+}
+
+export const payables = new PayablesStore();
@@ -1,4 +1,11 @@
-import { listPayouts, createPayout, deletePayout } from '$lib/api/payouts';
+import {
+  listPayouts,
+  createPayout,
+  deletePayout,
+  listPayoutOutstanding,
+  getPayoutSettlement,
+  getPayoutStatementPdf
+} from '$lib/api/payouts';
 
 export type PayoutMethod = 'cash' | 'transfer' | 'other';
 
@@ -12,9 +19,64 @@ export type Payout = {
   coversPeriodStart: string;
   coversPeriodEnd: string;
   notes: string;
+  // What the settlement statement behind the payout showed.
+  soldUnits: number;
+  settledAmount: number;
+  returnedUnits: number;
+  returnedValue: number;
+  createdBy: string;
 };
 
-export type PayoutInput = Omit<Payout, 'id' | 'code'>;
+// amount 0 pays exactly what the settlement owes.
+export type PayoutInput = Pick<
+  Payout,
+  'supplierId' | 'amount' | 'paidAt' | 'method' | 'coversPeriodStart' | 'coversPeriodEnd' | 'notes'
+>;
+
+// Per-consignor totals for a period; outstanding is what no payout has
+// settled yet.
+export type PayoutOutstanding = {
+  supplierId: string;
+  soldUnits: number;
+  owed: number;
+  returnedUnits: number;
+  returnedValue: number;
+  onHandUnits: number;
+  paid: number;
+  outstanding: number;
+};
+
+export type SettlementLine = {
+  orderId: string;
+  orderCode: string;
+  orderLineId: string;
+  soldAt: string;
+  productName: string;
+  variantName: string;
+  batchId: string;
+  batchCode: string;
+  qty: number;
+  unitCost: number;
+  amount: number;
+};
+
+export type Settlement = {
+  supplierId: string;
+  supplierName: string;
+  start: string;
+  end: string;
+  lines: SettlementLine[];
+  soldUnits: number;
+  owed: number;
+  settledUnits: number;
+  settledAmount: number;
+  earlierUnsettled: number;
+  returns: { id: string; code: string; returnedAt: string; qty: number; value: number }[];
+  returnedUnits: number;
+  returnedValue: number;
+  previousPayouts: Payout[];
+  previousPaid: number;
+};
 
 function normalizePayout(raw: unknown): Payout {
   const r = raw as Partial<Payout> & Record<string, unknown>;
@@ -27,7 +89,68 @@ function normalizePayout(raw: unknown): Payout {
     method: (r.method ?? 'cash') as PayoutMethod,
     coversPeriodStart: (r.coversPeriodStart ?? '') as string,
     coversPeriodEnd: (r.coversPeriodEnd ?? '') as string,
-    notes: (r.notes ?? '') as string
+    notes: (r.notes ?? '') as string,
+    soldUnits: Number(r.soldUnits ?? 0),
+    settledAmount: Number(r.settledAmount ?? 0),
+    returnedUnits: Number(r.returnedUnits ?? 0),
+    returnedValue: Number(r.returnedValue ?? 0),
+    createdBy: (r.createdBy ?? '') as string
+  };
+}
+
+function normalizeOutstanding(raw: unknown): PayoutOutstanding {
+  const r = raw as Record<string, unknown>;
+  return {
+    supplierId: String(r.supplierId ?? ''),
+    soldUnits: Number(r.soldUnits ?? 0),
+    owed: Number(r.owed ?? 0),
+    returnedUnits: Number(r.returnedUnits ?? 0),
+    returnedValue: Number(r.returnedValue ?? 0),
+    onHandUnits: Number(r.onHandUnits ?? 0),
+    paid: Number(r.paid ?? 0),
+    outstanding: Number(r.outstanding ?? 0)
+  };
+}
+
+function normalizeSettlement(raw: unknown): Settlement {
+  const r = raw as Record<string, unknown>;
+  const lines = (r.lines ?? []) as Record<string, unknown>[];
+  const returns = (r.returns ?? []) as Record<string, unknown>[];
+  const previous = (r.previousPayouts ?? []) as unknown[];
+  return {
+    supplierId: String(r.supplierId ?? ''),
+    supplierName: String(r.supplierName ?? ''),
+    start: String(r.start ?? ''),
+    end: String(r.end ?? ''),
+    lines: lines.map((l) => ({
+      orderId: String(l.orderId ?? ''),
+      orderCode: String(l.orderCode ?? ''),
+      orderLineId: String(l.orderLineId ?? ''),
+      soldAt: String(l.soldAt ?? ''),
+      productName: String(l.productName ?? ''),
+      variantName: String(l.variantName ?? ''),
+      batchId: String(l.batchId ?? ''),
+      batchCode: String(l.batchCode ?? ''),
+      qty: Number(l.qty ?? 0),
+      unitCost: Number(l.unitCost ?? 0),
+      amount: Number(l.amount ?? 0)
+    })),
+    soldUnits: Number(r.soldUnits ?? 0),
+    owed: Number(r.owed ?? 0),
+    settledUnits: Number(r.settledUnits ?? 0),
+    settledAmount: Number(r.settledAmount ?? 0),
+    earlierUnsettled: Number(r.earlierUnsettled ?? 0),
+    returns: returns.map((x) => ({
+      id: String(x.id ?? ''),
+      code: String(x.code ?? ''),
+      returnedAt: String(x.returnedAt ?? ''),
+      qty: Number(x.qty ?? 0),
+      value: Number(x.value ?? 0)
+    })),
+    returnedUnits: Number(r.returnedUnits ?? 0),
+    returnedValue: Number(r.returnedValue ?? 0),
+    previousPayouts: previous.map(normalizePayout),
+    previousPaid: Number(r.previousPaid ?? 0)
   };
 }
 
@@ -35,6 +158,7 @@ class PayoutsStore {
   items = $state<Payout[]>([]);
   loaded = $state(false);
   loading = $state(false);
+  outstanding = $state<PayoutOutstanding[]>([]);
 
   async load(): Promise<void> {
     if (this.loading) return;
@@ -78,13 +202,24 @@ class PayoutsStore {
     return this.items.find((p) => p.id === id);
   }
 
-  // Sum of payouts already made to a supplier, optionally up to a cutoff date.
-  paidToSupplier(supplierId: string, asOf?: string): number {
-    return this.items.reduce((s, p) => {
-      if (p.supplierId !== supplierId) return s;
-      if (asOf && p.paidAt > asOf) return s;
-      return s + p.amount;
-    }, 0);
+  async loadOutstanding(params?: { start?: string; end?: string }): Promise<void> {
+    const list = await listPayoutOutstanding(params);
+    this.outstanding = list.map(normalizeOutstanding);
+  }
+
+  async settlement(params: { supplierId: string; start?: string; end?: string }): Promise<Settlement> {
+    return normalizeSettlement(await getPayoutSettlement(params));
+  }
+
+  async openStatement(id: string): Promise<{ ok: boolean; reason?: string }> {
+    try {
+      const url = URL.createObjectURL(await getPayoutStatementPdf(id));
+      window.open(url, '_blank');
+      setTimeout(() => URL.revokeObjectURL(url), 60_000);
+      return { ok: true };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal membuka PDF.' };
+    }
   }
 
   countBySupplier(supplierId: string): number {
@@ -2,7 +2,7 @@ import type { PricingStrategy } from './products.svelte';
 import { user } from './user.svelte';
 import { listPriceChanges, createPriceChanges } from '$lib/api/price-changes';
 
-export type PriceChangeSource = 'manual' | 'bulk-adjust' | 'system';
+export type PriceChangeSource = 'manual' | 'bulk-adjust' | 'system' | 'scheduled';
 
 export type PriceChange = {
   id: string;
@@ -169,16 +169,18 @@ export const priceChanges = new PriceChangesStore();
 export const priceChangeSourceLabels: Record<PriceChangeSource, string> = {
   manual: 'Manual',
   'bulk-adjust': 'Bulk adjust',
-  system: 'Sistem'
+  system: 'Sistem',
+  scheduled: 'Terjadwal'
 };
 
 export const priceChangeSourceVariant: Record<
   PriceChangeSource,
-  'neutral' | 'brand' | 'info'
+  'neutral' | 'brand' | 'info' | 'warning'
 > = {
   manual: 'neutral',
   'bulk-adjust': 'brand',
-  system: 'info'
+  system: 'info',
+  scheduled: 'warning'
 };
 
 export function describePricing(s: PricingStrategy): string {
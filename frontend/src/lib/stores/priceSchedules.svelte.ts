@@ -0,0 +1,102 @@
+import {
+  listPriceSchedules,
+  getPriceSchedule,
+  createPriceSchedule,
+  cancelPriceSchedule,
+  type PriceSchedule,
+  type PriceScheduleInput,
+  type PriceScheduleStatus
+} from '$lib/api/price-schedules';
+import { products } from './products.svelte';
+import { priceChanges } from './priceChanges.svelte';
+
+// Scheduled price changes. The server applies them on time; the cached
+// products and price history are reloaded when a reload finds that a
+// schedule went from pending to applied.
+class PriceSchedulesStore {
+  items = $state<PriceSchedule[]>([]);
+  loaded = $state(false);
+  loading = $state(false);
+
+  async load(): Promise<void> {
+    if (this.loading) return;
+    this.loading = true;
+    try {
+      const pendingBefore = new Set(
+        this.items.filter((s) => s.status === 'pending').map((s) => s.id)
+      );
+      const list = await listPriceSchedules();
+      const appliedSince = list.some((s) => s.status === 'applied' && pendingBefore.has(s.id));
+      this.items = list;
+      this.loaded = true;
+      if (appliedSince) {
+        void products.load();
+        void priceChanges.load();
+      }
+    } finally {
+      this.loading = false;
+    }
+  }
+
+  get(id: string): Promise<PriceSchedule> {
+    return getPriceSchedule(id);
+  }
+
+  pending(): PriceSchedule[] {
+    return this.items
+      .filter((s) => s.status === 'pending')
+      .sort((a, b) => a.effectiveAt.localeCompare(b.effectiveAt));
+  }
+
+  async create(
+    input: PriceScheduleInput
+  ): Promise<{ ok: boolean; schedule?: PriceSchedule; reason?: string }> {
+    if (!input.effectiveAt) return { ok: false, reason: 'Isi waktu berlaku.' };
+    if (new Date(input.effectiveAt).getTime() <= Date.now())
+      return { ok: false, reason: 'Waktu berlaku harus di masa depan.' };
+    if (input.items.length === 0) return { ok: false, reason: 'Tidak ada harga yang berubah.' };
+    try {
+      const schedule = await createPriceSchedule(input);
+      this.upsert(schedule);
+      return { ok: true, schedule };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal menyimpan jadwal.' };
+    }
+  }
+
+  async cancel(id: string): Promise<{ ok: boolean; schedule?: PriceSchedule; reason?: string }> {
+    try {
+      const schedule = await cancelPriceSchedule(id);
+      this.upsert(schedule);
+      return { ok: true, schedule };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal membatalkan jadwal.' };
+    }
+  }
+
+  private upsert(schedule: PriceSchedule) {
+    const row = { ...schedule, items: [] };
+    this.items = this.items.some((s) => s.id === schedule.id)
+      ? this.items.map((s) => (s.id === schedule.id ? row : s))
+      : [row, ...this.items];
+  }
+}
+
+export const priceSchedules = new PriceSchedulesStore();
+
+export const priceScheduleStatusLabels: Record<PriceScheduleStatus, string> = {
+  pending: 'Terjadwal',
+  applied: 'Diterapkan',
+  cancelled: 'Dibatalkan',
+  failed: 'Gagal'
+};
+
+export const priceScheduleStatusBadge: Record<
+  PriceScheduleStatus,
+  'info' | 'success' | 'neutral' | 'danger'
+> = {
+  pending: 'info',
+  applied: 'success',
+  cancelled: 'neutral',
+  failed: 'danger'
+};
@@ -4,14 +4,13 @@ import {
   productionModeOf,
   recipeOf,
   type CompositeComponent,
-  type Product,
-  type ProductVariant
+  type Product
 } from './products.svelte';
 import { batches, type Batch } from './batches.svelte';
 import { locations } from './locations.svelte';
 import { stockMovements } from './stockMovements.svelte';
 import { units } from './units.svelte';
-import { listProductionRuns, createProductionRun } from '$lib/api/production-runs';
+import { listProductionRuns, executeProductionRun } from '$lib/api/production-runs';
 
 export type ProductionRunStatus = 'completed' | 'cancelled';
 
@@ -325,12 +324,6 @@ class ProductionRunsStore {
     if (product.variants.length > 0 && !input.variantId)
       return { ok: false, reason: 'Pilih varian yang akan diproduksi.' };
 
-    const variant: ProductVariant | undefined = input.variantId
-      ? product.variants.find((v) => v.id === input.variantId)
-      : undefined;
-    if (input.variantId && !variant)
-      return { ok: false, reason: 'Varian tidak ditemukan.' };
-
     const producedQty = input.producedQty ?? input.intendedQty;
     if (producedQty <= 0)
       return { ok: false, reason: 'Jumlah yang dihasilkan harus lebih dari 0.' };
@@ -340,128 +333,23 @@ class ProductionRunsStore {
         reason: 'Jumlah yang dihasilkan tidak boleh lebih dari yang direncanakan.'
       };
 
-    // Recompute the plan right before consuming so we catch any stock change
-    // that happened between modal-open and submit.
-    const plan = planConsumption(product.id, input.variantId, input.intendedQty);
-    if (!plan.ok) {
-      return {
-        ok: false,
-        reason: plan.blockReasons[0] ?? 'Stok komponen tidak cukup.'
-      };
-    }
-
-    const locId = input.locationId || locations.defaultId();
-    const todayISO = new Date().toISOString().slice(0, 10);
-
-    // Reserve a transient run reference. Real id+code arrive after backend
-    // persistence; stock movements logged against the transient ref get
-    // updated to the real id once we know it.
-    const tempRunId = crypto.randomUUID();
-    const reference = { kind: 'production' as const, id: tempRunId };
-
-    // 2) FIFO-consume each planned draw, logging production-out per batch.
-    const consumptions: ConsumedComponent[] = [];
-    let actualTotalCost = 0;
-    for (const req of plan.requirements) {
-      for (const draw of req.draws) {
-        const batch = batches.getById(draw.batchId);
-        if (!batch || batch.qtyRemaining < draw.take) {
-          // Plan went stale between preview and submit. Roll back? In this
-          // memory-only seed app the loss is acceptable; the planner re-runs
-          // pre-submit so this should be extremely rare.
-          return {
-            ok: false,
-            reason: `Stok ${req.productName} berubah selama persiapan. Coba lagi.`
-          };
-        }
-        const updated = await batches.update(batch.id, {
-          qtyRemaining: batch.qtyRemaining - draw.take
-        });
-        consumptions.push({
-          productId: req.productId,
-          variantId: req.variantId,
-          batchId: batch.id,
-          batchCode: batch.code,
-          qtyConsumed: draw.take,
-          unitCost: draw.unitCost
-        });
-        actualTotalCost += draw.take * draw.unitCost;
-        await stockMovements.log({
-          kind: 'production-out',
-          productId: req.productId,
-          variantId: req.variantId,
-          locationId: batch.locationId,
-          batchId: batch.id,
-          qtyDelta: -draw.take,
-          qtyAfter: updated?.qtyRemaining ?? batch.qtyRemaining - draw.take,
-          unitCost: draw.unitCost,
-          reference,
-          notes: `Produksi ${product.name}${variant ? ` · ${variant.name}` : ''}`
-        });
-      }
-    }
-
-    // 3) Resolve expiresAt — explicit field wins; otherwise derive from
-    //    shelf-life-after-production (hours from now).
-    let expiresAt = input.expiresAt;
-    if (!expiresAt && product.shelfLifeAfterProductionHours) {
-      const d = new Date();
-      d.setHours(d.getHours() + product.shelfLifeAfterProductionHours);
-      expiresAt = d.toISOString().slice(0, 10);
-    }
-
-    const perUnitCost = actualTotalCost / producedQty;
-    const producedBatch = await batches.add({
-      productId: product.id,
-      variantId: input.variantId,
-      ownership: 'owned',
-      unitCost: perUnitCost,
-      qtyReceived: producedQty,
-      qtyRemaining: producedQty,
-      receivedAt: todayISO,
-      expiresAt: expiresAt || undefined,
-      locationId: locId,
-      notes:
-        input.notes?.trim() ||
-        `Hasil produksi${variant ? ` — ${variant.name}` : ''}`
-    });
-    await stockMovements.log({
-      kind: 'production-in',
-      productId: product.id,
-      variantId: input.variantId,
-      locationId: locId,
-      batchId: producedBatch.id,
-      qtyDelta: producedQty,
-      qtyAfter: producedQty,
-      unitCost: perUnitCost,
-      reference,
-      notes: `Produksi ${product.name}${variant ? ` · ${variant.name}` : ''}`
-    });
-
+    // The server consumes the component batches, creates the output batch
+    // and logs the production movements in one transaction; the local
+    // batches and movements are reloaded afterwards.
     try {
-      const created = await createProductionRun({
+      const created = await executeProductionRun({
         productId: product.id,
         variantId: input.variantId ?? null,
         intendedQty: input.intendedQty,
         producedQty,
-        producedBatchId: producedBatch.id,
-        unitCost: perUnitCost,
-        locationId: locId,
-        expiresAt: expiresAt ?? '',
+        locationId: input.locationId || locations.defaultId() || null,
+        expiresAt: input.expiresAt ?? '',
         shiftId: input.shiftId ?? null,
-        status: 'completed',
-        notes: input.notes ?? '',
-        componentConsumptions: consumptions.map((c) => ({
-          productId: c.productId,
-          variantId: c.variantId ?? null,
-          batchId: c.batchId,
-          batchCode: c.batchCode,
-          qtyConsumed: c.qtyConsumed,
-          unitCost: c.unitCost
-        }))
+        notes: input.notes ?? ''
       });
       const run = normalizeRun(created);
       this.items = [...this.items, run];
+      await Promise.all([batches.load(), stockMovements.load()]).catch(() => {});
       return { ok: true, run };
     } catch (err) {
       return { ok: false, reason: err instanceof Error ? err.message : 'Gagal simpan.' };
@@ -210,6 +210,7 @@ export type Product = {
   extras: ProductExtra[];
   requiresBatchLabel?: boolean;   // print a thermal label per received batch (perishables, lot-tracked items)
   requiresExpiration?: boolean;   // capture expiration date on every batch; FIFO walks expiration ASC
+  serialTracked?: boolean;        // one serial per unit, captured at PO receipt and required at sale
   // Regulatory / warranty info — optional, only used for regulated categories
   // (kosmetik, makanan, obat) or durable goods (elektronik).
   bpomNumber?: string;            // nomor izin edar BPOM (mis. "POM NA 18101200123")
@@ -1,16 +1,32 @@
 import { products, type Product, type ProductVariant } from './products.svelte';
 import { batches } from './batches.svelte';
-import { locations } from './locations.svelte';
 import { stockMovements } from './stockMovements.svelte';
 import {
   listPurchaseOrders,
+  getPurchaseOrder,
   createPurchaseOrder,
   updatePurchaseOrder,
-  deletePurchaseOrder
+  deletePurchaseOrder,
+  transitionPurchaseOrder,
+  receivePurchaseOrder,
+  emailPurchaseOrder,
+  listPurchaseOrderEmails,
+  getPurchaseOrderPdf,
+  type PurchaseOrderEmail,
+  type PurchaseOrderAction,
+  type ReceivePurchaseOrderInput
 } from '$lib/api/purchase-orders';
 
 export type PurchaseOrderType = 'standard' | 'consignment';
-export type PurchaseOrderStatus = 'draft' | 'sent' | 'partial' | 'received' | 'cancelled';
+export type PurchaseOrderStatus =
+  | 'draft'
+  | 'pending-approval'
+  | 'approved'
+  | 'sent'
+  | 'partial'
+  | 'received'
+  | 'closed'
+  | 'cancelled';
 
 export type PurchaseOrderPaymentMethod = 'cash' | 'transfer' | 'other';
 
@@ -20,6 +36,8 @@ export type PurchaseOrderPayment = {
   method: PurchaseOrderPaymentMethod;
   at: string;
   notes: string;
+  // The supplier payment this is a share of.
+  supplierPaymentId?: string;
 };
 
 export type PurchaseOrderLine = {
@@ -43,16 +61,20 @@ export type PurchaseOrder = {
   orderDate: string;
   expectedDate: string;
   receivedDate: string;
+  // First receipt + supplier payment terms; set by the server.
+  dueDate: string;
   lines: PurchaseOrderLine[];
   paidAmount: number;
+  // Debit notes from purchase returns; server-maintained, lowers the payable.
+  debitNoteAmount: number;
   payments: PurchaseOrderPayment[];
   notes: string;
 };
 
-export type PurchaseOrderInput = Omit<PurchaseOrder, 'id' | 'code' | 'paidAmount' | 'payments'> & {
-  paidAmount?: number;
-  payments?: PurchaseOrderPayment[];
-};
+export type PurchaseOrderInput = Omit<
+  PurchaseOrder,
+  'id' | 'code' | 'dueDate' | 'paidAmount' | 'debitNoteAmount' | 'payments'
+>;
 
 class PurchaseOrdersStore {
   items = $state<PurchaseOrder[]>([]);
@@ -76,8 +98,10 @@ class PurchaseOrdersStore {
       ...input,
       id: '',
       code: '',
-      paidAmount: input.paidAmount ?? 0,
-      payments: input.payments ?? []
+      dueDate: '',
+      paidAmount: 0,
+      debitNoteAmount: 0,
+      payments: []
     });
     const created = await createPurchaseOrder(payload);
     const po = normalizeIncoming(created);
@@ -113,179 +137,133 @@ class PurchaseOrdersStore {
     return this.items.find((p) => p.id === id);
   }
 
-  async markSent(id: string): Promise<{ ok: true } | { ok: false; reason: string }> {
-    const po = this.getById(id);
-    if (!po) return { ok: false, reason: 'PO tidak ditemukan.' };
-    if (po.status !== 'draft') return { ok: false, reason: 'Hanya draft yang bisa dikirim.' };
-    if (po.lines.length === 0) return { ok: false, reason: 'Tambahkan minimal satu item.' };
+  // Runs one state-machine step server-side (submit, approve, reject, send,
+  // close, cancel). The server checks the allowed source status, the
+  // approval permission and logs the transition.
+  async transition(
+    id: string,
+    action: PurchaseOrderAction,
+    notes = ''
+  ): Promise<{ ok: true; po: PurchaseOrder } | { ok: false; reason: string }> {
+    if (!this.getById(id)) return { ok: false, reason: 'PO tidak ditemukan.' };
     try {
-      await this.update(id, { status: 'sent' });
-      return { ok: true };
+      const po = normalizeIncoming(await transitionPurchaseOrder(id, action, notes));
+      this.items = this.items.map((p) => (p.id === id ? po : p));
+      return { ok: true, po };
     } catch (err) {
       return { ok: false, reason: err instanceof Error ? err.message : 'Gagal.' };
     }
   }
 
-  async cancel(id: string): Promise<{ ok: true } | { ok: false; reason: string }> {
-    const po = this.getById(id);
-    if (!po) return { ok: false, reason: 'PO tidak ditemukan.' };
-    if (po.status === 'received') return { ok: false, reason: 'PO yang sudah diterima tidak bisa dibatalkan.' };
-    if (po.status === 'cancelled') return { ok: false, reason: 'Sudah dibatalkan.' };
+  cancel(id: string) {
+    return this.transition(id, 'cancel');
+  }
+
+  // Email the PO's PDF to the supplier. The server marks an approved PO
+  // sent once the mail is accepted; a failed delivery is still logged.
+  async email(
+    id: string,
+    input: { cc?: string[]; message?: string }
+  ): Promise<{ ok: true; email: PurchaseOrderEmail } | { ok: false; reason: string }> {
+    if (!this.getById(id)) return { ok: false, reason: 'PO tidak ditemukan.' };
     try {
-      await this.update(id, { status: 'cancelled' });
-      return { ok: true };
+      const res = await emailPurchaseOrder(id, input);
+      const po = normalizeIncoming(res.purchaseOrder);
+      this.items = this.items.map((p) => (p.id === id ? po : p));
+      return { ok: true, email: res.email };
     } catch (err) {
-      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal.' };
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal mengirim email.' };
     }
   }
 
-  // Record a supplier payment. Validates locally, then PATCHes the PO with
-  // the appended payment + updated paidAmount.
-  async recordPayment(
-    poId: string,
-    args: { amount: number; method: PurchaseOrderPaymentMethod; notes?: string; at?: string }
-  ): Promise<{ ok: boolean; reason?: string; po?: PurchaseOrder }> {
-    const po = this.getById(poId);
-    if (!po) return { ok: false, reason: 'PO tidak ditemukan.' };
-    if (po.type === 'consignment')
-      return { ok: false, reason: 'PO konsinyasi memakai Pembayaran Konsinyasi, bukan Utang.' };
-    if (po.status === 'cancelled') return { ok: false, reason: 'PO sudah dibatalkan.' };
-    if (!Number.isFinite(args.amount) || args.amount <= 0)
-      return { ok: false, reason: 'Jumlah pembayaran harus lebih dari 0.' };
-    const total = poTotal(po);
-    const outstanding = total - po.paidAmount;
-    if (args.amount > outstanding + 0.0001)
-      return { ok: false, reason: `Jumlah melebihi sisa utang (${outstanding}).` };
-
-    const payment: PurchaseOrderPayment = {
-      id: crypto.randomUUID(),
-      amount: args.amount,
-      method: args.method,
-      at: args.at ?? new Date().toISOString(),
-      notes: args.notes ?? ''
-    };
+  emails(id: string): Promise<PurchaseOrderEmail[]> {
+    return listPurchaseOrderEmails(id);
+  }
+
+  // Opens the PO PDF in a new tab.
+  async openPdf(id: string): Promise<{ ok: boolean; reason?: string }> {
     try {
-      const updated = await this.update(poId, {
-        paidAmount: po.paidAmount + args.amount,
-        payments: [...po.payments, payment]
-      } as Partial<PurchaseOrderInput>);
-      return { ok: true, po: updated };
+      const url = URL.createObjectURL(await getPurchaseOrderPdf(id));
+      window.open(url, '_blank');
+      setTimeout(() => URL.revokeObjectURL(url), 60_000);
+      return { ok: true };
     } catch (err) {
-      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal.' };
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal membuka PDF.' };
     }
   }
 
-  // Receive flow: persist new PO state to backend, then run the stock-side
-  // effects (batches + stockMovements + optional supplier cost update) on the
-  // frontend. The latter still rely on frontend-only stores; will move to
-  // backend when batches/stockMovements migrate.
+  // Reload specific POs after a server-side change to them (supplier
+  // payments, for one).
+  async refresh(ids: string[]): Promise<void> {
+    const fresh = await Promise.all(ids.map((id) => getPurchaseOrder(id)));
+    const byId = new Map(fresh.map((r) => [String(r.id), normalizeIncoming(r)]));
+    this.items = this.items.map((p) => byId.get(p.id) ?? p);
+  }
+
+  // Receive flow: the server creates the batches and receive movements and
+  // moves the PO to partial/received in one transaction; the optional
+  // supplier cost update stays a frontend follow-up.
   async receive(
     id: string,
     opts?: {
       receivedDate?: string;
       receiveQty?: Record<string, number>;
       expiresAt?: Record<string, string>;
+      serials?: Record<string, string[]>;
       actualPrices?: Record<string, number>;
       updateSupplierCost?: Record<string, boolean>;
     }
   ): Promise<{ ok: boolean; reason?: string }> {
     const po = this.getById(id);
     if (!po) return { ok: false, reason: 'PO tidak ditemukan.' };
-    if (po.status === 'received') return { ok: false, reason: 'PO sudah diterima sepenuhnya.' };
-    if (po.status === 'cancelled') return { ok: false, reason: 'PO sudah dibatalkan.' };
-    if (po.status === 'draft') return { ok: false, reason: 'Kirim PO terlebih dulu.' };
+    if (po.status !== 'sent' && po.status !== 'partial')
+      return { ok: false, reason: 'PO belum dikirim atau sudah selesai.' };
     if (po.lines.length === 0) return { ok: false, reason: 'Tidak ada item untuk diterima.' };
 
-    const effectiveReceivedDate = opts?.receivedDate || new Date().toISOString().slice(0, 10);
-    type SideEffect = {
-      line: PurchaseOrderLine;
-      baseQty: number;
-      perBaseUnitCost: number;
-      expiresAt?: string;
-    };
-    const sideEffects: SideEffect[] = [];
-
-    let touched = 0;
-    const nextLines = po.lines.map((line) => {
-      if (line.quantity <= 0) return line;
-      const product = products.getById(line.productId);
-      if (!product) return line;
+    const lines: ReceivePurchaseOrderInput['lines'] = [];
+    for (const line of po.lines) {
       const remaining = line.quantity - line.receivedQty;
-      if (remaining <= 0) return line;
+      if (line.quantity <= 0 || remaining <= 0) continue;
       const requested = opts?.receiveQty?.[line.id];
-      const qtyToReceive = Math.max(
-        0,
-        Math.min(remaining, requested === undefined ? remaining : requested)
-      );
-      if (qtyToReceive === 0) return line;
-
-      const factor = line.unitFactor > 0 ? line.unitFactor : 1;
-      const baseQty = qtyToReceive * factor;
-      const effectiveUnitPrice = opts?.actualPrices?.[line.id] ?? line.unitPrice;
-      const perBaseUnitCost = effectiveUnitPrice / factor;
-
-      sideEffects.push({
-        line,
-        baseQty,
-        perBaseUnitCost,
-        expiresAt: opts?.expiresAt?.[line.id] || undefined
+      const qty = Math.max(0, Math.min(remaining, requested === undefined ? remaining : requested));
+      if (qty === 0) continue;
+      lines.push({
+        lineId: line.id,
+        qty,
+        expiresAt: opts?.expiresAt?.[line.id] || undefined,
+        serials: opts?.serials?.[line.id]?.length ? opts.serials[line.id] : undefined,
+        unitPrice: opts?.actualPrices?.[line.id]
       });
-
-      touched++;
-      return { ...line, receivedQty: line.receivedQty + qtyToReceive };
-    });
-
-    if (touched === 0) return { ok: false, reason: 'Tidak ada kuantitas untuk diterima.' };
-
-    const allReceived = nextLines.every((l) => l.receivedQty >= l.quantity);
-    const nextStatus: PurchaseOrderStatus = allReceived ? 'received' : 'partial';
-    const patch: Partial<PurchaseOrderInput> = { status: nextStatus, lines: nextLines };
-    if (allReceived) patch.receivedDate = effectiveReceivedDate;
+    }
+    if (lines.length === 0) return { ok: false, reason: 'Tidak ada kuantitas untuk diterima.' };
 
     try {
-      await this.update(id, patch);
+      const res = await receivePurchaseOrder(id, {
+        receivedDate: opts?.receivedDate,
+        lines
+      });
+      const updated = normalizeIncoming(res.purchaseOrder);
+      this.items = this.items.map((p) => (p.id === id ? updated : p));
     } catch (err) {
-      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal simpan PO.' };
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal menerima PO.' };
     }
-
-    for (const fx of sideEffects) {
-      const newBatch = await batches.add({
-        productId: fx.line.productId,
-        variantId: fx.line.variantId,
-        ownership: po.type === 'consignment' ? 'consignment' : 'owned',
-        supplierId: po.supplierId,
-        sourcePurchaseOrderId: po.id,
-        sourcePurchaseOrderLineId: fx.line.id,
-        unitCost: fx.perBaseUnitCost,
-        qtyReceived: fx.baseQty,
-        qtyRemaining: fx.baseQty,
-        receivedAt: effectiveReceivedDate,
-        expiresAt: fx.expiresAt,
-        locationId: locations.defaultId(),
-        notes: ''
-      });
-      await stockMovements.log({
-        kind: 'receive',
-        productId: fx.line.productId,
-        variantId: fx.line.variantId,
-        locationId: newBatch.locationId,
-        batchId: newBatch.id,
-        qtyDelta: fx.baseQty,
-        qtyAfter: newBatch.qtyRemaining,
-        unitCost: fx.perBaseUnitCost,
-        reference: { kind: 'po', id: po.id, code: po.code },
-        notes: po.type === 'consignment' ? 'Penerimaan konsinyasi' : 'Penerimaan PO'
-      });
-
-      if (opts?.updateSupplierCost?.[fx.line.id] && po.type !== 'consignment') {
-        const product = products.getById(fx.line.productId);
+    await Promise.all([batches.load(), stockMovements.load()]).catch(() => {});
+
+    if (po.type !== 'consignment') {
+      for (const l of lines) {
+        if (!opts?.updateSupplierCost?.[l.lineId]) continue;
+        const line = po.lines.find((x) => x.id === l.lineId);
+        if (!line) continue;
+        const factor = line.unitFactor > 0 ? line.unitFactor : 1;
+        const perBaseUnitCost = (l.unitPrice ?? line.unitPrice) / factor;
+        const product = products.getById(line.productId);
         const existing = product?.suppliers ?? [];
         const idx = existing.findIndex((s) => s.supplierId === po.supplierId);
         if (idx >= 0) {
-          const updated = existing.map((s, i) =>
-            i === idx ? { ...s, unitCost: fx.perBaseUnitCost } : s
+          const updatedSuppliers = existing.map((s, i) =>
+            i === idx ? { ...s, unitCost: perBaseUnitCost } : s
           );
-          void products.update(fx.line.productId, { suppliers: updated });
+          void products.update(line.productId, { suppliers: updatedSuppliers });
         }
       }
     }
@@ -330,7 +308,8 @@ function normalizeIncoming(raw: unknown): PurchaseOrder {
     amount: Number(p.amount ?? 0),
     method: (p.method ?? 'cash') as PurchaseOrderPaymentMethod,
     at: p.at ?? '',
-    notes: p.notes ?? ''
+    notes: p.notes ?? '',
+    supplierPaymentId: p.supplierPaymentId ?? undefined
   }));
   return {
     id: String(r.id ?? ''),
@@ -341,8 +320,10 @@ function normalizeIncoming(raw: unknown): PurchaseOrder {
     orderDate: (r.orderDate ?? '') as string,
     expectedDate: (r.expectedDate ?? '') as string,
     receivedDate: (r.receivedDate ?? '') as string,
+    dueDate: (r.dueDate ?? '') as string,
     lines,
     paidAmount: Number(r.paidAmount ?? 0),
+    debitNoteAmount: Number(r.debitNoteAmount ?? 0),
     payments,
     notes: (r.notes ?? '') as string
   };
@@ -408,8 +389,11 @@ export const purchaseOrderTypeLabels: Record<PurchaseOrderType, string> = {
 
 export const purchaseOrderStatusLabels: Record<PurchaseOrderStatus, string> = {
   draft: 'Draft',
+  'pending-approval': 'Menunggu persetujuan',
+  approved: 'Disetujui',
   sent: 'Terkirim',
   partial: 'Sebagian diterima',
   received: 'Diterima',
+  closed: 'Ditutup',
   cancelled: 'Dibatalkan'
 };
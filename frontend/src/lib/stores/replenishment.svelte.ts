@@ -0,0 +1,37 @@
+import {
+  getReplenishmentSuggestions,
+  type ReplenishmentSuggestion
+} from '$lib/api/replenishment';
+
+// Reorder suggestions computed server-side (sales rate with weekday
+// seasonality, on hand, open POs, primary supplier lead time). Reloaded
+// whenever the forecast window or buffer changes.
+class ReplenishmentStore {
+  suggestions = $state<ReplenishmentSuggestion[]>([]);
+  withoutPrimarySupplier = $state<ReplenishmentSuggestion[]>([]);
+  loaded = $state(false);
+  loading = $state(false);
+  error = $state('');
+
+  private seq = 0;
+
+  async load(params: { windowDays: number; bufferDays: number }): Promise<void> {
+    const seq = ++this.seq;
+    this.loading = true;
+    try {
+      const res = await getReplenishmentSuggestions({ ...params, all: true });
+      if (seq !== this.seq) return;
+      this.suggestions = res.suggestions;
+      this.withoutPrimarySupplier = res.withoutPrimarySupplier;
+      this.loaded = true;
+      this.error = '';
+    } catch (err) {
+      if (seq !== this.seq) return;
+      this.error = err instanceof Error ? err.message : 'Gagal memuat saran pemesanan.';
+    } finally {
+      if (seq === this.seq) this.loading = false;
+    }
+  }
+}
+
+export const replenishment = new ReplenishmentStore();
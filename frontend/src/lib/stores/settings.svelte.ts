@@ -20,10 +20,18 @@ export type FnbSettings = {
   requireTableNumber: boolean;
 };
 
+export type AllocationStrategy = 'fefo' | 'fifo';
+
 export type Settings = {
   inventory: {
     locationsEnabled: boolean;
     auditTrailEnabled: boolean;
+    /** Batch draw order for sales & production. FIFO by default; FEFO (earliest expiry first) is opt-in. */
+    allocationStrategy: AllocationStrategy;
+    /** Near-expiry horizon (days) for categories without their own. */
+    nearExpiryDays: number;
+    /** Minutes a held cart / pending order keeps its stock reservation. */
+    reservationTtlMinutes: number;
   };
   operations: {
     shiftsEnabled: boolean;
@@ -48,7 +56,10 @@ function defaultSettings(): Settings {
   return {
     inventory: {
       locationsEnabled: true,
-      auditTrailEnabled: true
+      auditTrailEnabled: true,
+      allocationStrategy: 'fifo',
+      nearExpiryDays: 30,
+      reservationTtlMinutes: 30
     },
     operations: {
       shiftsEnabled: true,
@@ -69,6 +80,12 @@ function mergeSettings(stored: unknown): Settings {
       s.inventory.locationsEnabled ?? base.inventory.locationsEnabled;
     base.inventory.auditTrailEnabled =
       s.inventory.auditTrailEnabled ?? base.inventory.auditTrailEnabled;
+    base.inventory.allocationStrategy =
+      s.inventory.allocationStrategy ?? base.inventory.allocationStrategy;
+    base.inventory.nearExpiryDays =
+      s.inventory.nearExpiryDays ?? base.inventory.nearExpiryDays;
+    base.inventory.reservationTtlMinutes =
+      s.inventory.reservationTtlMinutes ?? base.inventory.reservationTtlMinutes;
   }
   if (s.operations) {
     base.operations.shiftsEnabled =
@@ -121,6 +138,21 @@ class SettingsStore {
     this.persist();
   }
 
+  setAllocationStrategy(strategy: AllocationStrategy): void {
+    this.value.inventory.allocationStrategy = strategy;
+    this.persist();
+  }
+
+  setNearExpiryDays(days: number): void {
+    this.value.inventory.nearExpiryDays = days;
+    this.persist();
+  }
+
+  setReservationTtlMinutes(minutes: number): void {
+    this.value.inventory.reservationTtlMinutes = minutes;
+    this.persist();
+  }
+
   setShiftsEnabled(on: boolean): void {
     this.value.operations.shiftsEnabled = on;
     this.persist();
@@ -152,6 +184,10 @@ export function auditTrailEnabled(): boolean {
   return settings.value.inventory.auditTrailEnabled;
 }
 
+export function allocationStrategy(): AllocationStrategy {
+  return settings.value.inventory.allocationStrategy;
+}
+
 export function shiftsEnabled(): boolean {
   return settings.value.operations.shiftsEnabled;
 }
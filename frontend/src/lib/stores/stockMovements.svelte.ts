@@ -1,9 +1,4 @@
-import { settings } from './settings.svelte';
-import { user } from './user.svelte';
-import {
-  listStockMovements,
-  createStockMovement
-} from '$lib/api/stock-movements';
+import { listStockMovements } from '$lib/api/stock-movements';
 
 export type StockMovementKind =
   | 'receive'
@@ -15,8 +10,10 @@ export type StockMovementKind =
   | 'move-in'
   | 'move-relocate'
   | 'return-consignor'
+  | 'return-supplier'
   | 'production-in'
-  | 'production-out';
+  | 'production-out'
+  | 'write-off';
 
 export type StockMovementReferenceKind =
   | 'po'
@@ -36,6 +33,7 @@ export type StockMovementReference = {
 export type StockAdjustmentReason =
   | 'damaged'
   | 'expired'
+  | 'theft'
   | 'lost'
   | 'sample'
   | 'found'
@@ -46,6 +44,7 @@ export type StockAdjustmentReason =
 export const adjustmentReasonLabels: Record<StockAdjustmentReason, string> = {
   damaged: 'Rusak / pecah',
   expired: 'Kedaluwarsa',
+  theft: 'Pencurian',
   lost: 'Hilang',
   sample: 'Sampel / promo',
   found: 'Ditemukan',
@@ -57,6 +56,7 @@ export const adjustmentReasonLabels: Record<StockAdjustmentReason, string> = {
 export const adjustmentReasonsForOut: StockAdjustmentReason[] = [
   'damaged',
   'expired',
+  'theft',
   'lost',
   'sample',
   'correction',
@@ -89,11 +89,6 @@ export type StockMovement = {
   notes: string;
 };
 
-export type StockMovementInput = Omit<StockMovement, 'id' | 'code' | 'at' | 'performedBy'> & {
-  performedBy?: string;
-  at?: string;
-};
-
 function normalizeMovement(raw: unknown): StockMovement {
   const r = raw as Partial<StockMovement> & Record<string, unknown>;
   const ref = r.reference as
@@ -126,25 +121,6 @@ function normalizeMovement(raw: unknown): StockMovement {
   };
 }
 
-function toMovementPayload(input: StockMovementInput, performedBy: string): Record<string, unknown> {
-  return {
-    kind: input.kind,
-    productId: input.productId,
-    variantId: input.variantId || null,
-    locationId: input.locationId,
-    batchId: input.batchId,
-    qtyDelta: input.qtyDelta,
-    qtyAfter: input.qtyAfter,
-    unitCost: input.unitCost,
-    reference: input.reference ?? { kind: 'manual', id: '' },
-    reason: input.reason ?? null,
-    imageUrl: input.imageUrl ?? '',
-    performedBy,
-    notes: input.notes,
-    at: input.at ?? new Date().toISOString()
-  };
-}
-
 class StockMovementsStore {
   items = $state<StockMovement[]>([]);
   loaded = $state(false);
@@ -162,15 +138,6 @@ class StockMovementsStore {
     }
   }
 
-  async log(input: StockMovementInput): Promise<StockMovement | undefined> {
-    if (!settings.value.inventory.auditTrailEnabled) return undefined;
-    const performedBy = input.performedBy ?? user.current?.name ?? 'System';
-    const created = await createStockMovement(toMovementPayload(input, performedBy));
-    const m = normalizeMovement(created);
-    this.items = [...this.items, m];
-    return m;
-  }
-
   getById(id: string): StockMovement | undefined {
     return this.items.find((m) => m.id === id);
   }
@@ -229,8 +196,10 @@ export const movementKindLabels: Record<StockMovementKind, string> = {
   'move-in': 'Pindah masuk',
   'move-relocate': 'Relokasi',
   'return-consignor': 'Retur konsinyasi',
+  'return-supplier': 'Retur pembelian',
   'production-in': 'Produksi · hasil',
-  'production-out': 'Produksi · konsumsi'
+  'production-out': 'Produksi · konsumsi',
+  'write-off': 'Pemusnahan'
 };
 
 export const movementKindOptions: { value: StockMovementKind; label: string }[] =
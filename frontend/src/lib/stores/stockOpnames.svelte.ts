@@ -1,18 +1,24 @@
-import { batches, currentCost, stockByLocation, stockOf } from './batches.svelte';
+import { batches } from './batches.svelte';
+import { stockMovements } from './stockMovements.svelte';
 import { products } from './products.svelte';
 import { user } from './user.svelte';
 import {
   listStockOpnames,
   createStockOpname,
-  updateStockOpname
+  startStockOpname,
+  recordStockOpnameCounts,
+  completeStockOpname,
+  cancelStockOpname
 } from '$lib/api/stock-opnames';
 
-export type OpnameStatus = 'draft' | 'completed' | 'cancelled';
+export type OpnameStatus = 'draft' | 'counting' | 'completed' | 'cancelled';
 
 export type OpnameLine = {
   id: string;
   productId: string;
   variantId?: string;
+  batchId?: string;
+  locationId?: string;
   expectedQty: number;
   countedQty: number | null;
   unitCost: number;
@@ -88,6 +94,8 @@ function normalizeOpname(raw: unknown): StockOpname {
     id: String(l.id ?? ''),
     productId: String(l.productId ?? ''),
     variantId: (l.variantId as string | undefined) || undefined,
+    batchId: (l.batchId as string | undefined) || undefined,
+    locationId: (l.locationId as string | undefined) || undefined,
     expectedQty: Number(l.expectedQty ?? 0),
     countedQty: l.countedQty === null || l.countedQty === undefined ? null : Number(l.countedQty),
     unitCost: Number(l.unitCost ?? 0),
@@ -106,18 +114,6 @@ function normalizeOpname(raw: unknown): StockOpname {
   };
 }
 
-function linesToPayload(lines: OpnameLine[]): Array<Record<string, unknown>> {
-  return lines.map((l) => ({
-    id: l.id,
-    productId: l.productId,
-    variantId: l.variantId ?? null,
-    expectedQty: l.expectedQty,
-    countedQty: l.countedQty,
-    unitCost: l.unitCost,
-    notes: l.notes
-  }));
-}
-
 class StockOpnamesStore {
   items = $state<StockOpname[]>([]);
   loaded = $state(false);
@@ -135,8 +131,16 @@ class StockOpnamesStore {
     }
   }
 
-  // Build a draft opname (server-persisted). Snapshots expectedQty + unitCost
-  // per line. Composite products excluded — no batches to count.
+  private replace(o: StockOpname) {
+    this.items = this.items.some((x) => x.id === o.id)
+      ? this.items.map((x) => (x.id === o.id ? o : x))
+      : [...this.items, o];
+  }
+
+  // Creates the opname and starts it right away: the server snapshots
+  // expected qty and cost per batch in scope, so nothing is taken from the
+  // browser's copy of stock. Composite products are excluded — no batches
+  // to count.
   async buildDraft(args: {
     locationId?: string;
     categoryIds?: string[];
@@ -146,143 +150,113 @@ class StockOpnamesStore {
     const pickedSet = args.productIds ? new Set(args.productIds) : null;
     const catSet = args.categoryIds && args.categoryIds.length > 0 ? new Set(args.categoryIds) : null;
 
-    const candidates = products.items.filter((p) => {
-      if (p.status !== 'active') return false;
-      if (p.kind === 'composite') return false;
-      if (pickedSet) return pickedSet.has(p.id);
-      if (catSet) return catSet.has(p.categoryId);
-      return true;
-    });
+    const productIds = products.items
+      .filter((p) => {
+        if (p.status !== 'active') return false;
+        if (p.kind === 'composite') return false;
+        if (pickedSet) return pickedSet.has(p.id);
+        if (catSet) return catSet.has(p.categoryId);
+        return true;
+      })
+      .map((p) => p.id);
+
+    // Whole-catalog opnames let the server take every batch in stock.
+    const scoped = pickedSet || catSet;
+    if (scoped && productIds.length === 0) throw new Error('Tidak ada produk untuk dihitung.');
 
-    const lines: Array<Omit<OpnameLine, 'id'>> = [];
-    for (const p of candidates) {
-      const variantList = p.variants.length > 0 ? p.variants : [undefined];
-      for (const v of variantList) {
-        const variantId = v?.id;
-        const expectedQty = args.locationId
-          ? stockByLocation(p.id, variantId).get(args.locationId) ?? 0
-          : stockOf(p.id, variantId);
-        if (!pickedSet && expectedQty <= 0) continue;
-        const unitCost = currentCost(p.id, variantId);
-        lines.push({
-          productId: p.id,
-          variantId,
-          expectedQty,
-          countedQty: null,
-          unitCost,
-          notes: ''
-        });
-      }
+    const created = normalizeOpname(
+      await createStockOpname({
+        locationId: args.locationId ?? null,
+        performedBy: user.current?.name ?? 'System',
+        notes: args.notes ?? ''
+      })
+    );
+    this.replace(created);
+    try {
+      const started = normalizeOpname(
+        await startStockOpname(created.id, { productIds: scoped ? productIds : [] })
+      );
+      this.replace(started);
+      return started;
+    } catch (err) {
+      // Nothing in stock for this scope: drop the empty draft.
+      await cancelStockOpname(created.id)
+        .then((o) => this.replace(normalizeOpname(o)))
+        .catch(() => {});
+      throw err;
     }
+  }
 
-    const created = await createStockOpname({
-      locationId: args.locationId ?? null,
-      performedBy: user.current?.name ?? 'System',
-      status: 'draft',
-      notes: args.notes ?? '',
-      lines: lines.map((l) => ({
-        productId: l.productId,
-        variantId: l.variantId ?? null,
-        expectedQty: l.expectedQty,
-        countedQty: l.countedQty,
-        unitCost: l.unitCost,
-        notes: l.notes
-      }))
-    });
-    const opname = normalizeOpname(created);
-    this.items = [...this.items, opname];
-    return opname;
+  // Starts a draft created elsewhere (e.g. a scheduled cycle count).
+  async start(id: string): Promise<{ ok: boolean; reason?: string }> {
+    try {
+      this.replace(normalizeOpname(await startStockOpname(id, {})));
+      return { ok: true };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal memulai opname.' };
+    }
   }
 
   async updateLine(
     opnameId: string,
     lineId: string,
-    patch: Partial<OpnameLine>
+    patch: Pick<Partial<OpnameLine>, 'countedQty' | 'notes'>
   ): Promise<void> {
-    const opname = this.getById(opnameId);
-    if (!opname) return;
-    const nextLines = opname.lines.map((l) =>
-      l.id === lineId ? { ...l, ...patch } : l
-    );
-    const updated = await updateStockOpname(opnameId, {
-      lines: linesToPayload(nextLines)
-    });
-    const o = normalizeOpname(updated);
-    this.items = this.items.map((x) => (x.id === opnameId ? o : x));
+    const line = this.getById(opnameId)?.lines.find((l) => l.id === lineId);
+    if (!line) return;
+    const next = { ...line, ...patch };
+    const updated = await recordStockOpnameCounts(opnameId, [
+      { lineId, countedQty: next.countedQty, notes: next.notes }
+    ]);
+    this.replace(normalizeOpname(updated));
   }
 
   getById(id: string): StockOpname | undefined {
     return this.items.find((o) => o.id === id);
   }
 
-  // Reconcile variances: per line with countedQty set + variance != 0, call
-  // batches.adjustStock with an opname reference. Resulting movement rows
-  // reference this opname.
+  // Posts the variances server-side (net of movements during counting) as
+  // opname adjust movements, then refreshes stock.
   async complete(
     id: string,
     opts?: { skipUncounted?: boolean }
-  ): Promise<{ ok: boolean; reason?: string; adjusted: number; skipped: number }> {
+  ): Promise<{ ok: boolean; reason?: string; adjusted: number; pending: number; skipped: number }> {
     const opname = this.getById(id);
-    if (!opname) return { ok: false, reason: 'Opname tidak ditemukan.', adjusted: 0, skipped: 0 };
-    if (opname.status !== 'draft')
-      return { ok: false, reason: 'Opname sudah selesai atau dibatalkan.', adjusted: 0, skipped: 0 };
-
-    const reference = { kind: 'opname' as const, id: opname.id, code: opname.code };
-    let adjusted = 0;
-    let skipped = 0;
-
-    for (const line of opname.lines) {
-      if (line.countedQty === null) {
-        if (opts?.skipUncounted) {
-          skipped++;
-          continue;
-        }
-        return {
-          ok: false,
-          reason: 'Masih ada baris yang belum dihitung. Aktifkan skipUncounted untuk melewatinya.',
-          adjusted,
-          skipped
-        };
-      }
-      const variance = line.countedQty - line.expectedQty;
-      if (variance === 0) continue;
-      await batches.adjustStock({
-        productId: line.productId,
-        variantId: line.variantId,
-        delta: variance,
-        unitCost: line.unitCost,
-        locationId: opname.locationId,
-        reference,
-        notes:
-          variance < 0
-            ? `Shrinkage dari opname ${opname.code}${line.notes ? ` · ${line.notes}` : ''}`
-            : `Surplus dari opname ${opname.code}${line.notes ? ` · ${line.notes}` : ''}`
-      });
-      adjusted++;
+    if (!opname) return { ok: false, reason: 'Opname tidak ditemukan.', adjusted: 0, pending: 0, skipped: 0 };
+    if (opname.status !== 'counting')
+      return { ok: false, reason: 'Opname belum dimulai atau sudah selesai.', adjusted: 0, pending: 0, skipped: 0 };
+    try {
+      const res = await completeStockOpname(id, { skipUncounted: opts?.skipUncounted });
+      this.replace(normalizeOpname(res.opname));
+      await Promise.all([batches.load(), stockMovements.load()]).catch(() => {});
+      return {
+        ok: true,
+        adjusted: res.summary.adjustedLines,
+        pending: res.summary.pendingLines,
+        skipped: opts?.skipUncounted ? res.summary.uncountedLines : 0
+      };
+    } catch (err) {
+      return {
+        ok: false,
+        reason: err instanceof Error ? err.message : 'Gagal menyelesaikan opname.',
+        adjusted: 0,
+        pending: 0,
+        skipped: 0
+      };
     }
-
-    const updated = await updateStockOpname(id, {
-      status: 'completed',
-      completedAt: new Date().toISOString()
-    });
-    const o = normalizeOpname(updated);
-    this.items = this.items.map((x) => (x.id === id ? o : x));
-
-    return { ok: true, adjusted, skipped };
   }
 
   async cancel(id: string): Promise<{ ok: boolean; reason?: string }> {
     const opname = this.getById(id);
     if (!opname) return { ok: false, reason: 'Opname tidak ditemukan.' };
-    if (opname.status !== 'draft')
-      return { ok: false, reason: 'Hanya draft yang bisa dibatalkan.' };
-    const updated = await updateStockOpname(id, {
-      status: 'cancelled',
-      completedAt: new Date().toISOString()
-    });
-    const o = normalizeOpname(updated);
-    this.items = this.items.map((x) => (x.id === id ? o : x));
-    return { ok: true };
+    if (opname.status !== 'draft' && opname.status !== 'counting')
+      return { ok: false, reason: 'Opname sudah selesai atau dibatalkan.' };
+    try {
+      this.replace(normalizeOpname(await cancelStockOpname(id)));
+      return { ok: true };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal membatalkan opname.' };
+    }
   }
 }
 
@@ -290,6 +264,7 @@ export const stockOpnames = new StockOpnamesStore();
 
 export const opnameStatusLabels: Record<OpnameStatus, string> = {
   draft: 'Draft',
+  counting: 'Penghitungan',
   completed: 'Selesai',
   cancelled: 'Dibatalkan'
 };
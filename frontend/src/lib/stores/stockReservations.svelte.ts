@@ -0,0 +1,67 @@
+import {
+  getStockAvailability,
+  releaseReservations,
+  replaceReservations,
+  type ReservationLine
+} from '$lib/api/stock-reservations';
+
+function key(productId: string, variantId?: string): string {
+  return `${productId}:${variantId ?? ''}`;
+}
+
+// Server-side holds for the POS tabs. Each tab reserves its goods lines
+// under holder cart:<session id>; what other tabs, terminals and pending
+// orders hold is subtracted from the stock the cart may still add.
+class StockReservationsStore {
+  heldByOthers = $state<Record<string, number>>({});
+  error = $state('');
+
+  private seq = 0;
+
+  heldFor(productId: string, variantId?: string): number {
+    return this.heldByOthers[key(productId, variantId)] ?? 0;
+  }
+
+  async loadFor(cartId: string): Promise<void> {
+    const seq = ++this.seq;
+    try {
+      const rows = await getStockAvailability({ excludeHolder: { kind: 'cart', id: cartId } });
+      if (seq !== this.seq) return;
+      const next: Record<string, number> = {};
+      for (const r of rows) {
+        if (r.reserved > 0) next[key(r.productId, r.variantId)] = r.reserved;
+      }
+      this.heldByOthers = next;
+      this.error = '';
+    } catch (err) {
+      if (seq !== this.seq) return;
+      this.error = err instanceof Error ? err.message : 'Gagal memuat reservasi stok.';
+    }
+  }
+
+  /**
+   * Re-post the cart's lines (also renews the TTL). An empty cart releases
+   * its holds. On failure the previous holds are kept and the server's
+   * reason is returned.
+   */
+  async syncCart(
+    cartId: string,
+    lines: ReservationLine[]
+  ): Promise<{ ok: boolean; reason?: string }> {
+    try {
+      if (lines.length === 0) await releaseReservations('cart', cartId);
+      else await replaceReservations('cart', cartId, lines);
+      return { ok: true };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal.' };
+    } finally {
+      await this.loadFor(cartId);
+    }
+  }
+
+  async releaseCart(cartId: string): Promise<void> {
+    await releaseReservations('cart', cartId).catch(() => {});
+  }
+}
+
+export const stockReservations = new StockReservationsStore();
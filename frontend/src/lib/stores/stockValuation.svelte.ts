@@ -0,0 +1,29 @@
+import { getStockHistory, type StockHistory } from '$lib/api/stock-snapshots';
+
+// Daily on-hand value read from the end-of-day stock snapshots. Reloaded
+// whenever the period or location filter changes.
+class StockValuationStore {
+  history = $state<StockHistory | null>(null);
+  loading = $state(false);
+  error = $state('');
+
+  private seq = 0;
+
+  async load(params: { start: string; end: string; locationId?: string }): Promise<void> {
+    const seq = ++this.seq;
+    this.loading = true;
+    try {
+      const res = await getStockHistory(params);
+      if (seq !== this.seq) return;
+      this.history = res;
+      this.error = '';
+    } catch (err) {
+      if (seq !== this.seq) return;
+      this.error = err instanceof Error ? err.message : 'Gagal memuat riwayat nilai stok.';
+    } finally {
+      if (seq === this.seq) this.loading = false;
+    }
+  }
+}
+
+export const stockValuation = new StockValuationStore();
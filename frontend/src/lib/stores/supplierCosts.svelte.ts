@@ -0,0 +1,120 @@
+import {
+  importPriceList,
+  listPriceImports,
+  getSupplierCostAlerts,
+  compareSupplierCosts,
+  listSupplierCostHistory,
+  type PriceImport,
+  type PriceImportInput,
+  type PriceImportSummary,
+  type SupplierCostAlerts,
+  type SupplierCostChange,
+  type SupplierCostOffer
+} from '$lib/api/supplier-costs';
+import { products } from './products.svelte';
+
+// Supplier price lists and the cost history they feed. Matching, change
+// detection and alerts all happen server-side; the store keeps the last
+// preview so the page can show it before the upload is applied.
+class SupplierCostsStore {
+  imports = $state<PriceImportSummary[]>([]);
+  alerts = $state<SupplierCostAlerts | null>(null);
+  preview = $state<PriceImport | null>(null);
+  loading = $state(false);
+  // Compare results per product, for the forms that show what a supplier
+  // charges and last delivered at. Filled by ensureOffers.
+  offers = $state<Record<string, SupplierCostOffer[]>>({});
+
+  private requested = new Set<string>();
+
+  async loadImports(): Promise<void> {
+    this.imports = await listPriceImports();
+  }
+
+  async loadAlerts(days = 30): Promise<void> {
+    if (this.loading) return;
+    this.loading = true;
+    try {
+      this.alerts = await getSupplierCostAlerts({ days });
+    } finally {
+      this.loading = false;
+    }
+  }
+
+  // Dry run: match the file and report what would change, saving nothing.
+  async check(input: PriceImportInput): Promise<{ ok: boolean; reason?: string }> {
+    try {
+      this.preview = await importPriceList({ ...input, dryRun: true });
+      return { ok: true };
+    } catch (err) {
+      this.preview = null;
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal membaca file.' };
+    }
+  }
+
+  // Apply the price list. Changes effective today update the products'
+  // supplier costs at once, so products are reloaded.
+  async apply(
+    input: PriceImportInput
+  ): Promise<{ ok: boolean; result?: PriceImport; reason?: string }> {
+    try {
+      const result = await importPriceList({ ...input, dryRun: false });
+      this.preview = null;
+      this.offers = {};
+      this.requested.clear();
+      await Promise.all([this.loadImports(), this.loadAlerts(), products.load()]);
+      return { ok: true, result };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal mengimpor.' };
+    }
+  }
+
+  history(params: { productId?: string; supplierId?: string }): Promise<SupplierCostChange[]> {
+    return listSupplierCostHistory(params);
+  }
+
+  compare(productId: string): Promise<SupplierCostOffer[]> {
+    return compareSupplierCosts(productId);
+  }
+
+  // Loads the offers of products not fetched yet. Call from an effect.
+  ensureOffers(productIds: string[]): void {
+    for (const id of productIds) {
+      if (!id || this.requested.has(id)) continue;
+      this.requested.add(id);
+      compareSupplierCosts(id)
+        .then((list) => {
+          this.offers[id] = list;
+        })
+        .catch(() => {
+          this.requested.delete(id);
+        });
+    }
+  }
+
+  offerFrom(productId: string, supplierId: string): SupplierCostOffer | undefined {
+    return this.offers[productId]?.find((o) => o.supplierId === supplierId);
+  }
+
+  // What the supplier last delivered this product at, or null before any
+  // receipt (or before ensureOffers has loaded the product).
+  lastReceived(
+    productId: string,
+    supplierId: string
+  ): { unitCost: number; receivedAt: string; daysAgo: number; changePct: number } | null {
+    const offer = this.offerFrom(productId, supplierId);
+    if (!offer || !offer.lastReceivedAt) return null;
+    const received = new Date(offer.lastReceivedAt).getTime();
+    const daysAgo = Number.isNaN(received)
+      ? 0
+      : Math.max(0, Math.floor((Date.now() - received) / 86400000));
+    return {
+      unitCost: offer.lastReceivedCost,
+      receivedAt: offer.lastReceivedAt,
+      daysAgo,
+      changePct: offer.changePct
+    };
+  }
+}
+
+export const supplierCosts = new SupplierCostsStore();
@@ -0,0 +1,100 @@
+import {
+  listSupplierInvoices,
+  getSupplierInvoice,
+  createSupplierInvoice,
+  updateSupplierInvoice,
+  matchSupplierInvoice,
+  cancelSupplierInvoice,
+  type SupplierInvoice,
+  type SupplierInvoiceInput,
+  type SupplierInvoiceStatus
+} from '$lib/api/supplier-invoices';
+import { payables } from './payables.svelte';
+
+// Supplier invoices. Matching happens server-side on every save (and when
+// goods are received); any change can move amounts in or out of payables,
+// so the AP report is reloaded after each one.
+class SupplierInvoicesStore {
+  items = $state<SupplierInvoice[]>([]);
+  loaded = $state(false);
+  loading = $state(false);
+
+  async load(params?: { supplierId?: string; status?: SupplierInvoiceStatus }): Promise<void> {
+    if (this.loading) return;
+    this.loading = true;
+    try {
+      this.items = await listSupplierInvoices(params);
+      this.loaded = true;
+    } finally {
+      this.loading = false;
+    }
+  }
+
+  get(id: string): Promise<SupplierInvoice> {
+    return getSupplierInvoice(id);
+  }
+
+  forPurchaseOrder(purchaseOrderId: string): Promise<SupplierInvoice[]> {
+    return listSupplierInvoices({ purchaseOrderId });
+  }
+
+  async save(
+    input: SupplierInvoiceInput,
+    id?: string
+  ): Promise<{ ok: boolean; invoice?: SupplierInvoice; reason?: string }> {
+    if (!input.invoiceNumber.trim()) return { ok: false, reason: 'Nomor faktur wajib diisi.' };
+    const lines = input.lines.filter((l) => l.qty > 0);
+    if (lines.length === 0) return { ok: false, reason: 'Isi qty minimal satu item.' };
+    try {
+      const body = { ...input, lines };
+      const invoice = id ? await updateSupplierInvoice(id, body) : await createSupplierInvoice(body);
+      this.upsert(invoice);
+      void payables.load();
+      return { ok: true, invoice };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal menyimpan faktur.' };
+    }
+  }
+
+  async match(id: string): Promise<{ ok: boolean; invoice?: SupplierInvoice; reason?: string }> {
+    return this.act(() => matchSupplierInvoice(id));
+  }
+
+  async cancel(id: string): Promise<{ ok: boolean; invoice?: SupplierInvoice; reason?: string }> {
+    return this.act(() => cancelSupplierInvoice(id));
+  }
+
+  private async act(
+    fn: () => Promise<SupplierInvoice>
+  ): Promise<{ ok: boolean; invoice?: SupplierInvoice; reason?: string }> {
+    try {
+      const invoice = await fn();
+      this.upsert(invoice);
+      void payables.load();
+      return { ok: true, invoice };
+    } catch (err) {
+      return { ok: false, reason: err instanceof Error ? err.message : 'Gagal memproses faktur.' };
+    }
+  }
+
+  private upsert(invoice: SupplierInvoice) {
+    const row = { ...invoice, lines: [] };
+    this.items = this.items.some((i) => i.id === invoice.id)
+      ? this.items.map((i) => (i.id === invoice.id ? row : i))
+      : [row, ...this.items];
+  }
+}
+
+export const supplierInvoices = new SupplierInvoicesStore();
+
+export const supplierInvoiceStatusLabels: Record<SupplierInvoiceStatus, string> = {
+  matched: 'Cocok',
+  discrepancy: 'Selisih',
+  cancelled: 'Dibatalkan'
+};
+
+export const supplierInvoiceStatusBadge: Record<SupplierInvoiceStatus, 'success' | 'warning' | 'neutral'> = {
+  matched: 'success',
+  discrepancy: 'warning',
+  cancelled: 'neutral'
+};
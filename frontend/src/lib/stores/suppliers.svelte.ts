@@ -17,6 +17,8 @@ export type Supplier = {
   phone: string;
   address: string;
   leadTimeDays: number;
+  // Net days: received POs fall due this long after the first receipt.
+  paymentTermsDays: number;
   status: SupplierStatus;
   notes: string;
 };
@@ -32,6 +34,7 @@ function toSupplier(s: ApiSupplier): Supplier {
     phone: s.phone,
     address: s.address,
     leadTimeDays: s.leadTimeDays,
+    paymentTermsDays: s.paymentTermsDays ?? 0,
     status: s.status,
     notes: s.notes
   };
@@ -45,6 +48,7 @@ function toApiInput(s: SupplierInput): ApiSupplierInput {
     phone: s.phone,
     address: s.address,
     leadTimeDays: s.leadTimeDays,
+    paymentTermsDays: s.paymentTermsDays,
     status: s.status,
     notes: s.notes
   };
@@ -3,6 +3,7 @@ import { employees, type Employee } from './employees.svelte';
 import { roles, type Role } from './roles.svelte';
 import { setToken, ApiError } from '$lib/api/client';
 import { login as apiLogin, me as apiMe, type ApiUser } from '$lib/api/auth';
+import { activeStore } from './activeStore.svelte';
 
 const TOKEN_KEY = 'pos.auth.token';
 
@@ -64,6 +65,7 @@ class UserState {
       if (typeof window !== 'undefined') {
         window.localStorage.setItem(TOKEN_KEY, res.token);
       }
+      await activeStore.load();
       return { ok: true };
     } catch (err) {
       if (err instanceof ApiError) {
@@ -80,6 +82,7 @@ class UserState {
     this.token = null;
     this.apiUser = null;
     setToken(null);
+    activeStore.reset();
     if (typeof window !== 'undefined') {
       window.localStorage.removeItem(TOKEN_KEY);
     }
@@ -92,6 +95,7 @@ class UserState {
     this.token = token;
     try {
       this.apiUser = await apiMe();
+      await activeStore.load();
     } catch {
       this.logout();
     }
@@ -167,7 +167,7 @@ export function forecastSubjects(): ForecastSubject[] {
 }
 
 // Whether the composite has its sales tracked at the composite-product line
-// level (yes — applyOrderToStock records line.productId regardless of kind).
+// level (yes — the sale records line.productId regardless of kind).
 // Helper kept for clarity; not currently used externally.
 export function isCompositeLine(productId: string): boolean {
   return isComposite(products.getById(productId) ?? ({} as never));